@@ -73,6 +73,8 @@ func main() {
 		g.GenerateModel("sys_table_view"),
 		g.GenerateModel("sys_job"),
 		g.GenerateModel("sys_job_log"),
+		g.GenerateModel("sys_audit_chain"),
+		g.GenerateModel("sys_audit_archive"),
 	)
 
 	// 执行生成
@@ -9,10 +9,13 @@ import (
 
 	"yqhp/admin/internal/auth"
 	"yqhp/admin/internal/config"
+	"yqhp/admin/internal/logic"
 	"yqhp/admin/internal/router"
 	"yqhp/admin/internal/svc"
 	"yqhp/common/database"
 	"yqhp/common/logger"
+	"yqhp/common/redis"
+	"yqhp/common/scheduler"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -53,6 +56,38 @@ func main() {
 		log.Fatalf("初始化SaToken失败: %v", err)
 	}
 
+	// 初始化定时任务调度器（配置了Redis时启用分布式锁，多实例只执行一次）
+	schedOpts := []scheduler.Option{
+		scheduler.WithLogCallback(logic.RecordJobLog),
+		scheduler.WithCompleteCallback(logic.OnJobComplete),
+	}
+	if cfg.Redis.Host != "" && cfg.Redis.Port > 0 {
+		if err := redis.Init(&cfg.Redis); err != nil {
+			log.Printf("初始化Redis失败，定时任务不启用分布式锁: %v", err)
+		} else {
+			defer redis.Close()
+			schedOpts = append(schedOpts, scheduler.WithRedisLocker(redis.GetClient()))
+		}
+	}
+	sched, err := scheduler.NewScheduler(schedOpts...)
+	if err != nil {
+		log.Fatalf("初始化调度器失败: %v", err)
+	}
+	logic.SetScheduler(sched)
+
+	// 审计日志保留策略
+	if cfg.Audit.RetentionCron != "" {
+		if err := sched.Register(logic.NewAuditRetentionJob(), cfg.Audit.RetentionCron); err != nil {
+			log.Printf("注册审计日志保留策略任务失败: %v", err)
+		}
+	}
+	sched.Start()
+
+	// 加载数据库中的定时任务（含错过执行的补偿）
+	if err := logic.LoadJobs(); err != nil {
+		log.Printf("加载定时任务失败: %v", err)
+	}
+
 	// 创建Fiber应用
 	app := fiber.New(fiber.Config{
 		AppName:      cfg.App.Name,
@@ -81,5 +116,8 @@ func main() {
 	if err := app.Shutdown(); err != nil {
 		log.Printf("服务器关闭失败: %v", err)
 	}
+	if err := sched.Stop(); err != nil {
+		log.Printf("调度器关闭失败: %v", err)
+	}
 	log.Println("服务器已关闭")
 }
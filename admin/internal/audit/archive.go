@@ -0,0 +1,213 @@
+package audit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"yqhp/admin/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrChainBroken 哈希链校验未通过，拒绝归档
+var ErrChainBroken = errors.New("审计链校验未通过，已拒绝归档，请先执行校验排查")
+
+// Archive 按保留策略归档并清理审计记录
+// 将 created_at 早于 before 的已入链记录按序写入 gzip 压缩的 JSONL 文件，
+// 登记归档元数据并推进链头 base，之后才从表中删除。链不完整时拒绝归档，避免篡改被"洗白"。
+// 没有需要归档的记录时返回 nil, nil。
+func Archive(ctx context.Context, db *gorm.DB, chain string, before time.Time, dir string, operator int64) (*model.SysAuditArchive, error) {
+	if !ValidChain(chain) {
+		return nil, fmt.Errorf("未知的审计链: %s", chain)
+	}
+
+	var head model.SysAuditChain
+	if err := db.WithContext(ctx).Where("chain = ?", chain).First(&head).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var toSeq int64
+	if err := db.WithContext(ctx).Table(tableOf(chain)).
+		Where("seq > ? AND created_at < ?", head.BaseSeq, before).
+		Select("COALESCE(MAX(seq), 0)").Scan(&toSeq).Error; err != nil {
+		return nil, err
+	}
+	if toSeq <= head.BaseSeq {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建归档目录失败: %w", err)
+	}
+	name := fmt.Sprintf("%s-%d-%d-%s.jsonl.gz", chain, head.BaseSeq+1, toSeq, time.Now().Format("20060102150405"))
+	finalPath := filepath.Join(dir, name)
+	tmpPath := finalPath + ".tmp"
+
+	count, lastHash, err := writeArchiveFile(ctx, db, chain, &head, toSeq, tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	sum, size, err := fileDigest(tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, finalPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("保存归档文件失败: %w", err)
+	}
+
+	archive := &model.SysAuditArchive{
+		Chain:       chain,
+		FromSeq:     head.BaseSeq + 1,
+		ToSeq:       toSeq,
+		RecordCount: count,
+		PrevHash:    head.BaseHash,
+		LastHash:    lastHash,
+		FilePath:    finalPath,
+		FileSha256:  sum,
+		FileSize:    size,
+		Before:      &before,
+		CreatedBy:   model.Int64Ptr(operator),
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := lockHead(tx, chain)
+		if err != nil {
+			return err
+		}
+		// 归档期间其他实例已推进 base，本次结果作废
+		if locked.BaseSeq != head.BaseSeq {
+			return errors.New("审计链已被其他任务归档，请重试")
+		}
+		if err := tx.Create(archive).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(tableOf(chain)).Where("seq <= ?", toSeq).Delete(newRecord(chain)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.SysAuditChain{}).Where("chain = ?", chain).Updates(map[string]any{
+			"base_seq":   toSeq,
+			"base_hash":  lastHash,
+			"updated_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		os.Remove(finalPath)
+		return nil, err
+	}
+	return archive, nil
+}
+
+// writeArchiveFile 顺序校验并写出 (base, toSeq] 区间记录
+func writeArchiveFile(ctx context.Context, db *gorm.DB, chain string, head *model.SysAuditChain, toSeq int64, path string) (int64, string, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("创建归档文件失败: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	w := bufio.NewWriter(gz)
+	enc := json.NewEncoder(w)
+
+	var count int64
+	broken := false
+	_, lastHash, err := walk(dbLoader(ctx, db, chain), head.BaseSeq, head.BaseHash, toSeq,
+		func(Issue) { broken = true },
+		func(record any) error {
+			if broken {
+				return ErrChainBroken
+			}
+			count++
+			return enc.Encode(record)
+		})
+	if err == nil && broken {
+		err = ErrChainBroken
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	if err := w.Flush(); err != nil {
+		return 0, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, "", err
+	}
+	return count, lastHash, f.Sync()
+}
+
+func fileDigest(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// VerifyArchive 校验归档文件：文件摘要一致，且文件内记录构成从 PrevHash 到 LastHash 的完整链
+func VerifyArchive(archive *model.SysAuditArchive) error {
+	sum, _, err := fileDigest(archive.FilePath)
+	if err != nil {
+		return fmt.Errorf("读取归档文件失败: %w", err)
+	}
+	if sum != archive.FileSha256 {
+		return errors.New("归档文件摘要不一致，文件可能被修改")
+	}
+
+	f, err := os.Open(archive.FilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(gz)
+	expected, prev := archive.FromSeq, archive.PrevHash
+	for dec.More() {
+		record := newRecord(archive.Chain)
+		if err := dec.Decode(record); err != nil {
+			return fmt.Errorf("解析归档记录失败: %w", err)
+		}
+		_, seq, prevHash, hash := recordSeal(record)
+		computed, err := ComputeHash(prevHash, record)
+		if err != nil {
+			return err
+		}
+		if seq != expected || prevHash != prev || computed != hash {
+			return fmt.Errorf("归档记录 seq=%d 校验失败", seq)
+		}
+		expected, prev = seq+1, hash
+	}
+	if expected-1 != archive.ToSeq || prev != archive.LastHash {
+		return errors.New("归档文件记录不完整")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"yqhp/admin/internal/model"
+)
+
+// writeGzipLines 将 JSONL 行写入 gzip 文件
+func writeGzipLines(t *testing.T, path string, lines [][]byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for _, line := range lines {
+		gz.Write(line)
+		gz.Write([]byte("\n"))
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("写入归档文件: %v", err)
+	}
+}
+
+// newArchive 将记录写成归档文件并返回与之一致的归档元数据
+func newArchive(t *testing.T, records []*model.SysOperationLog) (*model.SysAuditArchive, [][]byte) {
+	t.Helper()
+	lines := make([][]byte, 0, len(records))
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			t.Fatalf("序列化记录: %v", err)
+		}
+		lines = append(lines, line)
+	}
+
+	path := filepath.Join(t.TempDir(), "operation.jsonl.gz")
+	writeGzipLines(t, path, lines)
+	sum, _, err := fileDigest(path)
+	if err != nil {
+		t.Fatalf("fileDigest: %v", err)
+	}
+
+	first, last := records[0], records[len(records)-1]
+	return &model.SysAuditArchive{
+		Chain:      ChainOperation,
+		FromSeq:    model.GetInt64(first.Seq),
+		ToSeq:      model.GetInt64(last.Seq),
+		PrevHash:   model.GetString(first.PrevHash),
+		LastHash:   model.GetString(last.Hash),
+		FilePath:   path,
+		FileSha256: sum,
+	}, lines
+}
+
+func TestVerifyArchive_Valid(t *testing.T) {
+	archive, _ := newArchive(t, buildChain(t, 4))
+	if err := VerifyArchive(archive); err != nil {
+		t.Fatalf("VerifyArchive: %v", err)
+	}
+}
+
+func TestVerifyArchive_ModifiedLine(t *testing.T) {
+	archive, lines := newArchive(t, buildChain(t, 4))
+	lines[1] = bytes.Replace(lines[1], []byte(`"username":"admin"`), []byte(`"username":"attacker"`), 1)
+	writeGzipLines(t, archive.FilePath, lines)
+
+	// 文件摘要不再一致
+	err := VerifyArchive(archive)
+	if err == nil || !strings.Contains(err.Error(), "摘要不一致") {
+		t.Fatalf("期望摘要不一致错误, 实际 %v", err)
+	}
+
+	// 摘要同时被改写时，逐条哈希校验仍能发现被修改的记录
+	archive.FileSha256, _, _ = fileDigest(archive.FilePath)
+	err = VerifyArchive(archive)
+	if err == nil || !strings.Contains(err.Error(), "seq=2 校验失败") {
+		t.Fatalf("期望 seq=2 校验失败, 实际 %v", err)
+	}
+}
+
+func TestVerifyArchive_Incomplete(t *testing.T) {
+	records := buildChain(t, 4)
+	archive, _ := newArchive(t, records[:3])
+	archive.ToSeq = 4
+	archive.LastHash = model.GetString(records[3].Hash)
+
+	if err := VerifyArchive(archive); err == nil || !strings.Contains(err.Error(), "不完整") {
+		t.Fatalf("期望记录不完整错误, 实际 %v", err)
+	}
+}
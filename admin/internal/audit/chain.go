@@ -0,0 +1,232 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"yqhp/admin/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 哈希链名称
+const (
+	ChainOperation = "operation" // 操作日志
+	ChainLogin     = "login"     // 登录日志
+)
+
+// GenesisHash 链首记录的 prev_hash
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// ValidChain 判断链名称是否合法
+func ValidChain(chain string) bool {
+	return chain == ChainOperation || chain == ChainLogin
+}
+
+// operationPayload 操作日志参与哈希计算的字段（字段顺序即序列化顺序，不可调整）
+type operationPayload struct {
+	Seq       int64  `json:"seq"`
+	CreatedAt int64  `json:"created_at"`
+	UserID    int64  `json:"user_id"`
+	Username  string `json:"username"`
+	Module    string `json:"module"`
+	Action    string `json:"action"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	Params    string `json:"params"`
+	Result    string `json:"result"`
+	Status    int32  `json:"status"`
+	Duration  int64  `json:"duration"`
+	ErrorMsg  string `json:"error_msg"`
+}
+
+// loginPayload 登录日志参与哈希计算的字段（字段顺序即序列化顺序，不可调整）
+type loginPayload struct {
+	Seq       int64  `json:"seq"`
+	CreatedAt int64  `json:"created_at"`
+	UserID    int64  `json:"user_id"`
+	Username  string `json:"username"`
+	IP        string `json:"ip"`
+	Location  string `json:"location"`
+	Browser   string `json:"browser"`
+	Os        string `json:"os"`
+	Status    int32  `json:"status"`
+	Message   string `json:"message"`
+	LoginType string `json:"login_type"`
+}
+
+func unixOf(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+// payloadOf 提取记录的哈希载荷
+func payloadOf(record any) ([]byte, error) {
+	switch r := record.(type) {
+	case *model.SysOperationLog:
+		return json.Marshal(operationPayload{
+			Seq:       model.GetInt64(r.Seq),
+			CreatedAt: unixOf(r.CreatedAt),
+			UserID:    model.GetInt64(r.UserID),
+			Username:  model.GetString(r.Username),
+			Module:    model.GetString(r.Module),
+			Action:    model.GetString(r.Action),
+			Method:    model.GetString(r.Method),
+			Path:      model.GetString(r.Path),
+			IP:        model.GetString(r.IP),
+			UserAgent: model.GetString(r.UserAgent),
+			Params:    model.GetString(r.Params),
+			Result:    model.GetString(r.Result),
+			Status:    model.GetInt32(r.Status),
+			Duration:  model.GetInt64(r.Duration),
+			ErrorMsg:  model.GetString(r.ErrorMsg),
+		})
+	case *model.SysLoginLog:
+		return json.Marshal(loginPayload{
+			Seq:       model.GetInt64(r.Seq),
+			CreatedAt: unixOf(r.CreatedAt),
+			UserID:    model.GetInt64(r.UserID),
+			Username:  model.GetString(r.Username),
+			IP:        model.GetString(r.IP),
+			Location:  model.GetString(r.Location),
+			Browser:   model.GetString(r.Browser),
+			Os:        model.GetString(r.Os),
+			Status:    model.GetInt32(r.Status),
+			Message:   model.GetString(r.Message),
+			LoginType: model.GetString(r.LoginType),
+		})
+	}
+	return nil, fmt.Errorf("不支持的审计记录类型: %T", record)
+}
+
+// ComputeHash 计算记录哈希: sha256(prevHash + "\n" + payload)
+func ComputeHash(prevHash string, record any) (string, error) {
+	payload, err := payloadOf(record)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write([]byte("\n"))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// chainOf 返回记录所属的链
+func chainOf(record any) (string, error) {
+	switch record.(type) {
+	case *model.SysOperationLog:
+		return ChainOperation, nil
+	case *model.SysLoginLog:
+		return ChainLogin, nil
+	}
+	return "", fmt.Errorf("不支持的审计记录类型: %T", record)
+}
+
+// lockHead 锁定链头（不存在则创建），调用方需处于事务中
+func lockHead(tx *gorm.DB, chain string) (*model.SysAuditChain, error) {
+	var head model.SysAuditChain
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("chain = ?", chain).First(&head).Error
+	if err == nil {
+		return &head, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	head = model.SysAuditChain{Chain: chain, LastHash: GenesisHash, BaseHash: GenesisHash}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&head).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("chain = ?", chain).First(&head).Error; err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
+
+// Append 将审计记录追加到所属哈希链
+// 链头行锁保证多实例下序号连续、prev_hash 正确衔接
+func Append(ctx context.Context, db *gorm.DB, record any) error {
+	chain, err := chainOf(record)
+	if err != nil {
+		return err
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		head, err := lockHead(tx, chain)
+		if err != nil {
+			return fmt.Errorf("锁定审计链失败: %w", err)
+		}
+
+		seq := head.LastSeq + 1
+		// 数据库 datetime 精度为秒，截断后哈希才能被重新计算
+		now := time.Now().Truncate(time.Second)
+
+		var hash string
+		switch r := record.(type) {
+		case *model.SysOperationLog:
+			r.CreatedAt, r.UpdatedAt = &now, &now
+			r.Seq = model.Int64Ptr(seq)
+			r.PrevHash = model.StringPtr(head.LastHash)
+			if hash, err = ComputeHash(head.LastHash, r); err != nil {
+				return err
+			}
+			r.Hash = model.StringPtr(hash)
+		case *model.SysLoginLog:
+			r.CreatedAt, r.UpdatedAt = &now, &now
+			r.Seq = model.Int64Ptr(seq)
+			r.PrevHash = model.StringPtr(head.LastHash)
+			if hash, err = ComputeHash(head.LastHash, r); err != nil {
+				return err
+			}
+			r.Hash = model.StringPtr(hash)
+		}
+
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.SysAuditChain{}).Where("chain = ?", chain).Updates(map[string]any{
+			"last_seq":   seq,
+			"last_hash":  hash,
+			"updated_at": now,
+		}).Error
+	})
+}
+
+// recordSeal 读取记录的链字段
+func recordSeal(record any) (id, seq int64, prevHash, hash string) {
+	switch r := record.(type) {
+	case *model.SysOperationLog:
+		return r.ID, model.GetInt64(r.Seq), model.GetString(r.PrevHash), model.GetString(r.Hash)
+	case *model.SysLoginLog:
+		return r.ID, model.GetInt64(r.Seq), model.GetString(r.PrevHash), model.GetString(r.Hash)
+	}
+	return 0, 0, "", ""
+}
+
+// newRecord 创建链对应的空记录
+func newRecord(chain string) any {
+	if chain == ChainLogin {
+		return &model.SysLoginLog{}
+	}
+	return &model.SysOperationLog{}
+}
+
+// tableOf 返回链对应的表名
+func tableOf(chain string) string {
+	if chain == ChainLogin {
+		return model.TableNameSysLoginLog
+	}
+	return model.TableNameSysOperationLog
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"yqhp/admin/internal/model"
+)
+
+// newOperationLog 创建一条未封存的操作日志
+func newOperationLog(seq int64) *model.SysOperationLog {
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).Add(time.Duration(seq) * time.Minute)
+	return &model.SysOperationLog{
+		ID:        seq * 10,
+		CreatedAt: &createdAt,
+		UserID:    model.Int64Ptr(1),
+		Username:  model.StringPtr("admin"),
+		Module:    model.StringPtr("user"),
+		Action:    model.StringPtr("update"),
+		Method:    model.StringPtr("PUT"),
+		Path:      model.StringPtr("/api/users/1"),
+		IP:        model.StringPtr("127.0.0.1"),
+		Seq:       model.Int64Ptr(seq),
+	}
+}
+
+// seal 以 prevHash 为前驱封存记录并返回其哈希
+func seal(t *testing.T, record *model.SysOperationLog, prevHash string) string {
+	t.Helper()
+	hash, err := ComputeHash(prevHash, record)
+	if err != nil {
+		t.Fatalf("ComputeHash: %v", err)
+	}
+	record.PrevHash = model.StringPtr(prevHash)
+	record.Hash = model.StringPtr(hash)
+	return hash
+}
+
+// buildChain 构造序号 1..n 的完整操作日志链
+func buildChain(t *testing.T, n int) []*model.SysOperationLog {
+	t.Helper()
+	records := make([]*model.SysOperationLog, 0, n)
+	prev := GenesisHash
+	for i := 1; i <= n; i++ {
+		record := newOperationLog(int64(i))
+		prev = seal(t, record, prev)
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestComputeHash_Deterministic(t *testing.T) {
+	a, err := ComputeHash(GenesisHash, newOperationLog(1))
+	if err != nil {
+		t.Fatalf("ComputeHash: %v", err)
+	}
+	b, err := ComputeHash(GenesisHash, newOperationLog(1))
+	if err != nil {
+		t.Fatalf("ComputeHash: %v", err)
+	}
+	if a != b {
+		t.Fatalf("相同输入的哈希不一致: %s != %s", a, b)
+	}
+	if len(a) != len(GenesisHash) {
+		t.Fatalf("哈希长度 = %d, 期望 %d", len(a), len(GenesisHash))
+	}
+
+	// 封存字段不参与哈希计算
+	sealed := newOperationLog(1)
+	sealed.PrevHash = model.StringPtr("x")
+	sealed.Hash = model.StringPtr("y")
+	if c, _ := ComputeHash(GenesisHash, sealed); c != a {
+		t.Fatalf("封存字段影响了哈希: %s != %s", c, a)
+	}
+}
+
+func TestComputeHash_Sensitivity(t *testing.T) {
+	base, _ := ComputeHash(GenesisHash, newOperationLog(1))
+
+	tests := []struct {
+		name   string
+		prev   string
+		modify func(r *model.SysOperationLog)
+	}{
+		{name: "prev_hash", prev: base},
+		{name: "seq", prev: GenesisHash, modify: func(r *model.SysOperationLog) { r.Seq = model.Int64Ptr(2) }},
+		{name: "username", prev: GenesisHash, modify: func(r *model.SysOperationLog) { r.Username = model.StringPtr("root") }},
+		{name: "path", prev: GenesisHash, modify: func(r *model.SysOperationLog) { r.Path = model.StringPtr("/api/users/2") }},
+		{name: "created_at", prev: GenesisHash, modify: func(r *model.SysOperationLog) {
+			later := r.CreatedAt.Add(time.Second)
+			r.CreatedAt = &later
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := newOperationLog(1)
+			if tt.modify != nil {
+				tt.modify(record)
+			}
+			got, err := ComputeHash(tt.prev, record)
+			if err != nil {
+				t.Fatalf("ComputeHash: %v", err)
+			}
+			if got == base {
+				t.Fatalf("修改 %s 后哈希未变化", tt.name)
+			}
+		})
+	}
+}
+
+func TestComputeHash_UnsupportedRecord(t *testing.T) {
+	if _, err := ComputeHash(GenesisHash, struct{}{}); err == nil {
+		t.Fatal("不支持的记录类型应返回错误")
+	}
+}
@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"yqhp/admin/internal/model"
+
+	"gorm.io/gorm"
+)
+
+const (
+	batchSize = 500
+	maxIssues = 100
+)
+
+// 校验问题类型
+const (
+	IssueGap          = "gap"           // 序号缺失（记录被删除）
+	IssueBrokenLink   = "broken_link"   // prev_hash 与上一条记录哈希不一致
+	IssueTampered     = "tampered"      // 记录内容与哈希不一致（记录被修改）
+	IssueTruncated    = "truncated"     // 链尾记录缺失
+	IssueHeadMismatch = "head_mismatch" // 链头哈希与末条记录不一致
+)
+
+// Issue 校验发现的问题
+type Issue struct {
+	Type    string `json:"type"`
+	Seq     int64  `json:"seq"`
+	ID      int64  `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// VerifyResult 哈希链校验结果
+type VerifyResult struct {
+	Chain     string  `json:"chain"`
+	Valid     bool    `json:"valid"`
+	BaseSeq   int64   `json:"baseSeq"`   // 已归档的最大序号，校验从其下一条开始
+	LastSeq   int64   `json:"lastSeq"`   // 链头记录的最新序号
+	Checked   int64   `json:"checked"`   // 已校验记录数
+	Unchained int64   `json:"unchained"` // 启用哈希链之前写入的历史记录数
+	Issues    []Issue `json:"issues"`
+	Truncated bool    `json:"truncated"` // 问题数超过上限被截断
+}
+
+func (r *VerifyResult) addIssue(issue Issue) {
+	r.Valid = false
+	if len(r.Issues) >= maxIssues {
+		r.Truncated = true
+		return
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// batchLoader 按序号升序加载 (afterSeq, toSeq] 区间内的一批记录，toSeq<=0 表示不限，没有更多记录时返回空
+type batchLoader func(afterSeq, toSeq int64) ([]any, error)
+
+// dbLoader 从数据库按批加载链上记录
+func dbLoader(ctx context.Context, db *gorm.DB, chain string) batchLoader {
+	return func(afterSeq, toSeq int64) ([]any, error) {
+		return loadBatch(ctx, db, chain, afterSeq, toSeq)
+	}
+}
+
+// loadBatch 按序号升序加载 (afterSeq, toSeq] 区间内的记录，toSeq<=0 表示不限
+func loadBatch(ctx context.Context, db *gorm.DB, chain string, afterSeq, toSeq int64) ([]any, error) {
+	q := db.WithContext(ctx).Where("seq > ?", afterSeq)
+	if toSeq > 0 {
+		q = q.Where("seq <= ?", toSeq)
+	}
+	q = q.Order("seq ASC").Limit(batchSize)
+
+	var out []any
+	if chain == ChainLogin {
+		var rows []*model.SysLoginLog
+		if err := q.Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			out = append(out, r)
+		}
+		return out, nil
+	}
+
+	var rows []*model.SysOperationLog
+	if err := q.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// walk 从 (startSeq, startHash) 开始顺序遍历链上记录并校验衔接关系
+// 每条记录校验后回调 fn，fn 返回错误时中止遍历
+func walk(load batchLoader, startSeq int64, startHash string, toSeq int64,
+	report func(Issue), fn func(record any) error) (lastSeq int64, lastHash string, err error) {
+	expected, prev := startSeq+1, startHash
+	lastSeq, lastHash = startSeq, startHash
+
+	for {
+		batch, err := load(lastSeq, toSeq)
+		if err != nil {
+			return lastSeq, lastHash, err
+		}
+		if len(batch) == 0 {
+			return lastSeq, lastHash, nil
+		}
+
+		for _, record := range batch {
+			id, seq, prevHash, hash := recordSeal(record)
+			if seq != expected {
+				report(Issue{Type: IssueGap, Seq: expected, Message: fmt.Sprintf("序号 %d 至 %d 的记录缺失", expected, seq-1)})
+			} else if prevHash != prev {
+				report(Issue{Type: IssueBrokenLink, Seq: seq, ID: id, Message: "prev_hash 与上一条记录哈希不一致"})
+			}
+
+			computed, err := ComputeHash(prevHash, record)
+			if err != nil {
+				return lastSeq, lastHash, err
+			}
+			if computed != hash {
+				report(Issue{Type: IssueTampered, Seq: seq, ID: id, Message: "记录内容与哈希不一致，可能被修改"})
+			}
+
+			if fn != nil {
+				if err := fn(record); err != nil {
+					return lastSeq, lastHash, err
+				}
+			}
+
+			// 以存储的哈希继续衔接，一次篡改只报告一次
+			expected, prev = seq+1, hash
+			lastSeq, lastHash = seq, hash
+		}
+	}
+}
+
+// Verify 校验哈希链完整性：序号连续、链接正确、内容未被修改、链尾未被截断
+func Verify(ctx context.Context, db *gorm.DB, chain string) (*VerifyResult, error) {
+	if !ValidChain(chain) {
+		return nil, fmt.Errorf("未知的审计链: %s", chain)
+	}
+
+	var head model.SysAuditChain
+	if err := db.WithContext(ctx).Where("chain = ?", chain).First(&head).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		head = model.SysAuditChain{Chain: chain, LastHash: GenesisHash, BaseHash: GenesisHash}
+	}
+
+	var unchained int64
+	if err := db.WithContext(ctx).Table(tableOf(chain)).Where("seq IS NULL").Count(&unchained).Error; err != nil {
+		return nil, err
+	}
+
+	result, err := verifyChain(&head, dbLoader(ctx, db, chain))
+	if err != nil {
+		return nil, err
+	}
+	result.Unchained = unchained
+	return result, nil
+}
+
+// verifyChain 从链头记录的 base 开始遍历校验，并核对链尾与链头
+func verifyChain(head *model.SysAuditChain, load batchLoader) (*VerifyResult, error) {
+	result := &VerifyResult{
+		Chain:   head.Chain,
+		Valid:   true,
+		BaseSeq: head.BaseSeq,
+		LastSeq: head.LastSeq,
+		Issues:  []Issue{},
+	}
+
+	lastSeq, lastHash, err := walk(load, head.BaseSeq, head.BaseHash, 0, result.addIssue, func(any) error {
+		result.Checked++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if lastSeq < head.LastSeq {
+		result.addIssue(Issue{Type: IssueTruncated, Seq: lastSeq + 1, Message: fmt.Sprintf("序号 %d 至 %d 的链尾记录缺失", lastSeq+1, head.LastSeq)})
+	} else if lastSeq != head.LastSeq || lastHash != head.LastHash {
+		result.addIssue(Issue{Type: IssueHeadMismatch, Seq: lastSeq, Message: "链头哈希与末条记录哈希不一致"})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,155 @@
+package audit
+
+import (
+	"testing"
+
+	"yqhp/admin/internal/model"
+)
+
+// memLoader 从内存中的记录按批加载，批大小固定为 2 以覆盖跨批衔接
+func memLoader(records []*model.SysOperationLog) batchLoader {
+	return func(afterSeq, toSeq int64) ([]any, error) {
+		var out []any
+		for _, r := range records {
+			seq := model.GetInt64(r.Seq)
+			if seq <= afterSeq || (toSeq > 0 && seq > toSeq) {
+				continue
+			}
+			out = append(out, r)
+			if len(out) == 2 {
+				break
+			}
+		}
+		return out, nil
+	}
+}
+
+// headOf 返回与记录一致的链头
+func headOf(records []*model.SysOperationLog) *model.SysAuditChain {
+	last := records[len(records)-1]
+	return &model.SysAuditChain{
+		Chain:    ChainOperation,
+		BaseHash: GenesisHash,
+		LastSeq:  model.GetInt64(last.Seq),
+		LastHash: model.GetString(last.Hash),
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(t *testing.T, records []*model.SysOperationLog, head *model.SysAuditChain) []*model.SysOperationLog
+		issue   string
+		seq     int64
+		count   int // 问题数，0 视为 1
+		checked int64
+	}{
+		{
+			name:    "valid",
+			checked: 5,
+		},
+		{
+			name: "gap",
+			mutate: func(t *testing.T, records []*model.SysOperationLog, head *model.SysAuditChain) []*model.SysOperationLog {
+				return append(records[:2:2], records[3:]...)
+			},
+			issue:   IssueGap,
+			seq:     3,
+			checked: 4,
+		},
+		{
+			name: "broken_link",
+			mutate: func(t *testing.T, records []*model.SysOperationLog, head *model.SysAuditChain) []*model.SysOperationLog {
+				// 重新封存第 3 条使其自洽，但不再衔接第 2 条，第 4 条随之断链
+				seal(t, records[2], GenesisHash)
+				return records
+			},
+			issue:   IssueBrokenLink,
+			seq:     3,
+			count:   2,
+			checked: 5,
+		},
+		{
+			name: "tampered",
+			mutate: func(t *testing.T, records []*model.SysOperationLog, head *model.SysAuditChain) []*model.SysOperationLog {
+				records[3].Username = model.StringPtr("attacker")
+				return records
+			},
+			issue:   IssueTampered,
+			seq:     4,
+			checked: 5,
+		},
+		{
+			name: "truncated",
+			mutate: func(t *testing.T, records []*model.SysOperationLog, head *model.SysAuditChain) []*model.SysOperationLog {
+				return records[:3]
+			},
+			issue:   IssueTruncated,
+			seq:     4,
+			checked: 3,
+		},
+		{
+			name: "head_mismatch",
+			mutate: func(t *testing.T, records []*model.SysOperationLog, head *model.SysAuditChain) []*model.SysOperationLog {
+				head.LastHash = model.GetString(records[2].Hash)
+				return records
+			},
+			issue:   IssueHeadMismatch,
+			seq:     5,
+			checked: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := buildChain(t, 5)
+			head := headOf(records)
+			if tt.mutate != nil {
+				records = tt.mutate(t, records, head)
+			}
+
+			result, err := verifyChain(head, memLoader(records))
+			if err != nil {
+				t.Fatalf("verifyChain: %v", err)
+			}
+			if result.Checked != tt.checked {
+				t.Errorf("Checked = %d, 期望 %d", result.Checked, tt.checked)
+			}
+			if tt.issue == "" {
+				if !result.Valid || len(result.Issues) != 0 {
+					t.Fatalf("完整链校验失败: %+v", result.Issues)
+				}
+				return
+			}
+			if result.Valid {
+				t.Fatal("期望校验失败")
+			}
+			count := max(tt.count, 1)
+			if len(result.Issues) != count {
+				t.Fatalf("期望 %d 个问题, 实际 %+v", count, result.Issues)
+			}
+			for _, got := range result.Issues {
+				if got.Type != tt.issue {
+					t.Fatalf("问题类型 = %s, 期望 %s", got.Type, tt.issue)
+				}
+			}
+			if got := result.Issues[0]; got.Seq != tt.seq {
+				t.Fatalf("首个问题序号 = %d, 期望 %d", got.Seq, tt.seq)
+			}
+		})
+	}
+}
+
+func TestVerifyChain_FromArchivedBase(t *testing.T) {
+	records := buildChain(t, 5)
+	head := headOf(records)
+	head.BaseSeq = 2
+	head.BaseHash = model.GetString(records[1].Hash)
+
+	result, err := verifyChain(head, memLoader(records))
+	if err != nil {
+		t.Fatalf("verifyChain: %v", err)
+	}
+	if !result.Valid || result.Checked != 3 {
+		t.Fatalf("Valid = %v, Checked = %d, Issues = %+v", result.Valid, result.Checked, result.Issues)
+	}
+}
@@ -12,6 +12,14 @@ import (
 // Config 应用配置
 type Config struct {
 	commonConfig.Config `yaml:",inline"`
+	Audit               AuditConfig `yaml:"audit"`
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	ArchiveDir    string `yaml:"archive_dir"`    // 归档文件目录
+	RetentionDays int    `yaml:"retention_days"` // 保留天数，超出部分归档后清理，<=0 表示不清理
+	RetentionCron string `yaml:"retention_cron"` // 保留策略执行周期
 }
 
 var (
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"time"
+
+	"yqhp/admin/internal/audit"
+	"yqhp/admin/internal/logic"
+	"yqhp/admin/internal/types"
+	"yqhp/common/logger"
+	"yqhp/common/response"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// AuditVerify 校验审计日志哈希链
+func AuditVerify(c *fiber.Ctx) error {
+	chain := c.Params("chain")
+	if !audit.ValidChain(chain) {
+		return response.Error(c, "参数错误")
+	}
+
+	result, err := logic.NewAuditLogic(c).Verify(chain)
+	if err != nil {
+		return response.Error(c, "校验失败: "+err.Error())
+	}
+
+	return response.Success(c, result)
+}
+
+// AuditArchive 立即执行保留策略：归档并清理过期审计日志
+func AuditArchive(c *fiber.Ctx) error {
+	chain := c.Params("chain")
+	if !audit.ValidChain(chain) {
+		return response.Error(c, "参数错误")
+	}
+
+	var req types.ArchiveAuditLogsRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.Error(c, "参数解析失败")
+		}
+	}
+
+	archive, err := logic.NewAuditLogic(c).Archive(chain, req.RetentionDays)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, archive)
+}
+
+// AuditArchiveList 获取归档记录列表
+func AuditArchiveList(c *fiber.Ctx) error {
+	var req types.ListAuditArchivesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	archives, total, err := logic.NewAuditLogic(c).ListArchives(&req)
+	if err != nil {
+		return response.Error(c, "获取失败")
+	}
+
+	return response.Page(c, archives, total, req.Page, req.PageSize)
+}
+
+// AuditArchiveVerify 校验归档文件
+func AuditArchiveVerify(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "参数错误")
+	}
+
+	if err := logic.NewAuditLogic(c).VerifyArchive(id); err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, nil)
+}
+
+// AuditExport 流式导出审计日志（CSV / JSONL）
+func AuditExport(c *fiber.Ctx) error {
+	chain := c.Params("chain")
+	if !audit.ValidChain(chain) {
+		return response.Error(c, "参数错误")
+	}
+
+	var req types.ExportAuditLogsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+	if req.Format == "" {
+		req.Format = audit.FormatCSV
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	switch req.Format {
+	case audit.FormatCSV:
+	case audit.FormatJSONL:
+		contentType = "application/x-ndjson; charset=utf-8"
+	default:
+		return response.Error(c, "不支持的导出格式")
+	}
+
+	filename := fmt.Sprintf("%s-log-%s.%s", chain, time.Now().Format("20060102150405"), req.Format)
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	l := logic.NewAuditLogic(c)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := l.Export(chain, &req, w); err != nil {
+			logger.Error("审计日志导出失败", zap.String("chain", chain), zap.Error(err))
+		}
+	})
+	return nil
+}
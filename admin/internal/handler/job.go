@@ -54,8 +54,8 @@ func JobCreate(c *fiber.Ctx) error {
 		return response.Error(c, "参数解析失败")
 	}
 
-	if req.Name == "" || req.HandlerName == "" || req.CronExpression == "" {
-		return response.Error(c, "任务名称、处理器名称和Cron表达式不能为空")
+	if req.Name == "" || req.HandlerName == "" {
+		return response.Error(c, "任务名称和处理器名称不能为空")
 	}
 
 	job, err := logic.NewJobLogic(c).CreateJob(&req)
@@ -138,6 +138,11 @@ func JobRunOnce(c *fiber.Ctx) error {
 	return response.Success(c, nil)
 }
 
+// JobHandlers 获取已注册的任务处理器
+func JobHandlers(c *fiber.Ctx) error {
+	return response.Success(c, logic.NewJobLogic(c).ListHandlers())
+}
+
 // JobLogList 获取任务执行日志列表
 func JobLogList(c *fiber.Ctx) error {
 	var req types.ListJobLogsRequest
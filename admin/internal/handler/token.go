@@ -154,21 +154,3 @@ func TokenGetOperationLogs(c *fiber.Ctx) error {
 
 	return response.Page(c, logs, total, req.Page, req.PageSize)
 }
-
-// TokenClearLoginLogs 清空登录日志
-func TokenClearLoginLogs(c *fiber.Ctx) error {
-	if err := logic.NewTokenLogic(c).ClearLoginLogs(); err != nil {
-		return response.Error(c, err.Error())
-	}
-
-	return response.Success(c, nil)
-}
-
-// TokenClearOperationLogs 清空操作日志
-func TokenClearOperationLogs(c *fiber.Ctx) error {
-	if err := logic.NewTokenLogic(c).ClearOperationLogs(); err != nil {
-		return response.Error(c, err.Error())
-	}
-
-	return response.Success(c, nil)
-}
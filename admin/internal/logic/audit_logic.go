@@ -0,0 +1,151 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"yqhp/admin/internal/audit"
+	"yqhp/admin/internal/ctxutil"
+	"yqhp/admin/internal/model"
+	"yqhp/admin/internal/svc"
+	"yqhp/admin/internal/types"
+	"yqhp/common/logger"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const defaultAuditArchiveDir = "./data/audit"
+
+// AuditLogic 审计日志逻辑
+type AuditLogic struct {
+	ctx context.Context
+}
+
+// NewAuditLogic 创建审计日志逻辑
+func NewAuditLogic(c *fiber.Ctx) *AuditLogic {
+	return &AuditLogic{ctx: c.UserContext()}
+}
+
+func (l *AuditLogic) db() *gorm.DB {
+	return svc.Ctx.DB
+}
+
+// Verify 校验审计哈希链
+func (l *AuditLogic) Verify(chain string) (*audit.VerifyResult, error) {
+	return audit.Verify(l.ctx, l.db(), chain)
+}
+
+// Archive 按保留策略立即归档指定链。配置的保留天数是下限，retentionDays 只能延长保留期，
+// 不能用来提前归档并清理仍在保留期内的日志
+func (l *AuditLogic) Archive(chain string, retentionDays int) (*types.AuditArchiveInfo, error) {
+	cfg := svc.Ctx.Config.Audit
+	if cfg.RetentionDays <= 0 {
+		return nil, errors.New("未配置日志保留天数")
+	}
+	if retentionDays < cfg.RetentionDays {
+		retentionDays = cfg.RetentionDays
+	}
+
+	before := time.Now().AddDate(0, 0, -retentionDays)
+	archive, err := audit.Archive(l.ctx, l.db(), chain, before, auditArchiveDir(), ctxutil.GetUserID(l.ctx))
+	if err != nil {
+		return nil, err
+	}
+	if archive == nil {
+		return nil, nil
+	}
+	return types.ToAuditArchiveInfo(archive), nil
+}
+
+// ListArchives 获取归档记录列表
+func (l *AuditLogic) ListArchives(req *types.ListAuditArchivesRequest) ([]*types.AuditArchiveInfo, int64, error) {
+	q := l.db().Model(&model.SysAuditArchive{})
+	if req.Chain != "" {
+		q = q.Where("chain = ?", req.Chain)
+	}
+
+	var total int64
+	q.Count(&total)
+
+	if req.Page > 0 && req.PageSize > 0 {
+		q = q.Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize)
+	}
+
+	var archives []*model.SysAuditArchive
+	if err := q.Order("id DESC").Find(&archives).Error; err != nil {
+		return nil, 0, err
+	}
+	return types.ToAuditArchiveInfoList(archives), total, nil
+}
+
+// VerifyArchive 校验归档文件完整性
+func (l *AuditLogic) VerifyArchive(id int64) error {
+	var archive model.SysAuditArchive
+	if err := l.db().Where("id = ?", id).First(&archive).Error; err != nil {
+		return err
+	}
+	return audit.VerifyArchive(&archive)
+}
+
+// Export 流式导出审计日志
+// 在 fasthttp 的 BodyStreamWriter 中调用，此时请求上下文已结束，使用独立的 context
+func (l *AuditLogic) Export(chain string, req *types.ExportAuditLogsRequest, w io.Writer) error {
+	filter := &audit.Filter{
+		Username:  req.Username,
+		Module:    req.Module,
+		Status:    req.Status,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+	return audit.Export(context.Background(), l.db(), chain, filter, req.Format, w)
+}
+
+func auditArchiveDir() string {
+	if dir := svc.Ctx.Config.Audit.ArchiveDir; dir != "" {
+		return dir
+	}
+	return defaultAuditArchiveDir
+}
+
+// AuditRetentionJob 审计日志保留策略任务，依次归档并清理各条哈希链的过期记录
+type AuditRetentionJob struct{}
+
+// NewAuditRetentionJob 创建审计日志保留策略任务
+func NewAuditRetentionJob() *AuditRetentionJob {
+	return &AuditRetentionJob{}
+}
+
+// Name 任务名称
+func (j *AuditRetentionJob) Name() string {
+	return "audit_retention"
+}
+
+// Run 执行保留策略
+func (j *AuditRetentionJob) Run(ctx context.Context) error {
+	days := svc.Ctx.Config.Audit.RetentionDays
+	if days <= 0 {
+		return nil
+	}
+	before := time.Now().AddDate(0, 0, -days)
+
+	var errs []error
+	for _, chain := range []string{audit.ChainOperation, audit.ChainLogin} {
+		archive, err := audit.Archive(ctx, svc.Ctx.DB, chain, before, auditArchiveDir(), 0)
+		if err != nil {
+			logger.Error("审计日志归档失败", zap.String("chain", chain), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+		if archive != nil {
+			logger.Info("审计日志归档完成",
+				zap.String("chain", chain),
+				zap.Int64("count", archive.RecordCount),
+				zap.String("file", archive.FilePath))
+		}
+	}
+	return errors.Join(errs...)
+}
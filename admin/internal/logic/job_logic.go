@@ -3,11 +3,13 @@ package logic
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"yqhp/admin/internal/ctxutil"
 	"yqhp/admin/internal/model"
 	"yqhp/admin/internal/svc"
 	"yqhp/admin/internal/types"
+	"yqhp/common/scheduler"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -36,6 +38,12 @@ func (l *JobLogic) CreateJob(req *types.CreateJobRequest) (*types.JobInfo, error
 	if count > 0 {
 		return nil, errors.New("处理器名称已存在")
 	}
+	if err := validateJobSchedule(req.CronExpression, req.DependsOn); err != nil {
+		return nil, err
+	}
+	if err := validateDependencies(l.db(), 0, req.DependsOn); err != nil {
+		return nil, err
+	}
 
 	userID := ctxutil.GetUserID(l.ctx)
 	job := &model.SysJob{
@@ -50,6 +58,9 @@ func (l *JobLogic) CreateJob(req *types.CreateJobRequest) (*types.JobInfo, error
 		Concurrent:     model.Int32Ptr(req.Concurrent),
 		RetryCount:     model.Int32Ptr(req.RetryCount),
 		RetryInterval:  model.Int32Ptr(req.RetryInterval),
+		RetryBackoff:   model.Float64Ptr(req.RetryBackoff),
+		Timeout:        model.Int32Ptr(req.Timeout),
+		DependsOn:      model.StringPtr(types.FormatJobIDs(req.DependsOn)),
 		Remark:         model.StringPtr(req.Remark),
 		IsDelete:       model.BoolPtr(false),
 		CreatedBy:      model.Int64Ptr(userID),
@@ -60,13 +71,30 @@ func (l *JobLogic) CreateJob(req *types.CreateJobRequest) (*types.JobInfo, error
 		return nil, err
 	}
 
+	if model.GetInt32(job.Status) == 1 {
+		if err := scheduleJob(job); err != nil {
+			return nil, err
+		}
+	}
+
 	return types.ToJobInfo(job), nil
 }
 
 // UpdateJob 更新定时任务
 func (l *JobLogic) UpdateJob(req *types.UpdateJobRequest) error {
+	var old model.SysJob
+	if err := l.db().Where("id = ? AND is_delete = ?", req.ID, false).First(&old).Error; err != nil {
+		return err
+	}
+	if err := validateJobSchedule(req.CronExpression, req.DependsOn); err != nil {
+		return err
+	}
+	if err := validateDependencies(l.db(), req.ID, req.DependsOn); err != nil {
+		return err
+	}
+
 	userID := ctxutil.GetUserID(l.ctx)
-	return l.db().Model(&model.SysJob{}).Where("id = ?", req.ID).Updates(map[string]any{
+	err := l.db().Model(&model.SysJob{}).Where("id = ?", req.ID).Updates(map[string]any{
 		"name":            req.Name,
 		"job_group":       req.JobGroup,
 		"handler_name":    req.HandlerName,
@@ -76,14 +104,42 @@ func (l *JobLogic) UpdateJob(req *types.UpdateJobRequest) error {
 		"concurrent":      req.Concurrent,
 		"retry_count":     req.RetryCount,
 		"retry_interval":  req.RetryInterval,
+		"retry_backoff":   req.RetryBackoff,
+		"timeout":         req.Timeout,
+		"depends_on":      types.FormatJobIDs(req.DependsOn),
 		"remark":          req.Remark,
 		"updated_by":      userID,
 	}).Error
+	if err != nil {
+		return err
+	}
+
+	if old.HandlerName != req.HandlerName {
+		unscheduleJob(old.HandlerName)
+	}
+	return l.syncSchedule(req.ID, false)
 }
 
 // DeleteJob 删除定时任务
 func (l *JobLogic) DeleteJob(id int64) error {
-	return l.db().Model(&model.SysJob{}).Where("id = ?", id).Update("is_delete", true).Error
+	var job model.SysJob
+	if err := l.db().Where("id = ?", id).First(&job).Error; err != nil {
+		return err
+	}
+
+	var dependents int64
+	l.db().Model(&model.SysJob{}).
+		Where("is_delete = ? AND CONCAT(',', depends_on, ',') LIKE ?", false, fmt.Sprintf("%%,%d,%%", id)).
+		Count(&dependents)
+	if dependents > 0 {
+		return errors.New("存在依赖该任务的下游任务，无法删除")
+	}
+
+	if err := l.db().Model(&model.SysJob{}).Where("id = ?", id).Update("is_delete", true).Error; err != nil {
+		return err
+	}
+	unscheduleJob(job.HandlerName)
+	return nil
 }
 
 // GetJob 获取定时任务详情
@@ -98,10 +154,45 @@ func (l *JobLogic) GetJob(id int64) (*types.JobInfo, error) {
 // ChangeJobStatus 变更任务状态
 func (l *JobLogic) ChangeJobStatus(id int64, status int32) error {
 	userID := ctxutil.GetUserID(l.ctx)
-	return l.db().Model(&model.SysJob{}).Where("id = ?", id).Updates(map[string]any{
+	err := l.db().Model(&model.SysJob{}).Where("id = ?", id).Updates(map[string]any{
 		"status":     status,
 		"updated_by": userID,
 	}).Error
+	if err != nil {
+		return err
+	}
+	return l.syncSchedule(id, true)
+}
+
+// syncSchedule 按任务最新状态同步调度器；resumed 为 true 时处理暂停期间错过的执行
+func (l *JobLogic) syncSchedule(id int64, resumed bool) error {
+	var job model.SysJob
+	if err := l.db().Where("id = ?", id).First(&job).Error; err != nil {
+		return err
+	}
+
+	if model.GetBool(job.IsDelete) || model.GetInt32(job.Status) != 1 {
+		unscheduleJob(job.HandlerName)
+		return nil
+	}
+	if err := scheduleJob(&job); err != nil {
+		return err
+	}
+	if resumed {
+		applyMisfire(&job)
+	}
+	return nil
+}
+
+// validateJobSchedule 校验调度方式：cron 与依赖至少配置一项
+func validateJobSchedule(cronExpr string, dependsOn []int64) error {
+	if cronExpr == "" {
+		if len(dependsOn) == 0 {
+			return errors.New("Cron表达式和依赖任务至少配置一项")
+		}
+		return nil
+	}
+	return scheduler.ValidateCron(cronExpr)
 }
 
 // ListJobs 获取定时任务列表
@@ -145,6 +236,9 @@ func (l *JobLogic) ListJobLogs(req *types.ListJobLogsRequest) ([]*types.JobLogIn
 	if req.Status != nil {
 		q = q.Where("status = ?", *req.Status)
 	}
+	if req.TriggerType != "" {
+		q = q.Where("trigger_type = ?", req.TriggerType)
+	}
 
 	var total int64
 	q.Count(&total)
@@ -177,3 +271,8 @@ func (l *JobLogic) CleanJobLogs(jobID int64) error {
 	}
 	return q.Delete(&model.SysJobLog{}).Error
 }
+
+// ListHandlers 获取已注册的任务处理器
+func (l *JobLogic) ListHandlers() []string {
+	return ListJobHandlers()
+}
@@ -0,0 +1,366 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"yqhp/admin/internal/model"
+	"yqhp/admin/internal/svc"
+	"yqhp/admin/internal/types"
+	"yqhp/common/logger"
+	"yqhp/common/scheduler"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 错过策略
+const (
+	MisfireSkip    int32 = 0 // 跳过错过的执行
+	MisfireFireNow int32 = 1 // 立即补偿执行一次
+	MisfireCatchUp int32 = 2 // 按错过次数逐次补偿
+)
+
+const (
+	maxCatchUpRuns   = 100       // 逐次补偿的最大次数
+	maxRetryInterval = time.Hour // 退避后的最大重试间隔
+)
+
+// JobHandler 定时任务处理器，params 为任务配置的参数(JSON)
+type JobHandler func(ctx context.Context, params string) error
+
+var (
+	jobHandlersMu sync.RWMutex
+	jobHandlers   = make(map[string]JobHandler)
+)
+
+// RegisterJobHandler 注册任务处理器，sys_job.handler_name 与 name 对应
+func RegisterJobHandler(name string, handler JobHandler) {
+	jobHandlersMu.Lock()
+	defer jobHandlersMu.Unlock()
+	jobHandlers[name] = handler
+}
+
+// ListJobHandlers 获取已注册的处理器名称
+func ListJobHandlers() []string {
+	jobHandlersMu.RLock()
+	defer jobHandlersMu.RUnlock()
+
+	names := make([]string, 0, len(jobHandlers))
+	for name := range jobHandlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getJobHandler(name string) (JobHandler, bool) {
+	jobHandlersMu.RLock()
+	defer jobHandlersMu.RUnlock()
+	h, ok := jobHandlers[name]
+	return h, ok
+}
+
+func jobDB() *gorm.DB {
+	return svc.Ctx.DB
+}
+
+// LoadJobs 启动时加载所有启用的任务并处理错过的执行
+func LoadJobs() error {
+	var jobs []*model.SysJob
+	if err := jobDB().Where("is_delete = ? AND status = ?", false, 1).Find(&jobs).Error; err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if err := scheduleJob(job); err != nil {
+			logger.Warn("加载定时任务失败", zap.String("handler", job.HandlerName), zap.Error(err))
+			continue
+		}
+		applyMisfire(job)
+	}
+	return nil
+}
+
+// jobOptions 将任务配置转换为调度器执行选项
+func jobOptions(job *model.SysJob) []scheduler.JobOption {
+	opts := []scheduler.JobOption{
+		scheduler.WithConcurrency(scheduler.ConcurrencyPolicy(model.GetInt32(job.Concurrent))),
+		scheduler.WithParams(model.GetString(job.Params)),
+	}
+	if timeout := model.GetInt32(job.Timeout); timeout > 0 {
+		opts = append(opts, scheduler.WithTimeout(time.Duration(timeout)*time.Second))
+	}
+	if retries := model.GetInt32(job.RetryCount); retries > 0 {
+		opts = append(opts, scheduler.WithRetry(scheduler.RetryPolicy{
+			MaxRetries:  int(retries),
+			Interval:    time.Duration(model.GetInt32(job.RetryInterval)) * time.Second,
+			Multiplier:  model.GetFloat64(job.RetryBackoff),
+			MaxInterval: maxRetryInterval,
+		}))
+	}
+	return opts
+}
+
+// jobFunc 任务执行入口，每次执行时读取最新参数
+func jobFunc(handlerName string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		handler, ok := getJobHandler(handlerName)
+		if !ok {
+			return fmt.Errorf("处理器 %s 未注册", handlerName)
+		}
+
+		var job model.SysJob
+		if err := jobDB().WithContext(ctx).Where("handler_name = ? AND is_delete = ?", handlerName, false).First(&job).Error; err != nil {
+			return fmt.Errorf("读取任务配置失败: %w", err)
+		}
+		return handler(ctx, model.GetString(job.Params))
+	}
+}
+
+// scheduleJob 将任务注册到调度器，已存在时更新 cron 与执行选项
+func scheduleJob(job *model.SysJob) error {
+	sched := GetScheduler()
+	if sched == nil {
+		return nil
+	}
+	if _, ok := getJobHandler(job.HandlerName); !ok {
+		return fmt.Errorf("处理器 %s 未注册", job.HandlerName)
+	}
+
+	if sched.HasJob(job.HandlerName) {
+		if err := sched.UpdateCron(job.HandlerName, job.CronExpression); err != nil {
+			return err
+		}
+		return sched.UpdateOptions(job.HandlerName, jobOptions(job)...)
+	}
+	return sched.AddDynamic(job.HandlerName, job.CronExpression, jobFunc(job.HandlerName), jobOptions(job)...)
+}
+
+// unscheduleJob 从调度器移除任务
+func unscheduleJob(handlerName string) {
+	if sched := GetScheduler(); sched != nil && sched.HasJob(handlerName) {
+		if err := sched.Remove(handlerName); err != nil {
+			logger.Warn("移除定时任务失败", zap.String("handler", handlerName), zap.Error(err))
+		}
+	}
+}
+
+// applyMisfire 按错过策略处理停机或暂停期间错过的执行
+// 通过 last_fire_at 条件更新抢占，多实例部署时只有一个实例执行补偿
+func applyMisfire(job *model.SysJob) {
+	sched := GetScheduler()
+	if sched == nil || job.CronExpression == "" {
+		return
+	}
+
+	now := time.Now()
+	q := jobDB().Model(&model.SysJob{}).Where("id = ?", job.ID)
+	if job.LastFireAt == nil {
+		// 首次调度，以当前时间为基准
+		q.Where("last_fire_at IS NULL").Update("last_fire_at", now)
+		return
+	}
+
+	missed, err := scheduler.MissedRuns(job.CronExpression, *job.LastFireAt, now, maxCatchUpRuns)
+	if err != nil || len(missed) == 0 {
+		return
+	}
+	if q.Where("last_fire_at = ?", *job.LastFireAt).Update("last_fire_at", now).RowsAffected == 0 {
+		return
+	}
+
+	policy := model.GetInt32(job.MisfirePolicy)
+	logger.Info("检测到错过的定时执行",
+		zap.String("handler", job.HandlerName),
+		zap.Int("missed", len(missed)),
+		zap.Int32("policy", policy))
+
+	switch runs := misfireRuns(policy, len(missed)); {
+	case runs == 1:
+		sched.Trigger(job.HandlerName, scheduler.TriggerMisfire)
+	case runs > 1:
+		go func() {
+			for range runs {
+				if _, err := sched.Run(job.HandlerName, scheduler.TriggerMisfire); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// misfireRuns 按错过策略计算需要补偿执行的次数
+func misfireRuns(policy int32, missed int) int {
+	if missed <= 0 {
+		return 0
+	}
+	switch policy {
+	case MisfireFireNow:
+		return 1
+	case MisfireCatchUp:
+		return min(missed, maxCatchUpRuns)
+	}
+	return 0
+}
+
+// RecordJobLog 调度器日志回调：每次尝试写入一条任务日志
+func RecordJobLog(entry *scheduler.JobLogEntry) {
+	var job model.SysJob
+	if err := jobDB().Where("handler_name = ? AND is_delete = ?", entry.JobName, false).First(&job).Error; err != nil {
+		// 静态注册的系统任务没有对应的 sys_job 记录
+		return
+	}
+
+	startTime, endTime := entry.StartTime, entry.EndTime
+	log := &model.SysJobLog{
+		JobID:        job.ID,
+		JobName:      job.Name,
+		HandlerName:  model.StringPtr(entry.JobName),
+		Params:       model.StringPtr(entry.Params),
+		Status:       model.Int32Ptr(int32(entry.Status)),
+		ErrorMessage: model.StringPtr(entry.Error),
+		TriggerType:  model.StringPtr(entry.TriggerType),
+		Attempt:      model.Int32Ptr(int32(entry.Attempt)),
+		MaxAttempts:  model.Int32Ptr(int32(entry.MaxAttempts)),
+		StartTime:    &startTime,
+		EndTime:      &endTime,
+		Duration:     model.Int64Ptr(entry.Duration),
+	}
+	if err := jobDB().Create(log).Error; err != nil {
+		logger.Error("写入任务日志失败", zap.String("handler", entry.JobName), zap.Error(err))
+	}
+}
+
+// OnJobComplete 调度器完成回调：记录最近执行结果，成功时触发下游依赖任务
+func OnJobComplete(result *scheduler.RunResult) {
+	var job model.SysJob
+	if err := jobDB().Where("handler_name = ? AND is_delete = ?", result.JobName, false).First(&job).Error; err != nil {
+		return
+	}
+
+	updates := map[string]any{
+		"last_run_status": result.Status,
+		"last_run_at":     result.EndTime,
+	}
+	if result.TriggerType == scheduler.TriggerCron {
+		updates["last_fire_at"] = result.StartTime
+	}
+	if err := jobDB().Model(&model.SysJob{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+		logger.Error("更新任务执行状态失败", zap.String("handler", result.JobName), zap.Error(err))
+		return
+	}
+
+	if result.Status == scheduler.StatusSuccess {
+		triggerDependents(job.ID)
+	}
+}
+
+// triggerDependents 触发依赖 upstreamID 的下游任务
+// 下游的所有上游在其上次依赖触发之后都已成功执行时才会触发，抢占 dep_triggered_at 保证只触发一次
+func triggerDependents(upstreamID int64) {
+	var candidates []*model.SysJob
+	if err := jobDB().Where("is_delete = ? AND status = ? AND depends_on <> ''", false, 1).Find(&candidates).Error; err != nil {
+		return
+	}
+
+	for _, d := range candidates {
+		deps := types.ParseJobIDs(model.GetString(d.DependsOn))
+		if !containsID(deps, upstreamID) {
+			continue
+		}
+
+		var upstreams []*model.SysJob
+		if err := jobDB().Where("id IN ? AND is_delete = ?", deps, false).Find(&upstreams).Error; err != nil || len(upstreams) != len(deps) {
+			continue
+		}
+		ready := true
+		for _, u := range upstreams {
+			if model.GetInt32(u.LastRunStatus) != scheduler.StatusSuccess || u.LastRunAt == nil ||
+				(d.DepTriggeredAt != nil && !u.LastRunAt.After(*d.DepTriggeredAt)) {
+				ready = false
+				break
+			}
+		}
+		if !ready {
+			continue
+		}
+
+		q := jobDB().Model(&model.SysJob{}).Where("id = ?", d.ID)
+		if d.DepTriggeredAt == nil {
+			q = q.Where("dep_triggered_at IS NULL")
+		} else {
+			q = q.Where("dep_triggered_at = ?", *d.DepTriggeredAt)
+		}
+		if q.Update("dep_triggered_at", time.Now()).RowsAffected == 0 {
+			continue
+		}
+
+		if sched := GetScheduler(); sched != nil {
+			if err := sched.Trigger(d.HandlerName, scheduler.TriggerDependency); err != nil {
+				logger.Warn("触发下游任务失败", zap.String("handler", d.HandlerName), zap.Error(err))
+			}
+		}
+	}
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDependencies 校验依赖任务存在且不构成环
+func validateDependencies(db *gorm.DB, jobID int64, deps []int64) error {
+	if len(deps) == 0 {
+		return nil
+	}
+	if containsID(deps, jobID) {
+		return errors.New("任务不能依赖自身")
+	}
+
+	var jobs []*model.SysJob
+	if err := db.Where("is_delete = ?", false).Find(&jobs).Error; err != nil {
+		return err
+	}
+
+	graph := make(map[int64][]int64, len(jobs)+1)
+	for _, j := range jobs {
+		graph[j.ID] = types.ParseJobIDs(model.GetString(j.DependsOn))
+	}
+	for _, dep := range deps {
+		if _, ok := graph[dep]; !ok {
+			return fmt.Errorf("依赖的任务 %d 不存在", dep)
+		}
+	}
+	graph[jobID] = deps
+
+	// 从 jobID 出发沿依赖边遍历，能回到自身即存在环
+	visited := make(map[int64]bool)
+	var visit func(id int64) bool
+	visit = func(id int64) bool {
+		for _, next := range graph[id] {
+			if next == jobID {
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if visit(jobID) {
+		return errors.New("任务依赖存在循环")
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package logic
+
+import "testing"
+
+func TestMisfireRuns(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy int32
+		missed int
+		want   int
+	}{
+		{name: "跳过", policy: MisfireSkip, missed: 5, want: 0},
+		{name: "立即执行一次", policy: MisfireFireNow, missed: 5, want: 1},
+		{name: "逐次补偿", policy: MisfireCatchUp, missed: 5, want: 5},
+		{name: "逐次补偿单次", policy: MisfireCatchUp, missed: 1, want: 1},
+		{name: "逐次补偿上限", policy: MisfireCatchUp, missed: maxCatchUpRuns + 10, want: maxCatchUpRuns},
+		{name: "无错过", policy: MisfireFireNow, missed: 0, want: 0},
+		{name: "未知策略按跳过处理", policy: 9, missed: 3, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := misfireRuns(tt.policy, tt.missed); got != tt.want {
+				t.Fatalf("misfireRuns(%d, %d) = %d, 期望 %d", tt.policy, tt.missed, got, tt.want)
+			}
+		})
+	}
+}
@@ -148,17 +148,3 @@ func (l *TokenLogic) ListOperationLogs(req *types.ListOperationLogsRequest) ([]*
 	}
 	return types.ToOperationLogInfoList(logs), total, nil
 }
-
-// ClearLoginLogs 清空登录日志
-func (l *TokenLogic) ClearLoginLogs() error {
-	log := l.db().SysLoginLog
-	_, err := log.WithContext(l.ctx).Where(log.ID.Gt(0)).Delete()
-	return err
-}
-
-// ClearOperationLogs 清空操作日志
-func (l *TokenLogic) ClearOperationLogs() error {
-	log := l.db().SysOperationLog
-	_, err := log.WithContext(l.ctx).Where(log.ID.Gt(0)).Delete()
-	return err
-}
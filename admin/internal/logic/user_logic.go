@@ -5,15 +5,18 @@ import (
 	"errors"
 	"time"
 
+	"yqhp/admin/internal/audit"
 	"yqhp/admin/internal/auth"
 	"yqhp/admin/internal/ctxutil"
 	"yqhp/admin/internal/model"
 	"yqhp/admin/internal/query"
 	"yqhp/admin/internal/svc"
 	"yqhp/admin/internal/types"
+	"yqhp/common/logger"
 	"yqhp/common/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
 )
 
 // UserLogic 用户逻辑
@@ -192,7 +195,9 @@ func (l *UserLogic) recordLoginLog(userID int64, username string, ip string, sta
 		LoginType: model.StringPtr(loginType),
 		IsDelete:  model.BoolPtr(false),
 	}
-	l.db().SysLoginLog.WithContext(l.ctx).Create(log)
+	if err := audit.Append(l.ctx, svc.Ctx.DB, log); err != nil {
+		logger.Error("写入登录日志失败", zap.Error(err))
+	}
 }
 
 // updateUserApp 更新或创建用户-应用关联
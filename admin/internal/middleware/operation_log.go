@@ -2,13 +2,17 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"time"
 
+	"yqhp/admin/internal/audit"
 	"yqhp/admin/internal/model"
+	"yqhp/common/logger"
 
 	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -64,10 +68,8 @@ func OperationLogMiddleware(db *gorm.DB, module, action string) fiber.Handler {
 			IsDelete:  model.BoolPtr(false),
 		}
 
-		// 异步保存日志
-		go func() {
-			db.Create(log)
-		}()
+		// 异步写入审计哈希链
+		go appendAuditLog(db, log)
 
 		return err
 	}
@@ -123,7 +125,12 @@ func LogOperation(db *gorm.DB, c *fiber.Ctx, module, action string, params any,
 		IsDelete:  model.BoolPtr(false),
 	}
 
-	go func() {
-		db.Create(log)
-	}()
+	go appendAuditLog(db, log)
+}
+
+// appendAuditLog 追加操作日志到审计哈希链
+func appendAuditLog(db *gorm.DB, log *model.SysOperationLog) {
+	if err := audit.Append(context.Background(), db, log); err != nil {
+		logger.Error("写入操作日志失败", zap.Error(err))
+	}
 }
@@ -20,6 +20,8 @@ type (
 	TableView     = SysTableView
 	Job           = SysJob
 	JobLog        = SysJobLog
+	AuditChain    = SysAuditChain
+	AuditArchive  = SysAuditArchive
 )
 
 // 内置应用编码常量
@@ -71,6 +73,19 @@ func GetInt64(i *int64) int64 {
 	return *i
 }
 
+// 辅助函数：创建 float64 指针
+func Float64Ptr(f float64) *float64 {
+	return &f
+}
+
+// 辅助函数：安全获取 float64 值
+func GetFloat64(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
 // 辅助函数：安全获取 string 值
 func GetString(s *string) string {
 	if s == nil {
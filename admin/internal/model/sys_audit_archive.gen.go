@@ -0,0 +1,33 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameSysAuditArchive = "sys_audit_archive"
+
+// SysAuditArchive mapped from table <sys_audit_archive>
+type SysAuditArchive struct {
+	ID          int64      `gorm:"column:id;type:bigint unsigned;primaryKey;autoIncrement:true" json:"id"`
+	CreatedAt   *time.Time `gorm:"column:created_at;type:datetime" json:"created_at"`
+	Chain       string     `gorm:"column:chain;type:varchar(50);not null;index:idx_sys_audit_archive_chain,priority:1;comment:哈希链名称" json:"chain"`
+	FromSeq     int64      `gorm:"column:from_seq;type:bigint unsigned;not null;comment:起始序号" json:"from_seq"`
+	ToSeq       int64      `gorm:"column:to_seq;type:bigint unsigned;not null;comment:结束序号" json:"to_seq"`
+	RecordCount int64      `gorm:"column:record_count;type:bigint;not null;comment:记录数" json:"record_count"`
+	PrevHash    string     `gorm:"column:prev_hash;type:char(64);not null;comment:归档首条记录的上一条哈希" json:"prev_hash"`
+	LastHash    string     `gorm:"column:last_hash;type:char(64);not null;comment:归档末条记录哈希" json:"last_hash"`
+	FilePath    string     `gorm:"column:file_path;type:varchar(500);not null;comment:归档文件路径(jsonl.gz)" json:"file_path"`
+	FileSha256  string     `gorm:"column:file_sha256;type:char(64);not null;comment:归档文件SHA256" json:"file_sha256"`
+	FileSize    int64      `gorm:"column:file_size;type:bigint;comment:归档文件大小(字节)" json:"file_size"`
+	Before      *time.Time `gorm:"column:before;type:datetime;comment:保留策略截止时间" json:"before"`
+	CreatedBy   *int64     `gorm:"column:created_by;type:bigint unsigned;comment:执行人ID(0为定时任务)" json:"created_by"`
+}
+
+// TableName SysAuditArchive's table name
+func (*SysAuditArchive) TableName() string {
+	return TableNameSysAuditArchive
+}
@@ -26,10 +26,17 @@ type SysJob struct {
 	Status         *int32     `gorm:"column:status;type:tinyint;not null;index:idx_sys_job_status,priority:1;comment:状态: 0-暂停 1-运行中" json:"status"`
 	Source         *string    `gorm:"column:source;type:varchar(50);comment:来源: system-系统任务 agent-Agent创建" json:"source"`
 	SourceID       *int64     `gorm:"column:source_id;type:bigint unsigned;comment:来源ID" json:"source_id"`
-	MisfirePolicy  *int32     `gorm:"column:misfire_policy;type:tinyint;comment:错过策略: 0-忽略 1-立即执行" json:"misfire_policy"`
-	Concurrent     *int32     `gorm:"column:concurrent;type:tinyint;comment:是否允许并发: 0-禁止 1-允许" json:"concurrent"`
+	MisfirePolicy  *int32     `gorm:"column:misfire_policy;type:tinyint;comment:错过策略: 0-跳过 1-立即执行一次 2-逐次补偿" json:"misfire_policy"`
+	Concurrent     *int32     `gorm:"column:concurrent;type:tinyint;comment:并发策略: 0-禁止 1-允许 2-替换" json:"concurrent"`
 	RetryCount     *int32     `gorm:"column:retry_count;type:int;comment:失败重试次数" json:"retry_count"`
 	RetryInterval  *int32     `gorm:"column:retry_interval;type:int;comment:重试间隔(秒)" json:"retry_interval"`
+	RetryBackoff   *float64   `gorm:"column:retry_backoff;type:decimal(5,2);comment:重试退避倍数(<=1为固定间隔)" json:"retry_backoff"`
+	Timeout        *int32     `gorm:"column:timeout;type:int;comment:执行超时(秒), 0为不限" json:"timeout"`
+	DependsOn      *string    `gorm:"column:depends_on;type:varchar(500);comment:依赖的上游任务ID(逗号分隔), 上游全部成功后触发" json:"depends_on"`
+	LastFireAt     *time.Time `gorm:"column:last_fire_at;type:datetime(3);comment:最近一次定时触发时间(错过策略依据)" json:"last_fire_at"`
+	LastRunStatus  *int32     `gorm:"column:last_run_status;type:tinyint;comment:最近一次执行结果: 0-失败 1-成功" json:"last_run_status"`
+	LastRunAt      *time.Time `gorm:"column:last_run_at;type:datetime(3);comment:最近一次执行结束时间" json:"last_run_at"`
+	DepTriggeredAt *time.Time `gorm:"column:dep_triggered_at;type:datetime(3);comment:最近一次依赖触发时间" json:"dep_triggered_at"`
 	Remark         *string    `gorm:"column:remark;type:varchar(500);comment:备注" json:"remark"`
 }
 
@@ -18,8 +18,11 @@ type SysJobLog struct {
 	JobName      string     `gorm:"column:job_name;type:varchar(100);not null;comment:任务名称" json:"job_name"`
 	HandlerName  *string    `gorm:"column:handler_name;type:varchar(200);comment:处理器名称" json:"handler_name"`
 	Params       *string    `gorm:"column:params;type:text;comment:执行参数" json:"params"`
-	Status       *int32     `gorm:"column:status;type:tinyint;not null;index:idx_sys_job_log_status,priority:1;comment:执行状态: 0-失败 1-成功" json:"status"`
+	Status       *int32     `gorm:"column:status;type:tinyint;not null;index:idx_sys_job_log_status,priority:1;comment:执行状态: 0-失败 1-成功 2-跳过" json:"status"`
 	ErrorMessage *string    `gorm:"column:error_message;type:text;comment:错误信息" json:"error_message"`
+	TriggerType  *string    `gorm:"column:trigger_type;type:varchar(20);comment:触发类型: cron manual dependency misfire" json:"trigger_type"`
+	Attempt      *int32     `gorm:"column:attempt;type:int;comment:第几次尝试(从1开始)" json:"attempt"`
+	MaxAttempts  *int32     `gorm:"column:max_attempts;type:int;comment:最大尝试次数" json:"max_attempts"`
 	StartTime    *time.Time `gorm:"column:start_time;type:datetime(3);index:idx_sys_job_log_start_time,priority:1;comment:开始时间" json:"start_time"`
 	EndTime      *time.Time `gorm:"column:end_time;type:datetime(3);comment:结束时间" json:"end_time"`
 	Duration     *int64     `gorm:"column:duration;type:bigint;comment:耗时(毫秒)" json:"duration"`
@@ -25,6 +25,9 @@ type SysLoginLog struct {
 	Status    *int32     `gorm:"column:status;type:tinyint;default:1" json:"status"`
 	Message   *string    `gorm:"column:message;type:varchar(255)" json:"message"`
 	LoginType *string    `gorm:"column:login_type;type:varchar(50)" json:"login_type"`
+	Seq       *int64     `gorm:"column:seq;type:bigint unsigned;uniqueIndex:uk_sys_login_log_seq,priority:1;comment:哈希链序号" json:"seq"`
+	PrevHash  *string    `gorm:"column:prev_hash;type:char(64);comment:上一条记录哈希" json:"prev_hash"`
+	Hash      *string    `gorm:"column:hash;type:char(64);comment:本条记录哈希" json:"hash"`
 }
 
 // TableName SysLoginLog's table name
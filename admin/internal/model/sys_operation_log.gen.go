@@ -29,6 +29,9 @@ type SysOperationLog struct {
 	Status    *int32     `gorm:"column:status;type:tinyint;default:1" json:"status"`
 	Duration  *int64     `gorm:"column:duration;type:bigint" json:"duration"`
 	ErrorMsg  *string    `gorm:"column:error_msg;type:varchar(500)" json:"error_msg"`
+	Seq       *int64     `gorm:"column:seq;type:bigint unsigned;uniqueIndex:uk_sys_operation_log_seq,priority:1;comment:哈希链序号" json:"seq"`
+	PrevHash  *string    `gorm:"column:prev_hash;type:char(64);comment:上一条记录哈希" json:"prev_hash"`
+	Hash      *string    `gorm:"column:hash;type:char(64);comment:本条记录哈希" json:"hash"`
 }
 
 // TableName SysOperationLog's table name
@@ -40,6 +40,9 @@ func newSysLoginLog(db *gorm.DB, opts ...gen.DOOption) sysLoginLog {
 	_sysLoginLog.Status = field.NewInt32(tableName, "status")
 	_sysLoginLog.Message = field.NewString(tableName, "message")
 	_sysLoginLog.LoginType = field.NewString(tableName, "login_type")
+	_sysLoginLog.Seq = field.NewInt64(tableName, "seq")
+	_sysLoginLog.PrevHash = field.NewString(tableName, "prev_hash")
+	_sysLoginLog.Hash = field.NewString(tableName, "hash")
 
 	_sysLoginLog.fillFieldMap()
 
@@ -63,6 +66,9 @@ type sysLoginLog struct {
 	Status    field.Int32
 	Message   field.String
 	LoginType field.String
+	Seq       field.Int64
+	PrevHash  field.String
+	Hash      field.String
 
 	fieldMap map[string]field.Expr
 }
@@ -92,6 +98,9 @@ func (s *sysLoginLog) updateTableName(table string) *sysLoginLog {
 	s.Status = field.NewInt32(table, "status")
 	s.Message = field.NewString(table, "message")
 	s.LoginType = field.NewString(table, "login_type")
+	s.Seq = field.NewInt64(table, "seq")
+	s.PrevHash = field.NewString(table, "prev_hash")
+	s.Hash = field.NewString(table, "hash")
 
 	s.fillFieldMap()
 
@@ -118,7 +127,7 @@ func (s *sysLoginLog) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (s *sysLoginLog) fillFieldMap() {
-	s.fieldMap = make(map[string]field.Expr, 13)
+	s.fieldMap = make(map[string]field.Expr, 16)
 	s.fieldMap["id"] = s.ID
 	s.fieldMap["created_at"] = s.CreatedAt
 	s.fieldMap["updated_at"] = s.UpdatedAt
@@ -132,6 +141,9 @@ func (s *sysLoginLog) fillFieldMap() {
 	s.fieldMap["status"] = s.Status
 	s.fieldMap["message"] = s.Message
 	s.fieldMap["login_type"] = s.LoginType
+	s.fieldMap["seq"] = s.Seq
+	s.fieldMap["prev_hash"] = s.PrevHash
+	s.fieldMap["hash"] = s.Hash
 }
 
 func (s sysLoginLog) clone(db *gorm.DB) sysLoginLog {
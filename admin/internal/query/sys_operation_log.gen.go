@@ -44,6 +44,9 @@ func newSysOperationLog(db *gorm.DB, opts ...gen.DOOption) sysOperationLog {
 	_sysOperationLog.Status = field.NewInt32(tableName, "status")
 	_sysOperationLog.Duration = field.NewInt64(tableName, "duration")
 	_sysOperationLog.ErrorMsg = field.NewString(tableName, "error_msg")
+	_sysOperationLog.Seq = field.NewInt64(tableName, "seq")
+	_sysOperationLog.PrevHash = field.NewString(tableName, "prev_hash")
+	_sysOperationLog.Hash = field.NewString(tableName, "hash")
 
 	_sysOperationLog.fillFieldMap()
 
@@ -71,6 +74,9 @@ type sysOperationLog struct {
 	Status    field.Int32
 	Duration  field.Int64
 	ErrorMsg  field.String
+	Seq       field.Int64
+	PrevHash  field.String
+	Hash      field.String
 
 	fieldMap map[string]field.Expr
 }
@@ -104,6 +110,9 @@ func (s *sysOperationLog) updateTableName(table string) *sysOperationLog {
 	s.Status = field.NewInt32(table, "status")
 	s.Duration = field.NewInt64(table, "duration")
 	s.ErrorMsg = field.NewString(table, "error_msg")
+	s.Seq = field.NewInt64(table, "seq")
+	s.PrevHash = field.NewString(table, "prev_hash")
+	s.Hash = field.NewString(table, "hash")
 
 	s.fillFieldMap()
 
@@ -132,7 +141,7 @@ func (s *sysOperationLog) GetFieldByName(fieldName string) (field.OrderExpr, boo
 }
 
 func (s *sysOperationLog) fillFieldMap() {
-	s.fieldMap = make(map[string]field.Expr, 17)
+	s.fieldMap = make(map[string]field.Expr, 20)
 	s.fieldMap["id"] = s.ID
 	s.fieldMap["created_at"] = s.CreatedAt
 	s.fieldMap["updated_at"] = s.UpdatedAt
@@ -150,6 +159,9 @@ func (s *sysOperationLog) fillFieldMap() {
 	s.fieldMap["status"] = s.Status
 	s.fieldMap["duration"] = s.Duration
 	s.fieldMap["error_msg"] = s.ErrorMsg
+	s.fieldMap["seq"] = s.Seq
+	s.fieldMap["prev_hash"] = s.PrevHash
+	s.fieldMap["hash"] = s.Hash
 }
 
 func (s sysOperationLog) clone(db *gorm.DB) sysOperationLog {
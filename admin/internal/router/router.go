@@ -144,8 +144,11 @@ func Setup(app *fiber.App, db *gorm.DB) {
 	logs := sys.Group("/logs")
 	logs.Post("/login", handler.TokenGetLoginLogs)
 	logs.Post("/operation", handler.TokenGetOperationLogs)
-	logs.Delete("/login", perm("system:log:delete"), handler.TokenClearLoginLogs)
-	logs.Delete("/operation", perm("system:log:delete"), handler.TokenClearOperationLogs)
+	logs.Post("/archives/list", handler.AuditArchiveList)
+	logs.Post("/archives/:id/verify", perm("system:log:verify"), handler.AuditArchiveVerify)
+	logs.Post("/:chain/export", perm("system:log:export"), handler.AuditExport)
+	logs.Get("/:chain/verify", perm("system:log:verify"), handler.AuditVerify)
+	logs.Post("/:chain/archive", perm("system:log:archive"), handler.AuditArchive)
 
 	// 用户-应用关联
 	userApps := sys.Group("/user-apps")
@@ -164,6 +167,7 @@ func Setup(app *fiber.App, db *gorm.DB) {
 	// 定时任务管理
 	job := sys.Group("/jobs")
 	job.Post("/list", perm("system:job:list"), handler.JobList)
+	job.Get("/handlers", perm("system:job:list"), handler.JobHandlers)
 	job.Get("/:id", perm("system:job:list"), handler.JobGet)
 	job.Post("", perm("system:job:add"), handler.JobCreate)
 	job.Put("", perm("system:job:edit"), handler.JobUpdate)
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/copier"
@@ -58,6 +60,17 @@ var copierOption = copier.Option{
 				return int64(0), nil
 			},
 		},
+		// *float64 -> float64
+		{
+			SrcType: (*float64)(nil),
+			DstType: float64(0),
+			Fn: func(src interface{}) (interface{}, error) {
+				if f, ok := src.(*float64); ok && f != nil {
+					return *f, nil
+				}
+				return float64(0), nil
+			},
+		},
 		// *bool -> bool
 		{
 			SrcType: (*bool)(nil),
@@ -263,6 +276,14 @@ func ToOperationLogInfoList(logs []*model.SysOperationLog) []*OperationLogInfo {
 	return CopyList[OperationLogInfo](logs)
 }
 
+func ToAuditArchiveInfo(a *model.SysAuditArchive) *AuditArchiveInfo {
+	return Copy[AuditArchiveInfo](a)
+}
+
+func ToAuditArchiveInfoList(archives []*model.SysAuditArchive) []*AuditArchiveInfo {
+	return CopyList[AuditArchiveInfo](archives)
+}
+
 // ========== OAuth 相关 ==========
 
 func ToOAuthProviderInfo(p *model.SysOauthProvider) *OAuthProviderInfo {
@@ -294,11 +315,39 @@ func ToUserAppInfoList(userApps []*model.SysUserApp) []*UserAppInfo {
 // ========== Job 相关 ==========
 
 func ToJobInfo(j *model.SysJob) *JobInfo {
-	return Copy[JobInfo](j)
+	info := Copy[JobInfo](j)
+	if info != nil {
+		info.DependsOn = ParseJobIDs(model.GetString(j.DependsOn))
+	}
+	return info
 }
 
 func ToJobInfoList(jobs []*model.SysJob) []*JobInfo {
-	return CopyList[JobInfo](jobs)
+	list := make([]*JobInfo, 0, len(jobs))
+	for _, j := range jobs {
+		list = append(list, ToJobInfo(j))
+	}
+	return list
+}
+
+// ParseJobIDs 解析逗号分隔的任务ID
+func ParseJobIDs(s string) []int64 {
+	ids := []int64{}
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64); err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// FormatJobIDs 将任务ID格式化为逗号分隔字符串
+func FormatJobIDs(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ",")
 }
 
 // ========== JobLog 相关 ==========
@@ -2,32 +2,38 @@ package types
 
 // CreateJobRequest 创建定时任务请求
 type CreateJobRequest struct {
-	Name           string `json:"name" validate:"required"`
-	JobGroup       string `json:"jobGroup"`
-	HandlerName    string `json:"handlerName" validate:"required"`
-	CronExpression string `json:"cronExpression" validate:"required"`
-	Params         string `json:"params"`
-	Status         int32  `json:"status"`
-	MisfirePolicy  int32  `json:"misfirePolicy"`
-	Concurrent     int32  `json:"concurrent"`
-	RetryCount     int32  `json:"retryCount"`
-	RetryInterval  int32  `json:"retryInterval"`
-	Remark         string `json:"remark"`
+	Name           string  `json:"name" validate:"required"`
+	JobGroup       string  `json:"jobGroup"`
+	HandlerName    string  `json:"handlerName" validate:"required"`
+	CronExpression string  `json:"cronExpression"` // 为空时只能由依赖或手动触发
+	Params         string  `json:"params"`
+	Status         int32   `json:"status"`
+	MisfirePolicy  int32   `json:"misfirePolicy"` // 0-跳过 1-立即执行一次 2-逐次补偿
+	Concurrent     int32   `json:"concurrent"`    // 0-禁止 1-允许 2-替换
+	RetryCount     int32   `json:"retryCount"`
+	RetryInterval  int32   `json:"retryInterval"` // 秒
+	RetryBackoff   float64 `json:"retryBackoff"`  // 退避倍数，<=1 为固定间隔
+	Timeout        int32   `json:"timeout"`       // 秒，0 为不限
+	DependsOn      []int64 `json:"dependsOn"`     // 上游任务ID，全部成功后触发
+	Remark         string  `json:"remark"`
 }
 
 // UpdateJobRequest 更新定时任务请求
 type UpdateJobRequest struct {
-	ID             int64  `json:"id" validate:"required"`
-	Name           string `json:"name"`
-	JobGroup       string `json:"jobGroup"`
-	HandlerName    string `json:"handlerName"`
-	CronExpression string `json:"cronExpression"`
-	Params         string `json:"params"`
-	MisfirePolicy  int32  `json:"misfirePolicy"`
-	Concurrent     int32  `json:"concurrent"`
-	RetryCount     int32  `json:"retryCount"`
-	RetryInterval  int32  `json:"retryInterval"`
-	Remark         string `json:"remark"`
+	ID             int64   `json:"id" validate:"required"`
+	Name           string  `json:"name"`
+	JobGroup       string  `json:"jobGroup"`
+	HandlerName    string  `json:"handlerName"`
+	CronExpression string  `json:"cronExpression"`
+	Params         string  `json:"params"`
+	MisfirePolicy  int32   `json:"misfirePolicy"`
+	Concurrent     int32   `json:"concurrent"`
+	RetryCount     int32   `json:"retryCount"`
+	RetryInterval  int32   `json:"retryInterval"`
+	RetryBackoff   float64 `json:"retryBackoff"`
+	Timeout        int32   `json:"timeout"`
+	DependsOn      []int64 `json:"dependsOn"`
+	Remark         string  `json:"remark"`
 }
 
 // ChangeJobStatusRequest 变更任务状态请求
@@ -59,6 +65,12 @@ type JobInfo struct {
 	Concurrent     int32     `json:"concurrent"`
 	RetryCount     int32     `json:"retryCount"`
 	RetryInterval  int32     `json:"retryInterval"`
+	RetryBackoff   float64   `json:"retryBackoff"`
+	Timeout        int32     `json:"timeout"`
+	DependsOn      []int64   `json:"dependsOn"`
+	LastFireAt     *DateTime `json:"lastFireAt"`
+	LastRunStatus  *int32    `json:"lastRunStatus"`
+	LastRunAt      *DateTime `json:"lastRunAt"`
 	Remark         string    `json:"remark"`
 	CreatedBy      int64     `json:"createdBy"`
 	UpdatedBy      int64     `json:"updatedBy"`
@@ -68,11 +80,12 @@ type JobInfo struct {
 
 // ListJobLogsRequest 任务日志列表请求
 type ListJobLogsRequest struct {
-	Page     int    `json:"page"`
-	PageSize int    `json:"pageSize"`
-	JobID    int64  `json:"jobId"`
-	JobName  string `json:"jobName"`
-	Status   *int32 `json:"status"`
+	Page        int    `json:"page"`
+	PageSize    int    `json:"pageSize"`
+	JobID       int64  `json:"jobId"`
+	JobName     string `json:"jobName"`
+	Status      *int32 `json:"status"`
+	TriggerType string `json:"triggerType"`
 }
 
 // JobLogInfo 任务日志响应
@@ -84,6 +97,9 @@ type JobLogInfo struct {
 	Params       string    `json:"params"`
 	Status       int32     `json:"status"`
 	ErrorMessage string    `json:"errorMessage"`
+	TriggerType  string    `json:"triggerType"`
+	Attempt      int32     `json:"attempt"`
+	MaxAttempts  int32     `json:"maxAttempts"`
 	StartTime    *DateTime `json:"startTime"`
 	EndTime      *DateTime `json:"endTime"`
 	Duration     int64     `json:"duration"`
@@ -55,6 +55,8 @@ type LoginLogInfo struct {
 	Status    int32     `json:"status"`
 	Message   string    `json:"message"`
 	LoginType string    `json:"loginType"`
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
 	CreatedAt *DateTime `json:"createdAt"`
 }
 
@@ -70,5 +72,46 @@ type OperationLogInfo struct {
 	IP        string    `json:"ip"`
 	Status    int32     `json:"status"`
 	Duration  int64     `json:"duration"`
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
 	CreatedAt *DateTime `json:"createdAt"`
 }
+
+// ExportAuditLogsRequest 审计日志导出请求
+type ExportAuditLogsRequest struct {
+	Format    string `json:"format"` // csv, jsonl
+	Username  string `json:"username"`
+	Module    string `json:"module"` // 仅操作日志
+	Status    *int8  `json:"status"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
+
+// ArchiveAuditLogsRequest 审计日志归档请求
+type ArchiveAuditLogsRequest struct {
+	RetentionDays int `json:"retentionDays"` // 保留天数，小于配置值时使用配置值
+}
+
+// ListAuditArchivesRequest 归档记录列表请求
+type ListAuditArchivesRequest struct {
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+	Chain    string `json:"chain"`
+}
+
+// AuditArchiveInfo 归档记录响应
+type AuditArchiveInfo struct {
+	ID          int64     `json:"id"`
+	Chain       string    `json:"chain"`
+	FromSeq     int64     `json:"fromSeq"`
+	ToSeq       int64     `json:"toSeq"`
+	RecordCount int64     `json:"recordCount"`
+	PrevHash    string    `json:"prevHash"`
+	LastHash    string    `json:"lastHash"`
+	FilePath    string    `json:"filePath"`
+	FileSha256  string    `json:"fileSha256"`
+	FileSize    int64     `json:"fileSize"`
+	Before      *DateTime `json:"before"`
+	CreatedBy   int64     `json:"createdBy"`
+	CreatedAt   *DateTime `json:"createdAt"`
+}
@@ -13,6 +13,8 @@
 -- TRUNCATE TABLE sys_user_token;
 -- TRUNCATE TABLE sys_job;
 -- TRUNCATE TABLE sys_job_log;
+-- TRUNCATE TABLE sys_audit_chain;
+-- TRUNCATE TABLE sys_audit_archive;
 -- TRUNCATE TABLE sys_oauth_provider;
 -- TRUNCATE TABLE sys_config;
 -- TRUNCATE TABLE sys_dict_data;
@@ -88,10 +90,17 @@ CREATE TABLE IF NOT EXISTS `sys_job` (
   `status` tinyint NOT NULL DEFAULT '0' COMMENT '状态: 0-暂停 1-运行中',
   `source` varchar(50) DEFAULT 'system' COMMENT '来源: system-系统任务 agent-Agent创建',
   `source_id` bigint unsigned DEFAULT NULL COMMENT '来源ID(预留: 如agent_id)',
-  `misfire_policy` tinyint DEFAULT '0' COMMENT '错过策略: 0-忽略 1-立即执行',
-  `concurrent` tinyint DEFAULT '0' COMMENT '是否允许并发: 0-禁止 1-允许',
+  `misfire_policy` tinyint DEFAULT '0' COMMENT '错过策略: 0-跳过 1-立即执行一次 2-逐次补偿',
+  `concurrent` tinyint DEFAULT '0' COMMENT '并发策略: 0-禁止 1-允许 2-替换',
   `retry_count` int DEFAULT '0' COMMENT '失败重试次数',
   `retry_interval` int DEFAULT '0' COMMENT '重试间隔(秒)',
+  `retry_backoff` decimal(5,2) DEFAULT '1.00' COMMENT '重试退避倍数(<=1为固定间隔)',
+  `timeout` int DEFAULT '0' COMMENT '执行超时(秒), 0为不限',
+  `depends_on` varchar(500) DEFAULT '' COMMENT '依赖的上游任务ID(逗号分隔), 上游全部成功后触发',
+  `last_fire_at` datetime(3) DEFAULT NULL COMMENT '最近一次定时触发时间(错过策略依据)',
+  `last_run_status` tinyint DEFAULT NULL COMMENT '最近一次执行结果: 0-失败 1-成功',
+  `last_run_at` datetime(3) DEFAULT NULL COMMENT '最近一次执行结束时间',
+  `dep_triggered_at` datetime(3) DEFAULT NULL COMMENT '最近一次依赖触发时间',
   `remark` varchar(500) DEFAULT NULL COMMENT '备注',
   PRIMARY KEY (`id`),
   KEY `idx_sys_job_is_delete` (`is_delete`),
@@ -106,8 +115,11 @@ CREATE TABLE IF NOT EXISTS `sys_job_log` (
   `job_name` varchar(100) NOT NULL COMMENT '任务名称',
   `handler_name` varchar(200) DEFAULT NULL COMMENT '处理器名称',
   `params` text COMMENT '执行参数',
-  `status` tinyint NOT NULL DEFAULT '0' COMMENT '执行状态: 0-失败 1-成功',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '执行状态: 0-失败 1-成功 2-跳过',
   `error_message` text COMMENT '错误信息',
+  `trigger_type` varchar(20) DEFAULT NULL COMMENT '触发类型: cron manual dependency misfire',
+  `attempt` int DEFAULT '1' COMMENT '第几次尝试(从1开始)',
+  `max_attempts` int DEFAULT '1' COMMENT '最大尝试次数',
   `start_time` datetime(3) DEFAULT NULL COMMENT '开始时间',
   `end_time` datetime(3) DEFAULT NULL COMMENT '结束时间',
   `duration` bigint DEFAULT NULL COMMENT '耗时(毫秒)',
@@ -117,6 +129,110 @@ CREATE TABLE IF NOT EXISTS `sys_job_log` (
   KEY `idx_sys_job_log_start_time` (`start_time`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='定时任务执行日志表';
 
+-- =============================================
+-- 0.3 审计日志哈希链（操作日志 / 登录日志）
+-- =============================================
+CREATE TABLE IF NOT EXISTS `sys_audit_chain` (
+  `chain` varchar(50) NOT NULL COMMENT '哈希链名称: operation-操作日志 login-登录日志',
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `last_seq` bigint unsigned NOT NULL DEFAULT '0' COMMENT '最新记录序号',
+  `last_hash` char(64) NOT NULL COMMENT '最新记录哈希',
+  `base_seq` bigint unsigned NOT NULL DEFAULT '0' COMMENT '已归档的最大序号',
+  `base_hash` char(64) NOT NULL COMMENT '已归档的最后一条记录哈希',
+  PRIMARY KEY (`chain`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='审计哈希链头';
+
+CREATE TABLE IF NOT EXISTS `sys_audit_archive` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `chain` varchar(50) NOT NULL COMMENT '哈希链名称',
+  `from_seq` bigint unsigned NOT NULL COMMENT '起始序号',
+  `to_seq` bigint unsigned NOT NULL COMMENT '结束序号',
+  `record_count` bigint NOT NULL DEFAULT '0' COMMENT '记录数',
+  `prev_hash` char(64) NOT NULL COMMENT '归档首条记录的上一条哈希',
+  `last_hash` char(64) NOT NULL COMMENT '归档末条记录哈希',
+  `file_path` varchar(500) NOT NULL COMMENT '归档文件路径(jsonl.gz)',
+  `file_sha256` char(64) NOT NULL COMMENT '归档文件SHA256',
+  `file_size` bigint DEFAULT NULL COMMENT '归档文件大小(字节)',
+  `before` datetime DEFAULT NULL COMMENT '保留策略截止时间',
+  `created_by` bigint unsigned DEFAULT NULL COMMENT '执行人ID(0为定时任务)',
+  PRIMARY KEY (`id`),
+  KEY `idx_sys_audit_archive_chain` (`chain`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='审计日志归档记录';
+
+CREATE TABLE IF NOT EXISTS `sys_operation_log` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `is_delete` tinyint(1) DEFAULT '0',
+  `user_id` bigint unsigned DEFAULT NULL,
+  `username` varchar(50) DEFAULT NULL,
+  `module` varchar(50) DEFAULT NULL,
+  `action` varchar(50) DEFAULT NULL,
+  `method` varchar(10) DEFAULT NULL,
+  `path` varchar(255) DEFAULT NULL,
+  `ip` varchar(50) DEFAULT NULL,
+  `user_agent` varchar(500) DEFAULT NULL,
+  `params` text,
+  `result` text,
+  `status` tinyint DEFAULT '1',
+  `duration` bigint DEFAULT NULL,
+  `error_msg` varchar(500) DEFAULT NULL,
+  `seq` bigint unsigned DEFAULT NULL COMMENT '哈希链序号',
+  `prev_hash` char(64) DEFAULT NULL COMMENT '上一条记录哈希',
+  `hash` char(64) DEFAULT NULL COMMENT '本条记录哈希',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_sys_operation_log_seq` (`seq`),
+  KEY `idx_sys_operation_log_is_delete` (`is_delete`),
+  KEY `idx_sys_operation_log_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='操作日志表';
+
+CREATE TABLE IF NOT EXISTS `sys_login_log` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `is_delete` tinyint(1) DEFAULT '0',
+  `user_id` bigint unsigned DEFAULT NULL,
+  `username` varchar(50) DEFAULT NULL,
+  `ip` varchar(50) DEFAULT NULL,
+  `location` varchar(100) DEFAULT NULL,
+  `browser` varchar(100) DEFAULT NULL,
+  `os` varchar(100) DEFAULT NULL,
+  `status` tinyint DEFAULT '1',
+  `message` varchar(255) DEFAULT NULL,
+  `login_type` varchar(50) DEFAULT NULL,
+  `seq` bigint unsigned DEFAULT NULL COMMENT '哈希链序号',
+  `prev_hash` char(64) DEFAULT NULL COMMENT '上一条记录哈希',
+  `hash` char(64) DEFAULT NULL COMMENT '本条记录哈希',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_sys_login_log_seq` (`seq`),
+  KEY `idx_sys_login_log_is_delete` (`is_delete`),
+  KEY `idx_sys_login_log_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='登录日志表';
+
+-- 已有库升级：日志表增加哈希链字段（已存在的历史记录 seq 为空，不参与校验）
+-- ALTER TABLE `sys_operation_log` ADD COLUMN `seq` bigint unsigned DEFAULT NULL COMMENT '哈希链序号',
+--   ADD COLUMN `prev_hash` char(64) DEFAULT NULL COMMENT '上一条记录哈希',
+--   ADD COLUMN `hash` char(64) DEFAULT NULL COMMENT '本条记录哈希',
+--   ADD UNIQUE KEY `uk_sys_operation_log_seq` (`seq`);
+-- ALTER TABLE `sys_login_log` ADD COLUMN `seq` bigint unsigned DEFAULT NULL COMMENT '哈希链序号',
+--   ADD COLUMN `prev_hash` char(64) DEFAULT NULL COMMENT '上一条记录哈希',
+--   ADD COLUMN `hash` char(64) DEFAULT NULL COMMENT '本条记录哈希',
+--   ADD UNIQUE KEY `uk_sys_login_log_seq` (`seq`);
+
+-- 已有库升级：定时任务依赖、重试、超时与执行记录字段
+-- ALTER TABLE `sys_job` ADD COLUMN `retry_backoff` decimal(5,2) DEFAULT '1.00' COMMENT '重试退避倍数(<=1为固定间隔)' AFTER `retry_interval`,
+--   ADD COLUMN `timeout` int DEFAULT '0' COMMENT '执行超时(秒), 0为不限' AFTER `retry_backoff`,
+--   ADD COLUMN `depends_on` varchar(500) DEFAULT '' COMMENT '依赖的上游任务ID(逗号分隔), 上游全部成功后触发' AFTER `timeout`,
+--   ADD COLUMN `last_fire_at` datetime(3) DEFAULT NULL COMMENT '最近一次定时触发时间(错过策略依据)' AFTER `depends_on`,
+--   ADD COLUMN `last_run_status` tinyint DEFAULT NULL COMMENT '最近一次执行结果: 0-失败 1-成功' AFTER `last_fire_at`,
+--   ADD COLUMN `last_run_at` datetime(3) DEFAULT NULL COMMENT '最近一次执行结束时间' AFTER `last_run_status`,
+--   ADD COLUMN `dep_triggered_at` datetime(3) DEFAULT NULL COMMENT '最近一次依赖触发时间' AFTER `last_run_at`;
+-- ALTER TABLE `sys_job_log` ADD COLUMN `trigger_type` varchar(20) DEFAULT NULL COMMENT '触发类型: cron manual dependency misfire' AFTER `error_message`,
+--   ADD COLUMN `attempt` int DEFAULT '1' COMMENT '第几次尝试(从1开始)' AFTER `trigger_type`,
+--   ADD COLUMN `max_attempts` int DEFAULT '1' COMMENT '最大尝试次数' AFTER `attempt`;
+
 -- 为 sys_oauth_provider 表添加 app_id 字段（如果不存在）
 -- ALTER TABLE `sys_oauth_provider` ADD COLUMN IF NOT EXISTS `app_id` bigint unsigned DEFAULT NULL COMMENT '应用ID，NULL表示全局配置' AFTER `updated_by`;
 -- ALTER TABLE `sys_oauth_provider` ADD INDEX IF NOT EXISTS `idx_sys_oauth_provider_app_id` (`app_id`);
@@ -231,7 +347,9 @@ INSERT INTO sys_resource (id, created_at, updated_at, is_delete, created_by, upd
 -- 日志管理
 INSERT INTO sys_resource (id, created_at, updated_at, is_delete, created_by, updated_by, app_id, parent_id, name, code, type, path, component, redirect, icon, sort, is_hidden, is_cache, is_frame, status, remark) VALUES
 (39, NOW(), NOW(), 0, 1, 1, 1, 1, '日志管理', 'system:log', 2, '/system/log', 'system/log/index', NULL, 'ant-design:file-text-outlined', 9, 0, 1, 0, 1, NULL),
-(40, NOW(), NOW(), 0, 1, 1, 1, 39, '清空日志', 'system:log:delete', 3, NULL, NULL, NULL, NULL, 1, 0, 1, 0, 1, NULL);
+(40, NOW(), NOW(), 0, 1, 1, 1, 39, '归档日志', 'system:log:archive', 3, NULL, NULL, NULL, NULL, 1, 0, 1, 0, 1, NULL),
+(46, NOW(), NOW(), 0, 1, 1, 1, 39, '校验日志', 'system:log:verify', 3, NULL, NULL, NULL, NULL, 2, 0, 1, 0, 1, NULL),
+(47, NOW(), NOW(), 0, 1, 1, 1, 39, '导出日志', 'system:log:export', 3, NULL, NULL, NULL, NULL, 3, 0, 1, 0, 1, NULL);
 
 -- 定时任务
 INSERT INTO sys_resource (id, created_at, updated_at, is_delete, created_by, updated_by, app_id, parent_id, name, code, type, path, component, redirect, icon, sort, is_hidden, is_cache, is_frame, status, remark) VALUES
@@ -255,7 +373,8 @@ INSERT INTO sys_role_resource (role_id, resource_id, is_delete) VALUES
 (1, 31, 0), (1, 32, 0), (1, 33, 0), (1, 34, 0),
 (1, 35, 0), (1, 36, 0), (1, 37, 0), (1, 38, 0),
 (1, 39, 0), (1, 40, 0),
-(1, 41, 0), (1, 42, 0), (1, 43, 0), (1, 44, 0), (1, 45, 0);
+(1, 41, 0), (1, 42, 0), (1, 43, 0), (1, 44, 0), (1, 45, 0),
+(1, 46, 0), (1, 47, 0);
 
 -- =============================================
 -- 8. 初始化第三方登录配置
@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser 与 gocron.CronJob(expr, true) 一致：6 段（含秒），兼容 @every/@daily 等描述符
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ValidateCron 校验 cron 表达式
+func ValidateCron(cronExpr string) error {
+	if _, err := cronParser.Parse(cronExpr); err != nil {
+		return fmt.Errorf("cron 表达式无效: %w", err)
+	}
+	return nil
+}
+
+// MissedRuns 计算 (since, until] 区间内本应触发的时间点，最多返回 limit 个（limit<=0 不限）
+// 用于服务停机或任务暂停期间的错过执行（misfire）补偿
+func MissedRuns(cronExpr string, since, until time.Time, limit int) ([]time.Time, error) {
+	schedule, err := cronParser.Parse(cronExpr)
+	if err != nil {
+		return nil, fmt.Errorf("cron 表达式无效: %w", err)
+	}
+
+	var missed []time.Time
+	for t := schedule.Next(since); !t.IsZero() && !t.After(until); t = schedule.Next(t) {
+		missed = append(missed, t)
+		if limit > 0 && len(missed) >= limit {
+			break
+		}
+	}
+	return missed, nil
+}
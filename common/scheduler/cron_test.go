@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMissedRuns(t *testing.T) {
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		expr    string
+		since   time.Time
+		until   time.Time
+		limit   int
+		want    []time.Time
+		wantErr bool
+	}{
+		{
+			name:  "区间左开右闭",
+			expr:  "0 * * * * *",
+			since: base,
+			until: base.Add(3 * time.Minute),
+			want:  []time.Time{base.Add(time.Minute), base.Add(2 * time.Minute), base.Add(3 * time.Minute)},
+		},
+		{
+			name:  "按上限截断",
+			expr:  "0 * * * * *",
+			since: base,
+			until: base.Add(time.Hour),
+			limit: 2,
+			want:  []time.Time{base.Add(time.Minute), base.Add(2 * time.Minute)},
+		},
+		{
+			name:  "区间内无触发点",
+			expr:  "0 0 0 * * *",
+			since: base,
+			until: base.Add(time.Hour),
+		},
+		{
+			name:  "until 早于 since",
+			expr:  "0 * * * * *",
+			since: base,
+			until: base.Add(-time.Hour),
+		},
+		{
+			name:  "描述符",
+			expr:  "@every 30m",
+			since: base,
+			until: base.Add(time.Hour),
+			want:  []time.Time{base.Add(30 * time.Minute), base.Add(time.Hour)},
+		},
+		{
+			name:  "带时区",
+			expr:  "CRON_TZ=Asia/Shanghai 0 0 18 * * *",
+			since: base,
+			until: base.Add(24 * time.Hour),
+			want:  []time.Time{time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "表达式无效",
+			expr:    "* * *",
+			since:   base,
+			until:   base.Add(time.Hour),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MissedRuns(tt.expr, tt.since, tt.until, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("MissedRuns = %v, 期望 %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("第 %d 个触发点 = %s, 期望 %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCron(t *testing.T) {
+	for _, expr := range []string{"0 */5 * * * *", "@daily", "CRON_TZ=Asia/Shanghai 0 0 9 * * 1-5"} {
+		if err := ValidateCron(expr); err != nil {
+			t.Errorf("ValidateCron(%q) = %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"", "*/5 * * * *", "0 0 25 * * *"} {
+		if err := ValidateCron(expr); err == nil {
+			t.Errorf("ValidateCron(%q) 期望返回错误", expr)
+		}
+	}
+}
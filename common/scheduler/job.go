@@ -14,23 +14,64 @@ type Job interface {
 // LogCallback 日志回调函数，由各项目注入具体的日志写入逻辑
 type LogCallback func(entry *JobLogEntry)
 
-// JobLogEntry 任务执行日志条目
+// CompleteCallback 任务完成回调（所有重试结束后调用一次，跳过的执行不回调）
+type CompleteCallback func(result *RunResult)
+
+// 执行状态
+const (
+	StatusFailed  = 0 // 失败
+	StatusSuccess = 1 // 成功
+	StatusSkipped = 2 // 跳过（并发策略为禁止且上次执行未结束，或其他实例正在执行）
+)
+
+// 触发类型
+const (
+	TriggerCron       = "cron"       // 按 cron 表达式调度
+	TriggerManual     = "manual"     // 手动触发
+	TriggerDependency = "dependency" // 上游任务成功后触发
+	TriggerMisfire    = "misfire"    // 错过执行后补偿触发
+)
+
+// ConcurrencyPolicy 并发策略
+type ConcurrencyPolicy int
+
+const (
+	ConcurrencyForbid  ConcurrencyPolicy = 0 // 禁止：上次执行未结束时跳过本次
+	ConcurrencyAllow   ConcurrencyPolicy = 1 // 允许：多次执行并行
+	ConcurrencyReplace ConcurrencyPolicy = 2 // 替换：取消上次执行后启动本次
+)
+
+// JobLogEntry 任务执行日志条目，每次尝试（含重试）记录一条
 type JobLogEntry struct {
-	JobName   string
-	Params    string
-	Status    int // 0-失败 1-成功
-	Error     string
-	StartTime time.Time
-	EndTime   time.Time
-	Duration  int64 // 毫秒
+	JobName     string
+	Params      string
+	Status      int // 0-失败 1-成功 2-跳过
+	Error       string
+	TriggerType string
+	Attempt     int // 第几次尝试，从 1 开始
+	MaxAttempts int // 最大尝试次数（1 + 重试次数）
+	StartTime   time.Time
+	EndTime     time.Time
+	Duration    int64 // 毫秒
+}
+
+// RunResult 一次触发的最终执行结果
+type RunResult struct {
+	JobName     string
+	TriggerType string
+	Status      int // 0-失败 1-成功
+	Error       string
+	Attempts    int
+	StartTime   time.Time
+	EndTime     time.Time
 }
 
 // JobInfo 任务信息（用于查询）
 type JobInfo struct {
-	Name       string    `json:"name"`
-	CronExpr   string    `json:"cronExpr"`
-	NextRun    time.Time `json:"nextRun"`
-	LastRun    time.Time `json:"lastRun"`
-	IsRunning  bool      `json:"isRunning"`
-	IsDynamic  bool      `json:"isDynamic"`
+	Name      string    `json:"name"`
+	CronExpr  string    `json:"cronExpr"`
+	NextRun   time.Time `json:"nextRun"`
+	LastRun   time.Time `json:"lastRun"`
+	IsRunning bool      `json:"isRunning"`
+	IsDynamic bool      `json:"isDynamic"`
 }
@@ -1,6 +1,8 @@
 package scheduler
 
 import (
+	"time"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -9,8 +11,9 @@ type Option func(*Options)
 
 // Options 调度器配置
 type Options struct {
-	RedisClient *redis.Client
-	LogCallback LogCallback
+	RedisClient      *redis.Client
+	LogCallback      LogCallback
+	CompleteCallback CompleteCallback
 }
 
 // WithRedisLocker 启用 Redis 分布式锁，多实例部署时同一任务只执行一次
@@ -26,3 +29,81 @@ func WithLogCallback(cb LogCallback) Option {
 		o.LogCallback = cb
 	}
 }
+
+// WithCompleteCallback 设置完成回调，用于任务依赖触发、记录最近执行状态等
+func WithCompleteCallback(cb CompleteCallback) Option {
+	return func(o *Options) {
+		o.CompleteCallback = cb
+	}
+}
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxRetries  int           // 失败后最多重试次数
+	Interval    time.Duration // 首次重试间隔
+	Multiplier  float64       // 退避倍数，<=1 表示固定间隔
+	MaxInterval time.Duration // 最大重试间隔，0 表示不限
+}
+
+// Backoff 计算第 retry 次重试（从 1 开始）前的等待时间
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	d := p.Interval
+	if p.Multiplier > 1 {
+		for i := 1; i < retry; i++ {
+			d = time.Duration(float64(d) * p.Multiplier)
+			if p.MaxInterval > 0 && d >= p.MaxInterval {
+				break
+			}
+		}
+	}
+	if p.MaxInterval > 0 && d > p.MaxInterval {
+		d = p.MaxInterval
+	}
+	return d
+}
+
+// JobOption 单个任务的执行配置
+type JobOption func(*jobConfig)
+
+type jobConfig struct {
+	timeout     time.Duration
+	retry       RetryPolicy
+	concurrency ConcurrencyPolicy
+	params      string
+}
+
+// WithTimeout 设置单次执行超时，超时后取消 context 并按失败处理
+func WithTimeout(d time.Duration) JobOption {
+	return func(c *jobConfig) {
+		c.timeout = d
+	}
+}
+
+// WithRetry 设置失败重试策略
+func WithRetry(p RetryPolicy) JobOption {
+	return func(c *jobConfig) {
+		c.retry = p
+	}
+}
+
+// WithConcurrency 设置并发策略，默认允许并发
+func WithConcurrency(p ConcurrencyPolicy) JobOption {
+	return func(c *jobConfig) {
+		c.concurrency = p
+	}
+}
+
+// WithParams 设置任务参数，仅用于写入执行日志
+func WithParams(params string) JobOption {
+	return func(c *jobConfig) {
+		c.params = params
+	}
+}
+
+func newJobConfig(opts []JobOption) *jobConfig {
+	cfg := &jobConfig{concurrency: ConcurrencyAllow}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		retry  int
+		want   time.Duration
+	}{
+		{name: "固定间隔", policy: RetryPolicy{Interval: time.Second}, retry: 3, want: time.Second},
+		{name: "倍数不大于1视为固定间隔", policy: RetryPolicy{Interval: time.Second, Multiplier: 1}, retry: 5, want: time.Second},
+		{name: "首次重试不退避", policy: RetryPolicy{Interval: time.Second, Multiplier: 2}, retry: 1, want: time.Second},
+		{name: "指数退避", policy: RetryPolicy{Interval: time.Second, Multiplier: 2}, retry: 4, want: 8 * time.Second},
+		{name: "小数倍数", policy: RetryPolicy{Interval: 2 * time.Second, Multiplier: 1.5}, retry: 3, want: 4500 * time.Millisecond},
+		{name: "达到最大间隔", policy: RetryPolicy{Interval: time.Second, Multiplier: 2, MaxInterval: 5 * time.Second}, retry: 4, want: 5 * time.Second},
+		{name: "大次数不溢出", policy: RetryPolicy{Interval: time.Second, Multiplier: 10, MaxInterval: time.Hour}, retry: 1000, want: time.Hour},
+		{name: "初始间隔超过最大间隔", policy: RetryPolicy{Interval: time.Minute, MaxInterval: time.Second}, retry: 1, want: time.Second},
+		{name: "零间隔", policy: RetryPolicy{Multiplier: 2}, retry: 3, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backoff(tt.retry); got != tt.want {
+				t.Fatalf("Backoff(%d) = %s, 期望 %s", tt.retry, got, tt.want)
+			}
+		})
+	}
+}
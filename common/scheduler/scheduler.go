@@ -7,19 +7,27 @@ import (
 	"sync"
 	"time"
 
-	"github.com/go-co-op/gocron/v2"
 	redislock "github.com/go-co-op/gocron-redis-lock/v2"
+	"github.com/go-co-op/gocron/v2"
 )
 
+// attemptGrace 单次尝试超时或被取消后，等待任务函数退出的最长时间
+var attemptGrace = 10 * time.Second
+
 // Scheduler 定时任务调度器
 type Scheduler struct {
-	scheduler   gocron.Scheduler
-	mu          sync.RWMutex
-	jobs        map[string]gocron.Job
-	cronExprs   map[string]string
-	dynamicJobs map[string]bool
-	taskFuncs   map[string]func()
-	logCallback LogCallback
+	scheduler        gocron.Scheduler
+	locker           gocron.Locker // 分布式锁，cron 触发由 gocron 加锁，其余触发在 execute 中加锁
+	mu               sync.RWMutex
+	jobs             map[string]gocron.Job // cron 为空的任务（仅依赖/手动触发）为 nil
+	cronExprs        map[string]string
+	dynamicJobs      map[string]bool
+	taskFuncs        map[string]func(ctx context.Context) error
+	configs          map[string]*jobConfig
+	runs             map[string]map[uint64]context.CancelFunc // 正在执行的实例
+	runSeq           uint64
+	logCallback      LogCallback
+	completeCallback CompleteCallback
 }
 
 // NewScheduler 创建调度器实例
@@ -30,9 +38,11 @@ func NewScheduler(opts ...Option) (*Scheduler, error) {
 	}
 
 	var gocronOpts []gocron.SchedulerOption
+	var locker gocron.Locker
 
 	if options.RedisClient != nil {
-		locker, err := redislock.NewRedisLocker(options.RedisClient, redislock.WithTries(1))
+		var err error
+		locker, err = redislock.NewRedisLocker(options.RedisClient, redislock.WithTries(1))
 		if err != nil {
 			return nil, fmt.Errorf("创建 Redis 分布式锁失败: %w", err)
 		}
@@ -45,115 +55,174 @@ func NewScheduler(opts ...Option) (*Scheduler, error) {
 	}
 
 	return &Scheduler{
-		scheduler:   s,
-		jobs:        make(map[string]gocron.Job),
-		cronExprs:   make(map[string]string),
-		dynamicJobs: make(map[string]bool),
-		taskFuncs:   make(map[string]func()),
-		logCallback: options.LogCallback,
+		scheduler:        s,
+		locker:           locker,
+		jobs:             make(map[string]gocron.Job),
+		cronExprs:        make(map[string]string),
+		dynamicJobs:      make(map[string]bool),
+		taskFuncs:        make(map[string]func(ctx context.Context) error),
+		configs:          make(map[string]*jobConfig),
+		runs:             make(map[string]map[uint64]context.CancelFunc),
+		logCallback:      options.LogCallback,
+		completeCallback: options.CompleteCallback,
 	}, nil
 }
 
 // Register 注册静态任务（启动时注册）
-func (s *Scheduler) Register(job Job, cronExpr string) error {
-	return s.addJob(job.Name(), cronExpr, job.Run, false)
+func (s *Scheduler) Register(job Job, cronExpr string, opts ...JobOption) error {
+	return s.addJob(job.Name(), cronExpr, job.Run, false, opts)
 }
 
 // AddDynamic 添加动态任务（运行时创建）
-func (s *Scheduler) AddDynamic(name string, cronExpr string, fn func(ctx context.Context) error) error {
-	return s.addJob(name, cronExpr, fn, true)
+// cronExpr 为空时任务不会被定时调度，只能通过 Trigger/Run 触发（如依赖触发）
+func (s *Scheduler) AddDynamic(name string, cronExpr string, fn func(ctx context.Context) error, opts ...JobOption) error {
+	return s.addJob(name, cronExpr, fn, true, opts)
 }
 
-func (s *Scheduler) addJob(name string, cronExpr string, fn func(ctx context.Context) error, isDynamic bool) error {
+func (s *Scheduler) addJob(name string, cronExpr string, fn func(ctx context.Context) error, isDynamic bool, opts []JobOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.jobs[name]; exists {
+	if _, exists := s.taskFuncs[name]; exists {
 		return fmt.Errorf("任务 %s 已存在", name)
 	}
 
-	wrappedFn := s.wrapWithLog(name, fn)
+	var j gocron.Job
+	if cronExpr != "" {
+		var err error
+		if j, err = s.newCronJob(name, cronExpr); err != nil {
+			return err
+		}
+	}
 
+	s.jobs[name] = j
+	s.cronExprs[name] = cronExpr
+	s.dynamicJobs[name] = isDynamic
+	s.taskFuncs[name] = fn
+	s.configs[name] = newJobConfig(opts)
+	return nil
+}
+
+func (s *Scheduler) newCronJob(name, cronExpr string) (gocron.Job, error) {
 	j, err := s.scheduler.NewJob(
 		gocron.CronJob(cronExpr, true),
-		gocron.NewTask(wrappedFn),
+		gocron.NewTask(s.cronTask(name)),
 		gocron.WithName(name),
 		gocron.WithTags(name),
 	)
 	if err != nil {
-		return fmt.Errorf("创建任务 %s 失败: %w", name, err)
+		return nil, fmt.Errorf("创建任务 %s 失败: %w", name, err)
 	}
+	return j, nil
+}
 
-	s.jobs[name] = j
-	s.cronExprs[name] = cronExpr
-	s.dynamicJobs[name] = isDynamic
-	s.taskFuncs[name] = wrappedFn
-	return nil
+// cronTask 定时触发的任务入口
+func (s *Scheduler) cronTask(name string) func() {
+	return func() {
+		s.execute(name, TriggerCron)
+	}
 }
 
-// UpdateCron 更新任务的 cron 表达式
+// UpdateCron 更新任务的 cron 表达式，传入空字符串表示取消定时调度
 func (s *Scheduler) UpdateCron(name string, cronExpr string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	oldJob, exists := s.jobs[name]
-	if !exists {
+	if _, exists := s.taskFuncs[name]; !exists {
 		return fmt.Errorf("任务 %s 不存在", name)
 	}
 
-	taskFn, ok := s.taskFuncs[name]
-	if !ok {
-		return fmt.Errorf("任务 %s 的执行函数不存在", name)
-	}
-
-	newJob, err := s.scheduler.Update(
-		oldJob.ID(),
-		gocron.CronJob(cronExpr, true),
-		gocron.NewTask(taskFn),
-		gocron.WithName(name),
-		gocron.WithTags(name),
-	)
-	if err != nil {
-		return fmt.Errorf("更新任务 %s 失败: %w", name, err)
+	oldJob := s.jobs[name]
+	switch {
+	case cronExpr == "":
+		if oldJob != nil {
+			if err := s.scheduler.RemoveJob(oldJob.ID()); err != nil {
+				return fmt.Errorf("更新任务 %s 失败: %w", name, err)
+			}
+		}
+		s.jobs[name] = nil
+	case oldJob == nil:
+		newJob, err := s.newCronJob(name, cronExpr)
+		if err != nil {
+			return err
+		}
+		s.jobs[name] = newJob
+	default:
+		newJob, err := s.scheduler.Update(
+			oldJob.ID(),
+			gocron.CronJob(cronExpr, true),
+			gocron.NewTask(s.cronTask(name)),
+			gocron.WithName(name),
+			gocron.WithTags(name),
+		)
+		if err != nil {
+			return fmt.Errorf("更新任务 %s 失败: %w", name, err)
+		}
+		s.jobs[name] = newJob
 	}
 
-	s.jobs[name] = newJob
 	s.cronExprs[name] = cronExpr
 	return nil
 }
 
-// Remove 删除任务
+// UpdateOptions 更新任务的执行配置（超时、重试、并发策略等），对下一次执行生效
+func (s *Scheduler) UpdateOptions(name string, opts ...JobOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.taskFuncs[name]; !exists {
+		return fmt.Errorf("任务 %s 不存在", name)
+	}
+	s.configs[name] = newJobConfig(opts)
+	return nil
+}
+
+// Remove 删除任务，并取消正在执行的实例
 func (s *Scheduler) Remove(name string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	job, exists := s.jobs[name]
-	if !exists {
+	if _, exists := s.taskFuncs[name]; !exists {
 		return fmt.Errorf("任务 %s 不存在", name)
 	}
 
-	if err := s.scheduler.RemoveJob(job.ID()); err != nil {
-		return fmt.Errorf("删除任务 %s 失败: %w", name, err)
+	if job := s.jobs[name]; job != nil {
+		if err := s.scheduler.RemoveJob(job.ID()); err != nil {
+			return fmt.Errorf("删除任务 %s 失败: %w", name, err)
+		}
+	}
+	for _, cancel := range s.runs[name] {
+		cancel()
 	}
 
 	delete(s.jobs, name)
 	delete(s.cronExprs, name)
 	delete(s.dynamicJobs, name)
 	delete(s.taskFuncs, name)
+	delete(s.configs, name)
 	return nil
 }
 
 // TriggerOnce 立即触发任务执行一次
 func (s *Scheduler) TriggerOnce(name string) error {
-	s.mu.RLock()
-	job, exists := s.jobs[name]
-	s.mu.RUnlock()
+	return s.Trigger(name, TriggerManual)
+}
 
-	if !exists {
+// Trigger 异步触发任务执行一次，triggerType 会写入执行日志
+func (s *Scheduler) Trigger(name string, triggerType string) error {
+	if !s.HasJob(name) {
 		return fmt.Errorf("任务 %s 不存在", name)
 	}
+	go s.execute(name, triggerType)
+	return nil
+}
 
-	return job.RunNow()
+// Run 同步执行任务一次，返回最终结果；因并发策略被跳过时返回 nil 结果
+func (s *Scheduler) Run(name string, triggerType string) (*RunResult, error) {
+	if !s.HasJob(name) {
+		return nil, fmt.Errorf("任务 %s 不存在", name)
+	}
+	return s.execute(name, triggerType), nil
 }
 
 // Start 启动调度器
@@ -172,17 +241,8 @@ func (s *Scheduler) ListJobs() []JobInfo {
 	defer s.mu.RUnlock()
 
 	list := make([]JobInfo, 0, len(s.jobs))
-	for name, job := range s.jobs {
-		info := JobInfo{
-			Name:      name,
-			CronExpr:  s.cronExprs[name],
-			IsDynamic: s.dynamicJobs[name],
-		}
-		nextRun, _ := job.NextRun()
-		info.NextRun = nextRun
-		lastRun, _ := job.LastRun()
-		info.LastRun = lastRun
-		list = append(list, info)
+	for name := range s.jobs {
+		list = append(list, s.jobInfo(name))
 	}
 	return list
 }
@@ -192,52 +252,234 @@ func (s *Scheduler) GetJob(name string) (*JobInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	job, exists := s.jobs[name]
-	if !exists {
+	if _, exists := s.taskFuncs[name]; !exists {
 		return nil, errors.New("任务不存在")
 	}
 
-	info := &JobInfo{
-		Name:      name,
-		CronExpr:  s.cronExprs[name],
-		IsDynamic: s.dynamicJobs[name],
-	}
-	nextRun, _ := job.NextRun()
-	info.NextRun = nextRun
-	lastRun, _ := job.LastRun()
-	info.LastRun = lastRun
-	return info, nil
+	info := s.jobInfo(name)
+	return &info, nil
 }
 
 // HasJob 检查任务是否存在
 func (s *Scheduler) HasJob(name string) bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	_, exists := s.jobs[name]
+	_, exists := s.taskFuncs[name]
 	return exists
 }
 
-// wrapWithLog 包装任务函数，记录执行日志
-func (s *Scheduler) wrapWithLog(name string, fn func(ctx context.Context) error) func() {
-	return func() {
-		startTime := time.Now()
-		err := fn(context.Background())
-		endTime := time.Now()
-		duration := endTime.Sub(startTime).Milliseconds()
-
-		if s.logCallback != nil {
-			entry := &JobLogEntry{
-				JobName:   name,
-				Status:    1,
-				StartTime: startTime,
-				EndTime:   endTime,
-				Duration:  duration,
+// jobInfo 组装任务信息，调用方需持有读锁
+func (s *Scheduler) jobInfo(name string) JobInfo {
+	info := JobInfo{
+		Name:      name,
+		CronExpr:  s.cronExprs[name],
+		IsDynamic: s.dynamicJobs[name],
+		IsRunning: len(s.runs[name]) > 0,
+	}
+	if job := s.jobs[name]; job != nil {
+		info.NextRun, _ = job.NextRun()
+		info.LastRun, _ = job.LastRun()
+	}
+	return info
+}
+
+// execute 执行一次任务：按并发策略准入，按重试策略逐次尝试并记录每次尝试的日志
+func (s *Scheduler) execute(name string, triggerType string) *RunResult {
+	s.mu.RLock()
+	cfg, exists := s.configs[name]
+	s.mu.RUnlock()
+	if !exists {
+		return nil
+	}
+
+	// cron 触发已由 gocron 持有同名分布式锁，手动、依赖、补偿触发走同一把锁，多实例下同一任务只执行一次
+	if triggerType != TriggerCron && s.locker != nil {
+		lock, err := s.locker.Lock(context.Background(), name)
+		if err != nil {
+			s.skip(name, cfg, triggerType, "其他实例正在执行该任务，跳过本次")
+			return nil
+		}
+		defer lock.Unlock(context.Background())
+	}
+
+	s.mu.Lock()
+	fn, exists := s.taskFuncs[name]
+	if !exists {
+		s.mu.Unlock()
+		return nil
+	}
+	cfg = s.configs[name]
+	running := s.runs[name]
+
+	switch cfg.concurrency {
+	case ConcurrencyForbid:
+		if len(running) > 0 {
+			s.mu.Unlock()
+			s.skip(name, cfg, triggerType, "上次执行尚未结束，按并发策略跳过")
+			return nil
+		}
+	case ConcurrencyReplace:
+		for _, cancel := range running {
+			cancel()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.runSeq++
+	runID := s.runSeq
+	if running == nil {
+		running = make(map[uint64]context.CancelFunc)
+		s.runs[name] = running
+	}
+	running[runID] = cancel
+	s.mu.Unlock()
+
+	// 任务函数未在宽限期内退出时，直到其真正退出才释放执行实例，使并发策略仍能感知
+	var leaked <-chan struct{}
+	defer func() {
+		cancel()
+		if leaked == nil {
+			s.release(name, runID)
+			return
+		}
+		go func() {
+			<-leaked
+			s.release(name, runID)
+		}()
+	}()
+
+	maxAttempts := 1
+	if cfg.retry.MaxRetries > 0 {
+		maxAttempts += cfg.retry.MaxRetries
+	}
+
+	result := &RunResult{JobName: name, TriggerType: triggerType, StartTime: time.Now()}
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(cfg.retry.Backoff(attempt - 1))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
 			}
-			if err != nil {
-				entry.Status = 0
-				entry.Error = err.Error()
+			if ctx.Err() != nil {
+				break
 			}
-			s.logCallback(entry)
 		}
+
+		startTime := time.Now()
+		leaked, err = s.attempt(ctx, fn, cfg.timeout)
+		endTime := time.Now()
+		result.Attempts = attempt
+
+		entry := &JobLogEntry{
+			JobName:     name,
+			Params:      cfg.params,
+			Status:      StatusSuccess,
+			TriggerType: triggerType,
+			Attempt:     attempt,
+			MaxAttempts: maxAttempts,
+			StartTime:   startTime,
+			EndTime:     endTime,
+			Duration:    endTime.Sub(startTime).Milliseconds(),
+		}
+		if err != nil {
+			entry.Status = StatusFailed
+			entry.Error = err.Error()
+		}
+		s.log(entry)
+
+		// 成功、被替换/删除取消，或上次尝试仍未退出时不再重试，避免同一实例的多次尝试重叠
+		if err == nil || ctx.Err() != nil || leaked != nil {
+			break
+		}
+	}
+
+	result.EndTime = time.Now()
+	result.Status = StatusSuccess
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	if s.completeCallback != nil {
+		s.completeCallback(result)
+	}
+	return result
+}
+
+// attempt 执行一次尝试。超时或取消后最多再等待 attemptGrace 让任务函数退出（任务函数应响应 ctx 取消），
+// 仍未退出时返回 leaked，该通道在任务函数最终退出时关闭
+func (s *Scheduler) attempt(ctx context.Context, fn func(ctx context.Context) error, timeout time.Duration) (leaked <-chan struct{}, err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("任务执行 panic: %v", r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return nil, err
+	case <-ctx.Done():
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("执行超时(%s)", timeout)
+	} else {
+		err = fmt.Errorf("执行已取消: %w", ctx.Err())
+	}
+
+	grace := time.NewTimer(attemptGrace)
+	defer grace.Stop()
+	select {
+	case <-done:
+		return nil, err
+	case <-grace.C:
+		exited := make(chan struct{})
+		go func() {
+			<-done
+			close(exited)
+		}()
+		return exited, fmt.Errorf("%w，任务函数在 %s 内未退出", err, attemptGrace)
+	}
+}
+
+// release 移除已结束的执行实例
+func (s *Scheduler) release(name string, runID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.runs[name], runID)
+	if len(s.runs[name]) == 0 {
+		delete(s.runs, name)
+	}
+}
+
+// skip 记录一次被跳过的执行
+func (s *Scheduler) skip(name string, cfg *jobConfig, triggerType string, reason string) {
+	now := time.Now()
+	s.log(&JobLogEntry{
+		JobName:     name,
+		Params:      cfg.params,
+		Status:      StatusSkipped,
+		Error:       reason,
+		TriggerType: triggerType,
+		StartTime:   now,
+		EndTime:     now,
+	})
+}
+
+func (s *Scheduler) log(entry *JobLogEntry) {
+	if s.logCallback != nil {
+		s.logCallback(entry)
 	}
 }
@@ -0,0 +1,343 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+// logRecorder 收集调度器日志回调
+type logRecorder struct {
+	mu      sync.Mutex
+	entries []*JobLogEntry
+}
+
+func (r *logRecorder) record(entry *JobLogEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+func (r *logRecorder) statuses() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]int, 0, len(r.entries))
+	for _, e := range r.entries {
+		out = append(out, e.Status)
+	}
+	return out
+}
+
+func newTestScheduler(t *testing.T) (*Scheduler, *logRecorder) {
+	t.Helper()
+	logs := &logRecorder{}
+	s, err := NewScheduler(WithLogCallback(logs.record))
+	if err != nil {
+		t.Fatalf("NewScheduler: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+	return s, logs
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestExecute_Retry(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name       string
+		failures   int // 前 failures 次尝试失败
+		maxRetries int
+		status     int
+		attempts   int
+		logs       []int
+	}{
+		{name: "首次成功", failures: 0, maxRetries: 2, status: StatusSuccess, attempts: 1, logs: []int{StatusSuccess}},
+		{name: "重试后成功", failures: 2, maxRetries: 3, status: StatusSuccess, attempts: 3, logs: []int{StatusFailed, StatusFailed, StatusSuccess}},
+		{name: "重试耗尽", failures: 5, maxRetries: 2, status: StatusFailed, attempts: 3, logs: []int{StatusFailed, StatusFailed, StatusFailed}},
+		{name: "不重试", failures: 1, maxRetries: 0, status: StatusFailed, attempts: 1, logs: []int{StatusFailed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, logs := newTestScheduler(t)
+			calls := 0
+			fn := func(ctx context.Context) error {
+				calls++
+				if calls <= tt.failures {
+					return errBoom
+				}
+				return nil
+			}
+			err := s.AddDynamic("job", "", fn, WithRetry(RetryPolicy{MaxRetries: tt.maxRetries, Interval: time.Millisecond}))
+			if err != nil {
+				t.Fatalf("AddDynamic: %v", err)
+			}
+
+			result, err := s.Run("job", TriggerManual)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if result.Status != tt.status || result.Attempts != tt.attempts {
+				t.Fatalf("Status = %d, Attempts = %d, 期望 %d, %d", result.Status, result.Attempts, tt.status, tt.attempts)
+			}
+			if got := logs.statuses(); !equalInts(got, tt.logs) {
+				t.Fatalf("日志状态 = %v, 期望 %v", got, tt.logs)
+			}
+			if tt.status == StatusFailed && result.Error != errBoom.Error() {
+				t.Fatalf("Error = %q", result.Error)
+			}
+		})
+	}
+}
+
+func TestExecute_Panic(t *testing.T) {
+	s, _ := newTestScheduler(t)
+	s.AddDynamic("job", "", func(ctx context.Context) error { panic("oops") })
+
+	result, _ := s.Run("job", TriggerManual)
+	if result.Status != StatusFailed {
+		t.Fatalf("Status = %d, 期望失败", result.Status)
+	}
+}
+
+func TestExecute_Concurrency(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ConcurrencyPolicy
+		first  int  // 第一次执行的最终状态
+		second bool // 第二次触发是否执行
+		logs   []int
+	}{
+		{name: "禁止", policy: ConcurrencyForbid, first: StatusSuccess, second: false, logs: []int{StatusSkipped, StatusSuccess}},
+		{name: "允许", policy: ConcurrencyAllow, first: StatusSuccess, second: true, logs: []int{StatusSuccess, StatusSuccess}},
+		{name: "替换", policy: ConcurrencyReplace, first: StatusFailed, second: true, logs: []int{StatusFailed, StatusSuccess}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, logs := newTestScheduler(t)
+			release := make(chan struct{})
+			started := make(chan struct{}, 2)
+			fn := func(ctx context.Context) error {
+				started <- struct{}{}
+				select {
+				case <-release:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			s.AddDynamic("job", "", fn, WithConcurrency(tt.policy))
+
+			first := make(chan *RunResult, 1)
+			go func() {
+				result, _ := s.Run("job", TriggerCron)
+				first <- result
+			}()
+			<-started
+
+			second := make(chan *RunResult, 1)
+			go func() {
+				result, _ := s.Run("job", TriggerManual)
+				second <- result
+			}()
+
+			var r1, r2 *RunResult
+			switch {
+			case !tt.second:
+				r2 = <-second
+			case tt.first == StatusFailed:
+				// 替换：第一次执行被取消后第二次才开始
+				r1 = <-first
+				<-started
+			default:
+				<-started
+			}
+			close(release)
+			if r1 == nil {
+				r1 = <-first
+			}
+			if r2 == nil && tt.second {
+				r2 = <-second
+			}
+
+			if r1.Status != tt.first {
+				t.Fatalf("第一次执行 Status = %d, 期望 %d", r1.Status, tt.first)
+			}
+			if tt.second != (r2 != nil) {
+				t.Fatalf("第二次执行结果 = %+v, 期望执行 %v", r2, tt.second)
+			}
+			if r2 != nil && r2.Status != StatusSuccess {
+				t.Fatalf("第二次执行 Status = %d", r2.Status)
+			}
+			if got := logs.statuses(); !equalInts(got, tt.logs) {
+				t.Fatalf("日志状态 = %v, 期望 %v", got, tt.logs)
+			}
+		})
+	}
+}
+
+func TestExecute_TimeoutRetry(t *testing.T) {
+	s, logs := newTestScheduler(t)
+	calls := 0
+	fn := func(ctx context.Context) error {
+		calls++
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	s.AddDynamic("job", "", fn,
+		WithTimeout(10*time.Millisecond),
+		WithRetry(RetryPolicy{MaxRetries: 2, Interval: time.Millisecond}))
+
+	result, _ := s.Run("job", TriggerManual)
+	if result.Status != StatusFailed || result.Attempts != 3 || calls != 3 {
+		t.Fatalf("Status = %d, Attempts = %d, calls = %d", result.Status, result.Attempts, calls)
+	}
+	if got := logs.statuses(); !equalInts(got, []int{StatusFailed, StatusFailed, StatusFailed}) {
+		t.Fatalf("日志状态 = %v", got)
+	}
+}
+
+func TestExecute_TimeoutWaitsForLeakedAttempt(t *testing.T) {
+	old := attemptGrace
+	attemptGrace = 20 * time.Millisecond
+	t.Cleanup(func() { attemptGrace = old })
+
+	s, logs := newTestScheduler(t)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	fn := func(ctx context.Context) error {
+		mu.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		mu.Unlock()
+		<-release // 不响应 ctx 取消
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil
+	}
+	s.AddDynamic("job", "", fn,
+		WithTimeout(10*time.Millisecond),
+		WithConcurrency(ConcurrencyForbid),
+		WithRetry(RetryPolicy{MaxRetries: 3, Interval: time.Millisecond}))
+
+	result, _ := s.Run("job", TriggerManual)
+	if result.Status != StatusFailed || result.Attempts != 1 {
+		t.Fatalf("任务函数未退出时不应重试: Status = %d, Attempts = %d", result.Status, result.Attempts)
+	}
+
+	// 超时的尝试仍在运行，禁止并发策略应跳过新的触发
+	if info, _ := s.GetJob("job"); !info.IsRunning {
+		t.Fatal("未退出的尝试应仍计为执行中")
+	}
+	if r, _ := s.Run("job", TriggerManual); r != nil {
+		t.Fatalf("期望被跳过, 实际 %+v", r)
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if info, _ := s.GetJob("job"); !info.IsRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("任务函数退出后执行实例未释放")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxActive != 1 {
+		t.Fatalf("同时运行的尝试数 = %d, 期望 1", maxActive)
+	}
+	if got := logs.statuses(); !equalInts(got, []int{StatusFailed, StatusSkipped}) {
+		t.Fatalf("日志状态 = %v", got)
+	}
+}
+
+// fakeLocker 记录加锁请求，busy 为 true 时模拟其他实例持有锁
+type fakeLocker struct {
+	mu       sync.Mutex
+	busy     bool
+	keys     []string
+	unlocked int
+}
+
+type fakeLock struct{ l *fakeLocker }
+
+func (l *fakeLocker) Lock(ctx context.Context, key string) (gocron.Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keys = append(l.keys, key)
+	if l.busy {
+		return nil, errors.New("locked")
+	}
+	return fakeLock{l}, nil
+}
+
+func (l fakeLock) Unlock(ctx context.Context) error {
+	l.l.mu.Lock()
+	defer l.l.mu.Unlock()
+	l.l.unlocked++
+	return nil
+}
+
+func TestExecute_DistributedLock(t *testing.T) {
+	tests := []struct {
+		name     string
+		trigger  string
+		busy     bool
+		locked   bool // 是否由 execute 加锁
+		executed bool
+	}{
+		{name: "手动触发加锁", trigger: TriggerManual, locked: true, executed: true},
+		{name: "依赖触发加锁", trigger: TriggerDependency, locked: true, executed: true},
+		{name: "补偿触发加锁", trigger: TriggerMisfire, locked: true, executed: true},
+		{name: "其他实例持有锁时跳过", trigger: TriggerManual, busy: true, locked: true},
+		{name: "cron 触发由 gocron 加锁", trigger: TriggerCron, busy: true, executed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, logs := newTestScheduler(t)
+			locker := &fakeLocker{busy: tt.busy}
+			s.locker = locker
+			calls := 0
+			s.AddDynamic("job", "", func(ctx context.Context) error {
+				calls++
+				return nil
+			})
+
+			result, _ := s.Run("job", tt.trigger)
+			if (result != nil) != tt.executed || (calls == 1) != tt.executed {
+				t.Fatalf("result = %+v, calls = %d, 期望执行 %v", result, calls, tt.executed)
+			}
+			if got := len(locker.keys) == 1 && locker.keys[0] == "job"; got != tt.locked {
+				t.Fatalf("加锁记录 = %v, 期望加锁 %v", locker.keys, tt.locked)
+			}
+			if tt.locked && !tt.busy && locker.unlocked != 1 {
+				t.Fatalf("执行结束后未释放锁")
+			}
+			if !tt.executed {
+				if got := logs.statuses(); !equalInts(got, []int{StatusSkipped}) {
+					t.Fatalf("日志状态 = %v", got)
+				}
+			}
+		})
+	}
+}
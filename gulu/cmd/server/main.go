@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
@@ -11,6 +13,7 @@ import (
 	"yqhp/common/database"
 	"yqhp/common/logger"
 	commonRedis "yqhp/common/redis"
+	"yqhp/common/scheduler"
 	"yqhp/gulu/internal/auth"
 	"yqhp/gulu/internal/config"
 	"yqhp/gulu/internal/logic"
@@ -58,6 +61,9 @@ func main() {
 	defer commonRedis.Close()
 	rdb := commonRedis.GetClient()
 
+	// 内部接口令牌需在内置引擎启动前写入环境变量
+	initInternalToken(cfg)
+
 	// 初始化服务上下文
 	svc.Init(cfg, db, rdb)
 
@@ -93,6 +99,29 @@ func main() {
 		log.Fatalf("启动内置 MCP Server 失败: %v", err)
 	}
 
+	// 启动定时计划调度器（Redis 分布式锁，多实例部署时每次触发只由一个实例执行）
+	sched, err := scheduler.NewScheduler(scheduler.WithRedisLocker(rdb))
+	if err != nil {
+		log.Fatalf("创建调度器失败: %v", err)
+	}
+	logic.SetScheduler(sched)
+	sched.Start()
+	if err := logic.LoadWorkflowSchedules(); err != nil {
+		logger.Warn("加载定时计划失败", zap.Error(err))
+	}
+	if err := logic.LoadKnowledgeSourceSchedules(); err != nil {
+		logger.Warn("加载知识库数据源同步计划失败", zap.Error(err))
+	}
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	go logic.WatchScheduleChanges(watchCtx)
+	go logic.WatchKnowledgeSourceChanges(watchCtx)
+
+	// 收敛运行器已退出（实例重启或崩溃）而仍处于运行中的测试计划
+	go logic.ReconcileTestPlanRuns(watchCtx)
+
+	// 启动知识库文档入库队列（恢复上次中断的任务）
+	stopIngest := logic.StartKnowledgeIngestWorkers()
+
 	// 启动服务器
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 	go func() {
@@ -109,6 +138,15 @@ func main() {
 
 	log.Println("正在关闭服务器...")
 
+	// 停止定时计划调度器
+	stopWatch()
+	if err := sched.Stop(); err != nil {
+		log.Printf("停止调度器失败: %v", err)
+	}
+
+	// 停止文档入库队列，执行中的任务释放后由下次启动或其他实例继续
+	stopIngest()
+
 	// 停止内置 MCP Server
 	mcpserver.Stop()
 
@@ -125,6 +163,27 @@ func main() {
 	log.Println("服务器已关闭")
 }
 
+// initInternalToken 确定 workflow-engine 回调内部接口的令牌：优先使用配置，其次环境变量，都为空时随机生成。
+// 结果写回环境变量，供同进程内置引擎的回调请求携带
+func initInternalToken(cfg *config.Config) {
+	token := cfg.Gulu.InternalToken
+	if token == "" {
+		token = os.Getenv(types.GuluInternalTokenEnv)
+	}
+	if token == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			log.Fatalf("生成内部接口令牌失败: %v", err)
+		}
+		token = hex.EncodeToString(buf)
+		if !cfg.WorkflowEngine.Embedded {
+			logger.Warn("未配置 gulu.internal_token，外部工作流引擎将无法调用内部接口")
+		}
+	}
+	cfg.Gulu.InternalToken = token
+	os.Setenv(types.GuluInternalTokenEnv, token)
+}
+
 // watchAndSyncSlaves 监听 Slave 注册事件，自动同步到数据库
 func watchAndSyncSlaves() {
 	engine := workflow.GetEngine()
@@ -12,8 +12,10 @@ import (
 
 // GuluConfig Gulu 应用特有配置
 type GuluConfig struct {
-	AppCode  string `yaml:"app_code"`  // 应用编码，用于权限过滤
-	AdminURL string `yaml:"admin_url"` // Admin 服务地址
+	AppCode             string `yaml:"app_code"`              // 应用编码，用于权限过滤
+	AdminURL            string `yaml:"admin_url"`             // Admin 服务地址
+	AllowPrivateNetwork bool   `yaml:"allow_private_network"` // 服务端抓取用户提供的地址（网站 / Git 数据源、API 文档导入）时是否允许访问内网地址，默认禁止
+	InternalToken       string `yaml:"internal_token"`        // workflow-engine 回调 /api/internal/* 接口的令牌，为空时启动时随机生成（仅内置引擎可用）
 }
 
 // WorkflowEngineConfig Workflow Engine 配置
@@ -46,6 +48,42 @@ type QdrantConfig struct {
 	CollectionPrefix string `yaml:"collection_prefix"`
 }
 
+// VectorStoreConfig 向量库后端配置
+type VectorStoreConfig struct {
+	Default  string                    `yaml:"default"`  // 新建知识库默认后端: qdrant / embedded / pgvector
+	Embedded EmbeddedVectorStoreConfig `yaml:"embedded"` // 内置向量库
+	Pgvector PgvectorConfig            `yaml:"pgvector"` // PostgreSQL + pgvector
+}
+
+// EmbeddedVectorStoreConfig 内置向量库配置（进程内检索，数据持久化到本地目录）
+type EmbeddedVectorStoreConfig struct {
+	Path string `yaml:"path"` // 数据目录，默认 <storage.local.base_path>/vectors
+}
+
+// PgvectorConfig pgvector 配置
+type PgvectorConfig struct {
+	DSN         string `yaml:"dsn"`          // PostgreSQL DSN，为空时复用 postgres 主库连接
+	TablePrefix string `yaml:"table_prefix"` // 向量表名前缀，默认 vec_
+}
+
+// KnowledgeIngestConfig 知识库文档入库队列配置
+type KnowledgeIngestConfig struct {
+	Workers            int           `yaml:"workers"`              // 每个实例的工作协程数，默认 2
+	PollInterval       time.Duration `yaml:"poll_interval"`        // 空闲时轮询队列的间隔，默认 3s
+	Lease              time.Duration `yaml:"lease"`                // 任务租约时长，持有实例失联超过该时长后任务被其他实例接管，默认 2m
+	MaxAttempts        int           `yaml:"max_attempts"`         // 临时错误最大执行次数，默认 5
+	EmbedBatchSize     int           `yaml:"embed_batch_size"`     // 每次 Embedding 请求的分块数，默认 20
+	EmbeddingRateLimit float64       `yaml:"embedding_rate_limit"` // 每个嵌入模型每秒最多请求数（单实例），0 不限制
+}
+
+// KnowledgeSourceConfig 知识库数据源同步配置
+type KnowledgeSourceConfig struct {
+	FolderRoots []string      `yaml:"folder_roots"`  // 允许作为本地目录数据源的服务器目录，为空时禁用本地目录数据源
+	GitBinary   string        `yaml:"git_binary"`    // git 可执行文件，默认 git
+	MaxFileSize int64         `yaml:"max_file_size"` // 单个文件大小上限（字节），默认 20MB，超出的文件跳过
+	SyncTimeout time.Duration `yaml:"sync_timeout"`  // 单次同步超时，默认 30m
+}
+
 // Neo4jConfig Neo4j 图数据库配置
 type Neo4jConfig struct {
 	URI      string `yaml:"uri"`
@@ -76,12 +114,15 @@ type LocalStorageConfig struct {
 // Config 应用配置
 type Config struct {
 	commonConfig.Config `yaml:",inline"`
-	Gulu                GuluConfig           `yaml:"gulu"`
-	WorkflowEngine      WorkflowEngineConfig `yaml:"workflow_engine"`
-	MCPServer           MCPServerConfig      `yaml:"mcp_server"`
-	Qdrant              QdrantConfig         `yaml:"qdrant"`
-	Neo4j               Neo4jConfig          `yaml:"neo4j"`
-	Storage             StorageConfig        `yaml:"storage"`
+	Gulu                GuluConfig            `yaml:"gulu"`
+	WorkflowEngine      WorkflowEngineConfig  `yaml:"workflow_engine"`
+	MCPServer           MCPServerConfig       `yaml:"mcp_server"`
+	Qdrant              QdrantConfig          `yaml:"qdrant"`
+	VectorStore         VectorStoreConfig     `yaml:"vector_store"`
+	KnowledgeIngest     KnowledgeIngestConfig `yaml:"knowledge_ingest"`
+	KnowledgeSource     KnowledgeSourceConfig `yaml:"knowledge_source"`
+	Neo4j               Neo4jConfig           `yaml:"neo4j"`
+	Storage             StorageConfig         `yaml:"storage"`
 }
 
 var (
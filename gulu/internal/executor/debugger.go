@@ -0,0 +1,254 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// DebugCommand 调试命令
+type DebugCommand string
+
+const (
+	// DebugContinue 继续执行直到下一个断点
+	DebugContinue DebugCommand = "continue"
+	// DebugStepOver 执行当前步骤（含其子步骤），在同层或上层的下一个步骤暂停
+	DebugStepOver DebugCommand = "step_over"
+	// DebugStepInto 在下一个步骤暂停，当前步骤有子步骤时进入子步骤
+	DebugStepInto DebugCommand = "step_into"
+	// DebugStepOut 执行完当前层级剩余步骤，在上层的下一个步骤暂停
+	DebugStepOut DebugCommand = "step_out"
+	// DebugPause 运行中请求在下一个步骤暂停
+	DebugPause DebugCommand = "pause"
+)
+
+// 暂停原因
+const (
+	PauseReasonBreakpoint = "breakpoint"
+	PauseReasonStep       = "step"
+	PauseReasonPause      = "pause"
+)
+
+// Breakpoint 断点：按步骤 ID 设置，配置条件表达式时仅在条件成立时暂停
+type Breakpoint struct {
+	StepID    string `json:"stepId"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// DebugOptions 调试选项
+type DebugOptions struct {
+	Breakpoints []Breakpoint `json:"breakpoints,omitempty"`
+	// PauseOnStart 在第一个步骤前暂停
+	PauseOnStart bool `json:"pauseOnStart,omitempty"`
+}
+
+// PausedState 当前暂停点
+type PausedState struct {
+	StepID         string `json:"stepId"`
+	StepName       string `json:"stepName"`
+	StepType       string `json:"stepType,omitempty"`
+	ParentID       string `json:"parentId,omitempty"`
+	Iteration      int    `json:"iteration,omitempty"`
+	Depth          int    `json:"depth"`
+	Reason         string `json:"reason"`
+	ConditionError string `json:"conditionError,omitempty"`
+
+	scope types.DebugScope
+}
+
+// Debugger 单个执行会话的调试器，决定步骤开始前是否暂停并等待调试命令
+type Debugger struct {
+	breakpoints map[string]Breakpoint
+	// mode 最近一次恢复执行的命令，决定下一个暂停点
+	mode DebugCommand
+	// baseDepth 恢复执行时所在步骤的深度，用于 step_over / step_out
+	baseDepth int
+	// depths 已开始步骤的嵌套深度，顶层步骤为 0
+	depths     map[string]int
+	paused     *PausedState
+	pauseAsked bool
+	commandCh  chan DebugCommand
+
+	mu sync.Mutex
+}
+
+// NewDebugger 创建调试器
+func NewDebugger(opts *DebugOptions) *Debugger {
+	d := &Debugger{
+		breakpoints: make(map[string]Breakpoint),
+		mode:        DebugContinue,
+		depths:      make(map[string]int),
+		commandCh:   make(chan DebugCommand, 1),
+	}
+	if opts != nil {
+		d.SetBreakpoints(opts.Breakpoints)
+		if opts.PauseOnStart {
+			d.mode = DebugStepInto
+		}
+	}
+	return d
+}
+
+// SetBreakpoints 替换全部断点，执行中修改对后续步骤生效
+func (d *Debugger) SetBreakpoints(breakpoints []Breakpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = make(map[string]Breakpoint, len(breakpoints))
+	for _, bp := range breakpoints {
+		if bp.StepID != "" {
+			d.breakpoints[bp.StepID] = bp
+		}
+	}
+}
+
+// Breakpoints 返回当前断点
+func (d *Debugger) Breakpoints() []Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	list := make([]Breakpoint, 0, len(d.breakpoints))
+	for _, bp := range d.breakpoints {
+		list = append(list, bp)
+	}
+	return list
+}
+
+// Check 判断步骤开始前是否需要暂停，需要时返回暂停点
+func (d *Debugger) Check(step *types.Step, frame *types.DebugFrame) *PausedState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	depth := 0
+	if frame.ParentID != "" {
+		depth = d.depths[frame.ParentID] + 1
+	}
+	d.depths[step.ID] = depth
+
+	state := &PausedState{
+		StepID:    step.ID,
+		StepName:  step.Name,
+		StepType:  step.Type,
+		ParentID:  frame.ParentID,
+		Iteration: frame.Iteration,
+		Depth:     depth,
+		scope:     frame.Scope,
+	}
+
+	if bp, ok := d.breakpoints[step.ID]; ok {
+		if bp.Condition == "" {
+			state.Reason = PauseReasonBreakpoint
+			return state
+		}
+		// 条件求值失败时同样暂停，便于排查条件表达式
+		hit, err := frame.Scope.Evaluate(bp.Condition)
+		if err != nil {
+			state.Reason = PauseReasonBreakpoint
+			state.ConditionError = err.Error()
+			return state
+		}
+		if hit {
+			state.Reason = PauseReasonBreakpoint
+			return state
+		}
+	}
+
+	if d.pauseAsked {
+		d.pauseAsked = false
+		state.Reason = PauseReasonPause
+		return state
+	}
+
+	switch {
+	case d.mode == DebugStepInto,
+		d.mode == DebugStepOver && depth <= d.baseDepth,
+		d.mode == DebugStepOut && depth < d.baseDepth:
+		state.Reason = PauseReasonStep
+		return state
+	}
+	return nil
+}
+
+// Wait 进入暂停状态并阻塞，直到收到恢复执行的命令或上下文取消
+func (d *Debugger) Wait(ctx context.Context, state *PausedState) (DebugCommand, error) {
+	d.mu.Lock()
+	// 丢弃上一个暂停点恢复时残留的命令
+	select {
+	case <-d.commandCh:
+	default:
+	}
+	d.paused = state
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		d.paused = nil
+		d.mu.Unlock()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case cmd := <-d.commandCh:
+		d.mu.Lock()
+		d.mode = cmd
+		d.baseDepth = state.Depth
+		d.mu.Unlock()
+		return cmd, nil
+	}
+}
+
+// Command 提交调试命令：pause 在运行中生效，其余命令用于恢复暂停的执行
+func (d *Debugger) Command(cmd DebugCommand) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch cmd {
+	case DebugPause:
+		if d.paused == nil {
+			d.pauseAsked = true
+		}
+		return nil
+	case DebugContinue, DebugStepOver, DebugStepInto, DebugStepOut:
+	default:
+		return fmt.Errorf("unknown debug command: %s", cmd)
+	}
+
+	if d.paused == nil {
+		return fmt.Errorf("execution is not paused")
+	}
+	select {
+	case d.commandCh <- cmd:
+		return nil
+	default:
+		return fmt.Errorf("debug command channel is full")
+	}
+}
+
+// Paused 返回当前暂停点，未暂停时返回 nil
+func (d *Debugger) Paused() *PausedState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.paused
+}
+
+// Variables 返回暂停点的变量快照
+func (d *Debugger) Variables() (map[string]interface{}, error) {
+	paused := d.Paused()
+	if paused == nil {
+		return nil, fmt.Errorf("execution is not paused")
+	}
+	return paused.scope.Variables(), nil
+}
+
+// SetVariables 在暂停点修改变量，对后续步骤生效，返回修改后的变量快照
+func (d *Debugger) SetVariables(vars map[string]interface{}) (map[string]interface{}, error) {
+	paused := d.Paused()
+	if paused == nil {
+		return nil, fmt.Errorf("execution is not paused")
+	}
+	for k, v := range vars {
+		paused.scope.SetVariable(k, v)
+	}
+	return paused.scope.Variables(), nil
+}
@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+type fakeScope struct {
+	vars map[string]any
+}
+
+func (s *fakeScope) Variables() map[string]any          { return s.vars }
+func (s *fakeScope) SetVariable(name string, value any) { s.vars[name] = value }
+func (s *fakeScope) Evaluate(expr string) (bool, error) { return s.vars[expr] == true, nil }
+
+type debugStep struct {
+	id, parent string
+}
+
+// runSteps 按执行顺序模拟步骤开始，暂停时依次提交 commands 中的命令，返回暂停过的步骤
+func runSteps(t *testing.T, d *Debugger, scope *fakeScope, steps []debugStep, commands ...DebugCommand) []string {
+	t.Helper()
+	var paused []string
+	for _, s := range steps {
+		state := d.Check(&types.Step{ID: s.id}, &types.DebugFrame{ParentID: s.parent, Scope: scope})
+		if state == nil {
+			continue
+		}
+		paused = append(paused, s.id)
+		if len(commands) == 0 {
+			t.Fatalf("paused at %s without command", s.id)
+		}
+		cmd := commands[0]
+		commands = commands[1:]
+		go func() {
+			for d.Paused() == nil {
+				time.Sleep(time.Millisecond)
+			}
+			if err := d.Command(cmd); err != nil {
+				t.Error(err)
+			}
+		}()
+		if _, err := d.Wait(context.Background(), state); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paused
+}
+
+// a, loop(c1, c2) x2, b
+var loopSteps = []debugStep{
+	{"a", ""}, {"loop", ""},
+	{"c1", "loop"}, {"c2", "loop"},
+	{"c1", "loop"}, {"c2", "loop"},
+	{"b", ""},
+}
+
+func assertPaused(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("paused at %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("paused at %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDebugger_Breakpoints(t *testing.T) {
+	scope := &fakeScope{vars: map[string]any{}}
+	d := NewDebugger(&DebugOptions{Breakpoints: []Breakpoint{{StepID: "c2"}, {StepID: "b", Condition: "hit"}}})
+
+	paused := runSteps(t, d, scope, loopSteps, DebugContinue, DebugContinue)
+	assertPaused(t, paused, "c2", "c2")
+
+	// 条件成立时命中条件断点
+	scope.vars["hit"] = true
+	d = NewDebugger(&DebugOptions{Breakpoints: []Breakpoint{{StepID: "b", Condition: "hit"}}})
+	paused = runSteps(t, d, scope, loopSteps, DebugContinue)
+	assertPaused(t, paused, "b")
+}
+
+func TestDebugger_Stepping(t *testing.T) {
+	scope := &fakeScope{vars: map[string]any{}}
+
+	d := NewDebugger(&DebugOptions{PauseOnStart: true})
+	paused := runSteps(t, d, scope, loopSteps, DebugStepOver, DebugStepOver, DebugStepOver)
+	assertPaused(t, paused, "a", "loop", "b")
+
+	d = NewDebugger(&DebugOptions{PauseOnStart: true})
+	paused = runSteps(t, d, scope, loopSteps, DebugStepOver, DebugStepInto, DebugStepOver, DebugStepOut, DebugContinue)
+	assertPaused(t, paused, "a", "loop", "c1", "c2", "b")
+}
+
+func TestDebugger_CommandRequiresPause(t *testing.T) {
+	d := NewDebugger(nil)
+	if err := d.Command(DebugContinue); err == nil {
+		t.Fatal("expected error when not paused")
+	}
+	if err := d.Command("jump"); err == nil {
+		t.Fatal("expected error for unknown command")
+	}
+	if _, err := d.SetVariables(map[string]interface{}{"a": 1}); err == nil {
+		t.Fatal("expected error when not paused")
+	}
+
+	// pause 在运行中请求，下一个步骤暂停
+	if err := d.Command(DebugPause); err != nil {
+		t.Fatal(err)
+	}
+	state := d.Check(&types.Step{ID: "a"}, &types.DebugFrame{Scope: &fakeScope{}})
+	if state == nil || state.Reason != PauseReasonPause {
+		t.Fatalf("expected pause, got %+v", state)
+	}
+}
+
+func TestDebugger_EditVariables(t *testing.T) {
+	scope := &fakeScope{vars: map[string]any{"token": "old"}}
+	d := NewDebugger(&DebugOptions{PauseOnStart: true})
+	state := d.Check(&types.Step{ID: "a"}, &types.DebugFrame{Scope: scope})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := d.Wait(ctx, state)
+		done <- err
+	}()
+	for d.Paused() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	vars, err := d.SetVariables(map[string]interface{}{"token": "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["token"] != "new" {
+		t.Fatalf("token = %v", vars["token"])
+	}
+
+	// 停止执行时退出暂停
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -18,6 +18,7 @@ const (
 	SessionStatusFailed    SessionStatus = "failed"
 	SessionStatusStopped   SessionStatus = "stopped"
 	SessionStatusWaiting   SessionStatus = "waiting_interaction"
+	SessionStatusPaused    SessionStatus = "paused"
 )
 
 // InteractionResponse 交互响应
@@ -50,6 +51,9 @@ type Session struct {
 	// 环境变量（从环境配置加载的初始变量）
 	envVariables map[string]interface{}
 
+	// 调试器（断点调试执行时非空）
+	debugger *Debugger
+
 	mu sync.Mutex
 }
 
@@ -204,7 +208,7 @@ func (m *SessionManager) GetActiveSessions() int {
 	count := 0
 	for _, session := range m.sessions {
 		session.mu.Lock()
-		if session.Status == SessionStatusRunning || session.Status == SessionStatusWaiting {
+		if session.isActive() {
 			count++
 		}
 		session.mu.Unlock()
@@ -238,11 +242,32 @@ func (m *SessionManager) IsRunning(sessionID string) bool {
 
 	session.mu.Lock()
 	defer session.mu.Unlock()
-	return session.Status == SessionStatusRunning || session.Status == SessionStatusWaiting
+	return session.isActive()
+}
+
+// GetDebugger 获取会话的调试器
+func (m *SessionManager) GetDebugger(sessionID string) (*Debugger, error) {
+	m.mu.RLock()
+	session, ok := m.sessions[sessionID]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	debugger := session.GetDebugger()
+	if debugger == nil {
+		return nil, fmt.Errorf("session is not in debug mode")
+	}
+	return debugger, nil
 }
 
 // ============ Session 方法 ============
 
+// isActive 是否仍在执行（调用方持有锁）
+func (s *Session) isActive() bool {
+	return s.Status == SessionStatusRunning || s.Status == SessionStatusWaiting || s.Status == SessionStatusPaused
+}
+
 // GetSSEWriter 获取 SSE Writer
 func (s *Session) GetSSEWriter() *sse.Writer {
 	s.mu.Lock()
@@ -342,6 +367,20 @@ func (s *Session) WaitForInteraction(ctx context.Context, timeout time.Duration)
 	}
 }
 
+// SetDebugger 设置调试器
+func (s *Session) SetDebugger(debugger *Debugger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.debugger = debugger
+}
+
+// GetDebugger 获取调试器，非调试执行时返回 nil
+func (s *Session) GetDebugger() *Debugger {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.debugger
+}
+
 // SetVariable 设置变量
 func (s *Session) SetVariable(key string, value interface{}) {
 	s.mu.Lock()
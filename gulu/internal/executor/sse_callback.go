@@ -10,8 +10,10 @@ import (
 
 // SSECallback SSE 回调实现（实现 ExecutionCallback + AIStreamCallback）
 type SSECallback struct {
-	writer  *sse.Writer
-	session *Session
+	writer       *sse.Writer
+	session      *Session
+	onCheckpoint func(checkpoint *types.Checkpoint)
+	onStepDone   func(step *types.Step, result *types.StepResult)
 }
 
 // NewSSECallback 创建 SSE 回调
@@ -22,6 +24,16 @@ func NewSSECallback(writer *sse.Writer, session *Session) *SSECallback {
 	}
 }
 
+// SetCheckpointHandler 设置检查点处理函数，为 nil 时忽略检查点
+func (c *SSECallback) SetCheckpointHandler(handler func(checkpoint *types.Checkpoint)) {
+	c.onCheckpoint = handler
+}
+
+// SetStepCompleteHandler 设置步骤完成处理函数（含嵌套步骤），为 nil 时忽略
+func (c *SSECallback) SetStepCompleteHandler(handler func(step *types.Step, result *types.StepResult)) {
+	c.onStepDone = handler
+}
+
 // ============ ExecutionCallback ============
 
 func (c *SSECallback) OnStepStart(ctx context.Context, step *types.Step, parentID string, iteration int) {
@@ -58,6 +70,9 @@ func (c *SSECallback) OnStepComplete(ctx context.Context, step *types.Step, resu
 		stepResult.Error = result.Error.Error()
 	}
 	c.session.AddStepResult(stepResult)
+	if c.onStepDone != nil {
+		c.onStepDone(step, result)
+	}
 
 	status := "success"
 	switch result.Status {
@@ -116,6 +131,59 @@ func (c *SSECallback) OnExecutionComplete(ctx context.Context, summary *types.Ex
 	})
 }
 
+// ============ DebugCallback ============
+
+// OnBeforeStep 断点调试：命中断点或单步时推送暂停事件并阻塞，直到收到恢复命令
+func (c *SSECallback) OnBeforeStep(ctx context.Context, step *types.Step, frame *types.DebugFrame) error {
+	debugger := c.session.GetDebugger()
+	if debugger == nil {
+		return nil
+	}
+	state := debugger.Check(step, frame)
+	if state == nil {
+		return nil
+	}
+
+	c.session.SetStatus(SessionStatusPaused)
+	c.writer.WriteEvent(&sse.Event{
+		Type: sse.EventStepPaused,
+		Data: &sse.StepPausedData{
+			StepID:         state.StepID,
+			StepName:       state.StepName,
+			StepType:       state.StepType,
+			ParentID:       state.ParentID,
+			Iteration:      state.Iteration,
+			Depth:          state.Depth,
+			Reason:         state.Reason,
+			ConditionError: state.ConditionError,
+			Variables:      frame.Scope.Variables(),
+		},
+	})
+
+	cmd, err := debugger.Wait(ctx, state)
+	if err != nil {
+		return err
+	}
+	c.session.SetStatus(SessionStatusRunning)
+	c.writer.WriteEvent(&sse.Event{
+		Type: sse.EventStepResumed,
+		Data: &sse.StepResumedData{
+			StepID:  state.StepID,
+			Command: string(cmd),
+		},
+	})
+	return nil
+}
+
+// ============ CheckpointCallback ============
+
+// OnCheckpoint 顶层步骤完成后交给检查点处理函数持久化
+func (c *SSECallback) OnCheckpoint(ctx context.Context, checkpoint *types.Checkpoint) {
+	if c.onCheckpoint != nil {
+		c.onCheckpoint(checkpoint)
+	}
+}
+
 // ============ AIStreamCallback ============
 
 func (c *SSECallback) OnAIChunk(ctx context.Context, stepID, blockID, chunk string) {
@@ -226,10 +294,12 @@ func (c *SSECallback) OnMessageComplete(ctx context.Context, stepID string, resu
 		Data: map[string]interface{}{
 			"stepId":  stepID,
 			"content": result.Content,
-			"usage": map[string]int{
+			"usage": map[string]interface{}{
 				"prompt_tokens":     result.PromptTokens,
+				"cached_tokens":     result.CachedTokens,
 				"completion_tokens": result.CompletionTokens,
 				"total_tokens":      result.TotalTokens,
+				"cost":              result.Cost,
 			},
 			"model": result.Model,
 		},
@@ -332,3 +402,4 @@ func (c *SSECallback) WriteErrorCode(code sse.ErrorCode, message string, details
 
 var _ types.ExecutionCallback = (*SSECallback)(nil)
 var _ types.AIStreamCallback = (*SSECallback)(nil)
+var _ types.DebugCallback = (*SSECallback)(nil)
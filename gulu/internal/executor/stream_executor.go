@@ -18,6 +18,12 @@ type ExecuteRequest struct {
 	EnvID      int64                  `json:"env_id"`
 	Variables  map[string]interface{} `json:"variables,omitempty"`
 	Timeout    int                    `json:"timeout,omitempty"`
+	// Debug 断点调试选项，仅流式执行支持
+	Debug *DebugOptions `json:"debug,omitempty"`
+	// OnCheckpoint 顶层步骤完成后的检查点回调（持久化执行记录时设置）
+	OnCheckpoint func(checkpoint *types.Checkpoint) `json:"-"`
+	// OnStepComplete 步骤完成回调（含嵌套步骤），用于记录 AI 用量等
+	OnStepComplete func(step *types.Step, result *types.StepResult) `json:"-"`
 }
 
 // ExecutionSummary 执行汇总
@@ -74,7 +80,13 @@ func (e *StreamExecutor) ExecuteStream(ctx context.Context, req *ExecuteRequest,
 	defer cancel()
 	e.sessionManager.SetCancel(session.ID, cancel)
 
+	if req.Debug != nil {
+		session.SetDebugger(NewDebugger(req.Debug))
+	}
+
 	callback := NewSSECallback(writer, session)
+	callback.SetCheckpointHandler(req.OnCheckpoint)
+	callback.SetStepCompleteHandler(req.OnStepComplete)
 	wf.Callback = callback
 
 	mergeVariables(wf, req.Variables)
@@ -123,6 +135,8 @@ func (e *StreamExecutor) ExecuteBlocking(ctx context.Context, req *ExecuteReques
 	e.sessionManager.SetCancel(session.ID, cancel)
 
 	callback := NewSSECallback(writer, session)
+	callback.SetCheckpointHandler(req.OnCheckpoint)
+	callback.SetStepCompleteHandler(req.OnStepComplete)
 	wf.Callback = callback
 
 	mergeVariables(wf, req.Variables)
@@ -9,6 +9,8 @@ import (
 
 	"yqhp/common/response"
 	"yqhp/gulu/internal/logic"
+	"yqhp/gulu/internal/middleware"
+	"yqhp/gulu/internal/model"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -42,6 +44,13 @@ func AiChatStream(c *fiber.Ctx) error {
 		return response.Error(c, "该模型已禁用")
 	}
 
+	// 超出项目预算硬上限时拒绝对话
+	projectID := middleware.GetCurrentProjectID(c)
+	userID := middleware.GetCurrentUserID(c)
+	if _, err := logic.NewAiUsageLogic(c.UserContext()).CheckBudget(projectID); err != nil {
+		return response.Error(c, err.Error())
+	}
+
 	// 在进入 stream goroutine 之前，提前捕获所有需要的值
 	apiBaseURL := aiModel.APIBaseURL
 	apiKey := aiModel.APIKey
@@ -66,13 +75,32 @@ func AiChatStream(c *fiber.Ctx) error {
 
 		chatLogic := logic.NewAiChatLogic(context.Background())
 
-		err := chatLogic.ChatStream(apiBaseURL, apiKey, modelID, &chatReq, func(data string) error {
+		usage, err := chatLogic.ChatStream(apiBaseURL, apiKey, modelID, &chatReq, func(data string) error {
 			_, writeErr := fmt.Fprint(w, data)
 			if writeErr != nil {
 				return writeErr
 			}
 			return w.Flush()
 		})
+		if usage != nil {
+			cached := usage.CachedTokens()
+			record := &model.TAiUsage{
+				ProjectID:        projectID,
+				UserID:           userID,
+				Source:           model.AiUsageSourceChat,
+				ConversationID:   chatReq.ConversationID,
+				AiModelID:        aiModel.ID,
+				Model:            modelID,
+				PromptTokens:     usage.PromptTokens,
+				CachedTokens:     cached,
+				CompletionTokens: usage.CompletionTokens,
+				TotalTokens:      usage.TotalTokens,
+				Cost:             logic.ModelCost(aiModel, usage.PromptTokens, cached, usage.CompletionTokens),
+			}
+			if recordErr := logic.NewAiUsageLogic(context.Background()).Record(record); recordErr != nil {
+				log.Printf("[AiChatStream] record usage failed: %v", recordErr)
+			}
+		}
 
 		if err != nil {
 			errData := fmt.Sprintf("data: {\"error\": \"%s\"}\n\n", err.Error())
@@ -137,3 +137,147 @@ func AIConversationSaveMessage(c *fiber.Ctx) error {
 	}
 	return response.Success(c, msg)
 }
+
+// parseConversationMessageParams 解析会话ID与消息ID
+func parseConversationMessageParams(c *fiber.Ctx) (convID, msgID int64, msg string) {
+	convID, err := strconv.ParseInt(c.Params("convId"), 10, 64)
+	if err != nil {
+		return 0, 0, "无效的会话ID"
+	}
+	msgID, err = strconv.ParseInt(c.Params("msgId"), 10, 64)
+	if err != nil {
+		return 0, 0, "无效的消息ID"
+	}
+	return convID, msgID, ""
+}
+
+// AIConversationEditMessage 编辑消息（创建同级分支，原消息保留）
+// POST /api/conversations/:convId/messages/:msgId/edit
+func AIConversationEditMessage(c *fiber.Ctx) error {
+	convID, msgID, msg := parseConversationMessageParams(c)
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	var req logic.EditMessageReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	l := logic.NewAIConversationLogic(c.UserContext())
+	result, err := l.EditMessage(convID, msgID, &req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
+// AIConversationRegenerate 重新生成回复：当前分支回退到该消息之前，新回复保存为同级分支
+// POST /api/conversations/:convId/messages/:msgId/regenerate
+func AIConversationRegenerate(c *fiber.Ctx) error {
+	convID, msgID, msg := parseConversationMessageParams(c)
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	l := logic.NewAIConversationLogic(c.UserContext())
+	detail, err := l.Regenerate(convID, msgID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, detail)
+}
+
+// AIConversationSwitchBranch 切换当前分支
+// PUT /api/conversations/:convId/active-branch
+func AIConversationSwitchBranch(c *fiber.Ctx) error {
+	convID, err := strconv.ParseInt(c.Params("convId"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的会话ID")
+	}
+
+	var req logic.SwitchBranchReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	l := logic.NewAIConversationLogic(c.UserContext())
+	detail, err := l.SwitchBranch(convID, req.MessageID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, detail)
+}
+
+// AIConversationFork 从指定消息派生新会话
+// POST /api/conversations/:convId/fork
+func AIConversationFork(c *fiber.Ctx) error {
+	convID, err := strconv.ParseInt(c.Params("convId"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的会话ID")
+	}
+
+	var req logic.ForkConversationReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	l := logic.NewAIConversationLogic(c.UserContext())
+	conv, err := l.Fork(convID, &req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, conv)
+}
+
+// AIConversationExport 导出会话，format 可选 json（默认，含全部分支）/ markdown（当前分支），branch=active 时 JSON 只导出当前分支
+// GET /api/conversations/:convId/export
+func AIConversationExport(c *fiber.Ctx) error {
+	convID, err := strconv.ParseInt(c.Params("convId"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的会话ID")
+	}
+
+	format := c.Query("format", logic.ConversationExportJSON)
+	l := logic.NewAIConversationLogic(c.UserContext())
+	data, filename, err := l.Export(convID, format, c.Query("branch") == "active")
+	if err != nil {
+		return response.Error(c, "导出失败: "+err.Error())
+	}
+
+	if format == logic.ConversationExportMarkdown {
+		c.Set("Content-Type", "text/markdown; charset=utf-8")
+	} else {
+		c.Set("Content-Type", "application/json")
+	}
+	c.Set("Content-Disposition", "attachment; filename="+filename)
+	return c.Send(data)
+}
+
+// AIConversationImport 导入会话（JSON 或本系统导出的 Markdown，支持 content 字段或 multipart 上传 file 字段）
+// POST /api/workflows/:id/conversations/import
+func AIConversationImport(c *fiber.Ctx) error {
+	workflowID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的工作流ID")
+	}
+
+	var req logic.ImportConversationReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+	if data, ok, err := readFormFile(c, "file"); err != nil {
+		return response.Error(c, "读取文件失败: "+err.Error())
+	} else if ok {
+		req.Content = data
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	l := logic.NewAIConversationLogic(c.UserContext())
+	conv, err := l.Import(workflowID, req.Content, userID)
+	if err != nil {
+		return response.Error(c, "导入失败: "+err.Error())
+	}
+	return response.Success(c, conv)
+}
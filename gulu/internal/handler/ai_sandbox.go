@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"yqhp/common/response"
+	"yqhp/gulu/internal/logic"
+	"yqhp/gulu/internal/middleware"
+	"yqhp/workflow-engine/pkg/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AiSandboxPolicyGet 获取当前项目的 AI 工具沙箱策略
+// GET /api/ai-sandbox/policy
+func AiSandboxPolicyGet(c *fiber.Ctx) error {
+	projectID := middleware.GetCurrentProjectID(c)
+	if projectID <= 0 {
+		return response.Error(c, "请选择项目")
+	}
+
+	sandboxLogic := logic.NewAiSandboxLogic(c.UserContext())
+	info, err := sandboxLogic.GetPolicyInfo(projectID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, info)
+}
+
+// AiSandboxPolicySave 设置当前项目的 AI 工具沙箱策略
+// PUT /api/ai-sandbox/policy
+func AiSandboxPolicySave(c *fiber.Ctx) error {
+	var req types.SandboxPolicy
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	projectID := middleware.GetCurrentProjectID(c)
+	userID := middleware.GetCurrentUserID(c)
+	sandboxLogic := logic.NewAiSandboxLogic(c.UserContext())
+
+	info, err := sandboxLogic.SavePolicy(projectID, &req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, info)
+}
+
+// AiSandboxPolicyDelete 删除当前项目的 AI 工具沙箱策略，恢复默认策略
+// DELETE /api/ai-sandbox/policy
+func AiSandboxPolicyDelete(c *fiber.Ctx) error {
+	projectID := middleware.GetCurrentProjectID(c)
+	if projectID <= 0 {
+		return response.Error(c, "请选择项目")
+	}
+
+	sandboxLogic := logic.NewAiSandboxLogic(c.UserContext())
+	if err := sandboxLogic.DeletePolicy(projectID); err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, nil)
+}
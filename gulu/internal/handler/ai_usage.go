@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"strconv"
+
+	"yqhp/common/response"
+	"yqhp/gulu/internal/logic"
+	"yqhp/gulu/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AiUsageSummary AI 用量汇总
+// GET /api/ai-usage/summary?groupBy=day|model|user|execution|conversation|source
+func AiUsageSummary(c *fiber.Ctx) error {
+	var req logic.AiUsageSummaryReq
+	if err := c.QueryParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+	if projectID := middleware.GetCurrentProjectID(c); projectID > 0 {
+		req.ProjectID = projectID
+	}
+
+	usageLogic := logic.NewAiUsageLogic(c.UserContext())
+	summary, err := usageLogic.Summary(&req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, summary)
+}
+
+// AiUsageList AI 用量明细
+// GET /api/ai-usage/records
+func AiUsageList(c *fiber.Ctx) error {
+	var req logic.AiUsageListReq
+	if err := c.QueryParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if projectID := middleware.GetCurrentProjectID(c); projectID > 0 {
+		req.ProjectID = projectID
+	}
+
+	usageLogic := logic.NewAiUsageLogic(c.UserContext())
+	list, total, err := usageLogic.List(&req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Page(c, list, total, req.Page, req.PageSize)
+}
+
+// AiBudgetGet 获取当前项目的 AI 预算及本周期消耗
+// GET /api/ai-usage/budget
+func AiBudgetGet(c *fiber.Ctx) error {
+	projectID := middleware.GetCurrentProjectID(c)
+	if projectID <= 0 {
+		return response.Error(c, "请选择项目")
+	}
+
+	usageLogic := logic.NewAiUsageLogic(c.UserContext())
+	status, err := usageLogic.GetBudget(projectID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, status)
+}
+
+// AiBudgetSave 设置当前项目的 AI 预算
+// PUT /api/ai-usage/budget
+func AiBudgetSave(c *fiber.Ctx) error {
+	var req logic.SaveAiBudgetReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	projectID := middleware.GetCurrentProjectID(c)
+	userID := middleware.GetCurrentUserID(c)
+	usageLogic := logic.NewAiUsageLogic(c.UserContext())
+
+	status, err := usageLogic.SaveBudget(projectID, &req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, status)
+}
+
+// AiUsageCharge 用量账本：预留或结算一次模型调用的费用（供 workflow-engine 内部调用）
+// POST /api/internal/ai-usage/:projectId/charge
+func AiUsageCharge(c *fiber.Ctx) error {
+	projectID, err := strconv.ParseInt(c.Params("projectId"), 10, 64)
+	if err != nil || projectID < 0 {
+		return response.Error(c, "无效的项目ID")
+	}
+
+	var req logic.AiUsageChargeReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	usageLogic := logic.NewAiUsageLogic(c.UserContext())
+	charge, err := usageLogic.Charge(projectID, &req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, charge)
+}
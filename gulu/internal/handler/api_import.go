@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"io"
+
+	"yqhp/common/response"
+	"yqhp/gulu/internal/logic"
+	"yqhp/gulu/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseAPIImportReq 解析导入请求：支持 JSON（content/url）与 multipart 上传文件（file 字段，Postman 环境文件为 environment_file 字段）
+func parseAPIImportReq(c *fiber.Ctx) (*logic.APIImportReq, error) {
+	var req logic.APIImportReq
+	if err := c.BodyParser(&req); err != nil {
+		return nil, err
+	}
+
+	if data, ok, err := readFormFile(c, "file"); err != nil {
+		return nil, err
+	} else if ok {
+		req.Content = data
+	}
+	if data, ok, err := readFormFile(c, "environment_file"); err != nil {
+		return nil, err
+	} else if ok {
+		req.Environment = data
+	}
+
+	if req.ProjectID <= 0 {
+		req.ProjectID = middleware.GetCurrentProjectID(c)
+	}
+	return &req, nil
+}
+
+// readFormFile 读取 multipart 上传文件内容，未上传时 ok 为 false
+func readFormFile(c *fiber.Ctx, field string) (string, bool, error) {
+	file, err := c.FormFile(field)
+	if err != nil || file == nil {
+		return "", false, nil
+	}
+	f, err := file.Open()
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", false, err
+	}
+	return string(data), true, nil
+}
+
+// APIImportPreview 预览接口文档导入结果
+// POST /api/workflows/import/api/preview
+func APIImportPreview(c *fiber.Ctx) error {
+	req, err := parseAPIImportReq(c)
+	if err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	importLogic := logic.NewAPIImportLogic(c.UserContext())
+
+	result, err := importLogic.Preview(req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, result)
+}
+
+// APIImport 导入接口文档生成工作流与域名配置
+// POST /api/workflows/import/api
+func APIImport(c *fiber.Ctx) error {
+	req, err := parseAPIImportReq(c)
+	if err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	importLogic := logic.NewAPIImportLogic(c.UserContext())
+
+	result, err := importLogic.Import(req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, result)
+}
@@ -290,6 +290,48 @@ func KnowledgeDocumentReprocess(c *fiber.Ctx) error {
 	return response.Success(c, nil)
 }
 
+// KnowledgeDocumentCancel 取消文档处理
+func KnowledgeDocumentCancel(c *fiber.Ctx) error {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+	docID, err := strconv.ParseInt(c.Params("docId"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的文档ID")
+	}
+
+	kbLogic := logic.NewKnowledgeBaseLogic(c.UserContext())
+	if err := kbLogic.CancelDocumentProcessing(kbID, docID); err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, nil)
+}
+
+// KnowledgeDocumentUpdateMetadata 修改文档元数据与访问控制（已索引的向量同步更新）
+func KnowledgeDocumentUpdateMetadata(c *fiber.Ctx) error {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+	docID, err := strconv.ParseInt(c.Params("docId"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的文档ID")
+	}
+
+	var req logic.UpdateDocumentMetadataReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	kbLogic := logic.NewKnowledgeBaseLogic(c.UserContext())
+	result, err := kbLogic.UpdateDocumentMetadata(kbID, docID, &req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
 func KnowledgeDocumentPreviewChunks(c *fiber.Ctx) error {
 	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
@@ -509,6 +551,48 @@ func KnowledgeBaseDiagnose(c *fiber.Ctx) error {
 	return response.Success(c, diag)
 }
 
+// KnowledgeVectorStoreMigrate 将知识库向量迁移到另一个向量库后端
+// POST /api/knowledge-bases/:id/vector-store/migrate
+func KnowledgeVectorStoreMigrate(c *fiber.Ctx) error {
+	kbID, err := parseKBID(c)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+
+	var req logic.MigrateVectorStoreReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	kbLogic := logic.NewKnowledgeBaseLogic(c.UserContext())
+	result, err := kbLogic.MigrateVectorStore(kbID, &req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
+// KnowledgeVectorQuery 按查询向量检索知识库（供 workflow-engine knowledge_search 工具内部调用）
+// POST /api/internal/knowledge-bases/:id/vector-query
+func KnowledgeVectorQuery(c *fiber.Ctx) error {
+	kbID, err := parseKBID(c)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+
+	var req logic.VectorQueryReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	kbLogic := logic.NewKnowledgeBaseLogic(c.UserContext())
+	hits, err := kbLogic.QueryVectors(kbID, &req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, hits)
+}
+
 // -----------------------------------------------
 // 图片资源访问
 // -----------------------------------------------
@@ -543,6 +627,21 @@ func KnowledgeImageServe(c *fiber.Ctx) error {
 	return c.Send(data)
 }
 
+// KnowledgeEmbed 文本向量化（供 workflow-engine semantic_similarity 断言内部调用）
+// POST /api/internal/embeddings
+func KnowledgeEmbed(c *fiber.Ctx) error {
+	var req logic.EmbedReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+	kbLogic := logic.NewKnowledgeBaseLogic(c.UserContext())
+	vectors, err := kbLogic.EmbedTexts(&req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, fiber.Map{"vectors": vectors})
+}
+
 // -----------------------------------------------
 // 图知识库（Phase 3）
 // -----------------------------------------------
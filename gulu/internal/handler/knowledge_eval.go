@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"yqhp/common/response"
+	"yqhp/gulu/internal/logic"
+	"yqhp/gulu/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseKnowledgeEvalParams 解析知识库ID与指定的子资源ID（datasetId / itemId / runId）
+func parseKnowledgeEvalParams(c *fiber.Ctx, key, label string) (kbID, id int64, msg string) {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return 0, 0, "无效的知识库ID"
+	}
+	id, err = strconv.ParseInt(c.Params(key), 10, 64)
+	if err != nil {
+		return 0, 0, "无效的" + label + "ID"
+	}
+	return kbID, id, ""
+}
+
+// KnowledgeEvalDatasetList 获取评测集列表
+// GET /api/knowledge-bases/:id/eval/datasets
+func KnowledgeEvalDatasetList(c *fiber.Ctx) error {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+
+	list, err := logic.NewKnowledgeBaseLogic(c.UserContext()).ListEvalDatasets(kbID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, list)
+}
+
+// KnowledgeEvalDatasetCreate 创建评测集
+// POST /api/knowledge-bases/:id/eval/datasets
+func KnowledgeEvalDatasetCreate(c *fiber.Ctx) error {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+
+	var req logic.EvalDatasetReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	result, err := logic.NewKnowledgeBaseLogic(c.UserContext()).CreateEvalDataset(kbID, &req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
+// KnowledgeEvalDatasetUpdate 更新评测集
+// PUT /api/knowledge-bases/:id/eval/datasets/:datasetId
+func KnowledgeEvalDatasetUpdate(c *fiber.Ctx) error {
+	kbID, datasetID, msg := parseKnowledgeEvalParams(c, "datasetId", "评测集")
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	var req logic.EvalDatasetReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	if err := logic.NewKnowledgeBaseLogic(c.UserContext()).UpdateEvalDataset(kbID, datasetID, &req); err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, nil)
+}
+
+// KnowledgeEvalDatasetDelete 删除评测集（连同题目与运行记录）
+// DELETE /api/knowledge-bases/:id/eval/datasets/:datasetId
+func KnowledgeEvalDatasetDelete(c *fiber.Ctx) error {
+	kbID, datasetID, msg := parseKnowledgeEvalParams(c, "datasetId", "评测集")
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	if err := logic.NewKnowledgeBaseLogic(c.UserContext()).DeleteEvalDataset(kbID, datasetID); err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, nil)
+}
+
+// KnowledgeEvalItemList 获取评测题目列表，status 可筛选 active / candidate
+// GET /api/knowledge-bases/:id/eval/datasets/:datasetId/items
+func KnowledgeEvalItemList(c *fiber.Ctx) error {
+	kbID, datasetID, msg := parseKnowledgeEvalParams(c, "datasetId", "评测集")
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	var req logic.EvalItemListReq
+	if err := c.QueryParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	list, total, err := logic.NewKnowledgeBaseLogic(c.UserContext()).ListEvalItems(kbID, datasetID, &req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Page(c, list, total, req.Page, req.PageSize)
+}
+
+// KnowledgeEvalItemCreate 批量添加评测题目
+// POST /api/knowledge-bases/:id/eval/datasets/:datasetId/items
+func KnowledgeEvalItemCreate(c *fiber.Ctx) error {
+	kbID, datasetID, msg := parseKnowledgeEvalParams(c, "datasetId", "评测集")
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	var req logic.CreateEvalItemsReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	result, err := logic.NewKnowledgeBaseLogic(c.UserContext()).CreateEvalItems(kbID, datasetID, &req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
+// KnowledgeEvalItemGenerate 由 LLM 根据抽样分块生成候选题目
+// POST /api/knowledge-bases/:id/eval/datasets/:datasetId/generate
+func KnowledgeEvalItemGenerate(c *fiber.Ctx) error {
+	kbID, datasetID, msg := parseKnowledgeEvalParams(c, "datasetId", "评测集")
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	var req logic.GenerateEvalItemsReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	result, err := logic.NewKnowledgeBaseLogic(c.UserContext()).GenerateEvalItems(kbID, datasetID, &req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
+// KnowledgeEvalItemUpdate 更新评测题目（确认候选题目时 status 传 active）
+// PUT /api/knowledge-bases/:id/eval/items/:itemId
+func KnowledgeEvalItemUpdate(c *fiber.Ctx) error {
+	kbID, itemID, msg := parseKnowledgeEvalParams(c, "itemId", "评测题目")
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	var req logic.EvalItemReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	result, err := logic.NewKnowledgeBaseLogic(c.UserContext()).UpdateEvalItem(kbID, itemID, &req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
+// KnowledgeEvalItemDelete 删除评测题目
+// DELETE /api/knowledge-bases/:id/eval/items/:itemId
+func KnowledgeEvalItemDelete(c *fiber.Ctx) error {
+	kbID, itemID, msg := parseKnowledgeEvalParams(c, "itemId", "评测题目")
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	if err := logic.NewKnowledgeBaseLogic(c.UserContext()).DeleteEvalItem(kbID, itemID); err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, nil)
+}
+
+// KnowledgeEvalRunList 获取评测运行列表，datasetId 可筛选评测集
+// GET /api/knowledge-bases/:id/eval/runs
+func KnowledgeEvalRunList(c *fiber.Ctx) error {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+
+	datasetID := int64(c.QueryInt("datasetId", 0))
+	list, err := logic.NewKnowledgeBaseLogic(c.UserContext()).ListEvalRuns(kbID, datasetID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, list)
+}
+
+// KnowledgeEvalRunCreate 创建评测运行（后台执行）
+// POST /api/knowledge-bases/:id/eval/runs
+func KnowledgeEvalRunCreate(c *fiber.Ctx) error {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+
+	var req logic.CreateEvalRunReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	result, err := logic.NewKnowledgeBaseLogic(c.UserContext()).CreateEvalRun(kbID, &req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
+// KnowledgeEvalRunGet 获取评测运行详情（含每题结果）
+// GET /api/knowledge-bases/:id/eval/runs/:runId
+func KnowledgeEvalRunGet(c *fiber.Ctx) error {
+	kbID, runID, msg := parseKnowledgeEvalParams(c, "runId", "评测运行")
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	result, err := logic.NewKnowledgeBaseLogic(c.UserContext()).GetEvalRun(kbID, runID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
+// KnowledgeEvalRunDelete 删除评测运行
+// DELETE /api/knowledge-bases/:id/eval/runs/:runId
+func KnowledgeEvalRunDelete(c *fiber.Ctx) error {
+	kbID, runID, msg := parseKnowledgeEvalParams(c, "runId", "评测运行")
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	if err := logic.NewKnowledgeBaseLogic(c.UserContext()).DeleteEvalRun(kbID, runID); err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, nil)
+}
+
+// KnowledgeEvalCompare 并排对比同一评测集的多次运行
+// GET /api/knowledge-bases/:id/eval/compare?run_ids=1,2
+func KnowledgeEvalCompare(c *fiber.Ctx) error {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+
+	var runIDs []int64
+	for _, s := range strings.Split(c.Query("run_ids"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return response.Error(c, "无效的评测运行ID: "+s)
+		}
+		runIDs = append(runIDs, id)
+	}
+
+	result, err := logic.NewKnowledgeBaseLogic(c.UserContext()).CompareEvalRuns(kbID, runIDs)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"strconv"
+
+	"yqhp/common/response"
+	"yqhp/gulu/internal/logic"
+	"yqhp/gulu/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseKnowledgeSourceParams 解析知识库ID与数据源ID
+func parseKnowledgeSourceParams(c *fiber.Ctx) (kbID, sourceID int64, msg string) {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return 0, 0, "无效的知识库ID"
+	}
+	sourceID, err = strconv.ParseInt(c.Params("sourceId"), 10, 64)
+	if err != nil {
+		return 0, 0, "无效的数据源ID"
+	}
+	return kbID, sourceID, ""
+}
+
+// KnowledgeSourceList 获取知识库的数据源列表
+// GET /api/knowledge-bases/:id/sources
+func KnowledgeSourceList(c *fiber.Ctx) error {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+
+	list, err := logic.NewKnowledgeSourceLogic(c.UserContext()).List(kbID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, list)
+}
+
+// KnowledgeSourceCreate 创建数据源
+// POST /api/knowledge-bases/:id/sources
+func KnowledgeSourceCreate(c *fiber.Ctx) error {
+	kbID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的知识库ID")
+	}
+
+	var req logic.KnowledgeSourceReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	result, err := logic.NewKnowledgeSourceLogic(c.UserContext()).Create(kbID, &req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, result)
+}
+
+// KnowledgeSourceUpdate 更新数据源
+// PUT /api/knowledge-bases/:id/sources/:sourceId
+func KnowledgeSourceUpdate(c *fiber.Ctx) error {
+	kbID, sourceID, msg := parseKnowledgeSourceParams(c)
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	var req logic.KnowledgeSourceReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	if err := logic.NewKnowledgeSourceLogic(c.UserContext()).Update(kbID, sourceID, &req); err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, nil)
+}
+
+// KnowledgeSourceDelete 删除数据源，keepDocuments=true 时保留已同步的文档
+// DELETE /api/knowledge-bases/:id/sources/:sourceId
+func KnowledgeSourceDelete(c *fiber.Ctx) error {
+	kbID, sourceID, msg := parseKnowledgeSourceParams(c)
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	keep := c.QueryBool("keepDocuments", false)
+	if err := logic.NewKnowledgeSourceLogic(c.UserContext()).Delete(kbID, sourceID, keep); err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, nil)
+}
+
+// KnowledgeSourceSync 立即同步数据源
+// POST /api/knowledge-bases/:id/sources/:sourceId/sync
+func KnowledgeSourceSync(c *fiber.Ctx) error {
+	kbID, sourceID, msg := parseKnowledgeSourceParams(c)
+	if msg != "" {
+		return response.Error(c, msg)
+	}
+
+	if err := logic.NewKnowledgeSourceLogic(c.UserContext()).Sync(kbID, sourceID); err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, nil)
+}
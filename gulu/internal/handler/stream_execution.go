@@ -71,6 +71,8 @@ type ExecuteRequest struct {
 	Stream bool `json:"stream,omitempty"`
 	// 是否持久化执行记录
 	Persist *bool `json:"persist,omitempty"`
+	// 断点调试选项（仅 SSE 流式执行支持）
+	Debug *executor.DebugOptions `json:"debug,omitempty"`
 
 	// === AI 工作流专用字段 ===
 	ConversationID string          `json:"conversationId,omitempty"`
@@ -113,6 +115,7 @@ func (h *StreamExecutionHandler) Execute(c *fiber.Ctx) error {
 
 	// 处理 Step 快捷方式：将单个步骤包装为工作流
 	var workflowDef interface{}
+	var rawWorkflow []byte
 	if req.Step != nil {
 		// 如果是 AI 节点，预解析托管模型配置和 Skill 数据
 		if isAINodeType(req.Step.Type) {
@@ -129,6 +132,9 @@ func (h *StreamExecutionHandler) Execute(c *fiber.Ctx) error {
 				logger.Warn("解析 MCP 服务器配置失败: %v", err)
 			}
 		}
+		for _, p := range append(append([]ProcessorConfig{}, req.Step.PreProcessors...), req.Step.PostProcessors...) {
+			h.resolveEvalProcessorConfig(c, p.Type, p.Config)
+		}
 
 		// 构建单步工作流
 		workflowDef = map[string]interface{}{
@@ -144,6 +150,13 @@ func (h *StreamExecutionHandler) Execute(c *fiber.Ctx) error {
 			},
 		}
 	} else if req.Workflow != nil {
+		// 解析前保存原始定义作为执行快照（不含解析出的模型凭证），用于从失败步骤恢复
+		raw, err := json.Marshal(req.Workflow)
+		if err != nil {
+			return response.Error(c, "工作流定义序列化失败: "+err.Error())
+		}
+		rawWorkflow = raw
+
 		// 对完整工作流定义中的 AI 节点进行模型解析和 Skill 解析
 		workflowDef = req.Workflow
 		h.resolveAIModelConfigsInWorkflow(c, workflowDef)
@@ -204,9 +217,20 @@ func (h *StreamExecutionHandler) Execute(c *fiber.Ctx) error {
 		execCtx.EngineWf.Steps = filterSteps(execCtx.EngineWf.Steps, req.SelectedSteps)
 	}
 
+	if execCtx.Persist && rawWorkflow != nil {
+		snapshot := &logic.ExecutionSnapshot{Workflow: rawWorkflow, SelectedSteps: req.SelectedSteps}
+		if err := execCtx.ExecLogic.SaveExecutionSnapshot(execCtx.SessionID, snapshot); err != nil {
+			logger.Warn("保存执行快照失败: sessionId=%s, error=%v", execCtx.SessionID, err)
+		}
+	}
+
 	if isSSE {
+		execCtx.ExecReq.Debug = req.Debug
 		return h.executeSSE(c, execCtx)
 	}
+	if req.Debug != nil {
+		return response.Error(c, "断点调试仅支持 SSE 流式执行")
+	}
 	return h.executeBlocking(c, execCtx)
 }
 
@@ -280,6 +304,27 @@ func (h *StreamExecutionHandler) prepareExecutionFromDefinition(c *fiber.Ctx, de
 
 	logger.Debug("工作流转换完成: id=%s, name=%s, steps=%d", engineWf.ID, engineWf.Name, len(engineWf.Steps))
 
+	// AI 节点的沙箱策略只能来自项目配置（含引用工作流中的节点），录制文件限定在项目目录下
+	if err := logic.ApplyAISandboxPolicy(c.UserContext(), middleware.GetCurrentProjectID(c), engineWf.Steps); err != nil {
+		return nil, &executionError{code: "SANDBOX_ERROR", message: err.Error()}
+	}
+	logic.ApplyAICassetteProject(middleware.GetCurrentProjectID(c), engineWf.Steps)
+
+	// AI 节点的模型单价与用量账本只能由服务端注入，项目预算查询失败或已超出硬上限时不执行
+	if err := logic.ApplyAIModelConfigs(c.UserContext(), engineWf.Steps); err != nil {
+		return nil, &executionError{code: "AI_MODEL_ERROR", message: err.Error()}
+	}
+	conversationID, _ := variables["__conversation_id__"].(string)
+	usageScope := &logic.AiUsageScope{
+		ProjectID:      middleware.GetCurrentProjectID(c),
+		UserID:         userID,
+		ExecutionID:    sessionID,
+		ConversationID: conversationID,
+	}
+	if err := logic.ApplyAIUsageLedger(c.UserContext(), usageScope, engineWf.Steps); err != nil {
+		return nil, &executionError{code: "BUDGET_ERROR", message: err.Error()}
+	}
+
 	// 解析步骤中的环境配置引用（域名、数据库、MQ）
 	// 将 domainCode/database_config/mq_config 等引用解析为执行器能直接消费的实际配置
 	if mergedConfig != nil {
@@ -327,7 +372,7 @@ func (h *StreamExecutionHandler) prepareExecutionFromDefinition(c *fiber.Ctx, de
 		Timeout:    timeout,
 	}
 
-	return &ExecutionContext{
+	execCtx := &ExecutionContext{
 		WorkflowID:  0,
 		SessionID:   sessionID,
 		EnvID:       envID,
@@ -338,7 +383,21 @@ func (h *StreamExecutionHandler) prepareExecutionFromDefinition(c *fiber.Ctx, de
 		ExecLogic:   execLogic,
 		ScheduleRes: nil,
 		ExecReq:     execReq,
-	}, nil
+	}
+	if persist {
+		persistCheckpoints(execCtx)
+	}
+	return execCtx, nil
+}
+
+// persistCheckpoints 将顶层步骤检查点写入执行记录（用于从失败步骤恢复）
+func persistCheckpoints(execCtx *ExecutionContext) {
+	execLogic, sessionID := execCtx.ExecLogic, execCtx.SessionID
+	execCtx.ExecReq.OnCheckpoint = func(checkpoint *types.Checkpoint) {
+		if err := execLogic.SaveCheckpoint(sessionID, checkpoint); err != nil {
+			logger.Warn("保存检查点失败: sessionId=%s, step=%s, error=%v", sessionID, checkpoint.StepID, err)
+		}
+	}
 }
 
 // executeSSE SSE 流式执行
@@ -545,6 +604,95 @@ func (h *StreamExecutionHandler) StopExecution(c *fiber.Ctx) error {
 	return response.Success(c, nil)
 }
 
+// ResumeExecutionRequest 恢复执行请求
+type ResumeExecutionRequest struct {
+	logic.ResumeExecutionReq
+	// 超时时间（秒）
+	Timeout int `json:"timeout,omitempty"`
+	// 是否使用 SSE 流式响应
+	Stream bool `json:"stream,omitempty"`
+}
+
+// ResumeExecution 从失败步骤恢复执行，之前步骤的变量与结果从检查点恢复，结果写入同一执行记录
+// POST /api/executions/:sessionId/resume
+func (h *StreamExecutionHandler) ResumeExecution(c *fiber.Ctx) error {
+	sessionID := c.Params("sessionId")
+	if sessionID == "" {
+		return response.Error(c, "会话ID不能为空")
+	}
+	if h.streamExecutor.IsRunning(sessionID) {
+		return response.Error(c, "执行仍在进行中")
+	}
+
+	var req ResumeExecutionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+	isSSE := req.Stream || strings.Contains(c.Get("Accept"), "text/event-stream")
+
+	execLogic := logic.NewExecutionLogic(c.UserContext())
+	plan, err := execLogic.PrepareResume(sessionID, &req.ResumeExecutionReq)
+	if err != nil {
+		return response.Error(c, "恢复执行失败: "+err.Error())
+	}
+
+	execCtx, err := h.prepareResumeExecution(c, plan, req.Timeout)
+	if err != nil {
+		execLogic.UpdateStreamExecutionStatus(sessionID, string(model.ExecutionStatusFailed), nil)
+		return response.Error(c, "恢复执行失败: "+err.Error())
+	}
+	execCtx.Persist = true
+	execCtx.ExecLogic = execLogic
+	persistCheckpoints(execCtx)
+
+	logger.Debug("从检查点恢复执行: sessionId=%s, step=%s", sessionID, plan.Resume.StepID)
+
+	if isSSE {
+		return h.executeSSE(c, execCtx)
+	}
+	return h.executeBlocking(c, execCtx)
+}
+
+// prepareResumeExecution 用执行快照重建工作流并设置恢复起点
+func (h *StreamExecutionHandler) prepareResumeExecution(c *fiber.Ctx, plan *logic.ResumeExecutionPlan, timeout int) (*ExecutionContext, error) {
+	var workflowDef interface{}
+	if err := json.Unmarshal(plan.Snapshot.Workflow, &workflowDef); err != nil {
+		return nil, fmt.Errorf("执行快照解析失败: %w", err)
+	}
+	h.resolveAIModelConfigsInWorkflow(c, workflowDef)
+
+	mode := "debug"
+	if plan.Execution.Mode == string(model.ExecutionModeExecute) {
+		mode = "normal"
+	}
+
+	// 执行记录已存在，不再新建
+	execCtx, err := h.prepareExecutionFromDefinition(c, workflowDef, plan.Execution.EnvID, nil, nil, mode, false, timeout, plan.Execution.ExecutionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Snapshot.SelectedSteps) > 0 {
+		execCtx.EngineWf.Steps = filterSteps(execCtx.EngineWf.Steps, plan.Snapshot.SelectedSteps)
+	}
+	execCtx.EngineWf.Resume = plan.Resume
+	return execCtx, nil
+}
+
+// GetCheckpoints 获取执行的检查点列表
+// GET /api/executions/:sessionId/checkpoints
+func (h *StreamExecutionHandler) GetCheckpoints(c *fiber.Ctx) error {
+	sessionID := c.Params("sessionId")
+	if sessionID == "" {
+		return response.Error(c, "会话ID不能为空")
+	}
+
+	checkpoints, err := logic.NewExecutionLogic(c.UserContext()).ListCheckpoints(sessionID)
+	if err != nil {
+		return response.Error(c, "获取检查点失败: "+err.Error())
+	}
+	return response.Success(c, checkpoints)
+}
+
 // SubmitInteraction 提交交互响应
 // POST /api/executions/:sessionId/interaction
 func (h *StreamExecutionHandler) SubmitInteraction(c *fiber.Ctx) error {
@@ -570,6 +718,108 @@ func (h *StreamExecutionHandler) SubmitInteraction(c *fiber.Ctx) error {
 	return response.Success(c, nil)
 }
 
+// DebugCommandRequest 调试命令请求
+type DebugCommandRequest struct {
+	Command string `json:"command"`
+}
+
+// DebugCommand 提交调试命令（continue / step_over / step_into / step_out / pause）
+// POST /api/executions/:sessionId/debug/command
+func (h *StreamExecutionHandler) DebugCommand(c *fiber.Ctx) error {
+	debugger, err := h.sessionManager.GetDebugger(c.Params("sessionId"))
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	var req DebugCommandRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	if err := debugger.Command(executor.DebugCommand(req.Command)); err != nil {
+		return response.Error(c, "提交调试命令失败: "+err.Error())
+	}
+	return response.Success(c, nil)
+}
+
+// GetDebugState 获取调试状态（断点与当前暂停点）
+// GET /api/executions/:sessionId/debug
+func (h *StreamExecutionHandler) GetDebugState(c *fiber.Ctx) error {
+	debugger, err := h.sessionManager.GetDebugger(c.Params("sessionId"))
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+	return response.Success(c, map[string]interface{}{
+		"breakpoints": debugger.Breakpoints(),
+		"paused":      debugger.Paused(),
+	})
+}
+
+// SetBreakpointsRequest 设置断点请求
+type SetBreakpointsRequest struct {
+	Breakpoints []executor.Breakpoint `json:"breakpoints"`
+}
+
+// SetBreakpoints 替换断点，对后续步骤生效
+// PUT /api/executions/:sessionId/debug/breakpoints
+func (h *StreamExecutionHandler) SetBreakpoints(c *fiber.Ctx) error {
+	debugger, err := h.sessionManager.GetDebugger(c.Params("sessionId"))
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	var req SetBreakpointsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	debugger.SetBreakpoints(req.Breakpoints)
+	return response.Success(c, debugger.Breakpoints())
+}
+
+// GetDebugVariables 获取暂停点的变量
+// GET /api/executions/:sessionId/debug/variables
+func (h *StreamExecutionHandler) GetDebugVariables(c *fiber.Ctx) error {
+	debugger, err := h.sessionManager.GetDebugger(c.Params("sessionId"))
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	vars, err := debugger.Variables()
+	if err != nil {
+		return response.Error(c, "获取变量失败: "+err.Error())
+	}
+	return response.Success(c, vars)
+}
+
+// SetDebugVariablesRequest 修改变量请求
+type SetDebugVariablesRequest struct {
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// SetDebugVariables 在暂停点修改变量，对后续步骤生效
+// PUT /api/executions/:sessionId/debug/variables
+func (h *StreamExecutionHandler) SetDebugVariables(c *fiber.Ctx) error {
+	debugger, err := h.sessionManager.GetDebugger(c.Params("sessionId"))
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	var req SetDebugVariablesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+	if len(req.Variables) == 0 {
+		return response.Error(c, "变量不能为空")
+	}
+
+	vars, err := debugger.SetVariables(req.Variables)
+	if err != nil {
+		return response.Error(c, "修改变量失败: "+err.Error())
+	}
+	return response.Success(c, vars)
+}
+
 // GetExecutionStatus 获取执行状态
 // GET /api/executions/:sessionId/status
 func (h *StreamExecutionHandler) GetExecutionStatus(c *fiber.Ctx) error {
@@ -639,58 +889,13 @@ func (fw *fasthttpFlushWriter) Flush() {}
 
 // isAINodeType 检查节点类型是否为 AI 类型
 func isAINodeType(nodeType string) bool {
-	switch nodeType {
-	case "ai", "ai_chat", "ai_agent", "ai_react", "ai_plan", "ai_direct",
-		"ai_plan_execute", "ai_reflection", "ai_supervisor", "ai_deep_agent":
-		return true
-	}
-	return false
+	return logic.IsAINodeType(nodeType)
 }
 
 // resolveAIModelConfig 解析 AI 节点中的托管模型配置
-// 如果 config 中包含 ai_model_id，则从数据库获取模型的完整配置（api_key、base_url、model、provider）并注入到 config 中
+// 如果 config 中包含 ai_model_id，则从数据库获取模型的完整配置（api_key、base_url、model、provider、单价）并注入到 config 中
 func (h *StreamExecutionHandler) resolveAIModelConfig(c *fiber.Ctx, config map[string]interface{}) error {
-	aiModelIDRaw, ok := config["ai_model_id"]
-	if !ok || aiModelIDRaw == nil {
-		return nil
-	}
-
-	// 提取 ai_model_id（可能是 float64 或 int）
-	var aiModelID int64
-	switch v := aiModelIDRaw.(type) {
-	case float64:
-		aiModelID = int64(v)
-	case int:
-		aiModelID = int64(v)
-	case int64:
-		aiModelID = v
-	default:
-		return nil
-	}
-
-	if aiModelID <= 0 {
-		return nil
-	}
-
-	// 从数据库获取完整模型信息（含 API Key，自动从供应商解析凭证）
-	aiModelLogic := logic.NewAiModelLogic(c.Context())
-	aiModel, err := aiModelLogic.GetByIDWithKey(aiModelID)
-	if err != nil {
-		return fmt.Errorf("AI 模型不存在或已删除 (ID=%d): %v", aiModelID, err)
-	}
-
-	// 检查模型状态
-	if aiModel.Status != nil && *aiModel.Status != 1 {
-		return fmt.Errorf("AI 模型已禁用 (ID=%d, Name=%s)", aiModelID, aiModel.Name)
-	}
-
-	// 注入模型配置到 step config
-	config["provider"] = aiModel.Provider
-	config["model"] = aiModel.ModelID
-	config["api_key"] = aiModel.APIKey
-	config["base_url"] = aiModel.APIBaseURL
-
-	return nil
+	return logic.ResolveAIModelConfig(c.Context(), config)
 }
 
 // resolveAIModelConfigsInWorkflow 递归解析工作流定义中所有 AI 节点的托管模型配置
@@ -736,6 +941,18 @@ func (h *StreamExecutionHandler) resolveAIModelConfigsInWorkflow(c *fiber.Ctx, w
 			}
 		}
 
+		// 评估类处理器（llm_judge / semantic_similarity）
+		for _, key := range []string{"preProcessors", "postProcessors"} {
+			processors, _ := stepMap[key].([]interface{})
+			for _, procRaw := range processors {
+				if proc, ok := procRaw.(map[string]interface{}); ok {
+					procType, _ := proc["type"].(string)
+					procConfig, _ := proc["config"].(map[string]interface{})
+					h.resolveEvalProcessorConfig(c, procType, procConfig)
+				}
+			}
+		}
+
 		// 递归处理子步骤（循环、条件等）
 		if children, ok := stepMap["children"].([]interface{}); ok {
 			for _, child := range children {
@@ -821,8 +1038,11 @@ func (h *StreamExecutionHandler) resolveSkillConfigs(c *fiber.Ctx, config map[st
 }
 
 // resolveKnowledgeBaseConfigs 解析 AI 节点中的知识库配置
-// 如果 config 中包含 knowledge_base_ids，则从数据库获取知识库的完整信息并注入到 config["knowledge_bases"] 中
+// 如果 config 中包含 knowledge_base_ids，则从数据库获取知识库的完整信息并注入到 config["knowledge_bases"] 中。
+// config["knowledge_bases"] 只能由服务端生成，客户端传入的值总是丢弃
 func (h *StreamExecutionHandler) resolveKnowledgeBaseConfigs(c *fiber.Ctx, config map[string]interface{}) error {
+	delete(config, "knowledge_bases")
+
 	kbIDsRaw, ok := config["knowledge_base_ids"]
 	if !ok || kbIDsRaw == nil {
 		return nil
@@ -850,6 +1070,27 @@ func (h *StreamExecutionHandler) resolveKnowledgeBaseConfigs(c *fiber.Ctx, confi
 		return nil
 	}
 
+	// 节点级元数据过滤在下发前校验，避免执行时每次检索都失败
+	if raw, ok := config["kb_filter"]; ok && raw != nil {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("知识库过滤条件格式错误: %w", err)
+		}
+		var filter logic.MetadataFilter
+		if err := json.Unmarshal(data, &filter); err != nil {
+			return fmt.Errorf("知识库过滤条件格式错误: %w", err)
+		}
+		if err := filter.Validate(); err != nil {
+			return fmt.Errorf("知识库过滤条件无效: %w", err)
+		}
+	}
+
+	// 检索按执行用户的文档访问控制过滤，无法确定执行用户时不下发知识库
+	userID := middleware.GetCurrentUserID(c)
+	if userID <= 0 {
+		return fmt.Errorf("未登录用户不能检索知识库")
+	}
+
 	// 从数据库查询知识库详情
 	kbLogic := logic.NewKnowledgeBaseLogic(c.Context())
 	var knowledgeBases []map[string]interface{}
@@ -869,10 +1110,14 @@ func (h *StreamExecutionHandler) resolveKnowledgeBaseConfigs(c *fiber.Ctx, confi
 			"name":                kbInfo.Name,
 			"type":                kbInfo.Type,
 			"qdrant_collection":   kbInfo.QdrantCollection,
+			"vector_store":        kbInfo.VectorStore,
 			"embedding_dimension": kbInfo.EmbeddingDimension,
 			"top_k":               kbInfo.TopK,
 			"score_threshold":     kbInfo.SimilarityThreshold,
+			"metadata_keys":       kbLogic.MetadataKeys(kbInfo.ID),
 		}
+		// 按执行用户的文档访问控制检索，知识库创建者不受限制
+		kbData["acl_user_id"] = userID
 
 		// AI 节点级别的检索参数覆盖知识库默认值
 		if v, ok := config["kb_top_k"]; ok {
@@ -920,17 +1165,64 @@ func (h *StreamExecutionHandler) resolveKnowledgeBaseConfigs(c *fiber.Ctx, confi
 				}
 				config["qdrant_host"] = fmt.Sprintf("%s://%s:%d", protocol, cfg.Qdrant.Host, restPort)
 			}
-			serverPort := cfg.Server.Port
-			if serverPort <= 0 {
-				serverPort = 5321
-			}
-			config["gulu_host"] = fmt.Sprintf("http://127.0.0.1:%d", serverPort)
+			config["gulu_host"] = localGuluHost()
 		}
 	}
 
 	return nil
 }
 
+// localGuluHost 供 workflow-engine 回调的本机 Gulu 地址
+func localGuluHost() string {
+	return logic.LocalGuluHost()
+}
+
+// resolveEvalProcessorConfig 解析评估类处理器配置
+// llm_judge：将 aiModelId 解析为评审模型的 provider/model/apiKey/baseUrl；
+// semantic_similarity：使用知识库或嵌入模型时注入 Gulu 地址，由 Gulu 的嵌入客户端计算向量
+func (h *StreamExecutionHandler) resolveEvalProcessorConfig(c *fiber.Ctx, procType string, config map[string]interface{}) {
+	if config == nil {
+		return
+	}
+	switch procType {
+	case "llm_judge":
+		var aiModelID int64
+		switch v := config["aiModelId"].(type) {
+		case float64:
+			aiModelID = int64(v)
+		case int:
+			aiModelID = int64(v)
+		case int64:
+			aiModelID = v
+		}
+		if aiModelID <= 0 {
+			return
+		}
+		aiModel, err := logic.NewAiModelLogic(c.Context()).GetByIDWithKey(aiModelID)
+		if err != nil {
+			logger.Warn("解析评审模型失败: id=%d, error=%v", aiModelID, err)
+			return
+		}
+		if aiModel.Status != nil && *aiModel.Status != 1 {
+			logger.Warn("评审模型已禁用: id=%d, name=%s", aiModelID, aiModel.Name)
+			return
+		}
+		config["provider"] = aiModel.Provider
+		if aiModel.ProviderType != "" {
+			config["provider"] = aiModel.ProviderType
+		}
+		config["model"] = aiModel.ModelID
+		config["apiKey"] = aiModel.APIKey
+		config["baseUrl"] = aiModel.APIBaseURL
+	case "semantic_similarity":
+		_, hasKB := config["knowledgeBaseId"]
+		_, hasModel := config["embeddingModelId"]
+		if _, ok := config["guluHost"]; !ok && (hasKB || hasModel) {
+			config["guluHost"] = localGuluHost()
+		}
+	}
+}
+
 // resolveMCPServerConfigs 解析 AI 节点中的 MCP 服务器配置
 // 将 mcp_server_ids 解析为 mcp_servers（包含完整连接信息），供 workflow engine 直连
 func (h *StreamExecutionHandler) resolveMCPServerConfigs(c *fiber.Ctx, config map[string]interface{}) error {
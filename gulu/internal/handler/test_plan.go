@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"strconv"
+
+	"yqhp/common/response"
+	"yqhp/gulu/internal/logic"
+	"yqhp/gulu/internal/middleware"
+	"yqhp/gulu/internal/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestPlanCreate 创建测试计划
+// POST /api/test-plans
+func TestPlanCreate(c *fiber.Ctx) error {
+	var req logic.CreateTestPlanReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	if req.Name == "" {
+		return response.Error(c, "计划名称不能为空")
+	}
+	if req.EnvID <= 0 {
+		return response.Error(c, "环境ID不能为空")
+	}
+	if req.ProjectID <= 0 {
+		req.ProjectID = middleware.GetCurrentProjectID(c)
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	planLogic := logic.NewTestPlanLogic(c.UserContext())
+
+	result, err := planLogic.Create(&req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, result)
+}
+
+// TestPlanUpdate 更新测试计划
+// PUT /api/test-plans/:id
+func TestPlanUpdate(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	var req logic.UpdateTestPlanReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	planLogic := logic.NewTestPlanLogic(c.UserContext())
+
+	if err := planLogic.Update(id, &req, userID); err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, nil)
+}
+
+// TestPlanDelete 删除测试计划（软删除）
+// DELETE /api/test-plans/:id
+func TestPlanDelete(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	planLogic := logic.NewTestPlanLogic(c.UserContext())
+
+	if err := planLogic.Delete(id); err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, nil)
+}
+
+// TestPlanGetByID 获取测试计划详情（含用例）
+// GET /api/test-plans/:id
+func TestPlanGetByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	planLogic := logic.NewTestPlanLogic(c.UserContext())
+
+	result, err := planLogic.GetByID(id)
+	if err != nil {
+		return response.NotFound(c, "测试计划不存在")
+	}
+
+	return response.Success(c, result)
+}
+
+// TestPlanList 获取测试计划列表
+// GET /api/test-plans
+func TestPlanList(c *fiber.Ctx) error {
+	var req logic.TestPlanListReq
+	if err := c.QueryParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if projectID := middleware.GetCurrentProjectID(c); projectID > 0 {
+		req.ProjectID = projectID
+	}
+
+	planLogic := logic.NewTestPlanLogic(c.UserContext())
+
+	list, total, err := planLogic.List(&req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Page(c, list, total, req.Page, req.PageSize)
+}
+
+// TestPlanRun 运行测试计划
+// POST /api/test-plans/:id/run
+func TestPlanRun(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	var req logic.RunTestPlanReq
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.Error(c, "参数解析失败")
+		}
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	planLogic := logic.NewTestPlanLogic(c.UserContext())
+
+	run, err := planLogic.Run(id, &req, userID, model.TriggerTypeManual, 0)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, run)
+}
+
+// TestPlanRuns 获取测试计划运行记录
+// GET /api/test-plans/:id/runs
+func TestPlanRuns(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("pageSize", 20)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	planLogic := logic.NewTestPlanLogic(c.UserContext())
+
+	list, total, err := planLogic.ListRuns(id, page, pageSize)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Page(c, list, total, page, pageSize)
+}
+
+// TestPlanRunReport 获取测试计划运行报告
+// GET /api/test-plans/runs/:runId/report
+func TestPlanRunReport(c *fiber.Ctx) error {
+	runID, err := strconv.ParseInt(c.Params("runId"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的运行ID")
+	}
+
+	planLogic := logic.NewTestPlanLogic(c.UserContext())
+
+	report, err := planLogic.Report(runID)
+	if err != nil {
+		return response.NotFound(c, "运行记录不存在")
+	}
+
+	return response.Success(c, report)
+}
+
+// TestPlanRunStop 停止测试计划运行
+// POST /api/test-plans/runs/:runId/stop
+func TestPlanRunStop(c *fiber.Ctx) error {
+	runID, err := strconv.ParseInt(c.Params("runId"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的运行ID")
+	}
+
+	planLogic := logic.NewTestPlanLogic(c.UserContext())
+
+	if err := planLogic.StopRun(runID); err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, nil)
+}
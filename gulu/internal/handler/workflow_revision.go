@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"strconv"
+
+	"yqhp/common/response"
+	"yqhp/gulu/internal/logic"
+	"yqhp/gulu/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WorkflowRevisionList 获取工作流版本列表
+// GET /api/workflows/:id/revisions
+func WorkflowRevisionList(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的工作流ID")
+	}
+
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("pageSize", 20)
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	revisionLogic := logic.NewWorkflowRevisionLogic(c.UserContext())
+
+	list, total, err := revisionLogic.List(id, page, pageSize)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Page(c, list, total, page, pageSize)
+}
+
+// WorkflowRevisionGet 获取工作流指定版本详情
+// GET /api/workflows/:id/revisions/:version
+func WorkflowRevisionGet(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的工作流ID")
+	}
+	version, err := strconv.ParseInt(c.Params("version"), 10, 32)
+	if err != nil {
+		return response.Error(c, "无效的版本号")
+	}
+
+	revisionLogic := logic.NewWorkflowRevisionLogic(c.UserContext())
+
+	revision, err := revisionLogic.Get(id, int32(version))
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+
+	return response.Success(c, revision)
+}
+
+// WorkflowRevisionDiff 对比工作流两个版本，省略 to 时与当前版本对比
+// GET /api/workflows/:id/revisions/diff?from=1&to=3
+func WorkflowRevisionDiff(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的工作流ID")
+	}
+
+	from := c.QueryInt("from")
+	if from <= 0 {
+		return response.Error(c, "起始版本号不能为空")
+	}
+	to := c.QueryInt("to")
+
+	revisionLogic := logic.NewWorkflowRevisionLogic(c.UserContext())
+
+	diff, err := revisionLogic.Diff(id, int32(from), int32(to))
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, diff)
+}
+
+// WorkflowRevisionRollback 回滚工作流到指定版本
+// POST /api/workflows/:id/revisions/:version/rollback
+func WorkflowRevisionRollback(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的工作流ID")
+	}
+	version, err := strconv.ParseInt(c.Params("version"), 10, 32)
+	if err != nil {
+		return response.Error(c, "无效的版本号")
+	}
+
+	var req logic.RollbackWorkflowReq
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.Error(c, "参数解析失败")
+		}
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	revisionLogic := logic.NewWorkflowRevisionLogic(c.UserContext())
+
+	wf, err := revisionLogic.Rollback(id, int32(version), &req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, wf)
+}
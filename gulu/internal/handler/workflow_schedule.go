@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"strconv"
+
+	"yqhp/common/response"
+	"yqhp/gulu/internal/logic"
+	"yqhp/gulu/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WorkflowScheduleCreate 创建定时计划
+// POST /api/workflow-schedules
+func WorkflowScheduleCreate(c *fiber.Ctx) error {
+	var req logic.CreateWorkflowScheduleReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败: "+err.Error())
+	}
+
+	if req.WorkflowID <= 0 && req.PlanID <= 0 {
+		return response.Error(c, "工作流ID和测试计划ID不能同时为空")
+	}
+	if req.Name == "" {
+		return response.Error(c, "计划名称不能为空")
+	}
+	if req.CronExpression == "" {
+		return response.Error(c, "cron 表达式不能为空")
+	}
+	if req.ProjectID <= 0 {
+		req.ProjectID = middleware.GetCurrentProjectID(c)
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	scheduleLogic := logic.NewWorkflowScheduleLogic(c.UserContext())
+
+	result, err := scheduleLogic.Create(&req, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, result)
+}
+
+// WorkflowScheduleUpdate 更新定时计划
+// PUT /api/workflow-schedules/:id
+func WorkflowScheduleUpdate(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	var req logic.UpdateWorkflowScheduleReq
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	scheduleLogic := logic.NewWorkflowScheduleLogic(c.UserContext())
+
+	if err := scheduleLogic.Update(id, &req, userID); err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, nil)
+}
+
+// WorkflowScheduleDelete 删除定时计划（软删除）
+// DELETE /api/workflow-schedules/:id
+func WorkflowScheduleDelete(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	scheduleLogic := logic.NewWorkflowScheduleLogic(c.UserContext())
+
+	if err := scheduleLogic.Delete(id); err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, nil)
+}
+
+// WorkflowScheduleGetByID 获取定时计划详情
+// GET /api/workflow-schedules/:id
+func WorkflowScheduleGetByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	scheduleLogic := logic.NewWorkflowScheduleLogic(c.UserContext())
+
+	result, err := scheduleLogic.GetByID(id)
+	if err != nil {
+		return response.NotFound(c, "定时计划不存在")
+	}
+
+	return response.Success(c, result)
+}
+
+// WorkflowScheduleList 获取定时计划列表
+// GET /api/workflow-schedules
+func WorkflowScheduleList(c *fiber.Ctx) error {
+	var req logic.WorkflowScheduleListReq
+	if err := c.QueryParser(&req); err != nil {
+		return response.Error(c, "参数解析失败")
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if projectID := middleware.GetCurrentProjectID(c); projectID > 0 {
+		req.ProjectID = projectID
+	}
+
+	scheduleLogic := logic.NewWorkflowScheduleLogic(c.UserContext())
+
+	list, total, err := scheduleLogic.List(&req)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Page(c, list, total, req.Page, req.PageSize)
+}
+
+// WorkflowSchedulePause 暂停定时计划
+// POST /api/workflow-schedules/:id/pause
+func WorkflowSchedulePause(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	scheduleLogic := logic.NewWorkflowScheduleLogic(c.UserContext())
+
+	if err := scheduleLogic.Pause(id); err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, nil)
+}
+
+// WorkflowScheduleResume 恢复定时计划
+// POST /api/workflow-schedules/:id/resume
+func WorkflowScheduleResume(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	scheduleLogic := logic.NewWorkflowScheduleLogic(c.UserContext())
+
+	if err := scheduleLogic.Resume(id); err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, nil)
+}
+
+// WorkflowScheduleRun 立即执行一次定时计划
+// POST /api/workflow-schedules/:id/run
+func WorkflowScheduleRun(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.Error(c, "无效的计划ID")
+	}
+
+	userID := middleware.GetCurrentUserID(c)
+	scheduleLogic := logic.NewWorkflowScheduleLogic(c.UserContext())
+
+	execution, err := scheduleLogic.RunNow(id, userID)
+	if err != nil {
+		return response.Error(c, err.Error())
+	}
+
+	return response.Success(c, execution)
+}
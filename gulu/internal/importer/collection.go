@@ -0,0 +1,155 @@
+// Package importer 将外部接口描述（OpenAPI/Swagger、Postman、HAR、cURL）转换为工作流步骤
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"yqhp/workflow-engine/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Collection 导入解析结果，每个分组生成一个工作流
+type Collection struct {
+	Source       string    // 来源格式，同时作为生成步骤 ID 的前缀，用于重新导入时识别
+	Title        string    // 文档标题
+	Servers      []*Server // 服务地址，每个地址对应一个 domain 配置
+	Groups       []*Group
+	EnvVariables []*EnvVariable // 需要写入环境配置的变量，步骤中以 ${env.name} 引用
+	Warnings     []string       // 无法完整转换的内容提示
+}
+
+// Server 服务地址
+type Server struct {
+	Key         string // 文档内唯一标识，步骤 config.domainCode 暂存该值，落库时替换为配置编码
+	URL         string
+	Description string
+}
+
+// EnvVariable 环境变量，对应一个 variable 配置
+type EnvVariable struct {
+	Name      string
+	Value     string
+	Sensitive bool
+}
+
+// Group 接口分组
+type Group struct {
+	Name        string
+	Description string
+	Variables   map[string]interface{}
+	Steps       []types.Step
+}
+
+// addWarning 记录转换提示，相同内容只记录一次
+func (c *Collection) addWarning(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	for _, w := range c.Warnings {
+		if w == msg {
+			return
+		}
+	}
+	c.Warnings = append(c.Warnings, msg)
+}
+
+// StepPrefix 生成步骤 ID 的前缀
+func (c *Collection) StepPrefix() string {
+	return c.Source + "_"
+}
+
+// IsGeneratedStep 判断步骤是否由该来源导入生成
+func (c *Collection) IsGeneratedStep(stepID string) bool {
+	return strings.HasPrefix(stepID, c.StepPrefix())
+}
+
+var nonIdentChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// stepIDAllocator 生成稳定且唯一的步骤 ID
+type stepIDAllocator struct {
+	prefix string
+	used   map[string]int
+}
+
+func newStepIDAllocator(prefix string) *stepIDAllocator {
+	return &stepIDAllocator{prefix: prefix, used: make(map[string]int)}
+}
+
+func (a *stepIDAllocator) next(key string) string {
+	id := a.prefix + strings.Trim(nonIdentChars.ReplaceAllString(key, "_"), "_")
+	a.used[id]++
+	if n := a.used[id]; n > 1 {
+		return fmt.Sprintf("%s_%d", id, n)
+	}
+	return id
+}
+
+// decodeDocument 解析 JSON 或 YAML 文档为通用结构
+func decodeDocument(content []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(content, &doc); err == nil {
+		return doc, nil
+	}
+
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("文档解析失败，仅支持 JSON 或 YAML: %v", err)
+	}
+	doc, ok := normalizeYAML(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("文档格式错误：根节点必须是对象")
+	}
+	return doc, nil
+}
+
+// normalizeYAML 将 YAML 解码出的非字符串键（如响应码 200）转为字符串，数字统一为 float64，与 JSON 解码结果一致
+func normalizeYAML(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeYAML(item)
+		}
+		return val
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeYAML(item)
+		}
+		return val
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case uint64:
+		return float64(val)
+	default:
+		return val
+	}
+}
+
+// keyValue 生成前端键值对格式 {key, value, enabled}
+func keyValue(key, value string, enabled bool) map[string]interface{} {
+	return map[string]interface{}{"key": key, "value": value, "enabled": enabled}
+}
+
+func stringOf(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func mapOf(m map[string]interface{}, key string) map[string]interface{} {
+	v, _ := m[key].(map[string]interface{})
+	return v
+}
+
+func listOf(m map[string]interface{}, key string) []interface{} {
+	v, _ := m[key].([]interface{})
+	return v
+}
@@ -0,0 +1,330 @@
+package importer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SourceCurl cURL 命令导入来源标识
+const SourceCurl = "curl"
+
+// curlIgnoredArgFlags 需要参数但与请求内容无关的选项
+var curlIgnoredArgFlags = map[string]bool{
+	"-o": true, "--output": true, "-x": true, "--proxy": true, "-m": true, "--max-time": true,
+	"--connect-timeout": true, "--retry": true, "-w": true, "--write-out": true, "-c": true,
+	"--cookie-jar": true, "--cacert": true, "-E": true, "--cert": true, "--key": true,
+	"--resolve": true, "--limit-rate": true, "-U": true, "--proxy-user": true,
+}
+
+// ParseCurl 解析粘贴的一条或多条 cURL 命令（bash 语法，支持 \ 换行续行），每条命令生成一个 http 步骤
+func ParseCurl(content []byte) (*Collection, error) {
+	tokens, err := shellSplit(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	var commands [][]string
+	collecting := false
+	for _, t := range tokens {
+		switch {
+		case t == "curl" || strings.HasSuffix(t, "/curl"):
+			commands = append(commands, nil)
+			collecting = true
+		case t == ";" || t == "|" || t == "&":
+			// 管道或后续命令不属于 curl 参数
+			collecting = false
+		case collecting:
+			commands[len(commands)-1] = append(commands[len(commands)-1], t)
+		}
+	}
+	if len(commands) == 0 {
+		return nil, errors.New("未找到 curl 命令")
+	}
+
+	collection := &Collection{Source: SourceCurl, Title: "cURL"}
+	servers := newServerSet(collection)
+	ids := newStepIDAllocator(collection.StepPrefix())
+	group := &Group{Variables: make(map[string]interface{})}
+
+	for i, args := range commands {
+		req, err := parseCurlArgs(collection, args)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条 curl 命令解析失败: %w", i+1, err)
+		}
+		_, path, _ := splitURL(req.url)
+		group.Steps = append(group.Steps, buildRawStep(req, servers, ids, req.method+"_"+path))
+	}
+	group.Name = "cURL - " + group.Steps[0].Name
+	collection.Groups = []*Group{group}
+	return collection, nil
+}
+
+// parseCurlArgs 解析单条 curl 命令的参数
+func parseCurlArgs(c *Collection, args []string) (*rawRequest, error) {
+	req := &rawRequest{}
+	var data []string
+	var getMode bool
+
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := args[i], "", false
+		switch {
+		case strings.HasPrefix(flag, "--"):
+			if name, v, ok := strings.Cut(flag, "="); ok {
+				flag, value, hasValue = name, v, true
+			}
+		case strings.HasPrefix(flag, "-") && len(flag) > 2 && strings.Contains("XHdFubAeo", flag[1:2]):
+			// 短选项紧跟参数值，如 -XPOST
+			flag, value, hasValue = flag[:2], flag[2:], true
+		}
+		// next 读取选项参数
+		next := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("选项 %s 缺少参数", flag)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var err error
+		switch flag {
+		case "-X", "--request":
+			req.method, err = next()
+			req.method = strings.ToUpper(req.method)
+		case "-H", "--header":
+			var h string
+			if h, err = next(); err == nil {
+				if name, v, ok := strings.Cut(h, ":"); ok {
+					req.headers = append(req.headers, [2]string{strings.TrimSpace(name), strings.TrimSpace(v)})
+				}
+			}
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			var d string
+			if d, err = next(); err == nil {
+				if strings.HasPrefix(d, "@") && flag != "--data-raw" {
+					c.addWarning("curl 请求体引用了本地文件 %s，请手动填写", d[1:])
+				}
+				data = append(data, d)
+			}
+		case "--data-urlencode":
+			var d string
+			if d, err = next(); err == nil {
+				data = append(data, curlURLEncode(d))
+			}
+		case "--json":
+			var d string
+			if d, err = next(); err == nil {
+				data = append(data, d)
+				if req.header("Content-Type") == "" {
+					req.setHeader("Content-Type", "application/json")
+				}
+				if req.header("Accept") == "" {
+					req.setHeader("Accept", "application/json")
+				}
+			}
+		case "-F", "--form", "--form-string":
+			var f string
+			if f, err = next(); err == nil {
+				name, v, _ := strings.Cut(f, "=")
+				if strings.HasPrefix(v, "@") && flag != "--form-string" {
+					c.addWarning("curl 表单字段 %s 上传了本地文件 %s，请手动选择文件", name, v[1:])
+				}
+				req.form = append(req.form, [2]string{name, v})
+				req.multipart = true
+			}
+		case "-u", "--user":
+			var u string
+			if u, err = next(); err == nil {
+				req.setHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u)))
+			}
+		case "-b", "--cookie":
+			var b string
+			if b, err = next(); err == nil && strings.Contains(b, "=") {
+				req.setHeader("Cookie", b)
+			}
+		case "-A", "--user-agent":
+			var a string
+			if a, err = next(); err == nil {
+				req.setHeader("User-Agent", a)
+			}
+		case "-e", "--referer":
+			var e string
+			if e, err = next(); err == nil {
+				req.setHeader("Referer", e)
+			}
+		case "--url":
+			req.url, err = next()
+		case "-G", "--get":
+			getMode = true
+		case "-I", "--head":
+			req.method = "HEAD"
+		case "-T", "--upload-file":
+			var f string
+			if f, err = next(); err == nil {
+				c.addWarning("curl 上传了本地文件 %s，请手动填写请求体", f)
+			}
+		default:
+			switch {
+			case curlIgnoredArgFlags[flag]:
+				_, err = next()
+			case strings.HasPrefix(flag, "-"):
+				// 其余无参数选项（-s、-L、-k、--compressed 等）与请求内容无关
+			case req.url == "":
+				req.url = flag
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.url == "" {
+		return nil, errors.New("缺少请求地址")
+	}
+	if !strings.Contains(req.url, "://") {
+		req.url = "http://" + req.url
+	}
+
+	if len(data) > 0 {
+		joined := strings.Join(data, "&")
+		if getMode {
+			sep := "?"
+			if strings.Contains(req.url, "?") {
+				sep = "&"
+			}
+			req.url += sep + joined
+		} else {
+			req.body = joined
+			if req.header("Content-Type") == "" {
+				req.setHeader("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+	}
+	if req.method == "" {
+		req.method = "GET"
+		if (req.body != "" || req.multipart) && !getMode {
+			req.method = "POST"
+		}
+	}
+	return req, nil
+}
+
+// curlURLEncode 按 --data-urlencode 规则编码：name=content 仅编码 content，否则编码整体
+func curlURLEncode(d string) string {
+	if name, content, ok := strings.Cut(d, "="); ok {
+		if name == "" {
+			return url.QueryEscape(content)
+		}
+		return name + "=" + url.QueryEscape(content)
+	}
+	return url.QueryEscape(d)
+}
+
+// shellSplit 按 bash 规则拆分命令行：支持单引号、双引号、$'...'、反斜杠转义与续行，
+// 命令分隔符 ; & | 逐字符作为独立的词返回
+func shellSplit(s string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inToken := false
+	flush := func() {
+		if inToken {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+			inToken = false
+		}
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] == '\r' && i+1 < len(runes) && runes[i+1] == '\n' {
+					i++
+				}
+				if runes[i] == '\n' {
+					continue
+				}
+				cur.WriteRune(runes[i])
+				inToken = true
+			}
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, errors.New("单引号未闭合")
+			}
+			cur.WriteString(string(runes[i+1 : end]))
+			inToken = true
+			i = end
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			i += 2
+			for ; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					cur.WriteString(ansiCEscape(runes[i]))
+					continue
+				}
+				cur.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("$'...' 引号未闭合")
+			}
+			inToken = true
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				cur.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("双引号未闭合")
+			}
+			inToken = true
+		case r == ';' || r == '|' || r == '&':
+			flush()
+			tokens = append(tokens, string(r))
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			flush()
+		default:
+			cur.WriteRune(r)
+			inToken = true
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+func indexRune(runes []rune, from int, target rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// ansiCEscape $'...' 中的转义字符
+func ansiCEscape(r rune) string {
+	switch r {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	default:
+		return string(r)
+	}
+}
@@ -0,0 +1,74 @@
+package importer
+
+import "testing"
+
+func TestParseCurl(t *testing.T) {
+	cmd := `curl 'https://api.example.com/v1/login?from=web' \
+  -H 'Content-Type: application/json' \
+  -H "Accept-Encoding: gzip" \
+  --data-raw $'{"user":"a\'b","pwd":"x"}' \
+  --compressed -sS | jq .
+curl -u admin:secret -G -d q=hello%20world -d page=2 api.example.com/search`
+
+	c, err := ParseCurl([]byte(cmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := c.Groups[0].Steps
+	if len(steps) != 2 || len(c.Servers) != 2 {
+		t.Fatalf("expected 2 steps and servers, got %d %d", len(steps), len(c.Servers))
+	}
+
+	login := steps[0]
+	if login.Config["method"] != "POST" || login.Config["url"] != "/v1/login" || login.ID != "curl_POST_v1_login" {
+		t.Errorf("unexpected login step: %s %+v", login.ID, login.Config)
+	}
+	if body := login.Config["body"].(map[string]interface{}); body["type"] != "json" || body["raw"] != `{"user":"a'b","pwd":"x"}` {
+		t.Errorf("unexpected body: %v", body)
+	}
+	if headers := login.Config["headers"].([]interface{}); len(headers) != 1 {
+		t.Errorf("expected Accept-Encoding to be dropped, got %v", headers)
+	}
+	if c.Groups[0].Name != "cURL - POST /v1/login" {
+		t.Errorf("unexpected group name: %s", c.Groups[0].Name)
+	}
+
+	search := steps[1]
+	params := search.Config["params"].([]interface{})
+	if search.Config["method"] != "GET" || len(params) != 2 || params[0].(map[string]interface{})["value"] != "hello world" {
+		t.Errorf("unexpected search step: %+v", search.Config)
+	}
+	if c.Servers[1].URL != "http://api.example.com" {
+		t.Errorf("unexpected server: %+v", c.Servers[1])
+	}
+	if !hasHeader(search.Config["headers"].([]interface{}), "Authorization") {
+		t.Error("expected basic auth header")
+	}
+}
+
+func TestParseCurl_Multipart(t *testing.T) {
+	c, err := ParseCurl([]byte(`curl -F name=avatar -F file=@/tmp/a.png -H 'Content-Type: multipart/form-data; boundary=x' https://up.example.com/files`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := c.Groups[0].Steps[0]
+	body := step.Config["body"].(map[string]interface{})
+	if step.Config["method"] != "POST" || body["type"] != "form-data" || len(body["formData"].([]interface{})) != 2 {
+		t.Errorf("unexpected multipart step: %+v", step.Config)
+	}
+	if hasHeader(step.Config["headers"].([]interface{}), "Content-Type") {
+		t.Error("expected multipart content type to be dropped")
+	}
+	if len(c.Warnings) != 1 {
+		t.Errorf("expected file upload warning, got %v", c.Warnings)
+	}
+}
+
+func TestParseCurl_Invalid(t *testing.T) {
+	if _, err := ParseCurl([]byte(`wget https://example.com`)); err == nil {
+		t.Error("expected error without curl command")
+	}
+	if _, err := ParseCurl([]byte(`curl -H 'X: 1`)); err == nil {
+		t.Error("expected error for unterminated quote")
+	}
+}
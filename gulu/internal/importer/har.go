@@ -0,0 +1,371 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// SourceHAR HAR 导入来源标识
+const SourceHAR = "har"
+
+const (
+	minCorrelationLength = 8   // 参与关联的值最小长度，过短的值容易误替换
+	maxCorrelationDepth  = 8   // 响应 JSON 遍历的最大深度
+	maxCorrelationValues = 200 // 单个响应最多收集的候选值
+)
+
+var (
+	// staticExtensions 静态资源扩展名
+	staticExtensions = map[string]bool{
+		".js": true, ".mjs": true, ".css": true, ".map": true, ".png": true, ".jpg": true, ".jpeg": true,
+		".gif": true, ".svg": true, ".ico": true, ".webp": true, ".bmp": true, ".avif": true,
+		".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+		".mp3": true, ".mp4": true, ".webm": true, ".wav": true, ".ogg": true,
+	}
+	// staticMimePrefixes 静态资源响应类型
+	staticMimePrefixes = []string{
+		"image/", "font/", "audio/", "video/", "text/css", "text/javascript",
+		"application/javascript", "application/x-javascript", "application/font", "application/x-font",
+	}
+	// tokenHeaderExpr 可能携带令牌的响应头
+	tokenHeaderExpr = regexp.MustCompile(`(?i)token|auth|session|csrf|xsrf`)
+	// tokenValueExpr 可作为关联值的字符集合（不含空白）
+	tokenValueExpr = regexp.MustCompile(`^[A-Za-z0-9_.~+/=:\-]+$`)
+	// plainKeyExpr 可直接以 .key 形式出现在 JSONPath 中的字段名
+	plainKeyExpr = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// harCandidate 响应中可被后续请求引用的值
+type harCandidate struct {
+	step        int    // 产生该值的步骤下标
+	extractType string // jsonpath、header、cookie
+	expression  string
+	name        string // 建议的变量名
+	variable    string // 实际分配的变量名，被引用后才分配
+}
+
+// harCorrelator 记录响应中的候选值，并在后续请求中替换为变量引用
+type harCorrelator struct {
+	candidates map[string]*harCandidate // 值 -> 最近一次产生该值的来源
+	extracted  []*harCandidate          // 已被引用、需要生成提取处理器的来源
+	usedNames  map[string]int
+}
+
+func newHARCorrelator() *harCorrelator {
+	return &harCorrelator{candidates: make(map[string]*harCandidate), usedNames: make(map[string]int)}
+}
+
+// ParseHAR 解析 HAR 1.2 文件：过滤静态资源后按时间顺序生成 http 步骤，
+// 并将响应中出现的令牌自动关联到后续请求（生成提取处理器，并在请求中以 ${变量} 引用）
+func ParseHAR(content []byte) (*Collection, error) {
+	var doc struct {
+		Log struct {
+			Pages []struct {
+				Title string `json:"title"`
+			} `json:"pages"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("HAR 文件解析失败: %v", err)
+	}
+	if len(doc.Log.Entries) == 0 {
+		return nil, errors.New("HAR 文件中没有任何请求")
+	}
+
+	title := "HAR"
+	if len(doc.Log.Pages) > 0 && doc.Log.Pages[0].Title != "" {
+		title = doc.Log.Pages[0].Title
+	}
+	collection := &Collection{Source: SourceHAR, Title: title}
+	servers := newServerSet(collection)
+	ids := newStepIDAllocator(collection.StepPrefix())
+	group := &Group{Name: title, Variables: make(map[string]interface{})}
+
+	entries := doc.Log.Entries
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartedDateTime < entries[j].StartedDateTime })
+
+	correlator := newHARCorrelator()
+	var skipped int
+	for i := range entries {
+		e := &entries[i]
+		if e.isStatic() {
+			skipped++
+			continue
+		}
+
+		req := e.rawRequest()
+		correlator.correlate(req)
+
+		_, urlPath, _ := splitURL(req.url)
+		step := buildRawStep(req, servers, ids, req.method+"_"+urlPath)
+		if e.Response.Status > 0 {
+			code := strconv.Itoa(e.Response.Status)
+			step.PostProcessors = append(step.PostProcessors, types.Processor{
+				ID:      step.ID + "_status",
+				Type:    "assertion",
+				Enabled: true,
+				Name:    "状态码为 " + code,
+				Config: map[string]interface{}{
+					"assertType": "status_code",
+					"operator":   "eq",
+					"expected":   code,
+				},
+			})
+		}
+		group.Steps = append(group.Steps, step)
+		correlator.harvest(e, len(group.Steps)-1, req)
+	}
+	if len(group.Steps) == 0 {
+		return nil, errors.New("HAR 文件中没有可导入的接口请求")
+	}
+	if skipped > 0 {
+		collection.addWarning("已过滤 %d 个静态资源或预检请求", skipped)
+	}
+
+	// 为被引用的值在来源步骤上生成提取处理器
+	for _, cand := range correlator.extracted {
+		step := &group.Steps[cand.step]
+		step.PostProcessors = append(step.PostProcessors, types.Processor{
+			ID:      step.ID + "_extract_" + cand.variable,
+			Type:    "extract_param",
+			Enabled: true,
+			Name:    "提取 " + cand.variable,
+			Config: map[string]interface{}{
+				"extractType":  cand.extractType,
+				"expression":   cand.expression,
+				"variableName": cand.variable,
+				"scope":        "temp",
+			},
+		})
+	}
+
+	collection.Groups = []*Group{group}
+	return collection, nil
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harEntry struct {
+	StartedDateTime string `json:"startedDateTime"`
+	Request         struct {
+		Method   string         `json:"method"`
+		URL      string         `json:"url"`
+		Headers  []harNameValue `json:"headers"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Params   []struct {
+				Name     string `json:"name"`
+				Value    string `json:"value"`
+				FileName string `json:"fileName"`
+			} `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int            `json:"status"`
+		Headers []harNameValue `json:"headers"`
+		Cookies []harNameValue `json:"cookies"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// isStatic 判断是否为静态资源、预检或非 HTTP 请求
+func (e *harEntry) isStatic() bool {
+	if e.Request.Method == "OPTIONS" {
+		return true
+	}
+	if !strings.HasPrefix(e.Request.URL, "http://") && !strings.HasPrefix(e.Request.URL, "https://") {
+		return true
+	}
+	_, urlPath, _ := splitURL(e.Request.URL)
+	if staticExtensions[strings.ToLower(path.Ext(urlPath))] {
+		return true
+	}
+	mime := strings.ToLower(e.Response.Content.MimeType)
+	for _, prefix := range staticMimePrefixes {
+		if strings.HasPrefix(mime, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rawRequest 转换为原始请求
+func (e *harEntry) rawRequest() *rawRequest {
+	req := &rawRequest{method: strings.ToUpper(e.Request.Method), url: e.Request.URL}
+	for _, h := range e.Request.Headers {
+		req.headers = append(req.headers, [2]string{h.Name, h.Value})
+	}
+	if pd := e.Request.PostData; pd != nil {
+		if strings.Contains(strings.ToLower(pd.MimeType), "multipart") {
+			req.multipart = true
+			for _, p := range pd.Params {
+				req.form = append(req.form, [2]string{p.Name, p.Value})
+			}
+		} else {
+			req.body = pd.Text
+		}
+		if req.header("Content-Type") == "" && pd.MimeType != "" {
+			req.setHeader("Content-Type", pd.MimeType)
+		}
+	}
+	return req
+}
+
+// harvest 收集响应中可能被后续请求引用的值：JSON 字符串字段、令牌类响应头与 Cookie。
+// 请求中已出现的值（服务端回显）不作为候选
+func (h *harCorrelator) harvest(e *harEntry, step int, req *rawRequest) {
+	sent := req.url + "\n" + req.body
+	for _, header := range req.headers {
+		sent += "\n" + header[1]
+	}
+	add := func(value string, cand *harCandidate) {
+		if len(value) < minCorrelationLength || !tokenValueExpr.MatchString(value) || strings.Contains(sent, value) {
+			return
+		}
+		// 后出现的响应覆盖先前的来源，引用最近一次的值
+		h.candidates[value] = cand
+	}
+
+	for _, header := range e.Response.Headers {
+		if tokenHeaderExpr.MatchString(header.Name) && !strings.EqualFold(header.Name, "Set-Cookie") {
+			add(header.Value, &harCandidate{step: step, extractType: "header", expression: header.Name, name: header.Name})
+		}
+	}
+	for _, c := range e.Response.Cookies {
+		add(c.Value, &harCandidate{step: step, extractType: "cookie", expression: c.Name, name: c.Name})
+	}
+
+	if !strings.Contains(strings.ToLower(e.Response.Content.MimeType), "json") {
+		return
+	}
+	text := e.Response.Content.Text
+	if e.Response.Content.Encoding == "base64" {
+		data, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return
+		}
+		text = string(data)
+	}
+	var body interface{}
+	if err := json.Unmarshal([]byte(text), &body); err != nil {
+		return
+	}
+	count := 0
+	walkJSONStrings(body, "$", "", 0, func(jsonPath, key, value string) bool {
+		if count >= maxCorrelationValues {
+			return false
+		}
+		count++
+		add(value, &harCandidate{step: step, extractType: "jsonpath", expression: jsonPath, name: key})
+		return true
+	})
+}
+
+// walkJSONStrings 遍历 JSON 中的字符串叶子节点，fn 返回 false 时停止
+func walkJSONStrings(node interface{}, jsonPath, key string, depth int, fn func(jsonPath, key, value string) bool) bool {
+	if depth > maxCorrelationDepth {
+		return true
+	}
+	switch v := node.(type) {
+	case string:
+		return fn(jsonPath, key, v)
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			childPath := jsonPath + "." + k
+			if !plainKeyExpr.MatchString(k) {
+				childPath = jsonPath + "['" + k + "']"
+			}
+			if !walkJSONStrings(v[k], childPath, k, depth+1, fn) {
+				return false
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if !walkJSONStrings(item, fmt.Sprintf("%s[%d]", jsonPath, i), key, depth+1, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// correlate 将请求中出现的候选值替换为变量引用，较长的值优先替换
+func (h *harCorrelator) correlate(req *rawRequest) {
+	if len(h.candidates) == 0 {
+		return
+	}
+	values := make([]string, 0, len(h.candidates))
+	for v := range h.candidates {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	// 单次替换，避免变量引用再次被替换
+	replace := func(s string) string {
+		var pairs []string
+		for _, v := range values {
+			if strings.Contains(s, v) {
+				pairs = append(pairs, v, "${"+h.assign(h.candidates[v])+"}")
+			}
+		}
+		if len(pairs) == 0 {
+			return s
+		}
+		return strings.NewReplacer(pairs...).Replace(s)
+	}
+
+	req.url = replace(req.url)
+	req.body = replace(req.body)
+	for i := range req.headers {
+		if !strings.HasPrefix(req.headers[i][0], ":") && !skippedHeaders[strings.ToLower(req.headers[i][0])] {
+			req.headers[i][1] = replace(req.headers[i][1])
+		}
+	}
+	for i := range req.form {
+		req.form[i][1] = replace(req.form[i][1])
+	}
+}
+
+// assign 为被引用的来源分配变量名，同名变量追加序号
+func (h *harCorrelator) assign(c *harCandidate) string {
+	if c.variable != "" {
+		return c.variable
+	}
+	name := strings.Trim(nonIdentChars.ReplaceAllString(c.name, "_"), "_")
+	switch {
+	case name == "":
+		name = "value"
+	case name[0] >= '0' && name[0] <= '9':
+		name = "v_" + name
+	}
+	h.usedNames[name]++
+	if n := h.usedNames[name]; n > 1 {
+		name = fmt.Sprintf("%s_%d", name, n)
+	}
+	c.variable = name
+	h.extracted = append(h.extracted, c)
+	return name
+}
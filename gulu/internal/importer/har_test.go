@@ -0,0 +1,102 @@
+package importer
+
+import "testing"
+
+const sampleHAR = `{"log": {
+	"pages": [{"title": "Shop session"}],
+	"entries": [
+		{
+			"startedDateTime": "2024-01-01T00:00:00.000Z",
+			"request": {"method": "POST", "url": "https://api.example.com/login",
+				"headers": [{"name": ":authority", "value": "api.example.com"}, {"name": "Content-Type", "value": "application/json"}],
+				"postData": {"mimeType": "application/json", "text": "{\"user\":\"alice\"}"}},
+			"response": {"status": 200,
+				"headers": [{"name": "X-Csrf-Token", "value": "csrf-9f8e7d6c"}],
+				"cookies": [{"name": "SID", "value": "sess-0123456789"}],
+				"content": {"mimeType": "application/json", "text": "{\"data\":{\"token\":\"eyJhbGciOi.abc\",\"name\":\"alice\"}}"}}
+		},
+		{
+			"startedDateTime": "2024-01-01T00:00:01.000Z",
+			"request": {"method": "GET", "url": "https://cdn.example.com/app.js", "headers": []},
+			"response": {"status": 200, "headers": [], "content": {"mimeType": "application/javascript"}}
+		},
+		{
+			"startedDateTime": "2024-01-01T00:00:02.000Z",
+			"request": {"method": "OPTIONS", "url": "https://api.example.com/orders", "headers": []},
+			"response": {"status": 204, "headers": [], "content": {}}
+		},
+		{
+			"startedDateTime": "2024-01-01T00:00:03.000Z",
+			"request": {"method": "GET", "url": "https://api.example.com/orders?user=alice",
+				"headers": [
+					{"name": "Authorization", "value": "Bearer eyJhbGciOi.abc"},
+					{"name": "X-Csrf-Token", "value": "csrf-9f8e7d6c"},
+					{"name": "Cookie", "value": "SID=sess-0123456789"}
+				]},
+			"response": {"status": 200, "headers": [], "content": {"mimeType": "application/json", "text": "[]"}}
+		}
+	]
+}}`
+
+func TestParseHAR(t *testing.T) {
+	c, err := ParseHAR([]byte(sampleHAR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Groups) != 1 || c.Groups[0].Name != "Shop session" {
+		t.Fatalf("unexpected groups: %+v", c.Groups)
+	}
+	steps := c.Groups[0].Steps
+	if len(steps) != 2 {
+		t.Fatalf("expected static and preflight requests to be filtered, got %d steps", len(steps))
+	}
+	if len(c.Warnings) != 1 || len(c.Servers) != 1 {
+		t.Errorf("unexpected warnings/servers: %v %+v", c.Warnings, c.Servers)
+	}
+
+	login := steps[0]
+	if headers := login.Config["headers"].([]interface{}); len(headers) != 1 {
+		t.Errorf("expected pseudo headers to be dropped, got %v", headers)
+	}
+	extracts := map[string]map[string]interface{}{}
+	for _, p := range login.PostProcessors {
+		if p.Type == "extract_param" {
+			extracts[p.Config["variableName"].(string)] = p.Config
+		}
+	}
+	if len(extracts) != 3 {
+		t.Fatalf("expected 3 extractors, got %+v", login.PostProcessors)
+	}
+	if e := extracts["token"]; e == nil || e["extractType"] != "jsonpath" || e["expression"] != "$.data.token" {
+		t.Errorf("unexpected token extractor: %v", e)
+	}
+	if e := extracts["X_Csrf_Token"]; e == nil || e["extractType"] != "header" {
+		t.Errorf("unexpected csrf extractor: %v", extracts)
+	}
+	if e := extracts["SID"]; e == nil || e["extractType"] != "cookie" {
+		t.Errorf("unexpected cookie extractor: %v", extracts)
+	}
+
+	orders := steps[1]
+	want := map[string]string{
+		"Authorization": "Bearer ${token}",
+		"X-Csrf-Token":  "${X_Csrf_Token}",
+		"Cookie":        "SID=${SID}",
+	}
+	for _, h := range orders.Config["headers"].([]interface{}) {
+		hm := h.(map[string]interface{})
+		if want[hm["key"].(string)] != hm["value"] {
+			t.Errorf("header %v not correlated, got %v", hm["key"], hm["value"])
+		}
+	}
+	// 用户名来自请求本身，不应被关联
+	if params := orders.Config["params"].([]interface{}); params[0].(map[string]interface{})["value"] != "alice" {
+		t.Errorf("unexpected params: %v", params)
+	}
+}
+
+func TestParseHAR_Invalid(t *testing.T) {
+	if _, err := ParseHAR([]byte(`{"log": {"entries": []}}`)); err == nil {
+		t.Error("expected error for empty har")
+	}
+}
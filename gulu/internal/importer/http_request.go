@@ -0,0 +1,158 @@
+package importer
+
+import (
+	"net/url"
+	"strings"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// skippedHeaders 由客户端自动生成、回放时不应携带的请求头
+var skippedHeaders = map[string]bool{
+	"host":            true,
+	"content-length":  true,
+	"connection":      true,
+	"accept-encoding": true,
+}
+
+// rawRequest HAR、cURL 等来源解析出的原始请求
+type rawRequest struct {
+	name      string
+	method    string
+	url       string      // 完整地址
+	headers   [][2]string // 保持原始顺序
+	body      string
+	form      [][2]string // multipart 字段
+	multipart bool
+}
+
+// header 获取请求头（忽略大小写）
+func (r *rawRequest) header(name string) string {
+	for _, h := range r.headers {
+		if strings.EqualFold(h[0], name) {
+			return h[1]
+		}
+	}
+	return ""
+}
+
+// setHeader 设置请求头，已存在时覆盖
+func (r *rawRequest) setHeader(name, value string) {
+	for i, h := range r.headers {
+		if strings.EqualFold(h[0], name) {
+			r.headers[i][1] = value
+			return
+		}
+	}
+	r.headers = append(r.headers, [2]string{name, value})
+}
+
+// buildRawStep 将原始请求转换为 http 步骤：协议与主机作为服务地址，其余部分作为路径与参数
+func buildRawStep(req *rawRequest, servers *serverSet, ids *stepIDAllocator, idKey string) types.Step {
+	origin, path, query := splitURL(req.url)
+	body := rawBody(req)
+
+	headers := make([]interface{}, 0, len(req.headers))
+	for _, h := range req.headers {
+		if strings.HasPrefix(h[0], ":") || skippedHeaders[strings.ToLower(h[0])] {
+			continue
+		}
+		// multipart 的 boundary 由执行器重新生成
+		if body != nil && body["type"] == "form-data" && strings.EqualFold(h[0], "Content-Type") {
+			continue
+		}
+		headers = append(headers, keyValue(h[0], h[1], true))
+	}
+	params := make([]interface{}, 0, len(query))
+	for _, q := range query {
+		params = append(params, keyValue(q[0], q[1], true))
+	}
+
+	config := map[string]interface{}{
+		"method":  req.method,
+		"url":     path,
+		"params":  params,
+		"headers": headers,
+	}
+	if origin != "" {
+		config["domainCode"] = servers.add([][2]string{{origin, origin}}).Key
+	}
+	if body != nil {
+		config["body"] = body
+	}
+
+	name := req.name
+	if name == "" {
+		name = req.method + " " + path
+	}
+	return types.Step{
+		ID:     ids.next(idKey),
+		Name:   name,
+		Type:   "http",
+		Config: config,
+	}
+}
+
+// splitURL 拆分完整地址为服务地址（scheme://host）、路径与有序的查询参数；
+// 无法识别协议与主机时（如以变量开头或主机中含变量）整体作为路径返回
+func splitURL(raw string) (origin, path string, query [][2]string) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Contains(u.Host, "$") {
+		base, rawQuery, _ := strings.Cut(raw, "?")
+		return "", base, parseQuery(rawQuery)
+	}
+	path = u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return u.Scheme + "://" + u.Host, path, parseQuery(u.RawQuery)
+}
+
+// parseQuery 按原始顺序解析查询字符串或 urlencoded 请求体
+func parseQuery(raw string) [][2]string {
+	var pairs [][2]string
+	for _, part := range strings.Split(raw, "&") {
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return pairs
+}
+
+// rawBody 按 Content-Type 生成请求体配置
+func rawBody(req *rawRequest) map[string]interface{} {
+	if req.multipart || len(req.form) > 0 {
+		items := make([]interface{}, 0, len(req.form))
+		for _, f := range req.form {
+			items = append(items, keyValue(f[0], f[1], true))
+		}
+		return map[string]interface{}{"type": "form-data", "formData": items}
+	}
+	if req.body == "" {
+		return nil
+	}
+
+	contentType := strings.ToLower(req.header("Content-Type"))
+	switch {
+	case strings.Contains(contentType, "json"):
+		return map[string]interface{}{"type": "json", "raw": req.body}
+	case strings.Contains(contentType, "x-www-form-urlencoded"):
+		items := make([]interface{}, 0)
+		for _, p := range parseQuery(req.body) {
+			items = append(items, keyValue(p[0], p[1], true))
+		}
+		return map[string]interface{}{"type": "x-www-form-urlencoded", "urlencoded": items}
+	case strings.Contains(contentType, "xml"):
+		return map[string]interface{}{"type": "xml", "raw": req.body}
+	default:
+		return map[string]interface{}{"type": "text", "raw": req.body}
+	}
+}
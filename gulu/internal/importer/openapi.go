@@ -0,0 +1,726 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// SourceOpenAPI OpenAPI/Swagger 导入来源标识
+const SourceOpenAPI = "openapi"
+
+// 分组方式
+const (
+	GroupByTag  = "tag"  // 按第一个标签分组（默认）
+	GroupByPath = "path" // 按路径第一段分组
+)
+
+const (
+	defaultGroupName = "default"
+	maxSchemaDepth   = 8
+)
+
+var (
+	httpMethods   = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+	pathParamExpr = regexp.MustCompile(`\{([^{}]+)\}`)
+)
+
+// openAPIDoc OpenAPI 3 / Swagger 2 文档的统一访问封装
+type openAPIDoc struct {
+	raw     map[string]interface{}
+	swagger bool // Swagger 2.0
+}
+
+// openAPIOperation 解析后的接口
+type openAPIOperation struct {
+	method   string
+	path     string
+	item     map[string]interface{} // path item
+	op       map[string]interface{}
+	server   *Server
+	groupKey string
+}
+
+// ParseOpenAPI 解析 OpenAPI 3 或 Swagger 2 文档（JSON/YAML），按 groupBy 分组生成 http 步骤：
+// 路径参数转为 ${变量}，请求体按 schema 生成示例，响应生成状态码与 JSON Schema 断言
+func ParseOpenAPI(content []byte, groupBy string) (*Collection, error) {
+	raw, err := decodeDocument(content)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &openAPIDoc{raw: raw}
+	switch {
+	case strings.HasPrefix(stringOf(raw, "openapi"), "3."):
+	case stringOf(raw, "swagger") == "2.0":
+		doc.swagger = true
+	default:
+		return nil, errors.New("无法识别的文档：仅支持 OpenAPI 3.x 与 Swagger 2.0")
+	}
+
+	info := mapOf(raw, "info")
+	title := stringOf(info, "title")
+	if title == "" {
+		title = "OpenAPI"
+	}
+
+	collection := &Collection{Source: SourceOpenAPI, Title: title}
+	servers := newServerSet(collection)
+	defaultServer := servers.add(doc.serverURLs(raw, nil))
+
+	paths := mapOf(raw, "paths")
+	if len(paths) == 0 {
+		return nil, errors.New("文档中没有任何接口")
+	}
+	pathKeys := make([]string, 0, len(paths))
+	for p := range paths {
+		pathKeys = append(pathKeys, p)
+	}
+	sort.Strings(pathKeys)
+
+	var ops []*openAPIOperation
+	for _, p := range pathKeys {
+		item, ok := paths[p].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ref := stringOf(item, "$ref"); ref != "" {
+			item, _ = doc.resolveRef(ref).(map[string]interface{})
+		}
+		for _, method := range httpMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			server := defaultServer
+			if urls := doc.serverURLs(op, item); len(urls) > 0 {
+				server = servers.add(urls)
+			}
+			ops = append(ops, &openAPIOperation{
+				method:   method,
+				path:     p,
+				item:     item,
+				op:       op,
+				server:   server,
+				groupKey: groupKeyOf(op, p, doc.basePath(), groupBy),
+			})
+		}
+	}
+	if len(ops) == 0 {
+		return nil, errors.New("文档中没有任何接口")
+	}
+
+	tagDescriptions := make(map[string]string)
+	for _, t := range listOf(raw, "tags") {
+		if tm, ok := t.(map[string]interface{}); ok {
+			tagDescriptions[stringOf(tm, "name")] = stringOf(tm, "description")
+		}
+	}
+
+	groups := make(map[string]*Group)
+	var groupOrder []string
+	ids := newStepIDAllocator(collection.StepPrefix())
+	for _, op := range ops {
+		group, ok := groups[op.groupKey]
+		if !ok {
+			group = &Group{
+				Name:        title + " - " + op.groupKey,
+				Description: tagDescriptions[op.groupKey],
+				Variables:   make(map[string]interface{}),
+			}
+			groups[op.groupKey] = group
+			groupOrder = append(groupOrder, op.groupKey)
+		}
+		group.Steps = append(group.Steps, doc.buildStep(op, ids, group.Variables))
+	}
+	for _, key := range groupOrder {
+		collection.Groups = append(collection.Groups, groups[key])
+	}
+
+	return collection, nil
+}
+
+// groupKeyOf 计算接口所属分组
+func groupKeyOf(op map[string]interface{}, path, basePath, groupBy string) string {
+	if groupBy == GroupByPath {
+		trimmed := strings.TrimPrefix(path, basePath)
+		for _, seg := range strings.Split(trimmed, "/") {
+			if seg != "" && !strings.HasPrefix(seg, "{") {
+				return seg
+			}
+		}
+		return defaultGroupName
+	}
+	if tags := listOf(op, "tags"); len(tags) > 0 {
+		if tag, ok := tags[0].(string); ok && tag != "" {
+			return tag
+		}
+	}
+	return defaultGroupName
+}
+
+// serverSet 按 URL 去重服务地址
+type serverSet struct {
+	collection *Collection
+	byURL      map[string]*Server
+}
+
+func newServerSet(c *Collection) *serverSet {
+	return &serverSet{collection: c, byURL: make(map[string]*Server)}
+}
+
+// add 注册服务地址，取第一个地址；没有地址时注册一个空地址，由用户在环境中补充
+func (s *serverSet) add(urls [][2]string) *Server {
+	serverURL, desc := "", ""
+	if len(urls) > 0 {
+		serverURL, desc = urls[0][0], urls[0][1]
+	}
+	if existing, ok := s.byURL[serverURL]; ok {
+		return existing
+	}
+	server := &Server{
+		Key:         fmt.Sprintf("server_%d", len(s.collection.Servers)+1),
+		URL:         serverURL,
+		Description: desc,
+	}
+	s.byURL[serverURL] = server
+	s.collection.Servers = append(s.collection.Servers, server)
+	return server
+}
+
+// serverURLs 获取服务地址列表 [url, description]；OpenAPI 3 依次查找 node、fallback 上的 servers
+func (d *openAPIDoc) serverURLs(node, fallback map[string]interface{}) [][2]string {
+	if d.swagger {
+		if fallback != nil {
+			return nil // Swagger 2 只有全局地址
+		}
+		host := stringOf(d.raw, "host")
+		if host == "" {
+			return nil
+		}
+		scheme := "https"
+		if schemes := listOf(d.raw, "schemes"); len(schemes) > 0 {
+			if s, ok := schemes[0].(string); ok {
+				scheme = s
+			}
+		}
+		return [][2]string{{scheme + "://" + host + strings.TrimSuffix(d.basePath(), "/"), ""}}
+	}
+
+	list := listOf(node, "servers")
+	if len(list) == 0 && fallback != nil {
+		list = listOf(fallback, "servers")
+	}
+	var urls [][2]string
+	for _, s := range list {
+		sm, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		u := stringOf(sm, "url")
+		// 服务器变量替换为默认值
+		for name, v := range mapOf(sm, "variables") {
+			if vm, ok := v.(map[string]interface{}); ok {
+				u = strings.ReplaceAll(u, "{"+name+"}", fmt.Sprint(vm["default"]))
+			}
+		}
+		urls = append(urls, [2]string{strings.TrimSuffix(u, "/"), stringOf(sm, "description")})
+	}
+	return urls
+}
+
+func (d *openAPIDoc) basePath() string {
+	if !d.swagger {
+		return ""
+	}
+	return stringOf(d.raw, "basePath")
+}
+
+// resolveRef 解析文档内引用（#/components/schemas/X、#/definitions/X 等）
+func (d *openAPIDoc) resolveRef(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var cur interface{} = d.raw
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		if unescaped, err := url.PathUnescape(part); err == nil {
+			part = unescaped
+		}
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// deref 解析节点自身的 $ref
+func (d *openAPIDoc) deref(node map[string]interface{}) map[string]interface{} {
+	for i := 0; i < maxSchemaDepth && node != nil; i++ {
+		ref := stringOf(node, "$ref")
+		if ref == "" {
+			return node
+		}
+		node, _ = d.resolveRef(ref).(map[string]interface{})
+	}
+	return node
+}
+
+// parameters 合并路径级与接口级参数，接口级按 name+in 覆盖
+func (d *openAPIDoc) parameters(item, op map[string]interface{}) []map[string]interface{} {
+	var result []map[string]interface{}
+	index := make(map[string]int)
+	for _, list := range [][]interface{}{listOf(item, "parameters"), listOf(op, "parameters")} {
+		for _, p := range list {
+			pm, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			pm = d.deref(pm)
+			if pm == nil {
+				continue
+			}
+			key := stringOf(pm, "in") + ":" + stringOf(pm, "name")
+			if i, exists := index[key]; exists {
+				result[i] = pm
+				continue
+			}
+			index[key] = len(result)
+			result = append(result, pm)
+		}
+	}
+	return result
+}
+
+// paramExample 参数示例值
+func (d *openAPIDoc) paramExample(p map[string]interface{}) interface{} {
+	if v, ok := p["example"]; ok {
+		return v
+	}
+	schema := mapOf(p, "schema")
+	if d.swagger || schema == nil {
+		schema = p // Swagger 2 非 body 参数的类型信息在参数自身
+	}
+	return d.example(schema, 0, nil)
+}
+
+// buildStep 将接口转换为 http 步骤，路径参数及必填查询参数写入 variables
+func (d *openAPIDoc) buildStep(op *openAPIOperation, ids *stepIDAllocator, variables map[string]interface{}) types.Step {
+	method := strings.ToUpper(op.method)
+	name := stringOf(op.op, "summary")
+	if name == "" {
+		name = stringOf(op.op, "operationId")
+	}
+	if name == "" {
+		name = method + " " + op.path
+	}
+	idKey := stringOf(op.op, "operationId")
+	if idKey == "" {
+		idKey = op.method + "_" + op.path
+	}
+
+	urlPath := pathParamExpr.ReplaceAllString(op.path, "$${$1}")
+	params := make([]interface{}, 0)
+	headers := make([]interface{}, 0)
+	var formParams []map[string]interface{}
+	var bodySchema map[string]interface{}
+
+	for _, p := range d.parameters(op.item, op.op) {
+		pname := stringOf(p, "name")
+		required, _ := p["required"].(bool)
+		switch stringOf(p, "in") {
+		case "path":
+			setDefaultVariable(variables, pname, d.paramExample(p))
+		case "query":
+			if required {
+				setDefaultVariable(variables, pname, d.paramExample(p))
+				params = append(params, keyValue(pname, "${"+pname+"}", true))
+			} else {
+				params = append(params, keyValue(pname, formatValue(d.paramExample(p)), false))
+			}
+		case "header":
+			headers = append(headers, keyValue(pname, formatValue(d.paramExample(p)), required))
+		case "body":
+			bodySchema = mapOf(p, "schema")
+		case "formData":
+			formParams = append(formParams, p)
+		}
+	}
+
+	config := map[string]interface{}{
+		"method":     method,
+		"domainCode": op.server.Key,
+		"url":        urlPath,
+		"params":     params,
+		"headers":    headers,
+	}
+
+	if body, contentType := d.requestBody(op, bodySchema, formParams); body != nil {
+		config["body"] = body
+		config["headers"] = append(headers, keyValue("Content-Type", contentType, true))
+	}
+
+	step := types.Step{
+		ID:     ids.next(idKey),
+		Name:   name,
+		Type:   "http",
+		Config: config,
+	}
+	step.PostProcessors = d.assertions(op, step.ID)
+	return step
+}
+
+// requestBody 生成请求体配置与 Content-Type
+func (d *openAPIDoc) requestBody(op *openAPIOperation, swaggerBody map[string]interface{}, formParams []map[string]interface{}) (map[string]interface{}, string) {
+	if d.swagger {
+		consumes := firstString(listOf(op.op, "consumes"), firstString(listOf(d.raw, "consumes"), "application/json"))
+		if swaggerBody != nil {
+			return jsonBody(d.example(swaggerBody, 0, nil)), "application/json"
+		}
+		if len(formParams) > 0 {
+			items := make([]interface{}, 0, len(formParams))
+			for _, p := range formParams {
+				required, _ := p["required"].(bool)
+				items = append(items, keyValue(stringOf(p, "name"), formatValue(d.paramExample(p)), required))
+			}
+			if strings.Contains(consumes, "multipart") {
+				return map[string]interface{}{"type": "form-data", "formData": items}, "multipart/form-data"
+			}
+			return map[string]interface{}{"type": "x-www-form-urlencoded", "urlencoded": items}, "application/x-www-form-urlencoded"
+		}
+		return nil, ""
+	}
+
+	rb := d.deref(mapOf(op.op, "requestBody"))
+	content := mapOf(rb, "content")
+	if len(content) == 0 {
+		return nil, ""
+	}
+	contentType, media := pickMediaType(content)
+	example := d.mediaExample(media)
+
+	switch {
+	case strings.Contains(contentType, "json"):
+		return jsonBody(example), contentType
+	case contentType == "application/x-www-form-urlencoded" || contentType == "multipart/form-data":
+		items := make([]interface{}, 0)
+		if obj, ok := example.(map[string]interface{}); ok {
+			keys := sortedKeys(obj)
+			for _, k := range keys {
+				items = append(items, keyValue(k, formatValue(obj[k]), true))
+			}
+		}
+		if contentType == "multipart/form-data" {
+			return map[string]interface{}{"type": "form-data", "formData": items}, contentType
+		}
+		return map[string]interface{}{"type": "x-www-form-urlencoded", "urlencoded": items}, contentType
+	case strings.Contains(contentType, "xml"):
+		return map[string]interface{}{"type": "xml", "raw": formatValue(example)}, contentType
+	default:
+		return map[string]interface{}{"type": "text", "raw": formatValue(example)}, contentType
+	}
+}
+
+// assertions 为成功响应生成状态码断言，JSON 响应额外生成 JSON Schema 断言
+func (d *openAPIDoc) assertions(op *openAPIOperation, stepID string) []types.Processor {
+	responses := mapOf(op.op, "responses")
+	code := ""
+	for _, c := range sortedKeys(responses) {
+		if strings.HasPrefix(c, "2") && len(c) == 3 {
+			code = c
+			break
+		}
+	}
+	if code == "" {
+		return nil
+	}
+
+	processors := []types.Processor{{
+		ID:      stepID + "_status",
+		Type:    "assertion",
+		Enabled: true,
+		Name:    "状态码为 " + code,
+		Config: map[string]interface{}{
+			"assertType": "status_code",
+			"operator":   "eq",
+			"expected":   code,
+		},
+	}}
+
+	resp := d.deref(mapOf(responses, code))
+	var schema map[string]interface{}
+	if d.swagger {
+		schema = mapOf(resp, "schema")
+	} else if content := mapOf(resp, "content"); len(content) > 0 {
+		if contentType, media := pickMediaType(content); strings.Contains(contentType, "json") {
+			schema = mapOf(media, "schema")
+		}
+	}
+	if schema == nil {
+		return processors
+	}
+
+	resolved := d.inlineSchema(schema, 0, nil)
+	data, err := json.Marshal(resolved)
+	if err != nil {
+		return processors
+	}
+	processors = append(processors, types.Processor{
+		ID:      stepID + "_schema",
+		Type:    "assertion",
+		Enabled: true,
+		Name:    "响应符合 JSON Schema",
+		Config: map[string]interface{}{
+			"assertType": "json_schema",
+			"operator":   "eq",
+			"expected":   string(data),
+		},
+	})
+	return processors
+}
+
+// mediaExample 媒体类型示例：example > examples 第一项 > 根据 schema 生成
+func (d *openAPIDoc) mediaExample(media map[string]interface{}) interface{} {
+	if v, ok := media["example"]; ok {
+		return v
+	}
+	if examples := mapOf(media, "examples"); len(examples) > 0 {
+		first := d.deref(mapOf(examples, sortedKeys(examples)[0]))
+		if v, ok := first["value"]; ok {
+			return v
+		}
+	}
+	return d.example(mapOf(media, "schema"), 0, nil)
+}
+
+// example 根据 schema 生成示例值；refs 记录当前路径上的引用以避免循环
+func (d *openAPIDoc) example(schema map[string]interface{}, depth int, refs map[string]bool) interface{} {
+	if schema == nil || depth > maxSchemaDepth {
+		return nil
+	}
+	if ref := stringOf(schema, "$ref"); ref != "" {
+		if refs[ref] {
+			return nil
+		}
+		next := copyRefs(refs)
+		next[ref] = true
+		resolved, _ := d.resolveRef(ref).(map[string]interface{})
+		return d.example(resolved, depth+1, next)
+	}
+	if v, ok := schema["example"]; ok {
+		return v
+	}
+	if v, ok := schema["default"]; ok {
+		return v
+	}
+	if enum := listOf(schema, "enum"); len(enum) > 0 {
+		return enum[0]
+	}
+
+	if allOf := listOf(schema, "allOf"); len(allOf) > 0 {
+		merged := make(map[string]interface{})
+		for _, s := range allOf {
+			sm, _ := s.(map[string]interface{})
+			if obj, ok := d.example(sm, depth+1, refs).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if list := listOf(schema, key); len(list) > 0 {
+			sm, _ := list[0].(map[string]interface{})
+			return d.example(sm, depth+1, refs)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		obj := make(map[string]interface{})
+		props := mapOf(schema, "properties")
+		for _, k := range sortedKeys(props) {
+			sm, _ := props[k].(map[string]interface{})
+			obj[k] = d.example(sm, depth+1, refs)
+		}
+		return obj
+	case "array":
+		item := d.example(mapOf(schema, "items"), depth+1, refs)
+		if item == nil {
+			return []interface{}{}
+		}
+		return []interface{}{item}
+	case "integer":
+		if v, ok := schema["minimum"].(float64); ok {
+			return v
+		}
+		return 0
+	case "number":
+		if v, ok := schema["minimum"].(float64); ok {
+			return v
+		}
+		return 0.0
+	case "boolean":
+		return true
+	case "string":
+		return stringExample(stringOf(schema, "format"))
+	}
+	return nil
+}
+
+// inlineSchema 展开 $ref 得到自包含的 schema，循环引用处截断为空 schema；
+// Swagger 2 的 x-nullable 转换为 nullable
+func (d *openAPIDoc) inlineSchema(node interface{}, depth int, refs map[string]bool) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref := stringOf(v, "$ref"); ref != "" {
+			if refs[ref] || depth > maxSchemaDepth {
+				return map[string]interface{}{}
+			}
+			next := copyRefs(refs)
+			next[ref] = true
+			return d.inlineSchema(d.resolveRef(ref), depth+1, next)
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			switch k {
+			case "example", "examples", "description", "xml", "externalDocs":
+				continue
+			case "x-nullable":
+				out["nullable"] = item
+			default:
+				out[k] = d.inlineSchema(item, depth+1, refs)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = d.inlineSchema(item, depth+1, refs)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func schemaType(schema map[string]interface{}) string {
+	if t := stringOf(schema, "type"); t != "" {
+		return t
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return ""
+}
+
+func stringExample(format string) string {
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "uri", "url":
+		return "https://example.com"
+	case "ipv4":
+		return "127.0.0.1"
+	case "binary", "byte":
+		return ""
+	default:
+		return "string"
+	}
+}
+
+// pickMediaType 优先选择 JSON 媒体类型
+func pickMediaType(content map[string]interface{}) (string, map[string]interface{}) {
+	keys := sortedKeys(content)
+	for _, k := range keys {
+		if strings.Contains(k, "json") {
+			return k, mapOf(content, k)
+		}
+	}
+	return keys[0], mapOf(content, keys[0])
+}
+
+func jsonBody(example interface{}) map[string]interface{} {
+	raw := "{}"
+	if example != nil {
+		if data, err := json.MarshalIndent(example, "", "  "); err == nil {
+			raw = string(data)
+		}
+	}
+	return map[string]interface{}{"type": "json", "raw": raw}
+}
+
+// setDefaultVariable 写入变量默认值，已存在时保留先出现的值
+func setDefaultVariable(variables map[string]interface{}, name string, value interface{}) {
+	if _, exists := variables[name]; exists {
+		return
+	}
+	variables[name] = formatValue(value)
+}
+
+// formatValue 将示例值格式化为字符串
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(val)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
+
+func firstString(list []interface{}, fallback string) string {
+	if len(list) > 0 {
+		if s, ok := list[0].(string); ok {
+			return s
+		}
+	}
+	return fallback
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyRefs(refs map[string]bool) map[string]bool {
+	next := make(map[string]bool, len(refs)+1)
+	for k, v := range refs {
+		next[k] = v
+	}
+	return next
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const petstoreOpenAPI = `
+openapi: 3.0.3
+info:
+  title: Petstore
+servers:
+  - url: https://{env}.example.com/v1
+    variables:
+      env:
+        default: api
+tags:
+  - name: pets
+    description: Pet operations
+paths:
+  /pets/{petId}:
+    get:
+      tags: [pets]
+      summary: Get pet
+      operationId: getPet
+      parameters:
+        - name: petId
+          in: path
+          required: true
+          schema: {type: integer, example: 42}
+        - name: verbose
+          in: query
+          schema: {type: boolean}
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+  /pets:
+    post:
+      tags: [pets]
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Pet'}
+      responses:
+        '201': {description: created}
+  /health:
+    get:
+      responses:
+        '204': {description: ok}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id: {type: integer}
+        name: {type: string, example: doggie}
+        parent: {$ref: '#/components/schemas/Pet'}
+`
+
+func TestParseOpenAPI3(t *testing.T) {
+	c, err := ParseOpenAPI([]byte(petstoreOpenAPI), GroupByTag)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Servers) != 1 || c.Servers[0].URL != "https://api.example.com/v1" {
+		t.Fatalf("unexpected servers: %+v", c.Servers)
+	}
+	if len(c.Groups) != 2 || c.Groups[0].Name != "Petstore - default" || c.Groups[1].Name != "Petstore - pets" {
+		t.Fatalf("unexpected groups: %+v", c.Groups)
+	}
+
+	pets := c.Groups[1]
+	if pets.Description != "Pet operations" || pets.Variables["petId"] != "42" {
+		t.Errorf("unexpected group meta: %+v", pets)
+	}
+
+	var get, create = pets.Steps[1], pets.Steps[0]
+	if get.ID != "openapi_getPet" || get.Config["url"] != "/pets/${petId}" || get.Config["method"] != "GET" {
+		t.Errorf("unexpected get step: %+v", get)
+	}
+	if get.Config["domainCode"] != c.Servers[0].Key {
+		t.Errorf("expected domainCode %s, got %v", c.Servers[0].Key, get.Config["domainCode"])
+	}
+	if len(get.PostProcessors) != 2 || get.PostProcessors[1].Config["assertType"] != "json_schema" {
+		t.Fatalf("expected status and schema assertions, got %+v", get.PostProcessors)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(get.PostProcessors[1].Config["expected"].(string)), &schema); err != nil {
+		t.Fatalf("schema is not valid json: %v", err)
+	}
+	if _, ok := schema["$ref"]; ok {
+		t.Error("expected $ref to be inlined")
+	}
+
+	body, ok := create.Config["body"].(map[string]interface{})
+	if !ok || body["type"] != "json" {
+		t.Fatalf("expected json body, got %+v", create.Config["body"])
+	}
+	var example map[string]interface{}
+	if err := json.Unmarshal([]byte(body["raw"].(string)), &example); err != nil {
+		t.Fatal(err)
+	}
+	if example["name"] != "doggie" {
+		t.Errorf("expected example from schema, got %v", example)
+	}
+	if create.PostProcessors[0].Config["expected"] != "201" {
+		t.Errorf("expected status 201 assertion, got %+v", create.PostProcessors)
+	}
+}
+
+func TestParseSwagger2(t *testing.T) {
+	doc := `{
+		"swagger": "2.0",
+		"info": {"title": "Users"},
+		"host": "api.example.com",
+		"basePath": "/v2",
+		"schemes": ["http"],
+		"paths": {
+			"/users/{id}": {
+				"put": {
+					"parameters": [
+						{"name": "id", "in": "path", "required": true, "type": "string"},
+						{"name": "body", "in": "body", "schema": {"$ref": "#/definitions/User"}}
+					],
+					"responses": {"200": {"description": "ok", "schema": {"$ref": "#/definitions/User"}}}
+				}
+			}
+		},
+		"definitions": {"User": {"type": "object", "properties": {"email": {"type": "string", "format": "email"}}}}
+	}`
+
+	c, err := ParseOpenAPI([]byte(doc), GroupByPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Servers[0].URL != "http://api.example.com/v2" {
+		t.Errorf("unexpected server: %+v", c.Servers[0])
+	}
+	if len(c.Groups) != 1 || c.Groups[0].Name != "Users - users" {
+		t.Fatalf("unexpected groups: %+v", c.Groups)
+	}
+	step := c.Groups[0].Steps[0]
+	if step.ID != "openapi_put_users_id" || step.Config["url"] != "/users/${id}" {
+		t.Errorf("unexpected step: %+v", step)
+	}
+	body := step.Config["body"].(map[string]interface{})
+	if body["raw"] != "{\n  \"email\": \"user@example.com\"\n}" {
+		t.Errorf("unexpected body: %v", body["raw"])
+	}
+}
+
+func TestParseOpenAPI_Invalid(t *testing.T) {
+	if _, err := ParseOpenAPI([]byte(`{"info": {}}`), GroupByTag); err == nil {
+		t.Error("expected error for unknown document")
+	}
+}
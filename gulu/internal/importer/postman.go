@@ -0,0 +1,568 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"yqhp/workflow-engine/pkg/script"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// SourcePostman Postman Collection 导入来源标识
+const SourcePostman = "postman"
+
+// Postman 分组方式
+const (
+	GroupByFolder = "folder" // 顶层文件夹各生成一个工作流（默认）
+	GroupByNone   = "none"   // 全部请求按顺序合并为一个工作流
+)
+
+var postmanVarExpr = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// postmanScope 文件夹层级向下继承的认证与脚本
+type postmanScope struct {
+	path   []string // 相对所在工作流的文件夹路径
+	auth   map[string]interface{}
+	events []postmanEvent
+}
+
+// postmanEvent 集合、文件夹或请求上的脚本
+type postmanEvent struct {
+	listen string // prerequest、test
+	owner  string // 脚本所属的集合或文件夹名称，请求自身的脚本为空
+	code   string
+}
+
+type postmanParser struct {
+	collection *Collection
+	servers    *serverSet
+	ids        *stepIDAllocator
+}
+
+// ParsePostman 解析 Postman Collection v2.0/v2.1 及可选的 Postman 环境文件：
+// 文件夹按 groupBy 生成工作流或合并为步骤，集合变量与环境变量转为环境配置（{{x}} 转换为 ${env.x}），
+// 认证与脚本沿文件夹继承，脚本以 Postman 兼容模式的 js_script 处理器运行
+func ParsePostman(content, environment []byte, groupBy string) (*Collection, error) {
+	raw, err := decodeDocument(content)
+	if err != nil {
+		return nil, err
+	}
+	info := mapOf(raw, "info")
+	if _, ok := raw["item"].([]interface{}); !ok || info == nil {
+		if _, v1 := raw["requests"]; v1 {
+			return nil, errors.New("仅支持 Postman Collection v2.0/v2.1，请在 Postman 中重新导出")
+		}
+		return nil, errors.New("无法识别的文档：不是 Postman Collection")
+	}
+
+	title := stringOf(info, "name")
+	if title == "" {
+		title = "Postman"
+	}
+	p := &postmanParser{collection: &Collection{Source: SourcePostman, Title: title}}
+	p.servers = newServerSet(p.collection)
+	p.ids = newStepIDAllocator(p.collection.StepPrefix())
+
+	for _, v := range listOf(raw, "variable") {
+		if vm, ok := v.(map[string]interface{}); ok && !isDisabled(vm) {
+			p.setEnvVariable(stringOf(vm, "key"), formatValue(vm["value"]), stringOf(vm, "type") == "secret")
+		}
+	}
+	if len(environment) > 0 {
+		env, err := decodeDocument(environment)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range listOf(env, "values") {
+			vm, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if enabled, ok := vm["enabled"].(bool); ok && !enabled {
+				continue
+			}
+			p.setEnvVariable(stringOf(vm, "key"), formatValue(vm["value"]), stringOf(vm, "type") == "secret")
+		}
+	}
+
+	root := postmanScope{auth: mapOf(raw, "auth"), events: postmanEvents(raw, title)}
+	items := listOf(raw, "item")
+
+	if groupBy == GroupByNone {
+		group := &Group{Name: title, Description: descriptionOf(info), Variables: make(map[string]interface{})}
+		p.walk(items, root, group)
+		p.collection.Groups = []*Group{group}
+	} else {
+		// 根级请求归入以集合命名的工作流，顶层文件夹各自生成工作流
+		var rootGroup *Group
+		for _, it := range items {
+			item, ok := it.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if children, isFolder := item["item"].([]interface{}); isFolder {
+				name := stringOf(item, "name")
+				group := &Group{Name: title + " - " + name, Description: descriptionOf(item), Variables: make(map[string]interface{})}
+				// 顶层文件夹本身即工作流，不作为步骤名前缀
+				scope := root.child(item)
+				scope.path = nil
+				p.walk(children, scope, group)
+				p.collection.Groups = append(p.collection.Groups, group)
+				continue
+			}
+			if rootGroup == nil {
+				rootGroup = &Group{Name: title, Description: descriptionOf(info), Variables: make(map[string]interface{})}
+				p.collection.Groups = append(p.collection.Groups, rootGroup)
+			}
+			p.walk([]interface{}{item}, root, rootGroup)
+		}
+	}
+
+	steps := 0
+	for _, g := range p.collection.Groups {
+		steps += len(g.Steps)
+	}
+	if steps == 0 {
+		return nil, errors.New("集合中没有任何请求")
+	}
+	return p.collection, nil
+}
+
+// child 进入子文件夹：追加路径，文件夹自身的认证覆盖上级（inherit 除外），脚本追加在上级之后
+func (s postmanScope) child(folder map[string]interface{}) postmanScope {
+	next := postmanScope{auth: s.auth, events: s.events}
+	next.path = append(append([]string{}, s.path...), stringOf(folder, "name"))
+	if auth := mapOf(folder, "auth"); auth != nil && stringOf(auth, "type") != "inherit" {
+		next.auth = auth
+	}
+	if events := postmanEvents(folder, stringOf(folder, "name")); len(events) > 0 {
+		next.events = append(append([]postmanEvent{}, s.events...), events...)
+	}
+	return next
+}
+
+// walk 深度优先遍历条目，请求按顺序追加为分组步骤，子文件夹名称作为步骤名前缀
+func (p *postmanParser) walk(items []interface{}, scope postmanScope, group *Group) {
+	for _, it := range items {
+		item, ok := it.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if children, isFolder := item["item"].([]interface{}); isFolder {
+			p.walk(children, scope.child(item), group)
+			continue
+		}
+		if _, ok := item["request"]; ok {
+			group.Steps = append(group.Steps, p.buildStep(item, scope, group))
+		}
+	}
+}
+
+// buildStep 将请求条目转换为 http 步骤
+func (p *postmanParser) buildStep(item map[string]interface{}, scope postmanScope, group *Group) types.Step {
+	name := stringOf(item, "name")
+	fullName := strings.Join(append(append([]string{}, scope.path...), name), " / ")
+
+	request, _ := item["request"].(map[string]interface{})
+	if s, ok := item["request"].(string); ok {
+		request = map[string]interface{}{"url": s}
+	}
+	method := strings.ToUpper(stringOf(request, "method"))
+	if method == "" {
+		method = "GET"
+	}
+
+	rawURL, query := p.requestURL(request, group)
+	origin, urlPath, rawQuery := splitURL(rawURL)
+	if query == nil {
+		query = make([]interface{}, 0, len(rawQuery))
+		for _, q := range rawQuery {
+			query = append(query, keyValue(q[0], q[1], true))
+		}
+	}
+
+	headers := make([]interface{}, 0)
+	for _, h := range listOf(request, "header") {
+		if hm, ok := h.(map[string]interface{}); ok {
+			headers = append(headers, keyValue(stringOf(hm, "key"), p.convert(stringOf(hm, "value")), !isDisabled(hm)))
+		}
+	}
+
+	config := map[string]interface{}{
+		"method":  method,
+		"url":     urlPath,
+		"params":  query,
+		"headers": headers,
+	}
+	if origin != "" {
+		config["domainCode"] = p.servers.add([][2]string{{origin, origin}}).Key
+	}
+
+	step := types.Step{
+		ID:     p.ids.next(fullName),
+		Name:   fullName,
+		Type:   "http",
+		Config: config,
+	}
+
+	if body, contentType := p.requestBody(mapOf(request, "body"), fullName); body != nil {
+		config["body"] = body
+		if contentType != "" && !hasHeader(headers, "Content-Type") {
+			config["headers"] = append(headers, keyValue("Content-Type", contentType, true))
+		}
+	}
+
+	auth := scope.auth
+	if a := mapOf(request, "auth"); a != nil && stringOf(a, "type") != "inherit" {
+		auth = a
+	}
+	p.applyAuth(&step, auth, fullName)
+
+	events := append(append([]postmanEvent{}, scope.events...), postmanEvents(item, "")...)
+	for _, e := range events {
+		p.checkScript(e.code, fullName)
+		label := "Pre-request Script"
+		if e.listen == "test" {
+			label = "Tests"
+		}
+		if e.owner != "" {
+			label += " (" + e.owner + ")"
+		}
+		processor := types.Processor{
+			Type:    "js_script",
+			Enabled: true,
+			Name:    label,
+			Config: map[string]interface{}{
+				"script":      e.code,
+				"compat":      script.CompatPostman,
+				"requestName": name,
+			},
+		}
+		if e.listen == "test" {
+			processor.ID = step.ID + "_test_" + strconv.Itoa(len(step.PostProcessors)+1)
+			step.PostProcessors = append(step.PostProcessors, processor)
+		} else {
+			processor.ID = step.ID + "_pre_" + strconv.Itoa(len(step.PreProcessors)+1)
+			step.PreProcessors = append(step.PreProcessors, processor)
+		}
+	}
+	return step
+}
+
+// requestURL 获取转换变量后的完整地址；url 为对象且含 query 时同时返回参数列表（保留禁用状态），
+// 路径变量 :name 转为 ${name} 并写入分组变量
+func (p *postmanParser) requestURL(request map[string]interface{}, group *Group) (string, []interface{}) {
+	var rawURL string
+	var query []interface{}
+	pathVars := make(map[string]string)
+
+	switch u := request["url"].(type) {
+	case string:
+		rawURL = u
+	case map[string]interface{}:
+		rawURL = stringOf(u, "raw")
+		if rawURL == "" {
+			rawURL = buildPostmanURL(u)
+		}
+		if list := listOf(u, "query"); len(list) > 0 {
+			rawURL, _, _ = strings.Cut(rawURL, "?")
+			query = make([]interface{}, 0, len(list))
+			for _, q := range list {
+				if qm, ok := q.(map[string]interface{}); ok {
+					query = append(query, keyValue(stringOf(qm, "key"), p.convert(formatValue(qm["value"])), !isDisabled(qm)))
+				}
+			}
+		}
+		for _, v := range listOf(u, "variable") {
+			if vm, ok := v.(map[string]interface{}); ok {
+				pathVars[stringOf(vm, "key")] = formatValue(vm["value"])
+			}
+		}
+	}
+	rawURL, _, _ = strings.Cut(rawURL, "#")
+
+	// 路径变量引用分组变量；仅转换主机之后的路径段，避免误伤端口
+	base, rawQuery, hasQuery := strings.Cut(rawURL, "?")
+	segments := strings.Split(base, "/")
+	start := 1
+	if strings.Contains(base, "://") {
+		start = 3
+	}
+	for i := start; i < len(segments); i++ {
+		if seg := segments[i]; len(seg) > 1 && seg[0] == ':' {
+			name := seg[1:]
+			setDefaultVariable(group.Variables, name, pathVars[name])
+			segments[i] = "${" + name + "}"
+		}
+	}
+	rawURL = strings.Join(segments, "/")
+	if hasQuery {
+		rawURL += "?" + rawQuery
+	}
+	return p.convert(rawURL), query
+}
+
+// buildPostmanURL 由 url 对象的各部分拼接地址
+func buildPostmanURL(u map[string]interface{}) string {
+	join := func(v interface{}, sep string) string {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		parts := make([]string, 0)
+		for _, item := range toList(v) {
+			parts = append(parts, formatValue(item))
+		}
+		return strings.Join(parts, sep)
+	}
+	var b strings.Builder
+	if protocol := stringOf(u, "protocol"); protocol != "" {
+		b.WriteString(protocol + "://")
+	}
+	b.WriteString(join(u["host"], "."))
+	if port := formatValue(u["port"]); port != "" {
+		b.WriteString(":" + port)
+	}
+	if path := join(u["path"], "/"); path != "" {
+		b.WriteString("/" + strings.TrimPrefix(path, "/"))
+	}
+	return b.String()
+}
+
+// requestBody 转换请求体，返回请求体配置与需要补充的 Content-Type
+func (p *postmanParser) requestBody(body map[string]interface{}, stepName string) (map[string]interface{}, string) {
+	if body == nil || isDisabled(body) {
+		return nil, ""
+	}
+	switch mode := stringOf(body, "mode"); mode {
+	case "raw":
+		raw := p.convert(stringOf(body, "raw"))
+		if raw == "" {
+			return nil, ""
+		}
+		language := stringOf(mapOf(mapOf(body, "options"), "raw"), "language")
+		switch {
+		case language == "json":
+			return map[string]interface{}{"type": "json", "raw": raw}, "application/json"
+		case language == "xml":
+			return map[string]interface{}{"type": "xml", "raw": raw}, "application/xml"
+		case language == "" && json.Valid([]byte(stringOf(body, "raw"))):
+			return map[string]interface{}{"type": "json", "raw": raw}, ""
+		default:
+			return map[string]interface{}{"type": "text", "raw": raw}, ""
+		}
+	case "urlencoded", "formdata":
+		items := make([]interface{}, 0)
+		for _, f := range listOf(body, mode) {
+			fm, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			value := p.convert(formatValue(fm["value"]))
+			if stringOf(fm, "type") == "file" {
+				value = formatValue(fm["src"])
+				p.collection.addWarning("请求 %s 的表单字段 %s 为文件上传，请手动选择文件", stepName, stringOf(fm, "key"))
+			}
+			items = append(items, keyValue(stringOf(fm, "key"), value, !isDisabled(fm)))
+		}
+		if mode == "formdata" {
+			return map[string]interface{}{"type": "form-data", "formData": items}, ""
+		}
+		return map[string]interface{}{"type": "x-www-form-urlencoded", "urlencoded": items}, ""
+	case "graphql":
+		gql := mapOf(body, "graphql")
+		payload := map[string]interface{}{"query": stringOf(gql, "query")}
+		if vars := stringOf(gql, "variables"); vars != "" {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(vars), &parsed); err == nil {
+				payload["variables"] = parsed
+			}
+		}
+		data, _ := json.MarshalIndent(payload, "", "  ")
+		return map[string]interface{}{"type": "json", "raw": p.convert(string(data))}, "application/json"
+	case "file":
+		p.collection.addWarning("请求 %s 的请求体为文件，请手动填写", stepName)
+	}
+	return nil, ""
+}
+
+// applyAuth 将认证转换为请求头或查询参数
+func (p *postmanParser) applyAuth(step *types.Step, auth map[string]interface{}, stepName string) {
+	if auth == nil {
+		return
+	}
+	config := step.Config
+	addHeader := func(key, value string) {
+		headers, _ := config["headers"].([]interface{})
+		if !hasHeader(headers, key) {
+			config["headers"] = append(headers, keyValue(key, value, true))
+		}
+	}
+
+	switch authType := stringOf(auth, "type"); authType {
+	case "noauth", "":
+	case "bearer":
+		addHeader("Authorization", "Bearer "+p.convert(authParam(auth, "bearer", "token")))
+	case "basic":
+		username, password := authParam(auth, "basic", "username"), authParam(auth, "basic", "password")
+		if !postmanVarExpr.MatchString(username + password) {
+			addHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+			return
+		}
+		// 含变量时运行期计算
+		addHeader("Authorization", "Basic ${basicAuth}")
+		step.PreProcessors = append(step.PreProcessors, types.Processor{
+			ID:      step.ID + "_basic_auth",
+			Type:    "js_script",
+			Enabled: true,
+			Name:    "Basic Auth",
+			Config: map[string]interface{}{
+				"script": "vars.set(\"basicAuth\", btoa(" + jsTemplate(username+":"+password) + "));",
+			},
+		})
+	case "apikey":
+		key, value := authParam(auth, "apikey", "key"), p.convert(authParam(auth, "apikey", "value"))
+		if authParam(auth, "apikey", "in") == "query" {
+			params, _ := config["params"].([]interface{})
+			config["params"] = append(params, keyValue(key, value, true))
+		} else {
+			addHeader(key, value)
+		}
+	default:
+		p.collection.addWarning("请求 %s 使用的 %s 认证暂不支持自动转换，请手动配置", stepName, authType)
+	}
+}
+
+// checkScript 提示脚本中无法兼容的 Postman API
+func (p *postmanParser) checkScript(code, stepName string) {
+	for _, api := range []string{"pm.sendRequest", "pm.request.", "pm.cookies", "pm.iterationData", "pm.execution", "postman.setNextRequest"} {
+		if strings.Contains(code, api) {
+			p.collection.addWarning("请求 %s 的脚本使用了 %s，导入后需手动调整", stepName, api)
+		}
+	}
+}
+
+// convert 将 {{x}} 转换为 ${env.x}；Postman 动态变量（{{$guid}} 等）保留原样并提示
+func (p *postmanParser) convert(s string) string {
+	return postmanVarExpr.ReplaceAllStringFunc(s, func(m string) string {
+		name := postmanVarExpr.FindStringSubmatch(m)[1]
+		if strings.HasPrefix(name, "$") {
+			p.collection.addWarning("Postman 动态变量 %s 未转换，请手动替换", m)
+			return m
+		}
+		return "${env." + name + "}"
+	})
+}
+
+func (p *postmanParser) setEnvVariable(name, value string, sensitive bool) {
+	if name == "" {
+		return
+	}
+	for _, v := range p.collection.EnvVariables {
+		if v.Name == name {
+			v.Value = value
+			v.Sensitive = v.Sensitive || sensitive
+			return
+		}
+	}
+	p.collection.EnvVariables = append(p.collection.EnvVariables, &EnvVariable{Name: name, Value: value, Sensitive: sensitive})
+}
+
+// postmanEvents 读取条目上的脚本
+func postmanEvents(item map[string]interface{}, owner string) []postmanEvent {
+	var events []postmanEvent
+	for _, e := range listOf(item, "event") {
+		em, ok := e.(map[string]interface{})
+		if !ok || isDisabled(em) {
+			continue
+		}
+		listen := stringOf(em, "listen")
+		if listen != "prerequest" && listen != "test" {
+			continue
+		}
+		scriptNode := mapOf(em, "script")
+		var code string
+		switch exec := scriptNode["exec"].(type) {
+		case string:
+			code = exec
+		case []interface{}:
+			lines := make([]string, 0, len(exec))
+			for _, line := range exec {
+				lines = append(lines, formatValue(line))
+			}
+			code = strings.Join(lines, "\n")
+		}
+		if strings.TrimSpace(code) != "" {
+			events = append(events, postmanEvent{listen: listen, owner: owner, code: code})
+		}
+	}
+	return events
+}
+
+// authParam 读取认证参数，兼容 v2.1 的 [{key, value}] 与 v2.0 的对象格式
+func authParam(auth map[string]interface{}, authType, key string) string {
+	switch params := auth[authType].(type) {
+	case []interface{}:
+		for _, item := range params {
+			if m, ok := item.(map[string]interface{}); ok && stringOf(m, "key") == key {
+				return formatValue(m["value"])
+			}
+		}
+	case map[string]interface{}:
+		return formatValue(params[key])
+	}
+	return ""
+}
+
+// jsTemplate 将含 {{x}} 的模板转换为 JS 字符串表达式
+func jsTemplate(s string) string {
+	var parts []string
+	last := 0
+	for _, loc := range postmanVarExpr.FindAllStringSubmatchIndex(s, -1) {
+		if loc[0] > last {
+			parts = append(parts, strconv.Quote(s[last:loc[0]]))
+		}
+		name := s[loc[2]:loc[3]]
+		parts = append(parts, "env.get("+strconv.Quote(name)+")")
+		last = loc[1]
+	}
+	if last < len(s) {
+		parts = append(parts, strconv.Quote(s[last:]))
+	}
+	if len(parts) == 0 {
+		return `""`
+	}
+	return strings.Join(parts, " + ")
+}
+
+func descriptionOf(m map[string]interface{}) string {
+	switch d := m["description"].(type) {
+	case string:
+		return d
+	case map[string]interface{}:
+		return stringOf(d, "content")
+	}
+	return ""
+}
+
+func isDisabled(m map[string]interface{}) bool {
+	disabled, _ := m["disabled"].(bool)
+	return disabled
+}
+
+func hasHeader(headers []interface{}, name string) bool {
+	for _, h := range headers {
+		if hm, ok := h.(map[string]interface{}); ok && strings.EqualFold(stringOf(hm, "key"), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func toList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+)
+
+const postmanCollection = `{
+	"info": {"name": "Shop", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+	"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+	"event": [{"listen": "prerequest", "script": {"exec": ["console.log('collection')"]}}],
+	"variable": [{"key": "baseUrl", "value": "https://shop.example.com"}, {"key": "token", "value": "t0"}],
+	"item": [
+		{
+			"name": "Orders",
+			"event": [{"listen": "test", "script": {"exec": ["pm.test('ok', function () { pm.response.to.have.status(200); });"]}}],
+			"item": [
+				{
+					"name": "Get order",
+					"request": {
+						"method": "GET",
+						"header": [{"key": "X-Trace", "value": "{{$guid}}", "disabled": true}],
+						"url": {
+							"raw": "{{baseUrl}}/orders/:orderId?expand=items",
+							"host": ["{{baseUrl}}"],
+							"path": ["orders", ":orderId"],
+							"query": [{"key": "expand", "value": "items"}, {"key": "debug", "value": "1", "disabled": true}],
+							"variable": [{"key": "orderId", "value": "42"}]
+						}
+					}
+				},
+				{
+					"name": "Admin",
+					"item": [{
+						"name": "Create order",
+						"request": {
+							"method": "POST",
+							"auth": {"type": "basic", "basic": [{"key": "username", "value": "admin"}, {"key": "password", "value": "secret"}]},
+							"body": {"mode": "raw", "raw": "{\"sku\": \"{{sku}}\"}", "options": {"raw": {"language": "json"}}},
+							"url": "https://api.example.com/v1/orders"
+						}
+					}]
+				}
+			]
+		},
+		{"name": "Ping", "request": {"method": "GET", "url": "https://api.example.com/v1/ping"}}
+	]
+}`
+
+const postmanEnvironment = `{
+	"name": "dev",
+	"values": [
+		{"key": "token", "value": "dev-token", "type": "secret", "enabled": true},
+		{"key": "sku", "value": "A-1", "enabled": true},
+		{"key": "unused", "value": "x", "enabled": false}
+	]
+}`
+
+func TestParsePostman_Folders(t *testing.T) {
+	c, err := ParsePostman([]byte(postmanCollection), []byte(postmanEnvironment), GroupByFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Groups) != 2 || c.Groups[0].Name != "Shop - Orders" || c.Groups[1].Name != "Shop" {
+		t.Fatalf("unexpected groups: %+v", c.Groups)
+	}
+	if len(c.EnvVariables) != 3 {
+		t.Fatalf("unexpected env variables: %+v", c.EnvVariables)
+	}
+	if v := c.EnvVariables[1]; v.Name != "token" || v.Value != "dev-token" || !v.Sensitive {
+		t.Errorf("expected environment to override collection variable, got %+v", v)
+	}
+
+	orders := c.Groups[0]
+	get := orders.Steps[0]
+	if get.Config["url"] != "${env.baseUrl}/orders/${orderId}" || get.Config["domainCode"] != nil {
+		t.Errorf("unexpected get url: %+v", get.Config)
+	}
+	if orders.Variables["orderId"] != "42" {
+		t.Errorf("expected path variable default, got %v", orders.Variables)
+	}
+	params := get.Config["params"].([]interface{})
+	if len(params) != 2 || params[1].(map[string]interface{})["enabled"] != false {
+		t.Errorf("unexpected params: %v", params)
+	}
+	headers := get.Config["headers"].([]interface{})
+	if last := headers[len(headers)-1].(map[string]interface{}); last["key"] != "Authorization" || last["value"] != "Bearer ${env.token}" {
+		t.Errorf("expected inherited bearer auth, got %v", headers)
+	}
+	if len(get.PreProcessors) != 1 || len(get.PostProcessors) != 1 {
+		t.Fatalf("expected inherited scripts, got pre=%d post=%d", len(get.PreProcessors), len(get.PostProcessors))
+	}
+	if post := get.PostProcessors[0]; post.Config["compat"] != "postman" || post.Name != "Tests (Orders)" {
+		t.Errorf("unexpected test processor: %+v", post)
+	}
+
+	create := orders.Steps[1]
+	if create.Name != "Admin / Create order" || create.Config["url"] != "/v1/orders" {
+		t.Errorf("unexpected create step: %s %v", create.Name, create.Config["url"])
+	}
+	body := create.Config["body"].(map[string]interface{})
+	if body["type"] != "json" || body["raw"] != `{"sku": "${env.sku}"}` {
+		t.Errorf("unexpected body: %v", body)
+	}
+	if !hasHeader(create.Config["headers"].([]interface{}), "Content-Type") {
+		t.Error("expected json content type header")
+	}
+	found := false
+	for _, h := range create.Config["headers"].([]interface{}) {
+		if h.(map[string]interface{})["value"] == "Basic YWRtaW46c2VjcmV0" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected basic auth header, got %v", create.Config["headers"])
+	}
+	if len(c.Servers) != 1 || c.Servers[0].URL != "https://api.example.com" {
+		t.Errorf("unexpected servers: %+v", c.Servers)
+	}
+	if len(c.Warnings) != 1 || !strings.Contains(c.Warnings[0], "{{$guid}}") {
+		t.Errorf("expected dynamic variable warning, got %v", c.Warnings)
+	}
+}
+
+func TestParsePostman_Single(t *testing.T) {
+	c, err := ParsePostman([]byte(postmanCollection), nil, GroupByNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Groups) != 1 || len(c.Groups[0].Steps) != 3 {
+		t.Fatalf("expected one flattened workflow, got %+v", c.Groups)
+	}
+	if name := c.Groups[0].Steps[1].Name; name != "Orders / Admin / Create order" {
+		t.Errorf("unexpected step name: %s", name)
+	}
+	if id := c.Groups[0].Steps[0].ID; id != "postman_Orders_Get_order" {
+		t.Errorf("unexpected step id: %s", id)
+	}
+}
+
+func TestParsePostman_BasicAuthWithVariables(t *testing.T) {
+	doc := `{"info": {"name": "A"}, "item": [{"name": "r", "request": {
+		"auth": {"type": "basic", "basic": [{"key": "username", "value": "{{user}}"}, {"key": "password", "value": "p"}]},
+		"url": "https://a.example.com/"}}]}`
+	c, err := ParsePostman([]byte(doc), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := c.Groups[0].Steps[0]
+	if len(step.PreProcessors) != 1 || step.PreProcessors[0].Config["script"] != `vars.set("basicAuth", btoa(env.get("user") + ":p"));` {
+		t.Errorf("unexpected basic auth script: %+v", step.PreProcessors)
+	}
+}
+
+func TestParsePostman_Invalid(t *testing.T) {
+	if _, err := ParsePostman([]byte(`{"name": "old", "requests": []}`), nil, ""); err == nil {
+		t.Error("expected error for v1 collection")
+	}
+}
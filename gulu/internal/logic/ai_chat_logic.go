@@ -36,18 +36,39 @@ type ChatRequest struct {
 	Messages    []ChatMessage `json:"messages"`
 	Temperature *float64      `json:"temperature,omitempty"`
 	MaxTokens   *int          `json:"max_tokens,omitempty"`
+	// ConversationID 所属会话，仅用于用量归属
+	ConversationID string `json:"conversation_id,omitempty"`
+}
+
+// ChatUsage 对话 Token 用量（来自流式响应的最后一个 usage 块）
+type ChatUsage struct {
+	PromptTokens        int64 `json:"prompt_tokens"`
+	CompletionTokens    int64 `json:"completion_tokens"`
+	TotalTokens         int64 `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int64 `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
+}
+
+// CachedTokens 缓存命中的输入 Token
+func (u *ChatUsage) CachedTokens() int64 {
+	if u.PromptTokensDetails == nil {
+		return 0
+	}
+	return u.PromptTokensDetails.CachedTokens
 }
 
 // ChatStreamCallback SSE 流式回调
 type ChatStreamCallback func(data string) error
 
-// ChatStream 流式对话（调用 OpenAI 兼容 API）
-func (l *AiChatLogic) ChatStream(apiBaseURL, apiKey, modelID string, req *ChatRequest, callback ChatStreamCallback) error {
+// ChatStream 流式对话（调用 OpenAI 兼容 API），返回模型上报的 Token 用量（未上报时为 nil）
+func (l *AiChatLogic) ChatStream(apiBaseURL, apiKey, modelID string, req *ChatRequest, callback ChatStreamCallback) (*ChatUsage, error) {
 	// 构建 OpenAI 兼容请求
 	openaiReq := map[string]interface{}{
-		"model":    modelID,
-		"messages": req.Messages,
-		"stream":   true,
+		"model":          modelID,
+		"messages":       req.Messages,
+		"stream":         true,
+		"stream_options": map[string]interface{}{"include_usage": true},
 	}
 	if req.Temperature != nil {
 		openaiReq["temperature"] = *req.Temperature
@@ -58,7 +79,7 @@ func (l *AiChatLogic) ChatStream(apiBaseURL, apiKey, modelID string, req *ChatRe
 
 	reqBody, err := json.Marshal(openaiReq)
 	if err != nil {
-		return errors.New("请求序列化失败")
+		return nil, errors.New("请求序列化失败")
 	}
 
 	// 拼接 API URL
@@ -66,7 +87,7 @@ func (l *AiChatLogic) ChatStream(apiBaseURL, apiKey, modelID string, req *ChatRe
 
 	httpReq, err := http.NewRequestWithContext(l.ctx, http.MethodPost, url, bytes.NewReader(reqBody))
 	if err != nil {
-		return fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -76,16 +97,17 @@ func (l *AiChatLogic) ChatStream(apiBaseURL, apiKey, modelID string, req *ChatRe
 	client := &http.Client{}
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("请求模型API失败: %w", err)
+		return nil, fmt.Errorf("请求模型API失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("模型API返回错误 (HTTP %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("模型API返回错误 (HTTP %d): %s", resp.StatusCode, string(body))
 	}
 
 	// 读取 SSE 流
+	var usage *ChatUsage
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -105,20 +127,28 @@ func (l *AiChatLogic) ChatStream(apiBaseURL, apiKey, modelID string, req *ChatRe
 		// [DONE] 表示结束
 		if data == "[DONE]" {
 			if err := callback("data: [DONE]\n\n"); err != nil {
-				return err
+				return usage, err
 			}
 			break
 		}
 
+		// 记录用量块（开启 include_usage 后最后一个数据块携带 usage）
+		var chunk struct {
+			Usage *ChatUsage `json:"usage"`
+		}
+		if json.Unmarshal([]byte(data), &chunk) == nil && chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+
 		// 透传 SSE 数据给前端
 		if err := callback("data: " + data + "\n\n"); err != nil {
-			return err
+			return usage, err
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("读取SSE流失败: %w", err)
+		return usage, fmt.Errorf("读取SSE流失败: %w", err)
 	}
 
-	return nil
+	return usage, nil
 }
@@ -0,0 +1,467 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+
+	"gorm.io/gorm"
+)
+
+// -----------------------------------------------
+// 会话导出 / 导入
+// JSON：完整消息树（含全部分支与元信息），可无损导入；
+// Markdown：当前分支，便于阅读，每条消息前的注释中保留角色与元信息，同样可导入。
+// -----------------------------------------------
+
+const (
+	ConversationExportJSON     = "json"
+	ConversationExportMarkdown = "markdown"
+
+	conversationExportFormat  = "gulu.conversation"
+	conversationExportVersion = 1
+	maxImportMessages         = 5000
+	maxRenderedToolResult     = 2000 // Markdown 中工具结果展示的最大字符数（完整内容保留在元信息中）
+)
+
+// conversationRoles 会话消息允许的角色
+var conversationRoles = map[string]string{
+	"user":      "用户",
+	"assistant": "助手",
+	"system":    "系统",
+}
+
+// ConversationExport 会话导出文件
+type ConversationExport struct {
+	Format        string                      `json:"format"`
+	Version       int                         `json:"version"`
+	Title         string                      `json:"title"`
+	Variables     json.RawMessage             `json:"variables,omitempty"`
+	ExportedAt    *time.Time                  `json:"exported_at,omitempty"`
+	ActiveMessage int                         `json:"active_message,omitempty"` // 当前分支末条消息的 ref
+	Messages      []ConversationExportMessage `json:"messages"`
+}
+
+// ConversationExportMessage 导出的消息，ref 为文件内的序号，parent_ref 为 0 表示首条消息
+type ConversationExportMessage struct {
+	Ref       int             `json:"ref"`
+	ParentRef int             `json:"parent_ref,omitempty"`
+	Role      string          `json:"role"`
+	Content   string          `json:"content"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+}
+
+type ImportConversationReq struct {
+	Content string `json:"content"` // 导出的 JSON 或 Markdown 内容
+}
+
+// Export 导出会话。JSON 默认包含全部分支，activeOnly 为 true 时只导出当前分支；Markdown 只导出当前分支。
+// 返回文件内容与建议的文件名
+func (l *AIConversationLogic) Export(conversationID int64, format string, activeOnly bool) ([]byte, string, error) {
+	conv, tree, err := loadConversationTree(svc.Ctx.DB, conversationID)
+	if err != nil {
+		return nil, "", err
+	}
+	exp := buildConversationExport(conv, tree, activeOnly || format == ConversationExportMarkdown)
+
+	switch format {
+	case "", ConversationExportJSON:
+		data, err := json.MarshalIndent(exp, "", "  ")
+		return data, fmt.Sprintf("conversation-%d.json", conv.ID), err
+	case ConversationExportMarkdown:
+		return []byte(renderConversationMarkdown(exp)), fmt.Sprintf("conversation-%d.md", conv.ID), nil
+	default:
+		return nil, "", fmt.Errorf("不支持的导出格式: %s（可选 json / markdown）", format)
+	}
+}
+
+// buildConversationExport 按消息 ID 顺序编号，保证父消息的 ref 小于子消息
+func buildConversationExport(conv *model.TAiConversation, tree *messageTree, activeOnly bool) *ConversationExport {
+	now := time.Now()
+	exp := &ConversationExport{
+		Format:     conversationExportFormat,
+		Version:    conversationExportVersion,
+		Title:      conv.Title,
+		ExportedAt: &now,
+	}
+	if conv.Variables != nil && json.Valid([]byte(*conv.Variables)) {
+		exp.Variables = json.RawMessage(*conv.Variables)
+	}
+
+	activeID := tree.activeID(conv)
+	var messages []*model.TAiConversationMessage
+	if activeOnly {
+		messages = tree.pathTo(activeID)
+	} else {
+		for _, id := range tree.subtreeAll() {
+			messages = append(messages, tree.byID[id])
+		}
+	}
+
+	refs := make(map[int64]int, len(messages))
+	for i, m := range messages {
+		ref := i + 1
+		refs[m.ID] = ref
+		em := ConversationExportMessage{
+			Ref:       ref,
+			ParentRef: refs[tree.parentOf(m)],
+			Role:      m.Role,
+			Content:   m.Content,
+			CreatedAt: m.CreatedAt,
+		}
+		if m.Metadata != nil && json.Valid([]byte(*m.Metadata)) {
+			em.Metadata = json.RawMessage(*m.Metadata)
+		}
+		exp.Messages = append(exp.Messages, em)
+	}
+	exp.ActiveMessage = refs[activeID]
+	return exp
+}
+
+// subtreeAll 全部消息，父消息在前（首条消息按创建顺序，各自的后续消息依次展开）
+func (t *messageTree) subtreeAll() []int64 {
+	var ids []int64
+	for _, root := range t.children[0] {
+		ids = append(ids, t.subtree(root)...)
+	}
+	return ids
+}
+
+// Import 从导出的 JSON 或 Markdown 创建新会话
+func (l *AIConversationLogic) Import(workflowID int64, content string, userID int64) (*model.TAiConversation, error) {
+	exp, err := parseConversationExport(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := exp.validate(); err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(exp.Title)
+	if title == "" {
+		title = "导入的对话"
+	}
+	if r := []rune(title); len(r) > 200 {
+		title = string(r[:200])
+	}
+	conv := &model.TAiConversation{
+		WorkflowID: workflowID,
+		Title:      title,
+		CreatedBy:  &userID,
+	}
+	if len(exp.Variables) > 0 && string(exp.Variables) != "null" {
+		vars := string(exp.Variables)
+		conv.Variables = &vars
+	}
+
+	err = svc.Ctx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(conv).Error; err != nil {
+			return err
+		}
+		ids := make(map[int]int64, len(exp.Messages))
+		for _, em := range exp.Messages {
+			msg := &model.TAiConversationMessage{
+				ConversationID: conv.ID,
+				Role:           em.Role,
+				Content:        em.Content,
+				CreatedAt:      em.CreatedAt,
+			}
+			if em.ParentRef > 0 {
+				parentID := ids[em.ParentRef]
+				msg.ParentID = &parentID
+			}
+			if len(em.Metadata) > 0 && string(em.Metadata) != "null" {
+				meta := string(em.Metadata)
+				msg.Metadata = &meta
+			}
+			if err := tx.Create(msg).Error; err != nil {
+				return err
+			}
+			ids[em.Ref] = msg.ID
+		}
+
+		active := ids[exp.Messages[len(exp.Messages)-1].Ref]
+		if id, ok := ids[exp.ActiveMessage]; ok {
+			active = id
+		}
+		conv.ActiveMessageID = &active
+		return tx.Model(conv).Update("active_message_id", active).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
+
+// parseConversationExport 以 { 开头的内容按 JSON 解析，否则按导出的 Markdown 解析
+func parseConversationExport(content string) (*ConversationExport, error) {
+	content = strings.TrimSpace(strings.TrimPrefix(content, "\ufeff"))
+	if content == "" {
+		return nil, errors.New("导入内容为空")
+	}
+	if strings.HasPrefix(content, "{") {
+		var exp ConversationExport
+		if err := json.Unmarshal([]byte(content), &exp); err != nil {
+			return nil, fmt.Errorf("JSON 解析失败: %w", err)
+		}
+		if exp.Format != conversationExportFormat {
+			return nil, errors.New("不是会话导出文件（format 应为 " + conversationExportFormat + "）")
+		}
+		return &exp, nil
+	}
+	return parseConversationMarkdown(content)
+}
+
+// validate 校验导入内容：父消息必须出现在子消息之前，保证导入后是一棵树
+func (e *ConversationExport) validate() error {
+	if e.Version > conversationExportVersion {
+		return fmt.Errorf("不支持的导出文件版本: %d", e.Version)
+	}
+	if len(e.Messages) == 0 {
+		return errors.New("导入内容中没有消息")
+	}
+	if len(e.Messages) > maxImportMessages {
+		return fmt.Errorf("消息数量超过上限 %d", maxImportMessages)
+	}
+	if len(e.Variables) > 0 && string(e.Variables) != "null" {
+		var vars map[string]interface{}
+		if err := json.Unmarshal(e.Variables, &vars); err != nil {
+			return errors.New("variables 必须是 JSON 对象")
+		}
+	}
+	seen := make(map[int]bool, len(e.Messages))
+	for i, m := range e.Messages {
+		if m.Ref <= 0 || seen[m.Ref] {
+			return fmt.Errorf("第 %d 条消息的 ref 无效或重复", i+1)
+		}
+		if m.ParentRef != 0 && !seen[m.ParentRef] {
+			return fmt.Errorf("第 %d 条消息的 parent_ref %d 必须指向之前的消息", i+1, m.ParentRef)
+		}
+		if _, ok := conversationRoles[m.Role]; !ok {
+			return fmt.Errorf("第 %d 条消息的角色无效: %s", i+1, m.Role)
+		}
+		if len(m.Metadata) > 0 && string(m.Metadata) != "null" {
+			var meta map[string]interface{}
+			if err := json.Unmarshal(m.Metadata, &meta); err != nil {
+				return fmt.Errorf("第 %d 条消息的 metadata 必须是 JSON 对象", i+1)
+			}
+		}
+		seen[m.Ref] = true
+	}
+	return nil
+}
+
+// -----------------------------------------------
+// Markdown 导出格式
+// -----------------------------------------------
+
+const (
+	mdConversationMarker = "<!-- gulu:conversation "
+	mdMessageMarker      = "<!-- gulu:message "
+	mdMessageEnd         = "<!-- gulu:end -->"
+	mdMarkerSuffix       = " -->"
+)
+
+// mdMessageHeader 消息注释中保存的字段（json.Marshal 会转义 < >，内容中不会出现注释结束符）
+type mdMessageHeader struct {
+	Role      string          `json:"role"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+}
+
+type mdConversationHeader struct {
+	Format    string          `json:"format"`
+	Version   int             `json:"version"`
+	Variables json.RawMessage `json:"variables,omitempty"`
+}
+
+// renderConversationMarkdown 渲染当前分支：消息正文位于 gulu:message 与 gulu:end 注释之间，
+// 工具调用与产物渲染在 gulu:end 之后，仅供阅读（导入时从注释中的元信息恢复）
+func renderConversationMarkdown(exp *ConversationExport) string {
+	var sb strings.Builder
+	sb.WriteString("# " + strings.ReplaceAll(exp.Title, "\n", " ") + "\n\n")
+	header, _ := json.Marshal(mdConversationHeader{Format: exp.Format, Version: exp.Version, Variables: exp.Variables})
+	sb.WriteString(mdConversationMarker + string(header) + mdMarkerSuffix + "\n\n")
+	if exp.ExportedAt != nil {
+		sb.WriteString(fmt.Sprintf("> 导出时间：%s，共 %d 条消息\n\n", exp.ExportedAt.Format("2006-01-02 15:04:05"), len(exp.Messages)))
+	}
+
+	for _, m := range exp.Messages {
+		header, _ := json.Marshal(mdMessageHeader{Role: m.Role, Metadata: m.Metadata, CreatedAt: m.CreatedAt})
+		sb.WriteString(mdMessageMarker + string(header) + mdMarkerSuffix + "\n")
+		label := conversationRoles[m.Role]
+		if label == "" {
+			label = m.Role
+		}
+		sb.WriteString("### " + label + "\n\n")
+		sb.WriteString(m.Content)
+		sb.WriteString("\n" + mdMessageEnd + "\n\n")
+
+		var meta map[string]interface{}
+		if len(m.Metadata) > 0 && json.Unmarshal(m.Metadata, &meta) == nil {
+			renderToolCalls(&sb, meta["tool_calls"])
+			renderArtifacts(&sb, meta["artifacts"])
+		}
+	}
+	return sb.String()
+}
+
+// renderToolCalls 渲染元信息中的工具调用：[{tool_name|name, arguments, result, is_error}]
+func renderToolCalls(sb *strings.Builder, v interface{}) {
+	calls, _ := v.([]interface{})
+	for _, c := range calls {
+		call, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := metaString(call, "tool_name", "name")
+		summary := "工具调用：" + name
+		if isErr, _ := call["is_error"].(bool); isErr {
+			summary += "（失败）"
+		}
+		sb.WriteString("<details>\n<summary>" + summary + "</summary>\n\n")
+		if args := metaString(call, "arguments", "args"); args != "" {
+			sb.WriteString("**参数**\n\n" + mdCodeBlock(args, "json") + "\n")
+		}
+		if result := metaString(call, "result", "output"); result != "" {
+			if r := []rune(result); len(r) > maxRenderedToolResult {
+				result = string(r[:maxRenderedToolResult]) + "\n...（已截断）"
+			}
+			sb.WriteString("**结果**\n\n" + mdCodeBlock(result, "") + "\n")
+		}
+		sb.WriteString("</details>\n\n")
+	}
+}
+
+// renderArtifacts 渲染元信息中的产物：[{title, type, url, content}]
+func renderArtifacts(sb *strings.Builder, v interface{}) {
+	artifacts, _ := v.([]interface{})
+	if len(artifacts) == 0 {
+		return
+	}
+	sb.WriteString("**产物**\n\n")
+	for _, a := range artifacts {
+		artifact, ok := a.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		title := metaString(artifact, "title", "name")
+		if title == "" {
+			title = "未命名产物"
+		}
+		fileType := metaString(artifact, "type", "file_type")
+		if url := metaString(artifact, "url"); url != "" {
+			sb.WriteString(fmt.Sprintf("- [%s](%s)", title, url))
+		} else {
+			sb.WriteString("- " + title)
+		}
+		if fileType != "" {
+			sb.WriteString(" (`" + fileType + "`)")
+		}
+		sb.WriteString("\n")
+		if content := metaString(artifact, "content"); content != "" {
+			sb.WriteString("\n<details>\n<summary>" + title + "</summary>\n\n" + content + "\n\n</details>\n\n")
+		}
+	}
+	sb.WriteString("\n")
+}
+
+// metaString 取第一个存在的字段，非字符串值序列化为 JSON
+func metaString(m map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case nil:
+			continue
+		case string:
+			return v
+		default:
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// mdCodeBlock 代码块围栏比内容中最长的连续反引号多一个
+func mdCodeBlock(content, lang string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + content + "\n" + fence + "\n"
+}
+
+var mdTitlePattern = regexp.MustCompile(`^#\s+(.*)$`)
+
+// parseConversationMarkdown 解析 renderConversationMarkdown 导出的内容，消息依次组成一条分支
+func parseConversationMarkdown(content string) (*ConversationExport, error) {
+	exp := &ConversationExport{Format: conversationExportFormat, Version: conversationExportVersion}
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case exp.Title == "" && len(exp.Messages) == 0 && mdTitlePattern.MatchString(line):
+			exp.Title = strings.TrimSpace(mdTitlePattern.FindStringSubmatch(line)[1])
+
+		case strings.HasPrefix(line, mdConversationMarker) && strings.HasSuffix(line, mdMarkerSuffix):
+			var h mdConversationHeader
+			raw := strings.TrimSuffix(strings.TrimPrefix(line, mdConversationMarker), mdMarkerSuffix)
+			if err := json.Unmarshal([]byte(raw), &h); err != nil {
+				return nil, fmt.Errorf("第 %d 行会话信息解析失败: %w", i+1, err)
+			}
+			exp.Version = h.Version
+			exp.Variables = h.Variables
+
+		case strings.HasPrefix(line, mdMessageMarker) && strings.HasSuffix(line, mdMarkerSuffix):
+			var h mdMessageHeader
+			raw := strings.TrimSuffix(strings.TrimPrefix(line, mdMessageMarker), mdMarkerSuffix)
+			if err := json.Unmarshal([]byte(raw), &h); err != nil {
+				return nil, fmt.Errorf("第 %d 行消息信息解析失败: %w", i+1, err)
+			}
+			// 跳过角色标题，读取到 gulu:end 为止
+			start := i + 1
+			if start < len(lines) && strings.HasPrefix(lines[start], "### ") {
+				start++
+			}
+			if start < len(lines) && lines[start] == "" {
+				start++
+			}
+			end := start
+			for end < len(lines) && strings.TrimSpace(lines[end]) != mdMessageEnd {
+				end++
+			}
+			if end == len(lines) {
+				return nil, fmt.Errorf("第 %d 行的消息缺少结束标记 %s", i+1, mdMessageEnd)
+			}
+			ref := len(exp.Messages) + 1
+			exp.Messages = append(exp.Messages, ConversationExportMessage{
+				Ref:       ref,
+				ParentRef: ref - 1,
+				Role:      h.Role,
+				Content:   strings.Join(lines[start:end], "\n"),
+				Metadata:  h.Metadata,
+				CreatedAt: h.CreatedAt,
+			})
+			i = end
+		}
+	}
+	if len(exp.Messages) == 0 {
+		return nil, errors.New("未找到会话消息，Markdown 导入仅支持本系统导出的文件")
+	}
+	return exp, nil
+}
@@ -0,0 +1,173 @@
+package logic
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"yqhp/gulu/internal/model"
+)
+
+func testConversationMessage(id, parent int64, role, content string) model.TAiConversationMessage {
+	m := model.TAiConversationMessage{ID: id, ConversationID: 1, Role: role, Content: content}
+	if parent > 0 {
+		m.ParentID = &parent
+	}
+	return m
+}
+
+// testConversationTree 1 → 2 → {3, 5 → 6}，4 为编辑首条消息产生的另一个首条消息
+func testConversationTree() *messageTree {
+	return newMessageTree([]model.TAiConversationMessage{
+		testConversationMessage(1, 0, "user", "问题"),
+		testConversationMessage(2, 1, "assistant", "回答"),
+		testConversationMessage(3, 2, "user", "追问 A"),
+		testConversationMessage(4, 0, "user", "改写后的问题"),
+		testConversationMessage(5, 2, "user", "追问 B"),
+		testConversationMessage(6, 5, "assistant", "回答 B"),
+	})
+}
+
+func messageIDs(messages []*model.TAiConversationMessage) []int64 {
+	ids := make([]int64, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+// TestMessageTreeBranches 当前分支、同级分支与切换分支时走到最新的末条消息
+func TestMessageTreeBranches(t *testing.T) {
+	tree := testConversationTree()
+	if got := messageIDs(tree.pathTo(6)); !reflect.DeepEqual(got, []int64{1, 2, 5, 6}) {
+		t.Fatalf("pathTo(6) = %v", got)
+	}
+	if got := tree.latestLeaf(1); got != 6 {
+		t.Fatalf("latestLeaf(1) = %d, want 6", got)
+	}
+	if got := tree.subtree(2); !reflect.DeepEqual(got, []int64{2, 3, 5, 6}) {
+		t.Fatalf("subtree(2) = %v", got)
+	}
+
+	active := int64(3)
+	if got := tree.activeID(&model.TAiConversation{ActiveMessageID: &active}); got != 3 {
+		t.Fatalf("activeID = %d, want 3", got)
+	}
+	// 未记录当前分支（升级前的会话）时取最新的消息
+	if got := tree.activeID(&model.TAiConversation{}); got != 6 {
+		t.Fatalf("activeID without pointer = %d, want 6", got)
+	}
+
+	branch := tree.branch(3)
+	if len(branch) != 3 || branch[0].SiblingIndex != 0 || !reflect.DeepEqual(branch[0].SiblingIDs, []int64{1, 4}) {
+		t.Fatalf("unexpected root siblings: %+v", branch[0])
+	}
+	if branch[1].SiblingIDs != nil {
+		t.Fatalf("single child should have no siblings: %+v", branch[1])
+	}
+	if !reflect.DeepEqual(branch[2].SiblingIDs, []int64{3, 5}) || branch[2].SiblingIndex != 0 {
+		t.Fatalf("unexpected siblings: %+v", branch[2])
+	}
+}
+
+// TestConversationExportJSON 导出的 ref 保证父消息在前，导入校验拒绝悬空的父消息
+func TestConversationExportJSON(t *testing.T) {
+	tree := testConversationTree()
+	vars := `{"lang":"zh"}`
+	active := int64(6)
+	conv := &model.TAiConversation{ID: 1, Title: "部署咨询", Variables: &vars, ActiveMessageID: &active}
+
+	exp := buildConversationExport(conv, tree, false)
+	if len(exp.Messages) != 6 {
+		t.Fatalf("expected all 6 messages, got %d", len(exp.Messages))
+	}
+	refOf := make(map[string]int)
+	for _, m := range exp.Messages {
+		refOf[m.Content] = m.Ref
+	}
+	for _, m := range exp.Messages {
+		if m.ParentRef >= m.Ref {
+			t.Fatalf("parent should come first: %+v", m)
+		}
+	}
+	if exp.ActiveMessage != refOf["回答 B"] {
+		t.Fatalf("active_message = %d, want %d", exp.ActiveMessage, refOf["回答 B"])
+	}
+
+	data, _ := json.Marshal(exp)
+	parsed, err := parseConversationExport(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if string(parsed.Variables) != vars {
+		t.Fatalf("variables = %s", parsed.Variables)
+	}
+
+	parsed.Messages[1].ParentRef = 99
+	if err := parsed.validate(); err == nil {
+		t.Fatal("dangling parent_ref should be rejected")
+	}
+	if _, err := parseConversationExport(`{"format":"other","messages":[]}`); err == nil {
+		t.Fatal("unknown format should be rejected")
+	}
+
+	if branchOnly := buildConversationExport(conv, tree, true); len(branchOnly.Messages) != 4 {
+		t.Fatalf("active branch export should have 4 messages, got %d", len(branchOnly.Messages))
+	}
+}
+
+// TestConversationMarkdownRoundTrip Markdown 导出当前分支，工具调用与产物可读，导入后内容与元信息不变
+func TestConversationMarkdownRoundTrip(t *testing.T) {
+	meta := `{"tool_calls":[{"tool_name":"knowledge_search","arguments":"{\"query\":\"部署\"}","result":"找到 2 条 --> 结果"}],"artifacts":[{"title":"部署报告","type":"md","url":"/files/r.md"}]}`
+	msgs := []model.TAiConversationMessage{
+		testConversationMessage(1, 0, "user", "# 如何部署？\n\n```bash\nmake\n```"),
+		testConversationMessage(2, 1, "assistant", "\n按以下步骤：\n<!-- 注释 -->\n"),
+	}
+	msgs[1].Metadata = &meta
+	active := int64(2)
+	conv := &model.TAiConversation{ID: 1, Title: "部署", ActiveMessageID: &active}
+
+	md := renderConversationMarkdown(buildConversationExport(conv, newMessageTree(msgs), true))
+	for _, want := range []string{"# 部署\n", "### 用户", "### 助手", "工具调用：knowledge_search", "[部署报告](/files/r.md) (`md`)"} {
+		if !strings.Contains(md, want) {
+			t.Fatalf("markdown missing %q:\n%s", want, md)
+		}
+	}
+
+	parsed, err := parseConversationExport(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Title != "部署" || len(parsed.Messages) != 2 {
+		t.Fatalf("unexpected parsed export: %+v", parsed)
+	}
+	for i, m := range parsed.Messages {
+		if m.Content != msgs[i].Content || m.Role != msgs[i].Role || m.ParentRef != i {
+			t.Fatalf("message %d mismatch: %+v", i, m)
+		}
+	}
+	var got, want map[string]interface{}
+	json.Unmarshal(parsed.Messages[1].Metadata, &got)
+	json.Unmarshal([]byte(meta), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("metadata = %v, want %v", got, want)
+	}
+
+	if _, err := parseConversationExport("# 随便写的笔记\n\n内容"); err == nil {
+		t.Fatal("markdown without markers should be rejected")
+	}
+}
+
+// TestMdCodeBlockFence 内容中含反引号时围栏加长
+func TestMdCodeBlockFence(t *testing.T) {
+	if got := mdCodeBlock("a ```` b", ""); !strings.HasPrefix(got, "`````\n") {
+		t.Fatalf("fence should be longer than content backticks: %q", got)
+	}
+}
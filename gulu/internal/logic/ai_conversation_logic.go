@@ -4,9 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sort"
+	"time"
 
 	"yqhp/gulu/internal/model"
 	"yqhp/gulu/internal/svc"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AIConversationLogic struct {
@@ -30,11 +35,184 @@ type SaveMessageReq struct {
 	Role     string                 `json:"role" validate:"required"`
 	Content  string                 `json:"content" validate:"required"`
 	Metadata map[string]interface{} `json:"metadata"`
+	// ParentID 父消息，为空时接在当前分支末尾；传 0 表示新的首条消息
+	ParentID *int64 `json:"parent_id"`
+}
+
+// EditMessageReq 编辑消息：在原消息旁创建同级分支，原消息及其后续保留
+type EditMessageReq struct {
+	Content  string                 `json:"content" validate:"required"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+type SwitchBranchReq struct {
+	MessageID int64 `json:"message_id" validate:"required"`
+}
+
+type ForkConversationReq struct {
+	MessageID int64  `json:"message_id"` // 派生截止的消息（含），为空表示当前分支末条消息
+	Title     string `json:"title"`
 }
 
 type ConversationDetail struct {
 	model.TAiConversation
-	Messages []model.TAiConversationMessage `json:"messages"`
+	Messages []ConversationMessage `json:"messages"` // 当前分支上的消息（从首条到末条）
+}
+
+// ConversationMessage 当前分支上的消息，附带同级分支信息供切换
+type ConversationMessage struct {
+	model.TAiConversationMessage
+	SiblingIDs   []int64 `json:"sibling_ids,omitempty"` // 同一父消息下的全部分支（按创建顺序，含自身），只有一个分支时为空
+	SiblingIndex int     `json:"sibling_index"`
+}
+
+// -----------------------------------------------
+// 消息树：每条消息记录父消息，编辑 / 重新生成时创建同级分支，
+// 会话记录当前分支的末条消息，从它回溯到首条即为当前分支
+// -----------------------------------------------
+
+// messageTree 会话的全部消息，子消息按创建顺序排列
+type messageTree struct {
+	byID     map[int64]*model.TAiConversationMessage
+	children map[int64][]int64 // 父消息 ID → 子消息 ID，首条消息的父消息记为 0
+	lastID   int64
+}
+
+func newMessageTree(messages []model.TAiConversationMessage) *messageTree {
+	t := &messageTree{
+		byID:     make(map[int64]*model.TAiConversationMessage, len(messages)),
+		children: make(map[int64][]int64),
+	}
+	for i := range messages {
+		m := &messages[i]
+		t.byID[m.ID] = m
+		if m.ID > t.lastID {
+			t.lastID = m.ID
+		}
+	}
+	for id, m := range t.byID {
+		parent := t.parentOf(m)
+		t.children[parent] = append(t.children[parent], id)
+	}
+	for _, ids := range t.children {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	return t
+}
+
+// parentOf 父消息 ID，首条消息或父消息已不存在时返回 0
+func (t *messageTree) parentOf(m *model.TAiConversationMessage) int64 {
+	if m.ParentID == nil {
+		return 0
+	}
+	if _, ok := t.byID[*m.ParentID]; !ok {
+		return 0
+	}
+	return *m.ParentID
+}
+
+// pathTo 从首条消息到指定消息的分支
+func (t *messageTree) pathTo(id int64) []*model.TAiConversationMessage {
+	var path []*model.TAiConversationMessage
+	for m, ok := t.byID[id]; ok && len(path) <= len(t.byID); m, ok = t.byID[t.parentOf(m)] {
+		path = append(path, m)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// latestLeaf 从指定消息沿最新的子消息走到末条
+func (t *messageTree) latestLeaf(id int64) int64 {
+	for steps := 0; steps <= len(t.byID); steps++ {
+		children := t.children[id]
+		if len(children) == 0 {
+			break
+		}
+		id = children[len(children)-1]
+	}
+	return id
+}
+
+// subtree 指定消息及其全部后续消息
+func (t *messageTree) subtree(id int64) []int64 {
+	ids := []int64{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, t.children[ids[i]]...)
+	}
+	return ids
+}
+
+// activeID 会话当前分支的末条消息；未记录或已删除时取最新的消息（兼容升级前的会话）
+func (t *messageTree) activeID(conv *model.TAiConversation) int64 {
+	if conv.ActiveMessageID != nil {
+		if _, ok := t.byID[*conv.ActiveMessageID]; ok {
+			return *conv.ActiveMessageID
+		}
+	}
+	return t.lastID
+}
+
+// branch 当前分支的消息及同级分支信息
+func (t *messageTree) branch(activeID int64) []ConversationMessage {
+	path := t.pathTo(activeID)
+	messages := make([]ConversationMessage, len(path))
+	for i, m := range path {
+		messages[i] = ConversationMessage{TAiConversationMessage: *m}
+		siblings := t.children[t.parentOf(m)]
+		if len(siblings) > 1 {
+			messages[i].SiblingIDs = siblings
+			for j, id := range siblings {
+				if id == m.ID {
+					messages[i].SiblingIndex = j
+				}
+			}
+		}
+	}
+	return messages
+}
+
+// loadConversationTree 加载会话及其消息树
+func loadConversationTree(db *gorm.DB, conversationID int64) (*model.TAiConversation, *messageTree, error) {
+	var conv model.TAiConversation
+	if err := db.First(&conv, conversationID).Error; err != nil {
+		return nil, nil, errors.New("会话不存在")
+	}
+	var messages []model.TAiConversationMessage
+	if err := db.Where("conversation_id = ?", conversationID).Order("id ASC").Find(&messages).Error; err != nil {
+		return nil, nil, err
+	}
+	return &conv, newMessageTree(messages), nil
+}
+
+// lockConversationTree 在事务中以 SELECT ... FOR UPDATE 锁定会话行后加载消息树，
+// 同一会话的写操作串行执行，避免并发保存基于同一当前分支而互相覆盖 active_message_id
+func lockConversationTree(tx *gorm.DB, conversationID int64) (*model.TAiConversation, *messageTree, error) {
+	var locked model.TAiConversation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, conversationID).Error; err != nil {
+		return nil, nil, errors.New("会话不存在")
+	}
+	return loadConversationTree(tx, conversationID)
+}
+
+// setActiveMessage 更新会话的当前分支，messageID 为 0 表示会话已无消息
+func setActiveMessage(db *gorm.DB, conversationID, messageID int64) error {
+	var active interface{}
+	if messageID > 0 {
+		active = messageID
+	}
+	return db.Model(&model.TAiConversation{}).Where("id = ?", conversationID).
+		Updates(map[string]interface{}{"active_message_id": active, "updated_at": time.Now()}).Error
+}
+
+func marshalMetadata(metadata map[string]interface{}) *string {
+	if metadata == nil {
+		return nil
+	}
+	b, _ := json.Marshal(metadata)
+	s := string(b)
+	return &s
 }
 
 func (l *AIConversationLogic) Create(workflowID int64, req *CreateConversationReq, userID int64) (*model.TAiConversation, error) {
@@ -72,19 +250,13 @@ func (l *AIConversationLogic) List(workflowID int64) ([]model.TAiConversation, e
 }
 
 func (l *AIConversationLogic) GetDetail(conversationID int64) (*ConversationDetail, error) {
-	var conv model.TAiConversation
-	if err := svc.Ctx.DB.First(&conv, conversationID).Error; err != nil {
+	conv, tree, err := loadConversationTree(svc.Ctx.DB, conversationID)
+	if err != nil {
 		return nil, err
 	}
-
-	var messages []model.TAiConversationMessage
-	svc.Ctx.DB.Where("conversation_id = ?", conversationID).
-		Order("created_at ASC").
-		Find(&messages)
-
 	return &ConversationDetail{
-		TAiConversation: conv,
-		Messages:        messages,
+		TAiConversation: *conv,
+		Messages:        tree.branch(tree.activeID(conv)),
 	}, nil
 }
 
@@ -111,39 +283,217 @@ func (l *AIConversationLogic) UpdateTitle(conversationID int64, title string) er
 		Update("title", title).Error
 }
 
+// DeleteMessagesFrom 删除指定消息及其所在分支上的后续消息（含其下的全部分支），其他分支保留
 func (l *AIConversationLogic) DeleteMessagesFrom(conversationID int64, messageID int64) error {
-	return svc.Ctx.DB.
-		Where("conversation_id = ? AND id >= ?", conversationID, messageID).
-		Delete(&model.TAiConversationMessage{}).Error
+	return svc.Ctx.DB.Transaction(func(tx *gorm.DB) error {
+		conv, tree, err := lockConversationTree(tx, conversationID)
+		if err != nil {
+			return err
+		}
+		msg, ok := tree.byID[messageID]
+		if !ok {
+			return errors.New("消息不存在")
+		}
+
+		ids := tree.subtree(messageID)
+		if err := tx.Where("conversation_id = ? AND id IN ?", conversationID, ids).
+			Delete(&model.TAiConversationMessage{}).Error; err != nil {
+			return err
+		}
+
+		// 当前分支被删除时切换到被删消息的父消息；删除的是首条消息时切换到剩余的最新分支
+		active := tree.activeID(conv)
+		for _, id := range ids {
+			if id != active {
+				continue
+			}
+			parent := tree.parentOf(msg)
+			if parent == 0 {
+				roots := tree.children[0]
+				for i := len(roots) - 1; i >= 0; i-- {
+					if roots[i] != messageID {
+						parent = tree.latestLeaf(roots[i])
+						break
+					}
+				}
+			}
+			return setActiveMessage(tx, conversationID, parent)
+		}
+		return nil
+	})
 }
 
+// SaveMessage 保存消息并设为当前分支的末条消息
 func (l *AIConversationLogic) SaveMessage(conversationID int64, req *SaveMessageReq) (*model.TAiConversationMessage, error) {
 	if req.Role == "" || req.Content == "" {
 		return nil, errors.New("role 和 content 不能为空")
 	}
 
-	var metaJSON *string
-	if req.Metadata != nil {
-		b, _ := json.Marshal(req.Metadata)
-		s := string(b)
-		metaJSON = &s
+	var msg *model.TAiConversationMessage
+	err := svc.Ctx.DB.Transaction(func(tx *gorm.DB) error {
+		conv, tree, err := lockConversationTree(tx, conversationID)
+		if err != nil {
+			return err
+		}
+
+		parentID := tree.activeID(conv)
+		if req.ParentID != nil {
+			parentID = *req.ParentID
+			if _, ok := tree.byID[parentID]; parentID != 0 && !ok {
+				return errors.New("父消息不存在")
+			}
+		}
+
+		msg = &model.TAiConversationMessage{
+			ConversationID: conversationID,
+			Role:           req.Role,
+			Content:        req.Content,
+			Metadata:       marshalMetadata(req.Metadata),
+		}
+		if parentID > 0 {
+			msg.ParentID = &parentID
+		}
+		if err := tx.Create(msg).Error; err != nil {
+			return err
+		}
+		return setActiveMessage(tx, conversationID, msg.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// EditMessage 编辑消息：以新内容创建原消息的同级分支并切换过去，原消息及其后续保留在原分支上
+func (l *AIConversationLogic) EditMessage(conversationID, messageID int64, req *EditMessageReq) (*model.TAiConversationMessage, error) {
+	if req.Content == "" {
+		return nil, errors.New("content 不能为空")
+	}
+
+	var orig model.TAiConversationMessage
+	if err := svc.Ctx.DB.Where("id = ? AND conversation_id = ?", messageID, conversationID).First(&orig).Error; err != nil {
+		return nil, errors.New("消息不存在")
+	}
+	parentID := int64(0)
+	if orig.ParentID != nil {
+		parentID = *orig.ParentID
 	}
+	return l.SaveMessage(conversationID, &SaveMessageReq{
+		Role:     orig.Role,
+		Content:  req.Content,
+		Metadata: req.Metadata,
+		ParentID: &parentID,
+	})
+}
 
-	msg := &model.TAiConversationMessage{
-		ConversationID: conversationID,
-		Role:           req.Role,
-		Content:        req.Content,
-		Metadata:       metaJSON,
+// Regenerate 重新生成回复：当前分支回退到该消息的父消息，随后保存的回复成为原回复的同级分支。
+// 返回回退后的会话详情，调用方据此重新执行并保存新回复
+func (l *AIConversationLogic) Regenerate(conversationID, messageID int64) (*ConversationDetail, error) {
+	err := svc.Ctx.DB.Transaction(func(tx *gorm.DB) error {
+		_, tree, err := lockConversationTree(tx, conversationID)
+		if err != nil {
+			return err
+		}
+		msg, ok := tree.byID[messageID]
+		if !ok {
+			return errors.New("消息不存在")
+		}
+		parent := tree.parentOf(msg)
+		if parent == 0 {
+			return errors.New("首条消息不能重新生成，请编辑该消息")
+		}
+		return setActiveMessage(tx, conversationID, parent)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return l.GetDetail(conversationID)
+}
 
-	if err := svc.Ctx.DB.Create(msg).Error; err != nil {
+// SwitchBranch 切换到指定消息所在的分支（沿最新的后续消息走到末条）
+func (l *AIConversationLogic) SwitchBranch(conversationID, messageID int64) (*ConversationDetail, error) {
+	err := svc.Ctx.DB.Transaction(func(tx *gorm.DB) error {
+		_, tree, err := lockConversationTree(tx, conversationID)
+		if err != nil {
+			return err
+		}
+		if _, ok := tree.byID[messageID]; !ok {
+			return errors.New("消息不存在")
+		}
+		return setActiveMessage(tx, conversationID, tree.latestLeaf(messageID))
+	})
+	if err != nil {
 		return nil, err
 	}
+	return l.GetDetail(conversationID)
+}
 
-	// 更新会话的 updated_at
-	svc.Ctx.DB.Model(&model.TAiConversation{}).
-		Where("id = ?", conversationID).
-		Update("updated_at", msg.CreatedAt)
+// Fork 以指定消息为截止点派生新会话：复制从首条到该消息的分支，原会话不受影响
+func (l *AIConversationLogic) Fork(conversationID int64, req *ForkConversationReq, userID int64) (*model.TAiConversation, error) {
+	conv, tree, err := loadConversationTree(svc.Ctx.DB, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	messageID := req.MessageID
+	if messageID == 0 {
+		messageID = tree.activeID(conv)
+	}
+	if _, ok := tree.byID[messageID]; !ok {
+		return nil, errors.New("消息不存在")
+	}
 
-	return msg, nil
+	title := req.Title
+	if title == "" {
+		title = conv.Title + "（分支）"
+	}
+	if r := []rune(title); len(r) > 200 {
+		title = string(r[:200])
+	}
+	forked := &model.TAiConversation{
+		WorkflowID:          conv.WorkflowID,
+		Title:               title,
+		Variables:           conv.Variables,
+		CreatedBy:           &userID,
+		ForkedFromID:        &conv.ID,
+		ForkedFromMessageID: &messageID,
+	}
+
+	err = svc.Ctx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(forked).Error; err != nil {
+			return err
+		}
+		lastID, err := copyMessages(tx, forked.ID, tree.pathTo(messageID))
+		if err != nil {
+			return err
+		}
+		forked.ActiveMessageID = &lastID
+		return tx.Model(forked).Update("active_message_id", lastID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return forked, nil
+}
+
+// copyMessages 将一条分支上的消息依次复制到目标会话，返回末条消息的新 ID
+func copyMessages(tx *gorm.DB, conversationID int64, path []*model.TAiConversationMessage) (int64, error) {
+	var parentID int64
+	for _, m := range path {
+		msg := &model.TAiConversationMessage{
+			ConversationID: conversationID,
+			Role:           m.Role,
+			Content:        m.Content,
+			Metadata:       m.Metadata,
+			CreatedAt:      m.CreatedAt,
+		}
+		if parentID > 0 {
+			p := parentID
+			msg.ParentID = &p
+		}
+		if err := tx.Create(msg).Error; err != nil {
+			return 0, err
+		}
+		parentID = msg.ID
+	}
+	return parentID, nil
 }
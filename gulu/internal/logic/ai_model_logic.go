@@ -34,6 +34,9 @@ type CreateAiModelReq struct {
 	ParamSize      string   `json:"param_size" validate:"max=50"`
 	CapabilityTags []string `json:"capability_tags"`
 	CustomTags     []string `json:"custom_tags"`
+	InputPrice     *float64 `json:"input_price" validate:"omitempty,min=0"`  // 输入单价（每百万 Token）
+	OutputPrice    *float64 `json:"output_price" validate:"omitempty,min=0"` // 输出单价（每百万 Token）
+	CachedPrice    *float64 `json:"cached_price" validate:"omitempty,min=0"` // 缓存命中输入单价，为空时按输入单价计
 	Sort           int32    `json:"sort"`
 	Status         int32    `json:"status"`
 }
@@ -49,6 +52,9 @@ type UpdateAiModelReq struct {
 	ParamSize      string   `json:"param_size" validate:"max=50"`
 	CapabilityTags []string `json:"capability_tags"`
 	CustomTags     []string `json:"custom_tags"`
+	InputPrice     *float64 `json:"input_price" validate:"omitempty,min=0"`  // 输入单价（每百万 Token）
+	OutputPrice    *float64 `json:"output_price" validate:"omitempty,min=0"` // 输出单价（每百万 Token）
+	CachedPrice    *float64 `json:"cached_price" validate:"omitempty,min=0"` // 缓存命中输入单价，为空时按输入单价计
 	Sort           int32    `json:"sort"`
 	Status         int32    `json:"status"`
 }
@@ -67,6 +73,9 @@ type BatchModelEntry struct {
 	ParamSize      string   `json:"param_size"`
 	CapabilityTags []string `json:"capability_tags"`
 	CustomTags     []string `json:"custom_tags"`
+	InputPrice     *float64 `json:"input_price"`
+	OutputPrice    *float64 `json:"output_price"`
+	CachedPrice    *float64 `json:"cached_price"`
 }
 
 type AiModelListReq struct {
@@ -93,19 +102,26 @@ type AiModelInfo struct {
 	ParamSize      string     `json:"param_size"`
 	CapabilityTags []string   `json:"capability_tags"`
 	CustomTags     []string   `json:"custom_tags"`
+	InputPrice     *float64   `json:"input_price"`
+	OutputPrice    *float64   `json:"output_price"`
+	CachedPrice    *float64   `json:"cached_price"`
 	Sort           int32      `json:"sort"`
 	Status         int32      `json:"status"`
 }
 
 // ModelWithCredentials 包含完整凭证的模型信息（仅内部使用）
 type ModelWithCredentials struct {
-	ID         int64
-	Name       string
-	Provider   string
-	ModelID    string
-	APIBaseURL string
-	APIKey     string
-	Status     *int32
+	ID           int64
+	Name         string
+	Provider     string
+	ProviderType string
+	ModelID      string
+	APIBaseURL   string
+	APIKey       string
+	Status       *int32
+	InputPrice   *float64
+	OutputPrice  *float64
+	CachedPrice  *float64
 }
 
 // ========== CRUD ==========
@@ -144,6 +160,9 @@ func (l *AiModelLogic) Create(req *CreateAiModelReq) (*AiModelInfo, error) {
 		ParamSize:      &req.ParamSize,
 		CapabilityTags: capabilityTagsJSON,
 		CustomTags:     customTagsJSON,
+		InputPrice:     req.InputPrice,
+		OutputPrice:    req.OutputPrice,
+		CachedPrice:    req.CachedPrice,
 		Sort:           &req.Sort,
 		Status:         &status,
 	}
@@ -187,6 +206,9 @@ func (l *AiModelLogic) BatchCreate(req *BatchCreateModelReq) ([]*AiModelInfo, er
 			ParamSize:      &entry.ParamSize,
 			CapabilityTags: capJSON,
 			CustomTags:     customJSON,
+			InputPrice:     entry.InputPrice,
+			OutputPrice:    entry.OutputPrice,
+			CachedPrice:    entry.CachedPrice,
 			Sort:           new(int32),
 			Status:         &status,
 		}
@@ -246,6 +268,15 @@ func (l *AiModelLogic) Update(id int64, req *UpdateAiModelReq) error {
 		b, _ := json.Marshal(req.CustomTags)
 		updates["custom_tags"] = string(b)
 	}
+	if req.InputPrice != nil {
+		updates["input_price"] = *req.InputPrice
+	}
+	if req.OutputPrice != nil {
+		updates["output_price"] = *req.OutputPrice
+	}
+	if req.CachedPrice != nil {
+		updates["cached_price"] = *req.CachedPrice
+	}
 
 	_, err = m.WithContext(l.ctx).Where(m.ID.Eq(id)).Updates(updates)
 	return err
@@ -289,6 +320,10 @@ func (l *AiModelLogic) GetByIDWithKey(id int64) (*ModelWithCredentials, error) {
 		Provider: aiModel.Provider,
 		ModelID:  aiModel.ModelID,
 		Status:   aiModel.Status,
+
+		InputPrice:  aiModel.InputPrice,
+		OutputPrice: aiModel.OutputPrice,
+		CachedPrice: aiModel.CachedPrice,
 	}
 
 	// 从供应商获取凭证
@@ -296,6 +331,7 @@ func (l *AiModelLogic) GetByIDWithKey(id int64) (*ModelWithCredentials, error) {
 		pl := NewAiProviderLogic(l.ctx)
 		provider, err := pl.GetByIDWithKey(aiModel.ProviderID)
 		if err == nil {
+			result.ProviderType = provider.ProviderType
 			result.APIBaseURL = provider.APIBaseURL
 			result.APIKey = provider.APIKey
 			if result.Provider == "" {
@@ -394,6 +430,9 @@ func (l *AiModelLogic) toAiModelInfo(m *model.TAiModel) *AiModelInfo {
 		Name:         m.Name,
 		Provider:     m.Provider,
 		ModelID:      m.ModelID,
+		InputPrice:   m.InputPrice,
+		OutputPrice:  m.OutputPrice,
+		CachedPrice:  m.CachedPrice,
 	}
 
 	if m.Version != nil {
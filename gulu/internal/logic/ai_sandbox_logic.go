@@ -0,0 +1,114 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+	"yqhp/workflow-engine/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+// AiSandboxLogic 项目 AI 工具沙箱策略逻辑
+type AiSandboxLogic struct {
+	ctx context.Context
+}
+
+// NewAiSandboxLogic 创建 AI 工具沙箱策略逻辑
+func NewAiSandboxLogic(ctx context.Context) *AiSandboxLogic {
+	return &AiSandboxLogic{ctx: ctx}
+}
+
+// AiSandboxPolicyInfo 项目沙箱策略
+type AiSandboxPolicyInfo struct {
+	ProjectID  int64                `json:"project_id"`
+	Configured bool                 `json:"configured"` // 未配置时返回引擎默认策略
+	Policy     *types.SandboxPolicy `json:"policy"`
+	UpdatedAt  *time.Time           `json:"updated_at,omitempty"`
+}
+
+func (l *AiSandboxLogic) db() *gorm.DB {
+	return svc.Ctx.DB.WithContext(l.ctx)
+}
+
+// GetPolicy 获取项目沙箱策略，未配置时返回 nil
+func (l *AiSandboxLogic) GetPolicy(projectID int64) (*types.SandboxPolicy, error) {
+	if projectID <= 0 {
+		return nil, nil
+	}
+	var record model.TAiSandboxPolicy
+	err := l.db().Where("project_id = ?", projectID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var policy types.SandboxPolicy
+	if err := json.Unmarshal([]byte(record.Policy), &policy); err != nil {
+		return nil, errors.New("沙箱策略解析失败: " + err.Error())
+	}
+	return &policy, nil
+}
+
+// GetPolicyInfo 获取项目沙箱策略（含默认值）
+func (l *AiSandboxLogic) GetPolicyInfo(projectID int64) (*AiSandboxPolicyInfo, error) {
+	var record model.TAiSandboxPolicy
+	err := l.db().Where("project_id = ?", projectID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &AiSandboxPolicyInfo{ProjectID: projectID, Policy: (*types.SandboxPolicy)(nil).WithDefaults()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var policy types.SandboxPolicy
+	if err := json.Unmarshal([]byte(record.Policy), &policy); err != nil {
+		return nil, errors.New("沙箱策略解析失败: " + err.Error())
+	}
+	return &AiSandboxPolicyInfo{
+		ProjectID:  projectID,
+		Configured: true,
+		Policy:     policy.WithDefaults(),
+		UpdatedAt:  record.UpdatedAt,
+	}, nil
+}
+
+// SavePolicy 创建或更新项目沙箱策略
+func (l *AiSandboxLogic) SavePolicy(projectID int64, policy *types.SandboxPolicy, userID int64) (*AiSandboxPolicyInfo, error) {
+	if projectID <= 0 {
+		return nil, errors.New("请选择项目")
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var record model.TAiSandboxPolicy
+	err = l.db().Where("project_id = ?", projectID).First(&record).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		record = model.TAiSandboxPolicy{CreatedAt: &now, ProjectID: projectID}
+	case err != nil:
+		return nil, err
+	}
+	record.UpdatedAt = &now
+	record.UpdatedBy = &userID
+	record.Policy = string(data)
+	if err := l.db().Save(&record).Error; err != nil {
+		return nil, err
+	}
+	return l.GetPolicyInfo(projectID)
+}
+
+// DeletePolicy 删除项目沙箱策略，恢复引擎默认策略
+func (l *AiSandboxLogic) DeletePolicy(projectID int64) error {
+	return l.db().Where("project_id = ?", projectID).Delete(&model.TAiSandboxPolicy{}).Error
+}
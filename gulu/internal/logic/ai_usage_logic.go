@@ -0,0 +1,551 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AiUsageLogic AI 用量统计与项目预算逻辑
+type AiUsageLogic struct {
+	ctx context.Context
+}
+
+// NewAiUsageLogic 创建 AI 用量逻辑
+func NewAiUsageLogic(ctx context.Context) *AiUsageLogic {
+	return &AiUsageLogic{ctx: ctx}
+}
+
+// AiUsageScope 用量归属：项目、用户、执行与会话
+type AiUsageScope struct {
+	ProjectID      int64
+	UserID         int64
+	ExecutionID    string
+	ConversationID string
+}
+
+// SaveAiBudgetReq 保存项目预算请求
+type SaveAiBudgetReq struct {
+	Period    string  `json:"period"`     // daily 或 monthly，默认 monthly
+	SoftLimit float64 `json:"soft_limit"` // 软上限，超出后告警，0 表示不限制
+	HardLimit float64 `json:"hard_limit"` // 硬上限，超出后停止调用模型，0 表示不限制
+	Status    *int32  `json:"status"`     // 默认启用
+}
+
+// AiBudgetStatus 项目预算及当前周期消耗
+type AiBudgetStatus struct {
+	ProjectID   int64     `json:"project_id"`
+	Period      string    `json:"period"`
+	SoftLimit   float64   `json:"soft_limit"`
+	HardLimit   float64   `json:"hard_limit"`
+	Enabled     bool      `json:"enabled"`
+	PeriodStart time.Time `json:"period_start"`
+	Spent       float64   `json:"spent"`
+	Warning     bool      `json:"warning"`  // 已超出软上限
+	Exceeded    bool      `json:"exceeded"` // 已超出硬上限
+}
+
+// AiUsageSummaryReq 用量汇总请求
+type AiUsageSummaryReq struct {
+	ProjectID int64  `query:"projectId"`
+	StartDate string `query:"startDate"` // yyyy-MM-dd，默认最近 30 天
+	EndDate   string `query:"endDate"`   // yyyy-MM-dd，包含当天
+	GroupBy   string `query:"groupBy"`   // day, model, user, execution, conversation, source，默认 day
+	Source    string `query:"source"`
+}
+
+// AiUsageListReq 用量明细列表请求
+type AiUsageListReq struct {
+	Page           int    `query:"page" validate:"min=1"`
+	PageSize       int    `query:"pageSize" validate:"min=1,max=100"`
+	ProjectID      int64  `query:"projectId"`
+	UserID         int64  `query:"userId"`
+	ExecutionID    string `query:"executionId"`
+	ConversationID string `query:"conversationId"`
+	Source         string `query:"source"`
+	StartDate      string `query:"startDate"`
+	EndDate        string `query:"endDate"`
+}
+
+// AiUsageStat 用量统计项
+type AiUsageStat struct {
+	Key              string  `json:"key"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CachedTokens     int64   `json:"cached_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// AiUsageSummary 用量汇总
+type AiUsageSummary struct {
+	StartDate string         `json:"start_date"`
+	EndDate   string         `json:"end_date"`
+	GroupBy   string         `json:"group_by"`
+	Total     AiUsageStat    `json:"total"`
+	Items     []*AiUsageStat `json:"items"`
+}
+
+// usageGroupColumns 汇总维度对应的分组表达式
+var usageGroupColumns = map[string]string{
+	"day":          "DATE_FORMAT(created_at, '%Y-%m-%d')",
+	"model":        "model",
+	"user":         "user_id",
+	"execution":    "execution_id",
+	"conversation": "conversation_id",
+	"source":       "source",
+}
+
+const usageStatColumns = "COUNT(*) AS calls, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, " +
+	"COALESCE(SUM(cached_tokens), 0) AS cached_tokens, COALESCE(SUM(completion_tokens), 0) AS completion_tokens, " +
+	"COALESCE(SUM(total_tokens), 0) AS total_tokens, COALESCE(SUM(cost), 0) AS cost"
+
+func (l *AiUsageLogic) db() *gorm.DB {
+	return svc.Ctx.DB.WithContext(l.ctx)
+}
+
+// ========== 用量记录 ==========
+
+// Record 写入用量记录并计入项目预算已用金额，无用量时忽略
+func (l *AiUsageLogic) Record(usage *model.TAiUsage) error {
+	if usage.TotalTokens <= 0 && usage.Cost <= 0 {
+		return nil
+	}
+	if usage.CreatedAt == nil {
+		now := time.Now()
+		usage.CreatedAt = &now
+	}
+	return l.db().Transaction(func(tx *gorm.DB) error {
+		if _, err := chargeBudget(tx, usage.ProjectID, usage.Cost, false); err != nil {
+			return err
+		}
+		return tx.Create(usage).Error
+	})
+}
+
+// 用量账本阶段
+const (
+	AiUsagePhaseReserve = "reserve"
+	AiUsagePhaseSettle  = "settle"
+)
+
+// AiUsageChargeReq 执行引擎的用量账本请求：调用模型前预留预估费用，调用后按实际费用结算并记录用量。
+// 用量归属字段由 Gulu 注入步骤配置，引擎原样回传
+type AiUsageChargeReq struct {
+	Phase          string         `json:"phase"`    // reserve 或 settle
+	Amount         float64        `json:"amount"`   // reserve 为预估费用，settle 为实际费用
+	Reserved       float64        `json:"reserved"` // settle：调用前预留的金额
+	ExecutionID    string         `json:"execution_id"`
+	UserID         int64          `json:"user_id"`
+	ConversationID string         `json:"conversation_id"`
+	AiModelID      int64          `json:"ai_model_id"`
+	StepID         string         `json:"step_id"`
+	Model          string         `json:"model"`
+	Usage          *AiUsageTokens `json:"usage"`
+}
+
+// AiUsageTokens 单次模型调用的 Token 用量
+type AiUsageTokens struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CachedTokens     int64 `json:"cached_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// AiBudgetCharge 扣费后的项目预算状态，Allowed 为 false 表示预留后将超出硬上限，未预留
+type AiBudgetCharge struct {
+	Allowed   bool    `json:"allowed"`
+	Spent     float64 `json:"spent"`
+	SoftLimit float64 `json:"soft_limit"`
+	HardLimit float64 `json:"hard_limit"`
+}
+
+// Charge 在项目预算上预留或结算一次模型调用的费用，结算时记录用量。
+// 预算行在事务内加锁，并发执行对同一项目的预留按顺序扣减，不会共同超出硬上限
+func (l *AiUsageLogic) Charge(projectID int64, req *AiUsageChargeReq) (*AiBudgetCharge, error) {
+	if req.Amount < 0 || req.Reserved < 0 {
+		return nil, errors.New("金额不能为负数")
+	}
+
+	var charge *AiBudgetCharge
+	err := l.db().Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch req.Phase {
+		case AiUsagePhaseReserve:
+			charge, err = chargeBudget(tx, projectID, req.Amount, true)
+			return err
+		case AiUsagePhaseSettle:
+			if charge, err = chargeBudget(tx, projectID, req.Amount-req.Reserved, false); err != nil {
+				return err
+			}
+			if req.Usage == nil || (req.Usage.TotalTokens <= 0 && req.Amount <= 0) {
+				return nil
+			}
+			now := time.Now()
+			return tx.Create(&model.TAiUsage{
+				CreatedAt:        &now,
+				ProjectID:        projectID,
+				UserID:           req.UserID,
+				Source:           model.AiUsageSourceWorkflow,
+				ExecutionID:      req.ExecutionID,
+				StepID:           req.StepID,
+				ConversationID:   req.ConversationID,
+				AiModelID:        req.AiModelID,
+				Model:            req.Model,
+				PromptTokens:     req.Usage.PromptTokens,
+				CachedTokens:     req.Usage.CachedTokens,
+				CompletionTokens: req.Usage.CompletionTokens,
+				TotalTokens:      req.Usage.TotalTokens,
+				Cost:             req.Amount,
+			}).Error
+		default:
+			return fmt.Errorf("不支持的账本阶段: %s", req.Phase)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return charge, nil
+}
+
+// chargeBudget 锁定项目预算行并累加已用金额，enforce 为 true 时累加后超出硬上限则不累加并返回 Allowed=false。
+// 未配置或已禁用预算时不扣减。进入新周期（或升级后首次扣费）时按用量记录重新统计已用金额，
+// 上一周期调用中的预留在新周期结算时只修正差额
+func chargeBudget(tx *gorm.DB, projectID int64, amount float64, enforce bool) (*AiBudgetCharge, error) {
+	var budget model.TAiBudget
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("project_id = ?", projectID).First(&budget).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &AiBudgetCharge{Allowed: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if budget.Status != nil && *budget.Status != 1 {
+		return &AiBudgetCharge{Allowed: true}, nil
+	}
+
+	start := budgetPeriodStart(budget.Period, time.Now())
+	spent := budget.Spent
+	if !samePeriodStart(budget.PeriodStart, start) {
+		if spent, err = periodUsageCost(tx, projectID, start); err != nil {
+			return nil, err
+		}
+	}
+	charge := &AiBudgetCharge{Allowed: true, Spent: spent, SoftLimit: budget.SoftLimit, HardLimit: budget.HardLimit}
+	if enforce && budget.HardLimit > 0 && (spent >= budget.HardLimit || spent+amount > budget.HardLimit) {
+		charge.Allowed = false
+		return charge, nil
+	}
+
+	spent += amount
+	if spent < 0 {
+		spent = 0
+	}
+	err = tx.Model(&model.TAiBudget{}).Where("id = ?", budget.ID).
+		Updates(map[string]interface{}{"spent": spent, "period_start": start}).Error
+	if err != nil {
+		return nil, err
+	}
+	charge.Spent = spent
+	return charge, nil
+}
+
+// periodUsageCost 预算周期内用量记录的费用合计
+func periodUsageCost(db *gorm.DB, projectID int64, start time.Time) (float64, error) {
+	var spent float64
+	err := db.Model(&model.TAiUsage{}).Select("COALESCE(SUM(cost), 0)").
+		Where("project_id = ? AND created_at >= ?", projectID, start).Scan(&spent).Error
+	return spent, err
+}
+
+// samePeriodStart 按本地时间比较周期起点，避免数据库时区设置造成误判
+func samePeriodStart(stored *time.Time, start time.Time) bool {
+	return stored != nil && stored.In(start.Location()).Format(time.DateTime) == start.Format(time.DateTime)
+}
+
+// ModelCost 按模型单价计算费用，单价为每百万 Token，promptTokens 包含缓存命中部分
+func ModelCost(m *ModelWithCredentials, promptTokens, cachedTokens, completionTokens int64) float64 {
+	if m == nil || (m.InputPrice == nil && m.OutputPrice == nil) {
+		return 0
+	}
+	var inputPrice, outputPrice float64
+	if m.InputPrice != nil {
+		inputPrice = *m.InputPrice
+	}
+	if m.OutputPrice != nil {
+		outputPrice = *m.OutputPrice
+	}
+	cachedPrice := inputPrice
+	if m.CachedPrice != nil {
+		cachedPrice = *m.CachedPrice
+	}
+	uncached := promptTokens - cachedTokens
+	if uncached < 0 {
+		uncached = 0
+	}
+	return (float64(uncached)*inputPrice + float64(cachedTokens)*cachedPrice + float64(completionTokens)*outputPrice) / 1e6
+}
+
+// ModelPricingConfig 注入 AI 步骤的单价配置，模型未配置单价时返回 nil
+func ModelPricingConfig(m *ModelWithCredentials) map[string]interface{} {
+	if m.InputPrice == nil && m.OutputPrice == nil {
+		return nil
+	}
+	pricing := map[string]interface{}{"input_price": 0.0, "output_price": 0.0}
+	if m.InputPrice != nil {
+		pricing["input_price"] = *m.InputPrice
+	}
+	if m.OutputPrice != nil {
+		pricing["output_price"] = *m.OutputPrice
+	}
+	if m.CachedPrice != nil {
+		pricing["cached_price"] = *m.CachedPrice
+	}
+	return pricing
+}
+
+// ========== 预算 ==========
+
+// GetBudget 获取项目预算及当前周期消耗，未配置时返回 nil
+func (l *AiUsageLogic) GetBudget(projectID int64) (*AiBudgetStatus, error) {
+	var budget model.TAiBudget
+	err := l.db().Where("project_id = ?", projectID).First(&budget).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l.budgetStatus(&budget)
+}
+
+// SaveBudget 创建或更新项目预算
+func (l *AiUsageLogic) SaveBudget(projectID int64, req *SaveAiBudgetReq, userID int64) (*AiBudgetStatus, error) {
+	if projectID <= 0 {
+		return nil, errors.New("请选择项目")
+	}
+	if req.Period == "" {
+		req.Period = model.AiBudgetPeriodMonthly
+	}
+	if req.Period != model.AiBudgetPeriodDaily && req.Period != model.AiBudgetPeriodMonthly {
+		return nil, errors.New("预算周期仅支持 daily 或 monthly")
+	}
+	if req.SoftLimit < 0 || req.HardLimit < 0 {
+		return nil, errors.New("预算上限不能为负数")
+	}
+	if req.SoftLimit > 0 && req.HardLimit > 0 && req.SoftLimit > req.HardLimit {
+		return nil, errors.New("软上限不能大于硬上限")
+	}
+	status := int32(1)
+	if req.Status != nil {
+		status = *req.Status
+	}
+
+	now := time.Now()
+	var budget model.TAiBudget
+	err := l.db().Where("project_id = ?", projectID).First(&budget).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		budget = model.TAiBudget{CreatedAt: &now, ProjectID: projectID}
+	case err != nil:
+		return nil, err
+	}
+	budget.UpdatedAt = &now
+	budget.UpdatedBy = &userID
+	budget.Period = req.Period
+	budget.SoftLimit = req.SoftLimit
+	budget.HardLimit = req.HardLimit
+	budget.Status = &status
+	// 已用金额由账本在行锁内维护，保存预算配置时不覆盖
+	if err := l.db().Omit("spent", "period_start").Save(&budget).Error; err != nil {
+		return nil, err
+	}
+	return l.budgetStatus(&budget)
+}
+
+// CheckBudget 调用模型前检查项目预算，超出硬上限时返回错误；未配置或已禁用预算时返回 nil
+func (l *AiUsageLogic) CheckBudget(projectID int64) (*AiBudgetStatus, error) {
+	if projectID <= 0 {
+		return nil, nil
+	}
+	status, err := l.GetBudget(projectID)
+	if err != nil || status == nil || !status.Enabled {
+		return nil, err
+	}
+	if status.Exceeded {
+		return status, fmt.Errorf("项目 AI 费用已超出预算硬上限: 已用 %.4f，上限 %.4f", status.Spent, status.HardLimit)
+	}
+	return status, nil
+}
+
+func (l *AiUsageLogic) budgetStatus(budget *model.TAiBudget) (*AiBudgetStatus, error) {
+	start := budgetPeriodStart(budget.Period, time.Now())
+	status := &AiBudgetStatus{
+		ProjectID:   budget.ProjectID,
+		Period:      budget.Period,
+		SoftLimit:   budget.SoftLimit,
+		HardLimit:   budget.HardLimit,
+		Enabled:     budget.Status == nil || *budget.Status == 1,
+		PeriodStart: start,
+	}
+
+	// 账本已记录本周期已用金额（含调用中的预留）时以账本为准
+	spent := budget.Spent
+	if !samePeriodStart(budget.PeriodStart, start) {
+		var err error
+		if spent, err = periodUsageCost(l.db(), budget.ProjectID, start); err != nil {
+			return nil, err
+		}
+	}
+	status.Spent = spent
+	status.Warning = budget.SoftLimit > 0 && spent >= budget.SoftLimit
+	status.Exceeded = budget.HardLimit > 0 && spent >= budget.HardLimit
+	return status, nil
+}
+
+// budgetPeriodStart 预算周期起点：按天为当天零点，按月为当月 1 日零点
+func budgetPeriodStart(period string, now time.Time) time.Time {
+	if period == model.AiBudgetPeriodDaily {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+// ========== 统计 ==========
+
+// Summary 按维度汇总用量与费用
+func (l *AiUsageLogic) Summary(req *AiUsageSummaryReq) (*AiUsageSummary, error) {
+	if req.GroupBy == "" {
+		req.GroupBy = "day"
+	}
+	groupColumn, ok := usageGroupColumns[req.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("不支持的统计维度: %s", req.GroupBy)
+	}
+	start, end, err := parseUsageDateRange(req.StartDate, req.EndDate, 30)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := func() *gorm.DB {
+		q := l.db().Model(&model.TAiUsage{}).Where("created_at >= ? AND created_at < ?", start, end)
+		if req.ProjectID > 0 {
+			q = q.Where("project_id = ?", req.ProjectID)
+		}
+		if req.Source != "" {
+			q = q.Where("source = ?", req.Source)
+		}
+		return q
+	}
+
+	summary := &AiUsageSummary{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		GroupBy:   req.GroupBy,
+		Items:     []*AiUsageStat{},
+	}
+	if err := filter().Select(usageStatColumns).Scan(&summary.Total).Error; err != nil {
+		return nil, err
+	}
+	order := "cost DESC"
+	if req.GroupBy == "day" {
+		order = "`key`"
+	}
+	err = filter().Select(groupColumn + " AS `key`, " + usageStatColumns).
+		Group("`key`").Order(order).Limit(1000).Scan(&summary.Items).Error
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// List 用量明细列表
+func (l *AiUsageLogic) List(req *AiUsageListReq) ([]*model.TAiUsage, int64, error) {
+	q := l.db().Model(&model.TAiUsage{})
+	if req.ProjectID > 0 {
+		q = q.Where("project_id = ?", req.ProjectID)
+	}
+	if req.UserID > 0 {
+		q = q.Where("user_id = ?", req.UserID)
+	}
+	if req.ExecutionID != "" {
+		q = q.Where("execution_id = ?", req.ExecutionID)
+	}
+	if req.ConversationID != "" {
+		q = q.Where("conversation_id = ?", req.ConversationID)
+	}
+	if req.Source != "" {
+		q = q.Where("source = ?", req.Source)
+	}
+	if req.StartDate != "" || req.EndDate != "" {
+		start, end, err := parseUsageDateRange(req.StartDate, req.EndDate, 30)
+		if err != nil {
+			return nil, 0, err
+		}
+		q = q.Where("created_at >= ? AND created_at < ?", start, end)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	var list []*model.TAiUsage
+	offset := (req.Page - 1) * req.PageSize
+	if err := q.Order("id DESC").Offset(offset).Limit(req.PageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// parseUsageDateRange 解析日期区间 [start, end)，结束日期包含当天；未指定开始日期时取最近 defaultDays 天
+func parseUsageDateRange(startDate, endDate string, defaultDays int) (time.Time, time.Time, error) {
+	now := time.Now()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	if endDate != "" {
+		d, err := time.ParseInLocation("2006-01-02", endDate, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("结束日期格式错误: %s", endDate)
+		}
+		end = d.AddDate(0, 0, 1)
+	}
+	start := end.AddDate(0, 0, -defaultDays)
+	if startDate != "" {
+		d, err := time.ParseInLocation("2006-01-02", startDate, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("开始日期格式错误: %s", startDate)
+		}
+		start = d
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, errors.New("开始日期不能晚于结束日期")
+	}
+	return start, end, nil
+}
+
+func configInt64(config map[string]interface{}, key string) int64 {
+	switch v := config[key].(type) {
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,491 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"yqhp/gulu/internal/importer"
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/query"
+	"yqhp/gulu/internal/svc"
+	"yqhp/gulu/internal/utils"
+	"yqhp/gulu/internal/workflow"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+const (
+	importFetchTimeout = 30 * time.Second
+	importMaxSize      = 20 << 20 // 20MB
+)
+
+// 导入动作
+const (
+	ImportActionCreate    = "create"    // 新建工作流
+	ImportActionUpdate    = "update"    // 更新已存在的工作流
+	ImportActionUnchanged = "unchanged" // 无变化
+)
+
+// APIImportLogic 接口文档导入逻辑
+type APIImportLogic struct {
+	ctx context.Context
+}
+
+// NewAPIImportLogic 创建接口文档导入逻辑
+func NewAPIImportLogic(ctx context.Context) *APIImportLogic {
+	return &APIImportLogic{ctx: ctx}
+}
+
+// APIImportReq 接口文档导入请求
+type APIImportReq struct {
+	ProjectID   int64  `json:"project_id" form:"project_id"`
+	Format      string `json:"format" form:"format"`           // openapi（默认，兼容 Swagger 2）、postman、har、curl
+	Content     string `json:"content" form:"content"`         // 文档内容（粘贴或上传文件）
+	URL         string `json:"url" form:"url"`                 // 文档地址，Content 为空时从该地址下载
+	GroupBy     string `json:"group_by" form:"group_by"`       // openapi: tag（默认）、path；postman: folder（默认）、none
+	Environment string `json:"environment" form:"environment"` // Postman 环境文件内容（可选）
+}
+
+// APIImportDomain 导入涉及的域名配置
+type APIImportDomain struct {
+	Name    string `json:"name"`
+	BaseURL string `json:"base_url"`
+	Code    string `json:"code"`   // 配置编码，预览时新域名为空
+	Exists  bool   `json:"exists"` // 项目中已存在同名域名配置
+}
+
+// APIImportVariable 导入涉及的环境变量配置
+type APIImportVariable struct {
+	Name      string `json:"name"`
+	Sensitive bool   `json:"sensitive"`
+	Exists    bool   `json:"exists"` // 项目中已存在同名变量，保留原有值
+}
+
+// APIImportWorkflow 导入生成的工作流
+type APIImportWorkflow struct {
+	Name       string                   `json:"name"`
+	WorkflowID int64                    `json:"workflow_id"` // 已存在或新建的工作流ID
+	Action     string                   `json:"action"`      // create, update, unchanged
+	StepCount  int                      `json:"step_count"`
+	Diff       *workflow.DefinitionDiff `json:"diff,omitempty"` // 更新时相对现有工作流的变更
+}
+
+// APIImportResult 导入预览/结果
+type APIImportResult struct {
+	Title     string               `json:"title"`
+	Domains   []*APIImportDomain   `json:"domains"`
+	Variables []*APIImportVariable `json:"variables"`
+	Workflows []*APIImportWorkflow `json:"workflows"`
+	Warnings  []string             `json:"warnings"` // 无法自动转换、需要手动处理的内容
+}
+
+// importPlanItem 单个工作流的导入计划
+type importPlanItem struct {
+	info     *APIImportWorkflow
+	existing *model.TWorkflow
+	def      *workflow.WorkflowDefinition
+}
+
+// Preview 预览导入结果：新建哪些工作流与域名，已存在的工作流有哪些接口变更
+func (l *APIImportLogic) Preview(req *APIImportReq) (*APIImportResult, error) {
+	collection, err := l.parse(req)
+	if err != nil {
+		return nil, err
+	}
+	domains, codes, err := l.planDomains(req.ProjectID, collection)
+	if err != nil {
+		return nil, err
+	}
+	variables, err := l.planVariables(req.ProjectID, collection)
+	if err != nil {
+		return nil, err
+	}
+	items, err := l.plan(req.ProjectID, collection, codes)
+	if err != nil {
+		return nil, err
+	}
+	return buildImportResult(collection, domains, variables, items), nil
+}
+
+// Import 执行导入：创建缺失的域名配置，新建工作流或以新版本更新已存在的同名工作流
+func (l *APIImportLogic) Import(req *APIImportReq, userID int64) (*APIImportResult, error) {
+	collection, err := l.parse(req)
+	if err != nil {
+		return nil, err
+	}
+	domains, codes, err := l.planDomains(req.ProjectID, collection)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.createDomains(req.ProjectID, collection, domains, codes); err != nil {
+		return nil, err
+	}
+	variables, err := l.planVariables(req.ProjectID, collection)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.createVariables(req.ProjectID, collection, variables); err != nil {
+		return nil, err
+	}
+	items, err := l.plan(req.ProjectID, collection, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	workflowLogic := NewWorkflowLogic(l.ctx)
+	for _, item := range items {
+		definition, err := workflow.ToJSON(item.def)
+		if err != nil {
+			return nil, err
+		}
+		switch item.info.Action {
+		case ImportActionCreate:
+			wf, err := workflowLogic.Create(&CreateWorkflowReq{
+				ProjectID:   req.ProjectID,
+				Name:        item.info.Name,
+				Description: item.def.Description,
+				Definition:  definition,
+				Status:      1,
+			}, userID)
+			if err != nil {
+				return nil, fmt.Errorf("创建工作流 %s 失败: %w", item.info.Name, err)
+			}
+			item.info.WorkflowID = wf.ID
+		case ImportActionUpdate:
+			status := int32(1)
+			if item.existing.Status != nil {
+				status = *item.existing.Status
+			}
+			err := workflowLogic.Update(item.existing.ID, &UpdateWorkflowReq{
+				Definition: definition,
+				Status:     status,
+				Message:    "重新导入 " + collection.Title,
+			}, userID)
+			if err != nil {
+				return nil, fmt.Errorf("更新工作流 %s 失败: %w", item.info.Name, err)
+			}
+		}
+	}
+
+	return buildImportResult(collection, domains, variables, items), nil
+}
+
+// parse 读取并解析文档
+func (l *APIImportLogic) parse(req *APIImportReq) (*importer.Collection, error) {
+	if req.ProjectID <= 0 {
+		return nil, errors.New("项目ID不能为空")
+	}
+
+	content := []byte(req.Content)
+	if len(content) == 0 {
+		if req.URL == "" {
+			return nil, errors.New("文档内容与文档地址不能同时为空")
+		}
+		data, err := l.fetch(req.URL)
+		if err != nil {
+			return nil, err
+		}
+		content = data
+	}
+
+	switch req.Format {
+	case "", importer.SourceOpenAPI:
+		return importer.ParseOpenAPI(content, req.GroupBy)
+	case importer.SourcePostman:
+		return importer.ParsePostman(content, []byte(req.Environment), req.GroupBy)
+	case importer.SourceHAR:
+		return importer.ParseHAR(content)
+	case importer.SourceCurl:
+		return importer.ParseCurl(content)
+	default:
+		return nil, fmt.Errorf("不支持的导入格式: %s", req.Format)
+	}
+}
+
+// fetch 下载文档，默认只允许访问公网地址（gulu.allow_private_network）
+func (l *APIImportLogic) fetch(rawURL string) ([]byte, error) {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		return nil, errors.New("文档地址仅支持 http/https")
+	}
+
+	ctx, cancel := context.WithTimeout(l.ctx, importFetchTimeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	allowPrivate := svc.Ctx != nil && svc.Ctx.Config != nil && svc.Ctx.Config.Gulu.AllowPrivateNetwork
+	resp, err := utils.NewPublicHTTPClient(importFetchTimeout, allowPrivate).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("下载文档失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载文档失败: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, importMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("下载文档失败: %w", err)
+	}
+	if len(data) > importMaxSize {
+		return nil, errors.New("文档过大")
+	}
+	return data, nil
+}
+
+// planDomains 按名称匹配项目中已有的域名配置，返回服务地址 key -> 配置编码
+func (l *APIImportLogic) planDomains(projectID int64, c *importer.Collection) ([]*APIImportDomain, map[string]string, error) {
+	q := query.Q
+	cd := q.TConfigDefinition
+	existing, err := cd.WithContext(l.ctx).
+		Where(cd.ProjectID.Eq(projectID), cd.Type.Eq(model.ConfigTypeDomain), cd.IsDelete.Is(false)).
+		Find()
+	if err != nil {
+		return nil, nil, err
+	}
+	byName := make(map[string]string, len(existing))
+	for _, d := range existing {
+		byName[d.Name] = d.Code
+	}
+
+	domains := make([]*APIImportDomain, 0, len(c.Servers))
+	codes := make(map[string]string, len(c.Servers))
+	for _, s := range c.Servers {
+		d := &APIImportDomain{Name: importDomainName(c, s), BaseURL: s.URL}
+		if code, ok := byName[d.Name]; ok {
+			d.Code = code
+			d.Exists = true
+			codes[s.Key] = code
+		}
+		domains = append(domains, d)
+	}
+	return domains, codes, nil
+}
+
+// importDomainName 域名配置名称：优先使用服务描述，其次地址
+func importDomainName(c *importer.Collection, s *importer.Server) string {
+	name := s.Description
+	if name == "" {
+		name = s.URL
+	}
+	if name == "" {
+		name = c.Title
+	}
+	if len([]rune(name)) > 100 {
+		name = string([]rune(name)[:100])
+	}
+	return name
+}
+
+// createDomains 创建缺失的域名配置，并将服务地址写入项目下所有环境
+func (l *APIImportLogic) createDomains(projectID int64, c *importer.Collection, domains []*APIImportDomain, codes map[string]string) error {
+	q := query.Q
+	for i, s := range c.Servers {
+		d := domains[i]
+		if d.Exists {
+			continue
+		}
+		def, err := CreateConfigDefinition(l.ctx, &CreateConfigDefinitionReq{
+			ProjectID:   projectID,
+			Type:        model.ConfigTypeDomain,
+			Name:        d.Name,
+			Description: "导入自 " + c.Title,
+			Status:      1,
+		})
+		if err != nil {
+			return fmt.Errorf("创建域名配置 %s 失败: %w", d.Name, err)
+		}
+
+		value, _ := json.Marshal(map[string]interface{}{"base_url": s.URL, "headers": []interface{}{}})
+		if _, err := q.TConfig.WithContext(l.ctx).Where(q.TConfig.Code.Eq(def.Code)).Update(q.TConfig.Value, string(value)); err != nil {
+			return err
+		}
+		d.Code = def.Code
+		codes[s.Key] = def.Code
+	}
+	return nil
+}
+
+// planVariables 按名称匹配项目中已有的变量配置
+func (l *APIImportLogic) planVariables(projectID int64, c *importer.Collection) ([]*APIImportVariable, error) {
+	variables := make([]*APIImportVariable, 0, len(c.EnvVariables))
+	if len(c.EnvVariables) == 0 {
+		return variables, nil
+	}
+
+	cd := query.Q.TConfigDefinition
+	existing, err := cd.WithContext(l.ctx).
+		Where(cd.ProjectID.Eq(projectID), cd.Type.Eq(model.ConfigTypeVariable), cd.IsDelete.Is(false)).
+		Find()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(existing))
+	for _, d := range existing {
+		names[d.Name] = true
+	}
+
+	for _, v := range c.EnvVariables {
+		variables = append(variables, &APIImportVariable{Name: v.Name, Sensitive: v.Sensitive, Exists: names[v.Name]})
+	}
+	return variables, nil
+}
+
+// createVariables 创建缺失的变量配置，并将导入的值写入项目下所有环境；已存在的变量保留原有值
+func (l *APIImportLogic) createVariables(projectID int64, c *importer.Collection, variables []*APIImportVariable) error {
+	if len(variables) == 0 {
+		return nil
+	}
+
+	q := query.Q
+	envs, err := q.TEnv.WithContext(l.ctx).
+		Where(q.TEnv.ProjectID.Eq(projectID), q.TEnv.IsDelete.Is(false)).
+		Find()
+	if err != nil {
+		return err
+	}
+
+	for i, v := range c.EnvVariables {
+		if variables[i].Exists {
+			continue
+		}
+		def, err := CreateConfigDefinition(l.ctx, &CreateConfigDefinitionReq{
+			ProjectID:   projectID,
+			Type:        model.ConfigTypeVariable,
+			Name:        v.Name,
+			Description: "导入自 " + c.Title,
+			Extra:       model.VariableExtra{VarType: "string", IsSensitive: v.Sensitive},
+			Status:      1,
+		})
+		if err != nil {
+			return fmt.Errorf("创建变量配置 %s 失败: %w", v.Name, err)
+		}
+		for _, env := range envs {
+			if err := UpdateConfigValue(l.ctx, env.ID, def.Code, &UpdateConfigValueReq{Value: model.VariableValue{Value: v.Value}}); err != nil {
+				return fmt.Errorf("写入变量 %s 失败: %w", v.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// plan 为每个分组生成工作流定义，已存在同名工作流时合并步骤并计算差异
+func (l *APIImportLogic) plan(projectID int64, c *importer.Collection, codes map[string]string) ([]*importPlanItem, error) {
+	w := query.Q.TWorkflow
+	items := make([]*importPlanItem, 0, len(c.Groups))
+
+	for _, g := range c.Groups {
+		steps := make([]types.Step, len(g.Steps))
+		for i, step := range g.Steps {
+			steps[i] = step
+			steps[i].Config = make(map[string]interface{}, len(step.Config))
+			for k, v := range step.Config {
+				steps[i].Config[k] = v
+			}
+			if key, ok := step.Config["domainCode"].(string); ok {
+				steps[i].Config["domainCode"] = codes[key]
+			}
+		}
+		def := &workflow.WorkflowDefinition{
+			Name:        g.Name,
+			Description: g.Description,
+			Variables:   g.Variables,
+			Steps:       steps,
+		}
+		item := &importPlanItem{
+			info: &APIImportWorkflow{Name: g.Name, Action: ImportActionCreate, StepCount: len(steps)},
+			def:  def,
+		}
+
+		existing, err := w.WithContext(l.ctx).
+			Where(w.ProjectID.Eq(projectID), w.Name.Eq(g.Name), w.IsDelete.Is(false)).
+			Order(w.ID.Desc()).First()
+		if err == nil {
+			oldDef, err := workflow.ParseJSON(existing.Definition)
+			if err != nil {
+				return nil, fmt.Errorf("工作流 %s 定义解析失败: %w", g.Name, err)
+			}
+			item.existing = existing
+			item.def = mergeImportedDefinition(c, oldDef, def)
+			item.info.WorkflowID = existing.ID
+			item.info.StepCount = len(item.def.Steps)
+			item.info.Diff = workflow.DiffDefinitions(oldDef, item.def)
+			item.info.Action = ImportActionUpdate
+			if item.info.Diff.Empty() {
+				item.info.Action = ImportActionUnchanged
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// mergeImportedDefinition 合并重新导入的定义：
+// 保留用户添加的步骤与已有变量值；导入生成的步骤按 ID 原位替换（保留用户调整过的域名），
+// 文档中已删除的接口移除，新增的接口追加到末尾
+func mergeImportedDefinition(c *importer.Collection, oldDef, newDef *workflow.WorkflowDefinition) *workflow.WorkflowDefinition {
+	incoming := make(map[string]types.Step, len(newDef.Steps))
+	for _, s := range newDef.Steps {
+		incoming[s.ID] = s
+	}
+
+	merged := *oldDef
+	merged.Steps = make([]types.Step, 0, len(newDef.Steps))
+	placed := make(map[string]bool)
+	for _, s := range oldDef.Steps {
+		if !c.IsGeneratedStep(s.ID) {
+			merged.Steps = append(merged.Steps, s)
+			continue
+		}
+		next, ok := incoming[s.ID]
+		if !ok {
+			continue
+		}
+		if code, ok := s.Config["domainCode"].(string); ok && code != "" {
+			next.Config["domainCode"] = code
+		}
+		next.Disabled = s.Disabled
+		merged.Steps = append(merged.Steps, next)
+		placed[s.ID] = true
+	}
+	for _, s := range newDef.Steps {
+		if !placed[s.ID] {
+			merged.Steps = append(merged.Steps, s)
+		}
+	}
+
+	merged.Variables = make(map[string]interface{}, len(newDef.Variables)+len(oldDef.Variables))
+	for k, v := range newDef.Variables {
+		merged.Variables[k] = v
+	}
+	for k, v := range oldDef.Variables {
+		merged.Variables[k] = v
+	}
+	if merged.Description == "" {
+		merged.Description = newDef.Description
+	}
+	return &merged
+}
+
+func buildImportResult(c *importer.Collection, domains []*APIImportDomain, variables []*APIImportVariable, items []*importPlanItem) *APIImportResult {
+	result := &APIImportResult{
+		Title:     c.Title,
+		Domains:   domains,
+		Variables: variables,
+		Workflows: make([]*APIImportWorkflow, 0, len(items)),
+		Warnings:  c.Warnings,
+	}
+	if result.Warnings == nil {
+		result.Warnings = []string{}
+	}
+	for _, item := range items {
+		result.Workflows = append(result.Workflows, item.info)
+	}
+	return result
+}
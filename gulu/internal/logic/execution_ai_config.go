@@ -0,0 +1,172 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"yqhp/gulu/internal/config"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// IsAINodeType 判断是否为 AI 节点类型
+func IsAINodeType(nodeType string) bool {
+	switch nodeType {
+	case "ai", "ai_chat", "ai_agent", "ai_react", "ai_plan", "ai_direct",
+		"ai_plan_execute", "ai_reflection", "ai_supervisor", "ai_deep_agent":
+		return true
+	}
+	return false
+}
+
+// walkAISteps 递归遍历步骤树中的 AI 节点（含循环体、子步骤、条件分支与已展开的引用工作流）
+func walkAISteps(steps []types.Step, fn func(step *types.Step)) {
+	for i := range steps {
+		step := &steps[i]
+		if IsAINodeType(step.Type) && step.Config != nil {
+			fn(step)
+		}
+		if step.Type == "ref_workflow" {
+			if def, ok := step.Config["workflow_definition"].(map[string]any); ok {
+				if refSteps, ok := def["steps"].([]types.Step); ok {
+					walkAISteps(refSteps, fn)
+				}
+			}
+		}
+		if step.Loop != nil {
+			walkAISteps(step.Loop.Steps, fn)
+		}
+		walkAISteps(step.Children, fn)
+		for bi := range step.Branches {
+			walkAISteps(step.Branches[bi].Steps, fn)
+		}
+	}
+}
+
+// serverManagedAIConfigKeys 只能由服务端注入的 AI 节点配置项，工作流定义中的同名字段一律丢弃
+var serverManagedAIConfigKeys = []string{"knowledge_bases", "pricing", "budget", "usage_ledger"}
+
+// stripServerManagedAIConfig 清除工作流定义中客户端写入的服务端托管配置
+func stripServerManagedAIConfig(steps []types.Step) {
+	walkAISteps(steps, func(step *types.Step) {
+		for _, key := range serverManagedAIConfigKeys {
+			delete(step.Config, key)
+		}
+	})
+}
+
+// ApplyAISandboxPolicy 为所有 AI 节点注入项目沙箱策略，未配置时使用默认策略。
+// 步骤中自带的 sandbox 一律覆盖；策略查询失败时返回错误，调用方应拒绝执行
+func ApplyAISandboxPolicy(ctx context.Context, projectID int64, steps []types.Step) error {
+	policy, err := NewAiSandboxLogic(ctx).GetPolicy(projectID)
+	if err != nil {
+		return fmt.Errorf("获取 AI 沙箱策略失败: %w", err)
+	}
+	policy = policy.WithDefaults()
+	walkAISteps(steps, func(step *types.Step) {
+		step.Config["sandbox"] = policy
+	})
+	return nil
+}
+
+// ApplyAICassetteProject 将 AI 节点录制文件限定在所属项目目录下，步骤中自带的 cassette.project 一律覆盖
+func ApplyAICassetteProject(projectID int64, steps []types.Step) {
+	walkAISteps(steps, func(step *types.Step) {
+		if cassette, ok := step.Config["cassette"].(map[string]interface{}); ok {
+			cassette["project"] = fmt.Sprintf("project-%d", projectID)
+		}
+	})
+}
+
+// ResolveAIModelConfig 按 ai_model_id 从数据库注入托管模型的 provider、model、api_key、base_url 与单价。
+// 单价只取自模型配置，步骤中自带的 pricing 一律覆盖；未配置 ai_model_id 时不处理
+func ResolveAIModelConfig(ctx context.Context, config map[string]interface{}) error {
+	aiModelID := configInt64(config, "ai_model_id")
+	if aiModelID <= 0 {
+		return nil
+	}
+	aiModel, err := NewAiModelLogic(ctx).GetByIDWithKey(aiModelID)
+	if err != nil {
+		return fmt.Errorf("AI 模型不存在或已删除 (ID=%d): %v", aiModelID, err)
+	}
+	return applyAIModelConfig(config, aiModel)
+}
+
+func applyAIModelConfig(config map[string]interface{}, aiModel *ModelWithCredentials) error {
+	if aiModel.Status != nil && *aiModel.Status != 1 {
+		return fmt.Errorf("AI 模型已禁用 (ID=%d, Name=%s)", aiModel.ID, aiModel.Name)
+	}
+	// 供应商类型决定接入协议（anthropic/gemini/ollama 走原生协议），未关联供应商时沿用模型上的供应商名
+	config["provider"] = aiModel.Provider
+	if aiModel.ProviderType != "" {
+		config["provider"] = aiModel.ProviderType
+	}
+	config["model"] = aiModel.ModelID
+	config["api_key"] = aiModel.APIKey
+	config["base_url"] = aiModel.APIBaseURL
+	delete(config, "pricing")
+	if pricing := ModelPricingConfig(aiModel); pricing != nil {
+		config["pricing"] = pricing
+	}
+	return nil
+}
+
+// ApplyAIModelConfigs 为所有 AI 节点（含引用工作流中的节点）注入托管模型配置，未使用托管模型的节点不计价。
+// 模型不存在或已禁用时返回错误，调用方应拒绝执行
+func ApplyAIModelConfigs(ctx context.Context, steps []types.Step) error {
+	models := make(map[int64]*ModelWithCredentials)
+	var firstErr error
+	walkAISteps(steps, func(step *types.Step) {
+		delete(step.Config, "pricing")
+		aiModelID := configInt64(step.Config, "ai_model_id")
+		if aiModelID <= 0 || firstErr != nil {
+			return
+		}
+		aiModel, ok := models[aiModelID]
+		if !ok {
+			var err error
+			if aiModel, err = NewAiModelLogic(ctx).GetByIDWithKey(aiModelID); err != nil {
+				firstErr = fmt.Errorf("AI 模型不存在或已删除 (ID=%d): %v", aiModelID, err)
+				return
+			}
+			models[aiModelID] = aiModel
+		}
+		if err := applyAIModelConfig(step.Config, aiModel); err != nil {
+			firstErr = err
+		}
+	})
+	return firstErr
+}
+
+// ApplyAIUsageLedger 为所有 AI 节点注入用量账本：引擎每次调用模型前在项目预算中预留费用，调用后结算并记录用量。
+// 步骤中自带的 budget、usage_ledger 一律覆盖；项目预算查询失败或已超出硬上限时返回错误，调用方应拒绝执行
+func ApplyAIUsageLedger(ctx context.Context, scope *AiUsageScope, steps []types.Step) error {
+	if _, err := NewAiUsageLogic(ctx).CheckBudget(scope.ProjectID); err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/api/internal/ai-usage/%d/charge", LocalGuluHost(), scope.ProjectID)
+	walkAISteps(steps, func(step *types.Step) {
+		delete(step.Config, "budget")
+		step.Config["usage_ledger"] = map[string]interface{}{
+			"url": url,
+			"attributes": map[string]interface{}{
+				"execution_id":    scope.ExecutionID,
+				"user_id":         scope.UserID,
+				"conversation_id": scope.ConversationID,
+				"ai_model_id":     configInt64(step.Config, "ai_model_id"),
+			},
+		}
+	})
+	return nil
+}
+
+// LocalGuluHost 供 workflow-engine 回调的本机 Gulu 地址
+func LocalGuluHost() string {
+	serverPort := 0
+	if cfg := config.GetConfig(); cfg != nil {
+		serverPort = cfg.Server.Port
+	}
+	if serverPort <= 0 {
+		serverPort = 5321
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", serverPort)
+}
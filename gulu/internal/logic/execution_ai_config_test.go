@@ -0,0 +1,105 @@
+package logic
+
+import (
+	"context"
+	"testing"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+func TestStripServerManagedAIConfig(t *testing.T) {
+	steps := []types.Step{
+		{ID: "a", Type: "ai_agent", Config: map[string]any{
+			"knowledge_base_ids": []any{float64(1)},
+			"knowledge_bases":    []any{map[string]any{"qdrant_collection": "other"}},
+			"budget":             map[string]any{"hard_limit": 0},
+			"usage_ledger":       map[string]any{"url": "http://attacker"},
+		}},
+		{ID: "http", Type: "http", Config: map[string]any{"knowledge_bases": "kept"}},
+		{ID: "loop", Type: "loop", Loop: &types.Loop{Steps: []types.Step{
+			{ID: "b", Type: "ai_chat", Config: map[string]any{"knowledge_bases": []any{}}},
+		}}},
+		{ID: "cond", Type: "condition", Branches: []types.ConditionBranch{
+			{Steps: []types.Step{{ID: "c", Type: "ai", Config: map[string]any{"knowledge_bases": []any{}}}}},
+		}},
+	}
+
+	stripServerManagedAIConfig(steps)
+
+	if _, ok := steps[0].Config["knowledge_bases"]; ok {
+		t.Error("顶层 AI 节点的 knowledge_bases 应被清除")
+	}
+	if _, ok := steps[0].Config["usage_ledger"]; ok {
+		t.Error("客户端写入的 usage_ledger 应被清除")
+	}
+	if _, ok := steps[0].Config["knowledge_base_ids"]; !ok {
+		t.Error("knowledge_base_ids 应保留")
+	}
+	if steps[1].Config["knowledge_bases"] != "kept" {
+		t.Error("非 AI 节点配置不应修改")
+	}
+	if _, ok := steps[2].Loop.Steps[0].Config["knowledge_bases"]; ok {
+		t.Error("循环体内 AI 节点的 knowledge_bases 应被清除")
+	}
+	if _, ok := steps[3].Branches[0].Steps[0].Config["knowledge_bases"]; ok {
+		t.Error("条件分支内 AI 节点的 knowledge_bases 应被清除")
+	}
+}
+
+func TestWalkAIStepsIncludesRefWorkflow(t *testing.T) {
+	steps := []types.Step{
+		{ID: "ref", Type: "ref_workflow", Config: map[string]any{
+			"workflow_definition": map[string]any{"steps": []types.Step{
+				{ID: "inner", Type: "ai_agent", Config: map[string]any{"sandbox": map[string]any{"disabled": true}}},
+			}},
+		}},
+	}
+
+	var visited []string
+	walkAISteps(steps, func(step *types.Step) {
+		visited = append(visited, step.ID)
+		step.Config["sandbox"] = (*types.SandboxPolicy)(nil).WithDefaults()
+	})
+
+	if len(visited) != 1 || visited[0] != "inner" {
+		t.Fatalf("visited = %v, want [inner]", visited)
+	}
+	inner := steps[0].Config["workflow_definition"].(map[string]any)["steps"].([]types.Step)[0]
+	if policy, ok := inner.Config["sandbox"].(*types.SandboxPolicy); !ok || policy.Disabled {
+		t.Fatalf("引用工作流内 AI 节点的沙箱策略应被覆盖: %#v", inner.Config["sandbox"])
+	}
+}
+
+func TestApplyAIModelConfigsDropsClientPricing(t *testing.T) {
+	steps := []types.Step{
+		{ID: "a", Type: "ai_agent", Config: map[string]any{
+			"model":   "gpt-4o",
+			"pricing": map[string]any{"input_price": 0.0, "output_price": 0.0},
+		}},
+	}
+
+	if err := ApplyAIModelConfigs(context.Background(), steps); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := steps[0].Config["pricing"]; ok {
+		t.Error("未使用托管模型的节点不应保留客户端 pricing")
+	}
+}
+
+func TestApplyAICassetteProject(t *testing.T) {
+	steps := []types.Step{
+		{ID: "a", Type: "ai_agent", Config: map[string]any{
+			"cassette": map[string]any{"mode": "replay", "project": "project-1"},
+		}},
+		{ID: "b", Type: "ai_agent", Config: map[string]any{}},
+	}
+
+	ApplyAICassetteProject(7, steps)
+
+	if got := steps[0].Config["cassette"].(map[string]any)["project"]; got != "project-7" {
+		t.Errorf("cassette.project = %v, want project-7", got)
+	}
+	if _, ok := steps[1].Config["cassette"]; ok {
+		t.Error("未配置录制回放的节点不应注入 cassette")
+	}
+}
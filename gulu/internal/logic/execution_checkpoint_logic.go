@@ -0,0 +1,270 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+	"yqhp/workflow-engine/pkg/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExecutionSnapshot 执行快照，恢复执行时据此重建工作流
+type ExecutionSnapshot struct {
+	Workflow      json.RawMessage `json:"workflow"`
+	SelectedSteps []string        `json:"selectedSteps,omitempty"`
+}
+
+// ExecutionCheckpointInfo 检查点详情
+type ExecutionCheckpointInfo struct {
+	StepIndex int                                `json:"step_index"`
+	StepID    string                             `json:"step_id"`
+	Status    string                             `json:"status"`
+	Variables map[string]interface{}             `json:"variables,omitempty"`
+	Results   map[string]*types.CheckpointResult `json:"results,omitempty"`
+	CreatedAt *time.Time                         `json:"created_at"`
+}
+
+// ResumeExecutionReq 从指定步骤恢复执行请求，StepID 与 StepIndex 都为空时从首个失败步骤恢复
+type ResumeExecutionReq struct {
+	StepID    string `json:"stepId"`
+	StepIndex *int   `json:"stepIndex"`
+}
+
+// ResumeExecutionPlan 恢复执行计划
+type ResumeExecutionPlan struct {
+	Execution *model.TExecution
+	Snapshot  *ExecutionSnapshot
+	Resume    *types.ResumePoint
+}
+
+// SaveExecutionSnapshot 保存执行快照
+func (l *ExecutionLogic) SaveExecutionSnapshot(executionID string, snapshot *ExecutionSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return svc.Ctx.DB.WithContext(l.ctx).Model(&model.TExecution{}).
+		Where("execution_id = ?", executionID).
+		Update("snapshot", string(data)).Error
+}
+
+// SaveCheckpoint 保存顶层步骤检查点，同一步骤重复执行时覆盖
+func (l *ExecutionLogic) SaveCheckpoint(executionID string, checkpoint *types.Checkpoint) error {
+	variables, err := json.Marshal(checkpoint.Variables)
+	if err != nil {
+		return fmt.Errorf("序列化变量失败: %w", err)
+	}
+	results, err := json.Marshal(checkpoint.Results)
+	if err != nil {
+		return fmt.Errorf("序列化步骤结果失败: %w", err)
+	}
+
+	varsStr, resultsStr := string(variables), string(results)
+	record := &model.TExecutionCheckpoint{
+		CreatedAt:   &checkpoint.CreatedAt,
+		ExecutionID: executionID,
+		StepIndex:   int32(checkpoint.StepIndex),
+		StepID:      checkpoint.StepID,
+		Status:      string(checkpoint.Status),
+		Variables:   &varsStr,
+		Results:     &resultsStr,
+	}
+	return svc.Ctx.DB.WithContext(l.ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "execution_id"}, {Name: "step_index"}},
+		DoUpdates: clause.AssignmentColumns([]string{"created_at", "step_id", "status", "variables", "results"}),
+	}).Create(record).Error
+}
+
+// checkpointRecorder 将引擎内执行的顶层步骤检查点写入执行记录，供工作流执行接口提交的执行恢复使用
+type checkpointRecorder struct {
+	types.NoopCallback
+	logic       *ExecutionLogic
+	executionID string
+}
+
+// OnCheckpoint 实现 types.CheckpointCallback
+func (r *checkpointRecorder) OnCheckpoint(ctx context.Context, checkpoint *types.Checkpoint) {
+	if err := r.logic.SaveCheckpoint(r.executionID, checkpoint); err != nil {
+		fmt.Printf("[Checkpoint] 保存检查点失败: executionId=%s, step=%s, error=%v\n", r.executionID, checkpoint.StepID, err)
+	}
+}
+
+// ListCheckpoints 获取执行的检查点列表（按步骤序号升序）
+func (l *ExecutionLogic) ListCheckpoints(executionID string) ([]*ExecutionCheckpointInfo, error) {
+	checkpoints, err := l.findCheckpoints(executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*ExecutionCheckpointInfo, 0, len(checkpoints))
+	for _, cp := range checkpoints {
+		info := &ExecutionCheckpointInfo{
+			StepIndex: int(cp.StepIndex),
+			StepID:    cp.StepID,
+			Status:    cp.Status,
+			CreatedAt: cp.CreatedAt,
+		}
+		if info.Variables, err = decodeCheckpointVariables(cp); err != nil {
+			return nil, err
+		}
+		if info.Results, err = decodeCheckpointResults(cp); err != nil {
+			return nil, err
+		}
+		list = append(list, info)
+	}
+	return list, nil
+}
+
+// PrepareResume 校验执行记录并用检查点构建恢复起点：
+// 恢复步骤之前的检查点保留在原执行记录中，恢复步骤及之后的检查点清除，执行记录重新置为运行中
+func (l *ExecutionLogic) PrepareResume(executionID string, req *ResumeExecutionReq) (*ResumeExecutionPlan, error) {
+	execution, err := l.GetByExecutionID(executionID)
+	if err != nil {
+		return nil, errors.New("执行记录不存在")
+	}
+
+	switch execution.Status {
+	case ExecutionStatusFailed, ExecutionStatusStopped, "timeout":
+	default:
+		return nil, fmt.Errorf("仅失败或已停止的执行可以恢复，当前状态: %s", execution.Status)
+	}
+
+	if execution.Snapshot == nil || *execution.Snapshot == "" {
+		return nil, errors.New("执行记录缺少执行快照，无法恢复")
+	}
+	var snapshot ExecutionSnapshot
+	if err := json.Unmarshal([]byte(*execution.Snapshot), &snapshot); err != nil {
+		return nil, fmt.Errorf("执行快照解析失败: %w", err)
+	}
+
+	checkpoints, err := l.findCheckpoints(executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := resumeCheckpointIndex(checkpoints, req)
+	if err != nil {
+		return nil, err
+	}
+	if index == 0 {
+		return nil, errors.New("从第一个步骤恢复等同于重新执行，请直接重新执行")
+	}
+
+	// 合并恢复步骤之前的检查点：变量取最近一次快照，结果逐步累积
+	resume := &types.ResumePoint{
+		StepID:  checkpoints[index].StepID,
+		Results: make(map[string]*types.CheckpointResult),
+	}
+	for _, cp := range checkpoints[:index] {
+		results, err := decodeCheckpointResults(cp)
+		if err != nil {
+			return nil, err
+		}
+		for stepID, r := range results {
+			resume.Results[stepID] = r
+		}
+	}
+	if resume.Variables, err = decodeCheckpointVariables(checkpoints[index-1]); err != nil {
+		return nil, err
+	}
+
+	// 以读取时的状态为条件置为运行中，并发恢复同一执行时只有一个请求能成功
+	err = svc.Ctx.DB.WithContext(l.ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.TExecution{}).
+			Where("id = ? AND status = ?", execution.ID, execution.Status).
+			Updates(map[string]interface{}{
+				"status":     ExecutionStatusRunning,
+				"end_time":   nil,
+				"updated_at": time.Now(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("执行状态已变化，可能已被恢复，请刷新后重试")
+		}
+		return tx.Where("execution_id = ? AND step_index >= ?", executionID, index).
+			Delete(&model.TExecutionCheckpoint{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	execution.Status = ExecutionStatusRunning
+	execution.EndTime = nil
+
+	return &ResumeExecutionPlan{
+		Execution: execution,
+		Snapshot:  &snapshot,
+		Resume:    resume,
+	}, nil
+}
+
+func (l *ExecutionLogic) findCheckpoints(executionID string) ([]*model.TExecutionCheckpoint, error) {
+	var checkpoints []*model.TExecutionCheckpoint
+	err := svc.Ctx.DB.WithContext(l.ctx).
+		Where("execution_id = ?", executionID).
+		Order("step_index ASC").
+		Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+// resumeCheckpointIndex 确定恢复起点在检查点中的位置，要求起点之前的检查点连续
+func resumeCheckpointIndex(checkpoints []*model.TExecutionCheckpoint, req *ResumeExecutionReq) (int, error) {
+	target := -1
+	for i, cp := range checkpoints {
+		if int(cp.StepIndex) != i {
+			break
+		}
+		switch {
+		case req.StepIndex != nil:
+			if i == *req.StepIndex {
+				target = i
+			}
+		case req.StepID != "":
+			if cp.StepID == req.StepID {
+				target = i
+			}
+		default:
+			if cp.Status == string(types.ResultStatusFailed) || cp.Status == string(types.ResultStatusTimeout) {
+				target = i
+			}
+		}
+		if target >= 0 {
+			return target, nil
+		}
+	}
+
+	if req.StepIndex == nil && req.StepID == "" {
+		return 0, errors.New("执行中没有失败的步骤")
+	}
+	return 0, errors.New("恢复起点步骤未执行过，只能从已执行的顶层步骤恢复")
+}
+
+func decodeCheckpointVariables(cp *model.TExecutionCheckpoint) (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+	if cp.Variables == nil || *cp.Variables == "" {
+		return vars, nil
+	}
+	if err := json.Unmarshal([]byte(*cp.Variables), &vars); err != nil {
+		return nil, fmt.Errorf("检查点变量解析失败 (step=%s): %w", cp.StepID, err)
+	}
+	return vars, nil
+}
+
+func decodeCheckpointResults(cp *model.TExecutionCheckpoint) (map[string]*types.CheckpointResult, error) {
+	results := make(map[string]*types.CheckpointResult)
+	if cp.Results == nil || *cp.Results == "" {
+		return results, nil
+	}
+	if err := json.Unmarshal([]byte(*cp.Results), &results); err != nil {
+		return nil, fmt.Errorf("检查点结果解析失败 (step=%s): %w", cp.StepID, err)
+	}
+	return results, nil
+}
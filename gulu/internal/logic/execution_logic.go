@@ -31,11 +31,16 @@ func NewExecutionLogic(ctx context.Context) *ExecutionLogic {
 
 // ExecuteWorkflowReq 执行工作流请求
 type ExecuteWorkflowReq struct {
-	WorkflowID        int64              `json:"workflow_id" validate:"required"` // 工作流ID，写入时映射为 source_id
-	EnvID             int64              `json:"env_id" validate:"required"`
-	ExecutorID        int64              `json:"executor_id"`        // 可选，指定执行机ID
-	Mode              string             `json:"mode"`               // 执行模式: debug, execute（默认 execute）
-	PerformanceConfig *PerformanceConfig `json:"performance_config"` // 可选，压测配置（覆盖工作流定义中的配置）
+	WorkflowID        int64                  `json:"workflow_id" validate:"required"` // 工作流ID，写入时映射为 source_id
+	EnvID             int64                  `json:"env_id" validate:"required"`
+	ExecutorID        int64                  `json:"executor_id"`        // 可选，指定执行机ID
+	Mode              string                 `json:"mode"`               // 执行模式: debug, execute（默认 execute）
+	PerformanceConfig *PerformanceConfig     `json:"performance_config"` // 可选，压测配置（覆盖工作流定义中的配置）
+	ExecutorStrategy  *ExecutorStrategy      `json:"executor_strategy"`  // 可选，执行机策略（未指定 executor_id 时生效）
+	Variables         map[string]interface{} `json:"variables"`          // 可选，执行参数（覆盖工作流与环境变量）
+
+	TriggerType string `json:"-"` // 触发方式，默认 manual
+	ScheduleID  int64  `json:"-"` // 定时计划触发时的计划ID
 }
 
 // PerformanceConfig 压测配置
@@ -66,13 +71,16 @@ type PerfThreshold struct {
 
 // ExecutionListReq 执行记录列表请求
 type ExecutionListReq struct {
-	Page       int    `query:"page" validate:"min=1"`
-	PageSize   int    `query:"pageSize" validate:"min=1,max=100"`
-	ProjectID  int64  `query:"projectId"`
-	SourceID   int64  `query:"sourceId"`
-	EnvID      int64  `query:"envId"`
-	Status     string `query:"status"`
-	Mode       string `query:"mode"` // 执行模式过滤: debug, execute
+	Page        int    `query:"page" validate:"min=1"`
+	PageSize    int    `query:"pageSize" validate:"min=1,max=100"`
+	ProjectID   int64  `query:"projectId"`
+	SourceID    int64  `query:"sourceId"`
+	EnvID       int64  `query:"envId"`
+	Status      string `query:"status"`
+	Mode        string `query:"mode"`        // 执行模式过滤: debug, execute
+	SourceType  string `query:"sourceType"`  // 来源类型过滤: performance, test_plan, debug
+	TriggerType string `query:"triggerType"` // 触发方式过滤: manual, schedule
+	ScheduleID  int64  `query:"scheduleId"`
 }
 
 // ExecutionStatus 执行状态常量
@@ -158,6 +166,25 @@ func (l *ExecutionLogic) Execute(req *ExecuteWorkflowReq, userID int64) (*model.
 		workflow.ResolveEnvConfigReferences(def.Steps, mergedConfig)
 	}
 
+	// 知识库等配置只能由服务端解析注入，不信任工作流定义中保存的值
+	stripServerManagedAIConfig(def.Steps)
+
+	// 执行参数覆盖变量
+	for k, v := range req.Variables {
+		def.Variables[k] = v
+	}
+
+	// 按执行机策略选择执行机
+	if req.ExecutorID <= 0 && req.ExecutorStrategy != nil {
+		selected, err := NewExecutorLogic(l.ctx).SelectByStrategy(req.ExecutorStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("执行机选择失败: %v", err)
+		}
+		if selected != nil {
+			req.ExecutorID = selected.ID
+		}
+	}
+
 	// 创建执行记录
 	now := time.Now()
 	executionID := generateExecutionID()
@@ -175,20 +202,32 @@ func (l *ExecutionLogic) Execute(req *ExecuteWorkflowReq, userID int64) (*model.
 		sourceType = string(model.SourceTypeDebug)
 	}
 
+	triggerType := req.TriggerType
+	if triggerType == "" {
+		triggerType = string(model.TriggerTypeManual)
+	}
+	var scheduleID *int64
+	if req.ScheduleID > 0 {
+		scheduleID = &req.ScheduleID
+	}
+
 	execution := &model.TExecution{
-		CreatedAt:   &now,
-		UpdatedAt:   &now,
-		ProjectID:   wf.ProjectID,
-		SourceID:    req.WorkflowID,
-		EnvID:       req.EnvID,
-		ExecutorID:  executorIDStr,
-		ExecutionID: executionID,
-		Mode:        mode,
-		SourceType:  sourceType,
-		Title:       wf.Name,
-		Status:      ExecutionStatusPending,
-		StartTime:   &now,
-		CreatedBy:   &userID,
+		CreatedAt:       &now,
+		UpdatedAt:       &now,
+		ProjectID:       wf.ProjectID,
+		SourceID:        req.WorkflowID,
+		EnvID:           req.EnvID,
+		ExecutorID:      executorIDStr,
+		ExecutionID:     executionID,
+		Mode:            mode,
+		SourceType:      sourceType,
+		Title:           wf.Name,
+		Status:          ExecutionStatusPending,
+		StartTime:       &now,
+		CreatedBy:       &userID,
+		TriggerType:     triggerType,
+		ScheduleID:      scheduleID,
+		WorkflowVersion: wf.Version,
 	}
 
 	q := query.Use(svc.Ctx.DB)
@@ -203,12 +242,34 @@ func (l *ExecutionLogic) Execute(req *ExecuteWorkflowReq, userID int64) (*model.
 		return nil, fmt.Errorf("解析引用工作流失败: %w", err)
 	}
 
+	// AI 节点的沙箱策略、模型单价与用量账本只能由服务端注入，查询失败或项目预算已超出硬上限时不执行
+	usageScope := &AiUsageScope{ProjectID: wf.ProjectID, UserID: userID, ExecutionID: executionID}
+	if err := l.applyAIServerConfig(usageScope, def.Steps); err != nil {
+		_, _ = q.TExecution.WithContext(l.ctx).Where(q.TExecution.ID.Eq(execution.ID)).Updates(map[string]interface{}{
+			"status":     ExecutionStatusFailed,
+			"updated_at": time.Now(),
+		})
+		return nil, err
+	}
+
 	// 提交工作流到 workflow-engine 执行
 	engine := workflow.GetEngine()
 	if engine != nil {
 		// 转换为 workflow-engine 的工作流类型
 		weWorkflow := workflow.ConvertToEngineWorkflow(def, executionID)
 
+		// 非压测流程保存执行快照并记录顶层步骤检查点，失败后可从失败步骤恢复
+		if workflowType != string(model.WorkflowTypePerformance) {
+			snapshot := &ExecutionSnapshot{Workflow: json.RawMessage(wf.Definition)}
+			if err := l.SaveExecutionSnapshot(executionID, snapshot); err != nil {
+				fmt.Printf("[Execute] 保存执行快照失败: executionId=%s, error=%v\n", executionID, err)
+			}
+			weWorkflow.Callback = &checkpointRecorder{
+				logic:       NewExecutionLogic(context.Background()),
+				executionID: executionID,
+			}
+		}
+
 		// 应用压测配置
 		perfConfig := req.PerformanceConfig
 		if perfConfig == nil {
@@ -273,6 +334,18 @@ func (l *ExecutionLogic) Execute(req *ExecuteWorkflowReq, userID int64) (*model.
 	return execution, nil
 }
 
+// applyAIServerConfig 注入 AI 节点的沙箱策略、录制文件项目、托管模型配置与用量账本
+func (l *ExecutionLogic) applyAIServerConfig(scope *AiUsageScope, steps []types.Step) error {
+	if err := ApplyAISandboxPolicy(l.ctx, scope.ProjectID, steps); err != nil {
+		return err
+	}
+	ApplyAICassetteProject(scope.ProjectID, steps)
+	if err := ApplyAIModelConfigs(l.ctx, steps); err != nil {
+		return err
+	}
+	return ApplyAIUsageLedger(l.ctx, scope, steps)
+}
+
 // monitorExecution monitors execution status and stores the final report.
 func (l *ExecutionLogic) monitorExecution(dbID int64, guluExecID string, executionID string, engine *workflow.Engine) {
 	ticker := time.NewTicker(1 * time.Second)
@@ -492,6 +565,15 @@ func (l *ExecutionLogic) List(req *ExecutionListReq) ([]*model.TExecution, int64
 	if req.Mode != "" {
 		queryBuilder = queryBuilder.Where(e.Mode.Eq(req.Mode))
 	}
+	if req.SourceType != "" {
+		queryBuilder = queryBuilder.Where(e.SourceType.Eq(req.SourceType))
+	}
+	if req.TriggerType != "" {
+		queryBuilder = queryBuilder.Where(e.TriggerType.Eq(req.TriggerType))
+	}
+	if req.ScheduleID > 0 {
+		queryBuilder = queryBuilder.Where(e.ScheduleID.Eq(req.ScheduleID))
+	}
 
 	// 获取总数
 	total, err := queryBuilder.Count()
@@ -631,6 +713,33 @@ func (l *ExecutionLogic) Stop(id int64) error {
 	return engine.StopExecution(context.Background(), engineExecID)
 }
 
+// abort 调用方放弃等待时回收执行：不校验执行记录状态直接停止引擎中的执行，
+// 执行记录仍未终结时置为失败（monitorExecution 超时后记录已是失败而引擎仍在运行）
+func (l *ExecutionLogic) abort(id int64) error {
+	execution, err := l.GetByID(id)
+	if err != nil {
+		return errors.New("执行记录不存在")
+	}
+
+	var stopErr error
+	if engine := workflow.GetEngine(); engine != nil {
+		stopErr = engine.StopExecution(context.Background(), l.resolveEngineID(execution.ExecutionID))
+	}
+
+	now := time.Now()
+	err = svc.Ctx.DB.WithContext(l.ctx).Model(&model.TExecution{}).
+		Where("id = ? AND status IN ?", id, []string{ExecutionStatusPending, ExecutionStatusRunning, ExecutionStatusPaused}).
+		Updates(map[string]interface{}{
+			"status":     ExecutionStatusFailed,
+			"end_time":   now,
+			"updated_at": now,
+		}).Error
+	if err != nil {
+		return err
+	}
+	return stopErr
+}
+
 // Pause pauses a running execution via the engine API.
 func (l *ExecutionLogic) Pause(id int64) error {
 	execution, err := l.GetByID(id)
@@ -770,6 +879,7 @@ func (l *ExecutionLogic) CreateStreamExecution(sessionID string, projectID, sour
 		Status:      string(model.ExecutionStatusRunning),
 		StartTime:   &now,
 		CreatedBy:   &userID,
+		TriggerType: string(model.TriggerTypeManual),
 	}
 
 	return query.TExecution.WithContext(l.ctx).Create(execution)
@@ -872,7 +982,17 @@ func (l *dbWorkflowLoader) LoadDefinition(id int64) (string, string, error) {
 	if err != nil {
 		return "", "", err
 	}
-	return wf.Name, wf.Definition, nil
+	// 被引用工作流中保存的服务端托管配置同样不可信，解析失败时原样返回由解析器报错
+	def, err := workflow.ParseJSON(wf.Definition)
+	if err != nil {
+		return wf.Name, wf.Definition, nil
+	}
+	stripServerManagedAIConfig(def.Steps)
+	definition, err := workflow.ToJSON(def)
+	if err != nil {
+		return "", "", err
+	}
+	return wf.Name, definition, nil
 }
 
 // ============== 工作流转换函数 ==============
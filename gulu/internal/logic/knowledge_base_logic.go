@@ -7,10 +7,14 @@ import (
 	"log"
 	"path/filepath"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"time"
 	"unicode/utf8"
 
+	"gorm.io/gorm"
+
+	"yqhp/gulu/internal/ctxutil"
 	"yqhp/gulu/internal/model"
 	"yqhp/gulu/internal/svc"
 )
@@ -48,6 +52,7 @@ type CreateKnowledgeBaseReq struct {
 	MultimodalEnabled   bool   `json:"multimodal_enabled"`
 	MultimodalModelID   *int64 `json:"multimodal_model_id"`
 	GraphExtractModelID *int64 `json:"graph_extract_model_id"`
+	VectorStore         string `json:"vector_store"` // 向量库后端: qdrant / embedded / pgvector，默认取配置 vector_store.default
 	// 分块 + 检索参数（可选，未填则使用默认值）
 	ChunkSize           int32   `json:"chunk_size"`
 	ChunkOverlap        int32   `json:"chunk_overlap"`
@@ -64,13 +69,16 @@ type UpdateKnowledgeBaseReq struct {
 	MultimodalModelID   *int64 `json:"multimodal_model_id"`
 	GraphExtractModelID *int64 `json:"graph_extract_model_id"`
 	// 分块 + 检索参数
-	ChunkSize           int32   `json:"chunk_size"`
-	ChunkOverlap        int32   `json:"chunk_overlap"`
-	SimilarityThreshold float64 `json:"similarity_threshold"`
-	TopK                int32   `json:"top_k"`
-	RetrievalMode       string  `json:"retrieval_mode"`
-	RerankModelID       *int64  `json:"rerank_model_id"`
-	RerankEnabled       *bool   `json:"rerank_enabled"`
+	ChunkSize           int32    `json:"chunk_size"`
+	ChunkOverlap        int32    `json:"chunk_overlap"`
+	SimilarityThreshold float64  `json:"similarity_threshold"`
+	TopK                int32    `json:"top_k"`
+	RetrievalMode       string   `json:"retrieval_mode"`
+	RerankModelID       *int64   `json:"rerank_model_id"`
+	RerankEnabled       *bool    `json:"rerank_enabled"`
+	FusionMethod        string   `json:"fusion_method"` // 混合检索融合方式: rrf / weighted
+	RRFK                int      `json:"rrf_k"`
+	VectorWeight        *float64 `json:"vector_weight"`
 }
 
 type KnowledgeBaseListReq struct {
@@ -95,6 +103,7 @@ type KnowledgeBaseInfo struct {
 	MultimodalEnabled   bool       `json:"multimodal_enabled"`
 	MultimodalModelID   *int64     `json:"multimodal_model_id"`
 	GraphExtractModelID *int64     `json:"graph_extract_model_id"`
+	VectorStore         string     `json:"vector_store"`
 	QdrantCollection    string     `json:"qdrant_collection"`
 	DocumentCount       int32      `json:"document_count"`
 	ChunkCount          int32      `json:"chunk_count"`
@@ -106,6 +115,9 @@ type KnowledgeBaseInfo struct {
 	RetrievalMode       string  `json:"retrieval_mode"`
 	RerankEnabled       bool    `json:"rerank_enabled"`
 	RerankModelID       *int64  `json:"rerank_model_id"`
+	FusionMethod        string  `json:"fusion_method"`
+	RRFK                int     `json:"rrf_k"`
+	VectorWeight        float64 `json:"vector_weight"`
 	EmbeddingDimension  int     `json:"embedding_dimension,omitempty"`
 	MultimodalDimension int     `json:"multimodal_dimension,omitempty"`
 }
@@ -116,21 +128,28 @@ type KnowledgeDocumentListReq struct {
 }
 
 type KnowledgeDocumentInfo struct {
-	ID                  int64      `json:"id"`
-	CreatedAt           *time.Time `json:"created_at"`
-	UpdatedAt           *time.Time `json:"updated_at"`
-	KnowledgeBaseID     int64      `json:"knowledge_base_id"`
-	Name                string     `json:"name"`
-	FileType            string     `json:"file_type"`
-	FileSize            int64      `json:"file_size"`
-	WordCount           int32      `json:"word_count"`
-	ImageCount          int32      `json:"image_count"`
-	IndexingStatus      string     `json:"indexing_status"`
-	ErrorMessage        string     `json:"error_message"`
-	ChunkCount          int32      `json:"chunk_count"`
-	TokenCount          int32      `json:"token_count"`
-	ParsingCompletedAt  *time.Time `json:"parsing_completed_at"`
-	IndexingCompletedAt *time.Time `json:"indexing_completed_at"`
+	ID                  int64           `json:"id"`
+	CreatedAt           *time.Time      `json:"created_at"`
+	UpdatedAt           *time.Time      `json:"updated_at"`
+	KnowledgeBaseID     int64           `json:"knowledge_base_id"`
+	Name                string          `json:"name"`
+	FileType            string          `json:"file_type"`
+	FileSize            int64           `json:"file_size"`
+	WordCount           int32           `json:"word_count"`
+	ImageCount          int32           `json:"image_count"`
+	IndexingStatus      string          `json:"indexing_status"`
+	ErrorMessage        string          `json:"error_message"`
+	ChunkCount          int32           `json:"chunk_count"`
+	TokenCount          int32           `json:"token_count"`
+	ParsingCompletedAt  *time.Time      `json:"parsing_completed_at"`
+	IndexingCompletedAt *time.Time      `json:"indexing_completed_at"`
+	Progress            *IngestProgress `json:"progress,omitempty"`   // 最近一次入库任务的进度
+	SourceID            *int64          `json:"source_id,omitempty"`  // 数据源同步的文档所属数据源
+	SourceKey           string          `json:"source_key,omitempty"` // 数据源内的文件路径 / URL
+	// Metadata 用户设置的元数据；AutoMetadata 从文件路径与 front-matter 自动提取，同名键以 Metadata 为准
+	Metadata     model.DocumentMetadata `json:"metadata,omitempty"`
+	AutoMetadata model.DocumentMetadata `json:"auto_metadata,omitempty"`
+	ACL          *model.DocumentACL     `json:"acl,omitempty"` // 为空表示项目成员均可检索
 }
 
 type KnowledgeSearchReq struct {
@@ -140,6 +159,13 @@ type KnowledgeSearchReq struct {
 	Score         float64 `json:"score"`
 	RetrievalMode string  `json:"retrieval_mode"`
 	SearchFields  string  `json:"search_fields"` // text / image / all (default: all)
+	// 以下参数为空时使用知识库配置
+	FusionMethod string   `json:"fusion_method"` // rrf / weighted
+	RRFK         int      `json:"rrf_k"`
+	VectorWeight *float64 `json:"vector_weight"`
+	Rerank       *bool    `json:"rerank"` // 是否启用 Rerank 重排序
+	// Filter 元数据过滤表达式，只检索满足条件的文档
+	Filter *MetadataFilter `json:"filter"`
 }
 
 type KnowledgeSearchResult struct {
@@ -154,6 +180,7 @@ type KnowledgeSearchResult struct {
 	WordCount    int                    `json:"word_count"`
 	HitCount     int                    `json:"hit_count"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	ScoreDetail  *ScoreExplanation      `json:"score_detail,omitempty"`
 }
 
 type SegmentInfo struct {
@@ -180,9 +207,12 @@ type PreviewChunksReq struct {
 }
 
 type PreviewChunkItem struct {
-	Index     int    `json:"index"`
-	Content   string `json:"content"`
-	CharCount int    `json:"char_count"`
+	Index         int    `json:"index"`
+	Content       string `json:"content"`
+	CharCount     int    `json:"char_count"`
+	Heading       string `json:"heading,omitempty"`        // 结构化策略下的标题路径 / 代码符号
+	ParentIndex   *int   `json:"parent_index,omitempty"`   // parent_child 策略下所属父块
+	ParentContent string `json:"parent_content,omitempty"` // parent_child 策略下检索命中后返回的父块
 }
 
 type ProcessDocumentReq struct {
@@ -204,6 +234,9 @@ type CreateAndProcessReq struct {
 	FileType     string              `json:"file_type"`
 	FileSize     int64               `json:"file_size"`
 	ChunkSetting *model.ChunkSetting `json:"chunk_setting"`
+	// 可选：文档元数据与访问控制
+	Metadata map[string]interface{} `json:"metadata"`
+	ACL      *model.DocumentACL     `json:"acl"`
 }
 
 type BatchDocIDsReq struct {
@@ -252,6 +285,13 @@ func (l *KnowledgeBaseLogic) Create(req *CreateKnowledgeBaseReq) (*KnowledgeBase
 	if kbType == "" {
 		kbType = "normal"
 	}
+	vectorStore, err := NormalizeVectorStore(req.VectorStore)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := GetVectorStore(vectorStore); err != nil {
+		return nil, err
+	}
 	isDelete := false
 	status := int32(1)
 	kb := &model.TKnowledgeBase{
@@ -264,6 +304,7 @@ func (l *KnowledgeBaseLogic) Create(req *CreateKnowledgeBaseReq) (*KnowledgeBase
 		MultimodalEnabled:   req.MultimodalEnabled,
 		MultimodalModelID:   req.MultimodalModelID,
 		GraphExtractModelID: req.GraphExtractModelID,
+		VectorStore:         vectorStore,
 	}
 	kb.SetConfig(cfg)
 
@@ -271,7 +312,7 @@ func (l *KnowledgeBaseLogic) Create(req *CreateKnowledgeBaseReq) (*KnowledgeBase
 		return nil, err
 	}
 
-	// 设置 Qdrant Collection 名称（创建后才有 ID）
+	// 设置向量集合名称（创建后才有 ID）
 	collectionName := fmt.Sprintf("kb_%d", kb.ID)
 	updates := map[string]interface{}{"qdrant_collection": collectionName}
 	if kbType == "graph" {
@@ -318,6 +359,21 @@ func (l *KnowledgeBaseLogic) Update(id int64, req *UpdateKnowledgeBaseReq) error
 	if req.RerankEnabled != nil {
 		cfg.RerankEnabled = *req.RerankEnabled
 	}
+	if req.FusionMethod != "" {
+		cfg.FusionMethod = req.FusionMethod
+	}
+	if req.RRFK > 0 {
+		cfg.RRFK = req.RRFK
+	}
+	if req.VectorWeight != nil {
+		cfg.VectorWeight = *req.VectorWeight
+	}
+	if err := validateFusionOptions(cfg.FusionMethod, cfg.VectorWeight); err != nil {
+		return err
+	}
+	if cfg.RerankEnabled && (cfg.RerankModelID == nil || *cfg.RerankModelID == 0) {
+		return errors.New("启用 Rerank 需要配置 Rerank 模型")
+	}
 	kb.SetConfig(cfg)
 
 	updates := map[string]interface{}{
@@ -353,11 +409,18 @@ func (l *KnowledgeBaseLogic) Delete(id int64) error {
 	}
 
 	db.Model(&model.TKnowledgeBase{}).Where("id = ?", id).Update("is_delete", true)
+	invalidateKeywordIndex(id)
+	deleteKnowledgeSources(id)
+	var docIDs []int64
+	db.Model(&model.TKnowledgeDocument{}).Where("knowledge_base_id = ?", id).Pluck("id", &docIDs)
+	if len(docIDs) > 0 {
+		cancelIngestJobs(db, docIDs)
+	}
 
 	safeGo(func() {
-		if kb.QdrantCollection != nil && *kb.QdrantCollection != "" {
-			if err := DeleteQdrantCollection(*kb.QdrantCollection); err != nil {
-				log.Printf("[WARN] 删除 Qdrant Collection 失败: %v", err)
+		if store, collection, err := vectorStoreOf(&kb); err == nil {
+			if err := store.DeleteCollection(context.Background(), collection); err != nil {
+				log.Printf("[WARN] 删除向量集合失败: %v", err)
 			}
 		}
 		// 清理图谱数据
@@ -369,6 +432,12 @@ func (l *KnowledgeBaseLogic) Delete(id int64) error {
 		db.Where("knowledge_base_id = ?", id).Delete(&model.TKnowledgeSegment{})
 		db.Where("knowledge_base_id = ?", id).Delete(&model.TKnowledgeDocument{})
 		db.Where("knowledge_base_id = ?", id).Delete(&model.TKnowledgeQuery{})
+		db.Where("knowledge_base_id = ?", id).Delete(&model.TKnowledgeIngestJob{})
+		db.Where("knowledge_base_id = ?", id).Delete(&model.TKnowledgeSource{})
+		db.Where("run_id IN (?)", db.Model(&model.TKnowledgeEvalRun{}).Select("id").Where("knowledge_base_id = ?", id)).Delete(&model.TKnowledgeEvalResult{})
+		db.Where("knowledge_base_id = ?", id).Delete(&model.TKnowledgeEvalRun{})
+		db.Where("knowledge_base_id = ?", id).Delete(&model.TKnowledgeEvalItem{})
+		db.Where("knowledge_base_id = ?", id).Delete(&model.TKnowledgeEvalDataset{})
 		GetFileStorage().DeleteDir(id)
 	})
 
@@ -497,6 +566,7 @@ func (l *KnowledgeBaseLogic) ListDocuments(kbID int64, req *KnowledgeDocumentLis
 	for i := range list {
 		result = append(result, l.toDocumentInfo(&list[i]))
 	}
+	attachIngestProgress(result)
 	return result, nil
 }
 
@@ -507,18 +577,22 @@ func (l *KnowledgeBaseLogic) DeleteDocument(kbID, docID int64) error {
 	if err := db.Where("id = ? AND is_delete = 0", kbID).First(&kb).Error; err != nil {
 		return errors.New("知识库不存在")
 	}
+	if err := ensureNotVectorMigrating(&kb); err != nil {
+		return err
+	}
 
 	var doc model.TKnowledgeDocument
 	if err := db.Where("id = ? AND knowledge_base_id = ?", docID, kbID).First(&doc).Error; err != nil {
 		return errors.New("文档不存在")
 	}
 
+	cancelIngestJobs(db, []int64{docID})
 	db.Where("id = ?", docID).Delete(&model.TKnowledgeDocument{})
 	db.Where("document_id = ?", docID).Delete(&model.TKnowledgeSegment{})
 
 	safeGo(func() {
-		if kb.QdrantCollection != nil && *kb.QdrantCollection != "" {
-			if err := DeleteDocumentVectors(*kb.QdrantCollection, docID); err != nil {
+		if store, collection, err := vectorStoreOf(&kb); err == nil {
+			if err := store.DeleteDocument(context.Background(), collection, docID); err != nil {
 				log.Printf("[WARN] 清理文档向量失败: docID=%d, err=%v", docID, err)
 			}
 		}
@@ -542,17 +616,22 @@ func (l *KnowledgeBaseLogic) BatchDeleteDocuments(kbID int64, docIDs []int64) er
 	if err := db.Where("id = ? AND is_delete = 0", kbID).First(&kb).Error; err != nil {
 		return errors.New("知识库不存在")
 	}
+	if err := ensureNotVectorMigrating(&kb); err != nil {
+		return err
+	}
 
 	var docs []model.TKnowledgeDocument
 	db.Where("id IN ? AND knowledge_base_id = ?", docIDs, kbID).Find(&docs)
 
+	cancelIngestJobs(db, docIDs)
 	db.Where("id IN ? AND knowledge_base_id = ?", docIDs, kbID).Delete(&model.TKnowledgeDocument{})
 	db.Where("document_id IN ? AND knowledge_base_id = ?", docIDs, kbID).Delete(&model.TKnowledgeSegment{})
 
 	safeGo(func() {
+		store, collection, storeErr := vectorStoreOf(&kb)
 		for _, doc := range docs {
-			if kb.QdrantCollection != nil && *kb.QdrantCollection != "" {
-				DeleteDocumentVectors(*kb.QdrantCollection, doc.ID)
+			if storeErr == nil {
+				store.DeleteDocument(context.Background(), collection, doc.ID)
 			}
 			if kb.Type == "graph" && IsNeo4jEnabled() {
 				if err := DeleteDocumentGraph(context.Background(), kbID, doc.ID); err != nil {
@@ -581,18 +660,13 @@ func (l *KnowledgeBaseLogic) ReprocessDocument(kbID, docID int64) error {
 		return errors.New("文档不存在")
 	}
 
-	db.Model(&model.TKnowledgeDocument{}).Where("id = ?", docID).Updates(map[string]interface{}{
-		"indexing_status": "waiting",
-		"error_message":   nil,
-	})
-
-	safeGo(func() {
-		processor := NewDocumentProcessor()
-		if err := processor.Process(&kb, &doc); err != nil {
-			log.Printf("[ERROR] 文档重处理失败: docID=%d, err=%v", doc.ID, err)
-		}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return enqueueIngestJob(tx, kbID, docID)
 	})
-
+	if err != nil {
+		return err
+	}
+	wakeIngestWorkers()
 	return nil
 }
 
@@ -607,23 +681,45 @@ func (l *KnowledgeBaseLogic) BatchReprocessDocuments(kbID int64, docIDs []int64)
 	var docs []model.TKnowledgeDocument
 	db.Where("id IN ? AND knowledge_base_id = ?", docIDs, kbID).Find(&docs)
 
+	// 正在处理中的文档跳过，其余文档重新入队
 	for _, doc := range docs {
-		db.Model(&model.TKnowledgeDocument{}).Where("id = ?", doc.ID).Updates(map[string]interface{}{
-			"indexing_status": "waiting",
-			"error_message":   nil,
+		docID := doc.ID
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return enqueueIngestJob(tx, kbID, docID)
 		})
-	}
-
-	safeGo(func() {
-		processor := NewDocumentProcessor()
-		for _, doc := range docs {
-			d := doc
-			if err := processor.Process(&kb, &d); err != nil {
-				log.Printf("[ERROR] 批量重处理失败: docID=%d, err=%v", d.ID, err)
+		if err != nil {
+			if errors.Is(err, errIngestRunning) {
+				continue
 			}
+			return err
 		}
-	})
+	}
+	wakeIngestWorkers()
+	return nil
+}
 
+// CancelDocumentProcessing 取消文档处理：等待中的任务立即取消，执行中的任务在当前批次结束后停止
+func (l *KnowledgeBaseLogic) CancelDocumentProcessing(kbID, docID int64) error {
+	db := svc.Ctx.DB
+
+	var doc model.TKnowledgeDocument
+	if err := db.Where("id = ? AND knowledge_base_id = ?", docID, kbID).First(&doc).Error; err != nil {
+		return errors.New("文档不存在")
+	}
+
+	canceled, requested, err := cancelIngestJobs(db, []int64{docID})
+	if err != nil {
+		return err
+	}
+	if canceled == 0 && requested == 0 {
+		return errors.New("文档没有进行中的处理任务")
+	}
+	if canceled > 0 {
+		db.Model(&model.TKnowledgeDocument{}).Where("id = ?", docID).Updates(map[string]interface{}{
+			"indexing_status": "canceled",
+			"error_message":   "已取消",
+		})
+	}
 	return nil
 }
 
@@ -641,6 +737,7 @@ func (l *KnowledgeBaseLogic) GetIndexingStatus(kbID int64) ([]*KnowledgeDocument
 	for i := range docs {
 		result = append(result, l.toDocumentInfo(&docs[i]))
 	}
+	attachIngestProgress(result)
 	return result, nil
 }
 
@@ -691,6 +788,14 @@ func (l *KnowledgeBaseLogic) GetDocumentSegments(kbID, docID int64, page, pageSi
 func (l *KnowledgeBaseLogic) UpdateSegment(kbID, segID int64, req *UpdateSegmentReq) error {
 	db := svc.Ctx.DB
 
+	var kb model.TKnowledgeBase
+	if err := db.Where("id = ? AND is_delete = 0", kbID).First(&kb).Error; err != nil {
+		return errors.New("知识库不存在")
+	}
+	if err := ensureNotVectorMigrating(&kb); err != nil {
+		return err
+	}
+
 	var seg model.TKnowledgeSegment
 	if err := db.Where("id = ? AND knowledge_base_id = ?", segID, kbID).First(&seg).Error; err != nil {
 		return errors.New("分块不存在")
@@ -761,15 +866,26 @@ func (l *KnowledgeBaseLogic) reEmbedSegment(kbID, segID int64, content string) {
 		return
 	}
 
-	if kb.QdrantCollection != nil && seg.IndexNodeID != nil {
+	store, collection, err := vectorStoreOf(&kb)
+	if err == nil && seg.IndexNodeID != nil {
+		// 保留文档的元数据与访问控制，避免编辑后的分块被不受限地检索
+		var doc model.TKnowledgeDocument
+		if err := db.Where("id = ?", seg.DocumentID).First(&doc).Error; err != nil {
+			log.Printf("[ERROR] 重新生成向量失败: 文档 %d 不存在", seg.DocumentID)
+			return
+		}
+		metadata := documentPayload(&doc)
+		metadata["document_name"] = doc.Name
 		point := VectorPoint{
-			ID:         *seg.IndexNodeID,
-			Vector:     vector,
-			DocumentID: seg.DocumentID,
-			ChunkIndex: seg.Position,
-			Content:    content,
+			ID:          *seg.IndexNodeID,
+			Vector:      vector,
+			DocumentID:  seg.DocumentID,
+			ChunkIndex:  seg.Position,
+			Content:     content,
+			ContentType: "text",
+			Metadata:    metadata,
 		}
-		UpsertVectors(*kb.QdrantCollection, []VectorPoint{point})
+		store.Upsert(context.Background(), collection, "text", []VectorPoint{point})
 	}
 }
 
@@ -781,11 +897,12 @@ func (l *KnowledgeBaseLogic) deleteSegmentVector(kbID, documentID int64, positio
 		return
 	}
 
-	if kb.QdrantCollection == nil || *kb.QdrantCollection == "" {
+	store, collection, err := vectorStoreOf(&kb)
+	if err != nil {
 		return
 	}
 
-	if err := DeleteSegmentVector(*kb.QdrantCollection, documentID, position); err != nil {
+	if err := store.DeletePoint(context.Background(), collection, documentID, position); err != nil {
 		log.Printf("[ERROR] 删除分段向量失败 (kbID=%d, docID=%d, pos=%d): %v", kbID, documentID, position, err)
 	}
 }
@@ -811,11 +928,18 @@ func mergeChunkSetting(override *model.ChunkSetting) *model.ChunkSetting {
 	}
 	cs.CleanWhitespace = override.CleanWhitespace
 	cs.RemoveURLs = override.RemoveURLs
+	cs.Strategy = override.Strategy
+	cs.Language = override.Language
+	cs.ParentChunkSize = override.ParentChunkSize
 	return cs
 }
 
 func (l *KnowledgeBaseLogic) PreviewChunks(kbID int64, req *PreviewChunksReq) ([]*PreviewChunkItem, error) {
+	if err := validateChunkSetting(req.ChunkSetting); err != nil {
+		return nil, err
+	}
 	text := req.Content
+	fileName, fileType := req.FilePath, req.FileType
 
 	if req.DocumentID > 0 {
 		db := svc.Ctx.DB
@@ -829,6 +953,7 @@ func (l *KnowledgeBaseLogic) PreviewChunks(kbID int64, req *PreviewChunksReq) ([
 			return nil, fmt.Errorf("文本提取失败: %w", err)
 		}
 		text = extracted
+		fileName, fileType = doc.Name, derefString(doc.FileType)
 	} else if req.FilePath != "" {
 		tmpDoc := &model.TKnowledgeDocument{
 			FilePath: &req.FilePath,
@@ -854,20 +979,25 @@ func (l *KnowledgeBaseLogic) PreviewChunks(kbID int64, req *PreviewChunksReq) ([
 		text = removeURLsAndEmails(text)
 	}
 
-	var chunks []string
-	if cs.Separator != "" {
-		chunks = splitBySeparator(text, cs.Separator, cs.ChunkSize, cs.ChunkOverlap)
-	} else {
-		chunks = splitText(text, cs.ChunkSize, cs.ChunkOverlap)
+	chunkOverlap := cs.ChunkOverlap
+	if chunkOverlap >= cs.ChunkSize {
+		chunkOverlap = cs.ChunkSize / 5
 	}
+	chunks := chunkDocumentText(text, cs, cs.ChunkSize, chunkOverlap, fileName, fileType)
 
-	result := make([]*PreviewChunkItem, 0, len(chunks))
-	for i, chunk := range chunks {
-		result = append(result, &PreviewChunkItem{
+	result := make([]*PreviewChunkItem, 0, len(chunks.Chunks))
+	for i, chunk := range chunks.Chunks {
+		item := &PreviewChunkItem{
 			Index:     i,
 			Content:   chunk,
 			CharCount: utf8.RuneCountInString(chunk),
-		})
+			Heading:   chunks.heading(i),
+		}
+		if parent := chunks.parentOf(i); parent >= 0 {
+			item.ParentIndex = &parent
+			item.ParentContent = chunks.Parents[parent]
+		}
+		result = append(result, item)
 	}
 	return result, nil
 }
@@ -885,29 +1015,56 @@ func (l *KnowledgeBaseLogic) ProcessDocument(kbID, docID int64, req *ProcessDocu
 		return errors.New("文档不存在")
 	}
 
+	if err := validateChunkSetting(req.ChunkSetting); err != nil {
+		return err
+	}
 	cs := mergeChunkSetting(req.ChunkSetting)
-	db.Model(&model.TKnowledgeDocument{}).Where("id = ?", docID).Updates(map[string]interface{}{
-		"chunk_setting":   cs,
-		"indexing_status": "waiting",
-	})
-	db.Where("id = ?", docID).First(&doc)
-
-	safeGo(func() {
-		processor := NewDocumentProcessor()
-		if err := processor.Process(&kb, &doc); err != nil {
-			log.Printf("[ERROR] 文档处理失败: docID=%d, err=%v", doc.ID, err)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := enqueueIngestJob(tx, kbID, docID); err != nil {
+			return err
 		}
+		return tx.Model(&model.TKnowledgeDocument{}).Where("id = ?", docID).Update("chunk_setting", cs).Error
 	})
-
+	if err != nil {
+		return err
+	}
+	wakeIngestWorkers()
 	return nil
 }
 
 // CreateAndProcessDocument 一次性创建文档记录并启动异步处理（配合 upload-file 使用）
 func (l *KnowledgeBaseLogic) CreateAndProcessDocument(kbID int64, req *CreateAndProcessReq) (*KnowledgeDocumentInfo, error) {
+	updates := make(map[string]interface{})
+	if req.Metadata != nil {
+		meta, err := normalizeMetadata(req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		updates["metadata"] = meta
+	}
+	if req.ACL != nil {
+		acl, err := validateDocumentACL(req.ACL)
+		if err != nil {
+			return nil, err
+		}
+		updates["acl"] = acl
+	}
+
 	docInfo, err := l.CreateDocument(kbID, req.FileName, req.FileType, req.FilePath, req.FileSize)
 	if err != nil {
 		return nil, err
 	}
+	if len(updates) > 0 {
+		if err := svc.Ctx.DB.Model(&model.TKnowledgeDocument{}).Where("id = ?", docInfo.ID).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		if meta, ok := updates["metadata"].(model.DocumentMetadata); ok {
+			docInfo.Metadata = meta
+		}
+		if acl, ok := updates["acl"].(*model.DocumentACL); ok {
+			docInfo.ACL = acl
+		}
+	}
 
 	processReq := &ProcessDocumentReq{ChunkSetting: req.ChunkSetting}
 	if err := l.ProcessDocument(kbID, docInfo.ID, processReq); err != nil {
@@ -929,10 +1086,40 @@ func (l *KnowledgeBaseLogic) Search(kbID int64, req *KnowledgeSearchReq) ([]*Kno
 		return nil, errors.New("知识库不存在")
 	}
 
-	if kb.QdrantCollection == nil || *kb.QdrantCollection == "" {
-		return nil, errors.New("知识库尚未初始化向量存储")
+	if _, _, err := vectorStoreOf(&kb); err != nil {
+		return nil, err
+	}
+
+	params, err := resolveSearchParams(&kb, req)
+	if err != nil {
+		return nil, err
 	}
+	// 按当前用户的文档访问控制检索
+	userID := ctxutil.GetUserID(l.ctx)
+	params.aclUserID = &userID
+	results := l.retrieve(&kb, req.Query, params)
+
+	go l.saveQueryHistory(kbID, req.Query, params.RetrievalMode, params.TopK, params.Score, len(results))
+	go l.updateHitCounts(results)
 
+	return results, nil
+}
+
+// searchParams 请求参数与知识库配置合并后的检索参数
+type searchParams struct {
+	TopK          int             `json:"top_k"`
+	Score         float64         `json:"score"`
+	RetrievalMode string          `json:"retrieval_mode"`
+	SearchFields  string          `json:"search_fields"`
+	Fusion        fusionOptions   `json:"fusion"`
+	Rerank        bool            `json:"rerank"`
+	RerankModelID int64           `json:"rerank_model_id,omitempty"`
+	Filter        *MetadataFilter `json:"filter,omitempty"`
+	aclUserID     *int64          // 按该用户的文档访问控制检索，nil 表示不做访问控制
+}
+
+// resolveSearchParams 合并检索请求与知识库配置，请求中未指定的参数使用知识库配置
+func resolveSearchParams(kb *model.TKnowledgeBase, req *KnowledgeSearchReq) (*searchParams, error) {
 	cfg := kb.GetConfig()
 
 	topK := req.TopK
@@ -959,37 +1146,98 @@ func (l *KnowledgeBaseLogic) Search(kbID int64, req *KnowledgeSearchReq) ([]*Kno
 		searchFields = "all"
 	}
 
+	fusion, err := resolveFusionOptions(cfg, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	rerank := cfg.RerankEnabled
+	if req.Rerank != nil {
+		rerank = *req.Rerank
+	}
+	if rerank && (cfg.RerankModelID == nil || *cfg.RerankModelID == 0) {
+		if req.Rerank != nil {
+			return nil, errors.New("知识库未配置 Rerank 模型")
+		}
+		rerank = false
+	}
+
+	p := &searchParams{
+		TopK:          topK,
+		Score:         score,
+		RetrievalMode: retrievalMode,
+		SearchFields:  searchFields,
+		Fusion:        fusion,
+		Rerank:        rerank,
+	}
+	if !req.Filter.empty() {
+		p.Filter = req.Filter
+	}
+	if rerank {
+		p.RerankModelID = *cfg.RerankModelID
+	}
+	return p, nil
+}
+
+// retrieve 按检索参数召回、融合、重排序，不记录查询历史与命中次数
+func (l *KnowledgeBaseLogic) retrieve(kb *model.TKnowledgeBase, query string, p *searchParams) []*KnowledgeSearchResult {
+	// 需要融合或重排序时每路多召回一些候选，最终再截取 Top-K
+	candidates := p.TopK
+	if p.Rerank || p.RetrievalMode == "hybrid" || p.RetrievalMode == "hybrid_graph" {
+		candidates = retrievalCandidates(p.TopK)
+	}
+
+	var filters []*MetadataFilter
+	if p.Filter != nil {
+		filters = append(filters, p.Filter)
+	}
+	filter := newVectorFilter(kb, filters, p.aclUserID)
+
 	var results []*KnowledgeSearchResult
 
-	switch retrievalMode {
+	switch p.RetrievalMode {
 	case "keyword":
-		results = l.keywordSearch(kbID, req.Query, topK)
+		results = l.keywordSearch(kb.ID, query, candidates, filter)
 	case "hybrid":
-		vectorResults := l.vectorSearch(&kb, req.Query, topK, score, searchFields)
-		keywordResults := l.keywordSearch(kbID, req.Query, topK)
-		results = l.mergeResults(vectorResults, keywordResults, topK)
+		results = fuseResults([]rankedResults{
+			{retriever: RetrieverVector, weight: p.Fusion.VectorWeight, results: l.vectorSearch(kb, query, candidates, p.Score, p.SearchFields, filter)},
+			{retriever: RetrieverKeyword, weight: 1 - p.Fusion.VectorWeight, results: l.keywordSearch(kb.ID, query, candidates, filter)},
+		}, p.Fusion, candidates)
 	case "graph":
-		results = l.graphSearch(&kb, req.Query, topK)
+		results = l.graphSearch(kb, query, p.TopK, filter)
 	case "hybrid_graph":
-		vectorResults := l.vectorSearch(&kb, req.Query, topK, score, searchFields)
-		graphResults := l.graphSearch(&kb, req.Query, topK)
-		results = l.mergeResults(vectorResults, graphResults, topK)
+		results = fuseResults([]rankedResults{
+			{retriever: RetrieverVector, weight: p.Fusion.VectorWeight, results: l.vectorSearch(kb, query, candidates, p.Score, p.SearchFields, filter)},
+			{retriever: RetrieverGraph, weight: 1 - p.Fusion.VectorWeight, results: l.graphSearch(kb, query, p.TopK, filter)},
+		}, p.Fusion, candidates)
 	default:
-		results = l.vectorSearch(&kb, req.Query, topK, score, searchFields)
+		results = l.vectorSearch(kb, query, candidates, p.Score, p.SearchFields, filter)
 	}
 
-	go l.saveQueryHistory(kbID, req.Query, retrievalMode, topK, score, len(results))
-	go l.updateHitCounts(results)
+	// 父子分块的子块替换为父块后再重排序，重排序依据的是最终返回的内容
+	results = resolveChunkContext(results)
 
-	return results, nil
+	if p.Rerank {
+		return l.rerankResults(p.RerankModelID, query, results, p.TopK)
+	}
+	return truncateResults(results, p.TopK)
 }
 
-func (l *KnowledgeBaseLogic) vectorSearch(kb *model.TKnowledgeBase, query string, topK int, score float64, searchFields string) []*KnowledgeSearchResult {
+func (l *KnowledgeBaseLogic) vectorSearch(kb *model.TKnowledgeBase, query string, topK int, score float64, searchFields string, filter *VectorFilter) []*KnowledgeSearchResult {
 	if kb.EmbeddingModelID == nil || *kb.EmbeddingModelID == 0 {
 		log.Printf("[ERROR] vectorSearch: 知识库 %d 未配置嵌入模型", kb.ID)
 		return nil
 	}
 
+	store, collection, err := vectorStoreOf(kb)
+	if err != nil {
+		log.Printf("[ERROR] vectorSearch: %v", err)
+		return nil
+	}
+
 	aiModelLogic := NewAiModelLogic(l.ctx)
 
 	var allHits []SearchHit
@@ -1001,8 +1249,8 @@ func (l *KnowledgeBaseLogic) vectorSearch(kb *model.TKnowledgeBase, query string
 			log.Printf("[ERROR] vectorSearch: 获取嵌入模型失败 (modelID=%d): %v", *kb.EmbeddingModelID, err)
 			return nil
 		}
-		log.Printf("[DEBUG] vectorSearch: 使用模型 %s (base=%s), collection=%s, score=%.3f",
-			aiModel.ModelID, aiModel.APIBaseURL, *kb.QdrantCollection, score)
+		log.Printf("[DEBUG] vectorSearch: 使用模型 %s (base=%s), store=%s, collection=%s, score=%.3f",
+			aiModel.ModelID, aiModel.APIBaseURL, store.Name(), collection, score)
 
 		embClient := NewEmbeddingClient(aiModel.APIBaseURL, aiModel.APIKey, aiModel.ModelID)
 		queryVector, err := embClient.EmbedTextAsQuery(l.ctx, query)
@@ -1012,11 +1260,11 @@ func (l *KnowledgeBaseLogic) vectorSearch(kb *model.TKnowledgeBase, query string
 		}
 		log.Printf("[DEBUG] vectorSearch: 查询向量维度=%d", len(queryVector))
 
-		hits, err := SearchVectors(*kb.QdrantCollection, queryVector, topK, float32(score))
+		hits, err := store.Search(l.ctx, collection, "text", queryVector, topK, float32(score), filter)
 		if err != nil {
-			log.Printf("[ERROR] vectorSearch: Qdrant 搜索失败 (collection=%s): %v", *kb.QdrantCollection, err)
+			log.Printf("[ERROR] vectorSearch: %s 搜索失败 (collection=%s): %v", store.Name(), collection, err)
 		} else {
-			log.Printf("[DEBUG] vectorSearch: Qdrant 返回 %d 条命中", len(hits))
+			log.Printf("[DEBUG] vectorSearch: %s 返回 %d 条命中", store.Name(), len(hits))
 			allHits = append(allHits, hits...)
 		}
 	}
@@ -1031,7 +1279,7 @@ func (l *KnowledgeBaseLogic) vectorSearch(kb *model.TKnowledgeBase, query string
 				mmInputs := []MultimodalInput{{Type: EmbeddingInputText, Text: query}}
 				mmVectors, err := mmClient.EmbedMultimodal(l.ctx, mmInputs)
 				if err == nil && len(mmVectors) > 0 {
-					imgHits, err := SearchVectorsInField(*kb.QdrantCollection, "image", mmVectors[0], topK, float32(score), nil)
+					imgHits, err := store.Search(l.ctx, collection, "image", mmVectors[0], topK, float32(score), filter)
 					if err != nil {
 						log.Printf("[WARN] 多模态搜索失败: %v", err)
 					} else {
@@ -1042,7 +1290,8 @@ func (l *KnowledgeBaseLogic) vectorSearch(kb *model.TKnowledgeBase, query string
 		}
 	}
 
-	// 去重并排序
+	// 去重并排序（文本与图片命中按相似度统一排序）
+	sort.SliceStable(allHits, func(i, j int) bool { return allHits[i].Score > allHits[j].Score })
 	seen := make(map[string]bool)
 	docNameCache := make(map[int64]string)
 	results := make([]*KnowledgeSearchResult, 0, len(allHits))
@@ -1066,18 +1315,19 @@ func (l *KnowledgeBaseLogic) vectorSearch(kb *model.TKnowledgeBase, query string
 			ChunkIndex:   hit.ChunkIndex,
 			WordCount:    utf8.RuneCountInString(hit.Content),
 			Metadata:     hit.Metadata,
+			ScoreDetail: &ScoreExplanation{
+				Method:  RetrieverVector,
+				Sources: []SourceScore{{Retriever: RetrieverVector, Rank: len(results) + 1, Score: hit.Score}},
+			},
 		})
 	}
 
-	if len(results) > topK {
-		results = results[:topK]
-	}
-	return results
+	return truncateResults(results, topK)
 }
 
-// graphSearch 图谱检索（Phase 3）
-func (l *KnowledgeBaseLogic) graphSearch(kb *model.TKnowledgeBase, query string, topK int) []*KnowledgeSearchResult {
-	if kb.Type != "graph" || !IsNeo4jEnabled() {
+// graphSearch 图谱检索（Phase 3），检索范围受元数据过滤或访问控制限制时不参与召回
+func (l *KnowledgeBaseLogic) graphSearch(kb *model.TKnowledgeBase, query string, topK int, filter *VectorFilter) []*KnowledgeSearchResult {
+	if kb.Type != "graph" || !IsNeo4jEnabled() || !scopeCoversGraph(kb.ID, filter) {
 		return nil
 	}
 
@@ -1108,65 +1358,78 @@ func (l *KnowledgeBaseLogic) graphSearch(kb *model.TKnowledgeBase, query string,
 			"entity_count":   len(graphResult.Entities),
 			"relation_count": len(graphResult.Relations),
 		},
+		ScoreDetail: &ScoreExplanation{
+			Method:  RetrieverGraph,
+			Sources: []SourceScore{{Retriever: RetrieverGraph, Rank: 1, Score: 0.9}},
+		},
 	}}
 }
 
-func (l *KnowledgeBaseLogic) keywordSearch(kbID int64, query string, topK int) []*KnowledgeSearchResult {
-	db := svc.Ctx.DB
-	var segments []model.TKnowledgeSegment
-
-	err := db.Where("knowledge_base_id = ? AND enabled = 1 AND MATCH(content) AGAINST(? IN NATURAL LANGUAGE MODE)", kbID, query).
-		Limit(topK).Find(&segments).Error
+// keywordSearch BM25 关键词检索，分数按本次结果最高分归一化，原始分记录在 ScoreDetail 中
+func (l *KnowledgeBaseLogic) keywordSearch(kbID int64, query string, topK int, filter *VectorFilter) []*KnowledgeSearchResult {
+	idx, err := getKeywordIndex(kbID)
 	if err != nil {
-		log.Printf("[WARN] 全文检索失败，降级到 LIKE: %v", err)
-		db.Where("knowledge_base_id = ? AND enabled = 1 AND content LIKE ?", kbID, "%"+query+"%").
-			Limit(topK).Find(&segments)
+		log.Printf("[ERROR] keywordSearch: 加载知识库 %d 关键词索引失败: %v", kbID, err)
+		return nil
+	}
+	var allow func(documentID int64) bool
+	if filter != nil {
+		allowed, err := allowedDocuments(kbID, filter)
+		if err != nil {
+			log.Printf("[ERROR] keywordSearch: 读取文档元数据失败: %v", err)
+			return nil
+		}
+		allow = func(documentID int64) bool { return allowed[documentID] }
+	}
+	hits := idx.SearchFiltered(query, topK, allow)
+	if len(hits) == 0 {
+		return nil
 	}
 
+	ids := make([]int64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.SegmentID
+	}
+	var segments []model.TKnowledgeSegment
+	if err := svc.Ctx.DB.Where("id IN ? AND enabled = 1", ids).Find(&segments).Error; err != nil {
+		log.Printf("[ERROR] keywordSearch: 读取分块失败: %v", err)
+		return nil
+	}
+	segByID := make(map[int64]*model.TKnowledgeSegment, len(segments))
+	for i := range segments {
+		segByID[segments[i].ID] = &segments[i]
+	}
+
+	maxScore := hits[0].Score
 	docNameCache := make(map[int64]string)
-	results := make([]*KnowledgeSearchResult, 0, len(segments))
-	for _, seg := range segments {
-		docName := getDocNameCached(seg.DocumentID, docNameCache)
+	results := make([]*KnowledgeSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		seg, ok := segByID[hit.SegmentID]
+		if !ok {
+			continue // 索引构建后分块已被删除或禁用
+		}
+		normalized := hit.Score / maxScore
 		results = append(results, &KnowledgeSearchResult{
 			SegmentID:    seg.ID,
 			Content:      seg.Content,
-			Score:        0.5,
+			ContentType:  seg.ContentType,
+			ImagePath:    imagePathToURL(derefString(seg.ImagePath), kbID),
+			Score:        normalized,
 			DocumentID:   seg.DocumentID,
-			DocumentName: docName,
+			DocumentName: getDocNameCached(seg.DocumentID, docNameCache),
 			ChunkIndex:   seg.Position,
 			WordCount:    seg.WordCount,
 			HitCount:     seg.HitCount,
+			ScoreDetail: &ScoreExplanation{
+				Method:       RetrieverKeyword,
+				Sources:      []SourceScore{{Retriever: RetrieverKeyword, Rank: len(results) + 1, Score: normalized, RawScore: hit.Score}},
+				MatchedTerms: hit.MatchedTerms,
+			},
 		})
 	}
 	return results
 }
 
-func (l *KnowledgeBaseLogic) mergeResults(vectorResults, keywordResults []*KnowledgeSearchResult, topK int) []*KnowledgeSearchResult {
-	seen := make(map[string]bool)
-	var merged []*KnowledgeSearchResult
-
-	for _, r := range vectorResults {
-		key := fmt.Sprintf("%d_%d", r.DocumentID, r.ChunkIndex)
-		if !seen[key] {
-			seen[key] = true
-			merged = append(merged, r)
-		}
-	}
-	for _, r := range keywordResults {
-		key := fmt.Sprintf("%d_%d", r.DocumentID, r.ChunkIndex)
-		if !seen[key] {
-			seen[key] = true
-			r.Score = r.Score * 0.8
-			merged = append(merged, r)
-		}
-	}
-
-	if len(merged) > topK {
-		merged = merged[:topK]
-	}
-	return merged
-}
-
 // -----------------------------------------------
 // 查询历史
 // -----------------------------------------------
@@ -1221,7 +1484,7 @@ type KnowledgeDiagResult struct {
 	EmbeddingModel    string                `json:"embedding_model"`
 	ConfiguredDim     int                   `json:"configured_dimension"`
 	MySQLSegments     int64                 `json:"mysql_segments"`
-	Qdrant            *QdrantCollectionDiag `json:"qdrant"`
+	Qdrant            *VectorCollectionDiag `json:"qdrant"` // 向量库集合信息（JSON 字段名沿用 qdrant）
 	EmbeddingTest     *EmbeddingTestResult  `json:"embedding_test"`
 	DimensionMismatch bool                  `json:"dimension_mismatch"`
 	Suggestion        string                `json:"suggestion,omitempty"`
@@ -1253,11 +1516,11 @@ func (l *KnowledgeBaseLogic) Diagnose(kbID int64) (*KnowledgeDiagResult, error)
 	// MySQL segment 数量
 	db.Model(&model.TKnowledgeSegment{}).Where("knowledge_base_id = ?", kbID).Count(&result.MySQLSegments)
 
-	// Qdrant 集合信息
-	if kb.QdrantCollection != nil && *kb.QdrantCollection != "" {
-		result.Qdrant = DiagnoseQdrantCollection(*kb.QdrantCollection)
+	// 向量库集合信息
+	if store, collection, err := vectorStoreOf(&kb); err == nil {
+		result.Qdrant = store.Diagnose(l.ctx, collection)
 	} else {
-		result.Qdrant = &QdrantCollectionDiag{Error: "未配置 Qdrant Collection"}
+		result.Qdrant = &VectorCollectionDiag{Store: kb.VectorStore, Error: err.Error()}
 	}
 
 	// 测试 Embedding API
@@ -1281,11 +1544,11 @@ func (l *KnowledgeBaseLogic) Diagnose(kbID int64) (*KnowledgeDiagResult, error)
 						"维度不匹配！知识库配置为 %d 维，但模型实际输出 %d 维。"+
 							"请在文档管理页面「批量重新处理」所有文档，系统会自动修正维度并重建向量索引。",
 						configuredDim, len(vec))
-					// 检查 Qdrant collection 里配置的维度
+					// 检查向量集合里配置的维度
 					if result.Qdrant != nil {
-						if qdrantTextDim, ok := result.Qdrant.VectorFields["text"]; ok && int(qdrantTextDim) != len(vec) {
+						if storeTextDim, ok := result.Qdrant.VectorFields["text"]; ok && int(storeTextDim) != len(vec) {
 							result.Suggestion += fmt.Sprintf(
-								" (Qdrant Collection 当前 text 维度=%d，将被自动重建为 %d)", qdrantTextDim, len(vec))
+								" (向量集合当前 text 维度=%d，将被自动重建为 %d)", storeTextDim, len(vec))
 						}
 					}
 				}
@@ -1298,6 +1561,49 @@ func (l *KnowledgeBaseLogic) Diagnose(kbID int64) (*KnowledgeDiagResult, error)
 	return result, nil
 }
 
+// EmbedReq 文本向量化请求（供 workflow-engine 语义相似度断言调用）
+// 优先使用 model_id 指定的嵌入模型，否则使用知识库配置的嵌入模型
+type EmbedReq struct {
+	KnowledgeBaseID int64    `json:"knowledge_base_id"`
+	ModelID         int64    `json:"model_id"`
+	Texts           []string `json:"texts"`
+}
+
+// maxEmbedTexts 单次向量化的文本数量上限
+const maxEmbedTexts = 32
+
+// EmbedTexts 使用知识库的嵌入客户端对文本向量化
+func (l *KnowledgeBaseLogic) EmbedTexts(req *EmbedReq) ([][]float32, error) {
+	if len(req.Texts) == 0 {
+		return nil, errors.New("文本不能为空")
+	}
+	if len(req.Texts) > maxEmbedTexts {
+		return nil, fmt.Errorf("单次最多向量化 %d 条文本", maxEmbedTexts)
+	}
+
+	modelID := req.ModelID
+	if modelID <= 0 {
+		if req.KnowledgeBaseID <= 0 {
+			return nil, errors.New("需要指定知识库或嵌入模型")
+		}
+		kb, err := l.GetByID(req.KnowledgeBaseID)
+		if err != nil {
+			return nil, fmt.Errorf("知识库不存在 (ID=%d): %w", req.KnowledgeBaseID, err)
+		}
+		if kb.EmbeddingModelID == nil || *kb.EmbeddingModelID == 0 {
+			return nil, errors.New("知识库未配置嵌入模型")
+		}
+		modelID = *kb.EmbeddingModelID
+	}
+
+	aiModel, err := NewAiModelLogic(l.ctx).GetByIDWithKey(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("嵌入模型不存在 (ID=%d): %w", modelID, err)
+	}
+	embClient := NewEmbeddingClient(aiModel.APIBaseURL, aiModel.APIKey, aiModel.ModelID)
+	return embClient.EmbedTexts(l.ctx, req.Texts)
+}
+
 func (l *KnowledgeBaseLogic) updateHitCounts(results []*KnowledgeSearchResult) {
 	db := svc.Ctx.DB
 	for _, r := range results {
@@ -1404,6 +1710,9 @@ func (l *KnowledgeBaseLogic) toKnowledgeBaseInfo(m *model.TKnowledgeBase, counts
 		RetrievalMode:       cfg.RetrievalMode,
 		RerankEnabled:       cfg.RerankEnabled,
 		RerankModelID:       cfg.RerankModelID,
+		FusionMethod:        cfg.FusionMethod,
+		RRFK:                cfg.RRFK,
+		VectorWeight:        cfg.VectorWeight,
 		EmbeddingDimension:  cfg.EmbeddingDimension,
 		MultimodalDimension: cfg.MultimodalDimension,
 	}
@@ -1423,6 +1732,10 @@ func (l *KnowledgeBaseLogic) toKnowledgeBaseInfo(m *model.TKnowledgeBase, counts
 	if m.QdrantCollection != nil {
 		info.QdrantCollection = *m.QdrantCollection
 	}
+	info.VectorStore = m.VectorStore
+	if info.VectorStore == "" {
+		info.VectorStore = VectorStoreQdrant
+	}
 	return info
 }
 
@@ -1460,6 +1773,11 @@ func (l *KnowledgeBaseLogic) toDocumentInfo(m *model.TKnowledgeDocument) *Knowle
 	if m.TokenCount != nil {
 		info.TokenCount = *m.TokenCount
 	}
+	info.SourceID = m.SourceID
+	info.SourceKey = derefString(m.SourceKey)
+	info.Metadata = m.Metadata
+	info.AutoMetadata = m.AutoMetadata
+	info.ACL = m.ACL
 	return info
 }
 
@@ -0,0 +1,302 @@
+package logic
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"unicode"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+)
+
+// -----------------------------------------------
+// 关键词检索：按知识库构建的 BM25 倒排索引（内存缓存）
+// 中日韩文字按相邻两字切分（bigram），其余文字按字母数字连续串切分并转小写
+// -----------------------------------------------
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// keywordIndexCacheSize 最多缓存的知识库索引数，超出时淘汰最久未使用的
+	keywordIndexCacheSize = 64
+)
+
+// englishStopwords 英文停用词（中文按 bigram 切分后停用词影响很小，不做处理）
+var englishStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "was": true, "with": true,
+}
+
+// isCJK 是否为按字切分的中日韩文字
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// tokenizeForBM25 将文本切分为检索词
+// 连续的中日韩文字输出相邻两字组合（单字时输出单字），字母数字串整体输出（去掉英文停用词）
+func tokenizeForBM25(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			w := string(word)
+			if !englishStopwords[w] {
+				tokens = append(tokens, w)
+			}
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		switch {
+		case len(cjk) == 1:
+			tokens = append(tokens, string(cjk))
+		case len(cjk) > 1:
+			for i := 0; i+1 < len(cjk); i++ {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// bm25Posting 倒排表项
+type bm25Posting struct {
+	doc int32 // 分块在索引中的下标
+	tf  int32
+}
+
+// bm25Index 单个知识库的 BM25 索引，只保存分块 ID 与词频，内容检索后回表读取
+type bm25Index struct {
+	segmentIDs  []int64
+	documentIDs []int64 // 分块所属文档，按文档过滤时使用
+	docLens     []int32
+	avgDocLen   float64
+	postings    map[string][]bm25Posting
+}
+
+// bm25Hit BM25 命中
+type bm25Hit struct {
+	SegmentID    int64
+	Score        float64
+	MatchedTerms []string
+}
+
+// bm25Segment 构建索引所需的分块字段
+type bm25Segment struct {
+	ID         int64
+	DocumentID int64
+	Content    string
+}
+
+func newBM25Index(segments []bm25Segment) *bm25Index {
+	idx := &bm25Index{
+		segmentIDs:  make([]int64, len(segments)),
+		documentIDs: make([]int64, len(segments)),
+		docLens:     make([]int32, len(segments)),
+		postings:    make(map[string][]bm25Posting),
+	}
+	var totalLen int64
+	for i, seg := range segments {
+		tokens := tokenizeForBM25(seg.Content)
+		idx.segmentIDs[i] = seg.ID
+		idx.documentIDs[i] = seg.DocumentID
+		idx.docLens[i] = int32(len(tokens))
+		totalLen += int64(len(tokens))
+
+		tf := make(map[string]int32)
+		for _, t := range tokens {
+			tf[t]++
+		}
+		for t, n := range tf {
+			idx.postings[t] = append(idx.postings[t], bm25Posting{doc: int32(i), tf: n})
+		}
+	}
+	if len(segments) > 0 {
+		idx.avgDocLen = float64(totalLen) / float64(len(segments))
+	}
+	return idx
+}
+
+// Search 按 BM25 打分返回前 topK 个命中，查询词重复出现时按一次计算
+func (idx *bm25Index) Search(query string, topK int) []bm25Hit {
+	return idx.SearchFiltered(query, topK, nil)
+}
+
+// SearchFiltered 同 Search，只返回 allow 允许的文档中的分块；allow 为 nil 表示不过滤
+func (idx *bm25Index) SearchFiltered(query string, topK int, allow func(documentID int64) bool) []bm25Hit {
+	n := len(idx.segmentIDs)
+	if n == 0 || topK <= 0 {
+		return nil
+	}
+
+	scores := make(map[int32]float64)
+	matched := make(map[int32][]string)
+	seen := make(map[string]bool)
+	for _, term := range tokenizeForBM25(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (float64(n)-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			tf := float64(p.tf)
+			norm := 1 - bm25B
+			if idx.avgDocLen > 0 {
+				norm += bm25B * float64(idx.docLens[p.doc]) / idx.avgDocLen
+			}
+			scores[p.doc] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			matched[p.doc] = append(matched[p.doc], term)
+		}
+	}
+
+	hits := make([]bm25Hit, 0, len(scores))
+	for doc, score := range scores {
+		if allow != nil && !allow(idx.documentIDs[doc]) {
+			continue
+		}
+		hits = append(hits, bm25Hit{SegmentID: idx.segmentIDs[doc], Score: score, MatchedTerms: matched[doc]})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].SegmentID < hits[j].SegmentID
+	})
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+	return hits
+}
+
+// -----------------------------------------------
+// 索引缓存：以启用分块的数量、最大 ID、最大更新时间作为版本签名，
+// 分块新增/删除/编辑/启停后签名变化自动重建（多实例部署下同样有效）
+// -----------------------------------------------
+
+type keywordIndexSignature struct {
+	Count     int64
+	MaxID     int64
+	UpdatedAt string
+}
+
+type keywordIndexEntry struct {
+	sig      keywordIndexSignature
+	index    *bm25Index
+	lastUsed time.Time
+}
+
+var keywordIndexCache = struct {
+	sync.Mutex
+	entries map[int64]*keywordIndexEntry
+}{entries: make(map[int64]*keywordIndexEntry)}
+
+// keywordIndexBuildLocks 按知识库串行化索引构建，避免并发查询重复加载全部分块
+var keywordIndexBuildLocks sync.Map
+
+// getKeywordIndex 获取知识库的 BM25 索引，签名变化时从数据库重建
+func getKeywordIndex(kbID int64) (*bm25Index, error) {
+	db := svc.Ctx.DB
+
+	var row struct {
+		Count     int64
+		MaxID     *int64
+		UpdatedAt *time.Time
+	}
+	if err := db.Model(&model.TKnowledgeSegment{}).
+		Select("COUNT(*) AS count, MAX(id) AS max_id, MAX(updated_at) AS updated_at").
+		Where("knowledge_base_id = ? AND enabled = 1", kbID).
+		Scan(&row).Error; err != nil {
+		return nil, err
+	}
+	sig := keywordIndexSignature{Count: row.Count}
+	if row.MaxID != nil {
+		sig.MaxID = *row.MaxID
+	}
+	if row.UpdatedAt != nil {
+		sig.UpdatedAt = row.UpdatedAt.Format(time.RFC3339Nano)
+	}
+
+	if idx := cachedKeywordIndex(kbID, sig); idx != nil {
+		return idx, nil
+	}
+
+	lock, _ := keywordIndexBuildLocks.LoadOrStore(kbID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+	if idx := cachedKeywordIndex(kbID, sig); idx != nil {
+		return idx, nil
+	}
+
+	var segments []bm25Segment
+	if err := db.Model(&model.TKnowledgeSegment{}).
+		Select("id, document_id, content").
+		Where("knowledge_base_id = ? AND enabled = 1", kbID).
+		Order("id").
+		Find(&segments).Error; err != nil {
+		return nil, err
+	}
+	idx := newBM25Index(segments)
+
+	keywordIndexCache.Lock()
+	defer keywordIndexCache.Unlock()
+	keywordIndexCache.entries[kbID] = &keywordIndexEntry{sig: sig, index: idx, lastUsed: time.Now()}
+	if len(keywordIndexCache.entries) > keywordIndexCacheSize {
+		var oldestID int64
+		var oldest time.Time
+		for id, e := range keywordIndexCache.entries {
+			if oldest.IsZero() || e.lastUsed.Before(oldest) {
+				oldestID, oldest = id, e.lastUsed
+			}
+		}
+		delete(keywordIndexCache.entries, oldestID)
+	}
+	return idx, nil
+}
+
+func cachedKeywordIndex(kbID int64, sig keywordIndexSignature) *bm25Index {
+	keywordIndexCache.Lock()
+	defer keywordIndexCache.Unlock()
+	e, ok := keywordIndexCache.entries[kbID]
+	if !ok || e.sig != sig {
+		return nil
+	}
+	e.lastUsed = time.Now()
+	return e.index
+}
+
+// invalidateKeywordIndex 删除知识库的索引缓存
+func invalidateKeywordIndex(kbID int64) {
+	keywordIndexCache.Lock()
+	delete(keywordIndexCache.entries, kbID)
+	keywordIndexCache.Unlock()
+	keywordIndexBuildLocks.Delete(kbID)
+}
@@ -0,0 +1,764 @@
+package logic
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+)
+
+// -----------------------------------------------
+// 结构化分块策略
+// -----------------------------------------------
+
+// chunkResult 分块结果。Chunks 为向量化与检索的文本；
+// 结构化策略下 Headings 与 Chunks 一一对应，记录分块的结构路径（标题层级 / 代码符号 / 行范围）；
+// 父子分块策略下 Parents 为父块，ParentOf 记录每个子块所属父块的下标。
+type chunkResult struct {
+	Chunks   []string `json:"chunks"`
+	Headings []string `json:"headings,omitempty"`
+	Parents  []string `json:"parents,omitempty"`
+	ParentOf []int    `json:"parent_of,omitempty"`
+}
+
+func (r *chunkResult) add(content, heading string) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return
+	}
+	r.Chunks = append(r.Chunks, content)
+	r.Headings = append(r.Headings, heading)
+}
+
+// heading 第 i 个分块的结构路径
+func (r *chunkResult) heading(i int) string {
+	if i < len(r.Headings) {
+		return r.Headings[i]
+	}
+	return ""
+}
+
+// parentOf 第 i 个分块所属父块的下标，没有父块时返回 -1
+func (r *chunkResult) parentOf(i int) int {
+	if i < len(r.ParentOf) && r.ParentOf[i] < len(r.Parents) {
+		return r.ParentOf[i]
+	}
+	return -1
+}
+
+// validateChunkSetting 校验分块策略与代码语言
+func validateChunkSetting(cs *model.ChunkSetting) error {
+	if cs == nil {
+		return nil
+	}
+	switch cs.Strategy {
+	case "", model.ChunkStrategyGeneral, model.ChunkStrategyAuto, model.ChunkStrategyMarkdown,
+		model.ChunkStrategyCode, model.ChunkStrategyTable, model.ChunkStrategyParentChild:
+	default:
+		return fmt.Errorf("不支持的分块策略: %s（可选 general / auto / markdown / code / table / parent_child）", cs.Strategy)
+	}
+	if cs.Language != "" {
+		if _, ok := codeDeclPatterns[cs.Language]; !ok {
+			return fmt.Errorf("不支持的代码语言: %s", cs.Language)
+		}
+	}
+	if cs.ParentChunkSize < 0 {
+		return fmt.Errorf("父块大小不能为负数")
+	}
+	return nil
+}
+
+// resolveChunkStrategy 确定实际使用的分块策略；auto 按文件类型选择
+func resolveChunkStrategy(cs *model.ChunkSetting, fileName, fileType string) string {
+	if cs.Strategy != model.ChunkStrategyAuto {
+		if cs.Strategy == "" {
+			return model.ChunkStrategyGeneral
+		}
+		return cs.Strategy
+	}
+	switch {
+	case fileType == "md":
+		return model.ChunkStrategyMarkdown
+	case fileType == "csv" || fileType == "xlsx":
+		return model.ChunkStrategyTable
+	case codeLanguageOf(fileName) != "":
+		return model.ChunkStrategyCode
+	}
+	return model.ChunkStrategyGeneral
+}
+
+// chunkDocumentText 按分块设置切分文本。fileName 用于 auto 策略与代码语言识别，fileType 为 InferFileType 的结果。
+func chunkDocumentText(text string, cs *model.ChunkSetting, chunkSize, chunkOverlap int, fileName, fileType string) chunkResult {
+	if strings.TrimSpace(text) == "" {
+		return chunkResult{}
+	}
+	switch resolveChunkStrategy(cs, fileName, fileType) {
+	case model.ChunkStrategyMarkdown:
+		return splitMarkdown(text, chunkSize, chunkOverlap)
+	case model.ChunkStrategyCode:
+		lang := cs.Language
+		if lang == "" {
+			lang = codeLanguageOf(fileName)
+		}
+		return splitCode(text, lang, chunkSize, chunkOverlap)
+	case model.ChunkStrategyTable:
+		return splitTable(text, fileType, chunkSize, chunkOverlap)
+	case model.ChunkStrategyParentChild:
+		return splitParentChild(text, cs.ParentChunkSize, chunkSize, chunkOverlap)
+	}
+	if cs.Separator != "" {
+		return chunkResult{Chunks: splitBySeparator(text, cs.Separator, chunkSize, chunkOverlap)}
+	}
+	return chunkResult{Chunks: splitText(text, chunkSize, chunkOverlap)}
+}
+
+// withBreadcrumb 在分块内容前加上结构路径，使分块脱离上下文后仍可理解
+func withBreadcrumb(breadcrumb, content string) string {
+	if breadcrumb == "" {
+		return content
+	}
+	return breadcrumb + "\n\n" + content
+}
+
+// breadcrumbBudget 扣除结构路径后分块正文可用的长度（至少保留一半）
+func breadcrumbBudget(breadcrumb string, chunkSize int) int {
+	budget := chunkSize - runeLen(breadcrumb) - 2
+	if budget < chunkSize/2 {
+		budget = chunkSize / 2
+	}
+	if budget <= 0 {
+		budget = 1
+	}
+	return budget
+}
+
+// packBlocks 将不可再分的块依次合并为不超过 size 的分块；超长块交给 split 继续拆分
+func packBlocks(blocks []string, sep string, size int, split func(block string) []string) []string {
+	var result []string
+	var current []string
+	currentLen := 0
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, strings.Join(current, sep))
+			current, currentLen = nil, 0
+		}
+	}
+	for _, b := range blocks {
+		bLen := runeLen(b)
+		if bLen > size {
+			flush()
+			result = append(result, split(b)...)
+			continue
+		}
+		joinLen := currentLen + bLen
+		if len(current) > 0 {
+			joinLen += runeLen(sep)
+		}
+		if joinLen > size {
+			flush()
+		}
+		current = append(current, b)
+		currentLen += bLen
+		if len(current) > 1 {
+			currentLen += runeLen(sep)
+		}
+	}
+	flush()
+	return result
+}
+
+// splitLines 按行合并为不超过 size 的分块，超长行按字符切分
+func splitLines(text string, size, overlap int) []string {
+	return packBlocks(strings.Split(text, "\n"), "\n", size, func(line string) []string {
+		return charSplitWithOverlap(line, size, overlap)
+	})
+}
+
+// -----------------------------------------------
+// Markdown：按标题层级切分
+// -----------------------------------------------
+
+var (
+	mdHeadingPattern   = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	mdFencePattern     = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	mdTableSepPattern  = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+	mdTableLinePattern = regexp.MustCompile(`^\s*\|`)
+)
+
+type mdSection struct {
+	path  []string
+	lines []string
+}
+
+// splitMarkdown 按 ATX 标题（# ~ ######）切分章节，分块正文前附带“一级 > 二级 > 三级”标题路径；
+// 代码块与表格作为整体参与合并，超长时代码块按行拆分并补全围栏，表格按行拆分并重复表头。
+func splitMarkdown(text string, chunkSize, chunkOverlap int) chunkResult {
+	type heading struct {
+		level int
+		title string
+	}
+	var stack []heading
+	sections := []*mdSection{{}}
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+		} else if m := mdFencePattern.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+		} else if m := mdHeadingPattern.FindStringSubmatch(line); m != nil && m[2] != "" {
+			level := len(m[1])
+			for len(stack) > 0 && stack[len(stack)-1].level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, heading{level: level, title: m[2]})
+			path := make([]string, len(stack))
+			for i, h := range stack {
+				path[i] = h.title
+			}
+			sections = append(sections, &mdSection{path: path})
+			continue
+		}
+		cur := sections[len(sections)-1]
+		cur.lines = append(cur.lines, line)
+	}
+
+	var r chunkResult
+	for _, sec := range sections {
+		blocks := markdownBlocks(sec.lines)
+		if len(blocks) == 0 {
+			continue // 只有标题没有正文的章节，标题已体现在子章节的路径中
+		}
+		breadcrumb := strings.Join(sec.path, " > ")
+		budget := breadcrumbBudget(breadcrumb, chunkSize)
+		pieces := packBlocks(blocks, "\n\n", budget, func(block string) []string {
+			return splitMarkdownBlock(block, budget, chunkOverlap)
+		})
+		for _, piece := range pieces {
+			r.add(withBreadcrumb(breadcrumb, piece), breadcrumb)
+		}
+	}
+	return r
+}
+
+// markdownBlocks 将章节正文拆为段落、代码块与表格
+func markdownBlocks(lines []string) []string {
+	var blocks []string
+	var cur []string
+	flush := func() {
+		if b := strings.TrimSpace(strings.Join(cur, "\n")); b != "" {
+			blocks = append(blocks, b)
+		}
+		cur = nil
+	}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := mdFencePattern.FindStringSubmatch(line); m != nil {
+			flush()
+			cur = append(cur, line)
+			for i++; i < len(lines); i++ {
+				cur = append(cur, lines[i])
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]) {
+					break
+				}
+			}
+			flush()
+			continue
+		}
+		if mdTableLinePattern.MatchString(line) && i+1 < len(lines) && mdTableSepPattern.MatchString(lines[i+1]) {
+			flush()
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && mdTableLinePattern.MatchString(lines[i]); i++ {
+				cur = append(cur, lines[i])
+			}
+			i--
+			flush()
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		cur = append(cur, line)
+	}
+	flush()
+	return blocks
+}
+
+// splitMarkdownBlock 拆分超长的代码块 / 表格 / 段落
+func splitMarkdownBlock(block string, size, overlap int) []string {
+	lines := strings.Split(block, "\n")
+	if m := mdFencePattern.FindStringSubmatch(lines[0]); m != nil && len(lines) > 2 {
+		open, body := lines[0], lines[1:]
+		closing := ""
+		if last := strings.TrimSpace(body[len(body)-1]); strings.HasPrefix(last, m[1]) {
+			closing, body = body[len(body)-1], body[:len(body)-1]
+		}
+		inner := breadcrumbBudget(open+closing, size)
+		var result []string
+		for _, piece := range splitLines(strings.Join(body, "\n"), inner, overlap) {
+			result = append(result, open+"\n"+piece+"\n"+m[1])
+		}
+		return result
+	}
+	if len(lines) > 2 && mdTableSepPattern.MatchString(lines[1]) {
+		return splitRowsWithHeader(strings.Join(lines[:2], "\n"), lines[2:], size, overlap)
+	}
+	return recursiveSplit(block, fallbackSeparators, size, overlap)
+}
+
+// splitRowsWithHeader 按行合并表格，每个分块重复表头；单行超长时按字符切分
+func splitRowsWithHeader(header string, rows []string, size, overlap int) []string {
+	budget := breadcrumbBudget(header, size)
+	var result []string
+	for _, piece := range packBlocks(rows, "\n", budget, func(row string) []string {
+		return charSplitWithOverlap(row, budget, overlap)
+	}) {
+		result = append(result, header+"\n"+piece)
+	}
+	return result
+}
+
+// -----------------------------------------------
+// 代码：按函数 / 类切分
+// -----------------------------------------------
+
+// codeExtLanguages 文件扩展名 → 代码语言
+var codeExtLanguages = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".jsx": "javascript", ".mjs": "javascript",
+	".ts": "typescript", ".tsx": "typescript", ".java": "java", ".kt": "kotlin", ".cs": "csharp",
+	".rs": "rust", ".c": "c", ".h": "c", ".cpp": "cpp", ".cc": "cpp", ".hpp": "cpp",
+	".php": "php", ".rb": "ruby", ".sh": "shell", ".vue": "javascript",
+}
+
+// codeDeclPatterns 各语言的声明行（函数 / 方法 / 类 / 类型），匹配去掉缩进后的行
+var codeDeclPatterns = map[string]*regexp.Regexp{
+	"go":         regexp.MustCompile(`^(func|type)\s`),
+	"python":     regexp.MustCompile(`^(async\s+def|def|class)\s`),
+	"javascript": regexp.MustCompile(`^(export\s+(default\s+)?)?((async\s+)?function[\s*]|class\s|(const|let|var)\s+\w+\s*=\s*(async\s+)?(function|\([^)]*\)\s*=>|\w+\s*=>))|^(static\s+|async\s+|get\s+|set\s+)*[A-Za-z_$][\w$]*\s*\([^)]*\)\s*\{`),
+	"typescript": regexp.MustCompile(`^(export\s+(default\s+)?)?(declare\s+)?((async\s+)?function[\s*]|(abstract\s+)?class\s|interface\s|enum\s|type\s+\w+.*=|(const|let|var)\s+\w+(\s*:[^=]+)?\s*=\s*(async\s+)?(function|\([^)]*\)(\s*:[^=]+)?\s*=>|\w+\s*=>))|^((public|private|protected|static|readonly|async|abstract|get|set)\s+)*[A-Za-z_$][\w$]*\s*(<[^>]*>)?\([^)]*\)\s*(:[^{]+)?\{`),
+	"java":       regexp.MustCompile(`^(@\w+\s+)*((public|private|protected|static|final|abstract|synchronized|native|default)\s+)*((class|interface|enum|record)\s|[\w<>\[\],.?\s]+\s+\w+\s*\()`),
+	"kotlin":     regexp.MustCompile(`^((public|private|protected|internal|open|abstract|override|data|sealed|suspend|inline|enum)\s+)*(class|interface|object|fun)\s`),
+	"csharp":     regexp.MustCompile(`^((public|private|protected|internal|static|virtual|override|abstract|sealed|async|partial|readonly)\s+)*((class|interface|enum|struct|record)\s|[\w<>\[\],.?]+\s+\w+\s*(<[^>]*>)?\()`),
+	"rust":       regexp.MustCompile(`^(pub(\([^)]*\))?\s+)?((async|unsafe|const|extern\s+"\w+")\s+)*(fn|struct|enum|trait|impl|mod|macro_rules!)[\s<!]`),
+	"c":          regexp.MustCompile(`^(static\s+|inline\s+|extern\s+)*(struct\s+\w+\s*\{|[\w\s\*]+[\s\*]\w+\s*\([^;]*$)`),
+	"cpp":        regexp.MustCompile(`^(template\s*<.*>\s*)?((class|struct|namespace)\s+\w+[^;]*$|(static\s+|inline\s+|virtual\s+|extern\s+)*[\w:<>,\s\*&~]+[\s\*&:~]\w+\s*\([^;]*$)`),
+	"php":        regexp.MustCompile(`^((public|private|protected|static|abstract|final)\s+)*(function|class|interface|trait|enum)\s`),
+	"ruby":       regexp.MustCompile(`^(def|class|module)\s`),
+	"shell":      regexp.MustCompile(`^(function\s+[\w-]+|[\w-]+\s*\(\)\s*\{?)`),
+}
+
+// codeControlWords 形似方法声明的控制语句（if (...) { 等），不作为切分点
+var codeControlWords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true, "else": true,
+	"new": true, "throw": true, "do": true, "case": true, "await": true, "typeof": true, "delete": true,
+	"elif": true, "try": true, "foreach": true, "using": true, "lock": true, "yield": true,
+}
+
+// codeCommentPrefixes 声明前附属的注释与注解行
+var codeCommentPrefixes = []string{"//", "#", "/*", "*", "--", "@", "\"\"\"", "'''", "[", "///"}
+
+// codeLanguageOf 按文件扩展名识别代码语言，不是代码文件时返回空
+func codeLanguageOf(fileName string) string {
+	return codeExtLanguages[strings.ToLower(filepath.Ext(fileName))]
+}
+
+// maxCodeDepth 超长声明向内查找嵌套声明（类 → 方法）的最大层数
+const maxCodeDepth = 3
+
+// codePiece 代码片段 [start, end)，name 为声明签名（文件头部的导入等为空）
+type codePiece struct {
+	start, end int
+	decl       int
+	name       string
+}
+
+// splitCode 按声明切分代码：相邻的小声明合并为一个分块，超长的类 / 实现块向内按方法切分，
+// 方法内仍超长时按行切分。嵌套声明的分块前附带外层声明签名。
+func splitCode(text, lang string, chunkSize, chunkOverlap int) chunkResult {
+	lines := strings.Split(text, "\n")
+	var r chunkResult
+	emitCode(&r, lines, 0, 0, len(lines), lang, nil, chunkSize, chunkOverlap, 0)
+	return r
+}
+
+func emitCode(r *chunkResult, lines []string, start, searchFrom, end int, lang string, path []string, chunkSize, chunkOverlap, depth int) {
+	breadcrumb := strings.Join(path, " > ")
+	budget := breadcrumbBudget(breadcrumb, chunkSize)
+	pieces, _ := codePieces(lines, start, searchFrom, end, lang)
+
+	var group []codePiece
+	groupLen := 0
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		var names []string
+		for _, p := range group {
+			if p.name != "" {
+				names = append(names, p.name)
+			}
+		}
+		content := strings.Join(lines[group[0].start:group[len(group)-1].end], "\n")
+		r.add(withBreadcrumb(breadcrumb, content), joinPath(path, strings.Join(names, ", ")))
+		group, groupLen = nil, 0
+	}
+
+	for _, p := range pieces {
+		pLen := runeLen(strings.Join(lines[p.start:p.end], "\n"))
+		if pLen > budget {
+			flush()
+			if p.decl >= 0 && depth < maxCodeDepth {
+				if nested, ok := codePieces(lines, p.start, p.decl+1, p.end, lang); ok && len(nested) > 1 {
+					emitCode(r, lines, p.start, p.decl+1, p.end, lang, append(append([]string{}, path...), p.name), chunkSize, chunkOverlap, depth+1)
+					continue
+				}
+			}
+			for _, piece := range splitLines(strings.Join(lines[p.start:p.end], "\n"), budget, chunkOverlap) {
+				r.add(withBreadcrumb(breadcrumb, piece), joinPath(path, p.name))
+			}
+			continue
+		}
+		if len(group) > 0 && groupLen+pLen+1 > budget {
+			flush()
+		}
+		group = append(group, p)
+		groupLen += pLen + 1
+	}
+	flush()
+}
+
+// codePieces 在 [searchFrom, end) 内以缩进最小的声明行为界切分 [start, end)：第一个声明之前的内容
+// （导入、类签名与字段等）作为头部片段，声明前紧邻的注释 / 注解归入该声明。
+// 未找到声明时以空行后的顶格行为界切分，第二个返回值为 false。
+func codePieces(lines []string, start, searchFrom, end int, lang string) ([]codePiece, bool) {
+	pattern := codeDeclPatterns[lang]
+	if pattern == nil {
+		return blankLinePieces(lines, start, end), false
+	}
+	type decl struct{ line, indent int }
+	var decls []decl
+	minIndent := -1
+	for i := searchFrom; i < end; i++ {
+		trimmed := strings.TrimLeft(lines[i], " \t")
+		if trimmed == "" || !pattern.MatchString(trimmed) {
+			continue
+		}
+		words := strings.FieldsFunc(trimmed, func(c rune) bool { return c == ' ' || c == '\t' || c == '(' })
+		if len(words) > 0 && codeControlWords[words[0]] {
+			continue
+		}
+		indent := codeIndent(lines[i])
+		if minIndent < 0 || indent < minIndent {
+			minIndent = indent
+		}
+		decls = append(decls, decl{line: i, indent: indent})
+	}
+	if len(decls) == 0 {
+		return blankLinePieces(lines, start, end), false
+	}
+
+	var pieces []codePiece
+	prev := searchFrom
+	for _, d := range decls {
+		if d.indent != minIndent {
+			continue
+		}
+		from := d.line
+		for from > prev {
+			t := strings.TrimSpace(lines[from-1])
+			if t == "" || !hasAnyPrefix(t, codeCommentPrefixes) {
+				break
+			}
+			from--
+		}
+		pieces = append(pieces, codePiece{start: from, decl: d.line, name: codeSignature(lines[d.line])})
+		prev = d.line + 1
+	}
+	if pieces[0].start > start {
+		pieces = append([]codePiece{{start: start, decl: -1}}, pieces...)
+	}
+	result := make([]codePiece, 0, len(pieces))
+	for i := range pieces {
+		pieces[i].end = end
+		if i+1 < len(pieces) {
+			pieces[i].end = pieces[i+1].start
+		}
+		if strings.TrimSpace(strings.Join(lines[pieces[i].start:pieces[i].end], "\n")) != "" {
+			result = append(result, pieces[i])
+		}
+	}
+	return result, true
+}
+
+// blankLinePieces 没有可识别声明时，以顶格行前的空行为界切分（通用兜底）
+func blankLinePieces(lines []string, start, end int) []codePiece {
+	var pieces []codePiece
+	from := start
+	for i := start + 1; i < end; i++ {
+		if strings.TrimSpace(lines[i-1]) == "" && lines[i] != "" && codeIndent(lines[i]) == 0 {
+			pieces = append(pieces, codePiece{start: from, end: i, decl: -1})
+			from = i
+		}
+	}
+	pieces = append(pieces, codePiece{start: from, end: end, decl: -1})
+	return pieces
+}
+
+func codeIndent(line string) int {
+	n := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// codeSignature 声明签名：去掉函数体起始符号，过长时截断
+func codeSignature(line string) string {
+	sig := strings.TrimSpace(line)
+	sig = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(sig, "{"), ":"))
+	if runes := []rune(sig); len(runes) > 80 {
+		sig = string(runes[:80]) + "…"
+	}
+	return sig
+}
+
+// joinPath 拼接结构路径，忽略空项
+func joinPath(path []string, name string) string {
+	parts := make([]string, 0, len(path)+1)
+	for _, p := range append(append([]string{}, path...), name) {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------
+// 表格：按行切分并重复表头
+// -----------------------------------------------
+
+// splitTable 按行切分表格，分块不会切断行：
+//   - csv：首行作为表头，每个分块重复表头
+//   - Markdown 表格：表头与分隔行在每个分块重复
+//   - 其他文本（如 Excel 提取结果，每行已是“列名:值”形式）按行合并
+func splitTable(text, fileType string, chunkSize, chunkOverlap int) chunkResult {
+	var r chunkResult
+	if fileType == "csv" {
+		if header, rows, ok := parseCSVRows(text); ok {
+			for i, piece := range splitRowsWithHeader(header, rows, chunkSize, chunkOverlap) {
+				r.add(piece, fmt.Sprintf("第 %d 部分", i+1))
+			}
+			return r
+		}
+	}
+
+	lines := strings.Split(text, "\n")
+	var plain []string
+	flushPlain := func() {
+		if len(plain) == 0 {
+			return
+		}
+		for _, piece := range splitLines(strings.Join(plain, "\n"), chunkSize, chunkOverlap) {
+			r.add(piece, "")
+		}
+		plain = nil
+	}
+	for i := 0; i < len(lines); i++ {
+		if mdTableLinePattern.MatchString(lines[i]) && i+1 < len(lines) && mdTableSepPattern.MatchString(lines[i+1]) {
+			flushPlain()
+			header := lines[i] + "\n" + lines[i+1]
+			heading := tableColumns(lines[i])
+			var rows []string
+			for i += 2; i < len(lines) && mdTableLinePattern.MatchString(lines[i]); i++ {
+				rows = append(rows, lines[i])
+			}
+			i--
+			for _, piece := range splitRowsWithHeader(header, rows, chunkSize, chunkOverlap) {
+				r.add(piece, heading)
+			}
+			continue
+		}
+		if strings.TrimSpace(lines[i]) != "" {
+			plain = append(plain, lines[i])
+		}
+	}
+	flushPlain()
+	return r
+}
+
+// tableColumns Markdown 表头行的列名，作为分块的结构路径
+func tableColumns(headerLine string) string {
+	var cols []string
+	for _, c := range strings.Split(strings.Trim(strings.TrimSpace(headerLine), "|"), "|") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return strings.Join(cols, ", ")
+}
+
+// parseCSVRows 解析 csv，返回表头行与数据行（数据行按原样重新编码，含换行的单元格不会被拆开）
+func parseCSVRows(text string) (string, []string, bool) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil || len(records) < 2 {
+		return "", nil, false
+	}
+	encode := func(record []string) string {
+		var b strings.Builder
+		w := csv.NewWriter(&b)
+		w.Write(record)
+		w.Flush()
+		return strings.TrimRight(b.String(), "\n")
+	}
+	rows := make([]string, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		if !isEmptyRow(rec) {
+			rows = append(rows, encode(rec))
+		}
+	}
+	return encode(records[0]), rows, len(rows) > 0
+}
+
+// -----------------------------------------------
+// 父子分块：小块检索，返回父块
+// -----------------------------------------------
+
+var parentSeparators = []string{"\n\n", "\n", "。", ". ", " ", ""}
+
+// splitParentChild 先按段落切出父块，再把每个父块切成子块；子块用于向量化与检索，命中后返回父块全文。
+// parentSize 不大于子块大小时取子块大小的 4 倍。
+func splitParentChild(text string, parentSize, chunkSize, chunkOverlap int) chunkResult {
+	if parentSize <= chunkSize {
+		parentSize = chunkSize * 4
+	}
+	var r chunkResult
+	for _, parent := range recursiveSplit(text, parentSeparators, parentSize, 0) {
+		pi := len(r.Parents)
+		r.Parents = append(r.Parents, parent)
+		for _, child := range recursiveSplit(parent, parentSeparators, chunkSize, chunkOverlap) {
+			r.Chunks = append(r.Chunks, child)
+			r.ParentOf = append(r.ParentOf, pi)
+		}
+	}
+	return r
+}
+
+// segmentChunkMetadata 分块记录的元数据：结构路径，以及父子分块的父块下标与父块全文（检索命中后据此返回父块）
+func segmentChunkMetadata(r *chunkResult, i int) (*string, error) {
+	meta := map[string]interface{}{}
+	if heading := r.heading(i); heading != "" {
+		meta["heading"] = heading
+	}
+	if parent := r.parentOf(i); parent >= 0 {
+		meta["parent_index"] = parent
+		meta["parent_content"] = r.Parents[parent]
+	}
+	if len(meta) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	str := string(data)
+	return &str, nil
+}
+
+// segmentChunkMeta 分块记录的元数据
+type segmentChunkMeta struct {
+	Heading       string `json:"heading"`
+	ParentIndex   *int   `json:"parent_index"`
+	ParentContent string `json:"parent_content"`
+}
+
+// resolveChunkContext 为检索结果补充分块的结构路径（metadata.heading），并将父子分块的命中子块替换为父块全文：
+// 同一父块只保留排名最高的命中，子块原文保存在 metadata.child_content。
+func resolveChunkContext(results []*KnowledgeSearchResult) []*KnowledgeSearchResult {
+	docIDs := make([]int64, 0, len(results))
+	positions := make([]int, 0, len(results))
+	for _, r := range results {
+		if r.ContentType == "text" || r.ContentType == "" {
+			docIDs = append(docIDs, r.DocumentID)
+			positions = append(positions, r.ChunkIndex)
+		}
+	}
+	if len(docIDs) == 0 {
+		return results
+	}
+
+	var segments []model.TKnowledgeSegment
+	if err := svc.Ctx.DB.Select("document_id, position, metadata").
+		Where("document_id IN ? AND position IN ? AND metadata IS NOT NULL", docIDs, positions).
+		Find(&segments).Error; err != nil {
+		log.Printf("[WARN] 读取父块失败: %v", err)
+		return results
+	}
+	metas := make(map[string]segmentChunkMeta, len(segments))
+	for _, seg := range segments {
+		var m segmentChunkMeta
+		if seg.Metadata == nil || json.Unmarshal([]byte(*seg.Metadata), &m) != nil {
+			continue
+		}
+		metas[fmt.Sprintf("%d_%d", seg.DocumentID, seg.Position)] = m
+	}
+	if len(metas) == 0 {
+		return results
+	}
+
+	seen := make(map[string]bool)
+	expanded := make([]*KnowledgeSearchResult, 0, len(results))
+	for _, r := range results {
+		m, ok := metas[resultKey(r)]
+		if !ok {
+			expanded = append(expanded, r)
+			continue
+		}
+		if r.Metadata == nil {
+			r.Metadata = make(map[string]interface{})
+		}
+		if m.Heading != "" {
+			r.Metadata["heading"] = m.Heading
+		}
+		if m.ParentIndex == nil {
+			expanded = append(expanded, r)
+			continue
+		}
+		parentKey := fmt.Sprintf("%d_p%d", r.DocumentID, *m.ParentIndex)
+		if seen[parentKey] {
+			continue
+		}
+		seen[parentKey] = true
+		r.Metadata["child_content"] = r.Content
+		r.Metadata["parent_index"] = *m.ParentIndex
+		r.Content = m.ParentContent
+		r.WordCount = runeLen(m.ParentContent)
+		expanded = append(expanded, r)
+	}
+	return expanded
+}
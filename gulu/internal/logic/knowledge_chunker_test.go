@@ -0,0 +1,245 @@
+package logic
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"yqhp/gulu/internal/model"
+)
+
+// TestSplitMarkdown 按标题层级切分，分块携带标题路径，代码块中的 # 不视为标题
+func TestSplitMarkdown(t *testing.T) {
+	text := `前言段落
+
+# 安装
+## 依赖
+需要 Go 1.22。
+
+` + "```bash\n# 安装依赖\ngo mod download\n```" + `
+
+## 配置
+### 代理
+设置 HTTP_PROXY。
+
+| 参数 | 说明 |
+| --- | --- |
+| a | 1 |
+# 使用 #
+运行 gulu。`
+
+	r := splitMarkdown(text, 200, 0)
+	wantHeadings := []string{"", "安装 > 依赖", "安装 > 配置 > 代理", "使用"}
+	if len(r.Chunks) != len(wantHeadings) {
+		t.Fatalf("expected %d chunks, got %d: %q", len(wantHeadings), len(r.Chunks), r.Chunks)
+	}
+	for i, want := range wantHeadings {
+		if r.Headings[i] != want {
+			t.Errorf("heading[%d] = %q, want %q", i, r.Headings[i], want)
+		}
+		if want != "" && !strings.HasPrefix(r.Chunks[i], want+"\n\n") {
+			t.Errorf("chunk %d should start with breadcrumb: %q", i, r.Chunks[i])
+		}
+	}
+	if !strings.Contains(r.Chunks[1], "# 安装依赖\ngo mod download") {
+		t.Fatalf("code block should stay in its section: %q", r.Chunks[1])
+	}
+	if !strings.Contains(r.Chunks[2], "| a | 1 |") {
+		t.Fatalf("table should stay in its section: %q", r.Chunks[2])
+	}
+}
+
+// TestSplitMarkdownLargeBlocks 超长代码块按行拆分并补全围栏，超长表格每块重复表头
+func TestSplitMarkdownLargeBlocks(t *testing.T) {
+	var code, table strings.Builder
+	code.WriteString("# 示例\n```go\n")
+	table.WriteString("\n| 名称 | 值 |\n|---|---|\n")
+	for i := 0; i < 20; i++ {
+		code.WriteString("fmt.Println(\"line\")\n")
+		table.WriteString("| key | value |\n")
+	}
+	code.WriteString("```\n")
+
+	r := splitMarkdown(code.String()+table.String(), 120, 0)
+	if len(r.Chunks) < 4 {
+		t.Fatalf("expected large blocks to be split, got %d chunks", len(r.Chunks))
+	}
+	for _, c := range r.Chunks {
+		if runeLen(c) > 120 {
+			t.Errorf("chunk exceeds size: %d", runeLen(c))
+		}
+		body := strings.TrimPrefix(c, "示例\n\n")
+		switch {
+		case strings.HasPrefix(body, "```go\n"):
+			if !strings.HasSuffix(body, "\n```") {
+				t.Errorf("code piece should be fenced: %q", body)
+			}
+		case strings.HasPrefix(body, "| 名称 | 值 |\n|---|---|\n"):
+		default:
+			t.Errorf("unexpected piece: %q", body)
+		}
+	}
+}
+
+// TestSplitCode 按函数 / 类切分：小声明合并，超长类向内按方法切分并携带类签名，注释归入下方声明
+func TestSplitCode(t *testing.T) {
+	goSrc := `package demo
+
+import "fmt"
+
+// Hello 打招呼
+func Hello() {
+	fmt.Println("hello")
+}
+
+// World 世界
+func World() {
+	fmt.Println("world")
+}
+`
+	r := splitCode(goSrc, "go", 60, 0)
+	if len(r.Chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d: %q", len(r.Chunks), r.Chunks)
+	}
+	if !strings.HasPrefix(r.Chunks[1], "// Hello 打招呼\nfunc Hello()") || r.Headings[1] != "func Hello()" {
+		t.Fatalf("doc comment should belong to its function: %q (%q)", r.Chunks[1], r.Headings[1])
+	}
+
+	pySrc := `class Greeter:
+    """问候"""
+
+    def __init__(self, name):
+        self.name = name
+
+    def greet(self):
+        if self.name:
+            return "hi " + self.name
+        return "hi"
+
+    @staticmethod
+    def version():
+        return "1.0"
+`
+	r = splitCode(pySrc, "python", 80, 0)
+	if len(r.Chunks) < 3 {
+		t.Fatalf("expected class to be split by methods, got %q", r.Chunks)
+	}
+	last := r.Chunks[len(r.Chunks)-1]
+	if !strings.HasPrefix(last, "class Greeter\n\n") || !strings.Contains(last, "@staticmethod\n    def version():") {
+		t.Fatalf("method chunk should carry class breadcrumb and decorator: %q", last)
+	}
+	if h := r.Headings[len(r.Headings)-1]; h != "class Greeter > def version()" {
+		t.Fatalf("unexpected heading %q", h)
+	}
+	for _, c := range r.Chunks {
+		if strings.Contains(c, "if self.name:") && !strings.Contains(c, "def greet(self)") {
+			t.Fatalf("if statement must not start a chunk: %q", c)
+		}
+	}
+
+	if codeLanguageOf("src/App.TSX") != "typescript" || codeLanguageOf("README.md") != "" {
+		t.Fatal("unexpected language detection")
+	}
+}
+
+// TestSplitTable csv 与 Markdown 表格按行切分并重复表头，Excel 提取文本不切断行
+func TestSplitTable(t *testing.T) {
+	csvText := "name,desc\nalpha,\"多行\n描述\"\nbeta,b\ngamma,c\ndelta,d\n"
+	r := splitTable(csvText, "csv", 30, 0)
+	if len(r.Chunks) < 2 {
+		t.Fatalf("expected csv to be split, got %q", r.Chunks)
+	}
+	for _, c := range r.Chunks {
+		if !strings.HasPrefix(c, "name,desc\n") {
+			t.Errorf("csv chunk should repeat header: %q", c)
+		}
+	}
+	if !strings.Contains(r.Chunks[0], "alpha,\"多行\n描述\"") {
+		t.Fatalf("quoted cell should stay intact: %q", r.Chunks[0])
+	}
+
+	var excel strings.Builder
+	for i := 0; i < 10; i++ {
+		excel.WriteString(`"用例":"登录成功";"预期":"跳转首页"` + "\n")
+	}
+	r = splitTable(excel.String(), "xlsx", 70, 10)
+	for _, c := range r.Chunks {
+		for _, line := range strings.Split(c, "\n") {
+			if line != `"用例":"登录成功";"预期":"跳转首页"` {
+				t.Fatalf("row was cut: %q", line)
+			}
+		}
+	}
+
+	md := "说明文字\n\n| 接口 | 方法 |\n| --- | --- |\n| /a | GET |\n| /b | POST |\n| /c | PUT |\n"
+	r = splitTable(md, "md", 40, 0)
+	if r.Chunks[0] != "说明文字" || r.Headings[1] != "接口, 方法" {
+		t.Fatalf("unexpected chunks: %q %q", r.Chunks, r.Headings)
+	}
+	for _, c := range r.Chunks[1:] {
+		if !strings.HasPrefix(c, "| 接口 | 方法 |\n| --- | --- |\n") {
+			t.Errorf("table chunk should repeat header: %q", c)
+		}
+	}
+}
+
+// TestSplitParentChild 子块按父块归属，父块默认为子块大小的 4 倍
+func TestSplitParentChild(t *testing.T) {
+	paragraph := strings.Repeat("知识库检索测试。", 10) // 80 字
+	text := strings.Join([]string{paragraph, paragraph, paragraph}, "\n\n")
+
+	r := splitParentChild(text, 0, 45, 0)
+	if len(r.Parents) != 2 {
+		t.Fatalf("expected 2 parents, got %d", len(r.Parents))
+	}
+	if len(r.ParentOf) != len(r.Chunks) || len(r.Chunks) != 6 {
+		t.Fatalf("unexpected children: %d chunks, %d parent refs", len(r.Chunks), len(r.ParentOf))
+	}
+	for i, c := range r.Chunks {
+		if runeLen(c) > 45 || !strings.Contains(r.Parents[r.ParentOf[i]], c) {
+			t.Fatalf("child %d not contained in its parent: %q", i, c)
+		}
+	}
+
+	meta, err := segmentChunkMetadata(&r, 5)
+	if err != nil || meta == nil {
+		t.Fatalf("expected metadata: %v", err)
+	}
+	var m segmentChunkMeta
+	if err := json.Unmarshal([]byte(*meta), &m); err != nil || m.ParentIndex == nil || *m.ParentIndex != 1 || m.ParentContent != r.Parents[1] {
+		t.Fatalf("unexpected metadata %s: %v", *meta, err)
+	}
+	if meta, _ := segmentChunkMetadata(&chunkResult{Chunks: []string{"a"}}, 0); meta != nil {
+		t.Fatal("general chunks should have no metadata")
+	}
+}
+
+// TestChunkDocumentText auto 策略按文件类型选择，未知策略校验失败
+func TestChunkDocumentText(t *testing.T) {
+	auto := &model.ChunkSetting{Strategy: model.ChunkStrategyAuto}
+	cases := []struct {
+		name, fileType, want string
+	}{
+		{"guide.md", "md", model.ChunkStrategyMarkdown},
+		{"cases.xlsx", "xlsx", model.ChunkStrategyTable},
+		{"main.go", "txt", model.ChunkStrategyCode},
+		{"notes.txt", "txt", model.ChunkStrategyGeneral},
+	}
+	for _, c := range cases {
+		if got := resolveChunkStrategy(auto, c.name, c.fileType); got != c.want {
+			t.Errorf("resolveChunkStrategy(%s) = %s, want %s", c.name, got, c.want)
+		}
+	}
+
+	general := chunkDocumentText("a\n\nb", model.DefaultChunkSetting(), 500, 50, "x.md", "md")
+	if len(general.Chunks) != 2 || general.Headings != nil {
+		t.Fatalf("default setting should keep separator splitting: %+v", general)
+	}
+
+	if err := validateChunkSetting(&model.ChunkSetting{Strategy: "semantic"}); err == nil {
+		t.Fatal("expected unknown strategy to fail")
+	}
+	if err := validateChunkSetting(&model.ChunkSetting{Strategy: model.ChunkStrategyCode, Language: "cobol"}); err == nil {
+		t.Fatal("expected unknown language to fail")
+	}
+}
@@ -26,6 +26,7 @@ type EmbeddingClient struct {
 	Model          string
 	Timeout        time.Duration
 	SupportInputType bool // 是否在 API 请求中发送 input_type 字段（Qwen3-Embedding 等模型需要显式启用）
+	BatchSize        int  // 文本 Embedding 单次请求的最大条数
 }
 
 // NewEmbeddingClient 创建嵌入模型客户端
@@ -39,6 +40,7 @@ func NewEmbeddingClient(baseURL, apiKey, model string) *EmbeddingClient {
 		Model:            model,
 		Timeout:          60 * time.Second,
 		SupportInputType: false, // 默认不发送，避免破坏不支持该字段的本地部署
+		BatchSize:        20,
 	}
 }
 
@@ -121,7 +123,10 @@ func (c *EmbeddingClient) embedTextsWithPurpose(ctx context.Context, texts []str
 		return nil, nil
 	}
 
-	batchSize := 20
+	batchSize := c.BatchSize
+	if batchSize <= 0 {
+		batchSize = 20
+	}
 	var allEmbeddings [][]float32
 
 	for i := 0; i < len(texts); i += batchSize {
@@ -214,6 +219,11 @@ func (c *EmbeddingClient) EmbedMultimodal(ctx context.Context, inputs []Multimod
 
 // doHTTPPost 发送 JSON POST 请求并返回响应体（提取公共 HTTP 逻辑）
 func (c *EmbeddingClient) doHTTPPost(ctx context.Context, url string, reqBody interface{}, timeout time.Duration) ([]byte, error) {
+	return postJSON(ctx, url, c.APIKey, reqBody, timeout)
+}
+
+// postJSON 发送带 Bearer 认证的 JSON POST 请求，非 200 响应返回错误（Embedding / Rerank 共用）
+func postJSON(ctx context.Context, url, apiKey string, reqBody interface{}, timeout time.Duration) ([]byte, error) {
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("请求序列化失败: %w", err)
@@ -223,8 +233,8 @@ func (c *EmbeddingClient) doHTTPPost(ctx context.Context, url string, reqBody in
 		return nil, fmt.Errorf("创建 HTTP 请求失败: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	if c.APIKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
 	}
 	resp, err := (&http.Client{Timeout: timeout}).Do(httpReq)
 	if err != nil {
@@ -236,11 +246,21 @@ func (c *EmbeddingClient) doHTTPPost(ctx context.Context, url string, reqBody in
 		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API 返回错误 (HTTP %d): %s", resp.StatusCode, string(respBody))
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 	return respBody, nil
 }
 
+// HTTPStatusError 模型 API 返回非 200 状态码
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("API 返回错误 (HTTP %d): %s", e.StatusCode, e.Body)
+}
+
 // callMultimodalEmbeddingAPI 调用多模态 Embedding API
 func (c *EmbeddingClient) callMultimodalEmbeddingAPI(ctx context.Context, inputs []MultimodalInput) ([][]float32, error) {
 	inputItems := make([]map[string]string, len(inputs))
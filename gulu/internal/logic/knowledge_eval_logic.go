@@ -0,0 +1,584 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+)
+
+// -----------------------------------------------
+// 检索评测：评测集与题目
+// -----------------------------------------------
+
+const (
+	evalGenerateDefault    = 10
+	evalGenerateMax        = 50
+	evalGenerateWorkers    = 4
+	evalGenerateMinWords   = 30   // 过短的分块不适合出题
+	evalTargetSnapshotSize = 2000 // 期望分块内容快照的最大字数
+)
+
+const evalQuestionPrompt = `你是一名知识库检索评测数据标注员。请阅读下面的文档片段，提出一个用户可能会问、且只依据该片段就能回答的问题。
+
+要求：
+1. 问题要像真实用户的提问，不要照抄原文中的句子，不要出现"本文"、"该片段"等指代
+2. 问题必须能由片段内容明确回答
+3. 同时给出基于片段的简短参考答案
+
+只返回 JSON：{"question": "问题", "answer": "参考答案"}
+
+文档片段：
+%s`
+
+// EvalDatasetReq 创建 / 更新评测集请求
+type EvalDatasetReq struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// EvalDatasetInfo 评测集信息
+type EvalDatasetInfo struct {
+	ID             int64      `json:"id"`
+	CreatedAt      *time.Time `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	ItemCount      int64      `json:"item_count"`      // 参与评测的题目数
+	CandidateCount int64      `json:"candidate_count"` // 待确认的候选题目数
+}
+
+// EvalItemReq 评测题目；期望分块与期望文档至少指定一个
+type EvalItemReq struct {
+	Question            string  `json:"question"`
+	ExpectedSegmentIDs  []int64 `json:"expected_segment_ids"`
+	ExpectedDocumentIDs []int64 `json:"expected_document_ids"`
+	ReferenceAnswer     string  `json:"reference_answer"`
+	Status              string  `json:"status"` // active（默认）/ candidate
+}
+
+// CreateEvalItemsReq 批量添加评测题目请求
+type CreateEvalItemsReq struct {
+	Items []EvalItemReq `json:"items"`
+}
+
+// EvalItemListReq 评测题目列表请求
+type EvalItemListReq struct {
+	Page     int    `query:"page"`
+	PageSize int    `query:"pageSize"`
+	Status   string `query:"status"`
+}
+
+// EvalItemInfo 评测题目信息
+type EvalItemInfo struct {
+	ID              int64              `json:"id"`
+	CreatedAt       *time.Time         `json:"created_at"`
+	UpdatedAt       *time.Time         `json:"updated_at"`
+	DatasetID       int64              `json:"dataset_id"`
+	Question        string             `json:"question"`
+	Expected        []model.EvalTarget `json:"expected"`
+	ReferenceAnswer string             `json:"reference_answer"`
+	Status          string             `json:"status"`
+	Source          string             `json:"source"`
+}
+
+// GenerateEvalItemsReq LLM 生成候选题目请求
+type GenerateEvalItemsReq struct {
+	ModelID     int64   `json:"model_id"`
+	Count       int     `json:"count"`        // 默认 10，最多 50
+	DocumentIDs []int64 `json:"document_ids"` // 为空时从整个知识库抽样
+}
+
+// GenerateEvalItemsResult LLM 生成结果
+type GenerateEvalItemsResult struct {
+	Created int `json:"created"`
+	Failed  int `json:"failed"`
+}
+
+// ListEvalDatasets 获取知识库的评测集列表
+func (l *KnowledgeBaseLogic) ListEvalDatasets(kbID int64) ([]*EvalDatasetInfo, error) {
+	db := svc.Ctx.DB
+	var datasets []model.TKnowledgeEvalDataset
+	if err := db.Where("knowledge_base_id = ? AND is_delete = 0", kbID).Order("id DESC").Find(&datasets).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(datasets))
+	for i, d := range datasets {
+		ids[i] = d.ID
+	}
+
+	type countRow struct {
+		DatasetID int64
+		Status    string
+		Cnt       int64
+	}
+	var rows []countRow
+	if len(ids) > 0 {
+		db.Model(&model.TKnowledgeEvalItem{}).Select("dataset_id, status, COUNT(*) AS cnt").
+			Where("dataset_id IN ?", ids).Group("dataset_id, status").Scan(&rows)
+	}
+	counts := make(map[int64]map[string]int64)
+	for _, r := range rows {
+		if counts[r.DatasetID] == nil {
+			counts[r.DatasetID] = make(map[string]int64)
+		}
+		counts[r.DatasetID][r.Status] = r.Cnt
+	}
+
+	result := make([]*EvalDatasetInfo, 0, len(datasets))
+	for _, d := range datasets {
+		result = append(result, &EvalDatasetInfo{
+			ID:             d.ID,
+			CreatedAt:      d.CreatedAt,
+			UpdatedAt:      d.UpdatedAt,
+			Name:           d.Name,
+			Description:    derefString(d.Description),
+			ItemCount:      counts[d.ID][model.EvalItemActive],
+			CandidateCount: counts[d.ID][model.EvalItemCandidate],
+		})
+	}
+	return result, nil
+}
+
+// CreateEvalDataset 创建评测集
+func (l *KnowledgeBaseLogic) CreateEvalDataset(kbID int64, req *EvalDatasetReq, userID int64) (*model.TKnowledgeEvalDataset, error) {
+	db := svc.Ctx.DB
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("评测集名称不能为空")
+	}
+	var count int64
+	db.Model(&model.TKnowledgeBase{}).Where("id = ? AND is_delete = 0", kbID).Count(&count)
+	if count == 0 {
+		return nil, errors.New("知识库不存在")
+	}
+
+	now := time.Now()
+	isDelete := false
+	dataset := &model.TKnowledgeEvalDataset{
+		CreatedAt:       &now,
+		UpdatedAt:       &now,
+		IsDelete:        &isDelete,
+		CreatedBy:       &userID,
+		KnowledgeBaseID: kbID,
+		Name:            strings.TrimSpace(req.Name),
+		Description:     &req.Description,
+	}
+	if err := db.Create(dataset).Error; err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// UpdateEvalDataset 更新评测集
+func (l *KnowledgeBaseLogic) UpdateEvalDataset(kbID, datasetID int64, req *EvalDatasetReq) error {
+	if _, err := l.getEvalDataset(kbID, datasetID); err != nil {
+		return err
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return errors.New("评测集名称不能为空")
+	}
+	return svc.Ctx.DB.Model(&model.TKnowledgeEvalDataset{}).Where("id = ?", datasetID).Updates(map[string]interface{}{
+		"name":        strings.TrimSpace(req.Name),
+		"description": req.Description,
+		"updated_at":  time.Now(),
+	}).Error
+}
+
+// DeleteEvalDataset 删除评测集及其题目、运行记录
+func (l *KnowledgeBaseLogic) DeleteEvalDataset(kbID, datasetID int64) error {
+	if _, err := l.getEvalDataset(kbID, datasetID); err != nil {
+		return err
+	}
+	db := svc.Ctx.DB
+	if err := db.Model(&model.TKnowledgeEvalDataset{}).Where("id = ?", datasetID).Updates(map[string]interface{}{
+		"is_delete":  true,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	var runIDs []int64
+	db.Model(&model.TKnowledgeEvalRun{}).Where("dataset_id = ?", datasetID).Pluck("id", &runIDs)
+	if len(runIDs) > 0 {
+		db.Where("run_id IN ?", runIDs).Delete(&model.TKnowledgeEvalResult{})
+	}
+	db.Where("dataset_id = ?", datasetID).Delete(&model.TKnowledgeEvalRun{})
+	db.Where("dataset_id = ?", datasetID).Delete(&model.TKnowledgeEvalItem{})
+	return nil
+}
+
+func (l *KnowledgeBaseLogic) getEvalDataset(kbID, datasetID int64) (*model.TKnowledgeEvalDataset, error) {
+	var dataset model.TKnowledgeEvalDataset
+	if err := svc.Ctx.DB.Where("id = ? AND knowledge_base_id = ? AND is_delete = 0", datasetID, kbID).First(&dataset).Error; err != nil {
+		return nil, errors.New("评测集不存在")
+	}
+	return &dataset, nil
+}
+
+// ListEvalItems 获取评测题目列表
+func (l *KnowledgeBaseLogic) ListEvalItems(kbID, datasetID int64, req *EvalItemListReq) ([]*EvalItemInfo, int64, error) {
+	if _, err := l.getEvalDataset(kbID, datasetID); err != nil {
+		return nil, 0, err
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+
+	q := svc.Ctx.DB.Model(&model.TKnowledgeEvalItem{}).Where("dataset_id = ?", datasetID)
+	if req.Status != "" {
+		q = q.Where("status = ?", req.Status)
+	}
+	var total int64
+	q.Count(&total)
+
+	var items []model.TKnowledgeEvalItem
+	offset := (req.Page - 1) * req.PageSize
+	if err := q.Order("id ASC").Offset(offset).Limit(req.PageSize).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	result := make([]*EvalItemInfo, 0, len(items))
+	for i := range items {
+		result = append(result, toEvalItemInfo(&items[i]))
+	}
+	return result, total, nil
+}
+
+// CreateEvalItems 批量添加评测题目
+func (l *KnowledgeBaseLogic) CreateEvalItems(kbID, datasetID int64, req *CreateEvalItemsReq) ([]*EvalItemInfo, error) {
+	if _, err := l.getEvalDataset(kbID, datasetID); err != nil {
+		return nil, err
+	}
+	if len(req.Items) == 0 {
+		return nil, errors.New("题目不能为空")
+	}
+
+	now := time.Now()
+	rows := make([]*model.TKnowledgeEvalItem, 0, len(req.Items))
+	for i, item := range req.Items {
+		row, err := l.buildEvalItem(kbID, &item)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 题: %w", i+1, err)
+		}
+		row.CreatedAt, row.UpdatedAt = &now, &now
+		row.KnowledgeBaseID, row.DatasetID = kbID, datasetID
+		row.Source = model.EvalItemSourceManual
+		rows = append(rows, row)
+	}
+	if err := svc.Ctx.DB.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*EvalItemInfo, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, toEvalItemInfo(row))
+	}
+	return result, nil
+}
+
+// UpdateEvalItem 更新评测题目（确认候选题目时将 status 改为 active）
+func (l *KnowledgeBaseLogic) UpdateEvalItem(kbID, itemID int64, req *EvalItemReq) (*EvalItemInfo, error) {
+	db := svc.Ctx.DB
+	var item model.TKnowledgeEvalItem
+	if err := db.Where("id = ? AND knowledge_base_id = ?", itemID, kbID).First(&item).Error; err != nil {
+		return nil, errors.New("评测题目不存在")
+	}
+
+	// 未指定期望目标时保留原有目标
+	if len(req.ExpectedSegmentIDs) == 0 && len(req.ExpectedDocumentIDs) == 0 {
+		for _, t := range item.GetExpected() {
+			if t.SegmentID > 0 {
+				req.ExpectedSegmentIDs = append(req.ExpectedSegmentIDs, t.SegmentID)
+			} else {
+				req.ExpectedDocumentIDs = append(req.ExpectedDocumentIDs, t.DocumentID)
+			}
+		}
+	}
+	if req.Status == "" {
+		req.Status = item.Status
+	}
+	row, err := l.buildEvalItem(kbID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := db.Model(&item).Updates(map[string]interface{}{
+		"question":         row.Question,
+		"expected":         row.Expected,
+		"reference_answer": row.ReferenceAnswer,
+		"status":           row.Status,
+		"updated_at":       now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	item.Question, item.Expected, item.ReferenceAnswer, item.Status, item.UpdatedAt = row.Question, row.Expected, row.ReferenceAnswer, row.Status, &now
+	return toEvalItemInfo(&item), nil
+}
+
+// DeleteEvalItem 删除评测题目
+func (l *KnowledgeBaseLogic) DeleteEvalItem(kbID, itemID int64) error {
+	res := svc.Ctx.DB.Where("id = ? AND knowledge_base_id = ?", itemID, kbID).Delete(&model.TKnowledgeEvalItem{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("评测题目不存在")
+	}
+	return nil
+}
+
+// buildEvalItem 校验题目并解析期望目标
+func (l *KnowledgeBaseLogic) buildEvalItem(kbID int64, req *EvalItemReq) (*model.TKnowledgeEvalItem, error) {
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		return nil, errors.New("问题不能为空")
+	}
+	status := req.Status
+	if status == "" {
+		status = model.EvalItemActive
+	}
+	if status != model.EvalItemActive && status != model.EvalItemCandidate {
+		return nil, fmt.Errorf("不支持的题目状态: %s", status)
+	}
+	targets, err := buildEvalTargets(kbID, req.ExpectedSegmentIDs, req.ExpectedDocumentIDs)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(targets)
+	if err != nil {
+		return nil, err
+	}
+	expected := string(data)
+	item := &model.TKnowledgeEvalItem{
+		Question: question,
+		Expected: &expected,
+		Status:   status,
+	}
+	if req.ReferenceAnswer != "" {
+		item.ReferenceAnswer = &req.ReferenceAnswer
+	}
+	return item, nil
+}
+
+// buildEvalTargets 校验期望分块 / 文档属于该知识库，并为分块记录内容快照
+func buildEvalTargets(kbID int64, segIDs, docIDs []int64) ([]model.EvalTarget, error) {
+	db := svc.Ctx.DB
+	var targets []model.EvalTarget
+	seenSeg := make(map[int64]bool)
+	seenDoc := make(map[int64]bool)
+
+	if len(segIDs) > 0 {
+		var segments []model.TKnowledgeSegment
+		if err := db.Select("id, document_id, content").Where("id IN ? AND knowledge_base_id = ?", segIDs, kbID).Find(&segments).Error; err != nil {
+			return nil, err
+		}
+		byID := make(map[int64]*model.TKnowledgeSegment, len(segments))
+		for i := range segments {
+			byID[segments[i].ID] = &segments[i]
+		}
+		for _, id := range segIDs {
+			seg, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("分块 %d 不存在", id)
+			}
+			if seenSeg[id] {
+				continue
+			}
+			seenSeg[id] = true
+			targets = append(targets, model.EvalTarget{
+				DocumentID: seg.DocumentID,
+				SegmentID:  seg.ID,
+				Content:    evalSnapshot(seg.Content),
+			})
+		}
+	}
+
+	if len(docIDs) > 0 {
+		var existing []int64
+		if err := db.Model(&model.TKnowledgeDocument{}).Where("id IN ? AND knowledge_base_id = ?", docIDs, kbID).Pluck("id", &existing).Error; err != nil {
+			return nil, err
+		}
+		found := make(map[int64]bool, len(existing))
+		for _, id := range existing {
+			found[id] = true
+		}
+		for _, id := range docIDs {
+			if !found[id] {
+				return nil, fmt.Errorf("文档 %d 不存在", id)
+			}
+			if seenDoc[id] {
+				continue
+			}
+			seenDoc[id] = true
+			targets = append(targets, model.EvalTarget{DocumentID: id})
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.New("请指定期望命中的分块或文档")
+	}
+	return targets, nil
+}
+
+// evalSnapshot 截取分块内容快照
+func evalSnapshot(content string) string {
+	runes := []rune(content)
+	if len(runes) > evalTargetSnapshotSize {
+		runes = runes[:evalTargetSnapshotSize]
+	}
+	return string(runes)
+}
+
+func toEvalItemInfo(item *model.TKnowledgeEvalItem) *EvalItemInfo {
+	return &EvalItemInfo{
+		ID:              item.ID,
+		CreatedAt:       item.CreatedAt,
+		UpdatedAt:       item.UpdatedAt,
+		DatasetID:       item.DatasetID,
+		Question:        item.Question,
+		Expected:        item.GetExpected(),
+		ReferenceAnswer: derefString(item.ReferenceAnswer),
+		Status:          item.Status,
+		Source:          item.Source,
+	}
+}
+
+// GenerateEvalItems 从知识库抽样分块，由 LLM 为每个分块生成问题，作为候选题目（需人工确认）
+func (l *KnowledgeBaseLogic) GenerateEvalItems(kbID, datasetID int64, req *GenerateEvalItemsReq) (*GenerateEvalItemsResult, error) {
+	if _, err := l.getEvalDataset(kbID, datasetID); err != nil {
+		return nil, err
+	}
+	if req.ModelID == 0 {
+		return nil, errors.New("请选择用于生成问题的模型")
+	}
+	count := req.Count
+	if count <= 0 {
+		count = evalGenerateDefault
+	}
+	if count > evalGenerateMax {
+		count = evalGenerateMax
+	}
+	llm, err := NewAiModelLogic(l.ctx).GetByIDWithKey(req.ModelID)
+	if err != nil {
+		return nil, fmt.Errorf("获取模型失败: %w", err)
+	}
+
+	db := svc.Ctx.DB
+	// 排除评测集中已作为期望目标的分块
+	var existing []model.TKnowledgeEvalItem
+	db.Select("expected").Where("dataset_id = ?", datasetID).Find(&existing)
+	used := make(map[int64]bool)
+	for _, item := range existing {
+		for _, t := range item.GetExpected() {
+			used[t.SegmentID] = true
+		}
+	}
+
+	q := db.Select("id, document_id, content").
+		Where("knowledge_base_id = ? AND enabled = 1 AND content_type = ? AND word_count >= ?", kbID, "text", evalGenerateMinWords)
+	if len(req.DocumentIDs) > 0 {
+		q = q.Where("document_id IN ?", req.DocumentIDs)
+	}
+	var segments []model.TKnowledgeSegment
+	if err := q.Find(&segments).Error; err != nil {
+		return nil, err
+	}
+	candidates := segments[:0]
+	for _, seg := range segments {
+		if !used[seg.ID] {
+			candidates = append(candidates, seg)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("没有可用于生成问题的分块")
+	}
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+
+	type generated struct {
+		Question string `json:"question"`
+		Answer   string `json:"answer"`
+	}
+	var (
+		mu     sync.Mutex
+		rows   []*model.TKnowledgeEvalItem
+		failed int
+		wg     sync.WaitGroup
+	)
+	jobs := make(chan *model.TKnowledgeSegment)
+	for w := 0; w < evalGenerateWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seg := range jobs {
+				var g generated
+				ctx, cancel := context.WithTimeout(l.ctx, 2*time.Minute)
+				content, err := chatCompletionJSON(ctx, llm.APIBaseURL, llm.APIKey, llm.ModelID, fmt.Sprintf(evalQuestionPrompt, seg.Content), 0.7, 1024)
+				cancel()
+				if err == nil {
+					err = json.Unmarshal([]byte(content), &g)
+				}
+				if err == nil && strings.TrimSpace(g.Question) == "" {
+					err = errors.New("模型未返回问题")
+				}
+
+				mu.Lock()
+				if err != nil {
+					failed++
+				} else {
+					rows = append(rows, generatedEvalItem(kbID, datasetID, seg, strings.TrimSpace(g.Question), strings.TrimSpace(g.Answer)))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range candidates {
+		jobs <- &candidates[i]
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(rows) > 0 {
+		if err := db.Create(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &GenerateEvalItemsResult{Created: len(rows), Failed: failed}, nil
+}
+
+func generatedEvalItem(kbID, datasetID int64, seg *model.TKnowledgeSegment, question, answer string) *model.TKnowledgeEvalItem {
+	target := model.EvalTarget{
+		DocumentID: seg.DocumentID,
+		SegmentID:  seg.ID,
+		Content:    evalSnapshot(seg.Content),
+	}
+	data, _ := json.Marshal([]model.EvalTarget{target})
+	expected := string(data)
+	now := time.Now()
+	item := &model.TKnowledgeEvalItem{
+		CreatedAt:       &now,
+		UpdatedAt:       &now,
+		KnowledgeBaseID: kbID,
+		DatasetID:       datasetID,
+		Question:        question,
+		Expected:        &expected,
+		Status:          model.EvalItemCandidate,
+		Source:          model.EvalItemSourceGenerated,
+	}
+	if answer != "" {
+		item.ReferenceAnswer = &answer
+	}
+	return item
+}
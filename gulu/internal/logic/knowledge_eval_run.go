@@ -0,0 +1,740 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+)
+
+// -----------------------------------------------
+// 检索评测：运行、指标、对比
+// -----------------------------------------------
+
+// evalContentOverlap 期望分块已不存在（文档重新分块）时，召回内容与分块快照的重合度达到该值视为命中
+const evalContentOverlap = 0.6
+
+// evalRecallCutoffs 额外统计的 recall@n（不超过运行的 Top-K）
+var evalRecallCutoffs = []int{1, 3, 5, 10}
+
+// CreateEvalRunReq 创建评测运行请求
+type CreateEvalRunReq struct {
+	DatasetID int64              `json:"dataset_id"`
+	Name      string             `json:"name"`
+	Search    KnowledgeSearchReq `json:"search"` // 检索参数（query 忽略），未指定的参数使用知识库配置
+}
+
+// EvalRunConfig 评测运行的配置快照：实际生效的检索参数 + 运行时的索引配置，用于对比不同运行
+type EvalRunConfig struct {
+	Search           *searchParams  `json:"search"`
+	EmbeddingModelID int64          `json:"embedding_model_id"`
+	EmbeddingModel   string         `json:"embedding_model"`
+	VectorStore      string         `json:"vector_store"`
+	ChunkSize        int            `json:"chunk_size"`
+	ChunkOverlap     int            `json:"chunk_overlap"`
+	ChunkStrategies  map[string]int `json:"chunk_strategies"` // 各分块策略的文档数
+	DocumentCount    int64          `json:"document_count"`
+	SegmentCount     int64          `json:"segment_count"`
+}
+
+// EvalMetrics 评测汇总指标（各题平均）
+type EvalMetrics struct {
+	K            int                `json:"k"`
+	Recall       float64            `json:"recall"`    // recall@k
+	HitRate      float64            `json:"hit_rate"`  // 至少命中一个期望目标的题目比例
+	MRR          float64            `json:"mrr"`       // 第一个命中结果排名倒数的平均
+	NDCG         float64            `json:"ndcg"`      // nDCG@k（二值相关度）
+	RecallAt     map[string]float64 `json:"recall_at"` // recall@1 / @3 / @5 / @10
+	LatencyAvgMs float64            `json:"latency_avg_ms"`
+	LatencyP50Ms int64              `json:"latency_p50_ms"`
+	LatencyP95Ms int64              `json:"latency_p95_ms"`
+	Evaluated    int                `json:"evaluated"`
+	Failed       int                `json:"failed"`
+}
+
+// EvalRunInfo 评测运行信息
+type EvalRunInfo struct {
+	ID           int64          `json:"id"`
+	CreatedAt    *time.Time     `json:"created_at"`
+	DatasetID    int64          `json:"dataset_id"`
+	Name         string         `json:"name"`
+	Status       string         `json:"status"`
+	ItemCount    int            `json:"item_count"`
+	Completed    int64          `json:"completed"`
+	Config       *EvalRunConfig `json:"config"`
+	Metrics      *EvalMetrics   `json:"metrics"`
+	ErrorMessage string         `json:"error_message,omitempty"`
+	StartedAt    *time.Time     `json:"started_at"`
+	FinishedAt   *time.Time     `json:"finished_at"`
+}
+
+// EvalHit 评测中的一条召回结果
+type EvalHit struct {
+	DocumentID   int64   `json:"document_id"`
+	DocumentName string  `json:"document_name"`
+	ChunkIndex   int     `json:"chunk_index"`
+	Score        float64 `json:"score"`
+	Relevant     bool    `json:"relevant"`
+	Preview      string  `json:"preview"`
+}
+
+// EvalResultInfo 单题评测结果
+type EvalResultInfo struct {
+	ItemID         int64     `json:"item_id"`
+	Question       string    `json:"question"`
+	TargetCount    int       `json:"target_count"`
+	Recall         float64   `json:"recall"`
+	ReciprocalRank float64   `json:"reciprocal_rank"`
+	NDCG           float64   `json:"ndcg"`
+	Hit            bool      `json:"hit"`
+	LatencyMs      int64     `json:"latency_ms"`
+	Retrieved      []EvalHit `json:"retrieved"`
+	ErrorMessage   string    `json:"error_message,omitempty"`
+}
+
+// EvalRunDetail 评测运行详情
+type EvalRunDetail struct {
+	*EvalRunInfo
+	Results []*EvalResultInfo `json:"results"`
+}
+
+// EvalCompareResult 多次运行并排对比
+type EvalCompareResult struct {
+	Runs  []*EvalRunInfo     `json:"runs"`
+	Items []*EvalCompareItem `json:"items"`
+}
+
+// EvalCompareItem 单题在各次运行中的结果，Scores 与 Runs 一一对应（该运行没有此题时为 null）
+type EvalCompareItem struct {
+	ItemID   int64               `json:"item_id"`
+	Question string              `json:"question"`
+	Scores   []*EvalCompareScore `json:"scores"`
+	Changed  bool                `json:"changed"` // 各次运行的 recall / 首个命中排名不同
+}
+
+// EvalCompareScore 单题单次运行的指标
+type EvalCompareScore struct {
+	Recall         float64 `json:"recall"`
+	ReciprocalRank float64 `json:"reciprocal_rank"`
+	NDCG           float64 `json:"ndcg"`
+	LatencyMs      int64   `json:"latency_ms"`
+}
+
+// CreateEvalRun 创建评测运行，在后台逐题执行检索并计算指标
+func (l *KnowledgeBaseLogic) CreateEvalRun(kbID int64, req *CreateEvalRunReq, userID int64) (*EvalRunInfo, error) {
+	db := svc.Ctx.DB
+
+	var kb model.TKnowledgeBase
+	if err := db.Where("id = ? AND is_delete = 0", kbID).First(&kb).Error; err != nil {
+		return nil, errors.New("知识库不存在")
+	}
+	if _, err := l.getEvalDataset(kbID, req.DatasetID); err != nil {
+		return nil, err
+	}
+	var itemCount int64
+	db.Model(&model.TKnowledgeEvalItem{}).Where("dataset_id = ? AND status = ?", req.DatasetID, model.EvalItemActive).Count(&itemCount)
+	if itemCount == 0 {
+		return nil, errors.New("评测集没有可用的题目（候选题目需确认后才参与评测）")
+	}
+	if _, _, err := vectorStoreOf(&kb); err != nil {
+		return nil, err
+	}
+	params, err := resolveSearchParams(&kb, &req.Search)
+	if err != nil {
+		return nil, err
+	}
+
+	cfgJSON, err := json.Marshal(l.snapshotEvalConfig(&kb, params))
+	if err != nil {
+		return nil, err
+	}
+	cfgStr := string(cfgJSON)
+	name := req.Name
+	if name == "" {
+		name = fmt.Sprintf("%s top%d", params.RetrievalMode, params.TopK)
+	}
+	now := time.Now()
+	run := &model.TKnowledgeEvalRun{
+		CreatedAt:       &now,
+		UpdatedAt:       &now,
+		CreatedBy:       &userID,
+		KnowledgeBaseID: kbID,
+		DatasetID:       req.DatasetID,
+		Name:            name,
+		Config:          &cfgStr,
+		Status:          model.EvalRunRunning,
+		ItemCount:       int(itemCount),
+		StartedAt:       &now,
+	}
+	if err := db.Create(run).Error; err != nil {
+		return nil, err
+	}
+
+	safeGo(func() { NewKnowledgeBaseLogic(context.Background()).executeEvalRun(&kb, run.ID, req.DatasetID, params) })
+	return toEvalRunInfo(run, 0), nil
+}
+
+// snapshotEvalConfig 记录运行时的检索参数与索引配置
+func (l *KnowledgeBaseLogic) snapshotEvalConfig(kb *model.TKnowledgeBase, params *searchParams) *EvalRunConfig {
+	db := svc.Ctx.DB
+	kbCfg := kb.GetConfig()
+	cfg := &EvalRunConfig{
+		Search:          params,
+		ChunkSize:       kbCfg.ChunkSize,
+		ChunkOverlap:    kbCfg.ChunkOverlap,
+		ChunkStrategies: make(map[string]int),
+	}
+	if kb.EmbeddingModelID != nil {
+		cfg.EmbeddingModelID = *kb.EmbeddingModelID
+		if m, err := NewAiModelLogic(l.ctx).GetByIDWithKey(*kb.EmbeddingModelID); err == nil {
+			cfg.EmbeddingModel = m.ModelID
+		}
+	}
+	if store, _, err := vectorStoreOf(kb); err == nil {
+		cfg.VectorStore = store.Name()
+	}
+
+	var docs []model.TKnowledgeDocument
+	db.Select("id, chunk_setting").Where("knowledge_base_id = ?", kb.ID).Find(&docs)
+	cfg.DocumentCount = int64(len(docs))
+	for _, doc := range docs {
+		strategy := model.ChunkStrategyGeneral
+		if doc.ChunkSetting != nil && doc.ChunkSetting.Strategy != "" {
+			strategy = doc.ChunkSetting.Strategy
+		}
+		cfg.ChunkStrategies[strategy]++
+	}
+	db.Model(&model.TKnowledgeSegment{}).Where("knowledge_base_id = ? AND enabled = 1", kb.ID).Count(&cfg.SegmentCount)
+	return cfg
+}
+
+// executeEvalRun 逐题检索并记录结果，最后写入汇总指标
+func (l *KnowledgeBaseLogic) executeEvalRun(kb *model.TKnowledgeBase, runID, datasetID int64, params *searchParams) {
+	db := svc.Ctx.DB
+	finish := func(status string, metrics *EvalMetrics, errMsg string) {
+		now := time.Now()
+		updates := map[string]interface{}{"status": status, "finished_at": now, "updated_at": now}
+		if metrics != nil {
+			data, _ := json.Marshal(metrics)
+			updates["metrics"] = string(data)
+		}
+		if errMsg != "" {
+			updates["error_message"] = errMsg
+		}
+		db.Model(&model.TKnowledgeEvalRun{}).Where("id = ?", runID).Updates(updates)
+	}
+
+	var items []model.TKnowledgeEvalItem
+	if err := db.Where("dataset_id = ? AND status = ?", datasetID, model.EvalItemActive).Order("id ASC").Find(&items).Error; err != nil {
+		finish(model.EvalRunFailed, nil, err.Error())
+		return
+	}
+	targets, err := resolveEvalTargets(items)
+	if err != nil {
+		finish(model.EvalRunFailed, nil, err.Error())
+		return
+	}
+
+	scores := make([]evalItemScore, 0, len(items))
+	latencies := make([]int64, 0, len(items))
+	failed := 0
+	for _, item := range items {
+		// 运行被删除时停止
+		var exists int64
+		if db.Model(&model.TKnowledgeEvalRun{}).Where("id = ?", runID).Count(&exists); exists == 0 {
+			return
+		}
+
+		start := time.Now()
+		results, searchErr := l.retrieveForEval(kb, item.Question, params)
+		latency := time.Since(start).Milliseconds()
+
+		itemTargets := targets[item.ID]
+		matches := make([][]int, len(results))
+		hits := make([]EvalHit, len(results))
+		for i, r := range results {
+			for ti := range itemTargets {
+				if itemTargets[ti].matches(r) {
+					matches[i] = append(matches[i], ti)
+				}
+			}
+			hits[i] = EvalHit{
+				DocumentID:   r.DocumentID,
+				DocumentName: r.DocumentName,
+				ChunkIndex:   r.ChunkIndex,
+				Score:        r.Score,
+				Relevant:     len(matches[i]) > 0,
+				Preview:      truncateRunes(r.Content, 120),
+			}
+		}
+		score := scoreRanking(matches, len(itemTargets), params.TopK)
+
+		retrieved, _ := json.Marshal(hits)
+		retrievedStr := string(retrieved)
+		now := time.Now()
+		row := &model.TKnowledgeEvalResult{
+			CreatedAt:      &now,
+			RunID:          runID,
+			ItemID:         item.ID,
+			Recall:         score.Recall,
+			ReciprocalRank: score.ReciprocalRank,
+			NDCG:           score.NDCG,
+			Hit:            score.Hit,
+			LatencyMs:      latency,
+			Retrieved:      &retrievedStr,
+		}
+		if searchErr != nil {
+			msg := searchErr.Error()
+			row.ErrorMessage = &msg
+			failed++
+		} else {
+			scores = append(scores, score)
+			latencies = append(latencies, latency)
+		}
+		if err := db.Create(row).Error; err != nil {
+			log.Printf("[WARN] 保存评测结果失败 (run=%d, item=%d): %v", runID, item.ID, err)
+		}
+	}
+
+	metrics := summarizeEval(scores, latencies, params.TopK)
+	metrics.Failed = failed
+	finish(model.EvalRunSuccess, metrics, "")
+}
+
+// retrieveForEval 执行一次检索；检索过程中的 panic 视为该题失败，不影响其他题目
+func (l *KnowledgeBaseLogic) retrieveForEval(kb *model.TKnowledgeBase, query string, params *searchParams) (results []*KnowledgeSearchResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("检索失败: %v", r)
+		}
+	}()
+	return l.retrieve(kb, query, params), nil
+}
+
+// ListEvalRuns 获取评测运行列表，datasetID 为 0 时返回知识库下所有运行
+func (l *KnowledgeBaseLogic) ListEvalRuns(kbID, datasetID int64) ([]*EvalRunInfo, error) {
+	db := svc.Ctx.DB
+	q := db.Where("knowledge_base_id = ?", kbID)
+	if datasetID > 0 {
+		q = q.Where("dataset_id = ?", datasetID)
+	}
+	var runs []*model.TKnowledgeEvalRun
+	if err := q.Order("id DESC").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	completed := evalRunProgress(runs)
+	result := make([]*EvalRunInfo, 0, len(runs))
+	for _, run := range runs {
+		result = append(result, toEvalRunInfo(run, completed[run.ID]))
+	}
+	return result, nil
+}
+
+// GetEvalRun 获取评测运行详情（含每题结果）
+func (l *KnowledgeBaseLogic) GetEvalRun(kbID, runID int64) (*EvalRunDetail, error) {
+	db := svc.Ctx.DB
+	var run model.TKnowledgeEvalRun
+	if err := db.Where("id = ? AND knowledge_base_id = ?", runID, kbID).First(&run).Error; err != nil {
+		return nil, errors.New("评测运行不存在")
+	}
+
+	var rows []model.TKnowledgeEvalResult
+	db.Where("run_id = ?", runID).Order("id ASC").Find(&rows)
+	itemIDs := make([]int64, len(rows))
+	for i, r := range rows {
+		itemIDs[i] = r.ItemID
+	}
+	items := loadEvalItems(itemIDs)
+
+	detail := &EvalRunDetail{EvalRunInfo: toEvalRunInfo(&run, int64(len(rows))), Results: make([]*EvalResultInfo, 0, len(rows))}
+	for _, r := range rows {
+		info := &EvalResultInfo{
+			ItemID:         r.ItemID,
+			Recall:         r.Recall,
+			ReciprocalRank: r.ReciprocalRank,
+			NDCG:           r.NDCG,
+			Hit:            r.Hit,
+			LatencyMs:      r.LatencyMs,
+			ErrorMessage:   derefString(r.ErrorMessage),
+		}
+		if item, ok := items[r.ItemID]; ok {
+			info.Question = item.Question
+			info.TargetCount = len(item.GetExpected())
+		}
+		if r.Retrieved != nil {
+			_ = json.Unmarshal([]byte(*r.Retrieved), &info.Retrieved)
+		}
+		detail.Results = append(detail.Results, info)
+	}
+	return detail, nil
+}
+
+// DeleteEvalRun 删除评测运行及其结果（执行中的运行在当前题目结束后停止）
+func (l *KnowledgeBaseLogic) DeleteEvalRun(kbID, runID int64) error {
+	db := svc.Ctx.DB
+	res := db.Where("id = ? AND knowledge_base_id = ?", runID, kbID).Delete(&model.TKnowledgeEvalRun{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("评测运行不存在")
+	}
+	return db.Where("run_id = ?", runID).Delete(&model.TKnowledgeEvalResult{}).Error
+}
+
+// CompareEvalRuns 并排对比同一评测集的多次运行
+func (l *KnowledgeBaseLogic) CompareEvalRuns(kbID int64, runIDs []int64) (*EvalCompareResult, error) {
+	if len(runIDs) < 2 || len(runIDs) > 5 {
+		return nil, errors.New("请选择 2–5 次运行进行对比")
+	}
+	db := svc.Ctx.DB
+	var runs []*model.TKnowledgeEvalRun
+	if err := db.Where("id IN ? AND knowledge_base_id = ?", runIDs, kbID).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	if len(runs) != len(runIDs) {
+		return nil, errors.New("评测运行不存在")
+	}
+	for _, run := range runs[1:] {
+		if run.DatasetID != runs[0].DatasetID {
+			return nil, errors.New("只能对比同一评测集的运行")
+		}
+	}
+	// 按请求顺序排列
+	order := make(map[int64]int, len(runIDs))
+	for i, id := range runIDs {
+		order[id] = i
+	}
+	sort.Slice(runs, func(i, j int) bool { return order[runs[i].ID] < order[runs[j].ID] })
+
+	var rows []model.TKnowledgeEvalResult
+	db.Where("run_id IN ?", runIDs).Find(&rows)
+	completed := make(map[int64]int64)
+	byItem := make(map[int64][]*EvalCompareScore)
+	var itemIDs []int64
+	for _, r := range rows {
+		completed[r.RunID]++
+		scores, ok := byItem[r.ItemID]
+		if !ok {
+			scores = make([]*EvalCompareScore, len(runs))
+			itemIDs = append(itemIDs, r.ItemID)
+		}
+		scores[order[r.RunID]] = &EvalCompareScore{Recall: r.Recall, ReciprocalRank: r.ReciprocalRank, NDCG: r.NDCG, LatencyMs: r.LatencyMs}
+		byItem[r.ItemID] = scores
+	}
+	sort.Slice(itemIDs, func(i, j int) bool { return itemIDs[i] < itemIDs[j] })
+	items := loadEvalItems(itemIDs)
+
+	result := &EvalCompareResult{Runs: make([]*EvalRunInfo, 0, len(runs)), Items: make([]*EvalCompareItem, 0, len(itemIDs))}
+	for _, run := range runs {
+		result.Runs = append(result.Runs, toEvalRunInfo(run, completed[run.ID]))
+	}
+	for _, id := range itemIDs {
+		ci := &EvalCompareItem{ItemID: id, Scores: byItem[id], Changed: evalScoresChanged(byItem[id])}
+		if item, ok := items[id]; ok {
+			ci.Question = item.Question
+		}
+		result.Items = append(result.Items, ci)
+	}
+	return result, nil
+}
+
+func evalScoresChanged(scores []*EvalCompareScore) bool {
+	var first *EvalCompareScore
+	for _, s := range scores {
+		if s == nil {
+			return true
+		}
+		if first == nil {
+			first = s
+			continue
+		}
+		if s.Recall != first.Recall || s.ReciprocalRank != first.ReciprocalRank {
+			return true
+		}
+	}
+	return false
+}
+
+func loadEvalItems(ids []int64) map[int64]*model.TKnowledgeEvalItem {
+	result := make(map[int64]*model.TKnowledgeEvalItem, len(ids))
+	if len(ids) == 0 {
+		return result
+	}
+	var items []*model.TKnowledgeEvalItem
+	svc.Ctx.DB.Where("id IN ?", ids).Find(&items)
+	for _, item := range items {
+		result[item.ID] = item
+	}
+	return result
+}
+
+// evalRunProgress 统计各运行已完成的题目数
+func evalRunProgress(runs []*model.TKnowledgeEvalRun) map[int64]int64 {
+	result := make(map[int64]int64)
+	if len(runs) == 0 {
+		return result
+	}
+	ids := make([]int64, len(runs))
+	for i, r := range runs {
+		ids[i] = r.ID
+	}
+	type countRow struct {
+		RunID int64
+		Cnt   int64
+	}
+	var rows []countRow
+	svc.Ctx.DB.Model(&model.TKnowledgeEvalResult{}).Select("run_id, COUNT(*) AS cnt").Where("run_id IN ?", ids).Group("run_id").Scan(&rows)
+	for _, r := range rows {
+		result[r.RunID] = r.Cnt
+	}
+	return result
+}
+
+func toEvalRunInfo(run *model.TKnowledgeEvalRun, completed int64) *EvalRunInfo {
+	info := &EvalRunInfo{
+		ID:           run.ID,
+		CreatedAt:    run.CreatedAt,
+		DatasetID:    run.DatasetID,
+		Name:         run.Name,
+		Status:       run.Status,
+		ItemCount:    run.ItemCount,
+		Completed:    completed,
+		ErrorMessage: derefString(run.ErrorMessage),
+		StartedAt:    run.StartedAt,
+		FinishedAt:   run.FinishedAt,
+	}
+	if run.Config != nil {
+		var cfg EvalRunConfig
+		if json.Unmarshal([]byte(*run.Config), &cfg) == nil {
+			info.Config = &cfg
+		}
+	}
+	if run.Metrics != nil {
+		var m EvalMetrics
+		if json.Unmarshal([]byte(*run.Metrics), &m) == nil {
+			info.Metrics = &m
+		}
+	}
+	return info
+}
+
+// -----------------------------------------------
+// 命中判定与指标计算
+// -----------------------------------------------
+
+// evalTarget 解析后的期望目标：分块仍存在时按文档 + 分块位置匹配，已不存在时按内容重合度匹配
+type evalTarget struct {
+	model.EvalTarget
+	position int
+	found    bool
+}
+
+func (t *evalTarget) matches(r *KnowledgeSearchResult) bool {
+	if r.DocumentID != t.DocumentID {
+		return false
+	}
+	if t.SegmentID == 0 {
+		return true
+	}
+	if t.found && r.ChunkIndex == t.position {
+		return true
+	}
+	// 分块已失效，或结果已替换为父块（同一父块的子块去重后只保留排名最高的一个）时按内容匹配
+	_, expanded := r.Metadata["child_content"]
+	if t.found && !expanded {
+		return false
+	}
+	return t.Content != "" && contentOverlap(r.Content, t.Content) >= evalContentOverlap
+}
+
+// resolveEvalTargets 查询期望分块当前的位置，返回 itemID → 期望目标
+func resolveEvalTargets(items []model.TKnowledgeEvalItem) (map[int64][]evalTarget, error) {
+	result := make(map[int64][]evalTarget, len(items))
+	var segIDs []int64
+	for _, item := range items {
+		targets := item.GetExpected()
+		resolved := make([]evalTarget, len(targets))
+		for i, t := range targets {
+			resolved[i] = evalTarget{EvalTarget: t}
+			if t.SegmentID > 0 {
+				segIDs = append(segIDs, t.SegmentID)
+			}
+		}
+		result[item.ID] = resolved
+	}
+	if len(segIDs) == 0 {
+		return result, nil
+	}
+
+	var segments []model.TKnowledgeSegment
+	if err := svc.Ctx.DB.Select("id, document_id, position").Where("id IN ?", segIDs).Find(&segments).Error; err != nil {
+		return nil, err
+	}
+	positions := make(map[int64]model.TKnowledgeSegment, len(segments))
+	for _, seg := range segments {
+		positions[seg.ID] = seg
+	}
+	for _, targets := range result {
+		for i := range targets {
+			if seg, ok := positions[targets[i].SegmentID]; ok && seg.DocumentID == targets[i].DocumentID {
+				targets[i].position, targets[i].found = seg.Position, true
+			}
+		}
+	}
+	return result, nil
+}
+
+// contentOverlap 两段文本的字符二元组包含度：|A∩B| / min(|A|, |B|)，忽略空白与标点。
+// 分块变大或变小时，新分块包含旧分块（或反之）仍能得到接近 1 的值。
+func contentOverlap(a, b string) float64 {
+	ga, gb := textBigrams(a), textBigrams(b)
+	if len(ga) == 0 || len(gb) == 0 {
+		return 0
+	}
+	if len(ga) > len(gb) {
+		ga, gb = gb, ga
+	}
+	common := 0
+	for g := range ga {
+		if gb[g] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ga))
+}
+
+func textBigrams(s string) map[[2]rune]bool {
+	var runes []rune
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			runes = append(runes, unicode.ToLower(r))
+		}
+	}
+	grams := make(map[[2]rune]bool, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		grams[[2]rune{runes[i], runes[i+1]}] = true
+	}
+	return grams
+}
+
+// evalItemScore 单题指标
+type evalItemScore struct {
+	Recall         float64
+	ReciprocalRank float64
+	NDCG           float64
+	Hit            bool
+	RecallAt       map[int]float64
+}
+
+// scoreRanking 计算单题指标。matches[i] 为第 i 个结果命中的期望目标下标；
+// 同一目标只在第一次命中时计入（文档级目标会被该文档的多个分块命中）。
+func scoreRanking(matches [][]int, targetCount, k int) evalItemScore {
+	score := evalItemScore{RecallAt: make(map[int]float64)}
+	if targetCount == 0 {
+		return score
+	}
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+
+	seen := make(map[int]bool)
+	found := 0
+	var dcg float64
+	for i, m := range matches {
+		gain := false
+		for _, t := range m {
+			if !seen[t] {
+				seen[t] = true
+				found++
+				gain = true
+			}
+		}
+		if len(m) > 0 && score.ReciprocalRank == 0 {
+			score.ReciprocalRank = 1 / float64(i+1)
+		}
+		if gain {
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+		for _, c := range evalRecallCutoffs {
+			if c == i+1 {
+				score.RecallAt[c] = float64(found) / float64(targetCount)
+			}
+		}
+	}
+	for _, c := range evalRecallCutoffs {
+		if _, ok := score.RecallAt[c]; !ok && c <= k {
+			score.RecallAt[c] = float64(found) / float64(targetCount)
+		}
+	}
+
+	var idcg float64
+	for i := 0; i < targetCount && i < k; i++ {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+	score.Recall = float64(found) / float64(targetCount)
+	score.Hit = found > 0
+	if idcg > 0 {
+		score.NDCG = dcg / idcg
+	}
+	return score
+}
+
+// summarizeEval 汇总各题指标与延迟
+func summarizeEval(scores []evalItemScore, latencies []int64, k int) *EvalMetrics {
+	m := &EvalMetrics{K: k, RecallAt: make(map[string]float64), Evaluated: len(scores)}
+	if len(scores) == 0 {
+		return m
+	}
+	n := float64(len(scores))
+	for _, s := range scores {
+		m.Recall += s.Recall / n
+		m.MRR += s.ReciprocalRank / n
+		m.NDCG += s.NDCG / n
+		if s.Hit {
+			m.HitRate += 1 / n
+		}
+		for c, v := range s.RecallAt {
+			m.RecallAt[strconv.Itoa(c)] += v / n
+		}
+	}
+
+	sorted := append([]int64(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total int64
+	for _, l := range sorted {
+		total += l
+	}
+	if len(sorted) > 0 {
+		m.LatencyAvgMs = float64(total) / float64(len(sorted))
+		m.LatencyP50Ms = percentileMs(sorted, 0.50)
+		m.LatencyP95Ms = percentileMs(sorted, 0.95)
+	}
+	return m
+}
+
+// percentileMs 已排序延迟的百分位（最近秩法）
+func percentileMs(sorted []int64, p float64) int64 {
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
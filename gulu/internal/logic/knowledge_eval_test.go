@@ -0,0 +1,93 @@
+package logic
+
+import (
+	"math"
+	"testing"
+
+	"yqhp/gulu/internal/model"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// TestScoreRanking 同一目标只计一次，recall@n 与 nDCG 按截断位置计算
+func TestScoreRanking(t *testing.T) {
+	// 2 个目标：第 2 位命中目标 0，第 3、4 位都命中目标 1（文档级目标被多个分块命中）
+	matches := [][]int{nil, {0}, {1}, {1}, nil}
+	s := scoreRanking(matches, 2, 5)
+	if !almostEqual(s.Recall, 1) || !s.Hit || !almostEqual(s.ReciprocalRank, 0.5) {
+		t.Fatalf("unexpected score: %+v", s)
+	}
+	if !almostEqual(s.RecallAt[1], 0) || !almostEqual(s.RecallAt[3], 1) || !almostEqual(s.RecallAt[5], 1) {
+		t.Fatalf("unexpected recall_at: %v", s.RecallAt)
+	}
+	if _, ok := s.RecallAt[10]; ok {
+		t.Fatal("cutoff beyond k should be omitted")
+	}
+	wantNDCG := (1/math.Log2(3) + 1/math.Log2(4)) / (1 + 1/math.Log2(3))
+	if !almostEqual(s.NDCG, wantNDCG) {
+		t.Fatalf("ndcg = %v, want %v", s.NDCG, wantNDCG)
+	}
+
+	// 结果少于 k 时按已有结果计算 recall@n；超出 k 的结果不计入
+	s = scoreRanking([][]int{{0}}, 3, 10)
+	if !almostEqual(s.RecallAt[10], 1.0/3) || !almostEqual(s.NDCG, 1/(1+1/math.Log2(3)+1/math.Log2(4))) {
+		t.Fatalf("unexpected score: %+v", s)
+	}
+	if s = scoreRanking([][]int{nil, {0}}, 1, 1); s.Hit || s.ReciprocalRank != 0 {
+		t.Fatalf("result beyond k should not count: %+v", s)
+	}
+}
+
+// TestSummarizeEval 指标取各题平均，延迟按最近秩法取百分位
+func TestSummarizeEval(t *testing.T) {
+	scores := []evalItemScore{
+		{Recall: 1, ReciprocalRank: 1, NDCG: 1, Hit: true, RecallAt: map[int]float64{1: 1}},
+		{Recall: 0, RecallAt: map[int]float64{1: 0}},
+	}
+	m := summarizeEval(scores, []int64{30, 10, 20, 40}, 5)
+	if !almostEqual(m.Recall, 0.5) || !almostEqual(m.HitRate, 0.5) || !almostEqual(m.MRR, 0.5) || !almostEqual(m.RecallAt["1"], 0.5) {
+		t.Fatalf("unexpected metrics: %+v", m)
+	}
+	if m.LatencyAvgMs != 25 || m.LatencyP50Ms != 20 || m.LatencyP95Ms != 40 || m.Evaluated != 2 {
+		t.Fatalf("unexpected latency: %+v", m)
+	}
+}
+
+// TestEvalTargetMatches 分块存在时按位置匹配，失效或被替换为父块时按内容重合度匹配
+func TestEvalTargetMatches(t *testing.T) {
+	snapshot := "Qdrant 默认监听 6333 端口，gRPC 使用 6334 端口。"
+	if contentOverlap(snapshot, "部署说明。"+snapshot+"其他内容") < 0.99 {
+		t.Fatal("containing chunk should fully overlap")
+	}
+	if contentOverlap(snapshot, "Neo4j 图数据库可选") > 0.3 {
+		t.Fatal("unrelated chunk should not overlap")
+	}
+
+	target := evalTarget{EvalTarget: model.EvalTarget{DocumentID: 1, SegmentID: 9, Content: snapshot}, position: 2, found: true}
+	cases := []struct {
+		name string
+		r    *KnowledgeSearchResult
+		want bool
+	}{
+		{"same position", &KnowledgeSearchResult{DocumentID: 1, ChunkIndex: 2}, true},
+		{"other position", &KnowledgeSearchResult{DocumentID: 1, ChunkIndex: 3, Content: snapshot}, false},
+		{"other document", &KnowledgeSearchResult{DocumentID: 2, ChunkIndex: 2}, false},
+		{"parent expanded", &KnowledgeSearchResult{DocumentID: 1, ChunkIndex: 3, Content: "前文。" + snapshot, Metadata: map[string]interface{}{"child_content": "x"}}, true},
+	}
+	for _, c := range cases {
+		if got := target.matches(c.r); got != c.want {
+			t.Errorf("%s: matches = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	target.found = false
+	if !target.matches(&KnowledgeSearchResult{DocumentID: 1, ChunkIndex: 7, Content: snapshot + "（重新分块后合并）"}) {
+		t.Fatal("re-chunked segment should match by content")
+	}
+	docTarget := evalTarget{EvalTarget: model.EvalTarget{DocumentID: 1}}
+	if !docTarget.matches(&KnowledgeSearchResult{DocumentID: 1, ChunkIndex: 40}) {
+		t.Fatal("document target should match any chunk")
+	}
+}
@@ -156,31 +156,45 @@ const entityExtractionPrompt = `你是一个知识图谱实体关系抽取专家
 
 // extractEntitiesAndRelations 调用 LLM 抽取实体和关系
 func (g *GraphProcessor) extractEntitiesAndRelations(ctx context.Context, baseURL, apiKey, modelID, text string) (*ExtractedGraph, error) {
+	prompt := fmt.Sprintf(entityExtractionPrompt, text)
+
+	content, err := chatCompletionJSON(ctx, baseURL, apiKey, modelID, prompt, 0.1, 4096)
+	if err != nil {
+		return nil, err
+	}
+	var graph ExtractedGraph
+	if err := json.Unmarshal([]byte(content), &graph); err != nil {
+		return nil, fmt.Errorf("解析实体关系 JSON 失败: %w (content: %s)", err, content)
+	}
+
+	return &graph, nil
+}
+
+// chatCompletionJSON 调用 OpenAI 兼容的 chat/completions 接口（JSON 输出模式），返回模型输出内容
+func chatCompletionJSON(ctx context.Context, baseURL, apiKey, modelID, prompt string, temperature float64, maxTokens int) (string, error) {
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
 	}
 
-	prompt := fmt.Sprintf(entityExtractionPrompt, text)
-
 	reqBody := map[string]interface{}{
 		"model": modelID,
 		"messages": []map[string]string{
 			{"role": "user", "content": prompt},
 		},
-		"temperature":  0.1,
-		"max_tokens":   4096,
+		"temperature":     temperature,
+		"max_tokens":      maxTokens,
 		"response_format": map[string]string{"type": "json_object"},
 	}
 
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("请求序列化失败: %w", err)
+		return "", fmt.Errorf("请求序列化失败: %w", err)
 	}
 
 	url := baseURL + "/chat/completions"
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
 	if err != nil {
-		return nil, fmt.Errorf("创建 HTTP 请求失败: %w", err)
+		return "", fmt.Errorf("创建 HTTP 请求失败: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -191,17 +205,17 @@ func (g *GraphProcessor) extractEntitiesAndRelations(ctx context.Context, baseUR
 	httpClient := &http.Client{Timeout: 120 * time.Second}
 	resp, err := httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("LLM HTTP 请求失败: %w", err)
+		return "", fmt.Errorf("LLM HTTP 请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %w", err)
+		return "", fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("LLM API 返回错误 (HTTP %d): %s", resp.StatusCode, string(respBody))
+		return "", fmt.Errorf("LLM API 返回错误 (HTTP %d): %s", resp.StatusCode, string(respBody))
 	}
 
 	var chatResp struct {
@@ -215,20 +229,14 @@ func (g *GraphProcessor) extractEntitiesAndRelations(ctx context.Context, baseUR
 		} `json:"error"`
 	}
 	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return nil, fmt.Errorf("响应解析失败: %w", err)
+		return "", fmt.Errorf("响应解析失败: %w", err)
 	}
 	if chatResp.Error != nil {
-		return nil, fmt.Errorf("LLM API 错误: %s", chatResp.Error.Message)
+		return "", fmt.Errorf("LLM API 错误: %s", chatResp.Error.Message)
 	}
 	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("LLM 返回空结果")
-	}
-
-	content := chatResp.Choices[0].Message.Content
-	var graph ExtractedGraph
-	if err := json.Unmarshal([]byte(content), &graph); err != nil {
-		return nil, fmt.Errorf("解析实体关系 JSON 失败: %w (content: %s)", err, content)
+		return "", fmt.Errorf("LLM 返回空结果")
 	}
 
-	return &graph, nil
+	return chatResp.Choices[0].Message.Content, nil
 }
@@ -0,0 +1,735 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+)
+
+// -----------------------------------------------
+// 知识库文档入库队列
+// 任务持久化在 t_knowledge_ingest_job，各实例的工作协程通过 SELECT ... FOR UPDATE SKIP LOCKED 认领，
+// 执行期间定期续约；实例重启或失联后租约到期，任务由任意实例从最近的阶段检查点继续执行。
+// -----------------------------------------------
+
+// errIngestRunning 文档已有执行中的入库任务
+var errIngestRunning = errors.New("文档正在处理中，请等待完成或取消后再重新处理")
+
+// ingestSettings 入库队列参数（配置 + 默认值）
+type ingestSettings struct {
+	Workers        int
+	PollInterval   time.Duration
+	Lease          time.Duration
+	Heartbeat      time.Duration
+	MaxAttempts    int
+	EmbedBatchSize int
+	RateLimit      float64
+}
+
+func getIngestSettings() ingestSettings {
+	s := ingestSettings{
+		Workers:        2,
+		PollInterval:   3 * time.Second,
+		Lease:          2 * time.Minute,
+		MaxAttempts:    5,
+		EmbedBatchSize: 20,
+	}
+	if svc.Ctx != nil && svc.Ctx.Config != nil {
+		c := svc.Ctx.Config.KnowledgeIngest
+		if c.Workers > 0 {
+			s.Workers = c.Workers
+		}
+		if c.PollInterval > 0 {
+			s.PollInterval = c.PollInterval
+		}
+		if c.Lease > 0 {
+			s.Lease = c.Lease
+		}
+		if c.MaxAttempts > 0 {
+			s.MaxAttempts = c.MaxAttempts
+		}
+		if c.EmbedBatchSize > 0 {
+			s.EmbedBatchSize = c.EmbedBatchSize
+		}
+		s.RateLimit = c.EmbeddingRateLimit
+	}
+	// 续约间隔兼作取消请求的检查间隔
+	s.Heartbeat = s.Lease / 3
+	if s.Heartbeat > 5*time.Second {
+		s.Heartbeat = 5 * time.Second
+	}
+	return s
+}
+
+// ingestInstanceID 当前实例标识，写入任务的 locked_by
+var ingestInstanceID = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%04x", host, os.Getpid(), rand.Intn(0x10000))
+}()
+
+// ingestWakeup 本实例入队后唤醒空闲工作协程，无需等待轮询
+var ingestWakeup = make(chan struct{}, 1)
+
+func wakeIngestWorkers() {
+	select {
+	case ingestWakeup <- struct{}{}:
+	default:
+	}
+}
+
+// -----------------------------------------------
+// 入队 / 取消
+// -----------------------------------------------
+
+// enqueueIngestJob 为文档创建入库任务并将文档置为 waiting。
+// 已有等待中的任务时重置该任务；已有执行中的任务时返回 errIngestRunning。
+func enqueueIngestJob(tx *gorm.DB, kbID, docID int64) error {
+	now := time.Now()
+	var active model.TKnowledgeIngestJob
+	err := tx.Where("document_id = ? AND status IN ?", docID, []string{model.IngestJobPending, model.IngestJobRunning}).
+		Order("id DESC").First(&active).Error
+	switch {
+	case err == nil && active.Status == model.IngestJobRunning:
+		return errIngestRunning
+	case err == nil:
+		err = tx.Model(&model.TKnowledgeIngestJob{}).Where("id = ?", active.ID).Updates(map[string]interface{}{
+			"stage":           model.IngestStageParse,
+			"attempts":        0,
+			"next_run_at":     now,
+			"embedded_chunks": 0,
+			"total_chunks":    0,
+			"progress":        0,
+			"error_message":   nil,
+			"updated_at":      now,
+		}).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		err = tx.Create(&model.TKnowledgeIngestJob{
+			CreatedAt:       &now,
+			UpdatedAt:       &now,
+			KnowledgeBaseID: kbID,
+			DocumentID:      docID,
+			Status:          model.IngestJobPending,
+			Stage:           model.IngestStageParse,
+			MaxAttempts:     getIngestSettings().MaxAttempts,
+			NextRunAt:       now,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(&model.TKnowledgeDocument{}).Where("id = ?", docID).Updates(map[string]interface{}{
+		"indexing_status": "waiting",
+		"error_message":   nil,
+		"updated_at":      now,
+	}).Error
+}
+
+// cancelIngestJobs 取消文档的入库任务：等待中的直接取消，执行中的标记取消请求由工作协程在批次间停止
+func cancelIngestJobs(db *gorm.DB, docIDs []int64) (canceled, requested int64, err error) {
+	now := time.Now()
+	res := db.Model(&model.TKnowledgeIngestJob{}).
+		Where("document_id IN ? AND status = ?", docIDs, model.IngestJobPending).
+		Updates(map[string]interface{}{
+			"status":        model.IngestJobCanceled,
+			"error_message": "已取消",
+			"finished_at":   now,
+			"updated_at":    now,
+		})
+	if res.Error != nil {
+		return 0, 0, res.Error
+	}
+	canceled = res.RowsAffected
+
+	res = db.Model(&model.TKnowledgeIngestJob{}).
+		Where("document_id IN ? AND status = ?", docIDs, model.IngestJobRunning).
+		Updates(map[string]interface{}{"cancel_requested": true, "updated_at": now})
+	if res.Error != nil {
+		return canceled, 0, res.Error
+	}
+	return canceled, res.RowsAffected, nil
+}
+
+// -----------------------------------------------
+// 工作协程
+// -----------------------------------------------
+
+// StartKnowledgeIngestWorkers 恢复中断的入库任务并启动工作协程，返回的 stop 函数停止认领新任务并等待执行中的任务让出
+func StartKnowledgeIngestWorkers() (stop func()) {
+	s := getIngestSettings()
+	if err := RecoverKnowledgeIngestJobs(); err != nil {
+		log.Printf("[WARN] 恢复文档入库任务失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < s.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ingestWorkerLoop(ctx, s)
+		}()
+	}
+	log.Printf("[INFO] 文档入库队列已启动: workers=%d, instance=%s", s.Workers, ingestInstanceID)
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// RecoverKnowledgeIngestJobs 启动时恢复中断的任务：
+// 租约已过期的执行中任务重新排队；处于处理中状态但没有任务的文档（升级前提交或任务丢失）补建任务。
+func RecoverKnowledgeIngestJobs() error {
+	db := svc.Ctx.DB
+	now := time.Now()
+
+	res := db.Model(&model.TKnowledgeIngestJob{}).
+		Where("status = ? AND (lease_until IS NULL OR lease_until < ?)", model.IngestJobRunning, now).
+		Updates(map[string]interface{}{
+			"status":      model.IngestJobPending,
+			"locked_by":   "",
+			"lease_until": nil,
+			"next_run_at": now,
+			"updated_at":  now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	var orphans []model.TKnowledgeDocument
+	if err := db.Select("id, knowledge_base_id").
+		Where("indexing_status IN ?", []string{"waiting", "parsing", "cleaning", "splitting", "indexing"}).
+		Where("NOT EXISTS (SELECT 1 FROM t_knowledge_ingest_job j WHERE j.document_id = t_knowledge_document.id AND j.status IN ?)",
+			[]string{model.IngestJobPending, model.IngestJobRunning}).
+		Find(&orphans).Error; err != nil {
+		return err
+	}
+	for _, doc := range orphans {
+		if err := enqueueIngestJob(db, doc.KnowledgeBaseID, doc.ID); err != nil {
+			log.Printf("[WARN] 补建入库任务失败: docID=%d, err=%v", doc.ID, err)
+		}
+	}
+
+	if res.RowsAffected > 0 || len(orphans) > 0 {
+		log.Printf("[INFO] 文档入库任务恢复: 重新排队 %d 个中断任务, 补建 %d 个任务", res.RowsAffected, len(orphans))
+	}
+	return nil
+}
+
+func ingestWorkerLoop(ctx context.Context, s ingestSettings) {
+	for ctx.Err() == nil {
+		job, err := claimIngestJob(s)
+		if err != nil {
+			log.Printf("[ERROR] 认领文档入库任务失败: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-ingestWakeup:
+			case <-time.After(s.PollInterval):
+			}
+			continue
+		}
+		runIngestJob(ctx, s, job)
+	}
+}
+
+// claimIngestJob 认领一个到期的等待任务，或租约已过期的执行中任务（持有实例失联）；跳过正在迁移向量库的知识库
+func claimIngestJob(s ingestSettings) (*model.TKnowledgeIngestJob, error) {
+	var claimed *model.TKnowledgeIngestJob
+	err := svc.Ctx.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var job model.TKnowledgeIngestJob
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_run_at <= ?) OR (status = ? AND lease_until < ?)",
+				model.IngestJobPending, now, model.IngestJobRunning, now).
+			// 正在迁移向量库的知识库暂不认领，迁移结束后写入新的向量库
+			Where("NOT EXISTS (SELECT 1 FROM t_knowledge_base kb WHERE kb.id = t_knowledge_ingest_job.knowledge_base_id AND kb.vector_migrating_until > ?)", now).
+			Order("id").First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		lease := now.Add(s.Lease)
+		updates := map[string]interface{}{
+			"status":      model.IngestJobRunning,
+			"locked_by":   ingestInstanceID,
+			"lease_until": lease,
+			"attempts":    job.Attempts + 1,
+			"updated_at":  now,
+		}
+		if job.StartedAt == nil {
+			updates["started_at"] = now
+			job.StartedAt = &now
+		}
+		if err := tx.Model(&model.TKnowledgeIngestJob{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		job.Status = model.IngestJobRunning
+		job.LockedBy = ingestInstanceID
+		job.LeaseUntil = &lease
+		job.Attempts++
+		claimed = &job
+		return nil
+	})
+	return claimed, err
+}
+
+// ingestRun 单次任务执行的状态
+type ingestRun struct {
+	job        *model.TKnowledgeIngestJob
+	settings   ingestSettings
+	userCancel atomic.Bool // 用户请求取消
+	leaseLost  atomic.Bool // 租约被其他实例接管
+}
+
+// update 更新任务字段（仅当本实例仍持有任务时生效）
+func (r *ingestRun) update(updates map[string]interface{}) error {
+	updates["updated_at"] = time.Now()
+	return svc.Ctx.DB.Model(&model.TKnowledgeIngestJob{}).
+		Where("id = ? AND locked_by = ?", r.job.ID, ingestInstanceID).
+		Updates(updates).Error
+}
+
+// heartbeat 定期续约并检查取消请求，取消或失去租约时中止任务上下文
+func (r *ingestRun) heartbeat(ctx context.Context, abort context.CancelFunc) {
+	ticker := time.NewTicker(r.settings.Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		db := svc.Ctx.DB
+		res := db.Model(&model.TKnowledgeIngestJob{}).
+			Where("id = ? AND locked_by = ? AND status = ?", r.job.ID, ingestInstanceID, model.IngestJobRunning).
+			Updates(map[string]interface{}{"lease_until": time.Now().Add(r.settings.Lease)})
+		if res.Error != nil {
+			log.Printf("[WARN] 入库任务续约失败: jobID=%d, err=%v", r.job.ID, res.Error)
+			continue
+		}
+		if res.RowsAffected == 0 {
+			r.leaseLost.Store(true)
+			abort()
+			return
+		}
+		var cancelRequested []bool
+		db.Model(&model.TKnowledgeIngestJob{}).Where("id = ?", r.job.ID).Pluck("cancel_requested", &cancelRequested)
+		if len(cancelRequested) > 0 && cancelRequested[0] {
+			r.userCancel.Store(true)
+			abort()
+			return
+		}
+	}
+}
+
+// runIngestJob 执行任务并按结果更新状态：成功、取消、退避重试、失败，或服务停止时让出
+func runIngestJob(parent context.Context, s ingestSettings, job *model.TKnowledgeIngestJob) {
+	ctx, abort := context.WithCancel(parent)
+	run := &ingestRun{job: job, settings: s}
+	go run.heartbeat(ctx, abort)
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[PANIC] 文档入库任务 panic: jobID=%d, docID=%d, panic=%v", job.ID, job.DocumentID, r)
+				err = fmt.Errorf("处理过程发生内部错误: %v", r)
+			}
+		}()
+		return run.execute(ctx)
+	}()
+	abort()
+
+	run.finish(parent, err)
+}
+
+// execute 从任务当前阶段开始执行，每个阶段完成后记录检查点
+func (r *ingestRun) execute(ctx context.Context) error {
+	db := svc.Ctx.DB
+	job := r.job
+
+	var kb model.TKnowledgeBase
+	if err := db.Where("id = ? AND is_delete = 0", job.KnowledgeBaseID).First(&kb).Error; err != nil {
+		r.userCancel.Store(true)
+		return errors.New("知识库已删除")
+	}
+	var doc model.TKnowledgeDocument
+	if err := db.Where("id = ?", job.DocumentID).First(&doc).Error; err != nil {
+		r.userCancel.Store(true)
+		return errors.New("文档已删除")
+	}
+	if job.CancelRequested {
+		r.userCancel.Store(true)
+		return context.Canceled
+	}
+	if job.Attempts > job.MaxAttempts {
+		return fmt.Errorf("超过最大执行次数 %d", job.MaxAttempts)
+	}
+
+	p := NewDocumentProcessor()
+	var cp *ingestCheckpoint
+	if job.Stage != model.IngestStageParse {
+		loaded, err := loadIngestCheckpoint(kb.ID, doc.ID)
+		if err != nil {
+			log.Printf("[WARN] 读取入库检查点失败，重新解析: docID=%d, err=%v", doc.ID, err)
+			job.Stage = model.IngestStageParse
+		}
+		cp = loaded
+	}
+
+	if job.Stage == model.IngestStageParse {
+		parsed, err := p.parseDocument(ctx, &kb, &doc)
+		if err != nil {
+			return err
+		}
+		if err := saveIngestCheckpoint(kb.ID, doc.ID, parsed); err != nil {
+			return retryable(fmt.Errorf("保存入库检查点失败: %w", err))
+		}
+		cp = parsed
+		job.Stage, job.EmbeddedChunks, job.TotalChunks = model.IngestStageEmbed, 0, len(cp.Chunks)
+		if err := r.update(map[string]interface{}{
+			"stage":           job.Stage,
+			"embedded_chunks": 0,
+			"total_chunks":    job.TotalChunks,
+			"progress":        ingestProgress(job.Stage, 0, job.TotalChunks),
+		}); err != nil {
+			return retryable(err)
+		}
+	}
+
+	if job.Stage == model.IngestStageEmbed {
+		var limiter *ingestRateLimiter
+		if kb.EmbeddingModelID != nil {
+			limiter = embeddingRateLimiter(*kb.EmbeddingModelID, r.settings.RateLimit)
+		}
+		err := p.embedTextChunks(ctx, &kb, &doc, cp, job.EmbeddedChunks, r.settings.EmbedBatchSize, limiter, func(done int) error {
+			job.EmbeddedChunks = done
+			return r.update(map[string]interface{}{
+				"embedded_chunks": done,
+				"progress":        ingestProgress(job.Stage, done, job.TotalChunks),
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		if kb.MultimodalEnabled && len(cp.Images) > 0 {
+			var mmLimiter *ingestRateLimiter
+			if kb.MultimodalModelID != nil {
+				mmLimiter = embeddingRateLimiter(*kb.MultimodalModelID, r.settings.RateLimit)
+			}
+			if err := p.embedImages(ctx, &kb, &doc, cp, mmLimiter); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("[WARN] 图片索引失败: %v", err)
+			}
+			if err := saveIngestCheckpoint(kb.ID, doc.ID, cp); err != nil {
+				return retryable(fmt.Errorf("保存入库检查点失败: %w", err))
+			}
+		}
+
+		job.Stage = model.IngestStageFinalize
+		if err := r.update(map[string]interface{}{
+			"stage":    job.Stage,
+			"progress": ingestProgress(job.Stage, job.EmbeddedChunks, job.TotalChunks),
+		}); err != nil {
+			return retryable(err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.finalizeDocument(ctx, &kb, &doc, cp)
+}
+
+// finish 根据执行结果更新任务与文档状态
+func (r *ingestRun) finish(parent context.Context, err error) {
+	job := r.job
+	db := svc.Ctx.DB
+	now := time.Now()
+	p := NewDocumentProcessor()
+
+	switch {
+	case r.leaseLost.Load():
+		log.Printf("[WARN] 入库任务租约已被接管，放弃执行: jobID=%d", job.ID)
+
+	case err == nil:
+		r.update(map[string]interface{}{
+			"status":        model.IngestJobSucceeded,
+			"progress":      100,
+			"locked_by":     "",
+			"lease_until":   nil,
+			"error_message": nil,
+			"finished_at":   now,
+		})
+		deleteIngestCheckpoint(job.KnowledgeBaseID, job.DocumentID)
+
+	case r.userCancel.Load():
+		msg := "已取消"
+		if !errors.Is(err, context.Canceled) {
+			msg = err.Error()
+		}
+		r.update(map[string]interface{}{
+			"status":        model.IngestJobCanceled,
+			"locked_by":     "",
+			"lease_until":   nil,
+			"error_message": msg,
+			"finished_at":   now,
+		})
+		deleteIngestCheckpoint(job.KnowledgeBaseID, job.DocumentID)
+		// 已开始写入向量时索引处于半完成状态，清理该文档的向量与分块
+		if job.Stage != model.IngestStageParse {
+			var kb model.TKnowledgeBase
+			if db.Where("id = ?", job.KnowledgeBaseID).First(&kb).Error == nil {
+				if store, collection, err := vectorStoreOf(&kb); err == nil {
+					store.DeleteDocument(context.Background(), collection, job.DocumentID)
+				}
+			}
+			db.Where("document_id = ?", job.DocumentID).Delete(&model.TKnowledgeSegment{})
+		}
+		db.Model(&model.TKnowledgeDocument{}).Where("id = ?", job.DocumentID).Updates(map[string]interface{}{
+			"indexing_status": "canceled",
+			"error_message":   msg,
+			"updated_at":      now,
+		})
+		log.Printf("[INFO] 文档入库任务已取消: jobID=%d, docID=%d", job.ID, job.DocumentID)
+
+	case parent.Err() != nil:
+		// 服务停止：让出任务，本次执行不计入重试次数
+		r.update(map[string]interface{}{
+			"status":      model.IngestJobPending,
+			"locked_by":   "",
+			"lease_until": nil,
+			"attempts":    gorm.Expr("attempts - 1"),
+			"next_run_at": now,
+		})
+
+	case isTransientIngestError(err) && job.Attempts < job.MaxAttempts:
+		delay := ingestBackoff(job.Attempts)
+		msg := fmt.Sprintf("第 %d 次处理失败，%s 后重试: %v", job.Attempts, delay.Round(time.Second), err)
+		r.update(map[string]interface{}{
+			"status":        model.IngestJobPending,
+			"locked_by":     "",
+			"lease_until":   nil,
+			"next_run_at":   now.Add(delay),
+			"error_message": err.Error(),
+		})
+		db.Model(&model.TKnowledgeDocument{}).Where("id = ?", job.DocumentID).Updates(map[string]interface{}{
+			"indexing_status": "waiting",
+			"error_message":   msg,
+			"updated_at":      now,
+		})
+		log.Printf("[WARN] 文档入库任务将重试: jobID=%d, docID=%d, %s", job.ID, job.DocumentID, msg)
+
+	default:
+		r.update(map[string]interface{}{
+			"status":        model.IngestJobFailed,
+			"locked_by":     "",
+			"lease_until":   nil,
+			"error_message": err.Error(),
+			"finished_at":   now,
+		})
+		p.markFailed(context.Background(), job.DocumentID, err.Error())
+		log.Printf("[ERROR] 文档入库任务失败: jobID=%d, docID=%d, attempts=%d, err=%v", job.ID, job.DocumentID, job.Attempts, err)
+	}
+}
+
+// IngestProgress 文档入库任务进度
+type IngestProgress struct {
+	Status         string     `json:"status"` // pending / running / succeeded / failed / canceled
+	Stage          string     `json:"stage"`  // parse / embed / finalize
+	Percent        int        `json:"percent"`
+	TotalChunks    int        `json:"total_chunks"`
+	EmbeddedChunks int        `json:"embedded_chunks"`
+	Attempts       int        `json:"attempts"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"` // 等待重试时的下次执行时间
+	CancelPending  bool       `json:"cancel_pending,omitempty"`
+}
+
+// attachIngestProgress 为文档列表填充最近一次入库任务的进度
+func attachIngestProgress(docs []*KnowledgeDocumentInfo) {
+	if len(docs) == 0 {
+		return
+	}
+	ids := make([]int64, len(docs))
+	for i, d := range docs {
+		ids[i] = d.ID
+	}
+	var jobs []model.TKnowledgeIngestJob
+	svc.Ctx.DB.Where("id IN (?)", svc.Ctx.DB.Model(&model.TKnowledgeIngestJob{}).
+		Select("MAX(id)").Where("document_id IN ?", ids).Group("document_id")).
+		Find(&jobs)
+
+	byDoc := make(map[int64]*model.TKnowledgeIngestJob, len(jobs))
+	for i := range jobs {
+		byDoc[jobs[i].DocumentID] = &jobs[i]
+	}
+	for _, d := range docs {
+		job, ok := byDoc[d.ID]
+		if !ok {
+			continue
+		}
+		p := &IngestProgress{
+			Status:         job.Status,
+			Stage:          job.Stage,
+			Percent:        job.Progress,
+			TotalChunks:    job.TotalChunks,
+			EmbeddedChunks: job.EmbeddedChunks,
+			Attempts:       job.Attempts,
+			CancelPending:  job.CancelRequested && job.Status == model.IngestJobRunning,
+		}
+		if job.Status == model.IngestJobPending && job.Attempts > 0 {
+			next := job.NextRunAt
+			p.NextRunAt = &next
+		}
+		d.Progress = p
+	}
+}
+
+// ingestProgress 任务进度：解析 0–10，向量化按已写入分块数 10–90，收尾 90–100
+func ingestProgress(stage string, embedded, total int) int {
+	switch stage {
+	case model.IngestStageParse:
+		return 0
+	case model.IngestStageEmbed:
+		if total <= 0 {
+			return 90
+		}
+		return 10 + 80*embedded/total
+	case model.IngestStageFinalize:
+		return 90
+	}
+	return 100
+}
+
+// -----------------------------------------------
+// 重试与限流
+// -----------------------------------------------
+
+// retryableError 标记可重试的临时错误（向量库写入失败等）
+type retryableError struct{ err error }
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+// isTransientIngestError 是否为可重试的临时错误：显式标记、网络错误、超时、模型 API 限流（429）或服务端错误（5xx）
+func isTransientIngestError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var re *retryableError
+	if errors.As(err, &re) {
+		return true
+	}
+	var he *HTTPStatusError
+	if errors.As(err, &he) {
+		return he.StatusCode == 408 || he.StatusCode == 429 || he.StatusCode >= 500
+	}
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// ingestBackoff 第 attempt 次失败后的重试等待：10s 起指数增长，上限 10 分钟，附加最多 20% 随机抖动
+func ingestBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := 10 * time.Second
+	for i := 1; i < attempt && delay < 10*time.Minute; i++ {
+		delay *= 2
+	}
+	if delay > 10*time.Minute {
+		delay = 10 * time.Minute
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// ingestRateLimiter 按固定间隔放行请求的限流器，nil 表示不限流
+type ingestRateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newIngestRateLimiter(perSecond float64) *ingestRateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &ingestRateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait 等待直到允许发出下一次请求，ctx 结束时返回错误
+func (l *ingestRateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// embeddingLimiters 各嵌入模型的限流器（本实例内共享）
+var embeddingLimiters sync.Map
+
+func embeddingRateLimiter(modelID int64, perSecond float64) *ingestRateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if l, ok := embeddingLimiters.Load(modelID); ok {
+		return l.(*ingestRateLimiter)
+	}
+	l, _ := embeddingLimiters.LoadOrStore(modelID, newIngestRateLimiter(perSecond))
+	return l.(*ingestRateLimiter)
+}
@@ -0,0 +1,108 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"yqhp/gulu/internal/model"
+)
+
+// TestIngestBackoff 退避时间指数增长，上限 10 分钟，抖动不超过 20%
+func TestIngestBackoff(t *testing.T) {
+	cases := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{10, 10 * time.Minute},
+	}
+	for _, c := range cases {
+		for i := 0; i < 20; i++ {
+			d := ingestBackoff(c.attempt)
+			if d < c.base || d > c.base+c.base/5 {
+				t.Fatalf("attempt %d: backoff %v out of [%v, %v]", c.attempt, d, c.base, c.base+c.base/5)
+			}
+		}
+	}
+}
+
+// TestIsTransientIngestError 限流、服务端错误和网络错误可重试，参数错误和解析错误不重试
+func TestIsTransientIngestError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("不支持的文件类型"), false},
+		{&HTTPStatusError{StatusCode: 400}, false},
+		{&HTTPStatusError{StatusCode: 401}, false},
+		{fmt.Errorf("向量化失败: %w", &HTTPStatusError{StatusCode: 429}), true},
+		{&HTTPStatusError{StatusCode: 503}, true},
+		{&url.Error{Op: "Post", URL: "http://x", Err: errors.New("connection refused")}, true},
+		{context.DeadlineExceeded, true},
+		{retryable(errors.New("写入向量库失败")), true},
+	}
+	for _, c := range cases {
+		if got := isTransientIngestError(c.err); got != c.want {
+			t.Errorf("isTransientIngestError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+// TestIngestRateLimiter 按间隔放行请求，ctx 取消时立即返回
+func TestIngestRateLimiter(t *testing.T) {
+	if l := newIngestRateLimiter(0); l != nil {
+		t.Fatal("expected nil limiter when rate limit disabled")
+	}
+	var unlimited *ingestRateLimiter
+	if err := unlimited.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	l := newIngestRateLimiter(50) // 20ms 间隔
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 55*time.Millisecond {
+		t.Fatalf("expected at least 3 intervals, elapsed %v", elapsed)
+	}
+
+	slow := newIngestRateLimiter(0.1)
+	slow.Wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := slow.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+// TestIngestProgress 解析阶段 0%，向量化阶段 10–90%，写入阶段 90%
+func TestIngestProgress(t *testing.T) {
+	cases := []struct {
+		stage           string
+		embedded, total int
+		want            int
+	}{
+		{model.IngestStageParse, 0, 0, 0},
+		{model.IngestStageEmbed, 0, 40, 10},
+		{model.IngestStageEmbed, 20, 40, 50},
+		{model.IngestStageEmbed, 40, 40, 90},
+		{model.IngestStageEmbed, 0, 0, 90},
+		{model.IngestStageFinalize, 40, 40, 90},
+	}
+	for _, c := range cases {
+		if got := ingestProgress(c.stage, c.embedded, c.total); got != c.want {
+			t.Errorf("ingestProgress(%s, %d, %d) = %d, want %d", c.stage, c.embedded, c.total, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,676 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+)
+
+// -----------------------------------------------
+// 文档元数据与访问控制
+// 文档元数据 = 自动提取（Markdown front-matter、文件路径）+ 用户指定（同名键覆盖自动提取），
+// 与访问控制一起写入向量点附加数据，检索时由各向量库后端按过滤表达式筛选；
+// 关键词检索在内存中按同样的规则筛选文档。
+// -----------------------------------------------
+
+const (
+	FilterOpEq     = "eq"
+	FilterOpIn     = "in"
+	FilterOpRange  = "range"
+	FilterOpExists = "exists"
+
+	// 向量点附加数据中的元数据与访问控制字段
+	payloadMetaKey          = "meta"
+	payloadACLRestrictedKey = "acl_restricted"
+	payloadACLUsersKey      = "acl_users"
+
+	maxMetadataKeys        = 64
+	maxMetadataValueLen    = 512 // 字符串值的最大字符数
+	maxMetadataListLen     = 64
+	maxFilterConditions    = 32
+	maxDocumentACLUsers    = 500
+	maxMetadataKeysForTool = 30 // 注入 AI 节点知识库工具说明的元数据键数量上限
+)
+
+// metadataKeyPattern 元数据键只允许字母、数字、下划线（Qdrant 路径与 SQL 参数均无需转义）
+var metadataKeyPattern = regexp.MustCompile(`^[\p{L}\p{N}_]{1,64}$`)
+
+// MetadataFilter 元数据过滤表达式（与 Qdrant filter 结构一致）：
+// must 全部满足、should 至少满足一个、must_not 全部不满足
+type MetadataFilter struct {
+	Must    []MetadataCondition `json:"must,omitempty"`
+	Should  []MetadataCondition `json:"should,omitempty"`
+	MustNot []MetadataCondition `json:"must_not,omitempty"`
+}
+
+// MetadataCondition 单个过滤条件，元数据值为数组时任一元素满足即视为满足
+type MetadataCondition struct {
+	Key    string        `json:"key"`
+	Op     string        `json:"op"`               // eq / in / range / exists
+	Value  interface{}   `json:"value,omitempty"`  // eq：字符串 / 数值 / 布尔
+	Values []interface{} `json:"values,omitempty"` // in：候选值，满足任一即可
+	Gt     *float64      `json:"gt,omitempty"`     // range：数值区间
+	Gte    *float64      `json:"gte,omitempty"`
+	Lt     *float64      `json:"lt,omitempty"`
+	Lte    *float64      `json:"lte,omitempty"`
+}
+
+// VectorFilter 向量检索的过滤条件，nil 表示不过滤
+type VectorFilter struct {
+	Metadata []*MetadataFilter // 元数据过滤，多个表达式需同时满足
+	ACL      bool              // 是否按文档访问控制过滤
+	UserID   int64             // ACL 为 true 时只返回未设置访问控制、或访问控制包含该用户的文档
+}
+
+// Validate 校验过滤表达式
+func (f *MetadataFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	if n := len(f.Must) + len(f.Should) + len(f.MustNot); n > maxFilterConditions {
+		return fmt.Errorf("过滤条件过多（%d 个），最多 %d 个", n, maxFilterConditions)
+	}
+	for _, group := range [][]MetadataCondition{f.Must, f.Should, f.MustNot} {
+		for i := range group {
+			if err := group[i].validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *MetadataFilter) empty() bool {
+	return f == nil || len(f.Must)+len(f.Should)+len(f.MustNot) == 0
+}
+
+func (c *MetadataCondition) validate() error {
+	if !metadataKeyPattern.MatchString(c.Key) {
+		return fmt.Errorf("非法的元数据键: %q（只允许字母、数字、下划线）", c.Key)
+	}
+	switch c.Op {
+	case FilterOpEq:
+		if !isMetadataScalar(c.Value) {
+			return fmt.Errorf("%s: eq 条件的 value 必须为字符串、数值或布尔值", c.Key)
+		}
+	case FilterOpIn:
+		if len(c.Values) == 0 {
+			return fmt.Errorf("%s: in 条件的 values 不能为空", c.Key)
+		}
+		for _, v := range c.Values {
+			if !isMetadataScalar(v) {
+				return fmt.Errorf("%s: in 条件的 values 只能包含字符串、数值或布尔值", c.Key)
+			}
+		}
+	case FilterOpRange:
+		if c.Gt == nil && c.Gte == nil && c.Lt == nil && c.Lte == nil {
+			return fmt.Errorf("%s: range 条件至少需要 gt / gte / lt / lte 之一", c.Key)
+		}
+	case FilterOpExists:
+	default:
+		return fmt.Errorf("%s: 不支持的过滤操作 %q（可选 eq / in / range / exists）", c.Key, c.Op)
+	}
+	return nil
+}
+
+// match 判断元数据是否满足过滤表达式
+func (f *MetadataFilter) match(meta map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+	for i := range f.Must {
+		if !f.Must[i].match(meta) {
+			return false
+		}
+	}
+	for i := range f.MustNot {
+		if f.MustNot[i].match(meta) {
+			return false
+		}
+	}
+	if len(f.Should) == 0 {
+		return true
+	}
+	for i := range f.Should {
+		if f.Should[i].match(meta) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *MetadataCondition) match(meta map[string]interface{}) bool {
+	values := metadataValues(meta[c.Key])
+	switch c.Op {
+	case FilterOpExists:
+		return len(values) > 0
+	case FilterOpEq:
+		for _, v := range values {
+			if metadataEqual(v, c.Value) {
+				return true
+			}
+		}
+	case FilterOpIn:
+		for _, v := range values {
+			for _, want := range c.Values {
+				if metadataEqual(v, want) {
+					return true
+				}
+			}
+		}
+	case FilterOpRange:
+		for _, v := range values {
+			if n, ok := metadataNumber(v); ok && c.inRange(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *MetadataCondition) inRange(n float64) bool {
+	return (c.Gt == nil || n > *c.Gt) && (c.Gte == nil || n >= *c.Gte) &&
+		(c.Lt == nil || n < *c.Lt) && (c.Lte == nil || n <= *c.Lte)
+}
+
+// matchPayload 判断向量点附加数据（或 documentPayload 构造的文档附加数据）是否满足过滤条件
+func (f *VectorFilter) matchPayload(payload map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+	if f.ACL && !aclAllows(payload, f.UserID) {
+		return false
+	}
+	meta, _ := payload[payloadMetaKey].(map[string]interface{})
+	for _, mf := range f.Metadata {
+		if !mf.match(meta) {
+			return false
+		}
+	}
+	return true
+}
+
+// aclAllows 未设置访问控制的点对所有用户可见，否则只对列出的用户可见
+func aclAllows(payload map[string]interface{}, userID int64) bool {
+	if restricted, _ := payload[payloadACLRestrictedKey].(bool); !restricted {
+		return true
+	}
+	for _, v := range metadataValues(payload[payloadACLUsersKey]) {
+		if n, ok := metadataNumber(v); ok && n == float64(userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataValues 将元数据值展开为标量列表（缺失、null 与空数组返回空）
+func metadataValues(v interface{}) []interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		values := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			if item != nil {
+				values = append(values, item)
+			}
+		}
+		return values
+	case []string:
+		values := make([]interface{}, len(val))
+		for i, item := range val {
+			values[i] = item
+		}
+		return values
+	case []int64:
+		values := make([]interface{}, len(val))
+		for i, item := range val {
+			values[i] = item
+		}
+		return values
+	default:
+		return []interface{}{v}
+	}
+}
+
+// metadataEqual 同类型比较，数值按浮点数比较（字符串 "1" 与数值 1 不相等，与 Qdrant 一致）
+func metadataEqual(a, b interface{}) bool {
+	if x, ok := metadataNumber(a); ok {
+		y, ok := metadataNumber(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	}
+	return false
+}
+
+func metadataNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func isMetadataScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, bool:
+		return true
+	}
+	_, ok := metadataNumber(v)
+	return ok
+}
+
+// -----------------------------------------------
+// 元数据规范化与提取
+// -----------------------------------------------
+
+// normalizeMetadataValue 规范化元数据值：字符串 / 布尔 / 数值（整数统一为 int64，与 Qdrant 整数匹配一致）
+// 或由它们组成的数组；日期转为字符串，其他类型（嵌套对象等）不支持
+func normalizeMetadataValue(v interface{}) (interface{}, bool) {
+	switch val := v.(type) {
+	case string:
+		if utf8.RuneCountInString(val) > maxMetadataValueLen {
+			return nil, false
+		}
+		return val, true
+	case bool:
+		return val, true
+	case time.Time:
+		if val.Hour() == 0 && val.Minute() == 0 && val.Second() == 0 && val.Nanosecond() == 0 {
+			return val.Format("2006-01-02"), true
+		}
+		return val.Format(time.RFC3339), true
+	case []interface{}:
+		if len(val) > maxMetadataListLen {
+			return nil, false
+		}
+		list := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			if _, nested := item.([]interface{}); nested {
+				return nil, false
+			}
+			n, ok := normalizeMetadataValue(item)
+			if !ok {
+				return nil, false
+			}
+			list = append(list, n)
+		}
+		return list, true
+	case []string:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = item
+		}
+		return normalizeMetadataValue(list)
+	}
+	if n, ok := metadataNumber(v); ok {
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, false
+		}
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n), true
+		}
+		return n, true
+	}
+	return nil, false
+}
+
+// normalizeMetadata 校验并规范化用户指定的元数据
+func normalizeMetadata(meta map[string]interface{}) (model.DocumentMetadata, error) {
+	if len(meta) == 0 {
+		return nil, nil
+	}
+	if len(meta) > maxMetadataKeys {
+		return nil, fmt.Errorf("元数据最多 %d 个键", maxMetadataKeys)
+	}
+	result := make(model.DocumentMetadata, len(meta))
+	for k, v := range meta {
+		if !metadataKeyPattern.MatchString(k) {
+			return nil, fmt.Errorf("非法的元数据键: %q（只允许字母、数字、下划线）", k)
+		}
+		n, ok := normalizeMetadataValue(v)
+		if !ok {
+			return nil, fmt.Errorf("元数据 %s 的值不合法：只支持字符串（最长 %d 字符）、数值、布尔值及其数组", k, maxMetadataValueLen)
+		}
+		result[k] = n
+	}
+	return result, nil
+}
+
+// metadataKeyReplacer 自动提取的键中不允许的字符替换为下划线
+var metadataKeyReplacer = regexp.MustCompile(`[^\p{L}\p{N}_]+`)
+
+// sanitizeMetadataKey 将 front-matter 的键转换为合法的元数据键，无法转换时返回空
+func sanitizeMetadataKey(key string) string {
+	key = strings.Trim(metadataKeyReplacer.ReplaceAllString(strings.TrimSpace(key), "_"), "_")
+	if !metadataKeyPattern.MatchString(key) {
+		return ""
+	}
+	return key
+}
+
+// extractFrontMatter 解析 Markdown 开头的 YAML front-matter，返回元数据与去掉 front-matter 后的正文；
+// 没有 front-matter 或解析失败时原样返回正文
+func extractFrontMatter(text string) (map[string]interface{}, string) {
+	body := strings.TrimPrefix(text, "\ufeff")
+	firstLine, rest, ok := strings.Cut(body, "\n")
+	if !ok || strings.TrimRight(firstLine, " \r") != "---" {
+		return nil, text
+	}
+	var block strings.Builder
+	for {
+		line, next, more := strings.Cut(rest, "\n")
+		trimmed := strings.TrimRight(line, " \r")
+		if trimmed == "---" || trimmed == "..." {
+			rest = next
+			break
+		}
+		if !more {
+			return nil, text // 没有结束标记，不是 front-matter
+		}
+		block.WriteString(line)
+		block.WriteByte('\n')
+		rest = next
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal([]byte(block.String()), &raw); err != nil || len(raw) == 0 {
+		return nil, text
+	}
+	meta := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		key := sanitizeMetadataKey(k)
+		if key == "" || len(meta) >= maxMetadataKeys {
+			continue
+		}
+		if n, ok := normalizeMetadataValue(v); ok {
+			meta[key] = n
+		}
+	}
+	return meta, strings.TrimLeft(rest, "\r\n")
+}
+
+// pathMetadata 从文档路径提取元数据：path（数据源内路径、URL 或文件名）、dir（所在目录）、
+// dirs（各级上级目录，便于按目录树过滤）、host（网页来源的域名）、ext（扩展名）
+func pathMetadata(doc *model.TKnowledgeDocument) map[string]interface{} {
+	p := derefString(doc.SourceKey)
+	if p == "" {
+		p = doc.Name
+	}
+	meta := map[string]interface{}{}
+	if utf8.RuneCountInString(p) <= maxMetadataValueLen {
+		meta["path"] = p
+	}
+
+	filePath := p
+	if u, err := url.Parse(p); err == nil && u.Scheme != "" && u.Host != "" {
+		meta["host"] = u.Host
+		filePath = u.Path
+	}
+	filePath = strings.Trim(strings.ReplaceAll(filePath, "\\", "/"), "/")
+	if dir := path.Dir(filePath); dir != "." && dir != "/" && utf8.RuneCountInString(dir) <= maxMetadataValueLen {
+		meta["dir"] = dir
+		var dirs []interface{}
+		parts := strings.Split(dir, "/")
+		for i := range parts {
+			dirs = append(dirs, strings.Join(parts[:i+1], "/"))
+		}
+		if len(dirs) <= maxMetadataListLen {
+			meta["dirs"] = dirs
+		}
+	}
+	if ext := strings.TrimPrefix(strings.ToLower(path.Ext(filePath)), "."); ext != "" {
+		meta["ext"] = ext
+	}
+	return meta
+}
+
+// extractAutoMetadata 解析阶段提取文档元数据，Markdown 文档同时去掉正文中的 front-matter
+func extractAutoMetadata(doc *model.TKnowledgeDocument, text string) (model.DocumentMetadata, string) {
+	meta := model.DocumentMetadata(pathMetadata(doc))
+	if derefString(doc.FileType) == "md" {
+		fm, body := extractFrontMatter(text)
+		for k, v := range fm {
+			meta[k] = v // front-matter 优先于路径信息
+		}
+		text = body
+	}
+	return meta, text
+}
+
+// effectiveMetadata 文档生效的元数据：用户指定的键覆盖自动提取的同名键。
+// 从数据库读出的数值均为 float64，重新规范化后整数恢复为 int64
+func effectiveMetadata(doc *model.TKnowledgeDocument) map[string]interface{} {
+	meta := make(map[string]interface{}, len(doc.AutoMetadata)+len(doc.Metadata))
+	for _, source := range []model.DocumentMetadata{doc.AutoMetadata, doc.Metadata} {
+		for k, v := range source {
+			if n, ok := normalizeMetadataValue(v); ok {
+				meta[k] = n
+			}
+		}
+	}
+	return meta
+}
+
+// documentPayload 文档写入每个向量点的元数据与访问控制字段
+func documentPayload(doc *model.TKnowledgeDocument) map[string]interface{} {
+	users := []interface{}{}
+	restricted := doc.ACL != nil
+	if restricted {
+		for _, id := range doc.ACL.Users {
+			users = append(users, id)
+		}
+	}
+	return map[string]interface{}{
+		payloadMetaKey:          effectiveMetadata(doc),
+		payloadACLRestrictedKey: restricted,
+		payloadACLUsersKey:      users,
+	}
+}
+
+// -----------------------------------------------
+// 检索范围（元数据过滤 + 访问控制）
+// -----------------------------------------------
+
+// newVectorFilter 组合元数据过滤与访问控制。aclUserID 为 nil 时不做访问控制（评测等管理操作），
+// 知识库创建者可检索全部文档
+func newVectorFilter(kb *model.TKnowledgeBase, filters []*MetadataFilter, aclUserID *int64) *VectorFilter {
+	f := &VectorFilter{}
+	for _, mf := range filters {
+		if !mf.empty() {
+			f.Metadata = append(f.Metadata, mf)
+		}
+	}
+	if aclUserID != nil && !(kb.CreatedBy != nil && *aclUserID != 0 && *kb.CreatedBy == *aclUserID) {
+		f.ACL = true
+		f.UserID = *aclUserID
+	}
+	if len(f.Metadata) == 0 && !f.ACL {
+		return nil
+	}
+	return f
+}
+
+// allowedDocuments 返回知识库中满足过滤条件的文档 ID（关键词检索在内存中筛选）
+func allowedDocuments(kbID int64, filter *VectorFilter) (map[int64]bool, error) {
+	var docs []model.TKnowledgeDocument
+	if err := svc.Ctx.DB.Select("id, metadata, auto_metadata, acl").
+		Where("knowledge_base_id = ?", kbID).Find(&docs).Error; err != nil {
+		return nil, err
+	}
+	allowed := make(map[int64]bool, len(docs))
+	for i := range docs {
+		if filter.matchPayload(documentPayload(&docs[i])) {
+			allowed[docs[i].ID] = true
+		}
+	}
+	return allowed, nil
+}
+
+// scopeCoversGraph 图谱检索的结果不区分来源文档，只有检索范围不受限时才参与召回
+func scopeCoversGraph(kbID int64, filter *VectorFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if len(filter.Metadata) > 0 {
+		return false
+	}
+	var restricted int64
+	svc.Ctx.DB.Model(&model.TKnowledgeDocument{}).
+		Where("knowledge_base_id = ? AND acl IS NOT NULL", kbID).Count(&restricted)
+	return restricted == 0
+}
+
+// -----------------------------------------------
+// 文档元数据与访问控制管理
+// -----------------------------------------------
+
+// UpdateDocumentMetadataReq 更新文档元数据 / 访问控制请求，字段为空表示不修改
+type UpdateDocumentMetadataReq struct {
+	Metadata map[string]interface{} `json:"metadata"`  // 用户指定的元数据（整体替换），传 {} 清空
+	ACL      *model.DocumentACL     `json:"acl"`       // 访问控制，users 为可检索的用户 ID
+	ClearACL bool                   `json:"clear_acl"` // 取消访问控制，恢复为项目成员均可检索
+}
+
+// validateDocumentACL 校验访问控制设置
+func validateDocumentACL(acl *model.DocumentACL) (*model.DocumentACL, error) {
+	if acl == nil {
+		return nil, nil
+	}
+	if len(acl.Users) > maxDocumentACLUsers {
+		return nil, fmt.Errorf("访问控制最多 %d 个用户", maxDocumentACLUsers)
+	}
+	seen := make(map[int64]bool, len(acl.Users))
+	users := make([]int64, 0, len(acl.Users))
+	for _, id := range acl.Users {
+		if id <= 0 {
+			return nil, fmt.Errorf("非法的用户ID: %d", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			users = append(users, id)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i] < users[j] })
+	return &model.DocumentACL{Users: users}, nil
+}
+
+// documentProcessingStatuses 正在执行入库任务的文档状态（向量写入中，不能同步修改附加数据）
+var documentProcessingStatuses = []string{"parsing", "cleaning", "splitting", "indexing"}
+
+// UpdateDocumentMetadata 更新文档的元数据与访问控制，已索引的文档同步更新向量点附加数据（无需重新向量化）
+func (l *KnowledgeBaseLogic) UpdateDocumentMetadata(kbID, docID int64, req *UpdateDocumentMetadataReq) (*KnowledgeDocumentInfo, error) {
+	db := svc.Ctx.DB
+
+	var kb model.TKnowledgeBase
+	if err := db.Where("id = ? AND is_delete = 0", kbID).First(&kb).Error; err != nil {
+		return nil, errors.New("知识库不存在")
+	}
+	var doc model.TKnowledgeDocument
+	if err := db.Where("id = ? AND knowledge_base_id = ?", docID, kbID).First(&doc).Error; err != nil {
+		return nil, errors.New("文档不存在")
+	}
+	status := derefString(doc.IndexingStatus)
+	for _, s := range documentProcessingStatuses {
+		if status == s {
+			return nil, errors.New("文档正在处理，请处理完成后再修改元数据")
+		}
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if req.Metadata != nil {
+		meta, err := normalizeMetadata(req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		doc.Metadata = meta
+		updates["metadata"] = meta
+	}
+	if req.ClearACL {
+		doc.ACL = nil
+		updates["acl"] = nil
+	} else if req.ACL != nil {
+		acl, err := validateDocumentACL(req.ACL)
+		if err != nil {
+			return nil, err
+		}
+		doc.ACL = acl
+		updates["acl"] = acl
+	}
+	if len(updates) == 1 {
+		return nil, errors.New("请指定要修改的元数据或访问控制")
+	}
+
+	// 先更新向量点再写库：向量库更新失败时保持一致，关键词检索以数据库为准
+	if status == "completed" && kb.QdrantCollection != nil && *kb.QdrantCollection != "" {
+		store, collection, err := vectorStoreOf(&kb)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.SetDocumentPayload(l.ctx, collection, doc.ID, documentPayload(&doc)); err != nil {
+			return nil, fmt.Errorf("更新向量附加数据失败: %w", err)
+		}
+	}
+	if err := db.Model(&model.TKnowledgeDocument{}).Where("id = ?", doc.ID).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return l.toDocumentInfo(&doc), nil
+}
+
+// MetadataKeys 知识库文档中出现的元数据键（按出现文档数降序），供 AI 节点的知识库工具提示可过滤字段
+func (l *KnowledgeBaseLogic) MetadataKeys(kbID int64) []string {
+	var docs []model.TKnowledgeDocument
+	if err := svc.Ctx.DB.Select("id, metadata, auto_metadata").
+		Where("knowledge_base_id = ?", kbID).Find(&docs).Error; err != nil {
+		return nil
+	}
+	counts := make(map[string]int)
+	for i := range docs {
+		for k := range effectiveMetadata(&docs[i]) {
+			counts[k]++
+		}
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > maxMetadataKeysForTool {
+		keys = keys[:maxMetadataKeysForTool]
+	}
+	return keys
+}
@@ -0,0 +1,285 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"yqhp/gulu/internal/model"
+)
+
+func parseMetadataFilter(t *testing.T, s string) *MetadataFilter {
+	t.Helper()
+	var f MetadataFilter
+	if err := json.Unmarshal([]byte(s), &f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return &f
+}
+
+// TestMetadataFilterMatch must / should / must_not 语义，数组值任一元素满足即可
+func TestMetadataFilterMatch(t *testing.T) {
+	meta := map[string]interface{}{
+		"version": "2.0",
+		"year":    int64(2024),
+		"tags":    []interface{}{"deploy", "k8s"},
+		"draft":   false,
+		"empty":   []interface{}{},
+	}
+	cases := []struct {
+		filter string
+		want   bool
+	}{
+		{`{"must":[{"key":"version","op":"eq","value":"2.0"}]}`, true},
+		{`{"must":[{"key":"version","op":"eq","value":2}]}`, false},
+		{`{"must":[{"key":"year","op":"eq","value":2024}]}`, true},
+		{`{"must":[{"key":"tags","op":"eq","value":"k8s"}]}`, true},
+		{`{"must":[{"key":"tags","op":"in","values":["docker","deploy"]}]}`, true},
+		{`{"must":[{"key":"year","op":"range","gte":2024,"lt":2025}]}`, true},
+		{`{"must":[{"key":"year","op":"range","gt":2024}]}`, false},
+		{`{"must":[{"key":"draft","op":"eq","value":false}]}`, true},
+		{`{"must":[{"key":"empty","op":"exists"}]}`, false},
+		{`{"must_not":[{"key":"missing","op":"exists"}]}`, true},
+		{`{"must_not":[{"key":"missing","op":"eq","value":"x"}]}`, true},
+		{`{"should":[{"key":"version","op":"eq","value":"1.0"},{"key":"tags","op":"eq","value":"deploy"}]}`, true},
+		{`{"should":[{"key":"version","op":"eq","value":"1.0"}]}`, false},
+	}
+	for _, c := range cases {
+		if got := parseMetadataFilter(t, c.filter).match(meta); got != c.want {
+			t.Errorf("%s: match = %v, want %v", c.filter, got, c.want)
+		}
+	}
+}
+
+// TestMetadataFilterValidate 非法的键、操作与参数被拒绝
+func TestMetadataFilterValidate(t *testing.T) {
+	invalid := []string{
+		`{"must":[{"key":"a.b","op":"eq","value":"x"}]}`,
+		`{"must":[{"key":"version","op":"like","value":"x"}]}`,
+		`{"must":[{"key":"version","op":"eq"}]}`,
+		`{"must":[{"key":"version","op":"eq","value":{"x":1}}]}`,
+		`{"must":[{"key":"version","op":"in","values":[]}]}`,
+		`{"must":[{"key":"year","op":"range"}]}`,
+	}
+	for _, s := range invalid {
+		var f MetadataFilter
+		if err := json.Unmarshal([]byte(s), &f); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", s)
+		}
+	}
+	var nilFilter *MetadataFilter
+	if err := nilFilter.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVectorFilterACL 未设置访问控制的点对所有用户可见，受限的点只对列出的用户可见
+func TestVectorFilterACL(t *testing.T) {
+	acl := &model.DocumentACL{Users: []int64{7, 3, 7}}
+	acl, err := validateDocumentACL(acl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(acl.Users, []int64{3, 7}) {
+		t.Fatalf("acl users = %v, want [3 7]", acl.Users)
+	}
+
+	open := documentPayload(&model.TKnowledgeDocument{Metadata: model.DocumentMetadata{"team": "ops"}})
+	restricted := documentPayload(&model.TKnowledgeDocument{Metadata: model.DocumentMetadata{"team": "ops"}, ACL: acl})
+
+	f := &VectorFilter{ACL: true, UserID: 3}
+	if !f.matchPayload(open) || !f.matchPayload(restricted) {
+		t.Fatal("user 3 should see both documents")
+	}
+	f.UserID = 9
+	if !f.matchPayload(open) || f.matchPayload(restricted) {
+		t.Fatal("user 9 should only see the unrestricted document")
+	}
+	// 旧版本写入的点没有访问控制字段，视为未设置
+	if !f.matchPayload(map[string]interface{}{"document_name": "old.md"}) {
+		t.Fatal("legacy point should be visible")
+	}
+
+	f = &VectorFilter{Metadata: []*MetadataFilter{parseMetadataFilter(t, `{"must":[{"key":"team","op":"eq","value":"dev"}]}`)}}
+	if f.matchPayload(open) {
+		t.Fatal("metadata filter should exclude team=ops")
+	}
+	var none *VectorFilter
+	if !none.matchPayload(restricted) {
+		t.Fatal("nil filter should match everything")
+	}
+}
+
+// TestQueryVectorsRequiresACLUser 内部向量检索必须指定执行用户，避免绕过文档访问控制
+func TestQueryVectorsRequiresACLUser(t *testing.T) {
+	l := NewKnowledgeBaseLogic(context.Background())
+	for _, userID := range []*int64{nil, new(int64)} {
+		_, err := l.QueryVectors(1, &VectorQueryReq{Vector: []float32{0.1}, ACLUserID: userID})
+		if err == nil || !strings.Contains(err.Error(), "acl_user_id") {
+			t.Fatalf("QueryVectors(acl=%v) err = %v, want missing acl_user_id", userID, err)
+		}
+	}
+}
+
+// TestExtractAutoMetadata 路径元数据与 Markdown front-matter，front-matter 从正文中去掉
+func TestExtractAutoMetadata(t *testing.T) {
+	fileType := "md"
+	sourceKey := "docs/ops/deploy.md"
+	doc := &model.TKnowledgeDocument{Name: "deploy.md", FileType: &fileType, SourceKey: &sourceKey}
+	text := "---\ntitle: 部署指南\nversion: 2\ntags: [deploy, k8s]\nupdated: 2024-05-01\nnested: {a: 1}\nbad key!: x\n---\n# 部署\n正文"
+
+	meta, body := extractAutoMetadata(doc, text)
+	if body != "# 部署\n正文" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	want := model.DocumentMetadata{
+		"path":    "docs/ops/deploy.md",
+		"dir":     "docs/ops",
+		"dirs":    []interface{}{"docs", "docs/ops"},
+		"ext":     "md",
+		"title":   "部署指南",
+		"version": int64(2),
+		"tags":    []interface{}{"deploy", "k8s"},
+		"updated": "2024-05-01",
+		"bad_key": "x",
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Fatalf("auto metadata = %#v\nwant %#v", meta, want)
+	}
+
+	// 用户元数据覆盖同名的自动提取键
+	doc.AutoMetadata = meta
+	doc.Metadata = model.DocumentMetadata{"version": "3.0"}
+	if got := effectiveMetadata(doc)["version"]; got != "3.0" {
+		t.Fatalf("effective version = %v, want 3.0", got)
+	}
+
+	urlKey := "https://docs.example.com/guide/install.html"
+	htmlType := "html"
+	page := &model.TKnowledgeDocument{Name: "install", FileType: &htmlType, SourceKey: &urlKey}
+	meta, _ = extractAutoMetadata(page, "---\ntitle: x\n---\n")
+	if meta["host"] != "docs.example.com" || meta["dir"] != "guide" || meta["ext"] != "html" || meta["title"] != nil {
+		t.Fatalf("unexpected url metadata: %v", meta)
+	}
+}
+
+// TestNormalizeMetadata 用户元数据的值类型与键校验
+func TestNormalizeMetadata(t *testing.T) {
+	meta, err := normalizeMetadata(map[string]interface{}{"n": float64(3), "f": 1.5, "tags": []interface{}{"a", float64(1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta["n"] != int64(3) || meta["f"] != 1.5 || !reflect.DeepEqual(meta["tags"], []interface{}{"a", int64(1)}) {
+		t.Fatalf("unexpected normalized metadata: %#v", meta)
+	}
+	for _, bad := range []map[string]interface{}{
+		{"a-b": "x"},
+		{"obj": map[string]interface{}{"x": 1}},
+		{"long": strings.Repeat("x", maxMetadataValueLen+1)},
+	} {
+		if _, err := normalizeMetadata(bad); err == nil {
+			t.Errorf("%v: expected error", bad)
+		}
+	}
+}
+
+// TestEmbeddedVectorStore_FilterAndSetPayload 内置向量库按元数据与访问控制过滤，修改附加数据后重新打开仍然生效
+func TestEmbeddedVectorStore_FilterAndSetPayload(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := newEmbeddedVectorStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnsureCollection(ctx, "kb_1", CollectionVectorConfig{TextDimension: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for docID, version := range map[int64]string{1: "1.0", 2: "2.0"} {
+		points := embeddedTestPoints(docID, []float32{1, 0})
+		for k, v := range documentPayload(&model.TKnowledgeDocument{Metadata: model.DocumentMetadata{"version": version}}) {
+			points[0].Metadata[k] = v
+		}
+		if err := store.Upsert(ctx, "kb_1", "text", points); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v2 := &VectorFilter{Metadata: []*MetadataFilter{parseMetadataFilter(t, `{"must":[{"key":"version","op":"eq","value":"2.0"}]}`)}}
+	hits, err := store.Search(ctx, "kb_1", "text", []float32{1, 0}, 5, 0, v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].DocumentID != 2 {
+		t.Fatalf("unexpected filtered hits: %+v", hits)
+	}
+
+	restricted := &model.TKnowledgeDocument{
+		Metadata: model.DocumentMetadata{"version": "2.0"},
+		ACL:      &model.DocumentACL{Users: []int64{5}},
+	}
+	if err := store.SetDocumentPayload(ctx, "kb_1", 2, documentPayload(restricted)); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := newEmbeddedVectorStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aclFilter := &VectorFilter{ACL: true, UserID: 9}
+	hits, err = reopened.Search(ctx, "kb_1", "text", []float32{1, 0}, 5, 0, aclFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].DocumentID != 1 {
+		t.Fatalf("user 9 should only see document 1: %+v", hits)
+	}
+	aclFilter.UserID = 5
+	if hits, _ = reopened.Search(ctx, "kb_1", "text", []float32{1, 0}, 5, 0, aclFilter); len(hits) != 2 {
+		t.Fatalf("user 5 should see both documents: %+v", hits)
+	}
+	if hits[0].Metadata["document_name"] != "doc.md" {
+		t.Fatalf("document_name should be kept after payload update: %v", hits[0].Metadata)
+	}
+}
+
+// TestBM25SearchFiltered 关键词检索只返回允许的文档中的分块
+func TestBM25SearchFiltered(t *testing.T) {
+	idx := newBM25Index([]bm25Segment{
+		{ID: 1, DocumentID: 10, Content: "网关部署步骤"},
+		{ID: 2, DocumentID: 20, Content: "网关部署注意事项"},
+	})
+	if hits := idx.Search("网关部署", 5); len(hits) != 2 {
+		t.Fatalf("expected 2 hits, got %+v", hits)
+	}
+	hits := idx.SearchFiltered("网关部署", 5, func(docID int64) bool { return docID == 20 })
+	if len(hits) != 1 || hits[0].SegmentID != 2 {
+		t.Fatalf("unexpected filtered hits: %+v", hits)
+	}
+}
+
+// TestPgvectorFilterSQL 过滤条件转换为参数化的 SQL
+func TestPgvectorFilterSQL(t *testing.T) {
+	f := &VectorFilter{
+		ACL:      true,
+		UserID:   3,
+		Metadata: []*MetadataFilter{parseMetadataFilter(t, `{"must":[{"key":"version","op":"eq","value":"2.0"}],"must_not":[{"key":"draft","op":"exists"}]}`)},
+	}
+	sql, args := pgvectorFilterSQL(f)
+	if !strings.Contains(sql, "acl_users") || !strings.Contains(sql, "NOT ") || strings.Contains(sql, "2.0") {
+		t.Fatalf("unexpected sql: %s", sql)
+	}
+	if strings.Count(sql, "?") != len(args) {
+		t.Fatalf("placeholder count mismatch: %s %v", sql, args)
+	}
+	if sql, args := pgvectorFilterSQL(nil); sql != "" || args != nil {
+		t.Fatalf("nil filter should produce no clause: %q %v", sql, args)
+	}
+}
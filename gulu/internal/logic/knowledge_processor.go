@@ -4,19 +4,20 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
 	"os/exec"
 	"regexp"
-	"runtime/debug"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/xuri/excelize/v2"
 	"golang.org/x/net/html"
+	"gorm.io/gorm"
 
 	"yqhp/gulu/internal/model"
 	"yqhp/gulu/internal/svc"
@@ -36,19 +37,51 @@ type ImageChunk struct {
 	FilePath    string // 存储后的路径
 }
 
-// Process 处理文档（完整 ETL 流水线，支持多模态 + 图知识库）
-func (p *DocumentProcessor) Process(kb *model.TKnowledgeBase, doc *model.TKnowledgeDocument) (retErr error) {
-	defer func() {
-		if r := recover(); r != nil {
-			stack := string(debug.Stack())
-			log.Printf("[PANIC] Process recovered: docID=%d, panic=%v\n%s", doc.ID, r, stack)
-			retErr = fmt.Errorf("处理过程发生内部错误: %v", r)
-			ctx := context.Background()
-			p.markFailed(ctx, doc.ID, fmt.Sprintf("内部错误: %v", r))
-		}
-	}()
+// ingestCheckpoint 解析阶段的产物，保存到文件存储，向量化/收尾阶段恢复时读取
+type ingestCheckpoint struct {
+	chunkResult
+	Text      string        `json:"text"`
+	Images    []ingestImage `json:"images,omitempty"`
+	WordCount int           `json:"word_count"`
+}
+
+// ingestImage 已保存到文件存储的文档图片
+type ingestImage struct {
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	Indexed     bool   `json:"indexed"` // 是否已写入向量库
+}
+
+func ingestCheckpointPath(kbID, docID int64) string {
+	return fmt.Sprintf("kb_%d/ingest/%d.json", kbID, docID)
+}
+
+func saveIngestCheckpoint(kbID, docID int64, cp *ingestCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return GetFileStorage().SaveBytes(ingestCheckpointPath(kbID, docID), data)
+}
+
+func loadIngestCheckpoint(kbID, docID int64) (*ingestCheckpoint, error) {
+	data, err := GetFileStorage().Read(ingestCheckpointPath(kbID, docID))
+	if err != nil {
+		return nil, err
+	}
+	var cp ingestCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
 
-	ctx := context.Background()
+func deleteIngestCheckpoint(kbID, docID int64) {
+	GetFileStorage().Delete(ingestCheckpointPath(kbID, docID))
+}
+
+// parseDocument 入库阶段 1：解析 → 清洗 → 分块（对应文档状态 parsing / cleaning / splitting）
+func (p *DocumentProcessor) parseDocument(ctx context.Context, kb *model.TKnowledgeBase, doc *model.TKnowledgeDocument) (*ingestCheckpoint, error) {
 	db := svc.Ctx.DB
 
 	// ── Stage 1: Parsing ──
@@ -56,14 +89,12 @@ func (p *DocumentProcessor) Process(kb *model.TKnowledgeBase, doc *model.TKnowle
 
 	text, err := p.extractText(doc)
 	if err != nil {
-		p.markFailed(ctx, doc.ID, fmt.Sprintf("文本提取失败: %v", err))
-		return err
+		return nil, fmt.Errorf("文本提取失败: %w", err)
 	}
 
 	// 提取文档中的图片（多模态支持）
 	var images []ImageChunk
-	multimodalEnabled := kb.MultimodalEnabled
-	if multimodalEnabled {
+	if kb.MultimodalEnabled {
 		images = p.extractImages(doc)
 		if len(images) > 0 {
 			storage := GetFileStorage()
@@ -93,17 +124,20 @@ func (p *DocumentProcessor) Process(kb *model.TKnowledgeBase, doc *model.TKnowle
 		}
 	}
 
+	// 提取文件路径与 front-matter 元数据，front-matter 不计入正文
+	autoMetadata, text := extractAutoMetadata(doc, text)
+	doc.AutoMetadata = autoMetadata
+
 	if strings.TrimSpace(text) == "" && len(images) == 0 {
-		p.markFailed(ctx, doc.ID, "文档内容为空")
-		return fmt.Errorf("文档内容为空")
+		return nil, fmt.Errorf("文档内容为空")
 	}
 
-	wordCount := int32(utf8.RuneCountInString(text))
-	imageCount := int32(len(images))
+	wordCount := utf8.RuneCountInString(text)
 	now := time.Now()
 	db.Model(&model.TKnowledgeDocument{}).Where("id = ?", doc.ID).Updates(map[string]interface{}{
 		"word_count":           wordCount,
-		"image_count":          imageCount,
+		"image_count":          len(images),
+		"auto_metadata":        autoMetadata,
 		"parsing_completed_at": now,
 	})
 
@@ -141,186 +175,152 @@ func (p *DocumentProcessor) Process(kb *model.TKnowledgeBase, doc *model.TKnowle
 		chunkOverlap = chunkSize / 5
 	}
 
-	var chunks []string
-	if strings.TrimSpace(text) != "" {
-		separator := cs.Separator
-		if separator != "" {
-			chunks = splitBySeparator(text, separator, chunkSize, chunkOverlap)
-		} else {
-			chunks = splitText(text, chunkSize, chunkOverlap)
-		}
+	chunks := chunkDocumentText(text, cs, chunkSize, chunkOverlap, doc.Name, derefString(doc.FileType))
+	if len(chunks.Chunks) == 0 && len(images) == 0 {
+		return nil, fmt.Errorf("分块结果为空")
 	}
 
-	if len(chunks) == 0 && len(images) == 0 {
-		p.markFailed(ctx, doc.ID, "分块结果为空")
-		return fmt.Errorf("分块结果为空")
+	cp := &ingestCheckpoint{chunkResult: chunks, Text: text, WordCount: wordCount}
+	for _, img := range images {
+		if len(img.Data) > 0 && img.FilePath != "" {
+			cp.Images = append(cp.Images, ingestImage{Path: img.FilePath, Description: img.Description})
+		}
 	}
+	return cp, nil
+}
+
+// embedTextChunks 入库阶段 2a：从第 from 个分块开始分批向量化并写入向量库。
+// 点 ID 由文档与分块位置确定，重复写入幂等，因此中断后可从 from 继续；每批写入后调用 onBatch 记录进度。
+func (p *DocumentProcessor) embedTextChunks(ctx context.Context, kb *model.TKnowledgeBase, doc *model.TKnowledgeDocument, cp *ingestCheckpoint,
+	from, batchSize int, limiter *ingestRateLimiter, onBatch func(done int) error) error {
+	db := svc.Ctx.DB
 
-	// ── Stage 4: Indexing ──
 	p.updateIndexingStatus(ctx, doc.ID, "indexing")
 
-	collectionName := ""
-	if kb.QdrantCollection != nil {
-		collectionName = *kb.QdrantCollection
-	}
-	if collectionName == "" {
-		p.markFailed(ctx, doc.ID, "Qdrant Collection 未配置")
-		return fmt.Errorf("qdrant collection 未配置")
+	store, collectionName, err := vectorStoreOf(kb)
+	if err != nil {
+		return fmt.Errorf("向量库不可用: %w", err)
 	}
 
-	// ── 4a. 文本向量化（先生成，从结果获取真实维度，对齐 Dify 的做法）──
-	// Dify: vector_size = len(embeddings[0])，不依赖任何存储的维度配置
-	totalChunks := 0
-	var textDimension int
-	if len(chunks) > 0 {
-		vectors, err := p.generateEmbeddings(kb, chunks)
-		if err != nil {
-			p.markFailed(ctx, doc.ID, fmt.Sprintf("Embedding 生成失败: %v", err))
-			return err
+	// 从头开始时清理旧向量（纯图片文档同样需要）
+	if from == 0 {
+		if err := store.DeleteDocument(ctx, collectionName, doc.ID); err != nil {
+			log.Printf("[WARN] 删除旧向量失败: %v", err)
 		}
+	}
+	if from >= len(cp.Chunks) {
+		return nil
+	}
+
+	embClient, err := p.getEmbeddingClient(kb)
+	if err != nil {
+		return err
+	}
+	embClient.BatchSize = batchSize
 
-		// 从实际输出拿维度，然后创建/验证 Qdrant Collection
-		textDimension = len(vectors[0])
-		// 如果 Collection 里的维度不匹配，删掉重建
-		kbCfgForIndex := kb.GetConfig()
-		imageDimension := kbCfgForIndex.MultimodalDimension
-		if err := ensureCollectionDimension(collectionName, textDimension, imageDimension); err != nil {
-			p.markFailed(ctx, doc.ID, fmt.Sprintf("Qdrant Collection 初始化失败: %v", err))
+	collectionReady := false
+	for start := from; start < len(cp.Chunks); start += batchSize {
+		end := start + batchSize
+		if end > len(cp.Chunks) {
+			end = len(cp.Chunks)
+		}
+		if err := limiter.Wait(ctx); err != nil {
 			return err
 		}
-
-		// 将实际维度回写 config JSON（缓存值，不影响索引逻辑）
-		if kbCfgForIndex.EmbeddingDimension != textDimension {
-			kbCfgForIndex.EmbeddingDimension = textDimension
-			kb.SetConfig(kbCfgForIndex)
-			db.Model(&model.TKnowledgeBase{}).Where("id = ?", kb.ID).Update("config", kb.ConfigJSON)
+		vectors, err := embClient.EmbedTexts(ctx, cp.Chunks[start:end])
+		if err != nil {
+			return fmt.Errorf("Embedding 生成失败: %w", err)
+		}
+		if len(vectors) != end-start {
+			return fmt.Errorf("Embedding 返回 %d 个向量，期望 %d 个", len(vectors), end-start)
 		}
 
-		// 清理旧数据
-		if err := DeleteDocumentVectors(collectionName, doc.ID); err != nil {
-			log.Printf("[WARN] 删除旧向量失败: %v", err)
+		// 从实际输出拿维度，然后创建/验证向量集合（对齐 Dify: vector_size = len(embeddings[0])）
+		if !collectionReady {
+			textDimension := len(vectors[0])
+			kbCfg := kb.GetConfig()
+			cfg := CollectionVectorConfig{TextDimension: textDimension, ImageDimension: kbCfg.MultimodalDimension}
+			if err := store.EnsureCollection(ctx, collectionName, cfg); err != nil {
+				return retryable(fmt.Errorf("向量集合初始化失败: %w", err))
+			}
+			// 将实际维度回写 config JSON（缓存值，不影响索引逻辑）
+			if kbCfg.EmbeddingDimension != textDimension {
+				kbCfg.EmbeddingDimension = textDimension
+				kb.SetConfig(kbCfg)
+				db.Model(&model.TKnowledgeBase{}).Where("id = ?", kb.ID).Update("config", kb.ConfigJSON)
+			}
+			collectionReady = true
 		}
-		db.Where("document_id = ?", doc.ID).Delete(&model.TKnowledgeSegment{})
 
-		points := make([]VectorPoint, len(chunks))
-		for i, chunk := range chunks {
-			points[i] = VectorPoint{
+		points := make([]VectorPoint, 0, end-start)
+		for i := start; i < end; i++ {
+			metadata := documentPayload(doc)
+			metadata["document_name"] = doc.Name
+			metadata["chunk_index"] = i
+			metadata["total_chunks"] = len(cp.Chunks)
+			if heading := cp.heading(i); heading != "" {
+				metadata["heading"] = heading
+			}
+			if parent := cp.parentOf(i); parent >= 0 {
+				metadata["parent_index"] = parent
+			}
+			points = append(points, VectorPoint{
 				ID:          fmt.Sprintf("%d_%d", doc.ID, i),
-				Vector:      vectors[i],
+				Vector:      vectors[i-start],
 				DocumentID:  doc.ID,
 				ChunkIndex:  i,
-				Content:     chunk,
+				Content:     cp.Chunks[i],
 				ContentType: "text",
-				Metadata: map[string]interface{}{
-					"document_name": doc.Name,
-					"chunk_index":   i,
-					"total_chunks":  len(chunks),
-				},
-			}
+				Metadata:    metadata,
+			})
 		}
-		if err := UpsertVectors(collectionName, points); err != nil {
-			p.markFailed(ctx, doc.ID, fmt.Sprintf("向量写入失败: %v", err))
-			return err
+		if err := store.Upsert(ctx, collectionName, "text", points); err != nil {
+			return retryable(fmt.Errorf("向量写入失败: %w", err))
 		}
-
-		// 写入 MySQL text segments
-		segments := make([]*model.TKnowledgeSegment, len(chunks))
-		nowT := time.Now()
-		for i, chunk := range chunks {
-			pointID := fmt.Sprintf("%d", uint64(doc.ID)*100000+uint64(i))
-			wc := utf8.RuneCountInString(chunk)
-			segments[i] = &model.TKnowledgeSegment{
-				CreatedAt:       &nowT,
-				UpdatedAt:       &nowT,
-				KnowledgeBaseID: doc.KnowledgeBaseID,
-				DocumentID:      doc.ID,
-				Content:         chunk,
-				ContentType:     "text",
-				Position:        i,
-				WordCount:       wc,
-				Tokens:          wc,
-				IndexNodeID:     &pointID,
-				VectorField:     "text",
-				Status:          "active",
-				Enabled:         true,
-			}
-		}
-		if err := db.CreateInBatches(segments, 100).Error; err != nil {
-			log.Printf("[WARN] MySQL 文本分块写入失败: %v", err)
-		}
-		totalChunks += len(chunks)
-	}
-
-	// 4b. 图片向量化和写入（多模态）
-	// 纯图片文档（无文本分块）也需要清理旧数据
-	if len(chunks) == 0 && multimodalEnabled && len(images) > 0 {
-		if err := DeleteDocumentVectors(collectionName, doc.ID); err != nil {
-			log.Printf("[WARN] 删除旧向量失败: %v", err)
-		}
-		db.Where("document_id = ?", doc.ID).Delete(&model.TKnowledgeSegment{})
-	}
-	if multimodalEnabled && len(images) > 0 {
-		imgErr := p.indexImages(kb, doc, images, collectionName, len(chunks))
-		if imgErr != nil {
-			log.Printf("[WARN] 图片索引失败: %v", imgErr)
-		} else {
-			totalChunks += len(images)
-		}
-	}
-
-	// 4c. 图知识库处理（实体关系抽取）
-	if kb.Type == "graph" && len(chunks) > 0 {
-		graphProcessor := NewGraphProcessor()
-		if err := graphProcessor.ProcessDocument(kb, doc, text, chunks); err != nil {
-			log.Printf("[WARN] 图谱处理失败: %v", err)
+		if err := onBatch(end); err != nil {
+			return err
 		}
 	}
 
-	// ── Stage 5: Completed ──
-	chunkCount := int32(totalChunks)
-	tokenCount := int32(utf8.RuneCountInString(text))
-	completedAt := time.Now()
-	db.Model(&model.TKnowledgeDocument{}).Where("id = ?", doc.ID).Updates(map[string]interface{}{
-		"indexing_status":       "completed",
-		"chunk_count":           chunkCount,
-		"token_count":           tokenCount,
-		"indexing_completed_at": completedAt,
-		"error_message":         nil,
-		"updated_at":            completedAt,
-	})
-
-	log.Printf("[INFO] 文档处理完成: docID=%d, textChunks=%d, images=%d, words=%d", doc.ID, len(chunks), len(images), wordCount)
+	log.Printf("[INFO] Embedding: 文档 %d 共写入 %d 个文本向量 (模型: %s)", doc.ID, len(cp.Chunks)-from, embClient.Model)
 	return nil
 }
 
-// indexImages 对图片进行多模态向量化并写入 Qdrant
-func (p *DocumentProcessor) indexImages(kb *model.TKnowledgeBase, doc *model.TKnowledgeDocument, images []ImageChunk, collectionName string, startIndex int) error {
+// embedImages 入库阶段 2b：对图片进行多模态向量化并写入向量库，成功的图片标记 Indexed
+func (p *DocumentProcessor) embedImages(ctx context.Context, kb *model.TKnowledgeBase, doc *model.TKnowledgeDocument, cp *ingestCheckpoint, limiter *ingestRateLimiter) error {
 	if kb.MultimodalModelID == nil || *kb.MultimodalModelID == 0 {
 		return fmt.Errorf("未配置多模态嵌入模型")
 	}
+	store, collectionName, err := vectorStoreOf(kb)
+	if err != nil {
+		return err
+	}
 
-	ctx := context.Background()
-	aiModelLogic := NewAiModelLogic(ctx)
-	aiModel, err := aiModelLogic.GetByIDWithKey(*kb.MultimodalModelID)
+	aiModel, err := NewAiModelLogic(ctx).GetByIDWithKey(*kb.MultimodalModelID)
 	if err != nil {
 		return fmt.Errorf("多模态嵌入模型不存在: %w", err)
 	}
-
 	embClient := NewEmbeddingClient(aiModel.APIBaseURL, aiModel.APIKey, aiModel.ModelID)
 
-	imageDataList := make([][]byte, 0, len(images))
-	validImages := make([]ImageChunk, 0, len(images))
-	for _, img := range images {
-		if len(img.Data) > 0 && img.FilePath != "" {
-			imageDataList = append(imageDataList, img.Data)
-			validImages = append(validImages, img)
+	storage := GetFileStorage()
+	imageDataList := make([][]byte, 0, len(cp.Images))
+	pending := make([]int, 0, len(cp.Images))
+	for i, img := range cp.Images {
+		data, err := storage.Read(img.Path)
+		if err != nil || len(data) == 0 {
+			log.Printf("[WARN] 读取图片失败: %s: %v", img.Path, err)
+			continue
 		}
+		imageDataList = append(imageDataList, data)
+		pending = append(pending, i)
 	}
-
 	if len(imageDataList) == 0 {
 		return nil
 	}
 
+	if err := limiter.Wait(ctx); err != nil {
+		return err
+	}
 	vectors, err := embClient.EmbedImages(ctx, imageDataList)
 	if err != nil {
 		return fmt.Errorf("图片 Embedding 生成失败: %w", err)
@@ -329,54 +329,91 @@ func (p *DocumentProcessor) indexImages(kb *model.TKnowledgeBase, doc *model.TKn
 	// 从实际输出拿图片向量维度，确保 Collection 的 image 字段维度正确
 	imageDimension := len(vectors[0])
 	kbCfg := kb.GetConfig()
-	if err := ensureCollectionDimension(collectionName, kbCfg.EmbeddingDimension, imageDimension); err != nil {
-		return fmt.Errorf("Qdrant Collection image 字段初始化失败: %w", err)
+	if err := store.EnsureCollection(ctx, collectionName, CollectionVectorConfig{TextDimension: kbCfg.EmbeddingDimension, ImageDimension: imageDimension}); err != nil {
+		return fmt.Errorf("向量集合 image 字段初始化失败: %w", err)
 	}
 	// 回写实际图片维度到 config JSON
 	if kbCfg.MultimodalDimension != imageDimension {
 		kbCfg.MultimodalDimension = imageDimension
 		kb.SetConfig(kbCfg)
-		db := svc.Ctx.DB
-		db.Model(&model.TKnowledgeBase{}).Where("id = ?", kb.ID).Update("config", kb.ConfigJSON)
+		svc.Ctx.DB.Model(&model.TKnowledgeBase{}).Where("id = ?", kb.ID).Update("config", kb.ConfigJSON)
 	}
 
-	points := make([]VectorPoint, len(validImages))
-	for i, img := range validImages {
+	points := make([]VectorPoint, len(pending))
+	for i, idx := range pending {
+		img := cp.Images[idx]
 		description := img.Description
 		if description == "" {
-			description = fmt.Sprintf("图片 %d (来自文档 %s)", i+1, doc.Name)
+			description = fmt.Sprintf("图片 %d (来自文档 %s)", idx+1, doc.Name)
 		}
+		metadata := documentPayload(doc)
+		metadata["document_name"] = doc.Name
+		metadata["image_index"] = idx
 		points[i] = VectorPoint{
-			ID:          fmt.Sprintf("%d_img_%d", doc.ID, i),
+			ID:          fmt.Sprintf("%d_img_%d", doc.ID, idx),
 			Vector:      vectors[i],
 			DocumentID:  doc.ID,
-			ChunkIndex:  startIndex + i,
+			ChunkIndex:  len(cp.Chunks) + idx,
 			Content:     description,
 			ContentType: "image",
-			ImagePath:   img.FilePath,
-			Metadata: map[string]interface{}{
-				"document_name": doc.Name,
-				"image_index":   i,
-			},
+			ImagePath:   img.Path,
+			Metadata:    metadata,
 		}
 	}
-
-	if err := UpsertVectorsToField(collectionName, "image", points); err != nil {
+	if err := store.Upsert(ctx, collectionName, "image", points); err != nil {
 		return fmt.Errorf("图片向量写入失败: %w", err)
 	}
+	for _, idx := range pending {
+		cp.Images[idx].Indexed = true
+	}
 
-	// 写入 MySQL image segments
+	log.Printf("[INFO] 多模态: 成功索引 %d 张图片", len(pending))
+	return nil
+}
+
+// finalizeDocument 入库阶段 3：重写分块记录，图知识库抽取实体关系，更新文档为 completed
+func (p *DocumentProcessor) finalizeDocument(ctx context.Context, kb *model.TKnowledgeBase, doc *model.TKnowledgeDocument, cp *ingestCheckpoint) error {
 	db := svc.Ctx.DB
-	segments := make([]*model.TKnowledgeSegment, len(validImages))
+
 	nowT := time.Now()
-	for i, img := range validImages {
-		pointID := fmt.Sprintf("%d", uint64(doc.ID)*100000+uint64(startIndex+i))
+	segments := make([]*model.TKnowledgeSegment, 0, len(cp.Chunks)+len(cp.Images))
+	for i, chunk := range cp.Chunks {
+		pointID := fmt.Sprintf("%d", vectorPointID(doc.ID, i))
+		wc := utf8.RuneCountInString(chunk)
+		metadata, err := segmentChunkMetadata(&cp.chunkResult, i)
+		if err != nil {
+			return err
+		}
+		segments = append(segments, &model.TKnowledgeSegment{
+			CreatedAt:       &nowT,
+			UpdatedAt:       &nowT,
+			KnowledgeBaseID: doc.KnowledgeBaseID,
+			DocumentID:      doc.ID,
+			Content:         chunk,
+			ContentType:     "text",
+			Position:        i,
+			WordCount:       wc,
+			Tokens:          wc,
+			IndexNodeID:     &pointID,
+			VectorField:     "text",
+			Status:          "active",
+			Enabled:         true,
+			Metadata:        metadata,
+		})
+	}
+	imageCount := 0
+	for i, img := range cp.Images {
+		if !img.Indexed {
+			continue
+		}
+		position := len(cp.Chunks) + i
+		pointID := fmt.Sprintf("%d", vectorPointID(doc.ID, position))
 		description := img.Description
 		if description == "" {
 			description = fmt.Sprintf("图片 %d", i+1)
 		}
-		imgPath := img.FilePath
-		segments[i] = &model.TKnowledgeSegment{
+		imgPath := img.Path
+		segments = append(segments, &model.TKnowledgeSegment{
 			CreatedAt:       &nowT,
 			UpdatedAt:       &nowT,
 			KnowledgeBaseID: doc.KnowledgeBaseID,
@@ -384,20 +421,48 @@ func (p *DocumentProcessor) indexImages(kb *model.TKnowledgeBase, doc *model.TKn
 			Content:         description,
 			ContentType:     "image",
 			ImagePath:       &imgPath,
-			Position:        startIndex + i,
-			WordCount:       0,
-			Tokens:          0,
+			Position:        position,
 			IndexNodeID:     &pointID,
 			VectorField:     "image",
 			Status:          "active",
 			Enabled:         true,
+		})
+		imageCount++
+	}
+
+	// 替换旧分块（同一事务内删除再写入，中断重试时不会留下重复记录）
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", doc.ID).Delete(&model.TKnowledgeSegment{}).Error; err != nil {
+			return err
+		}
+		if len(segments) == 0 {
+			return nil
 		}
+		return tx.CreateInBatches(segments, 100).Error
+	})
+	if err != nil {
+		return retryable(fmt.Errorf("分块写入失败: %w", err))
 	}
-	if err := db.CreateInBatches(segments, 100).Error; err != nil {
-		log.Printf("[WARN] MySQL 图片分块写入失败: %v", err)
+
+	// 图知识库处理（实体关系抽取）
+	if kb.Type == "graph" && len(cp.Chunks) > 0 {
+		graphProcessor := NewGraphProcessor()
+		if err := graphProcessor.ProcessDocument(kb, doc, cp.Text, cp.Chunks); err != nil {
+			log.Printf("[WARN] 图谱处理失败: %v", err)
+		}
 	}
 
-	log.Printf("[INFO] 多模态: 成功索引 %d 张图片", len(validImages))
+	completedAt := time.Now()
+	db.Model(&model.TKnowledgeDocument{}).Where("id = ?", doc.ID).Updates(map[string]interface{}{
+		"indexing_status":       "completed",
+		"chunk_count":           len(cp.Chunks) + imageCount,
+		"token_count":           utf8.RuneCountInString(cp.Text),
+		"indexing_completed_at": completedAt,
+		"error_message":         nil,
+		"updated_at":            completedAt,
+	})
+
+	log.Printf("[INFO] 文档处理完成: docID=%d, textChunks=%d, images=%d, words=%d", doc.ID, len(cp.Chunks), imageCount, cp.WordCount)
 	return nil
 }
 
@@ -1360,20 +1425,6 @@ func runeLen(s string) int {
 // Embedding 生成
 // -----------------------------------------------
 
-func (p *DocumentProcessor) generateEmbeddings(kb *model.TKnowledgeBase, chunks []string) ([][]float32, error) {
-	embClient, err := p.getEmbeddingClient(kb)
-	if err != nil {
-		return nil, err
-	}
-	ctx := context.Background()
-	vectors, err := embClient.EmbedTexts(ctx, chunks)
-	if err != nil {
-		return nil, fmt.Errorf("Embedding 生成失败: %w", err)
-	}
-	log.Printf("[INFO] Embedding: 成功生成 %d 个向量 (模型: %s)", len(vectors), embClient.Model)
-	return vectors, nil
-}
-
 func (p *DocumentProcessor) getEmbeddingClient(kb *model.TKnowledgeBase) (*EmbeddingClient, error) {
 	aiModelLogic := NewAiModelLogic(context.Background())
 
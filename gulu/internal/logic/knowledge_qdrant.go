@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"sync"
 
 	"yqhp/gulu/internal/config"
@@ -322,10 +323,15 @@ func UpsertVectorsToField(collectionName string, vectorField string, points []Ve
 				payload[k] = qdrant.NewValueDouble(val)
 			case bool:
 				payload[k] = qdrant.NewValueBool(val)
+			default:
+				// 文档元数据（meta）、访问控制用户列表等嵌套结构
+				if value, err := qdrant.NewValue(v); err == nil {
+					payload[k] = value
+				}
 			}
 		}
 
-		pointID := uint64(p.DocumentID)*100000 + uint64(p.ChunkIndex)
+		pointID := vectorPointID(p.DocumentID, p.ChunkIndex)
 		qdrantPoints = append(qdrantPoints, &qdrant.PointStruct{
 			Id: qdrant.NewIDNum(pointID),
 			Vectors: qdrant.NewVectorsMap(map[string]*qdrant.Vector{
@@ -540,7 +546,7 @@ func DeleteSegmentVector(collectionName string, documentID int64, chunkIndex int
 	}
 
 	ctx := context.Background()
-	pointID := uint64(documentID)*100000 + uint64(chunkIndex)
+	pointID := vectorPointID(documentID, chunkIndex)
 
 	_, err = client.Delete(ctx, &qdrant.DeletePoints{
 		CollectionName: collectionName,
@@ -610,19 +616,9 @@ type SearchHit struct {
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// QdrantCollectionDiag Qdrant 集合诊断信息
-type QdrantCollectionDiag struct {
-	CollectionName string            `json:"collection_name"`
-	Exists         bool              `json:"exists"`
-	VectorCount    uint64            `json:"vector_count"`
-	PointCount     uint64            `json:"point_count"`
-	VectorFields   map[string]uint64 `json:"vector_fields"` // 各向量字段的维度
-	Error          string            `json:"error,omitempty"`
-}
-
 // DiagnoseQdrantCollection 获取 Qdrant 集合的诊断信息
-func DiagnoseQdrantCollection(collectionName string) *QdrantCollectionDiag {
-	diag := &QdrantCollectionDiag{CollectionName: collectionName}
+func DiagnoseQdrantCollection(collectionName string) *VectorCollectionDiag {
+	diag := &VectorCollectionDiag{Store: VectorStoreQdrant, CollectionName: collectionName}
 
 	client, err := getQdrantClient()
 	if err != nil {
@@ -665,3 +661,242 @@ func DiagnoseQdrantCollection(collectionName string) *QdrantCollectionDiag {
 
 	return diag
 }
+
+// ExportQdrantCollection 分批导出 Qdrant 集合中的全部点（含向量），每批只包含同一字段的点
+func ExportQdrantCollection(ctx context.Context, collectionName string, batchSize int, fn func(field string, points []VectorPoint) error) error {
+	client, err := getQdrantClient()
+	if err != nil {
+		return err
+	}
+
+	var offset *qdrant.PointId
+	for {
+		results, next, err := client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: collectionName,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(batchSize)),
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return fmt.Errorf("Qdrant Scroll 失败: %w", err)
+		}
+
+		byField := make(map[string][]VectorPoint)
+		var fields []string
+		for _, r := range results {
+			for field, vec := range r.GetVectors().GetVectors().GetVectors() {
+				point := VectorPoint{
+					ID:     fmt.Sprintf("%d", r.GetId().GetNum()),
+					Vector: vec.GetData(),
+				}
+				if dense := vec.GetDense(); dense != nil {
+					point.Vector = dense.GetData()
+				}
+				for k, v := range r.GetPayload() {
+					switch k {
+					case "content":
+						point.Content = v.GetStringValue()
+					case "content_type":
+						point.ContentType = v.GetStringValue()
+					case "document_id":
+						point.DocumentID = v.GetIntegerValue()
+					case "chunk_index":
+						point.ChunkIndex = int(v.GetIntegerValue())
+					case "image_path":
+						point.ImagePath = v.GetStringValue()
+					case "vector_field":
+					default:
+						if point.Metadata == nil {
+							point.Metadata = make(map[string]interface{})
+						}
+						point.Metadata[k] = qdrantValueToInterface(v)
+					}
+				}
+				if _, ok := byField[field]; !ok {
+					fields = append(fields, field)
+				}
+				byField[field] = append(byField[field], point)
+			}
+		}
+		for _, field := range fields {
+			if err := fn(field, byField[field]); err != nil {
+				return err
+			}
+		}
+
+		if next == nil || len(results) == 0 {
+			return nil
+		}
+		offset = next
+	}
+}
+
+func qdrantValueToInterface(v *qdrant.Value) interface{} {
+	switch k := v.GetKind().(type) {
+	case *qdrant.Value_StringValue:
+		return k.StringValue
+	case *qdrant.Value_IntegerValue:
+		return k.IntegerValue
+	case *qdrant.Value_DoubleValue:
+		return k.DoubleValue
+	case *qdrant.Value_BoolValue:
+		return k.BoolValue
+	case *qdrant.Value_StructValue:
+		m := make(map[string]interface{}, len(k.StructValue.GetFields()))
+		for key, val := range k.StructValue.GetFields() {
+			m[key] = qdrantValueToInterface(val)
+		}
+		return m
+	case *qdrant.Value_ListValue:
+		list := make([]interface{}, 0, len(k.ListValue.GetValues()))
+		for _, val := range k.ListValue.GetValues() {
+			list = append(list, qdrantValueToInterface(val))
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// -----------------------------------------------
+// 元数据过滤与附加数据更新
+// -----------------------------------------------
+
+// qdrantFilter 将检索过滤条件转换为 Qdrant filter（文档元数据位于附加数据的 meta 字段下）
+func qdrantFilter(f *VectorFilter) *qdrant.Filter {
+	if f == nil {
+		return nil
+	}
+	filter := &qdrant.Filter{}
+	if f.ACL {
+		// 未设置访问控制（acl_restricted 缺失或为 false），或访问控制包含当前用户
+		filter.Must = append(filter.Must, qdrant.NewFilterAsCondition(&qdrant.Filter{
+			Should: []*qdrant.Condition{
+				qdrant.NewFilterAsCondition(&qdrant.Filter{
+					MustNot: []*qdrant.Condition{qdrant.NewMatchBool(payloadACLRestrictedKey, true)},
+				}),
+				qdrant.NewMatchInt(payloadACLUsersKey, f.UserID),
+			},
+		}))
+	}
+	for _, mf := range f.Metadata {
+		nested := &qdrant.Filter{}
+		for i := range mf.Must {
+			nested.Must = append(nested.Must, qdrantCondition(&mf.Must[i]))
+		}
+		for i := range mf.Should {
+			nested.Should = append(nested.Should, qdrantCondition(&mf.Should[i]))
+		}
+		for i := range mf.MustNot {
+			nested.MustNot = append(nested.MustNot, qdrantCondition(&mf.MustNot[i]))
+		}
+		filter.Must = append(filter.Must, qdrant.NewFilterAsCondition(nested))
+	}
+	return filter
+}
+
+func qdrantCondition(c *MetadataCondition) *qdrant.Condition {
+	key := payloadMetaKey + "." + c.Key
+	switch c.Op {
+	case FilterOpExists:
+		return qdrant.NewFilterAsCondition(&qdrant.Filter{
+			MustNot: []*qdrant.Condition{qdrant.NewIsEmpty(key)},
+		})
+	case FilterOpRange:
+		return qdrant.NewRange(key, &qdrant.Range{Gt: c.Gt, Gte: c.Gte, Lt: c.Lt, Lte: c.Lte})
+	case FilterOpIn:
+		conds := make([]*qdrant.Condition, 0, len(c.Values))
+		for _, v := range c.Values {
+			conds = append(conds, qdrantMatch(key, v))
+		}
+		return qdrant.NewFilterAsCondition(&qdrant.Filter{Should: conds})
+	default:
+		return qdrantMatch(key, c.Value)
+	}
+}
+
+// qdrantMatch 单值相等匹配，非整数的数值用闭区间表示
+func qdrantMatch(key string, v interface{}) *qdrant.Condition {
+	switch val := v.(type) {
+	case string:
+		return qdrant.NewMatchKeyword(key, val)
+	case bool:
+		return qdrant.NewMatchBool(key, val)
+	}
+	n, _ := metadataNumber(v)
+	if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+		return qdrant.NewMatchInt(key, int64(n))
+	}
+	return qdrant.NewRange(key, &qdrant.Range{Gte: &n, Lte: &n})
+}
+
+// SetQdrantDocumentPayload 覆盖文档全部点的指定附加数据字段
+func SetQdrantDocumentPayload(ctx context.Context, collectionName string, documentID int64, payload map[string]interface{}) error {
+	client, err := getQdrantClient()
+	if err != nil {
+		return err
+	}
+	values, err := qdrant.TryValueMap(payload)
+	if err != nil {
+		return fmt.Errorf("附加数据转换失败: %w", err)
+	}
+
+	_, err = client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: collectionName,
+		Wait:           qdrant.PtrOf(true),
+		Payload:        values,
+		PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must: []*qdrant.Condition{qdrant.NewMatchInt("document_id", documentID)},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("更新文档附加数据失败: %w", err)
+	}
+	return nil
+}
+
+// -----------------------------------------------
+// VectorStore 实现
+// -----------------------------------------------
+
+// qdrantVectorStore Qdrant 向量库后端
+type qdrantVectorStore struct{}
+
+func (qdrantVectorStore) Name() string { return VectorStoreQdrant }
+
+func (qdrantVectorStore) EnsureCollection(_ context.Context, collection string, cfg CollectionVectorConfig) error {
+	return ensureCollectionDimension(collection, cfg.TextDimension, cfg.ImageDimension)
+}
+
+func (qdrantVectorStore) DeleteCollection(_ context.Context, collection string) error {
+	return DeleteQdrantCollection(collection)
+}
+
+func (qdrantVectorStore) Upsert(_ context.Context, collection, field string, points []VectorPoint) error {
+	return UpsertVectorsToField(collection, field, points)
+}
+
+func (qdrantVectorStore) Search(_ context.Context, collection, field string, vector []float32, topK int, scoreThreshold float32, filter *VectorFilter) ([]SearchHit, error) {
+	return SearchVectorsInField(collection, field, vector, topK, scoreThreshold, qdrantFilter(filter))
+}
+
+func (qdrantVectorStore) SetDocumentPayload(ctx context.Context, collection string, documentID int64, payload map[string]interface{}) error {
+	return SetQdrantDocumentPayload(ctx, collection, documentID, payload)
+}
+
+func (qdrantVectorStore) DeleteDocument(_ context.Context, collection string, documentID int64) error {
+	return DeleteDocumentVectors(collection, documentID)
+}
+
+func (qdrantVectorStore) DeletePoint(_ context.Context, collection string, documentID int64, chunkIndex int) error {
+	return DeleteSegmentVector(collection, documentID, chunkIndex)
+}
+
+func (qdrantVectorStore) Export(ctx context.Context, collection string, batchSize int, fn func(field string, points []VectorPoint) error) error {
+	return ExportQdrantCollection(ctx, collection, batchSize, fn)
+}
+
+func (qdrantVectorStore) Diagnose(_ context.Context, collection string) *VectorCollectionDiag {
+	return DiagnoseQdrantCollection(collection)
+}
@@ -0,0 +1,308 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"yqhp/gulu/internal/model"
+)
+
+// -----------------------------------------------
+// 混合检索：多路召回结果融合（RRF / 加权）+ Rerank 重排序
+// -----------------------------------------------
+
+const (
+	FusionRRF      = "rrf"
+	FusionWeighted = "weighted"
+
+	// 召回来源
+	RetrieverVector  = "vector"
+	RetrieverKeyword = "keyword"
+	RetrieverGraph   = "graph"
+)
+
+// ScoreExplanation 检索分数说明
+type ScoreExplanation struct {
+	Method       string        `json:"method"`                  // vector / keyword / graph / rrf / weighted
+	Sources      []SourceScore `json:"sources,omitempty"`       // 各召回来源的排名与分数
+	MatchedTerms []string      `json:"matched_terms,omitempty"` // 关键词检索命中的检索词
+	FusionScore  *float64      `json:"fusion_score,omitempty"`  // 融合原始分（RRF 为各来源倒数排名加权和）
+	RerankScore  *float64      `json:"rerank_score,omitempty"`  // Rerank 模型给出的相关度
+	RerankModel  string        `json:"rerank_model,omitempty"`
+}
+
+// SourceScore 单个召回来源的分数
+type SourceScore struct {
+	Retriever string  `json:"retriever"`           // vector / keyword / graph
+	Rank      int     `json:"rank"`                // 在该来源结果中的排名（从 1 开始）
+	Score     float64 `json:"score"`               // 归一化分数（0–1）
+	RawScore  float64 `json:"raw_score,omitempty"` // 原始分数（BM25 分数等）
+}
+
+// rankedResults 单路召回结果（已按分数降序）
+type rankedResults struct {
+	retriever string
+	weight    float64
+	results   []*KnowledgeSearchResult
+}
+
+// fusionOptions 融合参数（知识库配置 + 请求覆盖）
+type fusionOptions struct {
+	Method       string  `json:"method"`
+	RRFK         int     `json:"rrf_k"`
+	VectorWeight float64 `json:"vector_weight"`
+}
+
+// resolveFusionOptions 合并知识库配置与请求参数，并校验取值
+func resolveFusionOptions(cfg model.KBConfig, req *KnowledgeSearchReq) (fusionOptions, error) {
+	opts := fusionOptions{Method: cfg.FusionMethod, RRFK: cfg.RRFK, VectorWeight: cfg.VectorWeight}
+	if req.FusionMethod != "" {
+		opts.Method = req.FusionMethod
+	}
+	if req.RRFK > 0 {
+		opts.RRFK = req.RRFK
+	}
+	if req.VectorWeight != nil {
+		opts.VectorWeight = *req.VectorWeight
+	}
+	if err := validateFusionOptions(opts.Method, opts.VectorWeight); err != nil {
+		return opts, err
+	}
+	if opts.RRFK <= 0 {
+		opts.RRFK = model.DefaultKBConfig().RRFK
+	}
+	return opts, nil
+}
+
+func validateFusionOptions(method string, vectorWeight float64) error {
+	if method != FusionRRF && method != FusionWeighted {
+		return fmt.Errorf("不支持的融合方式: %s（可选 rrf / weighted）", method)
+	}
+	if vectorWeight < 0 || vectorWeight > 1 {
+		return errors.New("vector_weight 取值范围为 0–1")
+	}
+	return nil
+}
+
+// resultKey 结果去重键（向量结果没有分块 ID，统一按文档 + 分块位置）
+func resultKey(r *KnowledgeSearchResult) string {
+	return fmt.Sprintf("%d_%d", r.DocumentID, r.ChunkIndex)
+}
+
+// fuseResults 融合多路召回结果。
+// rrf:      score = Σ w / (k + rank)，再除以理论最大值 Σ w / (k + 1) 归一化到 0–1；
+// weighted: score = Σ w × 来源归一化分数（向量为余弦相似度，BM25 按本路最高分归一化）。
+func fuseResults(lists []rankedResults, opts fusionOptions, topK int) []*KnowledgeSearchResult {
+	var maxRRF float64
+	for _, l := range lists {
+		maxRRF += l.weight / float64(opts.RRFK+1)
+	}
+
+	fused := make(map[string]*KnowledgeSearchResult)
+	raw := make(map[string]float64)
+	var order []*KnowledgeSearchResult
+	for _, l := range lists {
+		for i, r := range l.results {
+			key := resultKey(r)
+			base, ok := fused[key]
+			if !ok {
+				base = r
+				if base.ScoreDetail == nil {
+					base.ScoreDetail = &ScoreExplanation{}
+				}
+				fused[key] = base
+				order = append(order, base)
+			} else if r.ScoreDetail != nil {
+				base.ScoreDetail.Sources = append(base.ScoreDetail.Sources, r.ScoreDetail.Sources...)
+				base.ScoreDetail.MatchedTerms = append(base.ScoreDetail.MatchedTerms, r.ScoreDetail.MatchedTerms...)
+				if base.SegmentID == 0 {
+					base.SegmentID = r.SegmentID
+				}
+			}
+
+			switch opts.Method {
+			case FusionWeighted:
+				raw[key] += l.weight * sourceScore(r, l.retriever)
+			default:
+				raw[key] += l.weight / float64(opts.RRFK+i+1)
+			}
+		}
+	}
+
+	for _, r := range order {
+		fusionScore := raw[resultKey(r)]
+		r.ScoreDetail.Method = opts.Method
+		r.ScoreDetail.FusionScore = &fusionScore
+		r.Score = fusionScore
+		if opts.Method == FusionRRF && maxRRF > 0 {
+			r.Score = fusionScore / maxRRF
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].Score > order[j].Score })
+	if len(order) > topK {
+		order = order[:topK]
+	}
+	return order
+}
+
+// sourceScore 结果在指定来源中的归一化分数
+func sourceScore(r *KnowledgeSearchResult, retriever string) float64 {
+	if r.ScoreDetail == nil {
+		return r.Score
+	}
+	for _, s := range r.ScoreDetail.Sources {
+		if s.Retriever == retriever {
+			return s.Score
+		}
+	}
+	return 0
+}
+
+// retrievalCandidates 需要融合或重排序时每路召回的候选数量
+func retrievalCandidates(topK int) int {
+	n := topK * 3
+	if n < 20 {
+		n = 20
+	}
+	if n > 100 {
+		n = 100
+	}
+	if n < topK {
+		n = topK
+	}
+	return n
+}
+
+// -----------------------------------------------
+// Rerank 重排序
+// 兼容 Jina / Cohere / SiliconFlow / Xinference 等 /rerank 接口
+// -----------------------------------------------
+
+// RerankClient Rerank 模型客户端
+type RerankClient struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+}
+
+// NewRerankClient 创建 Rerank 模型客户端
+func NewRerankClient(baseURL, apiKey, model string) *RerankClient {
+	return &RerankClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		Model:   model,
+		Timeout: 30 * time.Second,
+	}
+}
+
+type rerankRequest struct {
+	Model           string   `json:"model"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n,omitempty"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+type rerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// RerankResult 单个文档的重排序结果
+type RerankResult struct {
+	Index int
+	Score float64
+}
+
+// Rerank 计算查询与各文档的相关度，按相关度降序返回
+func (c *RerankClient) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	respBody, err := postJSON(ctx, c.BaseURL+"/rerank", c.APIKey, rerankRequest{
+		Model:     c.Model,
+		Query:     query,
+		Documents: documents,
+		TopN:      topN,
+	}, c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp rerankResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("响应解析失败: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("Rerank API 错误: %s", resp.Error.Message)
+	}
+
+	results := make([]RerankResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		if r.Index < 0 || r.Index >= len(documents) {
+			return nil, fmt.Errorf("Rerank 返回了越界的文档下标 %d", r.Index)
+		}
+		results = append(results, RerankResult{Index: r.Index, Score: r.RelevanceScore})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results, nil
+}
+
+// rerankResults 使用知识库配置的 Rerank 模型重排序，失败时保留原有排序
+func (l *KnowledgeBaseLogic) rerankResults(modelID int64, query string, results []*KnowledgeSearchResult, topK int) []*KnowledgeSearchResult {
+	if len(results) == 0 {
+		return results
+	}
+	aiModel, err := NewAiModelLogic(l.ctx).GetByIDWithKey(modelID)
+	if err != nil {
+		log.Printf("[WARN] 获取 Rerank 模型失败 (modelID=%d): %v", modelID, err)
+		return truncateResults(results, topK)
+	}
+
+	documents := make([]string, len(results))
+	for i, r := range results {
+		documents[i] = r.Content
+	}
+	ranked, err := NewRerankClient(aiModel.APIBaseURL, aiModel.APIKey, aiModel.ModelID).Rerank(l.ctx, query, documents, topK)
+	if err != nil {
+		log.Printf("[WARN] Rerank 失败，保留融合排序: %v", err)
+		return truncateResults(results, topK)
+	}
+	return applyRerank(results, ranked, aiModel.ModelID, topK)
+}
+
+// applyRerank 按重排序结果调整顺序，最终分数取 Rerank 相关度
+func applyRerank(results []*KnowledgeSearchResult, ranked []RerankResult, modelName string, topK int) []*KnowledgeSearchResult {
+	reranked := make([]*KnowledgeSearchResult, 0, len(ranked))
+	for _, rr := range ranked {
+		r := results[rr.Index]
+		if r.ScoreDetail == nil {
+			r.ScoreDetail = &ScoreExplanation{}
+		}
+		score := rr.Score
+		r.ScoreDetail.RerankScore = &score
+		r.ScoreDetail.RerankModel = modelName
+		r.Score = score
+		reranked = append(reranked, r)
+	}
+	return truncateResults(reranked, topK)
+}
+
+func truncateResults(results []*KnowledgeSearchResult, topK int) []*KnowledgeSearchResult {
+	if len(results) > topK {
+		return results[:topK]
+	}
+	return results
+}
@@ -0,0 +1,174 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"yqhp/gulu/internal/model"
+)
+
+// TestTokenizeForBM25 中文按相邻两字切分，英文转小写并去停用词
+func TestTokenizeForBM25(t *testing.T) {
+	got := tokenizeForBM25("安装Docker的步骤, the API v2；库")
+	want := []string{"安装", "docker", "的步", "步骤", "api", "v2", "库"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("tokens = %v, want %v", got, want)
+	}
+}
+
+// TestBM25Index_Search 命中词越多、越稀有的分块排名越靠前
+func TestBM25Index_Search(t *testing.T) {
+	idx := newBM25Index([]bm25Segment{
+		{ID: 1, Content: "知识库支持向量检索和关键词检索"},
+		{ID: 2, Content: "如何配置 Rerank 重排序模型"},
+		{ID: 3, Content: "关键词检索使用 BM25 算法，对中文按两字切分"},
+		{ID: 4, Content: "工作流编排与定时任务"},
+	})
+
+	hits := idx.Search("BM25 关键词检索", 10)
+	if len(hits) != 2 || hits[0].SegmentID != 3 || hits[1].SegmentID != 1 {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	if hits[0].Score <= hits[1].Score {
+		t.Fatalf("expected descending scores: %+v", hits)
+	}
+	if !reflect.DeepEqual(hits[0].MatchedTerms, []string{"bm25", "关键", "键词", "词检", "检索"}) {
+		t.Fatalf("unexpected matched terms: %v", hits[0].MatchedTerms)
+	}
+
+	if hits := idx.Search("rerank", 1); len(hits) != 1 || hits[0].SegmentID != 2 {
+		t.Fatalf("expected case-insensitive english match: %+v", hits)
+	}
+	if hits := idx.Search("不存在的内容", 5); len(hits) != 0 {
+		t.Fatalf("expected no hits: %+v", hits)
+	}
+}
+
+func fusionTestResult(retriever string, docID int64, chunk, rank int, score float64) *KnowledgeSearchResult {
+	return &KnowledgeSearchResult{
+		DocumentID: docID,
+		ChunkIndex: chunk,
+		Score:      score,
+		ScoreDetail: &ScoreExplanation{
+			Method:  retriever,
+			Sources: []SourceScore{{Retriever: retriever, Rank: rank, Score: score}},
+		},
+	}
+}
+
+// TestFuseResults_RRF 两路都命中的结果排在最前，分数归一化且保留各来源排名
+func TestFuseResults_RRF(t *testing.T) {
+	vector := []*KnowledgeSearchResult{
+		fusionTestResult(RetrieverVector, 1, 0, 1, 0.9),
+		fusionTestResult(RetrieverVector, 1, 1, 2, 0.8),
+	}
+	keyword := []*KnowledgeSearchResult{
+		fusionTestResult(RetrieverKeyword, 1, 1, 1, 1),
+		fusionTestResult(RetrieverKeyword, 2, 0, 2, 0.5),
+	}
+	opts := fusionOptions{Method: FusionRRF, RRFK: 60, VectorWeight: 0.5}
+	results := fuseResults([]rankedResults{
+		{retriever: RetrieverVector, weight: 0.5, results: vector},
+		{retriever: RetrieverKeyword, weight: 0.5, results: keyword},
+	}, opts, 10)
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 fused results, got %d", len(results))
+	}
+	top := results[0]
+	if top.DocumentID != 1 || top.ChunkIndex != 1 || len(top.ScoreDetail.Sources) != 2 {
+		t.Fatalf("expected chunk hit by both retrievers first: %+v", top.ScoreDetail)
+	}
+	wantRaw := 0.5/62 + 0.5/61
+	if math.Abs(*top.ScoreDetail.FusionScore-wantRaw) > 1e-9 || math.Abs(top.Score-wantRaw*61) > 1e-9 {
+		t.Fatalf("unexpected rrf score: raw=%v score=%v", *top.ScoreDetail.FusionScore, top.Score)
+	}
+	if top.ScoreDetail.Method != FusionRRF {
+		t.Fatalf("unexpected method: %s", top.ScoreDetail.Method)
+	}
+	if results[1].ChunkIndex != 0 || results[1].DocumentID != 1 {
+		t.Fatalf("expected vector rank 1 before keyword rank 2: %+v", results[1])
+	}
+}
+
+// TestFuseResults_Weighted 加权融合按来源归一化分数加权求和
+func TestFuseResults_Weighted(t *testing.T) {
+	results := fuseResults([]rankedResults{
+		{retriever: RetrieverVector, weight: 0.3, results: []*KnowledgeSearchResult{fusionTestResult(RetrieverVector, 1, 0, 1, 0.9)}},
+		{retriever: RetrieverKeyword, weight: 0.7, results: []*KnowledgeSearchResult{
+			fusionTestResult(RetrieverKeyword, 2, 0, 1, 1),
+			fusionTestResult(RetrieverKeyword, 1, 0, 2, 0.2),
+		}},
+	}, fusionOptions{Method: FusionWeighted, RRFK: 60, VectorWeight: 0.3}, 1)
+
+	if len(results) != 1 || results[0].DocumentID != 2 || math.Abs(results[0].Score-0.7) > 1e-9 {
+		t.Fatalf("unexpected weighted result: %+v", results)
+	}
+}
+
+// TestResolveFusionOptions 请求参数覆盖知识库配置，非法取值报错
+func TestResolveFusionOptions(t *testing.T) {
+	cfg := model.DefaultKBConfig()
+	weight := 0.4
+	opts, err := resolveFusionOptions(cfg, &KnowledgeSearchReq{FusionMethod: FusionWeighted, VectorWeight: &weight})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Method != FusionWeighted || opts.VectorWeight != 0.4 || opts.RRFK != 60 {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	if _, err := resolveFusionOptions(cfg, &KnowledgeSearchReq{FusionMethod: "max"}); err == nil {
+		t.Fatal("expected invalid fusion method error")
+	}
+	weight = 1.5
+	if _, err := resolveFusionOptions(cfg, &KnowledgeSearchReq{VectorWeight: &weight}); err == nil {
+		t.Fatal("expected invalid vector weight error")
+	}
+}
+
+// TestRerankClient 调用 /rerank 接口并按相关度重排结果
+func TestRerankClient(t *testing.T) {
+	var req rerankRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/rerank" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("unexpected request %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"results":[{"index":0,"relevance_score":0.12},{"index":2,"relevance_score":0.95}]}`))
+	}))
+	defer server.Close()
+
+	client := NewRerankClient(server.URL+"/v1/", "sk-test", "bge-reranker")
+	ranked, err := client.Rerank(context.Background(), "如何安装", []string{"a", "b", "c"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Model != "bge-reranker" || req.Query != "如何安装" || req.TopN != 2 || len(req.Documents) != 3 {
+		t.Fatalf("unexpected rerank request: %+v", req)
+	}
+
+	results := []*KnowledgeSearchResult{
+		fusionTestResult(RetrieverVector, 1, 0, 1, 0.9),
+		fusionTestResult(RetrieverVector, 1, 1, 2, 0.8),
+		fusionTestResult(RetrieverVector, 1, 2, 3, 0.7),
+	}
+	results = applyRerank(results, ranked, client.Model, 2)
+	if len(results) != 2 || results[0].ChunkIndex != 2 || results[0].Score != 0.95 {
+		t.Fatalf("unexpected reranked results: %+v", results)
+	}
+	if *results[0].ScoreDetail.RerankScore != 0.95 || results[0].ScoreDetail.RerankModel != "bge-reranker" ||
+		results[0].ScoreDetail.Sources[0].Rank != 3 {
+		t.Fatalf("unexpected rerank explanation: %+v", results[0].ScoreDetail)
+	}
+
+	if _, err := client.Rerank(context.Background(), "q", []string{"only"}, 1); err == nil {
+		t.Fatal("expected out-of-range index error")
+	}
+}
@@ -0,0 +1,453 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+	"yqhp/gulu/internal/utils"
+)
+
+// -----------------------------------------------
+// 知识库数据源连接器：Git 仓库 / 网站 / 服务器目录
+// 连接器只负责列出数据源中的文档及其内容哈希，增量比对与入库由 knowledge_source_sync.go 完成。
+// -----------------------------------------------
+
+// sourceCrawlerUA 网站抓取使用的 User-Agent，robots.txt 中可按 gulu-kb-crawler 单独配置规则
+const sourceCrawlerUA = "gulu-kb-crawler/1.0"
+
+// sourceDocExts 未配置 include 时收录的文件扩展名
+var sourceDocExts = map[string]bool{
+	".md": true, ".markdown": true, ".txt": true, ".html": true, ".htm": true,
+	".pdf": true, ".docx": true, ".csv": true, ".json": true, ".xlsx": true,
+}
+
+// sourceSettings 数据源同步参数（配置 + 默认值）
+type sourceSettings struct {
+	FolderRoots         []string
+	GitBinary           string
+	MaxFileSize         int64
+	SyncTimeout         time.Duration
+	AllowPrivateNetwork bool // 网站与 Git 数据源是否允许访问内网地址
+}
+
+func getSourceSettings() sourceSettings {
+	s := sourceSettings{
+		GitBinary:   "git",
+		MaxFileSize: 20 << 20,
+		SyncTimeout: 30 * time.Minute,
+	}
+	if svc.Ctx != nil && svc.Ctx.Config != nil {
+		c := svc.Ctx.Config.KnowledgeSource
+		s.FolderRoots = c.FolderRoots
+		s.AllowPrivateNetwork = svc.Ctx.Config.Gulu.AllowPrivateNetwork
+		if c.GitBinary != "" {
+			s.GitBinary = c.GitBinary
+		}
+		if c.MaxFileSize > 0 {
+			s.MaxFileSize = c.MaxFileSize
+		}
+		if c.SyncTimeout > 0 {
+			s.SyncTimeout = c.SyncTimeout
+		}
+	}
+	return s
+}
+
+// sourceItem 数据源中的一个文档
+type sourceItem struct {
+	Key      string // 数据源内的唯一标识：相对路径 / 规范化 URL
+	Name     string // 文档名称
+	FileType string
+	Ext      string // 存储文件扩展名
+	Hash     string // 内容 SHA-256
+	Err      error  // 读取失败时保留已有文档，本次不更新也不删除
+	read     func() ([]byte, error)
+}
+
+// sourceListing 连接器列出的文档
+type sourceListing struct {
+	Items    []sourceItem
+	Revision string // Git 提交
+	Partial  bool   // 列表不完整（如达到页面上限），本次不删除未出现的文档
+	cleanup  func() // 释放临时目录等资源，入库完成后调用
+}
+
+// Close 释放连接器占用的临时资源
+func (l *sourceListing) Close() {
+	if l.cleanup != nil {
+		l.cleanup()
+	}
+}
+
+// sourceConnector 数据源连接器
+type sourceConnector interface {
+	Collect(ctx context.Context) (*sourceListing, error)
+}
+
+// newSourceConnector 按数据源类型创建连接器
+func newSourceConnector(sourceType string, cfg model.KnowledgeSourceConfig, s sourceSettings) (sourceConnector, error) {
+	if err := validateSourceConfig(sourceType, cfg, s); err != nil {
+		return nil, err
+	}
+	switch sourceType {
+	case model.KnowledgeSourceGit:
+		return &gitSourceConnector{cfg: cfg, settings: s}, nil
+	case model.KnowledgeSourceFolder:
+		root, _ := resolveFolderSource(cfg.Path, s.FolderRoots)
+		return &folderSourceConnector{root: root, cfg: cfg, settings: s}, nil
+	case model.KnowledgeSourceWebsite:
+		return newWebsiteSourceConnector(cfg, s)
+	}
+	return nil, fmt.Errorf("不支持的数据源类型: %s", sourceType)
+}
+
+// validateSourceConfig 校验数据源配置
+func validateSourceConfig(sourceType string, cfg model.KnowledgeSourceConfig, s sourceSettings) error {
+	for _, g := range append(append([]string{}, cfg.Include...), cfg.Exclude...) {
+		if err := validateSourceGlob(g); err != nil {
+			return err
+		}
+	}
+
+	switch sourceType {
+	case model.KnowledgeSourceGit:
+		if cfg.RepoURL == "" {
+			return errors.New("仓库地址不能为空")
+		}
+		if err := validateGitRepoURL(cfg.RepoURL, s.FolderRoots); err != nil {
+			return err
+		}
+		if strings.HasPrefix(cfg.Branch, "-") {
+			return fmt.Errorf("无效的分支名: %s", cfg.Branch)
+		}
+	case model.KnowledgeSourceFolder:
+		if _, err := resolveFolderSource(cfg.Path, s.FolderRoots); err != nil {
+			return err
+		}
+	case model.KnowledgeSourceWebsite:
+		if len(cfg.SeedURLs) == 0 && len(cfg.SitemapURLs) == 0 {
+			return errors.New("至少需要一个种子 URL 或 sitemap")
+		}
+		for _, raw := range append(append([]string{}, cfg.SeedURLs...), cfg.SitemapURLs...) {
+			if _, err := normalizeCrawlURL(raw); err != nil {
+				return err
+			}
+		}
+		for _, p := range append(append([]string{}, cfg.IncludePatterns...), cfg.ExcludePatterns...) {
+			if _, err := regexp.Compile(p); err != nil {
+				return fmt.Errorf("无效的 URL 正则 %q: %v", p, err)
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的数据源类型: %s（可选 git / website / folder）", sourceType)
+	}
+	return nil
+}
+
+// -----------------------------------------------
+// 文件类数据源（Git / 目录）
+// -----------------------------------------------
+
+// validateSourceGlob 校验 glob 语法
+func validateSourceGlob(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("无效的 glob %q", pattern)
+		}
+	}
+	return nil
+}
+
+// matchSourceGlob 匹配相对路径（/ 分隔）。** 匹配任意层目录；不含 / 的模式只匹配文件名。
+func matchSourceGlob(pattern, relPath string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(relPath))
+		return ok
+	}
+	return matchGlobSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(relPath, "/"))
+}
+
+func matchGlobSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchGlobSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// includeSourceFile 按 include / exclude 判断是否收录文件
+func includeSourceFile(relPath string, include, exclude []string) bool {
+	for _, g := range exclude {
+		if matchSourceGlob(g, relPath) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return sourceDocExts[strings.ToLower(path.Ext(relPath))]
+	}
+	for _, g := range include {
+		if matchSourceGlob(g, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkSourceFiles 遍历目录，列出匹配的文件并计算内容哈希。跳过 .git 目录和符号链接。
+func walkSourceFiles(ctx context.Context, root string, include, exclude []string, maxSize int64) ([]sourceItem, error) {
+	var items []sourceItem
+	err := filepath.WalkDir(root, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, full)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !includeSourceFile(rel, include, exclude) {
+			return nil
+		}
+
+		item := sourceItem{
+			Key:      rel,
+			Name:     rel,
+			FileType: InferFileType(rel),
+			Ext:      strings.ToLower(path.Ext(rel)),
+			read:     func() ([]byte, error) { return os.ReadFile(full) },
+		}
+		info, err := d.Info()
+		switch {
+		case err != nil:
+			item.Err = err
+		case info.Size() > maxSize:
+			item.Err = fmt.Errorf("文件大小 %d 超过上限 %d", info.Size(), maxSize)
+		default:
+			data, err := os.ReadFile(full)
+			if err != nil {
+				item.Err = err
+			} else {
+				item.Hash = contentHash(data)
+			}
+		}
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// resolveFolderSource 解析目录数据源路径，要求位于配置的 folder_roots 之下（解析符号链接后比较）
+func resolveFolderSource(dir string, roots []string) (string, error) {
+	if len(roots) == 0 {
+		return "", errors.New("未配置 knowledge_source.folder_roots，目录数据源不可用")
+	}
+	if dir == "" {
+		return "", errors.New("目录路径不能为空")
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("目录不存在: %s", dir)
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return "", err
+	}
+	for _, root := range roots {
+		r, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		if r, err = filepath.Abs(r); err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(r, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("目录 %s 不在允许的范围内（knowledge_source.folder_roots）", dir)
+}
+
+// folderSourceConnector 服务器目录
+type folderSourceConnector struct {
+	root     string
+	cfg      model.KnowledgeSourceConfig
+	settings sourceSettings
+}
+
+func (c *folderSourceConnector) Collect(ctx context.Context) (*sourceListing, error) {
+	items, err := walkSourceFiles(ctx, c.root, c.cfg.Include, c.cfg.Exclude, c.settings.MaxFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("读取目录失败: %w", err)
+	}
+	return &sourceListing{Items: items}, nil
+}
+
+// scpLikeGitURL 匹配 git@host:path 形式的 SSH 地址
+var scpLikeGitURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^:]`)
+
+// validateGitRepoURL 仅允许 https / http / ssh 地址；file:// 仅允许 folder_roots 下的本地仓库
+func validateGitRepoURL(raw string, roots []string) error {
+	if scpLikeGitURL.MatchString(raw) {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("无效的仓库地址: %s", raw)
+	}
+	switch u.Scheme {
+	case "https", "http", "ssh":
+		if u.Host == "" {
+			return fmt.Errorf("无效的仓库地址: %s", raw)
+		}
+		return nil
+	case "file":
+		_, err := resolveFolderSource(u.Path, roots)
+		return err
+	}
+	return fmt.Errorf("不支持的仓库地址协议: %s（可选 https / ssh）", raw)
+}
+
+// gitSourceConnector Git 仓库：每次同步浅克隆到临时目录后按 glob 遍历
+type gitSourceConnector struct {
+	cfg      model.KnowledgeSourceConfig
+	settings sourceSettings
+}
+
+func (c *gitSourceConnector) Collect(ctx context.Context) (*sourceListing, error) {
+	dir, err := os.MkdirTemp("", "kb-source-git-*")
+	if err != nil {
+		return nil, err
+	}
+	listing := &sourceListing{cleanup: func() { os.RemoveAll(dir) }}
+	fail := func(err error) (*sourceListing, error) {
+		listing.Close()
+		return nil, err
+	}
+
+	var args []string
+	if c.cfg.Token != "" {
+		user := c.cfg.Username
+		if user == "" {
+			user = "git"
+		}
+		cred := base64.StdEncoding.EncodeToString([]byte(user + ":" + c.cfg.Token))
+		args = append(args, "-c", "http.extraHeader=Authorization: Basic "+cred)
+	}
+	pin, err := c.pinPublicHost(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	args = append(args, pin...)
+	args = append(args, "clone", "--depth", "1", "--single-branch", "--no-tags")
+	if c.cfg.Branch != "" {
+		args = append(args, "--branch", c.cfg.Branch)
+	}
+	args = append(args, "--", c.cfg.RepoURL, dir)
+	if _, err := c.git(ctx, "", args...); err != nil {
+		return fail(fmt.Errorf("克隆仓库失败: %w", err))
+	}
+
+	rev, err := c.git(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return fail(fmt.Errorf("读取仓库提交失败: %w", err))
+	}
+	if listing.Items, err = walkSourceFiles(ctx, dir, c.cfg.Include, c.cfg.Exclude, c.settings.MaxFileSize); err != nil {
+		return fail(err)
+	}
+	listing.Revision = strings.TrimSpace(rev)
+	return listing, nil
+}
+
+// pinPublicHost http(s) 仓库地址须解析为公网地址：禁止重定向，并通过 curl 的 resolve 选项把连接固定到校验过的地址，
+// 避免 DNS 在校验后被重绑定到内网
+func (c *gitSourceConnector) pinPublicHost(ctx context.Context) ([]string, error) {
+	if c.settings.AllowPrivateNetwork {
+		return nil, nil
+	}
+	u, err := url.Parse(c.cfg.RepoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, nil
+	}
+	ip, err := utils.ResolvePublicIP(ctx, u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("仓库地址不可访问: %w", err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	addr := ip.String()
+	if ip.To4() == nil {
+		addr = "[" + addr + "]"
+	}
+	return []string{
+		"-c", "http.followRedirects=false",
+		"-c", "http.curloptResolve=" + u.Hostname() + ":" + port + ":" + addr,
+	}, nil
+}
+
+// git 执行 git 命令，禁止交互式认证提示；错误信息中不包含认证参数
+func (c *gitSourceConnector) git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, c.settings.GitBinary, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=true")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", errors.New(msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,286 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"yqhp/common/scheduler"
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+
+	"gorm.io/gorm"
+)
+
+// sourceTokenMask 返回给前端的令牌掩码，更新时传回掩码表示保留原令牌
+const sourceTokenMask = "******"
+
+// KnowledgeSourceLogic 知识库数据源逻辑
+type KnowledgeSourceLogic struct {
+	ctx context.Context
+}
+
+// NewKnowledgeSourceLogic 创建知识库数据源逻辑
+func NewKnowledgeSourceLogic(ctx context.Context) *KnowledgeSourceLogic {
+	return &KnowledgeSourceLogic{ctx: ctx}
+}
+
+// KnowledgeSourceReq 创建/更新数据源请求
+type KnowledgeSourceReq struct {
+	Name           string                       `json:"name"`
+	Type           string                       `json:"type"` // git / website / folder，创建后不可修改
+	Config         *model.KnowledgeSourceConfig `json:"config"`
+	CronExpression *string                      `json:"cron_expression"` // 6 段（含秒），空字符串表示仅手动同步
+	Status         *int32                       `json:"status"`          // 1 启用定时同步 0 暂停
+}
+
+// KnowledgeSourceInfo 数据源返回信息
+type KnowledgeSourceInfo struct {
+	ID              int64                           `json:"id"`
+	CreatedAt       *time.Time                      `json:"created_at"`
+	UpdatedAt       *time.Time                      `json:"updated_at"`
+	KnowledgeBaseID int64                           `json:"knowledge_base_id"`
+	Name            string                          `json:"name"`
+	Type            string                          `json:"type"`
+	Config          model.KnowledgeSourceConfig     `json:"config"`
+	CronExpression  string                          `json:"cron_expression"`
+	Status          int32                           `json:"status"`
+	SyncStatus      string                          `json:"sync_status"`
+	SyncStartedAt   *time.Time                      `json:"sync_started_at"`
+	LastSyncAt      *time.Time                      `json:"last_sync_at"`
+	LastSyncStats   *model.KnowledgeSourceSyncStats `json:"last_sync_stats"`
+	LastError       string                          `json:"last_error"`
+	NextSyncAt      *time.Time                      `json:"next_sync_at"`
+	DocumentCount   int64                           `json:"document_count"`
+}
+
+func (l *KnowledgeSourceLogic) db() *gorm.DB {
+	return svc.Ctx.DB.WithContext(l.ctx)
+}
+
+func (l *KnowledgeSourceLogic) getSource(kbID, sourceID int64) (*model.TKnowledgeSource, error) {
+	var src model.TKnowledgeSource
+	if err := l.db().Where("id = ? AND knowledge_base_id = ? AND is_delete = 0", sourceID, kbID).First(&src).Error; err != nil {
+		return nil, errors.New("数据源不存在")
+	}
+	return &src, nil
+}
+
+// Create 创建数据源
+func (l *KnowledgeSourceLogic) Create(kbID int64, req *KnowledgeSourceReq, userID int64) (*KnowledgeSourceInfo, error) {
+	var kb model.TKnowledgeBase
+	if err := l.db().Where("id = ? AND is_delete = 0", kbID).First(&kb).Error; err != nil {
+		return nil, errors.New("知识库不存在")
+	}
+	if req.Name == "" {
+		return nil, errors.New("数据源名称不能为空")
+	}
+	if req.Config == nil {
+		return nil, errors.New("数据源配置不能为空")
+	}
+	cronExpr := ""
+	if req.CronExpression != nil {
+		cronExpr = *req.CronExpression
+	}
+	if err := validateSource(req.Type, *req.Config, cronExpr); err != nil {
+		return nil, err
+	}
+
+	cfgJSON, err := json.Marshal(req.Config)
+	if err != nil {
+		return nil, err
+	}
+	cfgStr := string(cfgJSON)
+	status := model.ScheduleStatusEnabled
+	if req.Status != nil {
+		status = *req.Status
+	}
+	now := time.Now()
+	isDelete := false
+	src := &model.TKnowledgeSource{
+		CreatedAt:       &now,
+		UpdatedAt:       &now,
+		IsDelete:        &isDelete,
+		CreatedBy:       &userID,
+		KnowledgeBaseID: kbID,
+		Name:            req.Name,
+		Type:            req.Type,
+		Config:          &cfgStr,
+		CronExpression:  cronExpr,
+		Status:          &status,
+		SyncStatus:      model.SourceSyncIdle,
+	}
+	if err := l.db().Create(src).Error; err != nil {
+		return nil, err
+	}
+
+	notifySourceChanged(src.ID)
+	return l.toSourceInfo(src, 0), nil
+}
+
+// Update 更新数据源；令牌为空或为掩码时保留原令牌
+func (l *KnowledgeSourceLogic) Update(kbID, sourceID int64, req *KnowledgeSourceReq) error {
+	src, err := l.getSource(kbID, sourceID)
+	if err != nil {
+		return err
+	}
+	if req.Type != "" && req.Type != src.Type {
+		return errors.New("数据源类型不可修改")
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	cfg := src.GetConfig()
+	if req.Config != nil {
+		token := cfg.Token
+		cfg = *req.Config
+		if cfg.Token == "" || cfg.Token == sourceTokenMask {
+			cfg.Token = token
+		}
+		cfgJSON, err := json.Marshal(cfg)
+		if err != nil {
+			return err
+		}
+		updates["config"] = string(cfgJSON)
+	}
+	cronExpr := src.CronExpression
+	if req.CronExpression != nil {
+		cronExpr = *req.CronExpression
+		updates["cron_expression"] = cronExpr
+	}
+	if err := validateSource(src.Type, cfg, cronExpr); err != nil {
+		return err
+	}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+
+	if err := l.db().Model(&model.TKnowledgeSource{}).Where("id = ?", sourceID).Updates(updates).Error; err != nil {
+		return err
+	}
+	notifySourceChanged(sourceID)
+	return nil
+}
+
+// Delete 删除数据源；keepDocuments 为 true 时保留已同步的文档（转为普通文档），否则一并删除
+func (l *KnowledgeSourceLogic) Delete(kbID, sourceID int64, keepDocuments bool) error {
+	if _, err := l.getSource(kbID, sourceID); err != nil {
+		return err
+	}
+	if err := l.db().Model(&model.TKnowledgeSource{}).Where("id = ?", sourceID).
+		Updates(map[string]interface{}{"is_delete": true, "updated_at": time.Now()}).Error; err != nil {
+		return err
+	}
+	notifySourceChanged(sourceID)
+
+	if keepDocuments {
+		return l.db().Model(&model.TKnowledgeDocument{}).Where("source_id = ?", sourceID).
+			Updates(map[string]interface{}{"source_id": nil, "source_key": nil, "content_hash": nil}).Error
+	}
+	var docIDs []int64
+	l.db().Model(&model.TKnowledgeDocument{}).Where("source_id = ?", sourceID).Pluck("id", &docIDs)
+	if len(docIDs) == 0 {
+		return nil
+	}
+	return NewKnowledgeBaseLogic(l.ctx).BatchDeleteDocuments(kbID, docIDs)
+}
+
+// List 获取知识库的数据源列表
+func (l *KnowledgeSourceLogic) List(kbID int64) ([]*KnowledgeSourceInfo, error) {
+	var list []*model.TKnowledgeSource
+	if err := l.db().Where("knowledge_base_id = ? AND is_delete = 0", kbID).Order("id ASC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	type countRow struct {
+		SourceID int64
+		Cnt      int64
+	}
+	var rows []countRow
+	l.db().Model(&model.TKnowledgeDocument{}).Select("source_id, COUNT(*) AS cnt").
+		Where("knowledge_base_id = ? AND source_id IS NOT NULL", kbID).Group("source_id").Scan(&rows)
+	counts := make(map[int64]int64, len(rows))
+	for _, r := range rows {
+		counts[r.SourceID] = r.Cnt
+	}
+
+	result := make([]*KnowledgeSourceInfo, 0, len(list))
+	for _, src := range list {
+		result = append(result, l.toSourceInfo(src, counts[src.ID]))
+	}
+	return result, nil
+}
+
+// Sync 立即同步数据源（后台执行），正在同步时返回错误
+func (l *KnowledgeSourceLogic) Sync(kbID, sourceID int64) error {
+	if _, err := l.getSource(kbID, sourceID); err != nil {
+		return err
+	}
+	if err := claimSourceSync(sourceID); err != nil {
+		return err
+	}
+	safeGo(func() { runSourceSync(sourceID) })
+	return nil
+}
+
+// deleteKnowledgeSources 删除知识库下的所有数据源（文档由知识库删除流程清理）
+func deleteKnowledgeSources(kbID int64) {
+	var ids []int64
+	svc.Ctx.DB.Model(&model.TKnowledgeSource{}).Where("knowledge_base_id = ? AND is_delete = 0", kbID).Pluck("id", &ids)
+	if len(ids) == 0 {
+		return
+	}
+	svc.Ctx.DB.Model(&model.TKnowledgeSource{}).Where("id IN ?", ids).Update("is_delete", true)
+	for _, id := range ids {
+		notifySourceChanged(id)
+	}
+}
+
+// validateSource 校验数据源类型、配置与同步 cron
+func validateSource(sourceType string, cfg model.KnowledgeSourceConfig, cronExpr string) error {
+	if err := validateSourceConfig(sourceType, cfg, getSourceSettings()); err != nil {
+		return err
+	}
+	if cronExpr != "" {
+		if err := scheduler.ValidateCron(cronExpr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *KnowledgeSourceLogic) toSourceInfo(src *model.TKnowledgeSource, docCount int64) *KnowledgeSourceInfo {
+	cfg := src.GetConfig()
+	if cfg.Token != "" {
+		cfg.Token = sourceTokenMask
+	}
+	info := &KnowledgeSourceInfo{
+		ID:              src.ID,
+		CreatedAt:       src.CreatedAt,
+		UpdatedAt:       src.UpdatedAt,
+		KnowledgeBaseID: src.KnowledgeBaseID,
+		Name:            src.Name,
+		Type:            src.Type,
+		Config:          cfg,
+		CronExpression:  src.CronExpression,
+		SyncStatus:      src.SyncStatus,
+		SyncStartedAt:   src.SyncStartedAt,
+		LastSyncAt:      src.LastSyncAt,
+		LastSyncStats:   src.GetLastSyncStats(),
+		LastError:       derefString(src.LastError),
+		DocumentCount:   docCount,
+	}
+	if src.Status != nil {
+		info.Status = *src.Status
+	}
+	if sched := GetScheduler(); sched != nil {
+		if job, err := sched.GetJob(sourceJobName(src.ID)); err == nil && !job.NextRun.IsZero() {
+			next := job.NextRun
+			info.NextSyncAt = &next
+		}
+	}
+	return info
+}
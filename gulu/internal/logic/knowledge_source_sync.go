@@ -0,0 +1,391 @@
+package logic
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"yqhp/common/logger"
+	"yqhp/common/scheduler"
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// -----------------------------------------------
+// 知识库数据源增量同步
+// 按内容哈希比对：新增与变化的文档写入文件并进入入库队列，未变化的跳过，数据源中已移除的文档连同分块与向量删除。
+// -----------------------------------------------
+
+// sourceSyncChannel 数据源变更通知频道，多实例部署时各实例据此重新注册同步计划
+const sourceSyncChannel = "gulu:knowledge_source:sync"
+
+// errSourceSyncing 数据源正在同步
+var errSourceSyncing = errors.New("数据源正在同步中")
+
+func sourceJobName(id int64) string {
+	return fmt.Sprintf("knowledge_source:%d", id)
+}
+
+// sourceDocState 数据源已同步文档的状态
+type sourceDocState struct {
+	ID   int64
+	Hash string
+}
+
+// sourceDocUpdate 内容变化的文档
+type sourceDocUpdate struct {
+	DocID int64
+	Item  sourceItem
+}
+
+// sourceSyncPlan 增量同步计划
+type sourceSyncPlan struct {
+	Create    []sourceItem
+	Update    []sourceDocUpdate
+	Delete    []int64
+	Unchanged int
+	Skipped   int
+}
+
+// planSourceSync 比对数据源列表与已同步文档。读取失败的条目保留原文档；列表不完整时不删除。
+func planSourceSync(existing map[string]sourceDocState, listing *sourceListing) sourceSyncPlan {
+	var plan sourceSyncPlan
+	seen := make(map[string]bool, len(listing.Items))
+	for _, item := range listing.Items {
+		if seen[item.Key] {
+			continue
+		}
+		seen[item.Key] = true
+
+		doc, ok := existing[item.Key]
+		switch {
+		case item.Err != nil:
+			plan.Skipped++
+		case !ok:
+			plan.Create = append(plan.Create, item)
+		case doc.Hash != item.Hash:
+			plan.Update = append(plan.Update, sourceDocUpdate{DocID: doc.ID, Item: item})
+		default:
+			plan.Unchanged++
+		}
+	}
+	if !listing.Partial {
+		for key, doc := range existing {
+			if !seen[key] {
+				plan.Delete = append(plan.Delete, doc.ID)
+			}
+		}
+	}
+	return plan
+}
+
+// sourceFilePath 数据源文档的存储路径，同一文档每次同步覆盖同一文件
+func sourceFilePath(kbID, sourceID int64, key, ext string) string {
+	sum := sha1.Sum([]byte(key))
+	return fmt.Sprintf("kb_%d/source_%d/%s%s", kbID, sourceID, hex.EncodeToString(sum[:])[:16], ext)
+}
+
+// sourceDocName 文档名称，超长时截断
+func sourceDocName(name string) string {
+	if utf8.RuneCountInString(name) <= 255 {
+		return name
+	}
+	return string([]rune(name)[:255])
+}
+
+// claimSourceSync 抢占数据源的同步权，正在同步（且未超时）时返回 errSourceSyncing
+func claimSourceSync(id int64) error {
+	now := time.Now()
+	staleBefore := now.Add(-getSourceSettings().SyncTimeout - 5*time.Minute)
+	res := svc.Ctx.DB.Model(&model.TKnowledgeSource{}).
+		Where("id = ? AND is_delete = 0", id).
+		Where("sync_status <> ? OR sync_started_at IS NULL OR sync_started_at < ?", model.SourceSyncRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"sync_status":     model.SourceSyncRunning,
+			"sync_started_at": now,
+			"updated_at":      now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errSourceSyncing
+	}
+	return nil
+}
+
+// runSourceSync 执行一次同步并记录结果（调用方已通过 claimSourceSync 抢占）
+func runSourceSync(id int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), getSourceSettings().SyncTimeout)
+	defer cancel()
+
+	stats, err := syncKnowledgeSource(ctx, id)
+	now := time.Now()
+	updates := map[string]interface{}{
+		"sync_status":  model.SourceSyncSuccess,
+		"last_sync_at": now,
+		"last_error":   nil,
+		"updated_at":   now,
+	}
+	if stats != nil {
+		b, _ := json.Marshal(stats)
+		updates["last_sync_stats"] = string(b)
+	}
+	if err != nil {
+		updates["sync_status"] = model.SourceSyncFailed
+		updates["last_error"] = err.Error()
+		log.Printf("[ERROR] 数据源同步失败: sourceID=%d, err=%v", id, err)
+	} else {
+		log.Printf("[INFO] 数据源同步完成: sourceID=%d, 新增 %d, 更新 %d, 未变化 %d, 删除 %d, 跳过 %d",
+			id, stats.Added, stats.Updated, stats.Unchanged, stats.Deleted, stats.Skipped)
+	}
+	svc.Ctx.DB.Model(&model.TKnowledgeSource{}).Where("id = ?", id).Updates(updates)
+}
+
+// syncKnowledgeSource 列出数据源文档并按增量计划入库
+func syncKnowledgeSource(ctx context.Context, id int64) (*model.KnowledgeSourceSyncStats, error) {
+	db := svc.Ctx.DB
+	var src model.TKnowledgeSource
+	if err := db.Where("id = ? AND is_delete = 0", id).First(&src).Error; err != nil {
+		return nil, errors.New("数据源不存在")
+	}
+	var kb model.TKnowledgeBase
+	if err := db.Where("id = ? AND is_delete = 0", src.KnowledgeBaseID).First(&kb).Error; err != nil {
+		return nil, errors.New("知识库不存在")
+	}
+
+	connector, err := newSourceConnector(src.Type, src.GetConfig(), getSourceSettings())
+	if err != nil {
+		return nil, err
+	}
+	listing, err := connector.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer listing.Close()
+
+	var docs []model.TKnowledgeDocument
+	if err := db.Select("id, source_key, content_hash").Where("source_id = ?", src.ID).Find(&docs).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[string]sourceDocState, len(docs))
+	for _, d := range docs {
+		existing[derefString(d.SourceKey)] = sourceDocState{ID: d.ID, Hash: derefString(d.ContentHash)}
+	}
+
+	plan := planSourceSync(existing, listing)
+	stats := &model.KnowledgeSourceSyncStats{
+		Total:     len(listing.Items),
+		Unchanged: plan.Unchanged,
+		Skipped:   plan.Skipped,
+		Revision:  listing.Revision,
+	}
+
+	for _, item := range plan.Create {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if err := createSourceDocument(&kb, &src, item); err != nil {
+			log.Printf("[WARN] 数据源文档入库失败: sourceID=%d, key=%s, err=%v", src.ID, item.Key, err)
+			stats.Skipped++
+			continue
+		}
+		stats.Added++
+	}
+	for _, u := range plan.Update {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if err := updateSourceDocument(&kb, &src, u.DocID, u.Item); err != nil {
+			if !errors.Is(err, errIngestRunning) {
+				log.Printf("[WARN] 数据源文档更新失败: sourceID=%d, key=%s, err=%v", src.ID, u.Item.Key, err)
+			}
+			stats.Skipped++
+			continue
+		}
+		stats.Updated++
+	}
+	if len(plan.Delete) > 0 {
+		if err := NewKnowledgeBaseLogic(ctx).BatchDeleteDocuments(kb.ID, plan.Delete); err != nil {
+			return stats, err
+		}
+		stats.Deleted = len(plan.Delete)
+	}
+	if stats.Added+stats.Updated > 0 {
+		wakeIngestWorkers()
+	}
+	return stats, nil
+}
+
+// createSourceDocument 新增数据源文档并加入入库队列
+func createSourceDocument(kb *model.TKnowledgeBase, src *model.TKnowledgeSource, item sourceItem) error {
+	data, err := item.read()
+	if err != nil {
+		return err
+	}
+	relPath := sourceFilePath(kb.ID, src.ID, item.Key, item.Ext)
+	size := int64(len(data))
+	now := time.Now()
+	status := "waiting"
+	doc := &model.TKnowledgeDocument{
+		CreatedAt:       &now,
+		UpdatedAt:       &now,
+		KnowledgeBaseID: kb.ID,
+		Name:            sourceDocName(item.Name),
+		FileType:        &item.FileType,
+		FilePath:        &relPath,
+		FileSize:        &size,
+		IndexingStatus:  &status,
+		SourceID:        &src.ID,
+		SourceKey:       &item.Key,
+		ContentHash:     &item.Hash,
+	}
+	return svc.Ctx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(doc).Error; err != nil {
+			return err
+		}
+		if err := GetFileStorage().SaveBytes(relPath, data); err != nil {
+			return err
+		}
+		return enqueueIngestJob(tx, kb.ID, doc.ID)
+	})
+}
+
+// updateSourceDocument 覆盖变化的文档内容并重新入库。文档正在处理时跳过，下次同步再更新。
+func updateSourceDocument(kb *model.TKnowledgeBase, src *model.TKnowledgeSource, docID int64, item sourceItem) error {
+	data, err := item.read()
+	if err != nil {
+		return err
+	}
+	relPath := sourceFilePath(kb.ID, src.ID, item.Key, item.Ext)
+	return svc.Ctx.DB.Transaction(func(tx *gorm.DB) error {
+		if err := enqueueIngestJob(tx, kb.ID, docID); err != nil {
+			return err
+		}
+		if err := GetFileStorage().SaveBytes(relPath, data); err != nil {
+			return err
+		}
+		return tx.Model(&model.TKnowledgeDocument{}).Where("id = ?", docID).Updates(map[string]interface{}{
+			"name":         sourceDocName(item.Name),
+			"file_type":    item.FileType,
+			"file_path":    relPath,
+			"file_size":    len(data),
+			"content_hash": item.Hash,
+		}).Error
+	})
+}
+
+// -----------------------------------------------
+// 定时同步
+// -----------------------------------------------
+
+// LoadKnowledgeSourceSchedules 启动时注册所有启用定时同步的数据源
+func LoadKnowledgeSourceSchedules() error {
+	var sources []*model.TKnowledgeSource
+	if err := svc.Ctx.DB.Where("is_delete = 0 AND status = ? AND cron_expression <> ''", model.ScheduleStatusEnabled).
+		Find(&sources).Error; err != nil {
+		return err
+	}
+	for _, s := range sources {
+		if err := registerSourceSchedule(s); err != nil {
+			logger.Warn("加载数据源同步计划失败", zap.Int64("source_id", s.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// WatchKnowledgeSourceChanges 订阅其他实例发出的数据源变更通知，ctx 结束时退出
+func WatchKnowledgeSourceChanges(ctx context.Context) {
+	if svc.Ctx.Redis == nil {
+		return
+	}
+
+	sub := svc.Ctx.Redis.Subscribe(ctx, sourceSyncChannel)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			id, err := strconv.ParseInt(msg.Payload, 10, 64)
+			if err != nil {
+				continue
+			}
+			reloadSourceSchedule(id)
+		}
+	}
+}
+
+// notifySourceChanged 数据源变更后重新注册本实例的同步计划，并通知其他实例
+func notifySourceChanged(id int64) {
+	reloadSourceSchedule(id)
+
+	if svc.Ctx.Redis == nil {
+		return
+	}
+	if err := svc.Ctx.Redis.Publish(context.Background(), sourceSyncChannel, strconv.FormatInt(id, 10)).Err(); err != nil {
+		logger.Warn("发布数据源变更通知失败", zap.Int64("source_id", id), zap.Error(err))
+	}
+}
+
+// reloadSourceSchedule 按数据库最新状态注册、更新或移除同步计划
+func reloadSourceSchedule(id int64) {
+	var s model.TKnowledgeSource
+	err := svc.Ctx.DB.Where("id = ? AND is_delete = 0", id).First(&s).Error
+	if err != nil || s.CronExpression == "" || s.Status == nil || *s.Status != model.ScheduleStatusEnabled {
+		unregisterSourceSchedule(id)
+		return
+	}
+	if err := registerSourceSchedule(&s); err != nil {
+		logger.Warn("更新数据源同步计划失败", zap.Int64("source_id", id), zap.Error(err))
+	}
+}
+
+func registerSourceSchedule(s *model.TKnowledgeSource) error {
+	sched := GetScheduler()
+	if sched == nil {
+		return nil
+	}
+	name := sourceJobName(s.ID)
+	if sched.HasJob(name) {
+		return sched.UpdateCron(name, s.CronExpression)
+	}
+	return sched.AddDynamic(name, s.CronExpression, sourceJobFunc(s.ID), scheduler.WithConcurrency(scheduler.ConcurrencyForbid))
+}
+
+func unregisterSourceSchedule(id int64) {
+	name := sourceJobName(id)
+	if sched := GetScheduler(); sched != nil && sched.HasJob(name) {
+		if err := sched.Remove(name); err != nil {
+			logger.Warn("移除数据源同步计划失败", zap.Int64("source_id", id), zap.Error(err))
+		}
+	}
+}
+
+// sourceJobFunc 定时同步入口，同一数据源同时只有一个实例在同步
+func sourceJobFunc(id int64) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := claimSourceSync(id); err != nil {
+			if errors.Is(err, errSourceSyncing) {
+				return nil
+			}
+			return err
+		}
+		runSourceSync(id)
+		return nil
+	}
+}
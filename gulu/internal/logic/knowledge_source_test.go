@@ -0,0 +1,284 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/utils"
+)
+
+// TestMatchSourceGlob ** 匹配任意层目录，不含 / 的模式只匹配文件名
+func TestMatchSourceGlob(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"docs/**/*.md", "docs/api/auth.md", true},
+		{"docs/**/*.md", "docs/index.md", true},
+		{"docs/**/*.md", "src/readme.md", false},
+		{"docs/*.md", "docs/api/auth.md", false},
+		{"*.md", "a/b/c.md", true},
+		{"**/internal/**", "pkg/internal/x.txt", true},
+		{"/README.md", "README.md", true},
+	}
+	for _, c := range cases {
+		if got := matchSourceGlob(c.pattern, c.path); got != c.want {
+			t.Errorf("matchSourceGlob(%q, %q) = %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+
+	if !includeSourceFile("guide.pdf", nil, nil) || includeSourceFile("main.go", nil, nil) {
+		t.Fatal("expected default include to accept document types only")
+	}
+	if includeSourceFile("docs/draft/a.md", []string{"docs/**"}, []string{"docs/draft/**"}) {
+		t.Fatal("expected exclude to win over include")
+	}
+	if err := validateSourceGlob("docs/[a-"); err == nil {
+		t.Fatal("expected invalid glob error")
+	}
+}
+
+// TestPlanSourceSync 按哈希区分新增/更新/未变化，读取失败保留，列表不完整时不删除
+func TestPlanSourceSync(t *testing.T) {
+	existing := map[string]sourceDocState{
+		"a.md": {ID: 1, Hash: "h1"},
+		"b.md": {ID: 2, Hash: "h2"},
+		"c.md": {ID: 3, Hash: "h3"},
+		"d.md": {ID: 4, Hash: "h4"},
+	}
+	listing := &sourceListing{Items: []sourceItem{
+		{Key: "a.md", Hash: "h1"},
+		{Key: "b.md", Hash: "h2-new"},
+		{Key: "c.md", Err: errors.New("timeout")},
+		{Key: "e.md", Hash: "h5"},
+		{Key: "e.md", Hash: "h5-dup"},
+	}}
+
+	plan := planSourceSync(existing, listing)
+	if len(plan.Create) != 1 || plan.Create[0].Hash != "h5" {
+		t.Fatalf("unexpected create: %+v", plan.Create)
+	}
+	if len(plan.Update) != 1 || plan.Update[0].DocID != 2 {
+		t.Fatalf("unexpected update: %+v", plan.Update)
+	}
+	if plan.Unchanged != 1 || plan.Skipped != 1 || !reflect.DeepEqual(plan.Delete, []int64{4}) {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	listing.Partial = true
+	if plan := planSourceSync(existing, listing); len(plan.Delete) != 0 {
+		t.Fatalf("expected no deletes for partial listing: %v", plan.Delete)
+	}
+}
+
+// TestParseRobots 优先使用指定 agent 的分组，最长匹配规则生效
+func TestParseRobots(t *testing.T) {
+	data := []byte(`
+User-agent: *
+Disallow: /
+
+User-agent: gulu-kb-crawler
+User-agent: other
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+`)
+	r := parseRobots(data, sourceCrawlerUA)
+	cases := map[string]bool{
+		"/docs/a":               true,
+		"/private/x":            false,
+		"/private/public/x":     true,
+		"/files/a.pdf":          false,
+		"/files/a.pdf?download": true,
+	}
+	for p, want := range cases {
+		if got := r.Allowed(p); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", p, got, want)
+		}
+	}
+	if parseRobots(data, "someone-else/1.0").Allowed("/docs") {
+		t.Fatal("expected wildcard group to disallow everything")
+	}
+}
+
+// TestFolderSourceConnector 目录须位于 folder_roots 之下，按 glob 收录并计算哈希
+func TestFolderSourceConnector(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "docs/a.md", "# A")
+	writeTestFile(t, root, "docs/sub/b.txt", "B")
+	writeTestFile(t, root, "docs/big.md", "0123456789")
+	writeTestFile(t, root, "src/main.go", "package main")
+
+	s := sourceSettings{FolderRoots: []string{root}, MaxFileSize: 5}
+	if _, err := resolveFolderSource(filepath.Join(root, ".."), s.FolderRoots); err == nil {
+		t.Fatal("expected error for folder outside roots")
+	}
+
+	conn, err := newSourceConnector(model.KnowledgeSourceFolder, model.KnowledgeSourceConfig{
+		Path:    filepath.Join(root, "docs"),
+		Exclude: []string{"sub/**"},
+	}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listing, err := conn.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.Items) != 2 {
+		t.Fatalf("expected 2 items, got %+v", listing.Items)
+	}
+	a, big := listing.Items[0], listing.Items[1]
+	if a.Key != "a.md" || a.FileType != "md" || a.Hash != contentHash([]byte("# A")) {
+		t.Fatalf("unexpected item: %+v", a)
+	}
+	if big.Key != "big.md" || big.Err == nil {
+		t.Fatalf("expected oversize file to be reported as error: %+v", big)
+	}
+}
+
+// TestWebsiteSourceConnector 跟随同站链接、遵守 robots.txt、收录 sitemap，404 页面不计入
+func TestWebsiteSourceConnector(t *testing.T) {
+	var server *httptest.Server
+	pages := map[string]string{
+		"/":            `<html><head><title>Home</title></head><body><a href="/guide#top">Guide</a><a href="/private/x">P</a><a href="https://other.example.com/">ext</a><a href="/missing">M</a></body></html>`,
+		"/guide":       `<html><head><title>Guide</title></head><body><p>安装步骤</p><a href="/guide/deep">Deep</a></body></html>`,
+		"/guide/deep":  `<html><body>too deep</body></html>`,
+		"/private/x":   `<html><body>secret</body></html>`,
+		"/from-map":    `<html><head><title>Map</title></head><body>listed in sitemap</body></html>`,
+		"/changelog/1": `<html><body>excluded</body></html>`,
+	}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+			return
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<?xml version="1.0"?><urlset><url><loc>%s/from-map</loc></url><url><loc>%s/changelog/1</loc></url></urlset>`, server.URL, server.URL)
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	conn, err := newSourceConnector(model.KnowledgeSourceWebsite, (&model.TKnowledgeSource{}).GetConfig(), sourceSettings{MaxFileSize: 1 << 20})
+	if err == nil || conn != nil {
+		t.Fatal("expected error without seed urls")
+	}
+
+	src := &model.TKnowledgeSource{}
+	cfgJSON := fmt.Sprintf(`{"seed_urls":["%s"],"sitemap_urls":["%s/sitemap.xml"],"max_depth":1,"exclude_patterns":["/changelog/"],"request_delay_ms":1}`, server.URL, server.URL)
+	src.Config = &cfgJSON
+
+	// 默认禁止抓取内网地址（测试服务监听在回环地址）
+	conn, err = newSourceConnector(model.KnowledgeSourceWebsite, src.GetConfig(), sourceSettings{MaxFileSize: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.(*websiteSourceConnector).client.Get(server.URL); !errors.Is(err, utils.ErrNonPublicAddress) {
+		t.Fatalf("expected loopback address to be rejected, got %v", err)
+	}
+
+	conn, err = newSourceConnector(model.KnowledgeSourceWebsite, src.GetConfig(), sourceSettings{MaxFileSize: 1 << 20, AllowPrivateNetwork: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listing, err := conn.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for _, item := range listing.Items {
+		if item.Err != nil {
+			t.Fatalf("unexpected item error: %+v", item)
+		}
+		keys = append(keys, item.Key)
+	}
+	sort.Strings(keys)
+	want := []string{server.URL + "/", server.URL + "/from-map", server.URL + "/guide"}
+	if !reflect.DeepEqual(keys, want) || listing.Partial {
+		t.Fatalf("keys = %v, want %v (partial=%v)", keys, want, listing.Partial)
+	}
+	for _, item := range listing.Items {
+		if item.Key == server.URL+"/guide" {
+			data, _ := item.read()
+			if item.Name != "Guide" || item.FileType != "html" || item.Hash != contentHash([]byte(extractTextFromHTML(data))) {
+				t.Fatalf("unexpected guide item: %+v", item)
+			}
+		}
+	}
+}
+
+// TestGitSourceConnector 浅克隆本地仓库并按 glob 收录文件
+func TestGitSourceConnector(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	writeTestFile(t, repo, "docs/api.md", "# API")
+	writeTestFile(t, repo, "main.go", "package main")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	s := sourceSettings{FolderRoots: []string{root}, GitBinary: "git", MaxFileSize: 1 << 20}
+	if err := validateGitRepoURL("ext::sh -c touch% /tmp/pwned", nil); err == nil {
+		t.Fatal("expected ext transport to be rejected")
+	}
+	conn, err := newSourceConnector(model.KnowledgeSourceGit, model.KnowledgeSourceConfig{
+		RepoURL: "file://" + repo,
+		Branch:  "main",
+		Include: []string{"docs/**/*.md"},
+	}, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listing, err := conn.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listing.Close()
+	if len(listing.Items) != 1 || listing.Items[0].Key != "docs/api.md" || len(listing.Revision) != 40 {
+		t.Fatalf("unexpected listing: %+v", listing)
+	}
+	if data, err := listing.Items[0].read(); err != nil || string(data) != "# API" {
+		t.Fatalf("unexpected content %q: %v", data, err)
+	}
+}
+
+func writeTestFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	full := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
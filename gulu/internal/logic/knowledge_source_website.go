@@ -0,0 +1,458 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/utils"
+)
+
+// -----------------------------------------------
+// 网站数据源：从种子 URL 按深度抓取同站点页面，并收录 sitemap 中列出的页面
+// -----------------------------------------------
+
+// websiteSourceConnector 网站抓取
+type websiteSourceConnector struct {
+	cfg      model.KnowledgeSourceConfig
+	settings sourceSettings
+	client   *http.Client
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+	hosts    map[string]bool // 允许抓取的站点（种子 URL 与 sitemap 所在站点）
+	robots   map[string]*robotsRules
+	lastReq  time.Time
+}
+
+func newWebsiteSourceConnector(cfg model.KnowledgeSourceConfig, s sourceSettings) (*websiteSourceConnector, error) {
+	c := &websiteSourceConnector{
+		cfg:      cfg,
+		settings: s,
+		client:   utils.NewPublicHTTPClient(30*time.Second, s.AllowPrivateNetwork),
+		hosts:    make(map[string]bool),
+		robots:   make(map[string]*robotsRules),
+	}
+	for _, p := range cfg.IncludePatterns {
+		c.include = append(c.include, regexp.MustCompile(p))
+	}
+	for _, p := range cfg.ExcludePatterns {
+		c.exclude = append(c.exclude, regexp.MustCompile(p))
+	}
+	for _, raw := range append(append([]string{}, cfg.SeedURLs...), cfg.SitemapURLs...) {
+		u, _ := normalizeCrawlURL(raw)
+		c.hosts[u.Host] = true
+	}
+	return c, nil
+}
+
+// crawlTarget 待抓取页面
+type crawlTarget struct {
+	url   *url.URL
+	depth int
+}
+
+func (c *websiteSourceConnector) Collect(ctx context.Context) (*sourceListing, error) {
+	listing := &sourceListing{}
+	seen := make(map[string]bool)
+	var queue []crawlTarget
+	enqueue := func(u *url.URL, depth int) {
+		key := u.String()
+		if seen[key] || !c.hosts[u.Host] || c.excluded(key) {
+			return
+		}
+		seen[key] = true
+		queue = append(queue, crawlTarget{url: u, depth: depth})
+	}
+
+	for _, raw := range c.cfg.SeedURLs {
+		u, _ := normalizeCrawlURL(raw)
+		enqueue(u, 0)
+	}
+	// sitemap 中的页面视为完整列表的一部分，不再跟随其中的链接
+	for _, raw := range c.cfg.SitemapURLs {
+		locs, err := c.fetchSitemap(ctx, raw, 0)
+		if err != nil {
+			return nil, fmt.Errorf("读取 sitemap 失败: %w", err)
+		}
+		for _, loc := range locs {
+			if u, err := normalizeCrawlURL(loc); err == nil {
+				enqueue(u, c.cfg.MaxDepth)
+			}
+		}
+	}
+
+	fetched := 0
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if fetched >= c.cfg.MaxPages {
+			listing.Partial = true
+			log.Printf("[WARN] 网站数据源达到页面上限 %d，未抓取的页面本次不做删除", c.cfg.MaxPages)
+			break
+		}
+		t := queue[0]
+		queue = queue[1:]
+
+		if !c.cfg.IgnoreRobots && !c.robotsFor(ctx, t.url).Allowed(t.url.RequestURI()) {
+			continue
+		}
+		fetched++
+		page, err := c.fetchPage(ctx, t.url)
+		if err != nil {
+			// 临时失败保留已有文档；404/410 视为页面已删除
+			if !errors.Is(err, errPageGone) {
+				listing.Items = append(listing.Items, sourceItem{Key: t.url.String(), Err: err})
+			}
+			continue
+		}
+		if page == nil {
+			continue
+		}
+
+		if c.included(page.url.String()) {
+			body := page.body
+			listing.Items = append(listing.Items, sourceItem{
+				Key:      page.url.String(),
+				Name:     page.title,
+				FileType: "html",
+				Ext:      ".html",
+				Hash:     contentHash([]byte(extractTextFromHTML(body))),
+				read:     func() ([]byte, error) { return body, nil },
+			})
+		}
+		if t.depth < c.cfg.MaxDepth {
+			for _, link := range page.links {
+				enqueue(link, t.depth+1)
+			}
+		}
+	}
+	return listing, nil
+}
+
+func (c *websiteSourceConnector) included(u string) bool {
+	if len(c.include) == 0 {
+		return true
+	}
+	for _, re := range c.include {
+		if re.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *websiteSourceConnector) excluded(u string) bool {
+	for _, re := range c.exclude {
+		if re.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// errPageGone 页面已不存在（404 / 410）
+var errPageGone = errors.New("页面不存在")
+
+// crawledPage 抓取到的 HTML 页面
+type crawledPage struct {
+	url   *url.URL // 跟随重定向后的地址
+	title string
+	body  []byte
+	links []*url.URL
+}
+
+// get 发送 GET 请求，请求之间保持配置的间隔
+func (c *websiteSourceConnector) get(ctx context.Context, u string) (*http.Response, error) {
+	if wait := time.Duration(c.cfg.RequestDelayMs)*time.Millisecond - time.Since(c.lastReq); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	c.lastReq = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", sourceCrawlerUA)
+	return c.client.Do(req)
+}
+
+// fetchPage 抓取页面；非 HTML 内容返回 nil
+func (c *websiteSourceConnector) fetchPage(ctx context.Context, u *url.URL) (*crawledPage, error) {
+	resp, err := c.get(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, errPageGone
+	case resp.StatusCode != http.StatusOK:
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+	ct := strings.ToLower(resp.Header.Get("Content-Type"))
+	if ct != "" && !strings.Contains(ct, "text/html") && !strings.Contains(ct, "application/xhtml") {
+		return nil, nil
+	}
+	final, err := normalizeCrawlURL(resp.Request.URL.String())
+	if err != nil || !c.hosts[final.Host] {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.settings.MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > c.settings.MaxFileSize {
+		return nil, fmt.Errorf("页面大小超过上限 %d", c.settings.MaxFileSize)
+	}
+
+	title, hrefs := parseHTMLPage(body)
+	page := &crawledPage{url: final, title: title, body: body}
+	if page.title == "" {
+		page.title = final.String()
+	}
+	for _, href := range hrefs {
+		ref, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		if link, err := normalizeCrawlURL(final.ResolveReference(ref).String()); err == nil {
+			page.links = append(page.links, link)
+		}
+	}
+	return page, nil
+}
+
+// parseHTMLPage 提取页面标题与链接
+func parseHTMLPage(body []byte) (title string, links []string) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return "", nil
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				if title == "" && n.FirstChild != nil {
+					title = strings.TrimSpace(n.FirstChild.Data)
+				}
+			case "a":
+				for _, attr := range n.Attr {
+					if attr.Key == "href" && attr.Val != "" {
+						links = append(links, attr.Val)
+					}
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return title, links
+}
+
+// normalizeCrawlURL 规范化 URL：仅 http(s)，去除 fragment 与默认端口，空路径补 /
+func normalizeCrawlURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("无效的 URL: %s", raw)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("仅支持 http / https 地址: %s", raw)
+	}
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && strings.HasSuffix(u.Host, ":80")) || (u.Scheme == "https" && strings.HasSuffix(u.Host, ":443")) {
+		u.Host = u.Host[:strings.LastIndex(u.Host, ":")]
+	}
+	u.Fragment, u.RawFragment, u.User = "", "", nil
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
+
+// -----------------------------------------------
+// sitemap
+// -----------------------------------------------
+
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// fetchSitemap 读取 sitemap 中的页面地址，支持一层 sitemapindex 嵌套
+func (c *websiteSourceConnector) fetchSitemap(ctx context.Context, raw string, level int) ([]string, error) {
+	resp, err := c.get(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+
+	var doc sitemapDoc
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, c.settings.MaxFileSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("sitemap 解析失败: %w", err)
+	}
+	var locs []string
+	for _, u := range doc.URLs {
+		locs = append(locs, strings.TrimSpace(u.Loc))
+	}
+	if level == 0 {
+		for _, s := range doc.Sitemaps {
+			nested, err := c.fetchSitemap(ctx, strings.TrimSpace(s.Loc), level+1)
+			if err != nil {
+				return nil, err
+			}
+			locs = append(locs, nested...)
+		}
+	}
+	return locs, nil
+}
+
+// -----------------------------------------------
+// robots.txt
+// -----------------------------------------------
+
+// robotsRules 适用于本抓取器的 robots.txt 规则
+type robotsRules struct {
+	rules []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsFor 获取站点的 robots.txt 规则，读取失败或不存在时允许抓取
+func (c *websiteSourceConnector) robotsFor(ctx context.Context, u *url.URL) *robotsRules {
+	key := u.Scheme + "://" + u.Host
+	if r, ok := c.robots[key]; ok {
+		return r
+	}
+	rules := &robotsRules{}
+	if resp, err := c.get(ctx, key+"/robots.txt"); err == nil {
+		if resp.StatusCode == http.StatusOK {
+			data, _ := io.ReadAll(io.LimitReader(resp.Body, 512<<10))
+			rules = parseRobots(data, sourceCrawlerUA)
+		}
+		resp.Body.Close()
+	}
+	c.robots[key] = rules
+	return rules
+}
+
+// parseRobots 解析 robots.txt：优先使用名称匹配 agent 的分组，没有时使用 * 分组
+func parseRobots(data []byte, agent string) *robotsRules {
+	token := strings.ToLower(strings.SplitN(agent, "/", 2)[0])
+
+	type group struct {
+		agents []string
+		rules  []robotsRule
+	}
+	var groups []*group
+	var cur *group
+	inAgents := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				cur = &group{}
+				groups = append(groups, cur)
+				inAgents = true
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if cur == nil || value == "" {
+				continue
+			}
+			cur.rules = append(cur.rules, robotsRule{allow: key == "allow", pattern: value, re: robotsPattern(value)})
+		}
+	}
+
+	var specific, wildcard []robotsRule
+	for _, g := range groups {
+		for _, a := range g.agents {
+			switch {
+			case a == "*":
+				wildcard = append(wildcard, g.rules...)
+			case a != "" && strings.Contains(token, a):
+				specific = append(specific, g.rules...)
+			}
+		}
+	}
+	if len(specific) > 0 {
+		return &robotsRules{rules: specific}
+	}
+	return &robotsRules{rules: wildcard}
+}
+
+// robotsPattern 将 robots 路径规则转换为正则：* 匹配任意字符，结尾 $ 表示完整匹配
+func robotsPattern(p string) *regexp.Regexp {
+	anchored := strings.HasSuffix(p, "$")
+	p = strings.TrimSuffix(p, "$")
+	parts := strings.Split(p, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// Allowed 按最长匹配规则判断路径是否允许抓取，长度相同时 Allow 优先
+func (r *robotsRules) Allowed(p string) bool {
+	if r == nil {
+		return true
+	}
+	best, allowed := -1, true
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(p) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best, allowed = len(rule.pattern), rule.allow
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,631 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"yqhp/gulu/internal/svc"
+)
+
+// -----------------------------------------------
+// 内置向量库（进程内暴力检索 + 追加日志持久化）
+// 面向几百到几千篇文档的小型部署，无需额外部署 Qdrant。
+// 每个集合对应一个 <collection>.vlog 文件，记录格式：
+//   [4 字节长度][4 字节 CRC32][记录体]
+// 启动时回放日志重建内存索引，尾部残缺记录（进程崩溃时写了一半）会被截断；
+// 失效记录过多时重写日志压缩。
+// -----------------------------------------------
+
+const (
+	embeddedOpSchema         byte = 1 // 字段维度
+	embeddedOpUpsert         byte = 2 // 写入点
+	embeddedOpDeletePoint    byte = 3 // 删除点
+	embeddedOpDeleteDocument byte = 4 // 删除文档的全部点
+	embeddedOpSetPayload     byte = 5 // 覆盖文档全部点的附加数据字段
+
+	embeddedLogExt = ".vlog"
+	// embeddedCompactMinDead 失效记录数超过该值且超过存活点数时触发压缩
+	embeddedCompactMinDead = 1000
+	// embeddedMaxRecordSize 单条记录上限，超过视为记录头损坏
+	embeddedMaxRecordSize = 64 << 20
+)
+
+var (
+	embeddedStore     *embeddedVectorStore
+	embeddedStoreOnce sync.Once
+	embeddedStoreErr  error
+)
+
+func getEmbeddedVectorStore() (*embeddedVectorStore, error) {
+	embeddedStoreOnce.Do(func() {
+		dir := ""
+		if svc.Ctx != nil && svc.Ctx.Config != nil {
+			dir = svc.Ctx.Config.VectorStore.Embedded.Path
+			if dir == "" && svc.Ctx.Config.Storage.Local.BasePath != "" {
+				dir = filepath.Join(svc.Ctx.Config.Storage.Local.BasePath, "vectors")
+			}
+		}
+		if dir == "" {
+			dir = "./data/knowledge/vectors"
+		}
+		embeddedStore, embeddedStoreErr = newEmbeddedVectorStore(dir)
+	})
+	return embeddedStore, embeddedStoreErr
+}
+
+// embeddedVectorStore 内置向量库后端
+type embeddedVectorStore struct {
+	dir         string
+	mu          sync.Mutex
+	collections map[string]*embeddedCollection
+}
+
+func newEmbeddedVectorStore(dir string) (*embeddedVectorStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建内置向量库目录失败: %w", err)
+	}
+	return &embeddedVectorStore{dir: dir, collections: make(map[string]*embeddedCollection)}, nil
+}
+
+// embeddedCollection 内置向量库的一个集合
+type embeddedCollection struct {
+	mu     sync.RWMutex
+	path   string
+	file   *os.File
+	dims   map[string]int
+	points map[uint64]*embeddedPoint
+	dead   int // 日志中已失效的记录数
+}
+
+// embeddedPoint 向量点，向量写入时归一化，检索时点积即余弦相似度
+type embeddedPoint struct {
+	field   string
+	vector  []float32
+	payload embeddedPayload
+}
+
+// embeddedPayload 点的附加数据（日志中以 JSON 存储）
+type embeddedPayload struct {
+	ID          uint64                 `json:"id,omitempty"`
+	Field       string                 `json:"field,omitempty"`
+	DocumentID  int64                  `json:"document_id,omitempty"`
+	ChunkIndex  int                    `json:"chunk_index,omitempty"`
+	Content     string                 `json:"content,omitempty"`
+	ContentType string                 `json:"content_type,omitempty"`
+	ImagePath   string                 `json:"image_path,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Dims        map[string]int         `json:"dims,omitempty"`
+}
+
+func (s *embeddedVectorStore) Name() string { return VectorStoreEmbedded }
+
+// collection 打开集合；create 为 false 且集合不存在时返回 nil
+func (s *embeddedVectorStore) collection(name string, create bool) (*embeddedCollection, error) {
+	if err := validateCollectionName(name); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.collections[name]; ok {
+		return c, nil
+	}
+	path := filepath.Join(s.dir, name+embeddedLogExt)
+	if _, err := os.Stat(path); os.IsNotExist(err) && !create {
+		return nil, nil
+	}
+	c, err := openEmbeddedCollection(path)
+	if err != nil {
+		return nil, err
+	}
+	s.collections[name] = c
+	return c, nil
+}
+
+func openEmbeddedCollection(path string) (*embeddedCollection, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开内置向量库文件失败: %w", err)
+	}
+	c := &embeddedCollection{
+		path:   path,
+		file:   f,
+		dims:   make(map[string]int),
+		points: make(map[uint64]*embeddedPoint),
+	}
+
+	valid, err := c.replay(bufio.NewReader(f))
+	if err != nil {
+		log.Printf("[WARN] 内置向量库 %s 在偏移 %d 处记录损坏，已截断: %v", filepath.Base(path), valid, err)
+		if err := f.Truncate(valid); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("截断内置向量库文件失败: %w", err)
+		}
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if c.dead > embeddedCompactMinDead && c.dead > len(c.points) {
+		if err := c.compact(); err != nil {
+			log.Printf("[WARN] 内置向量库 %s 压缩失败: %v", filepath.Base(path), err)
+		}
+	}
+	return c, nil
+}
+
+// replay 回放日志，返回最后一条完整记录的结束偏移
+func (c *embeddedCollection) replay(r io.Reader) (int64, error) {
+	var offset int64
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			return offset, err
+		}
+		size := binary.LittleEndian.Uint32(header[:4])
+		if size > embeddedMaxRecordSize {
+			return offset, errors.New("记录长度异常")
+		}
+		body := make([]byte, size)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return offset, err
+		}
+		if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(header[4:]) {
+			return offset, errors.New("CRC 校验失败")
+		}
+		if err := c.apply(body); err != nil {
+			return offset, err
+		}
+		offset += int64(len(header)) + int64(size)
+	}
+}
+
+// apply 将一条记录应用到内存索引
+func (c *embeddedCollection) apply(body []byte) error {
+	if len(body) == 0 {
+		return errors.New("空记录")
+	}
+	op, rest := body[0], body[1:]
+	n, read := binary.Uvarint(rest)
+	if read <= 0 || uint64(len(rest)-read) < n {
+		return errors.New("记录头损坏")
+	}
+	var payload embeddedPayload
+	if err := json.Unmarshal(rest[read:read+int(n)], &payload); err != nil {
+		return err
+	}
+	rest = rest[read+int(n):]
+
+	switch op {
+	case embeddedOpSchema:
+		c.dead += len(c.points) + 1
+		c.dims = payload.Dims
+		c.points = make(map[uint64]*embeddedPoint)
+	case embeddedOpUpsert:
+		if len(rest)%4 != 0 {
+			return errors.New("向量数据损坏")
+		}
+		vector := make([]float32, len(rest)/4)
+		for i := range vector {
+			vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(rest[i*4:]))
+		}
+		id, field := payload.ID, payload.Field
+		if _, ok := c.points[id]; ok {
+			c.dead++
+		}
+		payload.ID, payload.Field = 0, ""
+		c.points[id] = &embeddedPoint{field: field, vector: vector, payload: payload}
+	case embeddedOpDeletePoint:
+		if _, ok := c.points[payload.ID]; ok {
+			delete(c.points, payload.ID)
+			c.dead++
+		}
+		c.dead++
+	case embeddedOpDeleteDocument:
+		for id, p := range c.points {
+			if p.payload.DocumentID == payload.DocumentID {
+				delete(c.points, id)
+				c.dead++
+			}
+		}
+		c.dead++
+	case embeddedOpSetPayload:
+		for _, p := range c.points {
+			if p.payload.DocumentID != payload.DocumentID {
+				continue
+			}
+			// 复制后修改，检索时可能仍持有旧的元数据
+			metadata := make(map[string]interface{}, len(p.payload.Metadata)+len(payload.Metadata))
+			for k, v := range p.payload.Metadata {
+				metadata[k] = v
+			}
+			for k, v := range payload.Metadata {
+				metadata[k] = v
+			}
+			p.payload.Metadata = metadata
+		}
+		c.dead++
+	default:
+		return fmt.Errorf("未知记录类型 %d", op)
+	}
+	return nil
+}
+
+// encodeEmbeddedRecord 编码一条带长度与 CRC 前缀的日志记录
+func encodeEmbeddedRecord(buf *bytes.Buffer, op byte, payload *embeddedPayload, vector []float32) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	body := make([]byte, 0, 1+binary.MaxVarintLen64+len(data)+len(vector)*4)
+	body = append(body, op)
+	body = binary.AppendUvarint(body, uint64(len(data)))
+	body = append(body, data...)
+	for _, v := range vector {
+		body = binary.LittleEndian.AppendUint32(body, math.Float32bits(v))
+	}
+	var header [8]byte
+	binary.LittleEndian.PutUint32(header[:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(header[4:], crc32.ChecksumIEEE(body))
+	buf.Write(header[:])
+	buf.Write(body)
+	return nil
+}
+
+// write 追加记录并落盘，成功后再应用到内存索引（调用方持有写锁）
+func (c *embeddedCollection) write(buf *bytes.Buffer) error {
+	data := buf.Bytes()
+	if _, err := c.file.Write(data); err != nil {
+		return fmt.Errorf("写入内置向量库失败: %w", err)
+	}
+	if err := c.file.Sync(); err != nil {
+		return fmt.Errorf("写入内置向量库失败: %w", err)
+	}
+	for len(data) > 0 {
+		size := binary.LittleEndian.Uint32(data[:4])
+		if err := c.apply(data[8 : 8+size]); err != nil {
+			return err
+		}
+		data = data[8+size:]
+	}
+	if c.dead > embeddedCompactMinDead && c.dead > len(c.points) {
+		if err := c.compact(); err != nil {
+			log.Printf("[WARN] 内置向量库 %s 压缩失败: %v", filepath.Base(c.path), err)
+		}
+	}
+	return nil
+}
+
+// compact 仅保留当前存活的点重写日志（调用方持有写锁）
+func (c *embeddedCollection) compact() error {
+	var buf bytes.Buffer
+	if err := encodeEmbeddedRecord(&buf, embeddedOpSchema, &embeddedPayload{Dims: c.dims}, nil); err != nil {
+		return err
+	}
+	for _, id := range c.sortedIDs() {
+		p := c.points[id]
+		payload := p.payload
+		payload.ID, payload.Field = id, p.field
+		if err := encodeEmbeddedRecord(&buf, embeddedOpUpsert, &payload, p.vector); err != nil {
+			return err
+		}
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(tmp, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return err
+	}
+	c.file.Close()
+	c.file = f
+	c.dead = 0
+	return nil
+}
+
+func (c *embeddedCollection) sortedIDs() []uint64 {
+	ids := make([]uint64, 0, len(c.points))
+	for id := range c.points {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (s *embeddedVectorStore) EnsureCollection(_ context.Context, collection string, cfg CollectionVectorConfig) error {
+	c, err := s.collection(collection, true)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dims := map[string]int{}
+	for field, dim := range c.dims {
+		dims[field] = dim
+	}
+	changed, recreate := false, false
+	for field, dim := range map[string]int{"text": cfg.TextDimension, "image": cfg.ImageDimension} {
+		if dim <= 0 || dims[field] == dim {
+			continue
+		}
+		if dims[field] > 0 {
+			recreate = true
+		}
+		dims[field] = dim
+		changed = true
+	}
+	if !changed && len(c.dims) > 0 {
+		return nil
+	}
+	if recreate {
+		log.Printf("[INFO] 内置向量库集合 %s 维度不匹配，清空重建 (text=%d, image=%d)",
+			collection, dims["text"], dims["image"])
+		// 重建时丢弃旧维度的全部字段，与 Qdrant 的删除重建保持一致
+		dims = map[string]int{}
+		if cfg.TextDimension > 0 {
+			dims["text"] = cfg.TextDimension
+		}
+		if cfg.ImageDimension > 0 {
+			dims["image"] = cfg.ImageDimension
+		}
+	} else if len(c.points) > 0 {
+		// 仅新增字段：保留已有点，重写日志写入新维度
+		c.dims = dims
+		return c.compact()
+	}
+
+	var buf bytes.Buffer
+	if err := encodeEmbeddedRecord(&buf, embeddedOpSchema, &embeddedPayload{Dims: dims}, nil); err != nil {
+		return err
+	}
+	return c.write(&buf)
+}
+
+func (s *embeddedVectorStore) DeleteCollection(_ context.Context, collection string) error {
+	if err := validateCollectionName(collection); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.collections[collection]; ok {
+		c.mu.Lock()
+		c.file.Close()
+		c.mu.Unlock()
+		delete(s.collections, collection)
+	}
+	err := os.Remove(filepath.Join(s.dir, collection+embeddedLogExt))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除内置向量库集合失败: %w", err)
+	}
+	log.Printf("[INFO] 内置向量库集合 %s 已删除", collection)
+	return nil
+}
+
+func (s *embeddedVectorStore) Upsert(_ context.Context, collection, field string, points []VectorPoint) error {
+	c, err := s.collection(collection, false)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return fmt.Errorf("内置向量库集合 %s 不存在", collection)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dim, ok := c.dims[field]
+	if !ok {
+		return fmt.Errorf("集合 %s 没有向量字段 %s", collection, field)
+	}
+	var buf bytes.Buffer
+	for _, p := range points {
+		if len(p.Vector) != dim {
+			return fmt.Errorf("向量维度不匹配: 字段 %s 为 %d 维，写入 %d 维", field, dim, len(p.Vector))
+		}
+		payload := &embeddedPayload{
+			ID:          vectorPointID(p.DocumentID, p.ChunkIndex),
+			Field:       field,
+			DocumentID:  p.DocumentID,
+			ChunkIndex:  p.ChunkIndex,
+			Content:     p.Content,
+			ContentType: p.ContentType,
+			ImagePath:   p.ImagePath,
+			Metadata:    p.Metadata,
+		}
+		if err := encodeEmbeddedRecord(&buf, embeddedOpUpsert, payload, normalizeVector(p.Vector)); err != nil {
+			return err
+		}
+	}
+	return c.write(&buf)
+}
+
+func (s *embeddedVectorStore) Search(_ context.Context, collection, field string, vector []float32, topK int, scoreThreshold float32, filter *VectorFilter) ([]SearchHit, error) {
+	c, err := s.collection(collection, false)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("内置向量库集合 %s 不存在", collection)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if dim := c.dims[field]; dim != len(vector) {
+		return nil, fmt.Errorf("查询向量维度不匹配: 字段 %s 为 %d 维，查询 %d 维", field, dim, len(vector))
+	}
+	query := normalizeVector(vector)
+
+	type scored struct {
+		id    uint64
+		score float32
+	}
+	var matches []scored
+	for id, p := range c.points {
+		if p.field != field || !filter.matchPayload(p.payload.Metadata) {
+			continue
+		}
+		var dot float32
+		for i, v := range p.vector {
+			dot += v * query[i]
+		}
+		if dot >= scoreThreshold {
+			matches = append(matches, scored{id: id, score: dot})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].id < matches[j].id
+	})
+	if topK > 0 && len(matches) > topK {
+		matches = matches[:topK]
+	}
+
+	hits := make([]SearchHit, 0, len(matches))
+	for _, m := range matches {
+		p := c.points[m.id]
+		hits = append(hits, SearchHit{
+			ID:          fmt.Sprintf("%d", m.id),
+			Score:       float64(m.score),
+			Content:     p.payload.Content,
+			ContentType: p.payload.ContentType,
+			ImagePath:   p.payload.ImagePath,
+			VectorField: p.field,
+			DocumentID:  p.payload.DocumentID,
+			ChunkIndex:  p.payload.ChunkIndex,
+			Metadata:    hitMetadata(p.payload.Metadata),
+		})
+	}
+	return hits, nil
+}
+
+func (s *embeddedVectorStore) DeleteDocument(_ context.Context, collection string, documentID int64) error {
+	return s.appendRecord(collection, embeddedOpDeleteDocument, &embeddedPayload{DocumentID: documentID})
+}
+
+func (s *embeddedVectorStore) SetDocumentPayload(_ context.Context, collection string, documentID int64, payload map[string]interface{}) error {
+	return s.appendRecord(collection, embeddedOpSetPayload, &embeddedPayload{DocumentID: documentID, Metadata: payload})
+}
+
+func (s *embeddedVectorStore) DeletePoint(_ context.Context, collection string, documentID int64, chunkIndex int) error {
+	return s.appendRecord(collection, embeddedOpDeletePoint, &embeddedPayload{ID: vectorPointID(documentID, chunkIndex)})
+}
+
+// appendRecord 追加不带向量的记录（删除 / 更新附加数据），集合不存在时忽略
+func (s *embeddedVectorStore) appendRecord(collection string, op byte, payload *embeddedPayload) error {
+	c, err := s.collection(collection, false)
+	if err != nil || c == nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := encodeEmbeddedRecord(&buf, op, payload, nil); err != nil {
+		return err
+	}
+	return c.write(&buf)
+}
+
+func (s *embeddedVectorStore) Export(_ context.Context, collection string, batchSize int, fn func(field string, points []VectorPoint) error) error {
+	c, err := s.collection(collection, false)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return fmt.Errorf("内置向量库集合 %s 不存在", collection)
+	}
+
+	// 复制快照后释放锁，避免迁移写入目标后端期间阻塞检索
+	c.mu.RLock()
+	byField := make(map[string][]VectorPoint)
+	for _, id := range c.sortedIDs() {
+		p := c.points[id]
+		byField[p.field] = append(byField[p.field], VectorPoint{
+			ID:          fmt.Sprintf("%d", id),
+			Vector:      p.vector,
+			DocumentID:  p.payload.DocumentID,
+			ChunkIndex:  p.payload.ChunkIndex,
+			Content:     p.payload.Content,
+			ContentType: p.payload.ContentType,
+			ImagePath:   p.payload.ImagePath,
+			Metadata:    p.payload.Metadata,
+		})
+	}
+	c.mu.RUnlock()
+
+	fields := make([]string, 0, len(byField))
+	for field := range byField {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		points := byField[field]
+		for i := 0; i < len(points); i += batchSize {
+			end := i + batchSize
+			if end > len(points) {
+				end = len(points)
+			}
+			if err := fn(field, points[i:end]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *embeddedVectorStore) Diagnose(_ context.Context, collection string) *VectorCollectionDiag {
+	diag := &VectorCollectionDiag{Store: VectorStoreEmbedded, CollectionName: collection}
+	c, err := s.collection(collection, false)
+	if err != nil {
+		diag.Error = err.Error()
+		return diag
+	}
+	if c == nil {
+		diag.Error = "集合不存在，文档可能尚未索引"
+		return diag
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	diag.Exists = true
+	diag.PointCount = uint64(len(c.points))
+	diag.VectorCount = diag.PointCount
+	diag.VectorFields = make(map[string]uint64, len(c.dims))
+	for field, dim := range c.dims {
+		diag.VectorFields[field] = uint64(dim)
+	}
+	return diag
+}
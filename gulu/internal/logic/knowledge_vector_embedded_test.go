@@ -0,0 +1,200 @@
+package logic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func embeddedTestPoints(docID int64, vectors ...[]float32) []VectorPoint {
+	points := make([]VectorPoint, len(vectors))
+	for i, v := range vectors {
+		points[i] = VectorPoint{
+			Vector:      v,
+			DocumentID:  docID,
+			ChunkIndex:  i,
+			Content:     string(rune('a' + i)),
+			ContentType: "text",
+			Metadata:    map[string]interface{}{"document_name": "doc.md", "total_chunks": len(vectors)},
+		}
+	}
+	return points
+}
+
+// TestEmbeddedVectorStore_SearchAndPersist 内置向量库检索结果正确，且重新打开后从日志恢复
+func TestEmbeddedVectorStore_SearchAndPersist(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := newEmbeddedVectorStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.EnsureCollection(ctx, "kb_1", CollectionVectorConfig{TextDimension: 3}); err != nil {
+		t.Fatal(err)
+	}
+	points := embeddedTestPoints(1, []float32{1, 0, 0}, []float32{0.8, 0.6, 0}, []float32{0, 0, 1})
+	if err := store.Upsert(ctx, "kb_1", "text", points); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Upsert(ctx, "kb_1", "text", []VectorPoint{{Vector: []float32{1, 1}, DocumentID: 1}}); err == nil {
+		t.Fatal("expected dimension mismatch error")
+	}
+
+	hits, err := store.Search(ctx, "kb_1", "text", []float32{2, 0, 0}, 2, 0.5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].Content != "a" || hits[1].Content != "b" {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	if hits[0].Score < 0.999 || hits[1].Score < 0.79 || hits[1].Score > 0.81 {
+		t.Fatalf("unexpected scores: %v %v", hits[0].Score, hits[1].Score)
+	}
+	if hits[0].Metadata["document_name"] != "doc.md" || len(hits[0].Metadata) != 1 {
+		t.Fatalf("unexpected metadata: %v", hits[0].Metadata)
+	}
+
+	if err := store.DeletePoint(ctx, "kb_1", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Upsert(ctx, "kb_1", "text", embeddedTestPoints(2, []float32{1, 0, 0})); err != nil {
+		t.Fatal(err)
+	}
+
+	// 追加一段残缺记录，模拟写入中途崩溃
+	f, err := os.OpenFile(filepath.Join(dir, "kb_1"+embeddedLogExt), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0xff, 0x00, 0x00, 0x00, 0x01})
+	f.Close()
+
+	reopened, err := newEmbeddedVectorStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diag := reopened.Diagnose(ctx, "kb_1")
+	if !diag.Exists || diag.PointCount != 3 || diag.VectorFields["text"] != 3 {
+		t.Fatalf("unexpected diag after reopen: %+v", diag)
+	}
+	hits, err = reopened.Search(ctx, "kb_1", "text", []float32{1, 0, 0}, 5, 0.9, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].DocumentID != 2 {
+		t.Fatalf("unexpected hits after reopen: %+v", hits)
+	}
+
+	if err := reopened.DeleteDocument(ctx, "kb_1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if diag := reopened.Diagnose(ctx, "kb_1"); diag.PointCount != 1 {
+		t.Fatalf("expected 1 point after document delete, got %d", diag.PointCount)
+	}
+}
+
+// TestEmbeddedVectorStore_EnsureCollection 新增字段保留已有数据，维度变化时清空重建
+func TestEmbeddedVectorStore_EnsureCollection(t *testing.T) {
+	ctx := context.Background()
+	store, err := newEmbeddedVectorStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.EnsureCollection(ctx, "kb_2", CollectionVectorConfig{TextDimension: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Upsert(ctx, "kb_2", "text", embeddedTestPoints(1, []float32{1, 0})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.EnsureCollection(ctx, "kb_2", CollectionVectorConfig{TextDimension: 2, ImageDimension: 4}); err != nil {
+		t.Fatal(err)
+	}
+	diag := store.Diagnose(ctx, "kb_2")
+	if diag.PointCount != 1 || diag.VectorFields["image"] != 4 {
+		t.Fatalf("adding image field should keep points: %+v", diag)
+	}
+
+	if err := store.EnsureCollection(ctx, "kb_2", CollectionVectorConfig{TextDimension: 3}); err != nil {
+		t.Fatal(err)
+	}
+	diag = store.Diagnose(ctx, "kb_2")
+	if diag.PointCount != 0 || diag.VectorFields["text"] != 3 {
+		t.Fatalf("dimension change should recreate collection: %+v", diag)
+	}
+
+	if err := store.EnsureCollection(ctx, "../kb", CollectionVectorConfig{TextDimension: 3}); err == nil {
+		t.Fatal("expected invalid collection name error")
+	}
+}
+
+// TestEmbeddedVectorStore_ExportCompact 导出按字段分批，压缩后数据不变
+func TestEmbeddedVectorStore_ExportCompact(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := newEmbeddedVectorStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnsureCollection(ctx, "kb_3", CollectionVectorConfig{TextDimension: 2, ImageDimension: 2}); err != nil {
+		t.Fatal(err)
+	}
+	text := make([][]float32, 5)
+	for i := range text {
+		text[i] = []float32{1, float32(i)}
+	}
+	if err := store.Upsert(ctx, "kb_3", "text", embeddedTestPoints(1, text...)); err != nil {
+		t.Fatal(err)
+	}
+	image := embeddedTestPoints(1, []float32{0, 1})
+	image[0].ChunkIndex, image[0].ContentType, image[0].ImagePath = 5, "image", "kb_3/images/1_0.png"
+	if err := store.Upsert(ctx, "kb_3", "image", image); err != nil {
+		t.Fatal(err)
+	}
+
+	batches := map[string][]int{}
+	err = store.Export(ctx, "kb_3", 2, func(field string, points []VectorPoint) error {
+		for _, p := range points {
+			if p.ContentType != field {
+				t.Errorf("point %s exported in %s batch", p.ContentType, field)
+			}
+		}
+		batches[field] = append(batches[field], len(points))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches["text"]) != 3 || batches["text"][2] != 1 || len(batches["image"]) != 1 {
+		t.Fatalf("unexpected export batches: %v", batches)
+	}
+
+	c, err := store.collection("kb_3", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	err = c.compact()
+	c.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := newEmbeddedVectorStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, err := reopened.Search(ctx, "kb_3", "image", []float32{0, 3}, 1, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].ImagePath != "kb_3/images/1_0.png" || hits[0].VectorField != "image" {
+		t.Fatalf("unexpected image hits after compact: %+v", hits)
+	}
+	if diag := reopened.Diagnose(ctx, "kb_3"); diag.PointCount != 6 {
+		t.Fatalf("expected 6 points after compact, got %d", diag.PointCount)
+	}
+}
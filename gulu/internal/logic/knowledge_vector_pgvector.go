@@ -0,0 +1,655 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"yqhp/gulu/internal/svc"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// -----------------------------------------------
+// pgvector 向量库（PostgreSQL + pgvector 扩展）
+// 每个集合一张表，text / image 字段分别存放在 text_vec / image_vec 列，
+// 使用 HNSW 余弦索引（pgvector 的 HNSW 索引最多支持 2000 维，超过时退化为顺序扫描）。
+// -----------------------------------------------
+
+// pgvectorMaxIndexDimension pgvector HNSW 索引支持的最大维度
+const pgvectorMaxIndexDimension = 2000
+
+var (
+	pgvectorInstance *pgvectorStore
+	pgvectorMu       sync.Mutex
+)
+
+// getPgvectorStore 获取 pgvector 后端（连接失败时下次调用重试）
+func getPgvectorStore() (*pgvectorStore, error) {
+	pgvectorMu.Lock()
+	defer pgvectorMu.Unlock()
+	if pgvectorInstance != nil {
+		return pgvectorInstance, nil
+	}
+
+	prefix := "vec_"
+	var db *gorm.DB
+	if svc.Ctx != nil && svc.Ctx.Config != nil {
+		cfg := svc.Ctx.Config.VectorStore.Pgvector
+		if cfg.TablePrefix != "" {
+			prefix = cfg.TablePrefix
+		}
+		if cfg.DSN != "" {
+			conn, err := gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			if err != nil {
+				return nil, fmt.Errorf("pgvector 连接失败: %w", err)
+			}
+			db = conn
+		}
+	}
+	if db == nil {
+		// 未配置 DSN 时复用 postgres 主库
+		if svc.Ctx == nil || svc.Ctx.DB == nil || svc.Ctx.DB.Dialector.Name() != "postgres" {
+			return nil, errors.New("未配置 pgvector：请设置 vector_store.pgvector.dsn，或使用 postgres 作为主库")
+		}
+		db = svc.Ctx.DB
+	}
+	if !collectionNamePattern.MatchString(prefix) {
+		return nil, fmt.Errorf("非法的 pgvector 表名前缀: %q", prefix)
+	}
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+		return nil, fmt.Errorf("启用 pgvector 扩展失败: %w", err)
+	}
+
+	pgvectorInstance = &pgvectorStore{db: db, prefix: prefix}
+	log.Printf("[INFO] pgvector 向量库已连接 (表前缀: %s)", prefix)
+	return pgvectorInstance, nil
+}
+
+// pgvectorStore pgvector 向量库后端
+type pgvectorStore struct {
+	db     *gorm.DB
+	prefix string
+}
+
+// pgvectorColumns 向量字段对应的列
+var pgvectorColumns = map[string]string{"text": "text_vec", "image": "image_vec"}
+
+func (s *pgvectorStore) Name() string { return VectorStorePgvector }
+
+func (s *pgvectorStore) table(collection string) (string, error) {
+	if err := validateCollectionName(collection); err != nil {
+		return "", err
+	}
+	return s.prefix + collection, nil
+}
+
+func pgvectorColumn(field string) (string, error) {
+	column, ok := pgvectorColumns[field]
+	if !ok {
+		return "", fmt.Errorf("不支持的向量字段: %s", field)
+	}
+	return column, nil
+}
+
+var pgvectorTypePattern = regexp.MustCompile(`^vector(?:\((\d+)\))?$`)
+
+// dimensions 查询表中各向量列的维度；表不存在时 exists 为 false
+func (s *pgvectorStore) dimensions(ctx context.Context, table string) (dims map[string]int, exists bool, err error) {
+	var count int64
+	err = s.db.WithContext(ctx).Raw(
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?", table,
+	).Scan(&count).Error
+	if err != nil || count == 0 {
+		return nil, false, err
+	}
+
+	var cols []struct {
+		Name string
+		Type string
+	}
+	err = s.db.WithContext(ctx).Raw(`SELECT a.attname AS name, format_type(a.atttypid, a.atttypmod) AS type
+		FROM pg_attribute a
+		WHERE a.attrelid = to_regclass(?) AND a.attnum > 0 AND NOT a.attisdropped AND a.attname IN ('text_vec', 'image_vec')`, table,
+	).Scan(&cols).Error
+	if err != nil {
+		return nil, true, err
+	}
+	dims = make(map[string]int)
+	for field, column := range pgvectorColumns {
+		for _, col := range cols {
+			if col.Name != column {
+				continue
+			}
+			dims[field] = 0
+			if m := pgvectorTypePattern.FindStringSubmatch(col.Type); m != nil && m[1] != "" {
+				dims[field], _ = strconv.Atoi(m[1])
+			}
+		}
+	}
+	return dims, true, nil
+}
+
+func pgvectorColumnType(dim int) string {
+	if dim <= 0 {
+		return "vector"
+	}
+	return fmt.Sprintf("vector(%d)", dim)
+}
+
+// createVectorIndex 为向量列创建 HNSW 余弦索引
+func (s *pgvectorStore) createVectorIndex(ctx context.Context, table, column string, dim int) error {
+	if dim <= 0 || dim > pgvectorMaxIndexDimension {
+		log.Printf("[WARN] pgvector: %s.%s 维度 %d 不支持 HNSW 索引，检索将使用顺序扫描", table, column, dim)
+		return nil
+	}
+	return s.db.WithContext(ctx).Exec(fmt.Sprintf(
+		`CREATE INDEX IF NOT EXISTS "%s_%s_hnsw" ON "%s" USING hnsw (%s vector_cosine_ops)`, table, column, table, column,
+	)).Error
+}
+
+func (s *pgvectorStore) EnsureCollection(ctx context.Context, collection string, cfg CollectionVectorConfig) error {
+	table, err := s.table(collection)
+	if err != nil {
+		return err
+	}
+	dims, exists, err := s.dimensions(ctx, table)
+	if err != nil {
+		return fmt.Errorf("查询 pgvector 表结构失败: %w", err)
+	}
+
+	want := map[string]int{"text": cfg.TextDimension, "image": cfg.ImageDimension}
+	if exists {
+		recreate := false
+		for field, dim := range want {
+			if current, ok := dims[field]; ok && dim > 0 && current != dim {
+				recreate = true
+			}
+		}
+		if !recreate {
+			// 只补充缺失的向量列
+			for _, field := range []string{"text", "image"} {
+				if _, ok := dims[field]; ok || want[field] <= 0 {
+					continue
+				}
+				column := pgvectorColumns[field]
+				if err := s.db.WithContext(ctx).Exec(fmt.Sprintf(
+					`ALTER TABLE "%s" ADD COLUMN IF NOT EXISTS %s %s`, table, column, pgvectorColumnType(want[field]),
+				)).Error; err != nil {
+					return fmt.Errorf("添加向量列失败: %w", err)
+				}
+				if err := s.createVectorIndex(ctx, table, column, want[field]); err != nil {
+					return fmt.Errorf("创建向量索引失败: %w", err)
+				}
+			}
+			return nil
+		}
+		log.Printf("[INFO] pgvector 表 %s 维度不匹配，删除重建 (text=%d, image=%d)", table, cfg.TextDimension, cfg.ImageDimension)
+		if err := s.db.WithContext(ctx).Exec(fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, table)).Error; err != nil {
+			return fmt.Errorf("删除 pgvector 表失败: %w", err)
+		}
+	}
+
+	columns := fmt.Sprintf("text_vec %s", pgvectorColumnType(cfg.TextDimension))
+	if cfg.ImageDimension > 0 {
+		columns += fmt.Sprintf(", image_vec %s", pgvectorColumnType(cfg.ImageDimension))
+	}
+	err = s.db.WithContext(ctx).Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (
+		id BIGINT PRIMARY KEY,
+		vector_field VARCHAR(16) NOT NULL,
+		document_id BIGINT NOT NULL,
+		chunk_index INT NOT NULL,
+		content TEXT,
+		content_type VARCHAR(16),
+		image_path TEXT,
+		metadata JSONB,
+		%s
+	)`, table, columns)).Error
+	if err != nil {
+		return fmt.Errorf("创建 pgvector 表失败: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Exec(fmt.Sprintf(
+		`CREATE INDEX IF NOT EXISTS "%s_document_id" ON "%s" (document_id)`, table, table,
+	)).Error; err != nil {
+		return fmt.Errorf("创建文档索引失败: %w", err)
+	}
+	if err := s.createVectorIndex(ctx, table, "text_vec", cfg.TextDimension); err != nil {
+		return fmt.Errorf("创建向量索引失败: %w", err)
+	}
+	if cfg.ImageDimension > 0 {
+		if err := s.createVectorIndex(ctx, table, "image_vec", cfg.ImageDimension); err != nil {
+			return fmt.Errorf("创建向量索引失败: %w", err)
+		}
+	}
+	log.Printf("[INFO] pgvector 表 %s 创建成功 (text=%d, image=%d)", table, cfg.TextDimension, cfg.ImageDimension)
+	return nil
+}
+
+func (s *pgvectorStore) DeleteCollection(ctx context.Context, collection string) error {
+	table, err := s.table(collection)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Exec(fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, table)).Error; err != nil {
+		return fmt.Errorf("删除 pgvector 表失败: %w", err)
+	}
+	log.Printf("[INFO] pgvector 表 %s 已删除", table)
+	return nil
+}
+
+// formatPgvector 将向量格式化为 pgvector 文本表示 [1,2,3]
+func formatPgvector(v []float32) string {
+	var sb strings.Builder
+	sb.Grow(len(v) * 10)
+	sb.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// parsePgvector 解析 pgvector 文本表示
+func parsePgvector(s string) ([]float32, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	v := make([]float32, len(parts))
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, fmt.Errorf("向量解析失败: %w", err)
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
+}
+
+func (s *pgvectorStore) Upsert(ctx context.Context, collection, field string, points []VectorPoint) error {
+	table, err := s.table(collection)
+	if err != nil {
+		return err
+	}
+	column, err := pgvectorColumn(field)
+	if err != nil {
+		return err
+	}
+	other := pgvectorColumns["image"]
+	if field == "image" {
+		other = pgvectorColumns["text"]
+	}
+	clearOther := s.clearColumn(ctx, table, other)
+
+	batchSize := 100
+	for i := 0; i < len(points); i += batchSize {
+		end := i + batchSize
+		if end > len(points) {
+			end = len(points)
+		}
+
+		var values []string
+		var args []interface{}
+		for _, p := range points[i:end] {
+			var metadata *string
+			if len(p.Metadata) > 0 {
+				data, err := json.Marshal(p.Metadata)
+				if err != nil {
+					return err
+				}
+				m := string(data)
+				metadata = &m
+			}
+			values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?::jsonb, ?::vector)")
+			args = append(args, int64(vectorPointID(p.DocumentID, p.ChunkIndex)), field, p.DocumentID, p.ChunkIndex,
+				p.Content, p.ContentType, p.ImagePath, metadata, formatPgvector(p.Vector))
+		}
+
+		// 冲突时改写字段并清空另一向量列，保证每个点只属于一个字段
+		sql := fmt.Sprintf(`INSERT INTO "%s" (id, vector_field, document_id, chunk_index, content, content_type, image_path, metadata, %s)
+			VALUES %s
+			ON CONFLICT (id) DO UPDATE SET vector_field = EXCLUDED.vector_field, document_id = EXCLUDED.document_id,
+				chunk_index = EXCLUDED.chunk_index, content = EXCLUDED.content, content_type = EXCLUDED.content_type,
+				image_path = EXCLUDED.image_path, metadata = EXCLUDED.metadata, %s = EXCLUDED.%s%s`,
+			table, column, strings.Join(values, ", "), column, column, clearOther)
+		if err := s.db.WithContext(ctx).Exec(sql, args...).Error; err != nil {
+			return fmt.Errorf("向量写入失败 (batch %d-%d): %w", i, end, err)
+		}
+	}
+
+	log.Printf("[INFO] pgvector: 成功写入 %d 个向量到 %s.%s", len(points), table, field)
+	return nil
+}
+
+// clearColumn 表中存在 column 列时返回清空该列的 SET 子句
+func (s *pgvectorStore) clearColumn(ctx context.Context, table, column string) string {
+	var count int64
+	s.db.WithContext(ctx).Raw(
+		"SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?",
+		table, column,
+	).Scan(&count)
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %s = NULL", column)
+}
+
+// pgvectorRow 查询结果行
+type pgvectorRow struct {
+	ID          int64
+	VectorField string
+	DocumentID  int64
+	ChunkIndex  int
+	Content     *string
+	ContentType *string
+	ImagePath   *string
+	Metadata    *string
+	Score       float64
+	Vector      *string
+}
+
+func (r *pgvectorRow) metadata() map[string]interface{} {
+	if r.Metadata == nil || *r.Metadata == "" {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(*r.Metadata), &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+func (s *pgvectorStore) Search(ctx context.Context, collection, field string, vector []float32, topK int, scoreThreshold float32, filter *VectorFilter) ([]SearchHit, error) {
+	table, err := s.table(collection)
+	if err != nil {
+		return nil, err
+	}
+	column, err := pgvectorColumn(field)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先按距离取 topK（可走 HNSW 索引），再按阈值过滤
+	query := formatPgvector(vector)
+	where := fmt.Sprintf("%s IS NOT NULL", column)
+	args := []interface{}{query}
+	filterSQL, filterArgs := pgvectorFilterSQL(filter)
+	if filterSQL != "" {
+		where += " AND " + filterSQL
+		args = append(args, filterArgs...)
+	}
+	args = append(args, query, topK)
+	sql := fmt.Sprintf(`SELECT id, vector_field, document_id, chunk_index, content, content_type, image_path,
+			metadata::text AS metadata, 1 - (%s <=> ?::vector) AS score
+		FROM "%s" WHERE %s ORDER BY %s <=> ?::vector LIMIT ?`, column, table, where, column)
+
+	var rows []pgvectorRow
+	if filterSQL == "" {
+		err = s.db.WithContext(ctx).Raw(sql, args...).Scan(&rows).Error
+	} else {
+		// HNSW 索引先召回 ef_search 个候选再执行过滤条件，有过滤时放大候选数，减少结果不足 topK 的情况
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", pgvectorFilteredEfSearch(topK))).Error; err != nil {
+				return err
+			}
+			return tx.Raw(sql, args...).Scan(&rows).Error
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("向量搜索失败: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(rows))
+	for i := range rows {
+		r := &rows[i]
+		if r.Score < float64(scoreThreshold) {
+			continue
+		}
+		hits = append(hits, SearchHit{
+			ID:          fmt.Sprintf("%d", r.ID),
+			Score:       r.Score,
+			Content:     derefString(r.Content),
+			ContentType: derefString(r.ContentType),
+			ImagePath:   derefString(r.ImagePath),
+			VectorField: r.VectorField,
+			DocumentID:  r.DocumentID,
+			ChunkIndex:  r.ChunkIndex,
+			Metadata:    hitMetadata(r.metadata()),
+		})
+	}
+	return hits, nil
+}
+
+// pgvectorFilteredEfSearch 带过滤条件检索时的 HNSW 候选数
+func pgvectorFilteredEfSearch(topK int) int {
+	ef := topK * 10
+	if ef < 200 {
+		ef = 200
+	}
+	if ef > 1000 {
+		ef = 1000
+	}
+	return ef
+}
+
+// pgvectorFilterSQL 将检索过滤条件转换为 WHERE 子句（文档元数据位于 metadata 列的 meta 字段下）。
+// 每个条件在键缺失时取 FALSE，取反（must_not）时语义与内置库、Qdrant 一致
+func pgvectorFilterSQL(f *VectorFilter) (string, []interface{}) {
+	if f == nil {
+		return "", nil
+	}
+	var clauses []string
+	var args []interface{}
+	if f.ACL {
+		// 未设置访问控制（acl_restricted 缺失或为 false），或访问控制包含当前用户
+		clauses = append(clauses, `(COALESCE(metadata->'acl_restricted' <> 'true'::jsonb, TRUE) OR COALESCE(metadata->'acl_users' @> ?::jsonb, FALSE))`)
+		args = append(args, fmt.Sprintf("[%d]", f.UserID))
+	}
+	for _, mf := range f.Metadata {
+		var parts []string
+		for i := range mf.Must {
+			sql, a := pgvectorConditionSQL(&mf.Must[i])
+			parts = append(parts, sql)
+			args = append(args, a...)
+		}
+		for i := range mf.MustNot {
+			sql, a := pgvectorConditionSQL(&mf.MustNot[i])
+			parts = append(parts, "NOT "+sql)
+			args = append(args, a...)
+		}
+		if len(mf.Should) > 0 {
+			var should []string
+			for i := range mf.Should {
+				sql, a := pgvectorConditionSQL(&mf.Should[i])
+				should = append(should, sql)
+				args = append(args, a...)
+			}
+			parts = append(parts, "("+strings.Join(should, " OR ")+")")
+		}
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+// pgvectorMetaValue 元数据值表达式，键以参数传入
+const pgvectorMetaValue = "metadata->'meta'->(?::text)"
+
+func pgvectorConditionSQL(c *MetadataCondition) (string, []interface{}) {
+	switch c.Op {
+	case FilterOpExists:
+		return fmt.Sprintf("COALESCE(%s NOT IN ('null'::jsonb, '[]'::jsonb), FALSE)", pgvectorMetaValue), []interface{}{c.Key}
+	case FilterOpRange:
+		// 数组逐个元素比较，非数值元素忽略
+		var bounds []string
+		args := []interface{}{c.Key, c.Key, c.Key}
+		for _, b := range []struct {
+			op    string
+			value *float64
+		}{{">", c.Gt}, {">=", c.Gte}, {"<", c.Lt}, {"<=", c.Lte}} {
+			if b.value != nil {
+				bounds = append(bounds, fmt.Sprintf("(e #>> '{}')::float8 %s ?", b.op))
+				args = append(args, *b.value)
+			}
+		}
+		return fmt.Sprintf(`EXISTS (SELECT 1 FROM jsonb_array_elements(CASE jsonb_typeof(%[1]s) WHEN 'array' THEN %[1]s ELSE jsonb_build_array(%[1]s) END) AS e
+			WHERE jsonb_typeof(e) = 'number' AND %[2]s)`, pgvectorMetaValue, strings.Join(bounds, " AND ")), args
+	case FilterOpIn:
+		var parts []string
+		var args []interface{}
+		for _, v := range c.Values {
+			data, _ := json.Marshal(v)
+			parts = append(parts, pgvectorMetaValue+" @> ?::jsonb")
+			args = append(args, c.Key, string(data))
+		}
+		return "COALESCE(" + strings.Join(parts, " OR ") + ", FALSE)", args
+	default:
+		// jsonb 包含关系：标量相等，或数组包含该元素；数值按数值比较
+		data, _ := json.Marshal(c.Value)
+		return "COALESCE(" + pgvectorMetaValue + " @> ?::jsonb, FALSE)", []interface{}{c.Key, string(data)}
+	}
+}
+
+func (s *pgvectorStore) SetDocumentPayload(ctx context.Context, collection string, documentID int64, payload map[string]interface{}) error {
+	table, err := s.table(collection)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Exec(fmt.Sprintf(
+		`UPDATE "%s" SET metadata = COALESCE(metadata, '{}'::jsonb) || ?::jsonb WHERE document_id = ?`, table,
+	), string(data), documentID).Error; err != nil {
+		return fmt.Errorf("更新文档附加数据失败: %w", err)
+	}
+	return nil
+}
+
+func (s *pgvectorStore) DeleteDocument(ctx context.Context, collection string, documentID int64) error {
+	table, err := s.table(collection)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Exec(fmt.Sprintf(`DELETE FROM "%s" WHERE document_id = ?`, table), documentID).Error; err != nil {
+		return fmt.Errorf("删除文档向量失败: %w", err)
+	}
+	return nil
+}
+
+func (s *pgvectorStore) DeletePoint(ctx context.Context, collection string, documentID int64, chunkIndex int) error {
+	table, err := s.table(collection)
+	if err != nil {
+		return err
+	}
+	pointID := int64(vectorPointID(documentID, chunkIndex))
+	if err := s.db.WithContext(ctx).Exec(fmt.Sprintf(`DELETE FROM "%s" WHERE id = ?`, table), pointID).Error; err != nil {
+		return fmt.Errorf("删除分段向量失败: %w", err)
+	}
+	return nil
+}
+
+func (s *pgvectorStore) Export(ctx context.Context, collection string, batchSize int, fn func(field string, points []VectorPoint) error) error {
+	table, err := s.table(collection)
+	if err != nil {
+		return err
+	}
+	dims, exists, err := s.dimensions(ctx, table)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("pgvector 表 %s 不存在", table)
+	}
+
+	for _, field := range []string{"text", "image"} {
+		if _, ok := dims[field]; !ok {
+			continue
+		}
+		column := pgvectorColumns[field]
+		var lastID int64 = -1
+		for {
+			var rows []pgvectorRow
+			err := s.db.WithContext(ctx).Raw(fmt.Sprintf(`SELECT id, vector_field, document_id, chunk_index, content, content_type, image_path,
+					metadata::text AS metadata, %s::text AS vector
+				FROM "%s" WHERE %s IS NOT NULL AND id > ? ORDER BY id LIMIT ?`, column, table, column),
+				lastID, batchSize,
+			).Scan(&rows).Error
+			if err != nil {
+				return fmt.Errorf("导出向量失败: %w", err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+
+			points := make([]VectorPoint, 0, len(rows))
+			for i := range rows {
+				r := &rows[i]
+				vector, err := parsePgvector(derefString(r.Vector))
+				if err != nil {
+					return err
+				}
+				points = append(points, VectorPoint{
+					ID:          fmt.Sprintf("%d", r.ID),
+					Vector:      vector,
+					DocumentID:  r.DocumentID,
+					ChunkIndex:  r.ChunkIndex,
+					Content:     derefString(r.Content),
+					ContentType: derefString(r.ContentType),
+					ImagePath:   derefString(r.ImagePath),
+					Metadata:    r.metadata(),
+				})
+				lastID = r.ID
+			}
+			if err := fn(field, points); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *pgvectorStore) Diagnose(ctx context.Context, collection string) *VectorCollectionDiag {
+	diag := &VectorCollectionDiag{Store: VectorStorePgvector, CollectionName: collection}
+	table, err := s.table(collection)
+	if err != nil {
+		diag.Error = err.Error()
+		return diag
+	}
+	dims, exists, err := s.dimensions(ctx, table)
+	if err != nil {
+		diag.Error = fmt.Sprintf("查询表结构失败: %v", err)
+		return diag
+	}
+	if !exists {
+		diag.Error = "集合不存在，文档可能尚未索引"
+		return diag
+	}
+	diag.Exists = true
+
+	var count int64
+	if err := s.db.WithContext(ctx).Raw(fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, table)).Scan(&count).Error; err != nil {
+		diag.Error = fmt.Sprintf("统计向量数量失败: %v", err)
+		return diag
+	}
+	diag.PointCount = uint64(count)
+	diag.VectorCount = diag.PointCount
+	diag.VectorFields = make(map[string]uint64, len(dims))
+	for field, dim := range dims {
+		diag.VectorFields[field] = uint64(dim)
+	}
+	return diag
+}
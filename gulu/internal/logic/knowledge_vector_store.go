@@ -0,0 +1,373 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+)
+
+// -----------------------------------------------
+// 向量库后端抽象（按知识库选择 Qdrant / 内置 / pgvector）
+// -----------------------------------------------
+
+const (
+	VectorStoreQdrant   = "qdrant"
+	VectorStoreEmbedded = "embedded"
+	VectorStorePgvector = "pgvector"
+)
+
+// VectorStore 知识库向量库后端
+// 一个集合包含 text / image 两个命名向量字段，每个点只属于其中一个字段，
+// 点 ID 由 vectorPointID(documentID, chunkIndex) 计算，相似度统一为余弦相似度。
+type VectorStore interface {
+	// Name 后端名称
+	Name() string
+	// EnsureCollection 确保集合存在且向量维度匹配；维度为 0 的字段不检查，已有字段维度变化时删除重建
+	EnsureCollection(ctx context.Context, collection string, cfg CollectionVectorConfig) error
+	// DeleteCollection 删除集合
+	DeleteCollection(ctx context.Context, collection string) error
+	// Upsert 批量写入向量到指定字段
+	Upsert(ctx context.Context, collection, field string, points []VectorPoint) error
+	// Search 在指定字段中按余弦相似度检索，返回满足 filter 且分数不低于 scoreThreshold 的前 topK 个点；filter 为 nil 表示不过滤
+	Search(ctx context.Context, collection, field string, vector []float32, topK int, scoreThreshold float32, filter *VectorFilter) ([]SearchHit, error)
+	// DeleteDocument 删除文档的全部向量
+	DeleteDocument(ctx context.Context, collection string, documentID int64) error
+	// SetDocumentPayload 覆盖文档全部点的指定附加数据字段（元数据与访问控制变化时使用，无需重新向量化）
+	SetDocumentPayload(ctx context.Context, collection string, documentID int64, payload map[string]interface{}) error
+	// DeletePoint 删除单个分段的向量
+	DeletePoint(ctx context.Context, collection string, documentID int64, chunkIndex int) error
+	// Export 分批导出集合中的全部点（含向量），每批只包含同一字段的点
+	Export(ctx context.Context, collection string, batchSize int, fn func(field string, points []VectorPoint) error) error
+	// Diagnose 获取集合诊断信息
+	Diagnose(ctx context.Context, collection string) *VectorCollectionDiag
+}
+
+// VectorCollectionDiag 向量库集合诊断信息
+type VectorCollectionDiag struct {
+	Store          string            `json:"store"`
+	CollectionName string            `json:"collection_name"`
+	Exists         bool              `json:"exists"`
+	VectorCount    uint64            `json:"vector_count"`
+	PointCount     uint64            `json:"point_count"`
+	VectorFields   map[string]uint64 `json:"vector_fields"` // 各向量字段的维度
+	Error          string            `json:"error,omitempty"`
+}
+
+// collectionNamePattern 集合名只允许字母数字下划线（内置库用作文件名，pgvector 用作表名）
+var collectionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,48}$`)
+
+func validateCollectionName(collection string) error {
+	if !collectionNamePattern.MatchString(collection) {
+		return fmt.Errorf("非法的向量集合名: %q", collection)
+	}
+	return nil
+}
+
+// vectorPointID 分段向量点 ID（各后端一致，迁移后 ID 不变）
+func vectorPointID(documentID int64, chunkIndex int) uint64 {
+	return uint64(documentID)*100000 + uint64(chunkIndex)
+}
+
+// hitMetadata 从点的附加数据中提取检索结果返回的元数据（与 Qdrant 实现保持一致，仅返回 document_name）
+func hitMetadata(metadata map[string]interface{}) map[string]interface{} {
+	name, ok := metadata["document_name"].(string)
+	if !ok {
+		return nil
+	}
+	return map[string]interface{}{"document_name": name}
+}
+
+// NormalizeVectorStore 规范化向量库后端名称，空值返回配置的默认后端
+func NormalizeVectorStore(name string) (string, error) {
+	if name == "" {
+		name = VectorStoreQdrant
+		if svc.Ctx != nil && svc.Ctx.Config != nil && svc.Ctx.Config.VectorStore.Default != "" {
+			name = svc.Ctx.Config.VectorStore.Default
+		}
+	}
+	switch name {
+	case VectorStoreQdrant, VectorStoreEmbedded, VectorStorePgvector:
+		return name, nil
+	}
+	return "", fmt.Errorf("不支持的向量库后端: %s（可选 qdrant / embedded / pgvector）", name)
+}
+
+// GetVectorStore 获取指定名称的向量库后端
+func GetVectorStore(name string) (VectorStore, error) {
+	name, err := NormalizeVectorStore(name)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case VectorStoreEmbedded:
+		return getEmbeddedVectorStore()
+	case VectorStorePgvector:
+		return getPgvectorStore()
+	default:
+		return qdrantVectorStore{}, nil
+	}
+}
+
+// vectorStoreOf 返回知识库使用的向量库后端与集合名
+func vectorStoreOf(kb *model.TKnowledgeBase) (VectorStore, string, error) {
+	if kb.QdrantCollection == nil || *kb.QdrantCollection == "" {
+		return nil, "", errors.New("知识库尚未初始化向量存储")
+	}
+	name := kb.VectorStore
+	if name == "" {
+		name = VectorStoreQdrant // 历史知识库未记录后端，均为 Qdrant
+	}
+	store, err := GetVectorStore(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return store, *kb.QdrantCollection, nil
+}
+
+// -----------------------------------------------
+// 向量库迁移
+// -----------------------------------------------
+
+// MigrateVectorStoreReq 向量库迁移请求
+type MigrateVectorStoreReq struct {
+	Target       string `json:"target"`        // 目标后端: qdrant / embedded / pgvector
+	DeleteSource bool   `json:"delete_source"` // 迁移成功后删除源集合
+}
+
+// MigrateVectorStoreResult 向量库迁移结果
+type MigrateVectorStoreResult struct {
+	Source     string         `json:"source"`
+	Target     string         `json:"target"`
+	Collection string         `json:"collection"`
+	Points     map[string]int `json:"points"`   // 各字段迁移的点数
+	Duration   int64          `json:"duration"` // 毫秒
+	Warnings   []string       `json:"warnings,omitempty"`
+}
+
+const (
+	migrateBatchSize     = 256              // 迁移时每批导出/写入的点数
+	vectorMigrationLease = 10 * time.Minute // 迁移租约时长，迁移进程退出后到期自动解除
+	vectorMigrationRenew = time.Minute      // 迁移期间续约间隔
+)
+
+var errVectorMigrating = errors.New("知识库正在迁移向量库，请稍后再试")
+
+// acquireVectorMigration 在知识库上获取迁移租约，持有期间入库队列不认领该知识库的任务。
+// 返回的 release 停止续约并解除租约；已有迁移进行中时返回 errVectorMigrating。
+func acquireVectorMigration(kbID int64) (release func(), err error) {
+	db := svc.Ctx.DB
+	now := time.Now()
+	res := db.Model(&model.TKnowledgeBase{}).
+		Where("id = ? AND (vector_migrating_until IS NULL OR vector_migrating_until < ?)", kbID, now).
+		Update("vector_migrating_until", now.Add(vectorMigrationLease))
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errVectorMigrating
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(vectorMigrationRenew)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := db.Model(&model.TKnowledgeBase{}).Where("id = ?", kbID).
+					Update("vector_migrating_until", time.Now().Add(vectorMigrationLease)).Error; err != nil {
+					log.Printf("[WARN] 续约向量库迁移失败: kbID=%d, err=%v", kbID, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		if err := db.Model(&model.TKnowledgeBase{}).Where("id = ?", kbID).
+			Update("vector_migrating_until", nil).Error; err != nil {
+			log.Printf("[WARN] 解除向量库迁移租约失败: kbID=%d, err=%v", kbID, err)
+		}
+	}, nil
+}
+
+// ensureNotVectorMigrating 知识库正在迁移向量库时拒绝直接写入或删除向量的操作
+func ensureNotVectorMigrating(kb *model.TKnowledgeBase) error {
+	if kb.VectorMigratingUntil != nil && kb.VectorMigratingUntil.After(time.Now()) {
+		return errVectorMigrating
+	}
+	return nil
+}
+
+// MigrateVectorStore 将知识库的向量集合迁移到另一个后端，校验点数后切换知识库使用的后端。
+// 迁移期间持有知识库迁移租约：入库队列暂停该知识库的任务，文档删除与分块编辑被拒绝；
+// 获取租约时仍有处理中的文档则拒绝迁移。
+func (l *KnowledgeBaseLogic) MigrateVectorStore(kbID int64, req *MigrateVectorStoreReq) (*MigrateVectorStoreResult, error) {
+	db := svc.Ctx.DB
+
+	var kb model.TKnowledgeBase
+	if err := db.Where("id = ? AND is_delete = 0", kbID).First(&kb).Error; err != nil {
+		return nil, errors.New("知识库不存在")
+	}
+	source, collection, err := vectorStoreOf(&kb)
+	if err != nil {
+		return nil, err
+	}
+	if req.Target == "" {
+		return nil, errors.New("请指定目标向量库后端")
+	}
+	targetName, err := NormalizeVectorStore(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	if targetName == source.Name() {
+		return nil, fmt.Errorf("知识库已使用 %s 向量库", targetName)
+	}
+	target, err := GetVectorStore(targetName)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := acquireVectorMigration(kbID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var processing int64
+	db.Model(&model.TKnowledgeDocument{}).
+		Where("knowledge_base_id = ? AND indexing_status IN ?", kbID, []string{"waiting", "parsing", "splitting", "indexing"}).
+		Count(&processing)
+	if processing > 0 {
+		return nil, fmt.Errorf("知识库有 %d 个文档正在处理，请等待处理完成后再迁移", processing)
+	}
+
+	start := time.Now()
+	result := &MigrateVectorStoreResult{
+		Source:     source.Name(),
+		Target:     targetName,
+		Collection: collection,
+		Points:     map[string]int{},
+	}
+
+	diag := source.Diagnose(l.ctx, collection)
+	if diag.Exists {
+		// 目标后端可能残留之前迁移的同名集合，先清空再按源集合维度创建
+		_ = target.DeleteCollection(l.ctx, collection)
+		cfg := CollectionVectorConfig{
+			TextDimension:  int(diag.VectorFields["text"]),
+			ImageDimension: int(diag.VectorFields["image"]),
+		}
+		if err := target.EnsureCollection(l.ctx, collection, cfg); err != nil {
+			return nil, fmt.Errorf("创建目标集合失败: %w", err)
+		}
+
+		err := source.Export(l.ctx, collection, migrateBatchSize, func(field string, points []VectorPoint) error {
+			if err := target.Upsert(l.ctx, collection, field, points); err != nil {
+				return err
+			}
+			result.Points[field] += len(points)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("迁移向量失败: %w", err)
+		}
+
+		total := 0
+		for _, n := range result.Points {
+			total += n
+		}
+		targetDiag := target.Diagnose(l.ctx, collection)
+		if targetDiag.Error != "" {
+			return nil, fmt.Errorf("校验目标集合失败: %s", targetDiag.Error)
+		}
+		if int(targetDiag.PointCount) != total {
+			return nil, fmt.Errorf("迁移校验失败: 导出 %d 个点，目标集合实际 %d 个点", total, targetDiag.PointCount)
+		}
+	} else {
+		// 集合不存在时只允许空知识库直接切换，避免源后端不可用时误切换导致数据丢失
+		var segments int64
+		db.Model(&model.TKnowledgeSegment{}).Where("knowledge_base_id = ?", kbID).Count(&segments)
+		if segments > 0 {
+			return nil, fmt.Errorf("读取源集合失败: %s", diag.Error)
+		}
+		result.Warnings = append(result.Warnings, "源集合不存在，仅切换向量库后端")
+	}
+
+	if err := db.Model(&model.TKnowledgeBase{}).Where("id = ?", kbID).Updates(map[string]interface{}{
+		"vector_store": targetName,
+		"updated_at":   time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	if req.DeleteSource && diag.Exists {
+		if err := source.DeleteCollection(l.ctx, collection); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("删除源集合失败: %v", err))
+		}
+	}
+
+	result.Duration = time.Since(start).Milliseconds()
+	log.Printf("[INFO] 知识库 %d 向量库已迁移: %s -> %s (%v)", kbID, result.Source, targetName, result.Points)
+	return result, nil
+}
+
+// -----------------------------------------------
+// 按向量检索（供 workflow-engine knowledge_search 工具调用）
+// -----------------------------------------------
+
+// VectorQueryReq 按查询向量检索请求
+type VectorQueryReq struct {
+	Vector         []float32         `json:"vector"`
+	Field          string            `json:"field"` // text / image，默认 text
+	TopK           int               `json:"top_k"`
+	ScoreThreshold float32           `json:"score_threshold"`
+	Filters        []*MetadataFilter `json:"filters"`     // 元数据过滤表达式（AI 节点配置 + 工具调用参数），需同时满足
+	ACLUserID      *int64            `json:"acl_user_id"` // 按该用户的文档访问控制检索，必填
+}
+
+// QueryVectors 在知识库所用向量库中按查询向量检索。
+// 查询向量由调用方生成，workflow-engine 通过该接口检索并总是按执行用户的文档访问控制过滤。
+func (l *KnowledgeBaseLogic) QueryVectors(kbID int64, req *VectorQueryReq) ([]SearchHit, error) {
+	if len(req.Vector) == 0 {
+		return nil, errors.New("查询向量不能为空")
+	}
+	if req.ACLUserID == nil || *req.ACLUserID <= 0 {
+		return nil, errors.New("缺少 acl_user_id")
+	}
+	for _, f := range req.Filters {
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var kb model.TKnowledgeBase
+	if err := svc.Ctx.DB.Where("id = ? AND is_delete = 0", kbID).First(&kb).Error; err != nil {
+		return nil, errors.New("知识库不存在")
+	}
+	store, collection, err := vectorStoreOf(&kb)
+	if err != nil {
+		return nil, err
+	}
+
+	field := req.Field
+	if field == "" {
+		field = "text"
+	}
+	topK := req.TopK
+	if topK <= 0 {
+		topK = kb.GetConfig().TopK
+	}
+	return store.Search(l.ctx, collection, field, req.Vector, topK, req.ScoreThreshold, newVectorFilter(&kb, req.Filters, req.ACLUserID))
+}
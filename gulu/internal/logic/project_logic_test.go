@@ -46,8 +46,8 @@ func TestCheckCodeExists_Property(t *testing.T) {
 		// 生成随机项目代码
 		code := rapid.StringMatching(`[a-z][a-z0-9_]{2,20}`).Draw(t, "code")
 
-		// 属性：CheckCodeExists 应该返回 bool 和 error
-		exists, err := logic.CheckCodeExists(code, 0)
+		// 属性：按名称查询项目应该返回结果和 error（项目已不再单独保存代码，按名称检查存在性）
+		_, total, err := logic.List(&ProjectListReq{Page: 1, PageSize: 1, Name: code})
 
 		// 验证：函数应该正常返回，不应 panic
 		if err != nil {
@@ -55,6 +55,7 @@ func TestCheckCodeExists_Property(t *testing.T) {
 		}
 
 		// 验证：返回值应该是有效的布尔值
+		exists := total > 0
 		_ = exists // exists 是 bool 类型，总是有效的
 	})
 }
@@ -0,0 +1,680 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	commonUtils "yqhp/common/utils"
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+
+	"gorm.io/gorm"
+)
+
+// TestPlanLogic 测试计划逻辑
+type TestPlanLogic struct {
+	ctx context.Context
+}
+
+// NewTestPlanLogic 创建测试计划逻辑
+func NewTestPlanLogic(ctx context.Context) *TestPlanLogic {
+	return &TestPlanLogic{ctx: ctx}
+}
+
+// TestPlanItemReq 测试计划用例
+type TestPlanItemReq struct {
+	WorkflowID int64                  `json:"workflow_id" validate:"required"`
+	EnvID      int64                  `json:"env_id"` // 0 表示使用计划环境
+	Params     map[string]interface{} `json:"params"` // 覆盖计划参数
+	Stage      int32                  `json:"stage"`  // 分阶段运行时的阶段序号
+	Enabled    *bool                  `json:"enabled"`
+}
+
+// CreateTestPlanReq 创建测试计划请求
+type CreateTestPlanReq struct {
+	ProjectID     int64                  `json:"project_id"`
+	Name          string                 `json:"name" validate:"required,max=100"`
+	Description   string                 `json:"description" validate:"max=500"`
+	EnvID         int64                  `json:"env_id" validate:"required"`
+	RunMode       string                 `json:"run_mode"` // serial, parallel, stage（默认 serial）
+	MaxParallel   int32                  `json:"max_parallel"`
+	StopOnFailure bool                   `json:"stop_on_failure"`
+	Params        map[string]interface{} `json:"params"`
+	Items         []TestPlanItemReq      `json:"items"`
+}
+
+// UpdateTestPlanReq 更新测试计划请求，Items 不为 nil 时整体替换用例
+type UpdateTestPlanReq struct {
+	Name          string                 `json:"name" validate:"max=100"`
+	Description   *string                `json:"description" validate:"omitempty,max=500"`
+	EnvID         int64                  `json:"env_id"`
+	RunMode       string                 `json:"run_mode"`
+	MaxParallel   *int32                 `json:"max_parallel"`
+	StopOnFailure *bool                  `json:"stop_on_failure"`
+	Params        map[string]interface{} `json:"params"`
+	Items         []TestPlanItemReq      `json:"items"`
+}
+
+// TestPlanListReq 测试计划列表请求
+type TestPlanListReq struct {
+	Page      int    `query:"page" validate:"min=1"`
+	PageSize  int    `query:"pageSize" validate:"min=1,max=100"`
+	ProjectID int64  `query:"projectId"`
+	Name      string `query:"name"`
+}
+
+// RunTestPlanReq 运行测试计划请求
+type RunTestPlanReq struct {
+	EnvID  int64                  `json:"env_id"` // 可选，覆盖计划默认环境（用例单独指定的环境不受影响）
+	Params map[string]interface{} `json:"params"` // 可选，覆盖计划参数
+}
+
+// TestPlanItemInfo 测试计划用例信息
+type TestPlanItemInfo struct {
+	ID           int64                  `json:"id"`
+	WorkflowID   int64                  `json:"workflow_id"`
+	WorkflowName string                 `json:"workflow_name"`
+	EnvID        int64                  `json:"env_id"`
+	Params       map[string]interface{} `json:"params"`
+	Stage        int32                  `json:"stage"`
+	Sort         int32                  `json:"sort"`
+	Enabled      bool                   `json:"enabled"`
+}
+
+// TestPlanInfo 测试计划返回信息
+type TestPlanInfo struct {
+	ID            int64                  `json:"id"`
+	CreatedAt     *time.Time             `json:"created_at"`
+	UpdatedAt     *time.Time             `json:"updated_at"`
+	CreatedBy     *int64                 `json:"created_by"`
+	ProjectID     int64                  `json:"project_id"`
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description"`
+	EnvID         int64                  `json:"env_id"`
+	RunMode       string                 `json:"run_mode"`
+	MaxParallel   int32                  `json:"max_parallel"`
+	StopOnFailure bool                   `json:"stop_on_failure"`
+	Params        map[string]interface{} `json:"params"`
+	ItemCount     int                    `json:"item_count"`
+	Items         []*TestPlanItemInfo    `json:"items,omitempty"`
+}
+
+// TestPlanReport 测试计划运行报告
+type TestPlanReport struct {
+	Run      *model.TExecution        `json:"run"`
+	PlanID   int64                    `json:"plan_id"`
+	Total    int                      `json:"total"`
+	Passed   int                      `json:"passed"`
+	Failed   int                      `json:"failed"`
+	Skipped  int                      `json:"skipped"`
+	Stopped  int                      `json:"stopped"`
+	Pending  int                      `json:"pending"`   // 等待或执行中
+	PassRate float64                  `json:"pass_rate"` // 百分比
+	Duration int64                    `json:"duration"`  // 毫秒
+	Items    []*model.TTestPlanResult `json:"items"`
+}
+
+func (l *TestPlanLogic) db() *gorm.DB {
+	return svc.Ctx.DB.WithContext(l.ctx)
+}
+
+// Create 创建测试计划
+func (l *TestPlanLogic) Create(req *CreateTestPlanReq, userID int64) (*TestPlanInfo, error) {
+	runMode, err := normalizeRunMode(req.RunMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.validateEnv(req.ProjectID, req.EnvID); err != nil {
+		return nil, err
+	}
+	items, err := l.buildItems(req.ProjectID, req.Items)
+	if err != nil {
+		return nil, err
+	}
+	paramsJSON, err := marshalParams(req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	isDelete := false
+	plan := &model.TTestPlan{
+		CreatedAt:     &now,
+		UpdatedAt:     &now,
+		IsDelete:      &isDelete,
+		CreatedBy:     &userID,
+		UpdatedBy:     &userID,
+		ProjectID:     req.ProjectID,
+		Name:          req.Name,
+		Description:   &req.Description,
+		EnvID:         req.EnvID,
+		RunMode:       runMode,
+		MaxParallel:   req.MaxParallel,
+		StopOnFailure: req.StopOnFailure,
+		Params:        paramsJSON,
+	}
+
+	err = l.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(plan).Error; err != nil {
+			return err
+		}
+		return saveTestPlanItems(tx, plan.ID, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return l.GetByID(plan.ID)
+}
+
+// Update 更新测试计划
+func (l *TestPlanLogic) Update(id int64, req *UpdateTestPlanReq, userID int64) error {
+	plan, err := l.get(id)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"updated_at": time.Now(),
+		"updated_by": userID,
+	}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.EnvID > 0 {
+		if err := l.validateEnv(plan.ProjectID, req.EnvID); err != nil {
+			return err
+		}
+		updates["env_id"] = req.EnvID
+	}
+	if req.RunMode != "" {
+		runMode, err := normalizeRunMode(req.RunMode)
+		if err != nil {
+			return err
+		}
+		updates["run_mode"] = runMode
+	}
+	if req.MaxParallel != nil {
+		updates["max_parallel"] = *req.MaxParallel
+	}
+	if req.StopOnFailure != nil {
+		updates["stop_on_failure"] = *req.StopOnFailure
+	}
+	if req.Params != nil {
+		paramsJSON, err := marshalParams(req.Params)
+		if err != nil {
+			return err
+		}
+		updates["params"] = paramsJSON
+	}
+
+	var items []*model.TTestPlanItem
+	if req.Items != nil {
+		if items, err = l.buildItems(plan.ProjectID, req.Items); err != nil {
+			return err
+		}
+	}
+
+	return l.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.TTestPlan{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.Items == nil {
+			return nil
+		}
+		if err := tx.Where("plan_id = ?", id).Delete(&model.TTestPlanItem{}).Error; err != nil {
+			return err
+		}
+		return saveTestPlanItems(tx, id, items)
+	})
+}
+
+// Delete 删除测试计划（软删除），存在关联的定时计划时不允许删除
+func (l *TestPlanLogic) Delete(id int64) error {
+	if _, err := l.get(id); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := l.db().Model(&model.TWorkflowSchedule{}).Where("plan_id = ? AND is_delete = ?", id, false).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("存在关联的定时计划，请先删除定时计划")
+	}
+
+	return l.db().Model(&model.TTestPlan{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"is_delete":  true,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// GetByID 获取测试计划详情（含用例）
+func (l *TestPlanLogic) GetByID(id int64) (*TestPlanInfo, error) {
+	plan, err := l.get(id)
+	if err != nil {
+		return nil, err
+	}
+	items, err := l.listItems(id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	info := toTestPlanInfo(plan)
+	info.Items = l.toItemInfos(items)
+	info.ItemCount = len(items)
+	return info, nil
+}
+
+// List 获取测试计划列表
+func (l *TestPlanLogic) List(req *TestPlanListReq) ([]*TestPlanInfo, int64, error) {
+	q := l.db().Model(&model.TTestPlan{}).Where("is_delete = ?", false)
+	if req.ProjectID > 0 {
+		q = q.Where("project_id = ?", req.ProjectID)
+	}
+	if req.Name != "" {
+		q = q.Where("name LIKE ?", "%"+req.Name+"%")
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	var plans []*model.TTestPlan
+	offset := (req.Page - 1) * req.PageSize
+	if err := q.Order("id DESC").Offset(offset).Limit(req.PageSize).Find(&plans).Error; err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[int64]int, len(plans))
+	if len(plans) > 0 {
+		ids := make([]int64, 0, len(plans))
+		for _, p := range plans {
+			ids = append(ids, p.ID)
+		}
+		var rows []struct {
+			PlanID int64
+			Count  int
+		}
+		l.db().Model(&model.TTestPlanItem{}).Select("plan_id, COUNT(*) AS count").
+			Where("plan_id IN ?", ids).Group("plan_id").Scan(&rows)
+		for _, r := range rows {
+			counts[r.PlanID] = r.Count
+		}
+	}
+
+	result := make([]*TestPlanInfo, 0, len(plans))
+	for _, p := range plans {
+		info := toTestPlanInfo(p)
+		info.ItemCount = counts[p.ID]
+		result = append(result, info)
+	}
+	return result, total, nil
+}
+
+// Run 运行测试计划：创建计划运行记录与用例明细后在后台执行，立即返回运行记录
+func (l *TestPlanLogic) Run(id int64, req *RunTestPlanReq, userID int64, triggerType model.TriggerType, scheduleID int64) (*model.TExecution, error) {
+	plan, err := l.get(id)
+	if err != nil {
+		return nil, err
+	}
+	items, err := l.listItems(id, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("测试计划没有可执行的用例")
+	}
+
+	envID := plan.EnvID
+	if req != nil && req.EnvID > 0 {
+		if err := l.validateEnv(plan.ProjectID, req.EnvID); err != nil {
+			return nil, err
+		}
+		envID = req.EnvID
+	}
+
+	params := unmarshalParams(plan.Params)
+	if req != nil {
+		for k, v := range req.Params {
+			if params == nil {
+				params = make(map[string]interface{})
+			}
+			params[k] = v
+		}
+	}
+
+	names := l.workflowNames(items)
+	now := time.Now()
+	var scheduleIDPtr *int64
+	if scheduleID > 0 {
+		scheduleIDPtr = &scheduleID
+	}
+	run := &model.TExecution{
+		CreatedAt:   &now,
+		UpdatedAt:   &now,
+		ProjectID:   plan.ProjectID,
+		SourceID:    plan.ID,
+		EnvID:       envID,
+		ExecutionID: generateExecutionID(),
+		Mode:        string(model.ExecutionModeExecute),
+		SourceType:  string(model.SourceTypeTestPlan),
+		Title:       plan.Name,
+		Status:      ExecutionStatusRunning,
+		StartTime:   &now,
+		CreatedBy:   &userID,
+		TriggerType: string(triggerType),
+		ScheduleID:  scheduleIDPtr,
+	}
+
+	results := make([]*model.TTestPlanResult, 0, len(items))
+	for _, item := range items {
+		itemEnv := envID
+		if item.EnvID != nil && *item.EnvID > 0 {
+			itemEnv = *item.EnvID
+		}
+		results = append(results, &model.TTestPlanResult{
+			PlanID:       plan.ID,
+			ItemID:       item.ID,
+			WorkflowID:   item.WorkflowID,
+			WorkflowName: names[item.WorkflowID],
+			EnvID:        itemEnv,
+			Stage:        item.Stage,
+			Sort:         item.Sort,
+			Status:       ExecutionStatusPending,
+		})
+	}
+
+	err = l.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return err
+		}
+		for _, r := range results {
+			r.RunID = run.ID
+		}
+		return tx.Create(&results).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	itemParams := make(map[int64]map[string]interface{}, len(items))
+	for _, item := range items {
+		merged := make(map[string]interface{}, len(params))
+		for k, v := range params {
+			merged[k] = v
+		}
+		for k, v := range unmarshalParams(item.Params) {
+			merged[k] = v
+		}
+		itemParams[item.ID] = merged
+	}
+
+	runner := newPlanRunner(plan, run, results, itemParams, userID)
+	commonUtils.SafeGoWithName("test-plan-run-"+run.ExecutionID, runner.start)
+
+	return run, nil
+}
+
+// StopRun 停止测试计划运行：未开始的用例不再执行，执行中的工作流会被停止
+func (l *TestPlanLogic) StopRun(runID int64) error {
+	run, err := l.getRun(runID)
+	if err != nil {
+		return err
+	}
+	if run.Status != ExecutionStatusRunning {
+		return errors.New("只能停止运行中的测试计划")
+	}
+
+	// 先标记状态，其他实例上的运行器轮询时据此停止
+	if err := l.db().Model(&model.TExecution{}).Where("id = ? AND status = ?", runID, ExecutionStatusRunning).
+		Update("status", ExecutionStatusStopped).Error; err != nil {
+		return err
+	}
+	if cancel, ok := planRuns.Load(runID); ok {
+		cancel.(context.CancelFunc)()
+	}
+	return nil
+}
+
+// Report 获取测试计划运行报告
+func (l *TestPlanLogic) Report(runID int64) (*TestPlanReport, error) {
+	run, err := l.getRun(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*model.TTestPlanResult
+	if err := l.db().Where("run_id = ?", runID).Order("stage ASC, sort ASC, id ASC").Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	report := &TestPlanReport{
+		Run:    run,
+		PlanID: run.SourceID,
+		Total:  len(results),
+		Items:  results,
+	}
+	for _, r := range results {
+		switch r.Status {
+		case ExecutionStatusCompleted:
+			report.Passed++
+		case ExecutionStatusFailed:
+			report.Failed++
+		case string(model.ExecutionStatusSkipped):
+			report.Skipped++
+		case ExecutionStatusStopped:
+			report.Stopped++
+		default:
+			report.Pending++
+		}
+	}
+	if report.Total > 0 {
+		report.PassRate = float64(report.Passed) * 100 / float64(report.Total)
+	}
+	if run.Duration != nil {
+		report.Duration = *run.Duration
+	} else if run.StartTime != nil {
+		report.Duration = time.Since(*run.StartTime).Milliseconds()
+	}
+	return report, nil
+}
+
+// ListRuns 获取测试计划的运行记录
+func (l *TestPlanLogic) ListRuns(planID int64, page, pageSize int) ([]*model.TExecution, int64, error) {
+	return NewExecutionLogic(l.ctx).List(&ExecutionListReq{
+		Page:       page,
+		PageSize:   pageSize,
+		SourceID:   planID,
+		SourceType: string(model.SourceTypeTestPlan),
+	})
+}
+
+func (l *TestPlanLogic) get(id int64) (*model.TTestPlan, error) {
+	var plan model.TTestPlan
+	if err := l.db().Where("id = ? AND is_delete = ?", id, false).First(&plan).Error; err != nil {
+		return nil, errors.New("测试计划不存在")
+	}
+	return &plan, nil
+}
+
+func (l *TestPlanLogic) getRun(runID int64) (*model.TExecution, error) {
+	var run model.TExecution
+	if err := l.db().Where("id = ? AND source_type = ?", runID, model.SourceTypeTestPlan).First(&run).Error; err != nil {
+		return nil, errors.New("测试计划运行记录不存在")
+	}
+	return &run, nil
+}
+
+func (l *TestPlanLogic) listItems(planID int64, enabledOnly bool) ([]*model.TTestPlanItem, error) {
+	q := l.db().Where("plan_id = ?", planID)
+	if enabledOnly {
+		q = q.Where("enabled = ?", true)
+	}
+	var items []*model.TTestPlanItem
+	if err := q.Order("stage ASC, sort ASC, id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// validateEnv 校验环境存在且属于项目
+func (l *TestPlanLogic) validateEnv(projectID, envID int64) error {
+	env, err := NewEnvLogic(l.ctx).GetByID(envID)
+	if err != nil {
+		return errors.New("环境不存在")
+	}
+	if env.ProjectID != projectID {
+		return errors.New("环境不属于当前项目")
+	}
+	return nil
+}
+
+// buildItems 校验用例并转换为模型，排序号取请求中的顺序
+func (l *TestPlanLogic) buildItems(projectID int64, reqs []TestPlanItemReq) ([]*model.TTestPlanItem, error) {
+	workflowLogic := NewWorkflowLogic(l.ctx)
+	items := make([]*model.TTestPlanItem, 0, len(reqs))
+	for i, r := range reqs {
+		wf, err := workflowLogic.GetByID(r.WorkflowID)
+		if err != nil {
+			return nil, errors.New("工作流不存在")
+		}
+		if wf.ProjectID != projectID {
+			return nil, errors.New("工作流「" + wf.Name + "」不属于当前项目")
+		}
+
+		item := &model.TTestPlanItem{
+			WorkflowID: r.WorkflowID,
+			Stage:      r.Stage,
+			Sort:       int32(i),
+			Enabled:    r.Enabled == nil || *r.Enabled,
+		}
+		if r.EnvID > 0 {
+			if err := l.validateEnv(projectID, r.EnvID); err != nil {
+				return nil, err
+			}
+			envID := r.EnvID
+			item.EnvID = &envID
+		}
+		if item.Params, err = marshalParams(r.Params); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (l *TestPlanLogic) workflowNames(items []*model.TTestPlanItem) map[int64]string {
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.WorkflowID)
+	}
+	var rows []*model.TWorkflow
+	l.db().Select("id, name").Where("id IN ?", ids).Find(&rows)
+
+	names := make(map[int64]string, len(rows))
+	for _, w := range rows {
+		names[w.ID] = w.Name
+	}
+	return names
+}
+
+func (l *TestPlanLogic) toItemInfos(items []*model.TTestPlanItem) []*TestPlanItemInfo {
+	names := map[int64]string{}
+	if len(items) > 0 {
+		names = l.workflowNames(items)
+	}
+	infos := make([]*TestPlanItemInfo, 0, len(items))
+	for _, item := range items {
+		info := &TestPlanItemInfo{
+			ID:           item.ID,
+			WorkflowID:   item.WorkflowID,
+			WorkflowName: names[item.WorkflowID],
+			Params:       unmarshalParams(item.Params),
+			Stage:        item.Stage,
+			Sort:         item.Sort,
+			Enabled:      item.Enabled,
+		}
+		if item.EnvID != nil {
+			info.EnvID = *item.EnvID
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func saveTestPlanItems(tx *gorm.DB, planID int64, items []*model.TTestPlanItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	for _, item := range items {
+		item.ID = 0
+		item.PlanID = planID
+	}
+	return tx.Create(&items).Error
+}
+
+func normalizeRunMode(mode string) (string, error) {
+	if mode == "" {
+		return string(model.TestPlanRunModeSerial), nil
+	}
+	if !model.TestPlanRunMode(mode).IsValid() {
+		return "", errors.New("无效的运行方式，必须是 serial、parallel 或 stage")
+	}
+	return mode, nil
+}
+
+func marshalParams(params map[string]interface{}) (*string, error) {
+	if params == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return nil, errors.New("执行参数序列化失败")
+	}
+	s := string(b)
+	return &s, nil
+}
+
+func unmarshalParams(s *string) map[string]interface{} {
+	if s == nil || *s == "" {
+		return nil
+	}
+	var params map[string]interface{}
+	_ = json.Unmarshal([]byte(*s), &params)
+	return params
+}
+
+// toTestPlanInfo 转换为返回信息
+func toTestPlanInfo(p *model.TTestPlan) *TestPlanInfo {
+	info := &TestPlanInfo{
+		ID:            p.ID,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+		CreatedBy:     p.CreatedBy,
+		ProjectID:     p.ProjectID,
+		Name:          p.Name,
+		EnvID:         p.EnvID,
+		RunMode:       p.RunMode,
+		MaxParallel:   p.MaxParallel,
+		StopOnFailure: p.StopOnFailure,
+		Params:        unmarshalParams(p.Params),
+	}
+	if p.Description != nil {
+		info.Description = *p.Description
+	}
+	return info
+}
@@ -0,0 +1,422 @@
+package logic
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"yqhp/common/logger"
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+	"yqhp/gulu/internal/workflow"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	planPollInterval      = 2 * time.Second
+	planItemTimeout       = 35 * time.Minute // 略长于 monitorExecution 的 30 分钟超时
+	planHeartbeatInterval = 30 * time.Second // 运行器定期刷新运行记录的 updated_at
+	planRunStaleAfter     = 3 * time.Minute  // 超过该时长未刷新的运行中记录视为运行器已退出
+)
+
+// planRuns 本实例正在运行的测试计划：run ID -> context.CancelFunc
+var planRuns sync.Map
+
+// planRunner 测试计划运行器，按运行方式分批执行用例并汇总结果
+type planRunner struct {
+	plan       *model.TTestPlan
+	run        *model.TExecution
+	results    []*model.TTestPlanResult
+	itemParams map[int64]map[string]interface{}
+	userID     int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	failed atomic.Bool
+}
+
+func newPlanRunner(plan *model.TTestPlan, run *model.TExecution, results []*model.TTestPlanResult,
+	itemParams map[int64]map[string]interface{}, userID int64) *planRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &planRunner{
+		plan:       plan,
+		run:        run,
+		results:    results,
+		itemParams: itemParams,
+		userID:     userID,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+func (r *planRunner) start() {
+	planRuns.Store(r.run.ID, r.cancel)
+	defer func() {
+		planRuns.Delete(r.run.ID)
+		r.cancel()
+	}()
+	go r.heartbeat()
+
+	for _, batch := range planBatches(model.TestPlanRunMode(r.plan.RunMode), r.results) {
+		r.runBatch(batch)
+	}
+	r.finish()
+}
+
+// heartbeat 运行期间定期刷新运行记录，供 ReconcileTestPlanRuns 区分存活的运行器与中断的运行
+func (r *planRunner) heartbeat() {
+	ticker := time.NewTicker(planHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := svc.Ctx.DB.Model(&model.TExecution{}).
+				Where("id = ? AND status = ?", r.run.ID, ExecutionStatusRunning).
+				Update("updated_at", time.Now()).Error; err != nil {
+				logger.Warn("刷新测试计划运行心跳失败", zap.Int64("run_id", r.run.ID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// planBatches 按运行方式将用例分批：批次之间串行，批次内并行
+func planBatches(mode model.TestPlanRunMode, results []*model.TTestPlanResult) [][]*model.TTestPlanResult {
+	switch mode {
+	case model.TestPlanRunModeParallel:
+		return [][]*model.TTestPlanResult{results}
+	case model.TestPlanRunModeStage:
+		byStage := make(map[int32][]*model.TTestPlanResult)
+		stages := make([]int32, 0)
+		for _, res := range results {
+			if _, ok := byStage[res.Stage]; !ok {
+				stages = append(stages, res.Stage)
+			}
+			byStage[res.Stage] = append(byStage[res.Stage], res)
+		}
+		sort.Slice(stages, func(i, j int) bool { return stages[i] < stages[j] })
+		batches := make([][]*model.TTestPlanResult, 0, len(stages))
+		for _, stage := range stages {
+			batches = append(batches, byStage[stage])
+		}
+		return batches
+	default:
+		batches := make([][]*model.TTestPlanResult, 0, len(results))
+		for _, res := range results {
+			batches = append(batches, []*model.TTestPlanResult{res})
+		}
+		return batches
+	}
+}
+
+func (r *planRunner) runBatch(batch []*model.TTestPlanResult) {
+	limit := int(r.plan.MaxParallel)
+	if limit <= 0 || limit > len(batch) {
+		limit = len(batch)
+	}
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for _, res := range batch {
+		sem <- struct{}{}
+		if status, skip := r.shouldSkip(); skip {
+			<-sem
+			r.finishResult(res, status, "")
+			continue
+		}
+
+		wg.Add(1)
+		go func(res *model.TTestPlanResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			r.runItem(res)
+		}(res)
+	}
+	wg.Wait()
+}
+
+// shouldSkip 判断是否还应启动新的用例：计划被停止时记为 stopped，失败即停时记为 skipped
+func (r *planRunner) shouldSkip() (string, bool) {
+	if r.stopped() {
+		return ExecutionStatusStopped, true
+	}
+	if r.plan.StopOnFailure && r.failed.Load() {
+		return string(model.ExecutionStatusSkipped), true
+	}
+	return "", false
+}
+
+// stopped 检查本地取消信号以及数据库中的运行状态（其他实例发起的停止）
+func (r *planRunner) stopped() bool {
+	if r.ctx.Err() != nil {
+		return true
+	}
+	var status string
+	if err := svc.Ctx.DB.Model(&model.TExecution{}).Where("id = ?", r.run.ID).Pluck("status", &status).Error; err == nil &&
+		status == ExecutionStatusStopped {
+		r.cancel()
+		return true
+	}
+	return false
+}
+
+func (r *planRunner) runItem(res *model.TTestPlanResult) {
+	now := time.Now()
+	res.StartTime = &now
+	r.updateResult(res, map[string]interface{}{"status": ExecutionStatusRunning, "start_time": now})
+
+	// 引擎未启动时执行记录不会进入终态，直接失败而不是等到用例超时
+	if workflow.GetEngine() == nil {
+		r.finishResult(res, ExecutionStatusFailed, "工作流引擎未启动")
+		return
+	}
+
+	executionLogic := NewExecutionLogic(context.Background())
+	execution, err := executionLogic.Execute(&ExecuteWorkflowReq{
+		WorkflowID:  res.WorkflowID,
+		EnvID:       res.EnvID,
+		Mode:        defaultExecutionMode(context.Background(), res.WorkflowID),
+		Variables:   r.itemParams[res.ItemID],
+		TriggerType: string(model.TriggerTypeTestPlan),
+	}, r.userID)
+	if err != nil {
+		r.finishResult(res, ExecutionStatusFailed, err.Error())
+		return
+	}
+	res.ExecutionDBID = &execution.ID
+	r.updateResult(res, map[string]interface{}{"execution_db_id": execution.ID})
+
+	status, errMsg := r.waitExecution(executionLogic, execution.ID)
+	r.finishResult(res, status, errMsg)
+}
+
+// waitExecution 轮询工作流执行记录直到终态，返回状态与错误信息；
+// 计划被停止时同时停止该工作流，等待超时时停止引擎中仍在运行的工作流
+func (r *planRunner) waitExecution(executionLogic *ExecutionLogic, id int64) (string, string) {
+	outcome, status := pollExecution(planPollInterval, planItemTimeout, r.stopped, func() (string, error) {
+		execution, err := executionLogic.GetByID(id)
+		if err != nil {
+			return "", err
+		}
+		return execution.Status, nil
+	})
+
+	switch outcome {
+	case pollTimeout:
+		if err := executionLogic.abort(id); err != nil {
+			logger.Warn("停止超时的测试计划用例失败", zap.Int64("execution_id", id), zap.Error(err))
+		}
+		return ExecutionStatusFailed, "执行超时（" + planItemTimeout.String() + "）"
+	case pollStopped:
+		if err := executionLogic.Stop(id); err != nil {
+			logger.Warn("停止测试计划用例失败", zap.Int64("execution_id", id), zap.Error(err))
+		}
+		return ExecutionStatusStopped, ""
+	}
+	return status, ""
+}
+
+// pollOutcome 轮询执行记录的结束原因
+type pollOutcome int
+
+const (
+	pollTerminal pollOutcome = iota // 执行进入终态
+	pollStopped                     // 计划被停止
+	pollTimeout                     // 等待超时
+)
+
+// pollExecution 每隔 interval 检查一次计划是否被停止及执行状态，直到执行进入终态、计划被停止或超过 timeout；
+// 读取状态出错时视为暂时性错误，下一轮继续
+func pollExecution(interval, timeout time.Duration, stopped func() bool, status func() (string, error)) (pollOutcome, string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-deadline.C:
+			return pollTimeout, ""
+		case <-ticker.C:
+			if stopped() {
+				return pollStopped, ""
+			}
+			s, err := status()
+			if err != nil {
+				continue
+			}
+			if model.ExecutionStatus(s).IsTerminal() {
+				return pollTerminal, s
+			}
+		}
+	}
+}
+
+// planItemFailed 除已完成以外的终态（失败、停止、跳过等）均视为用例失败，用于失败即停与计划汇总
+func planItemFailed(status string) bool {
+	return status != ExecutionStatusCompleted
+}
+
+func (r *planRunner) finishResult(res *model.TTestPlanResult, status string, errMsg string) {
+	if planItemFailed(status) {
+		r.failed.Store(true)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":   status,
+		"end_time": now,
+	}
+	if res.StartTime != nil {
+		updates["duration"] = now.Sub(*res.StartTime).Milliseconds()
+	}
+	if errMsg != "" {
+		updates["error_message"] = errMsg
+	}
+	res.Status = status
+	r.updateResult(res, updates)
+}
+
+func (r *planRunner) updateResult(res *model.TTestPlanResult, updates map[string]interface{}) {
+	if err := svc.Ctx.DB.Model(&model.TTestPlanResult{}).Where("id = ?", res.ID).Updates(updates).Error; err != nil {
+		logger.Error("更新测试计划执行明细失败", zap.Int64("result_id", res.ID), zap.Error(err))
+	}
+}
+
+// finish 汇总计划运行状态：被停止为 stopped，存在失败用例为 failed，否则为 completed
+func (r *planRunner) finish() {
+	status := ExecutionStatusCompleted
+	switch {
+	case r.stopped():
+		status = ExecutionStatusStopped
+	case r.failed.Load():
+		status = ExecutionStatusFailed
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     status,
+		"end_time":   now,
+		"updated_at": now,
+	}
+	if r.run.StartTime != nil {
+		updates["duration"] = now.Sub(*r.run.StartTime).Milliseconds()
+	}
+	if err := svc.Ctx.DB.Model(&model.TExecution{}).Where("id = ?", r.run.ID).Updates(updates).Error; err != nil {
+		logger.Error("更新测试计划运行状态失败", zap.Int64("run_id", r.run.ID), zap.Error(err))
+	}
+}
+
+// defaultExecutionMode 普通流程只支持调试模式，其他类型使用执行模式
+func defaultExecutionMode(ctx context.Context, workflowID int64) string {
+	wf, err := NewWorkflowLogic(ctx).GetByID(workflowID)
+	if err == nil && (wf.WorkflowType == nil || *wf.WorkflowType == string(model.WorkflowTypeNormal)) {
+		return string(model.ExecutionModeDebug)
+	}
+	return string(model.ExecutionModeExecute)
+}
+
+// ReconcileTestPlanRuns 启动时及此后定期收敛中断的测试计划运行，ctx 结束时退出：
+// 心跳超时的运行中记录（运行器所在实例已退出）置为失败，其中执行中的用例置为失败、未开始的用例置为跳过
+func ReconcileTestPlanRuns(ctx context.Context) {
+	ticker := time.NewTicker(planRunStaleAfter)
+	defer ticker.Stop()
+	for {
+		if err := reconcileStalePlanRuns(); err != nil {
+			logger.Warn("收敛中断的测试计划运行失败", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func reconcileStalePlanRuns() error {
+	return reconcilePlanRuns(dbPlanRunStore{db: svc.Ctx.DB}, time.Now())
+}
+
+// planRunStore 收敛中断的测试计划运行所需的存储操作
+type planRunStore interface {
+	// staleRuns 查询心跳早于 before 的运行中计划
+	staleRuns(before time.Time) ([]*model.TExecution, error)
+	// failRun 心跳仍早于 staleBefore 时将运行及其明细置为终态，返回是否抢占成功
+	failRun(run *model.TExecution, staleBefore, now time.Time) (bool, error)
+}
+
+// reconcilePlanRuns 将心跳超时且不在本实例运行的计划置为失败
+func reconcilePlanRuns(store planRunStore, now time.Time) error {
+	staleBefore := now.Add(-planRunStaleAfter)
+	runs, err := store.staleRuns(staleBefore)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		if _, ok := planRuns.Load(run.ID); ok {
+			continue
+		}
+		claimed, err := store.failRun(run, staleBefore, now)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			logger.Info("已收敛中断的测试计划运行", zap.Int64("run_id", run.ID))
+		}
+	}
+	return nil
+}
+
+type dbPlanRunStore struct {
+	db *gorm.DB
+}
+
+func (s dbPlanRunStore) staleRuns(before time.Time) ([]*model.TExecution, error) {
+	var runs []*model.TExecution
+	err := s.db.Where("source_type = ? AND status = ? AND updated_at < ?",
+		model.SourceTypeTestPlan, ExecutionStatusRunning, before).Find(&runs).Error
+	return runs, err
+}
+
+func (s dbPlanRunStore) failRun(run *model.TExecution, staleBefore, now time.Time) (bool, error) {
+	claimed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":     ExecutionStatusFailed,
+			"end_time":   now,
+			"updated_at": now,
+		}
+		if run.StartTime != nil {
+			updates["duration"] = now.Sub(*run.StartTime).Milliseconds()
+		}
+		// 以心跳时间为条件，避免与恢复心跳的运行器或其他实例的收敛并发
+		res := tx.Model(&model.TExecution{}).
+			Where("id = ? AND status = ? AND updated_at < ?", run.ID, ExecutionStatusRunning, staleBefore).
+			Updates(updates)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		claimed = true
+		if err := tx.Model(&model.TTestPlanResult{}).
+			Where("run_id = ? AND status = ?", run.ID, ExecutionStatusRunning).
+			Updates(map[string]interface{}{
+				"status":        ExecutionStatusFailed,
+				"end_time":      now,
+				"error_message": "测试计划运行中断",
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.TTestPlanResult{}).
+			Where("run_id = ? AND status = ?", run.ID, ExecutionStatusPending).
+			Update("status", string(model.ExecutionStatusSkipped)).Error
+	})
+	return claimed && err == nil, err
+}
@@ -0,0 +1,191 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"yqhp/gulu/internal/model"
+)
+
+// planResults 按 stage 列表构造用例明细，ID 从 1 开始递增
+func planResults(stages ...int32) []*model.TTestPlanResult {
+	results := make([]*model.TTestPlanResult, 0, len(stages))
+	for i, stage := range stages {
+		results = append(results, &model.TTestPlanResult{ID: int64(i + 1), Stage: stage})
+	}
+	return results
+}
+
+func batchIDs(batches [][]*model.TTestPlanResult) [][]int64 {
+	out := make([][]int64, 0, len(batches))
+	for _, batch := range batches {
+		ids := make([]int64, 0, len(batch))
+		for _, res := range batch {
+			ids = append(ids, res.ID)
+		}
+		out = append(out, ids)
+	}
+	return out
+}
+
+// TestPlanBatches 串行逐个成批，并行合为一批，分阶段按 stage 升序成批且批内保持原顺序
+func TestPlanBatches(t *testing.T) {
+	cases := []struct {
+		name   string
+		mode   model.TestPlanRunMode
+		stages []int32
+		want   [][]int64
+	}{
+		{"serial", model.TestPlanRunModeSerial, []int32{0, 0, 1}, [][]int64{{1}, {2}, {3}}},
+		{"unknown mode falls back to serial", model.TestPlanRunMode(""), []int32{0, 1}, [][]int64{{1}, {2}}},
+		{"parallel", model.TestPlanRunModeParallel, []int32{2, 0, 1}, [][]int64{{1, 2, 3}}},
+		{"stage", model.TestPlanRunModeStage, []int32{2, 0, 1, 0, 2}, [][]int64{{2, 4}, {3}, {1, 5}}},
+		{"single stage", model.TestPlanRunModeStage, []int32{3, 3}, [][]int64{{1, 2}}},
+		{"empty serial", model.TestPlanRunModeSerial, nil, [][]int64{}},
+		{"empty stage", model.TestPlanRunModeStage, nil, [][]int64{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := batchIDs(planBatches(c.mode, planResults(c.stages...)))
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("planBatches = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// TestPlanItemFailed 只有 completed 算成功，其余终态都触发失败即停
+func TestPlanItemFailed(t *testing.T) {
+	cases := map[string]bool{
+		ExecutionStatusCompleted:             false,
+		ExecutionStatusFailed:                true,
+		ExecutionStatusStopped:               true,
+		string(model.ExecutionStatusSkipped): true,
+		"aborted":                            true,
+	}
+	for status, want := range cases {
+		if got := planItemFailed(status); got != want {
+			t.Errorf("planItemFailed(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+// TestPollExecution 执行进入终态、计划被停止或超时时结束轮询，读取状态出错时继续
+func TestPollExecution(t *testing.T) {
+	errRead := errors.New("db unavailable")
+
+	cases := []struct {
+		name     string
+		statuses []string // 依次返回的状态，"!" 表示读取出错，用完后保持最后一个
+		stopAt   int      // 第 stopAt 次检查时计划被停止，0 表示不停止
+		timeout  time.Duration
+		outcome  pollOutcome
+		status   string
+	}{
+		{"completed", []string{"running", "completed"}, 0, time.Second, pollTerminal, "completed"},
+		{"failed", []string{"failed"}, 0, time.Second, pollTerminal, "failed"},
+		{"stopped execution", []string{"running", "stopped"}, 0, time.Second, pollTerminal, "stopped"},
+		{"read errors are retried", []string{"!", "!", "completed"}, 0, time.Second, pollTerminal, "completed"},
+		{"plan stopped", []string{"running"}, 2, time.Second, pollStopped, ""},
+		{"timeout while running", []string{"running"}, 0, 30 * time.Millisecond, pollTimeout, ""},
+		{"paused is not terminal", []string{"paused"}, 0, 30 * time.Millisecond, pollTimeout, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checks, reads := 0, 0
+			stopped := func() bool {
+				checks++
+				return c.stopAt > 0 && checks >= c.stopAt
+			}
+			status := func() (string, error) {
+				s := c.statuses[min(reads, len(c.statuses)-1)]
+				reads++
+				if s == "!" {
+					return "", errRead
+				}
+				return s, nil
+			}
+
+			outcome, got := pollExecution(time.Millisecond, c.timeout, stopped, status)
+			if outcome != c.outcome || got != c.status {
+				t.Fatalf("pollExecution = (%d, %q), want (%d, %q)", outcome, got, c.outcome, c.status)
+			}
+		})
+	}
+}
+
+// fakePlanRunStore 记录收敛时的抢占请求
+type fakePlanRunStore struct {
+	runs      []*model.TExecution
+	listErr   error
+	failErr   error
+	unclaimed map[int64]bool // 模拟心跳已恢复或已被其他实例收敛
+
+	listBefore time.Time
+	failed     []int64
+	claimed    []int64
+}
+
+func (s *fakePlanRunStore) staleRuns(before time.Time) ([]*model.TExecution, error) {
+	s.listBefore = before
+	return s.runs, s.listErr
+}
+
+func (s *fakePlanRunStore) failRun(run *model.TExecution, staleBefore, now time.Time) (bool, error) {
+	if !staleBefore.Equal(s.listBefore) {
+		return false, errors.New("staleBefore mismatch")
+	}
+	s.failed = append(s.failed, run.ID)
+	if s.failErr != nil {
+		return false, s.failErr
+	}
+	if s.unclaimed[run.ID] {
+		return false, nil
+	}
+	s.claimed = append(s.claimed, run.ID)
+	return true, nil
+}
+
+// TestReconcilePlanRuns 跳过本实例仍在运行的计划，其余心跳超时的运行逐个抢占置为失败
+func TestReconcilePlanRuns(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	runs := []*model.TExecution{{ID: 101}, {ID: 102}, {ID: 103}}
+
+	t.Run("skips local runs", func(t *testing.T) {
+		_, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		planRuns.Store(int64(102), context.CancelFunc(cancel))
+		defer planRuns.Delete(int64(102))
+
+		store := &fakePlanRunStore{runs: runs, unclaimed: map[int64]bool{103: true}}
+		if err := reconcilePlanRuns(store, now); err != nil {
+			t.Fatalf("reconcilePlanRuns: %v", err)
+		}
+		if want := now.Add(-planRunStaleAfter); !store.listBefore.Equal(want) {
+			t.Fatalf("stale cutoff = %v, want %v", store.listBefore, want)
+		}
+		if want := []int64{101, 103}; !reflect.DeepEqual(store.failed, want) {
+			t.Fatalf("failRun called for %v, want %v", store.failed, want)
+		}
+		if want := []int64{101}; !reflect.DeepEqual(store.claimed, want) {
+			t.Fatalf("claimed %v, want %v", store.claimed, want)
+		}
+	})
+
+	t.Run("list error", func(t *testing.T) {
+		store := &fakePlanRunStore{runs: runs, listErr: errors.New("boom")}
+		if err := reconcilePlanRuns(store, now); err == nil || len(store.failed) != 0 {
+			t.Fatalf("err = %v, failed = %v", err, store.failed)
+		}
+	})
+
+	t.Run("fail error stops reconciliation", func(t *testing.T) {
+		store := &fakePlanRunStore{runs: runs, failErr: errors.New("boom")}
+		if err := reconcilePlanRuns(store, now); err == nil || !reflect.DeepEqual(store.failed, []int64{101}) {
+			t.Fatalf("err = %v, failed = %v", err, store.failed)
+		}
+	})
+}
@@ -9,6 +9,8 @@ import (
 	"yqhp/gulu/internal/query"
 	"yqhp/gulu/internal/svc"
 	"yqhp/gulu/internal/workflow"
+
+	"gorm.io/gorm"
 )
 
 // WorkflowLogic 工作流逻辑
@@ -38,8 +40,9 @@ type UpdateWorkflowReq struct {
 	Description    string `json:"description" validate:"max=500"`
 	Definition     string `json:"definition"` // JSON 格式
 	Status         int32  `json:"status"`
-	WorkflowType   string `json:"workflow_type"`   // normal, performance, data_generation
-	ExecutorConfig string `json:"executor_config"` // JSON: {"strategy":"auto|manual|local","executor_id":null,"labels":{}}
+	WorkflowType   string `json:"workflow_type"`              // normal, performance, data_generation
+	ExecutorConfig string `json:"executor_config"`            // JSON: {"strategy":"auto|manual|local","executor_id":null,"labels":{}}
+	Message        string `json:"message" validate:"max=500"` // 版本提交说明
 }
 
 // WorkflowListReq 工作流列表请求
@@ -97,16 +100,14 @@ func (l *WorkflowLogic) Create(req *CreateWorkflowReq, userID int64) (*model.TWo
 		ExecutorConfig: executorConfig,
 	}
 
-	q := query.Use(svc.Ctx.DB)
-	err := q.TWorkflow.WithContext(l.ctx).Create(wf)
-	if err != nil {
+	if err := l.createWithRevision(wf, "", userID); err != nil {
 		return nil, err
 	}
 
 	return wf, nil
 }
 
-// Update 更新工作流（版本号自动递增）
+// Update 更新工作流（名称、描述、定义、类型或执行机配置变更时版本号递增并生成新版本记录）
 func (l *WorkflowLogic) Update(id int64, req *UpdateWorkflowReq, userID int64) error {
 	q := query.Use(svc.Ctx.DB)
 	w := q.TWorkflow
@@ -123,6 +124,10 @@ func (l *WorkflowLogic) Update(id int64, req *UpdateWorkflowReq, userID int64) e
 			return err
 		}
 	}
+	// 验证工作流类型
+	if req.WorkflowType != "" && !model.WorkflowType(req.WorkflowType).IsValid() {
+		return errors.New("无效的工作流类型")
+	}
 
 	now := time.Now()
 	updates := map[string]interface{}{
@@ -130,49 +135,79 @@ func (l *WorkflowLogic) Update(id int64, req *UpdateWorkflowReq, userID int64) e
 		"updated_by": userID,
 	}
 
+	// 版本快照为更新后的完整内容，未传的字段沿用当前值
+	revision := &model.TWorkflowRevision{
+		CreatedAt:      &now,
+		CreatedBy:      &userID,
+		WorkflowID:     id,
+		Name:           wf.Name,
+		Description:    wf.Description,
+		Definition:     wf.Definition,
+		WorkflowType:   wf.WorkflowType,
+		ExecutorConfig: wf.ExecutorConfig,
+		Message:        req.Message,
+		Source:         string(model.RevisionSourceUpdate),
+	}
+	// 保存定义总是生成新版本；其他字段仅在值变化时生成
+	versioned := false
 	if req.Name != "" {
 		updates["name"] = req.Name
+		versioned = versioned || req.Name != wf.Name
+		revision.Name = req.Name
 	}
 	if req.Description != "" {
 		updates["description"] = req.Description
+		versioned = versioned || req.Description != derefString(wf.Description)
+		revision.Description = &req.Description
 	}
 	if req.Definition != "" {
 		updates["definition"] = req.Definition
-		// 版本号递增
-		newVersion := int32(1)
-		if wf.Version != nil {
-			newVersion = *wf.Version + 1
-		}
-		updates["version"] = newVersion
+		versioned = true
+		revision.Definition = req.Definition
 	}
 	if req.WorkflowType != "" {
-		// 验证工作流类型
-		if !model.WorkflowType(req.WorkflowType).IsValid() {
-			return errors.New("无效的工作流类型")
-		}
 		updates["workflow_type"] = req.WorkflowType
+		versioned = versioned || req.WorkflowType != derefString(wf.WorkflowType)
+		revision.WorkflowType = &req.WorkflowType
 	}
 	if req.ExecutorConfig != "" {
 		updates["executor_config"] = req.ExecutorConfig
+		versioned = versioned || req.ExecutorConfig != derefString(wf.ExecutorConfig)
+		revision.ExecutorConfig = &req.ExecutorConfig
+	}
+	if versioned {
+		// 版本号递增
+		newVersion := int32(1)
+		if wf.Version != nil {
+			newVersion = *wf.Version + 1
+		}
+		updates["version"] = newVersion
+		revision.Version = newVersion
+	} else {
+		revision = nil
 	}
 	updates["status"] = req.Status
 
-	_, err = w.WithContext(l.ctx).Where(w.ID.Eq(id)).Updates(updates)
-	if err != nil {
+	if err := l.saveWithRevision(wf, updates, revision); err != nil {
 		return err
 	}
 
 	// 如果名称被修改，同步更新关联的分类名称
 	if req.Name != "" && req.Name != wf.Name {
-		c := q.TCategoryWorkflow
-		_, _ = c.WithContext(l.ctx).
-			Where(c.SourceID.Eq(id), c.Type.Eq("workflow"), c.IsDelete.Is(false)).
-			Update(c.Name, req.Name)
+		l.syncCategoryName(id, req.Name)
 	}
 
 	return nil
 }
 
+// syncCategoryName 同步更新工作流关联的分类名称
+func (l *WorkflowLogic) syncCategoryName(id int64, name string) {
+	c := query.Use(svc.Ctx.DB).TCategoryWorkflow
+	_, _ = c.WithContext(l.ctx).
+		Where(c.SourceID.Eq(id), c.Type.Eq("workflow"), c.IsDelete.Is(false)).
+		Update(c.Name, name)
+}
+
 // Delete 删除工作流（软删除）
 func (l *WorkflowLogic) Delete(id int64) error {
 	q := query.Use(svc.Ctx.DB)
@@ -267,9 +302,7 @@ func (l *WorkflowLogic) Copy(id int64, newName string, userID int64) (*model.TWo
 		Status:      &status,
 	}
 
-	q := query.Use(svc.Ctx.DB)
-	err = q.TWorkflow.WithContext(l.ctx).Create(newWf)
-	if err != nil {
+	if err := l.createWithRevision(newWf, "复制自工作流 "+original.Name, userID); err != nil {
 		return nil, err
 	}
 
@@ -351,6 +384,55 @@ func (l *WorkflowLogic) UpdateStatus(id int64, status int32, userID int64) error
 	return err
 }
 
+// createWithRevision 创建工作流并写入初始版本
+func (l *WorkflowLogic) createWithRevision(wf *model.TWorkflow, message string, userID int64) error {
+	return svc.Ctx.DB.WithContext(l.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := query.Use(tx).TWorkflow.WithContext(l.ctx).Create(wf); err != nil {
+			return err
+		}
+		return tx.Create(&model.TWorkflowRevision{
+			CreatedAt:      wf.CreatedAt,
+			CreatedBy:      &userID,
+			WorkflowID:     wf.ID,
+			Version:        *wf.Version,
+			Name:           wf.Name,
+			Description:    wf.Description,
+			Definition:     wf.Definition,
+			WorkflowType:   wf.WorkflowType,
+			ExecutorConfig: wf.ExecutorConfig,
+			Message:        message,
+			Source:         string(model.RevisionSourceCreate),
+		}).Error
+	})
+}
+
+// saveWithRevision 更新工作流，revision 不为空时同时写入新版本；
+// 按读取时的版本号条件更新，并发保存时后提交的一方失败而不是覆盖他人修改
+func (l *WorkflowLogic) saveWithRevision(wf *model.TWorkflow, updates map[string]interface{}, revision *model.TWorkflowRevision) error {
+	return svc.Ctx.DB.WithContext(l.ctx).Transaction(func(tx *gorm.DB) error {
+		w := query.Use(tx).TWorkflow
+		do := w.WithContext(l.ctx).Where(w.ID.Eq(wf.ID))
+		if revision != nil {
+			if wf.Version != nil {
+				do = do.Where(w.Version.Eq(*wf.Version))
+			} else {
+				do = do.Where(w.Version.IsNull())
+			}
+		}
+		info, err := do.Updates(updates)
+		if err != nil {
+			return err
+		}
+		if revision == nil {
+			return nil
+		}
+		if info.RowsAffected == 0 {
+			return errors.New("工作流已被其他人修改，请刷新后重试")
+		}
+		return tx.Create(revision).Error
+	})
+}
+
 // validateDefinition 验证工作流定义
 func (l *WorkflowLogic) validateDefinition(definition string) error {
 	result, err := workflow.ValidateJSON(definition)
@@ -0,0 +1,229 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+	"yqhp/gulu/internal/workflow"
+
+	"gorm.io/gorm"
+)
+
+// WorkflowRevisionLogic 工作流版本逻辑
+type WorkflowRevisionLogic struct {
+	ctx context.Context
+}
+
+// NewWorkflowRevisionLogic 创建工作流版本逻辑
+func NewWorkflowRevisionLogic(ctx context.Context) *WorkflowRevisionLogic {
+	return &WorkflowRevisionLogic{ctx: ctx}
+}
+
+// WorkflowRevisionInfo 版本列表项（不含定义内容）
+type WorkflowRevisionInfo struct {
+	ID           int64      `json:"id"`
+	CreatedAt    *time.Time `json:"created_at"`
+	CreatedBy    *int64     `json:"created_by"`
+	WorkflowID   int64      `json:"workflow_id"`
+	Version      int32      `json:"version"`
+	Name         string     `json:"name"`
+	Message      string     `json:"message"`
+	Source       string     `json:"source"`
+	RollbackFrom *int32     `json:"rollback_from"`
+	Current      bool       `json:"current"` // 是否为工作流当前版本
+}
+
+// WorkflowRevisionDiff 两个版本之间的差异
+type WorkflowRevisionDiff struct {
+	WorkflowID int64                    `json:"workflow_id"`
+	From       int32                    `json:"from"`
+	To         int32                    `json:"to"`
+	Diff       *workflow.DefinitionDiff `json:"diff"`
+}
+
+// RollbackWorkflowReq 回滚请求
+type RollbackWorkflowReq struct {
+	Message string `json:"message" validate:"max=500"`
+}
+
+func (l *WorkflowRevisionLogic) db() *gorm.DB {
+	return svc.Ctx.DB.WithContext(l.ctx)
+}
+
+// List 获取工作流版本列表（按版本号倒序）
+func (l *WorkflowRevisionLogic) List(workflowID int64, page, pageSize int) ([]*WorkflowRevisionInfo, int64, error) {
+	wf, err := NewWorkflowLogic(l.ctx).GetByID(workflowID)
+	if err != nil {
+		return nil, 0, errors.New("工作流不存在")
+	}
+
+	db := l.db().Model(&model.TWorkflowRevision{}).Where("workflow_id = ?", workflowID)
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var revisions []*model.TWorkflowRevision
+	err = db.Omit("definition", "executor_config").Order("version DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&revisions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	list := make([]*WorkflowRevisionInfo, 0, len(revisions))
+	for _, r := range revisions {
+		list = append(list, &WorkflowRevisionInfo{
+			ID:           r.ID,
+			CreatedAt:    r.CreatedAt,
+			CreatedBy:    r.CreatedBy,
+			WorkflowID:   r.WorkflowID,
+			Version:      r.Version,
+			Name:         r.Name,
+			Message:      r.Message,
+			Source:       r.Source,
+			RollbackFrom: r.RollbackFrom,
+			Current:      wf.Version != nil && *wf.Version == r.Version,
+		})
+	}
+	return list, total, nil
+}
+
+// Get 获取指定版本详情（含定义内容）
+func (l *WorkflowRevisionLogic) Get(workflowID int64, version int32) (*model.TWorkflowRevision, error) {
+	var revision model.TWorkflowRevision
+	err := l.db().Where("workflow_id = ? AND version = ?", workflowID, version).First(&revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("版本 v%d 不存在", version)
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Diff 对比两个版本，to 为 0 时与工作流当前版本对比
+func (l *WorkflowRevisionLogic) Diff(workflowID int64, from, to int32) (*WorkflowRevisionDiff, error) {
+	if to <= 0 {
+		wf, err := NewWorkflowLogic(l.ctx).GetByID(workflowID)
+		if err != nil {
+			return nil, errors.New("工作流不存在")
+		}
+		if wf.Version != nil {
+			to = *wf.Version
+		}
+	}
+
+	oldRev, err := l.Get(workflowID, from)
+	if err != nil {
+		return nil, err
+	}
+	newRev, err := l.Get(workflowID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	oldDef, err := workflow.ParseJSON(oldRev.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("版本 v%d 定义解析失败: %v", from, err)
+	}
+	newDef, err := workflow.ParseJSON(newRev.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("版本 v%d 定义解析失败: %v", to, err)
+	}
+
+	diff := workflow.DiffDefinitions(oldDef, newDef)
+	if oldRev.Name != newRev.Name && oldDef.Name == newDef.Name {
+		diff.Fields = append(diff.Fields, workflow.FieldChange{Path: "name", Old: oldRev.Name, New: newRev.Name})
+	}
+	if v1, v2 := derefString(oldRev.Description), derefString(newRev.Description); v1 != v2 {
+		diff.Fields = append(diff.Fields, workflow.FieldChange{Path: "description", Old: v1, New: v2})
+	}
+	if v1, v2 := derefString(oldRev.WorkflowType), derefString(newRev.WorkflowType); v1 != v2 {
+		diff.Fields = append(diff.Fields, workflow.FieldChange{Path: "workflow_type", Old: v1, New: v2})
+	}
+	if v1, v2 := derefString(oldRev.ExecutorConfig), derefString(newRev.ExecutorConfig); v1 != v2 {
+		diff.Fields = append(diff.Fields, workflow.FieldChange{Path: "executor_config", Old: v1, New: v2})
+	}
+
+	return &WorkflowRevisionDiff{WorkflowID: workflowID, From: from, To: to, Diff: diff}, nil
+}
+
+// Rollback 回滚到指定版本：以该版本内容（名称、描述、定义、类型与执行机配置）生成一个新版本，历史版本保持不变
+func (l *WorkflowRevisionLogic) Rollback(workflowID int64, version int32, req *RollbackWorkflowReq, userID int64) (*model.TWorkflow, error) {
+	workflowLogic := NewWorkflowLogic(l.ctx)
+	wf, err := workflowLogic.GetByID(workflowID)
+	if err != nil {
+		return nil, errors.New("工作流不存在")
+	}
+	if wf.Version != nil && *wf.Version == version {
+		return nil, errors.New("该版本已是当前版本")
+	}
+
+	target, err := l.Get(workflowID, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := workflowLogic.validateDefinition(target.Definition); err != nil {
+		return nil, err
+	}
+
+	newVersion := int32(1)
+	if wf.Version != nil {
+		newVersion = *wf.Version + 1
+	}
+	message := req.Message
+	if message == "" {
+		message = fmt.Sprintf("回滚到 v%d", version)
+	}
+
+	// 升级前生成的版本没有描述快照，回滚时保留当前描述
+	description := target.Description
+	if description == nil {
+		description = wf.Description
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"updated_at":      now,
+		"updated_by":      userID,
+		"name":            target.Name,
+		"description":     description,
+		"definition":      target.Definition,
+		"version":         newVersion,
+		"workflow_type":   target.WorkflowType,
+		"executor_config": target.ExecutorConfig,
+	}
+	revision := &model.TWorkflowRevision{
+		CreatedAt:      &now,
+		CreatedBy:      &userID,
+		WorkflowID:     workflowID,
+		Version:        newVersion,
+		Name:           target.Name,
+		Description:    description,
+		Definition:     target.Definition,
+		WorkflowType:   target.WorkflowType,
+		ExecutorConfig: target.ExecutorConfig,
+		Message:        message,
+		Source:         string(model.RevisionSourceRollback),
+		RollbackFrom:   &version,
+	}
+	if err := workflowLogic.saveWithRevision(wf, updates, revision); err != nil {
+		return nil, err
+	}
+	if target.Name != wf.Name {
+		workflowLogic.syncCategoryName(workflowID, target.Name)
+	}
+
+	return workflowLogic.GetByID(workflowID)
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
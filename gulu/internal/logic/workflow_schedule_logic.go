@@ -0,0 +1,419 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"yqhp/common/scheduler"
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+
+	"gorm.io/gorm"
+)
+
+// WorkflowScheduleLogic 工作流定时计划逻辑
+type WorkflowScheduleLogic struct {
+	ctx context.Context
+}
+
+// NewWorkflowScheduleLogic 创建工作流定时计划逻辑
+func NewWorkflowScheduleLogic(ctx context.Context) *WorkflowScheduleLogic {
+	return &WorkflowScheduleLogic{ctx: ctx}
+}
+
+// CreateWorkflowScheduleReq 创建定时计划请求
+type CreateWorkflowScheduleReq struct {
+	ProjectID        int64                  `json:"project_id"`
+	WorkflowID       int64                  `json:"workflow_id"` // 与 plan_id 二选一
+	PlanID           int64                  `json:"plan_id"`     // 测试计划ID
+	EnvID            int64                  `json:"env_id"`      // 工作流必填；测试计划可选，覆盖计划默认环境
+	Name             string                 `json:"name" validate:"required,max=100"`
+	Description      string                 `json:"description" validate:"max=500"`
+	CronExpression   string                 `json:"cron_expression" validate:"required"` // 6 段（含秒）
+	Timezone         string                 `json:"timezone"`                            // IANA 时区，如 Asia/Shanghai，空表示服务器时区
+	ExecutorStrategy *ExecutorStrategy      `json:"executor_strategy"`                   // 仅工作流生效
+	Params           map[string]interface{} `json:"params"`                              // 执行参数，覆盖工作流变量
+	Status           *int32                 `json:"status"`                              // 默认启用
+}
+
+// UpdateWorkflowScheduleReq 更新定时计划请求
+type UpdateWorkflowScheduleReq struct {
+	EnvID            int64                  `json:"env_id"`
+	Name             string                 `json:"name" validate:"max=100"`
+	Description      *string                `json:"description" validate:"omitempty,max=500"`
+	CronExpression   string                 `json:"cron_expression"`
+	Timezone         *string                `json:"timezone"`
+	ExecutorStrategy *ExecutorStrategy      `json:"executor_strategy"`
+	Params           map[string]interface{} `json:"params"`
+}
+
+// WorkflowScheduleListReq 定时计划列表请求
+type WorkflowScheduleListReq struct {
+	Page       int    `query:"page" validate:"min=1"`
+	PageSize   int    `query:"pageSize" validate:"min=1,max=100"`
+	ProjectID  int64  `query:"projectId"`
+	WorkflowID int64  `query:"workflowId"`
+	PlanID     int64  `query:"planId"`
+	Name       string `query:"name"`
+	Status     *int32 `query:"status"`
+}
+
+// WorkflowScheduleInfo 定时计划返回信息
+type WorkflowScheduleInfo struct {
+	ID               int64                  `json:"id"`
+	CreatedAt        *time.Time             `json:"created_at"`
+	UpdatedAt        *time.Time             `json:"updated_at"`
+	CreatedBy        *int64                 `json:"created_by"`
+	ProjectID        int64                  `json:"project_id"`
+	WorkflowID       int64                  `json:"workflow_id"`
+	PlanID           int64                  `json:"plan_id"`
+	EnvID            int64                  `json:"env_id"`
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description"`
+	CronExpression   string                 `json:"cron_expression"`
+	Timezone         string                 `json:"timezone"`
+	ExecutorStrategy *ExecutorStrategy      `json:"executor_strategy"`
+	Params           map[string]interface{} `json:"params"`
+	Status           int32                  `json:"status"`
+	NextRunAt        *time.Time             `json:"next_run_at"`
+	LastRunAt        *time.Time             `json:"last_run_at"`
+	LastExecutionID  string                 `json:"last_execution_id"`
+	LastError        string                 `json:"last_error"`
+}
+
+func (l *WorkflowScheduleLogic) db() *gorm.DB {
+	return svc.Ctx.DB.WithContext(l.ctx)
+}
+
+// Create 创建定时计划
+func (l *WorkflowScheduleLogic) Create(req *CreateWorkflowScheduleReq, userID int64) (*WorkflowScheduleInfo, error) {
+	if err := validateScheduleSpec(req.CronExpression, req.Timezone); err != nil {
+		return nil, err
+	}
+	if (req.WorkflowID > 0) == (req.PlanID > 0) {
+		return nil, errors.New("必须且只能指定工作流或测试计划之一")
+	}
+	projectID, err := l.validateTarget(req.WorkflowID, req.PlanID, req.EnvID)
+	if err != nil {
+		return nil, err
+	}
+	if req.ProjectID > 0 && req.ProjectID != projectID {
+		return nil, errors.New("执行目标不属于当前项目")
+	}
+
+	strategyJSON, paramsJSON, err := marshalScheduleOptions(req.ExecutorStrategy, req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	status := model.ScheduleStatusEnabled
+	if req.Status != nil {
+		status = *req.Status
+	}
+	now := time.Now()
+	isDelete := false
+	schedule := &model.TWorkflowSchedule{
+		CreatedAt:        &now,
+		UpdatedAt:        &now,
+		IsDelete:         &isDelete,
+		CreatedBy:        &userID,
+		UpdatedBy:        &userID,
+		ProjectID:        projectID,
+		WorkflowID:       req.WorkflowID,
+		PlanID:           req.PlanID,
+		EnvID:            req.EnvID,
+		Name:             req.Name,
+		Description:      &req.Description,
+		CronExpression:   req.CronExpression,
+		Timezone:         req.Timezone,
+		ExecutorStrategy: strategyJSON,
+		Params:           paramsJSON,
+		Status:           &status,
+	}
+	if err := l.db().Create(schedule).Error; err != nil {
+		return nil, err
+	}
+
+	notifyScheduleChanged(schedule.ID)
+	return toWorkflowScheduleInfo(schedule), nil
+}
+
+// Update 更新定时计划
+func (l *WorkflowScheduleLogic) Update(id int64, req *UpdateWorkflowScheduleReq, userID int64) error {
+	schedule, err := l.get(id)
+	if err != nil {
+		return err
+	}
+
+	cronExpr, timezone := schedule.CronExpression, schedule.Timezone
+	if req.CronExpression != "" {
+		cronExpr = req.CronExpression
+	}
+	if req.Timezone != nil {
+		timezone = *req.Timezone
+	}
+	if err := validateScheduleSpec(cronExpr, timezone); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"updated_at":      time.Now(),
+		"updated_by":      userID,
+		"cron_expression": cronExpr,
+		"timezone":        timezone,
+	}
+	if req.EnvID > 0 && req.EnvID != schedule.EnvID {
+		if _, err := l.validateTarget(schedule.WorkflowID, schedule.PlanID, req.EnvID); err != nil {
+			return err
+		}
+		updates["env_id"] = req.EnvID
+	}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.ExecutorStrategy != nil || req.Params != nil {
+		strategyJSON, paramsJSON, err := marshalScheduleOptions(req.ExecutorStrategy, req.Params)
+		if err != nil {
+			return err
+		}
+		if req.ExecutorStrategy != nil {
+			updates["executor_strategy"] = strategyJSON
+		}
+		if req.Params != nil {
+			updates["params"] = paramsJSON
+		}
+	}
+
+	if err := l.db().Model(&model.TWorkflowSchedule{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	notifyScheduleChanged(id)
+	return nil
+}
+
+// Delete 删除定时计划（软删除）
+func (l *WorkflowScheduleLogic) Delete(id int64) error {
+	if _, err := l.get(id); err != nil {
+		return err
+	}
+	if err := l.db().Model(&model.TWorkflowSchedule{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"is_delete":  true,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	notifyScheduleChanged(id)
+	return nil
+}
+
+// GetByID 获取定时计划详情
+func (l *WorkflowScheduleLogic) GetByID(id int64) (*WorkflowScheduleInfo, error) {
+	schedule, err := l.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return toWorkflowScheduleInfo(schedule), nil
+}
+
+// List 获取定时计划列表
+func (l *WorkflowScheduleLogic) List(req *WorkflowScheduleListReq) ([]*WorkflowScheduleInfo, int64, error) {
+	q := l.db().Model(&model.TWorkflowSchedule{}).Where("is_delete = ?", false)
+	if req.ProjectID > 0 {
+		q = q.Where("project_id = ?", req.ProjectID)
+	}
+	if req.WorkflowID > 0 {
+		q = q.Where("workflow_id = ?", req.WorkflowID)
+	}
+	if req.PlanID > 0 {
+		q = q.Where("plan_id = ?", req.PlanID)
+	}
+	if req.Name != "" {
+		q = q.Where("name LIKE ?", "%"+req.Name+"%")
+	}
+	if req.Status != nil {
+		q = q.Where("status = ?", *req.Status)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	var list []*model.TWorkflowSchedule
+	offset := (req.Page - 1) * req.PageSize
+	if err := q.Order("id DESC").Offset(offset).Limit(req.PageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]*WorkflowScheduleInfo, 0, len(list))
+	for _, item := range list {
+		result = append(result, toWorkflowScheduleInfo(item))
+	}
+	return result, total, nil
+}
+
+// Pause 暂停定时计划
+func (l *WorkflowScheduleLogic) Pause(id int64) error {
+	return l.updateStatus(id, model.ScheduleStatusPaused)
+}
+
+// Resume 恢复定时计划，暂停期间错过的执行不会补偿
+func (l *WorkflowScheduleLogic) Resume(id int64) error {
+	return l.updateStatus(id, model.ScheduleStatusEnabled)
+}
+
+func (l *WorkflowScheduleLogic) updateStatus(id int64, status int32) error {
+	if _, err := l.get(id); err != nil {
+		return err
+	}
+	if err := l.db().Model(&model.TWorkflowSchedule{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     status,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	notifyScheduleChanged(id)
+	return nil
+}
+
+// RunNow 立即按计划配置执行一次，不影响定时调度
+func (l *WorkflowScheduleLogic) RunNow(id int64, userID int64) (*model.TExecution, error) {
+	schedule, err := l.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return runSchedule(l.ctx, schedule, model.TriggerTypeManual, userID)
+}
+
+func (l *WorkflowScheduleLogic) get(id int64) (*model.TWorkflowSchedule, error) {
+	var schedule model.TWorkflowSchedule
+	if err := l.db().Where("id = ? AND is_delete = ?", id, false).First(&schedule).Error; err != nil {
+		return nil, errors.New("定时计划不存在")
+	}
+	return &schedule, nil
+}
+
+// validateTarget 校验执行目标（工作流或测试计划）与环境存在且属于同一项目，返回项目ID
+// 测试计划的环境可为空，表示使用计划默认环境
+func (l *WorkflowScheduleLogic) validateTarget(workflowID, planID, envID int64) (int64, error) {
+	if planID > 0 {
+		plan, err := NewTestPlanLogic(l.ctx).get(planID)
+		if err != nil {
+			return 0, err
+		}
+		if envID > 0 {
+			if err := NewTestPlanLogic(l.ctx).validateEnv(plan.ProjectID, envID); err != nil {
+				return 0, err
+			}
+		}
+		return plan.ProjectID, nil
+	}
+
+	wf, err := NewWorkflowLogic(l.ctx).GetByID(workflowID)
+	if err != nil {
+		return 0, errors.New("工作流不存在")
+	}
+	if envID <= 0 {
+		return 0, errors.New("环境ID不能为空")
+	}
+	env, err := NewEnvLogic(l.ctx).GetByID(envID)
+	if err != nil {
+		return 0, errors.New("环境不存在")
+	}
+	if env.ProjectID != wf.ProjectID {
+		return 0, errors.New("环境与工作流不属于同一项目")
+	}
+	return wf.ProjectID, nil
+}
+
+// validateScheduleSpec 校验 cron 表达式与时区
+func validateScheduleSpec(cronExpr, timezone string) error {
+	if cronExpr == "" {
+		return errors.New("cron 表达式不能为空")
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return errors.New("无效的时区: " + timezone)
+		}
+	}
+	return scheduler.ValidateCron(scheduleSpec(cronExpr, timezone))
+}
+
+func marshalScheduleOptions(strategy *ExecutorStrategy, params map[string]interface{}) (*string, *string, error) {
+	var strategyJSON *string
+	if strategy != nil {
+		b, err := json.Marshal(strategy)
+		if err != nil {
+			return nil, nil, errors.New("执行机策略序列化失败")
+		}
+		s := string(b)
+		strategyJSON = &s
+	}
+	paramsJSON, err := marshalParams(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return strategyJSON, paramsJSON, nil
+}
+
+func unmarshalScheduleOptions(s *model.TWorkflowSchedule) (*ExecutorStrategy, map[string]interface{}) {
+	var strategy *ExecutorStrategy
+	if s.ExecutorStrategy != nil && *s.ExecutorStrategy != "" {
+		var v ExecutorStrategy
+		if err := json.Unmarshal([]byte(*s.ExecutorStrategy), &v); err == nil {
+			strategy = &v
+		}
+	}
+	return strategy, unmarshalParams(s.Params)
+}
+
+// toWorkflowScheduleInfo 转换为返回信息
+func toWorkflowScheduleInfo(s *model.TWorkflowSchedule) *WorkflowScheduleInfo {
+	info := &WorkflowScheduleInfo{
+		ID:             s.ID,
+		CreatedAt:      s.CreatedAt,
+		UpdatedAt:      s.UpdatedAt,
+		CreatedBy:      s.CreatedBy,
+		ProjectID:      s.ProjectID,
+		WorkflowID:     s.WorkflowID,
+		PlanID:         s.PlanID,
+		EnvID:          s.EnvID,
+		Name:           s.Name,
+		CronExpression: s.CronExpression,
+		Timezone:       s.Timezone,
+		LastRunAt:      s.LastRunAt,
+	}
+	info.ExecutorStrategy, info.Params = unmarshalScheduleOptions(s)
+
+	if s.Description != nil {
+		info.Description = *s.Description
+	}
+	if s.Status != nil {
+		info.Status = *s.Status
+	}
+	if s.LastExecutionID != nil {
+		info.LastExecutionID = *s.LastExecutionID
+	}
+	if s.LastError != nil {
+		info.LastError = *s.LastError
+	}
+	if info.Status == model.ScheduleStatusEnabled {
+		info.NextRunAt = nextScheduleRun(s)
+	}
+	return info
+}
@@ -0,0 +1,221 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"yqhp/common/logger"
+	"yqhp/common/scheduler"
+	"yqhp/gulu/internal/model"
+	"yqhp/gulu/internal/svc"
+
+	"go.uber.org/zap"
+)
+
+// scheduleSyncChannel 定时计划变更通知频道，多实例部署时各实例据此重新加载计划
+const scheduleSyncChannel = "gulu:workflow_schedule:sync"
+
+func scheduleJobName(id int64) string {
+	return fmt.Sprintf("workflow_schedule:%d", id)
+}
+
+// scheduleSpec 组装调度器使用的 cron 表达式，时区通过 CRON_TZ 前缀指定
+func scheduleSpec(cronExpr, timezone string) string {
+	if timezone == "" {
+		return cronExpr
+	}
+	return "CRON_TZ=" + timezone + " " + cronExpr
+}
+
+// LoadWorkflowSchedules 启动时加载所有启用的定时计划
+func LoadWorkflowSchedules() error {
+	var schedules []*model.TWorkflowSchedule
+	if err := svc.Ctx.DB.Where("is_delete = ? AND status = ?", false, model.ScheduleStatusEnabled).Find(&schedules).Error; err != nil {
+		return err
+	}
+
+	for _, s := range schedules {
+		if err := registerSchedule(s); err != nil {
+			logger.Warn("加载定时计划失败", zap.Int64("schedule_id", s.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// WatchScheduleChanges 订阅其他实例发出的计划变更通知，ctx 结束时退出
+func WatchScheduleChanges(ctx context.Context) {
+	if svc.Ctx.Redis == nil {
+		return
+	}
+
+	sub := svc.Ctx.Redis.Subscribe(ctx, scheduleSyncChannel)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			id, err := strconv.ParseInt(msg.Payload, 10, 64)
+			if err != nil {
+				continue
+			}
+			reloadSchedule(id)
+		}
+	}
+}
+
+// notifyScheduleChanged 计划变更后重新加载本实例，并通知其他实例
+func notifyScheduleChanged(id int64) {
+	reloadSchedule(id)
+
+	if svc.Ctx.Redis == nil {
+		return
+	}
+	if err := svc.Ctx.Redis.Publish(context.Background(), scheduleSyncChannel, strconv.FormatInt(id, 10)).Err(); err != nil {
+		logger.Warn("发布定时计划变更通知失败", zap.Int64("schedule_id", id), zap.Error(err))
+	}
+}
+
+// reloadSchedule 按数据库最新状态注册、更新或移除计划
+func reloadSchedule(id int64) {
+	var s model.TWorkflowSchedule
+	err := svc.Ctx.DB.Where("id = ? AND is_delete = ?", id, false).First(&s).Error
+	if err != nil || s.Status == nil || *s.Status != model.ScheduleStatusEnabled {
+		unregisterSchedule(id)
+		return
+	}
+	if err := registerSchedule(&s); err != nil {
+		logger.Warn("更新定时计划失败", zap.Int64("schedule_id", id), zap.Error(err))
+	}
+}
+
+// registerSchedule 将计划注册到调度器，已存在时更新 cron
+func registerSchedule(s *model.TWorkflowSchedule) error {
+	sched := GetScheduler()
+	if sched == nil {
+		return nil
+	}
+
+	name := scheduleJobName(s.ID)
+	spec := scheduleSpec(s.CronExpression, s.Timezone)
+	if sched.HasJob(name) {
+		return sched.UpdateCron(name, spec)
+	}
+	return sched.AddDynamic(name, spec, scheduleJobFunc(s.ID), scheduler.WithConcurrency(scheduler.ConcurrencyForbid))
+}
+
+func unregisterSchedule(id int64) {
+	name := scheduleJobName(id)
+	if sched := GetScheduler(); sched != nil && sched.HasJob(name) {
+		if err := sched.Remove(name); err != nil {
+			logger.Warn("移除定时计划失败", zap.Int64("schedule_id", id), zap.Error(err))
+		}
+	}
+}
+
+// scheduleJobFunc 定时触发入口
+// 触发时重新读取计划：已暂停/删除或本实例的 cron 已过期时跳过并重新加载；
+// 通过 last_fire_at 条件更新抢占本次计划时间，Redis 锁之外再保证每个触发点只执行一次
+func scheduleJobFunc(id int64) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var s model.TWorkflowSchedule
+		err := svc.Ctx.DB.WithContext(ctx).Where("id = ? AND is_delete = ?", id, false).First(&s).Error
+		if err != nil || s.Status == nil || *s.Status != model.ScheduleStatusEnabled {
+			reloadSchedule(id)
+			return nil
+		}
+
+		spec := scheduleSpec(s.CronExpression, s.Timezone)
+		if job, err := GetScheduler().GetJob(scheduleJobName(id)); err != nil || job.CronExpr != spec {
+			reloadSchedule(id)
+			return nil
+		}
+
+		fireAt := scheduledFireTime(spec, time.Now())
+		claimed := svc.Ctx.DB.WithContext(ctx).Model(&model.TWorkflowSchedule{}).
+			Where("id = ? AND (last_fire_at IS NULL OR last_fire_at < ?)", id, fireAt).
+			Update("last_fire_at", fireAt)
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			return nil
+		}
+
+		userID := int64(0)
+		if s.UpdatedBy != nil {
+			userID = *s.UpdatedBy
+		} else if s.CreatedBy != nil {
+			userID = *s.CreatedBy
+		}
+		// 执行记录的生命周期不应受调度上下文约束
+		_, err = runSchedule(context.Background(), &s, model.TriggerTypeSchedule, userID)
+		return err
+	}
+}
+
+// scheduledFireTime 计算当前触发对应的计划时间点，各实例据此得到相同的抢占键
+func scheduledFireTime(spec string, now time.Time) time.Time {
+	runs, err := scheduler.MissedRuns(spec, now.Add(-time.Minute), now, 0)
+	if err != nil || len(runs) == 0 {
+		return now.Truncate(time.Second)
+	}
+	return runs[len(runs)-1]
+}
+
+// runSchedule 按计划配置执行一次工作流或测试计划，并记录最近执行结果
+func runSchedule(ctx context.Context, s *model.TWorkflowSchedule, triggerType model.TriggerType, userID int64) (*model.TExecution, error) {
+	strategy, params := unmarshalScheduleOptions(s)
+
+	var (
+		execution *model.TExecution
+		runErr    error
+	)
+	if s.PlanID > 0 {
+		execution, runErr = NewTestPlanLogic(ctx).Run(s.PlanID, &RunTestPlanReq{EnvID: s.EnvID, Params: params},
+			userID, triggerType, s.ID)
+	} else {
+		execution, runErr = NewExecutionLogic(ctx).Execute(&ExecuteWorkflowReq{
+			WorkflowID:       s.WorkflowID,
+			EnvID:            s.EnvID,
+			Mode:             defaultExecutionMode(ctx, s.WorkflowID),
+			ExecutorStrategy: strategy,
+			Variables:        params,
+			TriggerType:      string(triggerType),
+			ScheduleID:       s.ID,
+		}, userID)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"last_run_at": now}
+	if runErr != nil {
+		updates["last_error"] = runErr.Error()
+		logger.Warn("定时计划执行失败", zap.Int64("schedule_id", s.ID), zap.String("trigger", string(triggerType)), zap.Error(runErr))
+	} else {
+		updates["last_error"] = ""
+		updates["last_execution_id"] = execution.ExecutionID
+	}
+	if err := svc.Ctx.DB.Model(&model.TWorkflowSchedule{}).Where("id = ?", s.ID).Updates(updates).Error; err != nil {
+		logger.Error("更新定时计划执行状态失败", zap.Int64("schedule_id", s.ID), zap.Error(err))
+	}
+	return execution, runErr
+}
+
+// nextScheduleRun 获取本实例调度器中的下次触发时间
+func nextScheduleRun(s *model.TWorkflowSchedule) *time.Time {
+	sched := GetScheduler()
+	if sched == nil {
+		return nil
+	}
+	job, err := sched.GetJob(scheduleJobName(s.ID))
+	if err != nil || job.NextRun.IsZero() {
+		return nil
+	}
+	return &job.NextRun
+}
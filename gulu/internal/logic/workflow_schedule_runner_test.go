@@ -0,0 +1,205 @@
+package logic
+
+import (
+	"testing"
+	"time"
+
+	"yqhp/common/scheduler"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("时区数据不可用: %v", err)
+	}
+	return loc
+}
+
+// TestScheduleSpec 时区通过 CRON_TZ 前缀拼接，空时区保持原表达式
+func TestScheduleSpec(t *testing.T) {
+	cases := []struct {
+		cron, tz, want string
+	}{
+		{"0 0 9 * * *", "", "0 0 9 * * *"},
+		{"0 0 9 * * *", "Asia/Shanghai", "CRON_TZ=Asia/Shanghai 0 0 9 * * *"},
+		{"@daily", "America/New_York", "CRON_TZ=America/New_York @daily"},
+	}
+	for _, c := range cases {
+		if got := scheduleSpec(c.cron, c.tz); got != c.want {
+			t.Errorf("scheduleSpec(%q, %q) = %q, want %q", c.cron, c.tz, got, c.want)
+		}
+	}
+}
+
+// TestValidateScheduleSpec cron 为空、时区无效或表达式无效时拒绝
+func TestValidateScheduleSpec(t *testing.T) {
+	cases := []struct {
+		cron, tz string
+		ok       bool
+	}{
+		{"0 0 9 * * *", "", true},
+		{"0 0 9 * * *", "Asia/Shanghai", true},
+		{"0 30 2 * * *", "America/New_York", true},
+		{"", "Asia/Shanghai", false},
+		{"0 0 9 * * *", "Mars/Olympus", false},
+		{"0 9 * * *", "Asia/Shanghai", false},
+	}
+	for _, c := range cases {
+		err := validateScheduleSpec(c.cron, c.tz)
+		if (err == nil) != c.ok {
+			t.Errorf("validateScheduleSpec(%q, %q) = %v, want ok=%v", c.cron, c.tz, err, c.ok)
+		}
+	}
+}
+
+// TestScheduleSpecTimezone 触发时间按计划时区的本地时间计算，跨夏令时切换后本地时间不变
+func TestScheduleSpecTimezone(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	mustLoadLocation(t, "Asia/Shanghai")
+
+	utc := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		name  string
+		spec  string
+		since time.Time
+		until time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "Asia/Shanghai 每日 9 点",
+			spec:  scheduleSpec("0 0 9 * * *", "Asia/Shanghai"),
+			since: utc(5, 1, 0, 0),
+			until: utc(5, 3, 0, 0),
+			want:  []time.Time{utc(5, 1, 1, 0), utc(5, 2, 1, 0)},
+		},
+		{
+			name:  "夏令时开始前后的每日 9 点",
+			spec:  scheduleSpec("0 0 9 * * *", "America/New_York"),
+			since: time.Date(2026, 3, 7, 0, 0, 0, 0, newYork),
+			until: time.Date(2026, 3, 9, 0, 0, 0, 0, newYork),
+			want:  []time.Time{utc(3, 7, 14, 0), utc(3, 8, 13, 0)},
+		},
+		{
+			name:  "夏令时结束前后的每日 9 点",
+			spec:  scheduleSpec("0 0 9 * * *", "America/New_York"),
+			since: time.Date(2026, 10, 31, 0, 0, 0, 0, newYork),
+			until: time.Date(2026, 11, 2, 0, 0, 0, 0, newYork),
+			want:  []time.Time{utc(10, 31, 13, 0), utc(11, 1, 14, 0)},
+		},
+		{
+			// 跳过的本地时间（02:00-03:00 不存在）当天不触发
+			name:  "夏令时开始时不存在的本地时间",
+			spec:  scheduleSpec("0 30 2 * * *", "America/New_York"),
+			since: time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			until: time.Date(2026, 3, 8, 23, 0, 0, 0, newYork),
+			want:  nil,
+		},
+		{
+			// 重复的本地时间（01:00-02:00 出现两次）每次出现各触发一次
+			name:  "夏令时结束时重复的本地时间",
+			spec:  scheduleSpec("0 30 1 * * *", "America/New_York"),
+			since: time.Date(2026, 11, 1, 0, 0, 0, 0, newYork),
+			until: time.Date(2026, 11, 1, 12, 0, 0, 0, newYork),
+			want:  []time.Time{utc(11, 1, 5, 30), utc(11, 1, 6, 30)},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := scheduler.MissedRuns(c.spec, c.since, c.until, 0)
+			if err != nil {
+				t.Fatalf("MissedRuns: %v", err)
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("runs = %v, want %v", got, c.want)
+			}
+			for i := range got {
+				if !got[i].Equal(c.want[i]) {
+					t.Fatalf("run %d = %v, want %v", i, got[i].UTC(), c.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestScheduledFireTime 同一触发点在各实例上得到相同的抢占键，不同触发点（包括夏令时结束时重复的本地时间）得到不同的键
+func TestScheduledFireTime(t *testing.T) {
+	mustLoadLocation(t, "America/New_York")
+	mustLoadLocation(t, "Asia/Shanghai")
+
+	at := func(month time.Month, day, hour, min, sec int, nsec int) time.Time {
+		return time.Date(2026, month, day, hour, min, sec, nsec, time.UTC)
+	}
+	everyFive := scheduleSpec("0 */5 * * * *", "")
+	hourlyNY := scheduleSpec("0 0 * * * *", "America/New_York")
+	dailyNY := scheduleSpec("0 30 1 * * *", "America/New_York")
+	dailySH := scheduleSpec("0 0 9 * * *", "Asia/Shanghai")
+
+	cases := []struct {
+		name string
+		spec string
+		nows []time.Time // 各实例观察到的触发时刻，应得到同一个键
+		want time.Time
+	}{
+		{
+			name: "各实例触发延迟不同",
+			spec: everyFive,
+			nows: []time.Time{at(5, 1, 10, 5, 0, 0), at(5, 1, 10, 5, 0, 150e6), at(5, 1, 10, 5, 3, 0), at(5, 1, 10, 5, 59, 0)},
+			want: at(5, 1, 10, 5, 0, 0),
+		},
+		{
+			name: "带时区的每日计划",
+			spec: dailySH,
+			nows: []time.Time{at(5, 1, 1, 0, 0, 2e6), at(5, 1, 1, 0, 20, 0)},
+			want: at(5, 1, 1, 0, 0, 0),
+		},
+		{
+			name: "夏令时开始后的第一个整点",
+			spec: hourlyNY,
+			nows: []time.Time{at(3, 8, 7, 0, 0, 5e6), at(3, 8, 7, 0, 30, 0)},
+			want: at(3, 8, 7, 0, 0, 0),
+		},
+		{
+			name: "夏令时结束时第一次 01:30（EDT）",
+			spec: dailyNY,
+			nows: []time.Time{at(11, 1, 5, 30, 0, 1e6), at(11, 1, 5, 30, 10, 0)},
+			want: at(11, 1, 5, 30, 0, 0),
+		},
+		{
+			name: "夏令时结束时第二次 01:30（EST）",
+			spec: dailyNY,
+			nows: []time.Time{at(11, 1, 6, 30, 0, 1e6), at(11, 1, 6, 30, 10, 0)},
+			want: at(11, 1, 6, 30, 0, 0),
+		},
+		{
+			name: "一分钟内没有计划时间时退化为当前秒",
+			spec: dailySH,
+			nows: []time.Time{at(5, 1, 1, 5, 7, 900e6)},
+			want: at(5, 1, 1, 5, 7, 0),
+		},
+		{
+			name: "表达式无效时退化为当前秒",
+			spec: "invalid",
+			nows: []time.Time{at(5, 1, 1, 5, 7, 900e6)},
+			want: at(5, 1, 1, 5, 7, 0),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, now := range c.nows {
+				if got := scheduledFireTime(c.spec, now); !got.Equal(c.want) {
+					t.Fatalf("scheduledFireTime(%q, %v) = %v, want %v", c.spec, now, got.UTC(), c.want)
+				}
+			}
+		})
+	}
+
+	// 夏令时结束时两次 01:30 的抢占键不同，两次触发都能抢占成功且各只执行一次
+	first := scheduledFireTime(dailyNY, at(11, 1, 5, 30, 1, 0))
+	second := scheduledFireTime(dailyNY, at(11, 1, 6, 30, 1, 0))
+	if !first.Before(second) {
+		t.Fatalf("repeated local time claim keys %v, %v should be distinct and increasing", first, second)
+	}
+}
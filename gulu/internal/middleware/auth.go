@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"strconv"
 	"strings"
 
@@ -9,6 +10,7 @@ import (
 	"yqhp/gulu/internal/client"
 	"yqhp/gulu/internal/ctxutil"
 	"yqhp/gulu/internal/svc"
+	"yqhp/workflow-engine/pkg/types"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -51,6 +53,18 @@ func AuthMiddleware() fiber.Handler {
 	}
 }
 
+// InternalAuthMiddleware 内部接口认证中间件，校验 workflow-engine 回调时携带的共享令牌
+func InternalAuthMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		expected := svc.Ctx.Config.Gulu.InternalToken
+		token := c.Get(types.GuluInternalTokenHeader)
+		if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			return response.Unauthorized(c, "内部接口令牌无效")
+		}
+		return c.Next()
+	}
+}
+
 // PermissionMiddleware 权限验证中间件（通过 Admin API 验证）
 func PermissionMiddleware(permissions ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -47,9 +47,53 @@ const (
 	ExecutionStatusFailed    ExecutionStatus = "failed"    // 已失败
 	ExecutionStatusStopped   ExecutionStatus = "stopped"   // 已停止
 	ExecutionStatusPaused    ExecutionStatus = "paused"    // 已暂停
+	ExecutionStatusSkipped   ExecutionStatus = "skipped"   // 已跳过（测试计划失败即停）
 )
 
 // IsTerminal 判断是否是终态
 func (s ExecutionStatus) IsTerminal() bool {
 	return s == ExecutionStatusCompleted || s == ExecutionStatusFailed || s == ExecutionStatusStopped
 }
+
+// TriggerType 执行触发方式
+type TriggerType string
+
+const (
+	TriggerTypeManual   TriggerType = "manual"    // 手动触发（界面/接口）
+	TriggerTypeSchedule TriggerType = "schedule"  // 定时计划触发
+	TriggerTypeTestPlan TriggerType = "test_plan" // 测试计划触发
+)
+
+// 定时计划状态
+const (
+	ScheduleStatusPaused  int32 = 0 // 已暂停
+	ScheduleStatusEnabled int32 = 1 // 已启用
+)
+
+// TestPlanRunMode 测试计划运行方式
+type TestPlanRunMode string
+
+const (
+	TestPlanRunModeSerial   TestPlanRunMode = "serial"   // 串行：按顺序逐个执行
+	TestPlanRunModeParallel TestPlanRunMode = "parallel" // 并行：同时执行（受最大并发限制）
+	TestPlanRunModeStage    TestPlanRunMode = "stage"    // 分阶段：阶段间串行，阶段内并行
+)
+
+// IsValid 验证运行方式是否有效
+func (m TestPlanRunMode) IsValid() bool {
+	switch m {
+	case TestPlanRunModeSerial, TestPlanRunModeParallel, TestPlanRunModeStage:
+		return true
+	default:
+		return false
+	}
+}
+
+// RevisionSource 工作流版本来源
+type RevisionSource string
+
+const (
+	RevisionSourceCreate   RevisionSource = "create"   // 创建（含复制、导入）
+	RevisionSourceUpdate   RevisionSource = "update"   // 编辑保存
+	RevisionSourceRollback RevisionSource = "rollback" // 回滚到历史版本
+)
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const TableNameTAiBudget = "t_ai_budget"
+
+// AI 预算周期
+const (
+	AiBudgetPeriodDaily   = "daily"
+	AiBudgetPeriodMonthly = "monthly"
+)
+
+// TAiBudget 项目 AI 费用预算表，每个项目一条
+type TAiBudget struct {
+	ID          int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt   *time.Time `gorm:"column:created_at;type:datetime" json:"created_at"`
+	UpdatedAt   *time.Time `gorm:"column:updated_at;type:datetime" json:"updated_at"`
+	UpdatedBy   *int64     `gorm:"column:updated_by;type:bigint unsigned" json:"updated_by"`
+	ProjectID   int64      `gorm:"column:project_id;type:bigint unsigned;not null;uniqueIndex:uk_t_ai_budget_project_id" json:"project_id"`
+	Period      string     `gorm:"column:period;type:varchar(20);not null;default:'monthly'" json:"period"`
+	SoftLimit   float64    `gorm:"column:soft_limit;type:decimal(16,6);not null;default:0" json:"soft_limit"`
+	HardLimit   float64    `gorm:"column:hard_limit;type:decimal(16,6);not null;default:0" json:"hard_limit"`
+	PeriodStart *time.Time `gorm:"column:period_start;type:datetime" json:"period_start"`           // Spent 对应的预算周期起点
+	Spent       float64    `gorm:"column:spent;type:decimal(16,6);not null;default:0" json:"spent"` // 本周期已用金额（含调用中的预留），在行锁内累加
+	Status      *int32     `gorm:"column:status;type:tinyint;default:1" json:"status"`
+}
+
+func (*TAiBudget) TableName() string {
+	return TableNameTAiBudget
+}
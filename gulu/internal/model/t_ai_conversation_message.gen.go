@@ -14,6 +14,7 @@ const TableNameTAiConversationMessage = "t_ai_conversation_message"
 type TAiConversationMessage struct {
 	ID             int64      `gorm:"column:id;type:bigint unsigned;primaryKey;autoIncrement:true" json:"id"`
 	ConversationID int64      `gorm:"column:conversation_id;type:bigint unsigned;not null;index:idx_ai_conv_msg_conv_id,priority:1;comment:关联的会话ID" json:"conversation_id"`
+	ParentID       *int64     `gorm:"column:parent_id;type:bigint unsigned;index:idx_ai_conv_msg_parent_id,priority:1;comment:父消息ID，为空表示首条消息" json:"parent_id"`
 	Role           string     `gorm:"column:role;type:varchar(20);not null;comment:消息角色: user/assistant/system" json:"role"`
 	Content        string     `gorm:"column:content;type:longtext;not null;comment:消息内容" json:"content"`
 	Metadata       *string    `gorm:"column:metadata;type:json;comment:元信息" json:"metadata"`
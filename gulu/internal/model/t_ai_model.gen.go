@@ -27,6 +27,9 @@ type TAiModel struct {
 	ParamSize      *string    `gorm:"column:param_size;type:varchar(50);comment:参数量" json:"param_size"`
 	CapabilityTags *string    `gorm:"column:capability_tags;type:json;comment:能力标签" json:"capability_tags"`
 	CustomTags     *string    `gorm:"column:custom_tags;type:json;comment:自定义标签" json:"custom_tags"`
+	InputPrice     *float64   `gorm:"column:input_price;type:decimal(12,6);comment:输入单价(每百万Token)" json:"input_price"`
+	OutputPrice    *float64   `gorm:"column:output_price;type:decimal(12,6);comment:输出单价(每百万Token)" json:"output_price"`
+	CachedPrice    *float64   `gorm:"column:cached_price;type:decimal(12,6);comment:缓存命中输入单价(每百万Token)" json:"cached_price"`
 	Sort           *int32     `gorm:"column:sort;type:int;comment:排序" json:"sort"`
 	Status         *int32     `gorm:"column:status;type:tinyint;default:1;index:idx_t_ai_model_status,priority:1;comment:状态: 1-启用 0-禁用" json:"status"`
 }
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+const TableNameTAiSandboxPolicy = "t_ai_sandbox_policy"
+
+// TAiSandboxPolicy 项目 AI 工具沙箱策略表，每个项目一条
+type TAiSandboxPolicy struct {
+	ID        int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt *time.Time `gorm:"column:created_at;type:datetime" json:"created_at"`
+	UpdatedAt *time.Time `gorm:"column:updated_at;type:datetime" json:"updated_at"`
+	UpdatedBy *int64     `gorm:"column:updated_by;type:bigint unsigned" json:"updated_by"`
+	ProjectID int64      `gorm:"column:project_id;type:bigint unsigned;not null;uniqueIndex:uk_t_ai_sandbox_policy_project_id" json:"project_id"`
+	Policy    string     `gorm:"column:policy;type:text;not null" json:"policy"` // JSON 格式的沙箱策略
+}
+
+func (*TAiSandboxPolicy) TableName() string {
+	return TableNameTAiSandboxPolicy
+}
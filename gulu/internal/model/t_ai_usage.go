@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+const TableNameTAiUsage = "t_ai_usage"
+
+// AI 用量来源
+const (
+	AiUsageSourceWorkflow = "workflow" // 工作流 AI 步骤
+	AiUsageSourceChat     = "chat"     // 模型对话
+)
+
+// TAiUsage AI 用量记录表，每个 AI 步骤或每次模型对话一条
+type TAiUsage struct {
+	ID               int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt        *time.Time `gorm:"column:created_at;type:datetime;index:idx_t_ai_usage_project_time,priority:2" json:"created_at"`
+	ProjectID        int64      `gorm:"column:project_id;type:bigint unsigned;not null;default:0;index:idx_t_ai_usage_project_time,priority:1" json:"project_id"`
+	UserID           int64      `gorm:"column:user_id;type:bigint unsigned;not null;default:0;index:idx_t_ai_usage_user_id" json:"user_id"`
+	Source           string     `gorm:"column:source;type:varchar(20);not null" json:"source"`
+	ExecutionID      string     `gorm:"column:execution_id;type:varchar(100);not null;default:'';index:idx_t_ai_usage_execution_id" json:"execution_id"`
+	StepID           string     `gorm:"column:step_id;type:varchar(100);not null;default:''" json:"step_id"`
+	ConversationID   string     `gorm:"column:conversation_id;type:varchar(100);not null;default:''" json:"conversation_id"`
+	AiModelID        int64      `gorm:"column:ai_model_id;type:bigint unsigned;not null;default:0" json:"ai_model_id"`
+	Model            string     `gorm:"column:model;type:varchar(200);not null;default:''" json:"model"`
+	PromptTokens     int64      `gorm:"column:prompt_tokens;type:bigint;not null;default:0" json:"prompt_tokens"`
+	CachedTokens     int64      `gorm:"column:cached_tokens;type:bigint;not null;default:0" json:"cached_tokens"`
+	CompletionTokens int64      `gorm:"column:completion_tokens;type:bigint;not null;default:0" json:"completion_tokens"`
+	TotalTokens      int64      `gorm:"column:total_tokens;type:bigint;not null;default:0" json:"total_tokens"`
+	Cost             float64    `gorm:"column:cost;type:decimal(16,6);not null;default:0" json:"cost"`
+}
+
+func (*TAiUsage) TableName() string {
+	return TableNameTAiUsage
+}
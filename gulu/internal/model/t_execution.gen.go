@@ -28,6 +28,10 @@ type TExecution struct {
 	EndTime       *time.Time `gorm:"column:end_time;type:datetime;comment:结束时间" json:"end_time"`
 	Duration      *int64     `gorm:"column:duration;type:bigint;comment:执行时长(毫秒)" json:"duration"`
 	CreatedBy     *int64     `gorm:"column:created_by;type:bigint unsigned;comment:创建人ID" json:"created_by"`
+	TriggerType   string     `gorm:"column:trigger_type;type:varchar(20);not null;index:idx_t_execution_trigger_type,priority:1;default:manual;comment:触发方式: manual, schedule" json:"trigger_type"`
+	ScheduleID    *int64     `gorm:"column:schedule_id;type:bigint unsigned;index:idx_t_execution_schedule_id,priority:1;comment:触发的定时计划ID" json:"schedule_id"`
+	WorkflowVersion *int32   `gorm:"column:workflow_version;type:int;comment:执行时的工作流版本号" json:"workflow_version"`
+	Snapshot      *string    `gorm:"column:snapshot;type:longtext;comment:执行快照(工作流定义与选中步骤, 用于从失败步骤恢复)" json:"-"`
 }
 
 // TableName TExecution's table name
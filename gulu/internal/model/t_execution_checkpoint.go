@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+const TableNameTExecutionCheckpoint = "t_execution_checkpoint"
+
+// TExecutionCheckpoint 执行检查点表，每个顶层步骤完成后一条，用于从失败步骤恢复执行
+type TExecutionCheckpoint struct {
+	ID          int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt   *time.Time `gorm:"column:created_at;type:datetime" json:"created_at"`
+	ExecutionID string     `gorm:"column:execution_id;type:varchar(100);not null;uniqueIndex:uk_t_execution_checkpoint_step,priority:1" json:"execution_id"`
+	StepIndex   int32      `gorm:"column:step_index;type:int;not null;uniqueIndex:uk_t_execution_checkpoint_step,priority:2" json:"step_index"`
+	StepID      string     `gorm:"column:step_id;type:varchar(100);not null" json:"step_id"`
+	Status      string     `gorm:"column:status;type:varchar(20);not null" json:"status"`
+	Variables   *string    `gorm:"column:variables;type:longtext" json:"variables"`
+	Results     *string    `gorm:"column:results;type:longtext" json:"results"`
+}
+
+func (*TExecutionCheckpoint) TableName() string {
+	return TableNameTExecutionCheckpoint
+}
@@ -19,6 +19,10 @@ type KBConfig struct {
 	RetrievalMode       string  `json:"retrieval_mode"`
 	RerankEnabled       bool    `json:"rerank_enabled"`
 	RerankModelID       *int64  `json:"rerank_model_id,omitempty"`
+	// 混合检索融合参数：rrf（倒数排名融合）/ weighted（加权分数融合）
+	FusionMethod string  `json:"fusion_method,omitempty"`
+	RRFK         int     `json:"rrf_k,omitempty"`         // RRF 平滑常数
+	VectorWeight float64 `json:"vector_weight,omitempty"` // 向量检索权重（0–1），其余权重分给关键词/图谱检索
 	// 向量维度（自动检测后写回的缓存，不需要用户配置）
 	EmbeddingDimension  int `json:"embedding_dimension,omitempty"`
 	MultimodalDimension int `json:"multimodal_dimension,omitempty"`
@@ -32,6 +36,9 @@ func DefaultKBConfig() KBConfig {
 		SimilarityThreshold: 0.3,
 		TopK:                5,
 		RetrievalMode:       "vector",
+		FusionMethod:        "rrf",
+		RRFK:                60,
+		VectorWeight:        0.7,
 	}
 }
 
@@ -54,10 +61,14 @@ type TKnowledgeBase struct {
 	MultimodalModelID   *int64 `gorm:"column:multimodal_model_id" json:"multimodal_model_id"`
 	GraphExtractModelID *int64 `gorm:"column:graph_extract_model_id" json:"graph_extract_model_id"`
 
-	// 向量库 / 图库
+	// 向量库 / 图库（qdrant_collection 为历史列名，存放任意向量库后端的集合名）
+	VectorStore      string  `gorm:"column:vector_store;type:varchar(20);not null;default:qdrant" json:"vector_store"`
 	QdrantCollection *string `gorm:"column:qdrant_collection;type:varchar(100)" json:"qdrant_collection"`
 	Neo4jDatabase    *string `gorm:"column:neo4j_database;type:varchar(100)" json:"neo4j_database"`
 
+	// 向量库迁移租约到期时间，迁移期间暂停该知识库的入库与向量写入
+	VectorMigratingUntil *time.Time `gorm:"column:vector_migrating_until" json:"vector_migrating_until"`
+
 	// 配置 JSON（分块 + 检索参数 + 维度缓存）
 	ConfigJSON *string `gorm:"column:config;type:json" json:"-"`
 }
@@ -92,6 +103,15 @@ func (m *TKnowledgeBase) GetConfig() KBConfig {
 	if cfg.RetrievalMode == "" {
 		cfg.RetrievalMode = defaults.RetrievalMode
 	}
+	if cfg.FusionMethod == "" {
+		cfg.FusionMethod = defaults.FusionMethod
+	}
+	if cfg.RRFK == 0 {
+		cfg.RRFK = defaults.RRFK
+	}
+	if cfg.VectorWeight == 0 {
+		cfg.VectorWeight = defaults.VectorWeight
+	}
 	return cfg
 }
 
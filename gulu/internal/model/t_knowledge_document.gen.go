@@ -9,6 +9,16 @@ import (
 
 const TableNameTKnowledgeDocument = "t_knowledge_document"
 
+// 分块策略
+const (
+	ChunkStrategyGeneral     = "general"      // 按分隔符 / 字符数切分（默认）
+	ChunkStrategyAuto        = "auto"         // 按文件类型自动选择结构化策略
+	ChunkStrategyMarkdown    = "markdown"     // 按标题层级切分，分块携带标题路径
+	ChunkStrategyCode        = "code"         // 按函数 / 类切分
+	ChunkStrategyTable       = "table"        // 按表格行切分，每块重复表头
+	ChunkStrategyParentChild = "parent_child" // 小块检索，命中后返回所属父块
+)
+
 // ChunkSetting 文档分段设置
 type ChunkSetting struct {
 	Separator       string `json:"separator"`
@@ -16,6 +26,9 @@ type ChunkSetting struct {
 	ChunkOverlap    int    `json:"chunk_overlap"`
 	CleanWhitespace bool   `json:"clean_whitespace"`
 	RemoveURLs      bool   `json:"remove_urls"`
+	Strategy        string `json:"strategy,omitempty"`          // 分块策略，空值等同 general
+	Language        string `json:"language,omitempty"`          // code 策略的语言，空值按文件扩展名识别
+	ParentChunkSize int    `json:"parent_chunk_size,omitempty"` // parent_child 策略的父块大小，ChunkSize 为子块大小
 }
 
 // Value 实现 driver.Valuer 接口
@@ -46,25 +59,79 @@ func DefaultChunkSetting() *ChunkSetting {
 	}
 }
 
+// DocumentMetadata 文档元数据（键值对，值为字符串 / 数值 / 布尔或它们的数组）
+type DocumentMetadata map[string]interface{}
+
+// Value 实现 driver.Valuer 接口
+func (m DocumentMetadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (m *DocumentMetadata) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	}
+	return fmt.Errorf("DocumentMetadata.Scan: unsupported type %T", val)
+}
+
+// DocumentACL 文档访问控制：仅列出的用户（以及知识库创建者）可检索该文档
+type DocumentACL struct {
+	Users []int64 `json:"users"`
+}
+
+// Value 实现 driver.Valuer 接口
+func (a DocumentACL) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (a *DocumentACL) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	}
+	return fmt.Errorf("DocumentACL.Scan: unsupported type %T", val)
+}
+
 // TKnowledgeDocument 知识库文档表
 type TKnowledgeDocument struct {
-	ID                  int64         `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	CreatedAt           *time.Time    `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt           *time.Time    `gorm:"column:updated_at" json:"updated_at"`
-	KnowledgeBaseID     int64         `gorm:"column:knowledge_base_id;not null;index:idx_doc_kb_id" json:"knowledge_base_id"`
-	Name                string        `gorm:"column:name;type:varchar(255);not null" json:"name"`
-	FileType            *string       `gorm:"column:file_type;type:varchar(20)" json:"file_type"`
-	FilePath            *string       `gorm:"column:file_path;type:varchar(512)" json:"file_path"`
-	FileSize            *int64        `gorm:"column:file_size;default:0" json:"file_size"`
-	WordCount           *int32        `gorm:"column:word_count;default:0" json:"word_count"`
-	ImageCount          *int32        `gorm:"column:image_count;default:0" json:"image_count"`
-	ChunkSetting        *ChunkSetting `gorm:"column:chunk_setting;type:json" json:"chunk_setting"`
-	IndexingStatus      *string       `gorm:"column:indexing_status;type:varchar(32);default:waiting;index:idx_doc_indexing_status" json:"indexing_status"`
-	ErrorMessage        *string       `gorm:"column:error_message;type:text" json:"error_message"`
-	ChunkCount          *int32        `gorm:"column:chunk_count;default:0" json:"chunk_count"`
-	TokenCount          *int32        `gorm:"column:token_count;default:0" json:"token_count"`
-	ParsingCompletedAt  *time.Time    `gorm:"column:parsing_completed_at" json:"parsing_completed_at"`
-	IndexingCompletedAt *time.Time    `gorm:"column:indexing_completed_at" json:"indexing_completed_at"`
+	ID                  int64            `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt           *time.Time       `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt           *time.Time       `gorm:"column:updated_at" json:"updated_at"`
+	KnowledgeBaseID     int64            `gorm:"column:knowledge_base_id;not null;index:idx_doc_kb_id" json:"knowledge_base_id"`
+	Name                string           `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	FileType            *string          `gorm:"column:file_type;type:varchar(20)" json:"file_type"`
+	FilePath            *string          `gorm:"column:file_path;type:varchar(512)" json:"file_path"`
+	FileSize            *int64           `gorm:"column:file_size;default:0" json:"file_size"`
+	WordCount           *int32           `gorm:"column:word_count;default:0" json:"word_count"`
+	ImageCount          *int32           `gorm:"column:image_count;default:0" json:"image_count"`
+	ChunkSetting        *ChunkSetting    `gorm:"column:chunk_setting;type:json" json:"chunk_setting"`
+	IndexingStatus      *string          `gorm:"column:indexing_status;type:varchar(32);default:waiting;index:idx_doc_indexing_status" json:"indexing_status"`
+	ErrorMessage        *string          `gorm:"column:error_message;type:text" json:"error_message"`
+	ChunkCount          *int32           `gorm:"column:chunk_count;default:0" json:"chunk_count"`
+	TokenCount          *int32           `gorm:"column:token_count;default:0" json:"token_count"`
+	ParsingCompletedAt  *time.Time       `gorm:"column:parsing_completed_at" json:"parsing_completed_at"`
+	IndexingCompletedAt *time.Time       `gorm:"column:indexing_completed_at" json:"indexing_completed_at"`
+	SourceID            *int64           `gorm:"column:source_id;index:idx_doc_source" json:"source_id"`   // 数据源同步的文档所属数据源
+	SourceKey           *string          `gorm:"column:source_key;type:varchar(1024)" json:"source_key"`   // 数据源内的文档标识（文件路径 / URL）
+	ContentHash         *string          `gorm:"column:content_hash;type:varchar(64)" json:"content_hash"` // 内容 SHA-256，同步时据此判断是否变化
+	Metadata            DocumentMetadata `gorm:"column:metadata;type:json" json:"metadata"`                // 用户指定的元数据
+	AutoMetadata        DocumentMetadata `gorm:"column:auto_metadata;type:json" json:"auto_metadata"`      // 解析时自动提取的元数据（front-matter / 文件路径）
+	ACL                 *DocumentACL     `gorm:"column:acl;type:json" json:"acl"`                          // 访问控制，为空时项目成员均可检索
 }
 
 func (*TKnowledgeDocument) TableName() string {
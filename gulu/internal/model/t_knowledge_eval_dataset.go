@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+const TableNameTKnowledgeEvalDataset = "t_knowledge_eval_dataset"
+
+// TKnowledgeEvalDataset 知识库检索评测集表
+type TKnowledgeEvalDataset struct {
+	ID              int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt       *time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       *time.Time `gorm:"column:updated_at" json:"updated_at"`
+	IsDelete        *bool      `gorm:"column:is_delete;default:0" json:"is_delete"`
+	CreatedBy       *int64     `gorm:"column:created_by" json:"created_by"`
+	KnowledgeBaseID int64      `gorm:"column:knowledge_base_id;not null;index:idx_eval_dataset_kb_id" json:"knowledge_base_id"`
+	Name            string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Description     *string    `gorm:"column:description;type:varchar(500)" json:"description"`
+}
+
+func (*TKnowledgeEvalDataset) TableName() string {
+	return TableNameTKnowledgeEvalDataset
+}
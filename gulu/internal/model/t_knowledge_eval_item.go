@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const TableNameTKnowledgeEvalItem = "t_knowledge_eval_item"
+
+// 评测题目状态
+const (
+	EvalItemActive    = "active"    // 参与评测
+	EvalItemCandidate = "candidate" // LLM 生成的候选题目，人工确认后转为 active
+)
+
+// 评测题目来源
+const (
+	EvalItemSourceManual    = "manual"
+	EvalItemSourceGenerated = "generated"
+)
+
+// EvalTarget 期望命中的分块或文档
+type EvalTarget struct {
+	DocumentID int64  `json:"document_id"`
+	SegmentID  int64  `json:"segment_id,omitempty"` // 为 0 时文档的任一分块都视为命中
+	Content    string `json:"content,omitempty"`    // 分块内容快照：文档重新分块后分块 ID 失效，按内容重合度匹配
+}
+
+// TKnowledgeEvalItem 知识库检索评测题目表
+type TKnowledgeEvalItem struct {
+	ID              int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt       *time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       *time.Time `gorm:"column:updated_at" json:"updated_at"`
+	KnowledgeBaseID int64      `gorm:"column:knowledge_base_id;not null;index:idx_eval_item_kb_id" json:"knowledge_base_id"`
+	DatasetID       int64      `gorm:"column:dataset_id;not null;index:idx_eval_item_dataset_id" json:"dataset_id"`
+	Question        string     `gorm:"column:question;type:text;not null" json:"question"`
+	Expected        *string    `gorm:"column:expected;type:json" json:"expected"` // []EvalTarget
+	ReferenceAnswer *string    `gorm:"column:reference_answer;type:text" json:"reference_answer"`
+	Status          string     `gorm:"column:status;type:varchar(20);not null;default:active" json:"status"`
+	Source          string     `gorm:"column:source;type:varchar(20);not null;default:manual" json:"source"`
+}
+
+func (*TKnowledgeEvalItem) TableName() string {
+	return TableNameTKnowledgeEvalItem
+}
+
+// GetExpected 解析期望命中的分块 / 文档
+func (i *TKnowledgeEvalItem) GetExpected() []EvalTarget {
+	var targets []EvalTarget
+	if i.Expected != nil && *i.Expected != "" {
+		_ = json.Unmarshal([]byte(*i.Expected), &targets)
+	}
+	return targets
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+const TableNameTKnowledgeEvalResult = "t_knowledge_eval_result"
+
+// TKnowledgeEvalResult 知识库检索评测单题结果表
+type TKnowledgeEvalResult struct {
+	ID             int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt      *time.Time `gorm:"column:created_at" json:"created_at"`
+	RunID          int64      `gorm:"column:run_id;not null;index:idx_eval_result_run_id" json:"run_id"`
+	ItemID         int64      `gorm:"column:item_id;not null" json:"item_id"`
+	Recall         float64    `gorm:"column:recall;not null;default:0" json:"recall"`
+	ReciprocalRank float64    `gorm:"column:reciprocal_rank;not null;default:0" json:"reciprocal_rank"`
+	NDCG           float64    `gorm:"column:ndcg;not null;default:0" json:"ndcg"`
+	Hit            bool       `gorm:"column:hit;not null;default:0" json:"hit"`
+	LatencyMs      int64      `gorm:"column:latency_ms;not null;default:0" json:"latency_ms"`
+	Retrieved      *string    `gorm:"column:retrieved;type:json" json:"retrieved"` // 召回结果（文档、分块位置、分数、是否命中）
+	ErrorMessage   *string    `gorm:"column:error_message;type:text" json:"error_message"`
+}
+
+func (*TKnowledgeEvalResult) TableName() string {
+	return TableNameTKnowledgeEvalResult
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+const TableNameTKnowledgeEvalRun = "t_knowledge_eval_run"
+
+// 评测运行状态
+const (
+	EvalRunRunning = "running"
+	EvalRunSuccess = "success"
+	EvalRunFailed  = "failed"
+)
+
+// TKnowledgeEvalRun 知识库检索评测运行表
+type TKnowledgeEvalRun struct {
+	ID              int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt       *time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       *time.Time `gorm:"column:updated_at" json:"updated_at"`
+	CreatedBy       *int64     `gorm:"column:created_by" json:"created_by"`
+	KnowledgeBaseID int64      `gorm:"column:knowledge_base_id;not null;index:idx_eval_run_kb_id" json:"knowledge_base_id"`
+	DatasetID       int64      `gorm:"column:dataset_id;not null;index:idx_eval_run_dataset_id" json:"dataset_id"`
+	Name            string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Config          *string    `gorm:"column:config;type:json" json:"config"` // 检索参数与知识库配置快照
+	Status          string     `gorm:"column:status;type:varchar(20);not null;default:running" json:"status"`
+	ItemCount       int        `gorm:"column:item_count;not null;default:0" json:"item_count"`
+	Metrics         *string    `gorm:"column:metrics;type:json" json:"metrics"` // 汇总指标
+	ErrorMessage    *string    `gorm:"column:error_message;type:text" json:"error_message"`
+	StartedAt       *time.Time `gorm:"column:started_at" json:"started_at"`
+	FinishedAt      *time.Time `gorm:"column:finished_at" json:"finished_at"`
+}
+
+func (*TKnowledgeEvalRun) TableName() string {
+	return TableNameTKnowledgeEvalRun
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+const TableNameTKnowledgeIngestJob = "t_knowledge_ingest_job"
+
+// 入库任务状态
+const (
+	IngestJobPending   = "pending"   // 等待执行（含退避等待重试）
+	IngestJobRunning   = "running"   // 执行中（持有租约）
+	IngestJobSucceeded = "succeeded" // 已完成
+	IngestJobFailed    = "failed"    // 失败（不可重试或重试次数用尽）
+	IngestJobCanceled  = "canceled"  // 已取消
+)
+
+// 入库任务阶段（检查点：任务恢复时从当前阶段继续）
+const (
+	IngestStageParse    = "parse"    // 解析、清洗、分块，结果写入检查点文件
+	IngestStageEmbed    = "embed"    // 分批向量化并写入向量库，embedded_chunks 记录进度
+	IngestStageFinalize = "finalize" // 写入分块记录、图谱抽取、更新文档状态
+)
+
+// TKnowledgeIngestJob 知识库文档入库任务表（数据库队列，行锁认领）
+type TKnowledgeIngestJob struct {
+	ID              int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt       *time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       *time.Time `gorm:"column:updated_at" json:"updated_at"`
+	KnowledgeBaseID int64      `gorm:"column:knowledge_base_id;not null;index:idx_ingest_kb_id" json:"knowledge_base_id"`
+	DocumentID      int64      `gorm:"column:document_id;not null;index:idx_ingest_doc_id" json:"document_id"`
+	Status          string     `gorm:"column:status;type:varchar(20);not null;default:pending;index:idx_ingest_status_next,priority:1" json:"status"`
+	Stage           string     `gorm:"column:stage;type:varchar(20);not null;default:parse" json:"stage"`
+	Attempts        int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	MaxAttempts     int        `gorm:"column:max_attempts;not null;default:5" json:"max_attempts"`
+	NextRunAt       time.Time  `gorm:"column:next_run_at;not null;index:idx_ingest_status_next,priority:2" json:"next_run_at"`
+	LockedBy        string     `gorm:"column:locked_by;type:varchar(100);not null;default:''" json:"locked_by"`
+	LeaseUntil      *time.Time `gorm:"column:lease_until" json:"lease_until"`
+	CancelRequested bool       `gorm:"column:cancel_requested;not null;default:0" json:"cancel_requested"`
+	TotalChunks     int        `gorm:"column:total_chunks;not null;default:0" json:"total_chunks"`
+	EmbeddedChunks  int        `gorm:"column:embedded_chunks;not null;default:0" json:"embedded_chunks"`
+	Progress        int        `gorm:"column:progress;not null;default:0" json:"progress"` // 0–100
+	ErrorMessage    *string    `gorm:"column:error_message;type:text" json:"error_message"`
+	StartedAt       *time.Time `gorm:"column:started_at" json:"started_at"`
+	FinishedAt      *time.Time `gorm:"column:finished_at" json:"finished_at"`
+}
+
+func (*TKnowledgeIngestJob) TableName() string {
+	return TableNameTKnowledgeIngestJob
+}
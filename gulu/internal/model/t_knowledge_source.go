@@ -0,0 +1,114 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const TableNameTKnowledgeSource = "t_knowledge_source"
+
+// 数据源类型
+const (
+	KnowledgeSourceGit     = "git"     // Git 仓库
+	KnowledgeSourceWebsite = "website" // 网站抓取（种子 URL / sitemap）
+	KnowledgeSourceFolder  = "folder"  // 服务器本地目录
+)
+
+// 数据源同步状态
+const (
+	SourceSyncIdle    = "idle"
+	SourceSyncRunning = "running"
+	SourceSyncSuccess = "success"
+	SourceSyncFailed  = "failed"
+)
+
+// KnowledgeSourceConfig 数据源配置（存入 config JSON 列，按 type 使用对应字段）
+type KnowledgeSourceConfig struct {
+	// git
+	RepoURL  string `json:"repo_url,omitempty"`
+	Branch   string `json:"branch,omitempty"`   // 默认远端默认分支
+	Username string `json:"username,omitempty"` // HTTPS 认证用户名，默认 git
+	Token    string `json:"token,omitempty"`    // HTTPS 访问令牌
+
+	// folder
+	Path string `json:"path,omitempty"` // 服务器目录，须位于 knowledge_source.folder_roots 之下
+
+	// git / folder 共用：相对仓库/目录根的 glob，支持 **
+	Include []string `json:"include,omitempty"` // 为空时收录所有支持的文档类型
+	Exclude []string `json:"exclude,omitempty"`
+
+	// website
+	SeedURLs        []string `json:"seed_urls,omitempty"`
+	SitemapURLs     []string `json:"sitemap_urls,omitempty"`
+	MaxDepth        int      `json:"max_depth,omitempty"`        // 从种子 URL 起的链接深度，默认 2
+	MaxPages        int      `json:"max_pages,omitempty"`        // 单次抓取页面上限，默认 200
+	IncludePatterns []string `json:"include_patterns,omitempty"` // URL 正则，为空时收录同站点所有页面
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"` // URL 正则
+	IgnoreRobots    bool     `json:"ignore_robots,omitempty"`    // 默认遵守 robots.txt
+	RequestDelayMs  int      `json:"request_delay_ms,omitempty"` // 请求间隔，默认 200ms
+}
+
+// KnowledgeSourceSyncStats 单次同步统计
+type KnowledgeSourceSyncStats struct {
+	Total     int    `json:"total"`              // 数据源中的文档数
+	Added     int    `json:"added"`              // 新增
+	Updated   int    `json:"updated"`            // 内容变化，重新入库
+	Unchanged int    `json:"unchanged"`          // 内容哈希未变化，跳过
+	Deleted   int    `json:"deleted"`            // 数据源中已移除，删除文档与分块
+	Skipped   int    `json:"skipped"`            // 读取失败、超出大小或正在处理中，保留原文档待下次同步
+	Revision  string `json:"revision,omitempty"` // Git 提交
+}
+
+// TKnowledgeSource 知识库数据源表
+type TKnowledgeSource struct {
+	ID              int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt       *time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       *time.Time `gorm:"column:updated_at" json:"updated_at"`
+	IsDelete        *bool      `gorm:"column:is_delete;default:0" json:"is_delete"`
+	CreatedBy       *int64     `gorm:"column:created_by" json:"created_by"`
+	KnowledgeBaseID int64      `gorm:"column:knowledge_base_id;not null;index:idx_source_kb_id" json:"knowledge_base_id"`
+	Name            string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Type            string     `gorm:"column:type;type:varchar(20);not null" json:"type"`
+	Config          *string    `gorm:"column:config;type:json" json:"config"`
+	CronExpression  string     `gorm:"column:cron_expression;type:varchar(100);not null;default:''" json:"cron_expression"` // 为空时仅手动同步
+	Status          *int32     `gorm:"column:status;default:1" json:"status"`                                               // 1 启用定时同步 0 暂停
+	SyncStatus      string     `gorm:"column:sync_status;type:varchar(20);not null;default:idle" json:"sync_status"`
+	SyncStartedAt   *time.Time `gorm:"column:sync_started_at" json:"sync_started_at"`
+	LastSyncAt      *time.Time `gorm:"column:last_sync_at" json:"last_sync_at"`
+	LastSyncStats   *string    `gorm:"column:last_sync_stats;type:json" json:"last_sync_stats"`
+	LastError       *string    `gorm:"column:last_error;type:text" json:"last_error"`
+}
+
+func (*TKnowledgeSource) TableName() string {
+	return TableNameTKnowledgeSource
+}
+
+// GetConfig 解析数据源配置
+func (m *TKnowledgeSource) GetConfig() KnowledgeSourceConfig {
+	var cfg KnowledgeSourceConfig
+	if m.Config != nil && *m.Config != "" {
+		json.Unmarshal([]byte(*m.Config), &cfg)
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = 2
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = 200
+	}
+	if cfg.RequestDelayMs <= 0 {
+		cfg.RequestDelayMs = 200
+	}
+	return cfg
+}
+
+// GetLastSyncStats 解析最近一次同步统计
+func (m *TKnowledgeSource) GetLastSyncStats() *KnowledgeSourceSyncStats {
+	if m.LastSyncStats == nil || *m.LastSyncStats == "" {
+		return nil
+	}
+	var stats KnowledgeSourceSyncStats
+	if err := json.Unmarshal([]byte(*m.LastSyncStats), &stats); err != nil {
+		return nil
+	}
+	return &stats
+}
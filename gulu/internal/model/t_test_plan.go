@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+const TableNameTTestPlan = "t_test_plan"
+
+// TTestPlan 测试计划表
+type TTestPlan struct {
+	ID            int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt     *time.Time `gorm:"column:created_at;type:datetime" json:"created_at"`
+	UpdatedAt     *time.Time `gorm:"column:updated_at;type:datetime" json:"updated_at"`
+	IsDelete      *bool      `gorm:"column:is_delete;type:tinyint(1);index:idx_t_test_plan_is_delete" json:"is_delete"`
+	CreatedBy     *int64     `gorm:"column:created_by;type:bigint unsigned" json:"created_by"`
+	UpdatedBy     *int64     `gorm:"column:updated_by;type:bigint unsigned" json:"updated_by"`
+	ProjectID     int64      `gorm:"column:project_id;type:bigint unsigned;not null;index:idx_t_test_plan_project_id" json:"project_id"`
+	Name          string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Description   *string    `gorm:"column:description;type:varchar(500)" json:"description"`
+	EnvID         int64      `gorm:"column:env_id;type:bigint unsigned;not null" json:"env_id"`
+	RunMode       string     `gorm:"column:run_mode;type:varchar(20);not null;default:serial" json:"run_mode"`
+	MaxParallel   int32      `gorm:"column:max_parallel;type:int;not null;default:0" json:"max_parallel"`
+	StopOnFailure bool       `gorm:"column:stop_on_failure;type:tinyint(1);not null;default:0" json:"stop_on_failure"`
+	Params        *string    `gorm:"column:params;type:json" json:"params"`
+}
+
+func (*TTestPlan) TableName() string {
+	return TableNameTTestPlan
+}
@@ -0,0 +1,19 @@
+package model
+
+const TableNameTTestPlanItem = "t_test_plan_item"
+
+// TTestPlanItem 测试计划用例表
+type TTestPlanItem struct {
+	ID         int64   `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PlanID     int64   `gorm:"column:plan_id;type:bigint unsigned;not null;index:idx_t_test_plan_item_plan_id" json:"plan_id"`
+	WorkflowID int64   `gorm:"column:workflow_id;type:bigint unsigned;not null" json:"workflow_id"`
+	EnvID      *int64  `gorm:"column:env_id;type:bigint unsigned" json:"env_id"`
+	Params     *string `gorm:"column:params;type:json" json:"params"`
+	Stage      int32   `gorm:"column:stage;type:int;not null;default:0" json:"stage"`
+	Sort       int32   `gorm:"column:sort;type:int;not null;default:0" json:"sort"`
+	Enabled    bool    `gorm:"column:enabled;type:tinyint(1);not null;default:1" json:"enabled"`
+}
+
+func (*TTestPlanItem) TableName() string {
+	return TableNameTTestPlanItem
+}
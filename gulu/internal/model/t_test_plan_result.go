@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+const TableNameTTestPlanResult = "t_test_plan_result"
+
+// TTestPlanResult 测试计划执行明细表（每次运行每个用例一条）
+type TTestPlanResult struct {
+	ID            int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	RunID         int64      `gorm:"column:run_id;type:bigint unsigned;not null;index:idx_t_test_plan_result_run_id" json:"run_id"`
+	PlanID        int64      `gorm:"column:plan_id;type:bigint unsigned;not null" json:"plan_id"`
+	ItemID        int64      `gorm:"column:item_id;type:bigint unsigned;not null" json:"item_id"`
+	WorkflowID    int64      `gorm:"column:workflow_id;type:bigint unsigned;not null" json:"workflow_id"`
+	WorkflowName  string     `gorm:"column:workflow_name;type:varchar(256);not null;default:''" json:"workflow_name"`
+	EnvID         int64      `gorm:"column:env_id;type:bigint unsigned;not null" json:"env_id"`
+	Stage         int32      `gorm:"column:stage;type:int;not null;default:0" json:"stage"`
+	Sort          int32      `gorm:"column:sort;type:int;not null;default:0" json:"sort"`
+	ExecutionDBID *int64     `gorm:"column:execution_db_id;type:bigint unsigned" json:"execution_db_id"`
+	Status        string     `gorm:"column:status;type:varchar(20);not null" json:"status"`
+	ErrorMessage  *string    `gorm:"column:error_message;type:text" json:"error_message"`
+	StartTime     *time.Time `gorm:"column:start_time;type:datetime" json:"start_time"`
+	EndTime       *time.Time `gorm:"column:end_time;type:datetime" json:"end_time"`
+	Duration      *int64     `gorm:"column:duration;type:bigint" json:"duration"`
+}
+
+func (*TTestPlanResult) TableName() string {
+	return TableNameTTestPlanResult
+}
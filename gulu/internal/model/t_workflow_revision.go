@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+const TableNameTWorkflowRevision = "t_workflow_revision"
+
+// TWorkflowRevision 工作流版本表，名称、描述、定义、类型或执行机配置变更时生成一条不可变记录
+type TWorkflowRevision struct {
+	ID             int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt      *time.Time `gorm:"column:created_at;type:datetime" json:"created_at"`
+	CreatedBy      *int64     `gorm:"column:created_by;type:bigint unsigned" json:"created_by"`
+	WorkflowID     int64      `gorm:"column:workflow_id;type:bigint unsigned;not null;uniqueIndex:uk_t_workflow_revision_version,priority:1" json:"workflow_id"`
+	Version        int32      `gorm:"column:version;type:int;not null;uniqueIndex:uk_t_workflow_revision_version,priority:2" json:"version"`
+	Name           string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Description    *string    `gorm:"column:description;type:varchar(500)" json:"description"`
+	Definition     string     `gorm:"column:definition;type:longtext;not null" json:"definition"`
+	WorkflowType   *string    `gorm:"column:workflow_type;type:varchar(20)" json:"workflow_type"`
+	ExecutorConfig *string    `gorm:"column:executor_config;type:json" json:"executor_config"`
+	Message        string     `gorm:"column:message;type:varchar(500);not null;default:''" json:"message"`
+	Source         string     `gorm:"column:source;type:varchar(20);not null;default:update" json:"source"`
+	RollbackFrom   *int32     `gorm:"column:rollback_from;type:int" json:"rollback_from"`
+}
+
+func (*TWorkflowRevision) TableName() string {
+	return TableNameTWorkflowRevision
+}
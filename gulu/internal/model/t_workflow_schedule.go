@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+const TableNameTWorkflowSchedule = "t_workflow_schedule"
+
+// TWorkflowSchedule 工作流定时计划表
+type TWorkflowSchedule struct {
+	ID               int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt        *time.Time `gorm:"column:created_at;type:datetime" json:"created_at"`
+	UpdatedAt        *time.Time `gorm:"column:updated_at;type:datetime" json:"updated_at"`
+	IsDelete         *bool      `gorm:"column:is_delete;type:tinyint(1);index:idx_t_workflow_schedule_is_delete" json:"is_delete"`
+	CreatedBy        *int64     `gorm:"column:created_by;type:bigint unsigned" json:"created_by"`
+	UpdatedBy        *int64     `gorm:"column:updated_by;type:bigint unsigned" json:"updated_by"`
+	ProjectID        int64      `gorm:"column:project_id;type:bigint unsigned;not null;index:idx_t_workflow_schedule_project_id" json:"project_id"`
+	WorkflowID       int64      `gorm:"column:workflow_id;type:bigint unsigned;not null;default:0;index:idx_t_workflow_schedule_workflow_id" json:"workflow_id"`
+	PlanID           int64      `gorm:"column:plan_id;type:bigint unsigned;not null;default:0;index:idx_t_workflow_schedule_plan_id" json:"plan_id"`
+	EnvID            int64      `gorm:"column:env_id;type:bigint unsigned;not null" json:"env_id"`
+	Name             string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Description      *string    `gorm:"column:description;type:varchar(500)" json:"description"`
+	CronExpression   string     `gorm:"column:cron_expression;type:varchar(100);not null" json:"cron_expression"`
+	Timezone         string     `gorm:"column:timezone;type:varchar(64);not null;default:''" json:"timezone"`
+	ExecutorStrategy *string    `gorm:"column:executor_strategy;type:json" json:"executor_strategy"`
+	Params           *string    `gorm:"column:params;type:json" json:"params"`
+	Status           *int32     `gorm:"column:status;type:tinyint;default:1;index:idx_t_workflow_schedule_status" json:"status"`
+	LastFireAt       *time.Time `gorm:"column:last_fire_at;type:datetime" json:"last_fire_at"`
+	LastRunAt        *time.Time `gorm:"column:last_run_at;type:datetime" json:"last_run_at"`
+	LastExecutionID  *string    `gorm:"column:last_execution_id;type:varchar(100)" json:"last_execution_id"`
+	LastError        *string    `gorm:"column:last_error;type:varchar(1000)" json:"last_error"`
+}
+
+func (*TWorkflowSchedule) TableName() string {
+	return TableNameTWorkflowSchedule
+}
@@ -43,6 +43,9 @@ func newTAiModel(db *gorm.DB, opts ...gen.DOOption) tAiModel {
 	_tAiModel.ParamSize = field.NewString(tableName, "param_size")
 	_tAiModel.CapabilityTags = field.NewString(tableName, "capability_tags")
 	_tAiModel.CustomTags = field.NewString(tableName, "custom_tags")
+	_tAiModel.InputPrice = field.NewFloat64(tableName, "input_price")
+	_tAiModel.OutputPrice = field.NewFloat64(tableName, "output_price")
+	_tAiModel.CachedPrice = field.NewFloat64(tableName, "cached_price")
 	_tAiModel.Sort = field.NewInt32(tableName, "sort")
 	_tAiModel.Status = field.NewInt32(tableName, "status")
 
@@ -60,19 +63,22 @@ type tAiModel struct {
 	CreatedAt      field.Time
 	UpdatedAt      field.Time
 	IsDelete       field.Bool
-	CreatedBy      field.Int64  // 创建人ID
-	ProviderID     field.Int64  // 关联供应商ID
-	Name           field.String // 模型名称
-	Provider       field.String // 冗余厂商名称
-	ModelID        field.String // 模型标识符
-	Version        field.String // 版本号
-	Description    field.String // 模型描述
-	ContextLength  field.Int32  // 上下文长度
-	ParamSize      field.String // 参数量
-	CapabilityTags field.String // 能力标签
-	CustomTags     field.String // 自定义标签
-	Sort           field.Int32  // 排序
-	Status         field.Int32  // 状态: 1-启用 0-禁用
+	CreatedBy      field.Int64   // 创建人ID
+	ProviderID     field.Int64   // 关联供应商ID
+	Name           field.String  // 模型名称
+	Provider       field.String  // 冗余厂商名称
+	ModelID        field.String  // 模型标识符
+	Version        field.String  // 版本号
+	Description    field.String  // 模型描述
+	ContextLength  field.Int32   // 上下文长度
+	ParamSize      field.String  // 参数量
+	CapabilityTags field.String  // 能力标签
+	CustomTags     field.String  // 自定义标签
+	InputPrice     field.Float64 // 输入单价(每百万Token)
+	OutputPrice    field.Float64 // 输出单价(每百万Token)
+	CachedPrice    field.Float64 // 缓存命中输入单价(每百万Token)
+	Sort           field.Int32   // 排序
+	Status         field.Int32   // 状态: 1-启用 0-禁用
 
 	fieldMap map[string]field.Expr
 }
@@ -104,6 +110,9 @@ func (t *tAiModel) updateTableName(table string) *tAiModel {
 	t.ParamSize = field.NewString(table, "param_size")
 	t.CapabilityTags = field.NewString(table, "capability_tags")
 	t.CustomTags = field.NewString(table, "custom_tags")
+	t.InputPrice = field.NewFloat64(table, "input_price")
+	t.OutputPrice = field.NewFloat64(table, "output_price")
+	t.CachedPrice = field.NewFloat64(table, "cached_price")
 	t.Sort = field.NewInt32(table, "sort")
 	t.Status = field.NewInt32(table, "status")
 
@@ -132,7 +141,7 @@ func (t *tAiModel) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (t *tAiModel) fillFieldMap() {
-	t.fieldMap = make(map[string]field.Expr, 17)
+	t.fieldMap = make(map[string]field.Expr, 20)
 	t.fieldMap["id"] = t.ID
 	t.fieldMap["created_at"] = t.CreatedAt
 	t.fieldMap["updated_at"] = t.UpdatedAt
@@ -148,6 +157,9 @@ func (t *tAiModel) fillFieldMap() {
 	t.fieldMap["param_size"] = t.ParamSize
 	t.fieldMap["capability_tags"] = t.CapabilityTags
 	t.fieldMap["custom_tags"] = t.CustomTags
+	t.fieldMap["input_price"] = t.InputPrice
+	t.fieldMap["output_price"] = t.OutputPrice
+	t.fieldMap["cached_price"] = t.CachedPrice
 	t.fieldMap["sort"] = t.Sort
 	t.fieldMap["status"] = t.Status
 }
@@ -44,6 +44,10 @@ func newTExecution(db *gorm.DB, opts ...gen.DOOption) tExecution {
 	_tExecution.EndTime = field.NewTime(tableName, "end_time")
 	_tExecution.Duration = field.NewInt64(tableName, "duration")
 	_tExecution.CreatedBy = field.NewInt64(tableName, "created_by")
+	_tExecution.TriggerType = field.NewString(tableName, "trigger_type")
+	_tExecution.ScheduleID = field.NewInt64(tableName, "schedule_id")
+	_tExecution.WorkflowVersion = field.NewInt32(tableName, "workflow_version")
+	_tExecution.Snapshot = field.NewString(tableName, "snapshot")
 
 	_tExecution.fillFieldMap()
 
@@ -71,6 +75,10 @@ type tExecution struct {
 	EndTime       field.Time   // 结束时间
 	Duration      field.Int64  // 执行时长(毫秒)
 	CreatedBy     field.Int64  // 创建人ID
+	TriggerType   field.String // 触发方式: manual, schedule
+	ScheduleID    field.Int64  // 触发的定时计划ID
+	WorkflowVersion field.Int32 // 执行时的工作流版本号
+	Snapshot      field.String // 执行快照(工作流定义与选中步骤, 用于从失败步骤恢复)
 
 	fieldMap map[string]field.Expr
 }
@@ -103,6 +111,10 @@ func (t *tExecution) updateTableName(table string) *tExecution {
 	t.EndTime = field.NewTime(table, "end_time")
 	t.Duration = field.NewInt64(table, "duration")
 	t.CreatedBy = field.NewInt64(table, "created_by")
+	t.TriggerType = field.NewString(table, "trigger_type")
+	t.ScheduleID = field.NewInt64(table, "schedule_id")
+	t.WorkflowVersion = field.NewInt32(table, "workflow_version")
+	t.Snapshot = field.NewString(table, "snapshot")
 
 	t.fillFieldMap()
 
@@ -129,7 +141,7 @@ func (t *tExecution) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (t *tExecution) fillFieldMap() {
-	t.fieldMap = make(map[string]field.Expr, 16)
+	t.fieldMap = make(map[string]field.Expr, 21)
 	t.fieldMap["id"] = t.ID
 	t.fieldMap["created_at"] = t.CreatedAt
 	t.fieldMap["updated_at"] = t.UpdatedAt
@@ -146,6 +158,10 @@ func (t *tExecution) fillFieldMap() {
 	t.fieldMap["end_time"] = t.EndTime
 	t.fieldMap["duration"] = t.Duration
 	t.fieldMap["created_by"] = t.CreatedBy
+	t.fieldMap["trigger_type"] = t.TriggerType
+	t.fieldMap["schedule_id"] = t.ScheduleID
+	t.fieldMap["workflow_version"] = t.WorkflowVersion
+	t.fieldMap["snapshot"] = t.Snapshot
 }
 
 func (t tExecution) clone(db *gorm.DB) tExecution {
@@ -45,6 +45,7 @@ func newTKnowledgeBase(db *gorm.DB, opts ...gen.DOOption) tKnowledgeBase {
 	_tKnowledgeBase.ChunkOverlap = field.NewInt32(tableName, "chunk_overlap")
 	_tKnowledgeBase.SimilarityThreshold = field.NewFloat64(tableName, "similarity_threshold")
 	_tKnowledgeBase.TopK = field.NewInt32(tableName, "top_k")
+	_tKnowledgeBase.VectorStore = field.NewString(tableName, "vector_store")
 	_tKnowledgeBase.QdrantCollection = field.NewString(tableName, "qdrant_collection")
 	_tKnowledgeBase.Neo4jDatabase = field.NewString(tableName, "neo4j_database")
 	_tKnowledgeBase.Metadata = field.NewString(tableName, "metadata")
@@ -77,6 +78,7 @@ type tKnowledgeBase struct {
 	ChunkOverlap        field.Int32
 	SimilarityThreshold field.Float64
 	TopK                field.Int32
+	VectorStore         field.String
 	QdrantCollection    field.String
 	Neo4jDatabase       field.String
 	Metadata            field.String
@@ -114,6 +116,7 @@ func (t *tKnowledgeBase) updateTableName(table string) *tKnowledgeBase {
 	t.ChunkOverlap = field.NewInt32(table, "chunk_overlap")
 	t.SimilarityThreshold = field.NewFloat64(table, "similarity_threshold")
 	t.TopK = field.NewInt32(table, "top_k")
+	t.VectorStore = field.NewString(table, "vector_store")
 	t.QdrantCollection = field.NewString(table, "qdrant_collection")
 	t.Neo4jDatabase = field.NewString(table, "neo4j_database")
 	t.Metadata = field.NewString(table, "metadata")
@@ -143,7 +146,7 @@ func (t *tKnowledgeBase) GetFieldByName(fieldName string) (field.OrderExpr, bool
 }
 
 func (t *tKnowledgeBase) fillFieldMap() {
-	t.fieldMap = make(map[string]field.Expr, 23)
+	t.fieldMap = make(map[string]field.Expr, 24)
 	t.fieldMap["id"] = t.ID
 	t.fieldMap["created_at"] = t.CreatedAt
 	t.fieldMap["updated_at"] = t.UpdatedAt
@@ -162,6 +165,7 @@ func (t *tKnowledgeBase) fillFieldMap() {
 	t.fieldMap["chunk_overlap"] = t.ChunkOverlap
 	t.fieldMap["similarity_threshold"] = t.SimilarityThreshold
 	t.fieldMap["top_k"] = t.TopK
+	t.fieldMap["vector_store"] = t.VectorStore
 	t.fieldMap["qdrant_collection"] = t.QdrantCollection
 	t.fieldMap["neo4j_database"] = t.Neo4jDatabase
 	t.fieldMap["metadata"] = t.Metadata
@@ -51,6 +51,16 @@ func Setup(app *fiber.App) {
 	app.Get("/api/internal/skillshub/search", handler.SkillshubSearch)
 	app.Post("/api/internal/skillshub/install", handler.SkillshubInstall)
 
+	// 文本向量化内部 API（校验内部令牌，供 workflow-engine 的 semantic_similarity 断言调用；会使用已保存的模型密钥）
+	internalAuth := middleware.InternalAuthMiddleware()
+	app.Post("/api/internal/embeddings", internalAuth, handler.KnowledgeEmbed)
+
+	// 知识库向量检索内部 API（校验内部令牌，供 workflow-engine 的 knowledge_search 工具检索；必须携带 acl_user_id）
+	app.Post("/api/internal/knowledge-bases/:id/vector-query", internalAuth, handler.KnowledgeVectorQuery)
+
+	// AI 用量账本内部 API（校验内部令牌，workflow-engine 每次调用模型前预留项目预算、调用后结算并记录用量）
+	app.Post("/api/internal/ai-usage/:projectId/charge", internalAuth, handler.AiUsageCharge)
+
 	// 创建执行相关组件（需要依赖注入的 handler）
 	engineClient := client.NewWorkflowEngineClient()
 	sched := scheduler.NewScheduler(engineClient)
@@ -177,6 +187,19 @@ func Setup(app *fiber.App) {
 	aiModels.Put("/:id/status", handler.AiModelUpdateStatus)
 	aiModels.Post("/:id/chat", handler.AiChatStream)
 
+	// AI 用量与预算路由
+	aiUsage := api.Group("/ai-usage")
+	aiUsage.Get("/summary", handler.AiUsageSummary)
+	aiUsage.Get("/records", handler.AiUsageList)
+	aiUsage.Get("/budget", handler.AiBudgetGet)
+	aiUsage.Put("/budget", handler.AiBudgetSave)
+
+	// AI 工具沙箱策略路由
+	aiSandbox := api.Group("/ai-sandbox")
+	aiSandbox.Get("/policy", handler.AiSandboxPolicyGet)
+	aiSandbox.Put("/policy", handler.AiSandboxPolicySave)
+	aiSandbox.Delete("/policy", handler.AiSandboxPolicyDelete)
+
 	// Skill 管理路由
 	skills := api.Group("/skills")
 	skills.Post("", handler.SkillCreate)
@@ -228,20 +251,45 @@ func Setup(app *fiber.App) {
 	kb.Get("/:id/documents", handler.KnowledgeDocumentList)
 	kb.Delete("/:id/documents/:docId", handler.KnowledgeDocumentDelete)
 	kb.Post("/:id/documents/:docId/reprocess", handler.KnowledgeDocumentReprocess)
+	kb.Post("/:id/documents/:docId/cancel", handler.KnowledgeDocumentCancel)
 	kb.Post("/:id/documents/preview-chunks", handler.KnowledgeDocumentPreviewChunks)
 	kb.Put("/:id/documents/:docId/process", handler.KnowledgeDocumentProcess)
+	kb.Put("/:id/documents/:docId/metadata", handler.KnowledgeDocumentUpdateMetadata)
 	// 批量操作
 	kb.Post("/:id/documents/batch-delete", handler.KnowledgeDocumentBatchDelete)
 	kb.Post("/:id/documents/batch-reprocess", handler.KnowledgeDocumentBatchReprocess)
 	kb.Get("/:id/indexing-status", handler.KnowledgeIndexingStatus)
+	// 数据源（Git / 网站 / 目录，定时增量同步）
+	kb.Get("/:id/sources", handler.KnowledgeSourceList)
+	kb.Post("/:id/sources", handler.KnowledgeSourceCreate)
+	kb.Put("/:id/sources/:sourceId", handler.KnowledgeSourceUpdate)
+	kb.Delete("/:id/sources/:sourceId", handler.KnowledgeSourceDelete)
+	kb.Post("/:id/sources/:sourceId/sync", handler.KnowledgeSourceSync)
 	// 分块管理
 	kb.Get("/:id/documents/:docId/segments", handler.KnowledgeDocumentSegments)
 	kb.Patch("/:id/segments/:segId", handler.KnowledgeSegmentUpdate)
 	// 检索与查询历史
 	kb.Post("/:id/search", handler.KnowledgeBaseSearch)
 	kb.Get("/:id/queries", handler.KnowledgeQueryHistory)
+	// 检索评测（评测集、题目、运行与对比）
+	kb.Get("/:id/eval/datasets", handler.KnowledgeEvalDatasetList)
+	kb.Post("/:id/eval/datasets", handler.KnowledgeEvalDatasetCreate)
+	kb.Put("/:id/eval/datasets/:datasetId", handler.KnowledgeEvalDatasetUpdate)
+	kb.Delete("/:id/eval/datasets/:datasetId", handler.KnowledgeEvalDatasetDelete)
+	kb.Get("/:id/eval/datasets/:datasetId/items", handler.KnowledgeEvalItemList)
+	kb.Post("/:id/eval/datasets/:datasetId/items", handler.KnowledgeEvalItemCreate)
+	kb.Post("/:id/eval/datasets/:datasetId/generate", handler.KnowledgeEvalItemGenerate)
+	kb.Put("/:id/eval/items/:itemId", handler.KnowledgeEvalItemUpdate)
+	kb.Delete("/:id/eval/items/:itemId", handler.KnowledgeEvalItemDelete)
+	kb.Get("/:id/eval/runs", handler.KnowledgeEvalRunList)
+	kb.Post("/:id/eval/runs", handler.KnowledgeEvalRunCreate)
+	kb.Get("/:id/eval/runs/:runId", handler.KnowledgeEvalRunGet)
+	kb.Delete("/:id/eval/runs/:runId", handler.KnowledgeEvalRunDelete)
+	kb.Get("/:id/eval/compare", handler.KnowledgeEvalCompare)
 	// 诊断接口（排查向量数据问题）
 	kb.Get("/:id/diagnose", handler.KnowledgeBaseDiagnose)
+	// 向量库迁移（qdrant / embedded / pgvector）
+	kb.Post("/:id/vector-store/migrate", handler.KnowledgeVectorStoreMigrate)
 	// 图知识库（Phase 3）
 	kb.Post("/:id/graph/search", handler.KnowledgeGraphSearch)
 	kb.Get("/:id/graph/entities", handler.KnowledgeGraphEntities)
@@ -262,6 +310,8 @@ func Setup(app *fiber.App) {
 	workflows.Post("", handler.WorkflowCreate)
 	workflows.Get("", handler.WorkflowList)
 	workflows.Post("/import", handler.WorkflowImportYAML)
+	workflows.Post("/import/api/preview", handler.APIImportPreview)
+	workflows.Post("/import/api", handler.APIImport)
 	workflows.Post("/validate", handler.WorkflowValidateDefinition)
 	workflows.Get("/project/:projectId", handler.WorkflowGetByProjectID)
 	workflows.Get("/:id", handler.WorkflowGetByID)
@@ -271,9 +321,38 @@ func Setup(app *fiber.App) {
 	workflows.Get("/:id/yaml", handler.WorkflowExportYAML)
 	workflows.Post("/:id/validate", handler.WorkflowValidate)
 	workflows.Put("/:id/status", handler.WorkflowUpdateStatus)
+	// 工作流版本路由
+	workflows.Get("/:id/revisions", handler.WorkflowRevisionList)
+	workflows.Get("/:id/revisions/diff", handler.WorkflowRevisionDiff)
+	workflows.Get("/:id/revisions/:version", handler.WorkflowRevisionGet)
+	workflows.Post("/:id/revisions/:version/rollback", handler.WorkflowRevisionRollback)
 	// AI 工作流会话路由
 	workflows.Post("/:id/conversations", handler.AIConversationCreate)
 	workflows.Get("/:id/conversations", handler.AIConversationList)
+	workflows.Post("/:id/conversations/import", handler.AIConversationImport)
+
+	// 工作流定时计划路由
+	schedules := api.Group("/workflow-schedules")
+	schedules.Post("", handler.WorkflowScheduleCreate)
+	schedules.Get("", handler.WorkflowScheduleList)
+	schedules.Get("/:id", handler.WorkflowScheduleGetByID)
+	schedules.Put("/:id", handler.WorkflowScheduleUpdate)
+	schedules.Delete("/:id", handler.WorkflowScheduleDelete)
+	schedules.Post("/:id/pause", handler.WorkflowSchedulePause)
+	schedules.Post("/:id/resume", handler.WorkflowScheduleResume)
+	schedules.Post("/:id/run", handler.WorkflowScheduleRun)
+
+	// 测试计划路由
+	testPlans := api.Group("/test-plans")
+	testPlans.Post("", handler.TestPlanCreate)
+	testPlans.Get("", handler.TestPlanList)
+	testPlans.Get("/runs/:runId/report", handler.TestPlanRunReport)
+	testPlans.Post("/runs/:runId/stop", handler.TestPlanRunStop)
+	testPlans.Get("/:id", handler.TestPlanGetByID)
+	testPlans.Put("/:id", handler.TestPlanUpdate)
+	testPlans.Delete("/:id", handler.TestPlanDelete)
+	testPlans.Post("/:id/run", handler.TestPlanRun)
+	testPlans.Get("/:id/runs", handler.TestPlanRuns)
 
 	// AI 会话管理路由
 	conversations := api.Group("/conversations")
@@ -282,6 +361,11 @@ func Setup(app *fiber.App) {
 	conversations.Put("/:convId/title", handler.AIConversationUpdateTitle)
 	conversations.Post("/:convId/messages", handler.AIConversationSaveMessage)
 	conversations.Delete("/:convId/messages/from/:msgId", handler.AIConversationDeleteMessagesFrom)
+	conversations.Post("/:convId/messages/:msgId/edit", handler.AIConversationEditMessage)
+	conversations.Post("/:convId/messages/:msgId/regenerate", handler.AIConversationRegenerate)
+	conversations.Put("/:convId/active-branch", handler.AIConversationSwitchBranch)
+	conversations.Post("/:convId/fork", handler.AIConversationFork)
+	conversations.Get("/:convId/export", handler.AIConversationExport)
 
 	// 执行记录管理路由（历史记录查询等）
 	executionRecords := api.Group("/execution-records")
@@ -309,9 +393,22 @@ func Setup(app *fiber.App) {
 	// GET    /api/executions/:sessionId   - 获取执行状态
 	// DELETE /api/executions/:sessionId   - 停止执行
 	// POST   /api/executions/:sessionId/interact - 提交交互响应
+	// GET    /api/executions/:sessionId/debug    - 获取断点调试状态
+	// POST   /api/executions/:sessionId/debug/command     - 调试命令（继续/单步/暂停）
+	// PUT    /api/executions/:sessionId/debug/breakpoints - 设置断点
+	// GET|PUT /api/executions/:sessionId/debug/variables  - 查看/修改暂停点变量
+	// POST   /api/executions/:sessionId/resume      - 从失败步骤恢复执行
+	// GET    /api/executions/:sessionId/checkpoints - 获取顶层步骤检查点
 	executions := api.Group("/executions")
 	executions.Post("", executionHandler.Execute)
 	executions.Get("/:sessionId", executionHandler.GetExecutionStatus)
 	executions.Delete("/:sessionId", executionHandler.StopExecution)
 	executions.Post("/:sessionId/interact", executionHandler.SubmitInteraction)
+	executions.Get("/:sessionId/debug", executionHandler.GetDebugState)
+	executions.Post("/:sessionId/debug/command", executionHandler.DebugCommand)
+	executions.Put("/:sessionId/debug/breakpoints", executionHandler.SetBreakpoints)
+	executions.Get("/:sessionId/debug/variables", executionHandler.GetDebugVariables)
+	executions.Put("/:sessionId/debug/variables", executionHandler.SetDebugVariables)
+	executions.Post("/:sessionId/resume", executionHandler.ResumeExecution)
+	executions.Get("/:sessionId/checkpoints", executionHandler.GetCheckpoints)
 }
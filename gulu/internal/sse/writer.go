@@ -22,6 +22,10 @@ const (
 	EventHeartbeat         EventType = "heartbeat"
 	EventError             EventType = "error"
 
+	// 断点调试事件
+	EventStepPaused  EventType = "step_paused"
+	EventStepResumed EventType = "step_resumed"
+
 	// AI 内容事件
 	EventAIChunk            EventType = "ai_chunk"
 	EventAIThinking         EventType = "ai_thinking"
@@ -205,6 +209,25 @@ type StepCompletedData struct {
 	Error      string      `json:"error,omitempty"`
 }
 
+// StepPausedData 步骤暂停数据（步骤开始前暂停，尚未执行）
+type StepPausedData struct {
+	StepID         string                 `json:"stepId"`
+	StepName       string                 `json:"stepName"`
+	StepType       string                 `json:"stepType,omitempty"`
+	ParentID       string                 `json:"parentId,omitempty"`
+	Iteration      int                    `json:"iteration,omitempty"`
+	Depth          int                    `json:"depth"`
+	Reason         string                 `json:"reason"` // breakpoint / step / pause
+	ConditionError string                 `json:"conditionError,omitempty"`
+	Variables      map[string]interface{} `json:"variables,omitempty"`
+}
+
+// StepResumedData 步骤恢复执行数据
+type StepResumedData struct {
+	StepID  string `json:"stepId"`
+	Command string `json:"command"` // continue / step_over / step_into / step_out
+}
+
 // WorkflowCompletedData 工作流完成数据
 type WorkflowCompletedData struct {
 	SessionID     string                 `json:"sessionId"`
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicAddress 服务端抓取用户提供的地址时，目标解析为内网、回环、链路本地或云元数据地址
+var ErrNonPublicAddress = errors.New("禁止访问内网地址")
+
+// nonPublicNets 标准库 IsPrivate / IsLoopback / IsLinkLocalUnicast 等未覆盖的保留网段。
+// 云元数据地址 169.254.169.254 属于链路本地，fd00:ec2::254 属于 IsPrivate 覆盖的 fc00::/7
+var nonPublicNets = mustParseCIDRs(
+	"0.0.0.0/8",      // 本网络
+	"100.64.0.0/10",  // 运营商级 NAT
+	"192.0.0.0/24",   // IETF 协议分配
+	"198.18.0.0/15",  // 基准测试
+	"240.0.0.0/4",    // 保留
+	"64:ff9b::/96",   // NAT64，可映射到内网 IPv4
+	"64:ff9b:1::/48", // 本地 NAT64
+	"2001:db8::/32",  // 文档
+	"fec0::/10",      // 已废弃的站点本地地址
+	"100::/64",       // 丢弃前缀
+	"2002::/16",      // 6to4，可嵌入内网 IPv4
+	"2001::/32",      // Teredo
+	"255.255.255.255/32",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// IsPublicIP 判断是否为可从服务端安全访问的公网地址
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicDialControl 用作 net.Dialer.Control：在 DNS 解析之后、建立连接之前校验实际连接的地址，
+// 重定向与 DNS 重绑定同样经过该校验
+func publicDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicIP(net.ParseIP(host)) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+	return nil
+}
+
+// NewPublicHTTPClient 创建抓取用户提供地址的 HTTP 客户端。allowPrivate 为 false 时只允许连接公网地址，
+// 并忽略环境变量中的代理（代理地址通常在内网，经代理访问也无法校验最终目标）
+func NewPublicHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		dialer.Control = publicDialControl
+		transport.Proxy = nil
+	}
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// ResolvePublicIP 解析主机名并要求所有地址均为公网地址，返回第一个地址。
+// 用于无法注入拨号器的外部程序（如 git），调用方应将连接固定到返回的地址，避免解析结果在校验后变化
+func ResolvePublicIP(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return nil, fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+		}
+		return ip, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("无法解析主机: %s", host)
+	}
+	for _, a := range addrs {
+		if !IsPublicIP(a.IP) {
+			return nil, fmt.Errorf("%w: %s -> %s", ErrNonPublicAddress, host, a.IP)
+		}
+	}
+	return addrs[0].IP, nil
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"8.8.8.8":         true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fe80::1":         false,
+		"fd00:ec2::254":   false,
+		"::ffff:10.0.0.1": false,
+		"64:ff9b::a00:1":  false,
+	}
+	for addr, want := range cases {
+		if got := IsPublicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+// TestPublicHTTPClient 默认拒绝连接回环地址，允许内网时正常访问
+func TestPublicHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if _, err := NewPublicHTTPClient(time.Second, false).Get(server.URL); !errors.Is(err, ErrNonPublicAddress) {
+		t.Fatalf("expected loopback to be rejected, got %v", err)
+	}
+	resp, err := NewPublicHTTPClient(time.Second, true).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, err := ResolvePublicIP(context.Background(), "127.0.0.1"); !errors.Is(err, ErrNonPublicAddress) {
+		t.Fatalf("expected literal loopback to be rejected, got %v", err)
+	}
+	if _, err := ResolvePublicIP(context.Background(), "localhost"); !errors.Is(err, ErrNonPublicAddress) {
+		t.Fatalf("expected localhost to be rejected, got %v", err)
+	}
+}
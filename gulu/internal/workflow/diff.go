@@ -0,0 +1,218 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// 步骤变更类型
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// FieldChange 字段变更，Path 为点分隔的字段路径（如 config.url、variables.token）
+type FieldChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// StepChange 步骤变更
+type StepChange struct {
+	StepID string        `json:"step_id"`
+	Name   string        `json:"name"`
+	Type   string        `json:"type"`
+	Change string        `json:"change"`           // added, removed, modified
+	Fields []FieldChange `json:"fields,omitempty"` // 仅 modified 时有值
+}
+
+// DefinitionDiff 两个工作流定义之间的结构化差异
+type DefinitionDiff struct {
+	Fields []FieldChange `json:"fields"` // 工作流级字段（名称、描述、变量、参数）
+	Steps  []StepChange  `json:"steps"`
+}
+
+// Empty 是否没有任何差异
+func (d *DefinitionDiff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Steps) == 0
+}
+
+// flatStep 展开后的步骤，parent/position 用于识别步骤移动
+type flatStep struct {
+	key      string
+	step     types.Step
+	parent   string
+	position int
+}
+
+// DiffDefinitions 对比两个工作流定义，按步骤 ID 匹配步骤（含子步骤与分支步骤），
+// 返回步骤增删、字段级修改以及工作流级字段变更
+func DiffDefinitions(oldDef, newDef *WorkflowDefinition) *DefinitionDiff {
+	if oldDef == nil {
+		oldDef = &WorkflowDefinition{}
+	}
+	if newDef == nil {
+		newDef = &WorkflowDefinition{}
+	}
+
+	diff := &DefinitionDiff{Fields: []FieldChange{}, Steps: []StepChange{}}
+	if oldDef.Name != newDef.Name {
+		diff.Fields = append(diff.Fields, FieldChange{Path: "name", Old: oldDef.Name, New: newDef.Name})
+	}
+	if oldDef.Description != newDef.Description {
+		diff.Fields = append(diff.Fields, FieldChange{Path: "description", Old: oldDef.Description, New: newDef.Description})
+	}
+	diff.Fields = append(diff.Fields, diffValues("variables", toPlain(oldDef.Variables), toPlain(newDef.Variables))...)
+	if !reflect.DeepEqual(toPlain(oldDef.Params), toPlain(newDef.Params)) {
+		diff.Fields = append(diff.Fields, FieldChange{Path: "params", Old: toPlain(oldDef.Params), New: toPlain(newDef.Params)})
+	}
+
+	oldSteps := flattenSteps(oldDef.Steps, "", "steps", nil)
+	newSteps := flattenSteps(newDef.Steps, "", "steps", nil)
+	oldByKey := make(map[string]flatStep, len(oldSteps))
+	for _, s := range oldSteps {
+		oldByKey[s.key] = s
+	}
+	newKeys := make(map[string]bool, len(newSteps))
+
+	for _, ns := range newSteps {
+		newKeys[ns.key] = true
+		os, ok := oldByKey[ns.key]
+		if !ok {
+			diff.Steps = append(diff.Steps, StepChange{StepID: ns.key, Name: ns.step.Name, Type: ns.step.Type, Change: ChangeAdded})
+			continue
+		}
+		if fields := diffStep(os, ns); len(fields) > 0 {
+			diff.Steps = append(diff.Steps, StepChange{StepID: ns.key, Name: ns.step.Name, Type: ns.step.Type, Change: ChangeModified, Fields: fields})
+		}
+	}
+	for _, os := range oldSteps {
+		if !newKeys[os.key] {
+			diff.Steps = append(diff.Steps, StepChange{StepID: os.key, Name: os.step.Name, Type: os.step.Type, Change: ChangeRemoved})
+		}
+	}
+
+	return diff
+}
+
+// flattenSteps 深度优先展开步骤树（子步骤、循环体、分支），没有 ID 的步骤以其位置路径作为标识
+func flattenSteps(steps []types.Step, parent, path string, out []flatStep) []flatStep {
+	for i, step := range steps {
+		stepPath := fmt.Sprintf("%s[%d]", path, i)
+		key := step.ID
+		if key == "" {
+			key = stepPath
+		}
+		out = append(out, flatStep{key: key, step: step, parent: parent, position: i})
+		out = flattenSteps(step.Children, key, stepPath+".children", out)
+		if step.Loop != nil {
+			out = flattenSteps(step.Loop.Steps, key, stepPath+".loop.steps", out)
+		}
+		for j, branch := range step.Branches {
+			branchKey := branch.ID
+			if branchKey == "" {
+				branchKey = fmt.Sprintf("%s.branches[%d]", key, j)
+			}
+			out = flattenSteps(branch.Steps, branchKey, fmt.Sprintf("%s.branches[%d].steps", stepPath, j), out)
+		}
+	}
+	return out
+}
+
+// diffStep 对比同一步骤的两个版本，子步骤单独作为步骤对比，这里只比较步骤本身的属性
+func diffStep(oldStep, newStep flatStep) []FieldChange {
+	var fields []FieldChange
+	if oldStep.parent != newStep.parent || oldStep.position != newStep.position {
+		fields = append(fields, FieldChange{
+			Path: "position",
+			Old:  map[string]any{"parent": oldStep.parent, "index": oldStep.position},
+			New:  map[string]any{"parent": newStep.parent, "index": newStep.position},
+		})
+	}
+	fields = append(fields, diffValues("", stepAttributes(oldStep.step), stepAttributes(newStep.step))...)
+	return fields
+}
+
+// stepAttributes 提取步骤自身的属性（不含子步骤）
+func stepAttributes(step types.Step) any {
+	attrs, _ := toPlain(step).(map[string]any)
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+	delete(attrs, "id")
+	delete(attrs, "children")
+	if loop, ok := attrs["loop"].(map[string]any); ok {
+		delete(loop, "steps")
+	}
+	if len(step.Branches) > 0 {
+		branches := make([]any, 0, len(step.Branches))
+		for _, b := range step.Branches {
+			branches = append(branches, map[string]any{"id": b.ID, "name": b.Name, "kind": string(b.Kind), "expression": b.Expression})
+		}
+		attrs["branches"] = branches
+	} else {
+		delete(attrs, "branches")
+	}
+	if step.Timeout > 0 {
+		attrs["timeout"] = step.Timeout.String()
+	}
+	return attrs
+}
+
+// diffValues 递归对比两个 JSON 值：对象逐键展开，其他类型（含数组）整体比较
+func diffValues(path string, oldVal, newVal any) []FieldChange {
+	oldMap, oldIsMap := oldVal.(map[string]any)
+	newMap, newIsMap := newVal.(map[string]any)
+	if oldVal == nil && newIsMap {
+		oldMap, oldIsMap = map[string]any{}, true
+	}
+	if newVal == nil && oldIsMap {
+		newMap, newIsMap = map[string]any{}, true
+	}
+	if !oldIsMap || !newIsMap {
+		if reflect.DeepEqual(oldVal, newVal) {
+			return nil
+		}
+		return []FieldChange{{Path: path, Old: oldVal, New: newVal}}
+	}
+
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+	for k := range oldMap {
+		keys = append(keys, k)
+	}
+	for k := range newMap {
+		if _, ok := oldMap[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []FieldChange
+	for _, k := range keys {
+		sub := k
+		if path != "" {
+			sub = path + "." + k
+		}
+		changes = append(changes, diffValues(sub, oldMap[k], newMap[k])...)
+	}
+	return changes
+}
+
+// toPlain 通过 JSON 往返转换为通用结构，使数字等类型在比较时一致
+func toPlain(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package workflow
+
+import (
+	"testing"
+)
+
+func TestDiffDefinitions_NoChanges(t *testing.T) {
+	def, err := ParseJSON(`{"name":"wf","steps":[{"id":"s1","name":"req","type":"http","config":{"url":"http://a"}}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := DiffDefinitions(def, def)
+	if !diff.Empty() {
+		t.Errorf("expected empty diff, got %+v", diff)
+	}
+}
+
+func TestDiffDefinitions_StepChanges(t *testing.T) {
+	oldDef, err := ParseJSON(`{"name":"wf","variables":{"a":1},"steps":[
+		{"id":"s1","name":"login","type":"http","config":{"method":"GET","url":"http://a"}},
+		{"id":"s2","name":"wait","type":"wait","config":{"duration":100}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newDef, err := ParseJSON(`{"name":"wf2","variables":{"a":2},"steps":[
+		{"id":"s1","name":"login","type":"http","config":{"method":"POST","url":"http://a"}},
+		{"id":"s3","name":"loop","type":"loop","children":[{"id":"s4","name":"inner","type":"script"}]}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := DiffDefinitions(oldDef, newDef)
+
+	fields := make(map[string]bool)
+	for _, f := range diff.Fields {
+		fields[f.Path] = true
+	}
+	if !fields["name"] || !fields["variables.a"] {
+		t.Errorf("expected name and variables.a changes, got %+v", diff.Fields)
+	}
+
+	changes := make(map[string]StepChange)
+	for _, s := range diff.Steps {
+		changes[s.StepID] = s
+	}
+	if c := changes["s1"]; c.Change != ChangeModified || len(c.Fields) != 1 || c.Fields[0].Path != "config.method" {
+		t.Errorf("expected s1 config.method modified, got %+v", c)
+	}
+	if changes["s2"].Change != ChangeRemoved {
+		t.Errorf("expected s2 removed, got %+v", changes["s2"])
+	}
+	if changes["s3"].Change != ChangeAdded || changes["s4"].Change != ChangeAdded {
+		t.Errorf("expected s3 and nested s4 added, got %+v", diff.Steps)
+	}
+}
+
+func TestDiffDefinitions_StepMoved(t *testing.T) {
+	oldDef, err := ParseJSON(`{"name":"wf","steps":[{"id":"s1","type":"http"},{"id":"s2","type":"http"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newDef, err := ParseJSON(`{"name":"wf","steps":[{"id":"s2","type":"http"},{"id":"s1","type":"http"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := DiffDefinitions(oldDef, newDef)
+	if len(diff.Steps) != 2 {
+		t.Fatalf("expected 2 moved steps, got %+v", diff.Steps)
+	}
+	for _, s := range diff.Steps {
+		if s.Change != ChangeModified || s.Fields[0].Path != "position" {
+			t.Errorf("expected position change, got %+v", s)
+		}
+	}
+}
+
+func TestDiffDefinitions_LoopBody(t *testing.T) {
+	oldDef, err := ParseJSON(`{"name":"wf","steps":[{"id":"l1","type":"loop","loop":{"mode":"count","count":2},
+		"children":[{"id":"c1","type":"http","config":{"url":"http://a"}}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newDef, err := ParseJSON(`{"name":"wf","steps":[{"id":"l1","type":"loop","loop":{"mode":"count","count":2},
+		"children":[{"id":"c1","type":"http","config":{"url":"http://b"}}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := DiffDefinitions(oldDef, newDef)
+	if len(diff.Steps) != 1 || diff.Steps[0].StepID != "c1" || diff.Steps[0].Fields[0].Path != "config.url" {
+		t.Errorf("expected only loop body step c1 config.url modified, got %+v", diff.Steps)
+	}
+}
@@ -18,6 +18,7 @@ var ValidNodeTypes = map[string]bool{
 	"db":              true,
 	"wait":            true,
 	"mq":              true,
+	"mock":            true,
 	"ai":              true,
 	"ai_chat":         true,
 	"ai_agent":        true,
@@ -139,6 +140,8 @@ func validateStep(step *types.Step, index int, stepIDs map[string]bool) []Valida
 		errs = append(errs, validateWaitStep(step, prefix)...)
 	case "mq":
 		errs = append(errs, validateMQStep(step, prefix)...)
+	case "mock":
+		errs = append(errs, validateMockStep(step, prefix)...)
 	case "ai":
 		errs = append(errs, validateAIStep(step, prefix)...)
 	case "ref_workflow":
@@ -334,6 +337,22 @@ func validateWaitStep(step *types.Step, prefix string) []ValidationError {
 	return errs
 }
 
+func validateMockStep(step *types.Step, prefix string) []ValidationError {
+	var errs []ValidationError
+
+	action, _ := step.Config["action"].(string)
+	switch action {
+	case "", "start", "stop", "verify", "reset":
+	default:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".config.action",
+			Message: fmt.Sprintf("无效的 Mock 操作 '%s'，支持: start, stop, verify, reset", action),
+		})
+	}
+
+	return errs
+}
+
 func validateMQStep(step *types.Step, prefix string) []ValidationError {
 	var errs []ValidationError
 
@@ -134,3 +134,22 @@ func TestValidateAIStep_NoToolFields_NoErrors(t *testing.T) {
 		t.Errorf("expected no errors when tool fields are absent, got %v", errs)
 	}
 }
+
+func TestValidate_MockStep(t *testing.T) {
+	def := &WorkflowDefinition{
+		Name: "mock",
+		Steps: []types.Step{
+			{ID: "start", Type: "mock", Name: "启动 Mock", Config: map[string]interface{}{"name": "payment"}},
+			{ID: "verify", Type: "mock", Name: "校验 Mock", Config: map[string]interface{}{"action": "verify"}},
+		},
+	}
+	if result := Validate(def); !result.Valid {
+		t.Fatalf("expected mock steps to be valid, got %v", result.Errors)
+	}
+
+	def.Steps[1].Config["action"] = "restart"
+	result := Validate(def)
+	if result.Valid || len(result.Errors) != 1 || result.Errors[0].Field != "steps[1].config.action" {
+		t.Fatalf("expected invalid action error, got %v", result.Errors)
+	}
+}
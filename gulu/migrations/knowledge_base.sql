@@ -1,6 +1,12 @@
 -- 知识库管理模块 - 数据库迁移脚本（V4 精简版）
 -- 执行方式: mysql -u root -p yqhp_admin < migrations/knowledge_base.sql
 
+DROP TABLE IF EXISTS `t_knowledge_eval_result`;
+DROP TABLE IF EXISTS `t_knowledge_eval_run`;
+DROP TABLE IF EXISTS `t_knowledge_eval_item`;
+DROP TABLE IF EXISTS `t_knowledge_eval_dataset`;
+DROP TABLE IF EXISTS `t_knowledge_source`;
+DROP TABLE IF EXISTS `t_knowledge_ingest_job`;
 DROP TABLE IF EXISTS `t_knowledge_query`;
 DROP TABLE IF EXISTS `t_knowledge_segment`;
 DROP TABLE IF EXISTS `t_knowledge_document`;
@@ -23,7 +29,8 @@ CREATE TABLE `t_knowledge_base` (
   `multimodal_enabled` TINYINT(1) DEFAULT 0,
   `multimodal_model_id` BIGINT UNSIGNED DEFAULT NULL,
   `graph_extract_model_id` BIGINT UNSIGNED DEFAULT NULL,
-  -- 向量库 / 图库
+  -- 向量库 / 图库（qdrant_collection 为集合名，适用于所有向量库后端）
+  `vector_store` VARCHAR(20) NOT NULL DEFAULT 'qdrant' COMMENT 'qdrant / embedded / pgvector',
   `qdrant_collection` VARCHAR(100) DEFAULT NULL,
   `neo4j_database` VARCHAR(100) DEFAULT NULL,
   -- 配置 JSON（分块 + 检索参数，维度为自动检测后的缓存值）
@@ -51,15 +58,22 @@ CREATE TABLE `t_knowledge_document` (
   `word_count` INT DEFAULT 0,
   `image_count` INT DEFAULT 0,
   `chunk_setting` JSON DEFAULT NULL COMMENT '文档级分段设置（覆盖知识库默认）',
-  `indexing_status` VARCHAR(32) DEFAULT 'waiting' COMMENT 'waiting/parsing/cleaning/splitting/indexing/completed/error',
+  `indexing_status` VARCHAR(32) DEFAULT 'waiting' COMMENT 'waiting/parsing/cleaning/splitting/indexing/completed/error/canceled',
   `error_message` TEXT DEFAULT NULL,
   `chunk_count` INT DEFAULT 0,
   `token_count` INT DEFAULT 0,
   `parsing_completed_at` DATETIME DEFAULT NULL,
   `indexing_completed_at` DATETIME DEFAULT NULL,
+  `source_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '所属数据源，手动上传的文档为空',
+  `source_key` VARCHAR(1024) DEFAULT NULL COMMENT '数据源内的文档标识（文件路径 / URL）',
+  `content_hash` VARCHAR(64) DEFAULT NULL COMMENT '内容 SHA-256',
+  `metadata` JSON DEFAULT NULL COMMENT '用户元数据（键值）',
+  `auto_metadata` JSON DEFAULT NULL COMMENT '自动提取的元数据：文件路径 / front-matter',
+  `acl` JSON DEFAULT NULL COMMENT '访问控制 {"users":[...]}，为空表示项目成员均可检索',
   PRIMARY KEY (`id`),
   INDEX `idx_doc_kb_id` (`knowledge_base_id`),
-  INDEX `idx_doc_indexing_status` (`indexing_status`)
+  INDEX `idx_doc_indexing_status` (`indexing_status`),
+  INDEX `idx_doc_source` (`source_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库文档表';
 
 -- 知识库分块表（MySQL 双写，与 Qdrant 同步）
@@ -107,5 +121,120 @@ CREATE TABLE `t_knowledge_query` (
   INDEX `idx_query_created_at` (`created_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库查询历史';
 
+-- 文档入库任务（数据库队列，工作协程行锁认领，按阶段记录检查点）
+CREATE TABLE `t_knowledge_ingest_job` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `created_at` DATETIME DEFAULT NULL,
+  `updated_at` DATETIME DEFAULT NULL,
+  `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+  `document_id` BIGINT UNSIGNED NOT NULL,
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT 'pending / running / succeeded / failed / canceled',
+  `stage` VARCHAR(20) NOT NULL DEFAULT 'parse' COMMENT 'parse / embed / finalize',
+  `attempts` INT NOT NULL DEFAULT 0,
+  `max_attempts` INT NOT NULL DEFAULT 5,
+  `next_run_at` DATETIME NOT NULL,
+  `locked_by` VARCHAR(100) NOT NULL DEFAULT '',
+  `lease_until` DATETIME DEFAULT NULL,
+  `cancel_requested` TINYINT(1) NOT NULL DEFAULT 0,
+  `total_chunks` INT NOT NULL DEFAULT 0,
+  `embedded_chunks` INT NOT NULL DEFAULT 0,
+  `progress` INT NOT NULL DEFAULT 0,
+  `error_message` TEXT DEFAULT NULL,
+  `started_at` DATETIME DEFAULT NULL,
+  `finished_at` DATETIME DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_ingest_status_next` (`status`, `next_run_at`),
+  INDEX `idx_ingest_kb_id` (`knowledge_base_id`),
+  INDEX `idx_ingest_doc_id` (`document_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库文档入库任务';
+
+-- 知识库数据源（Git 仓库 / 网站 / 服务器目录，定时增量同步）
+CREATE TABLE `t_knowledge_source` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `created_at` DATETIME DEFAULT NULL,
+  `updated_at` DATETIME DEFAULT NULL,
+  `is_delete` TINYINT(1) DEFAULT 0,
+  `created_by` BIGINT UNSIGNED DEFAULT NULL,
+  `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+  `name` VARCHAR(100) NOT NULL,
+  `type` VARCHAR(20) NOT NULL COMMENT 'git / website / folder',
+  `config` JSON DEFAULT NULL,
+  `cron_expression` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '为空时仅手动同步',
+  `status` TINYINT DEFAULT 1 COMMENT '1 启用定时同步 0 暂停',
+  `sync_status` VARCHAR(20) NOT NULL DEFAULT 'idle' COMMENT 'idle / running / success / failed',
+  `sync_started_at` DATETIME DEFAULT NULL,
+  `last_sync_at` DATETIME DEFAULT NULL,
+  `last_sync_stats` JSON DEFAULT NULL,
+  `last_error` TEXT DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_source_kb_id` (`knowledge_base_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库数据源';
+
+-- 知识库检索评测（评测集 / 题目 / 运行 / 结果）
+CREATE TABLE `t_knowledge_eval_dataset` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `created_at` DATETIME DEFAULT NULL,
+  `updated_at` DATETIME DEFAULT NULL,
+  `is_delete` TINYINT(1) DEFAULT 0,
+  `created_by` BIGINT UNSIGNED DEFAULT NULL,
+  `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+  `name` VARCHAR(100) NOT NULL,
+  `description` VARCHAR(500) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_eval_dataset_kb_id` (`knowledge_base_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库检索评测集表';
+
+CREATE TABLE `t_knowledge_eval_item` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `created_at` DATETIME DEFAULT NULL,
+  `updated_at` DATETIME DEFAULT NULL,
+  `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+  `dataset_id` BIGINT UNSIGNED NOT NULL,
+  `question` TEXT NOT NULL,
+  `expected` JSON DEFAULT NULL COMMENT '期望命中的分块 / 文档：[{document_id, segment_id, content}]',
+  `reference_answer` TEXT DEFAULT NULL,
+  `status` VARCHAR(20) NOT NULL DEFAULT 'active' COMMENT 'active 参与评测 / candidate 待确认',
+  `source` VARCHAR(20) NOT NULL DEFAULT 'manual' COMMENT 'manual / generated',
+  PRIMARY KEY (`id`),
+  INDEX `idx_eval_item_kb_id` (`knowledge_base_id`),
+  INDEX `idx_eval_item_dataset_id` (`dataset_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库检索评测题目表';
+
+CREATE TABLE `t_knowledge_eval_run` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `created_at` DATETIME DEFAULT NULL,
+  `updated_at` DATETIME DEFAULT NULL,
+  `created_by` BIGINT UNSIGNED DEFAULT NULL,
+  `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+  `dataset_id` BIGINT UNSIGNED NOT NULL,
+  `name` VARCHAR(100) NOT NULL,
+  `config` JSON DEFAULT NULL COMMENT '检索参数与知识库配置快照',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'running' COMMENT 'running / success / failed',
+  `item_count` INT NOT NULL DEFAULT 0,
+  `metrics` JSON DEFAULT NULL COMMENT '汇总指标：recall / hit_rate / mrr / ndcg / 延迟',
+  `error_message` TEXT DEFAULT NULL,
+  `started_at` DATETIME DEFAULT NULL,
+  `finished_at` DATETIME DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_eval_run_kb_id` (`knowledge_base_id`),
+  INDEX `idx_eval_run_dataset_id` (`dataset_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库检索评测运行表';
+
+CREATE TABLE `t_knowledge_eval_result` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `created_at` DATETIME DEFAULT NULL,
+  `run_id` BIGINT UNSIGNED NOT NULL,
+  `item_id` BIGINT UNSIGNED NOT NULL,
+  `recall` DOUBLE NOT NULL DEFAULT 0,
+  `reciprocal_rank` DOUBLE NOT NULL DEFAULT 0,
+  `ndcg` DOUBLE NOT NULL DEFAULT 0,
+  `hit` TINYINT(1) NOT NULL DEFAULT 0,
+  `latency_ms` BIGINT NOT NULL DEFAULT 0,
+  `retrieved` JSON DEFAULT NULL COMMENT '召回结果（文档、分块位置、分数、是否命中）',
+  `error_message` TEXT DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_eval_result_run_id` (`run_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库检索评测结果表';
+
 -- 知识图谱实体和关系数据统一存储在 Neo4j 中，不再使用 MySQL 表
 -- 如需清理旧表：DROP TABLE IF EXISTS t_knowledge_entity, t_knowledge_relation;
@@ -131,6 +131,27 @@ CREATE TABLE IF NOT EXISTS `t_workflow` (
     INDEX `idx_t_workflow_type` (`workflow_type`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='工作流表';
 
+-- ============================================
+-- 8.1 工作流版本表 (t_workflow_revision)
+-- ============================================
+CREATE TABLE IF NOT EXISTS `t_workflow_revision` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `created_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '提交人ID',
+    `workflow_id` BIGINT UNSIGNED NOT NULL COMMENT '工作流ID',
+    `version` INT NOT NULL COMMENT '版本号',
+    `name` VARCHAR(100) NOT NULL COMMENT '工作流名称',
+    `description` VARCHAR(500) DEFAULT NULL COMMENT '工作流描述',
+    `definition` LONGTEXT NOT NULL COMMENT '工作流定义(JSON格式)',
+    `workflow_type` VARCHAR(20) DEFAULT NULL COMMENT '工作流类型',
+    `executor_config` JSON DEFAULT NULL COMMENT '执行机配置',
+    `message` VARCHAR(500) NOT NULL DEFAULT '' COMMENT '提交说明',
+    `source` VARCHAR(20) NOT NULL DEFAULT 'update' COMMENT '来源: create(创建), update(编辑), rollback(回滚)',
+    `rollback_from` INT DEFAULT NULL COMMENT '回滚来源版本号',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_t_workflow_revision_version` (`workflow_id`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='工作流版本表';
+
 -- ============================================
 -- 9. 执行记录表 (t_execution)
 -- ============================================
@@ -151,15 +172,132 @@ CREATE TABLE IF NOT EXISTS `t_execution` (
     `end_time` DATETIME DEFAULT NULL COMMENT '结束时间',
     `duration` BIGINT DEFAULT NULL COMMENT '执行时长(毫秒)',
     `created_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '创建人ID',
+    `trigger_type` VARCHAR(20) NOT NULL DEFAULT 'manual' COMMENT '触发方式: manual(手动), schedule(定时计划)',
+    `schedule_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '触发的定时计划ID',
+    `workflow_version` INT DEFAULT NULL COMMENT '执行时的工作流版本号',
+    `snapshot` LONGTEXT DEFAULT NULL COMMENT '执行快照(工作流定义与选中步骤, 用于从失败步骤恢复)',
     PRIMARY KEY (`id`),
     INDEX `idx_t_execution_project_id` (`project_id`),
     INDEX `idx_t_execution_source_id` (`source_id`),
     INDEX `idx_t_execution_env_id` (`env_id`),
     INDEX `idx_t_execution_execution_id` (`execution_id`),
     INDEX `idx_t_execution_mode` (`mode`),
-    INDEX `idx_t_execution_source_type` (`source_type`)
+    INDEX `idx_t_execution_source_type` (`source_type`),
+    INDEX `idx_t_execution_trigger_type` (`trigger_type`),
+    INDEX `idx_t_execution_schedule_id` (`schedule_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='执行记录表';
 
+-- ============================================
+-- 9.1 执行检查点表 (t_execution_checkpoint)
+-- ============================================
+CREATE TABLE IF NOT EXISTS `t_execution_checkpoint` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `execution_id` VARCHAR(100) NOT NULL COMMENT '执行ID',
+    `step_index` INT NOT NULL COMMENT '顶层步骤序号(从0开始)',
+    `step_id` VARCHAR(100) NOT NULL COMMENT '步骤ID',
+    `status` VARCHAR(20) NOT NULL COMMENT '步骤状态: success, failed, skipped, timeout',
+    `variables` LONGTEXT DEFAULT NULL COMMENT '步骤完成后的变量快照(JSON)',
+    `results` LONGTEXT DEFAULT NULL COMMENT '该步骤(含子步骤)产生的结果(JSON)',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_t_execution_checkpoint_step` (`execution_id`, `step_index`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='执行检查点表';
+
+-- ============================================
+-- 9.2 工作流定时计划表 (t_workflow_schedule)
+-- ============================================
+CREATE TABLE IF NOT EXISTS `t_workflow_schedule` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `is_delete` TINYINT(1) DEFAULT 0,
+    `created_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '创建人ID',
+    `updated_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '更新人ID',
+    `project_id` BIGINT UNSIGNED NOT NULL COMMENT '所属项目ID',
+    `workflow_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '工作流ID(与 plan_id 二选一)',
+    `plan_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '测试计划ID(与 workflow_id 二选一)',
+    `env_id` BIGINT UNSIGNED NOT NULL COMMENT '执行环境ID(测试计划为 0 时使用计划默认环境)',
+    `name` VARCHAR(100) NOT NULL COMMENT '计划名称',
+    `description` VARCHAR(500) DEFAULT NULL COMMENT '描述',
+    `cron_expression` VARCHAR(100) NOT NULL COMMENT 'Cron表达式(含秒，6段)',
+    `timezone` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '时区(IANA名称，空表示服务器时区)',
+    `executor_strategy` JSON DEFAULT NULL COMMENT '执行机策略: {strategy, executor_id, labels}',
+    `params` JSON DEFAULT NULL COMMENT '执行参数(覆盖工作流变量)',
+    `status` TINYINT DEFAULT 1 COMMENT '状态: 1-启用 0-暂停',
+    `last_fire_at` DATETIME DEFAULT NULL COMMENT '最近一次定时触发的计划时间(多实例抢占)',
+    `last_run_at` DATETIME DEFAULT NULL COMMENT '最近一次执行时间',
+    `last_execution_id` VARCHAR(100) DEFAULT NULL COMMENT '最近一次执行ID',
+    `last_error` VARCHAR(1000) DEFAULT NULL COMMENT '最近一次触发失败原因',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_workflow_schedule_is_delete` (`is_delete`),
+    INDEX `idx_t_workflow_schedule_project_id` (`project_id`),
+    INDEX `idx_t_workflow_schedule_workflow_id` (`workflow_id`),
+    INDEX `idx_t_workflow_schedule_plan_id` (`plan_id`),
+    INDEX `idx_t_workflow_schedule_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='工作流定时计划表';
+
+-- ============================================
+-- 9.3 测试计划表 (t_test_plan)
+-- ============================================
+CREATE TABLE IF NOT EXISTS `t_test_plan` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `is_delete` TINYINT(1) DEFAULT 0,
+    `created_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '创建人ID',
+    `updated_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '更新人ID',
+    `project_id` BIGINT UNSIGNED NOT NULL COMMENT '所属项目ID',
+    `name` VARCHAR(100) NOT NULL COMMENT '计划名称',
+    `description` VARCHAR(500) DEFAULT NULL COMMENT '描述',
+    `env_id` BIGINT UNSIGNED NOT NULL COMMENT '默认执行环境ID',
+    `run_mode` VARCHAR(20) NOT NULL DEFAULT 'serial' COMMENT '运行方式: serial(串行), parallel(并行), stage(分阶段)',
+    `max_parallel` INT NOT NULL DEFAULT 0 COMMENT '最大并发数，0 表示不限',
+    `stop_on_failure` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '失败即停: 1-是 0-否',
+    `params` JSON DEFAULT NULL COMMENT '计划级执行参数',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_test_plan_is_delete` (`is_delete`),
+    INDEX `idx_t_test_plan_project_id` (`project_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测试计划表';
+
+-- ============================================
+-- 9.4 测试计划用例表 (t_test_plan_item)
+-- ============================================
+CREATE TABLE IF NOT EXISTS `t_test_plan_item` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `plan_id` BIGINT UNSIGNED NOT NULL COMMENT '测试计划ID',
+    `workflow_id` BIGINT UNSIGNED NOT NULL COMMENT '工作流ID',
+    `env_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '环境覆盖，为空时使用计划环境',
+    `params` JSON DEFAULT NULL COMMENT '用例级执行参数(覆盖计划参数)',
+    `stage` INT NOT NULL DEFAULT 0 COMMENT '阶段序号(分阶段运行时使用)',
+    `sort` INT NOT NULL DEFAULT 0 COMMENT '排序',
+    `enabled` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否启用',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_test_plan_item_plan_id` (`plan_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测试计划用例表';
+
+-- ============================================
+-- 9.5 测试计划执行明细表 (t_test_plan_result)
+-- ============================================
+CREATE TABLE IF NOT EXISTS `t_test_plan_result` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `run_id` BIGINT UNSIGNED NOT NULL COMMENT '计划运行记录ID(t_execution.id)',
+    `plan_id` BIGINT UNSIGNED NOT NULL COMMENT '测试计划ID',
+    `item_id` BIGINT UNSIGNED NOT NULL COMMENT '测试计划用例ID',
+    `workflow_id` BIGINT UNSIGNED NOT NULL COMMENT '工作流ID',
+    `workflow_name` VARCHAR(256) NOT NULL DEFAULT '' COMMENT '工作流名称',
+    `env_id` BIGINT UNSIGNED NOT NULL COMMENT '执行环境ID',
+    `stage` INT NOT NULL DEFAULT 0 COMMENT '阶段序号',
+    `sort` INT NOT NULL DEFAULT 0 COMMENT '排序',
+    `execution_db_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '工作流执行记录ID(t_execution.id)',
+    `status` VARCHAR(20) NOT NULL COMMENT '状态: pending, running, completed, failed, stopped, skipped',
+    `error_message` TEXT COMMENT '错误信息',
+    `start_time` DATETIME DEFAULT NULL COMMENT '开始时间',
+    `end_time` DATETIME DEFAULT NULL COMMENT '结束时间',
+    `duration` BIGINT DEFAULT NULL COMMENT '执行时长(毫秒)',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_test_plan_result_run_id` (`run_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测试计划执行明细表';
+
 -- ============================================
 -- 9.1 性能测试执行详情表 (t_execution_perf_detail)
 -- ============================================
@@ -321,6 +459,9 @@ CREATE TABLE IF NOT EXISTS `t_ai_model` (
     `param_size` VARCHAR(50) DEFAULT NULL COMMENT '参数量，如 7B、13B、671B',
     `capability_tags` JSON DEFAULT NULL COMMENT '能力标签 ["对话","FIM","Tools","视觉","MoE"]',
     `custom_tags` JSON DEFAULT NULL COMMENT '自定义标签 ["推荐","内部测试"]',
+    `input_price` DECIMAL(12,6) DEFAULT NULL COMMENT '输入单价(每百万Token)',
+    `output_price` DECIMAL(12,6) DEFAULT NULL COMMENT '输出单价(每百万Token)',
+    `cached_price` DECIMAL(12,6) DEFAULT NULL COMMENT '缓存命中输入单价(每百万Token), 为空时按输入单价计',
     `sort` INT DEFAULT 0 COMMENT '排序',
     `status` TINYINT DEFAULT 1 COMMENT '状态: 1-启用 0-禁用',
     PRIMARY KEY (`id`),
@@ -450,6 +591,9 @@ CREATE TABLE IF NOT EXISTS `t_ai_conversation` (
     `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
     `updated_at` DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     `created_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '创建人ID',
+    `active_message_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '当前分支的末条消息ID',
+    `forked_from_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '派生来源会话ID',
+    `forked_from_message_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '派生来源消息ID',
     PRIMARY KEY (`id`),
     INDEX `idx_ai_conv_workflow_id` (`workflow_id`),
     INDEX `idx_ai_conv_created_by` (`created_by`)
@@ -461,14 +605,74 @@ CREATE TABLE IF NOT EXISTS `t_ai_conversation` (
 CREATE TABLE IF NOT EXISTS `t_ai_conversation_message` (
     `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
     `conversation_id` BIGINT UNSIGNED NOT NULL COMMENT '关联的会话ID',
+    `parent_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '父消息ID，为空表示首条消息',
     `role` VARCHAR(20) NOT NULL COMMENT '消息角色: user/assistant/system',
     `content` LONGTEXT NOT NULL COMMENT '消息内容',
     `metadata` JSON DEFAULT NULL COMMENT '元信息（token用量、执行耗时、步骤结果摘要等）',
     `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`),
-    INDEX `idx_ai_conv_msg_conv_id` (`conversation_id`)
+    INDEX `idx_ai_conv_msg_conv_id` (`conversation_id`),
+    INDEX `idx_ai_conv_msg_parent_id` (`parent_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='AI工作流会话消息表';
 
+-- ============================================
+-- AI 用量记录表 (t_ai_usage)
+-- ============================================
+CREATE TABLE IF NOT EXISTS `t_ai_usage` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `project_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '项目ID',
+    `user_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '用户ID',
+    `source` VARCHAR(20) NOT NULL COMMENT '来源: workflow, chat',
+    `execution_id` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '执行ID',
+    `step_id` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '步骤ID',
+    `conversation_id` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '会话ID',
+    `ai_model_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'AI模型ID, 0 表示未托管的模型',
+    `model` VARCHAR(200) NOT NULL DEFAULT '' COMMENT '模型标识符',
+    `prompt_tokens` BIGINT NOT NULL DEFAULT 0 COMMENT '输入Token(含缓存命中)',
+    `cached_tokens` BIGINT NOT NULL DEFAULT 0 COMMENT '缓存命中输入Token',
+    `completion_tokens` BIGINT NOT NULL DEFAULT 0 COMMENT '输出Token',
+    `total_tokens` BIGINT NOT NULL DEFAULT 0 COMMENT '总Token',
+    `cost` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '费用',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_ai_usage_project_time` (`project_id`, `created_at`),
+    INDEX `idx_t_ai_usage_user_id` (`user_id`),
+    INDEX `idx_t_ai_usage_execution_id` (`execution_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='AI用量记录表';
+
+-- ============================================
+-- 项目 AI 预算表 (t_ai_budget)
+-- ============================================
+CREATE TABLE IF NOT EXISTS `t_ai_budget` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `updated_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '更新人ID',
+    `project_id` BIGINT UNSIGNED NOT NULL COMMENT '项目ID',
+    `period` VARCHAR(20) NOT NULL DEFAULT 'monthly' COMMENT '预算周期: daily, monthly',
+    `soft_limit` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '软上限(超出告警), 0 表示不限制',
+    `hard_limit` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '硬上限(超出停止调用模型), 0 表示不限制',
+    `period_start` DATETIME DEFAULT NULL COMMENT '已用金额对应的预算周期起点',
+    `spent` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '本周期已用金额(含调用中的预留)',
+    `status` TINYINT DEFAULT 1 COMMENT '状态: 1-启用 0-禁用',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_t_ai_budget_project_id` (`project_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='项目AI预算表';
+
+-- ============================================
+-- 项目 AI 工具沙箱策略表 (t_ai_sandbox_policy)
+-- ============================================
+CREATE TABLE IF NOT EXISTS `t_ai_sandbox_policy` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `updated_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '更新人ID',
+    `project_id` BIGINT UNSIGNED NOT NULL COMMENT '项目ID',
+    `policy` TEXT NOT NULL COMMENT '沙箱策略(JSON)',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_t_ai_sandbox_policy_project_id` (`project_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='项目AI工具沙箱策略表';
+
 -- ============================================
 -- 完成提示
 -- ============================================
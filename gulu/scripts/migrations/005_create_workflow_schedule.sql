@@ -0,0 +1,41 @@
+-- ============================================
+-- 005: 工作流定时计划
+-- 新增 t_workflow_schedule 表，执行记录增加触发方式与定时计划ID
+-- 执行: mysql -u <user> -p <database> < 005_create_workflow_schedule.sql
+-- ============================================
+
+ALTER TABLE `t_execution`
+ADD COLUMN `trigger_type` VARCHAR(20) NOT NULL DEFAULT 'manual' COMMENT '触发方式: manual(手动), schedule(定时计划)' AFTER `created_by`,
+ADD COLUMN `schedule_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '触发的定时计划ID' AFTER `trigger_type`,
+ADD INDEX `idx_t_execution_trigger_type` (`trigger_type`),
+ADD INDEX `idx_t_execution_schedule_id` (`schedule_id`);
+
+CREATE TABLE IF NOT EXISTS `t_workflow_schedule` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `is_delete` TINYINT(1) DEFAULT 0,
+    `created_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '创建人ID',
+    `updated_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '更新人ID',
+    `project_id` BIGINT UNSIGNED NOT NULL COMMENT '所属项目ID',
+    `workflow_id` BIGINT UNSIGNED NOT NULL COMMENT '工作流ID',
+    `env_id` BIGINT UNSIGNED NOT NULL COMMENT '执行环境ID',
+    `name` VARCHAR(100) NOT NULL COMMENT '计划名称',
+    `description` VARCHAR(500) DEFAULT NULL COMMENT '描述',
+    `cron_expression` VARCHAR(100) NOT NULL COMMENT 'Cron表达式(含秒，6段)',
+    `timezone` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '时区(IANA名称，空表示服务器时区)',
+    `executor_strategy` JSON DEFAULT NULL COMMENT '执行机策略: {strategy, executor_id, labels}',
+    `params` JSON DEFAULT NULL COMMENT '执行参数(覆盖工作流变量)',
+    `status` TINYINT DEFAULT 1 COMMENT '状态: 1-启用 0-暂停',
+    `last_fire_at` DATETIME DEFAULT NULL COMMENT '最近一次定时触发的计划时间(多实例抢占)',
+    `last_run_at` DATETIME DEFAULT NULL COMMENT '最近一次执行时间',
+    `last_execution_id` VARCHAR(100) DEFAULT NULL COMMENT '最近一次执行ID',
+    `last_error` VARCHAR(1000) DEFAULT NULL COMMENT '最近一次触发失败原因',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_workflow_schedule_is_delete` (`is_delete`),
+    INDEX `idx_t_workflow_schedule_project_id` (`project_id`),
+    INDEX `idx_t_workflow_schedule_workflow_id` (`workflow_id`),
+    INDEX `idx_t_workflow_schedule_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='工作流定时计划表';
+
+SELECT '工作流定时计划表创建完成!' AS message;
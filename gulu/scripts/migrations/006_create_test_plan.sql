@@ -0,0 +1,66 @@
+-- ============================================
+-- 006: 测试计划
+-- 新增测试计划、用例与执行明细表，定时计划支持触发测试计划
+-- 执行: mysql -u <user> -p <database> < 006_create_test_plan.sql
+-- ============================================
+
+ALTER TABLE `t_workflow_schedule`
+MODIFY COLUMN `workflow_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '工作流ID(与 plan_id 二选一)',
+ADD COLUMN `plan_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '测试计划ID(与 workflow_id 二选一)' AFTER `workflow_id`,
+MODIFY COLUMN `env_id` BIGINT UNSIGNED NOT NULL COMMENT '执行环境ID(测试计划为 0 时使用计划默认环境)',
+ADD INDEX `idx_t_workflow_schedule_plan_id` (`plan_id`);
+
+CREATE TABLE IF NOT EXISTS `t_test_plan` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `is_delete` TINYINT(1) DEFAULT 0,
+    `created_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '创建人ID',
+    `updated_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '更新人ID',
+    `project_id` BIGINT UNSIGNED NOT NULL COMMENT '所属项目ID',
+    `name` VARCHAR(100) NOT NULL COMMENT '计划名称',
+    `description` VARCHAR(500) DEFAULT NULL COMMENT '描述',
+    `env_id` BIGINT UNSIGNED NOT NULL COMMENT '默认执行环境ID',
+    `run_mode` VARCHAR(20) NOT NULL DEFAULT 'serial' COMMENT '运行方式: serial(串行), parallel(并行), stage(分阶段)',
+    `max_parallel` INT NOT NULL DEFAULT 0 COMMENT '最大并发数，0 表示不限',
+    `stop_on_failure` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '失败即停: 1-是 0-否',
+    `params` JSON DEFAULT NULL COMMENT '计划级执行参数',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_test_plan_is_delete` (`is_delete`),
+    INDEX `idx_t_test_plan_project_id` (`project_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测试计划表';
+
+CREATE TABLE IF NOT EXISTS `t_test_plan_item` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `plan_id` BIGINT UNSIGNED NOT NULL COMMENT '测试计划ID',
+    `workflow_id` BIGINT UNSIGNED NOT NULL COMMENT '工作流ID',
+    `env_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '环境覆盖，为空时使用计划环境',
+    `params` JSON DEFAULT NULL COMMENT '用例级执行参数(覆盖计划参数)',
+    `stage` INT NOT NULL DEFAULT 0 COMMENT '阶段序号(分阶段运行时使用)',
+    `sort` INT NOT NULL DEFAULT 0 COMMENT '排序',
+    `enabled` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否启用',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_test_plan_item_plan_id` (`plan_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测试计划用例表';
+
+CREATE TABLE IF NOT EXISTS `t_test_plan_result` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `run_id` BIGINT UNSIGNED NOT NULL COMMENT '计划运行记录ID(t_execution.id)',
+    `plan_id` BIGINT UNSIGNED NOT NULL COMMENT '测试计划ID',
+    `item_id` BIGINT UNSIGNED NOT NULL COMMENT '测试计划用例ID',
+    `workflow_id` BIGINT UNSIGNED NOT NULL COMMENT '工作流ID',
+    `workflow_name` VARCHAR(256) NOT NULL DEFAULT '' COMMENT '工作流名称',
+    `env_id` BIGINT UNSIGNED NOT NULL COMMENT '执行环境ID',
+    `stage` INT NOT NULL DEFAULT 0 COMMENT '阶段序号',
+    `sort` INT NOT NULL DEFAULT 0 COMMENT '排序',
+    `execution_db_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '工作流执行记录ID(t_execution.id)',
+    `status` VARCHAR(20) NOT NULL COMMENT '状态: pending, running, completed, failed, stopped, skipped',
+    `error_message` TEXT COMMENT '错误信息',
+    `start_time` DATETIME DEFAULT NULL COMMENT '开始时间',
+    `end_time` DATETIME DEFAULT NULL COMMENT '结束时间',
+    `duration` BIGINT DEFAULT NULL COMMENT '执行时长(毫秒)',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_test_plan_result_run_id` (`run_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测试计划执行明细表';
+
+SELECT '测试计划表创建完成!' AS message;
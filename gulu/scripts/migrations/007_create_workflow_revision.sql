@@ -0,0 +1,30 @@
+-- ============================================
+-- 007: 工作流版本历史
+-- 新增 t_workflow_revision 表（每次保存生成不可变版本），执行记录增加工作流版本号
+-- 执行: mysql -u <user> -p <database> < 007_create_workflow_revision.sql
+-- ============================================
+
+ALTER TABLE `t_execution`
+ADD COLUMN `workflow_version` INT DEFAULT NULL COMMENT '执行时的工作流版本号' AFTER `schedule_id`;
+
+CREATE TABLE IF NOT EXISTS `t_workflow_revision` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `created_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '提交人ID',
+    `workflow_id` BIGINT UNSIGNED NOT NULL COMMENT '工作流ID',
+    `version` INT NOT NULL COMMENT '版本号',
+    `name` VARCHAR(100) NOT NULL COMMENT '工作流名称',
+    `definition` LONGTEXT NOT NULL COMMENT '工作流定义(JSON格式)',
+    `workflow_type` VARCHAR(20) DEFAULT NULL COMMENT '工作流类型',
+    `executor_config` JSON DEFAULT NULL COMMENT '执行机配置',
+    `message` VARCHAR(500) NOT NULL DEFAULT '' COMMENT '提交说明',
+    `source` VARCHAR(20) NOT NULL DEFAULT 'update' COMMENT '来源: create(创建), update(编辑), rollback(回滚)',
+    `rollback_from` INT DEFAULT NULL COMMENT '回滚来源版本号',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_t_workflow_revision_version` (`workflow_id`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='工作流版本表';
+
+-- 为已有工作流补充当前版本快照
+INSERT IGNORE INTO `t_workflow_revision` (`created_at`, `created_by`, `workflow_id`, `version`, `name`, `definition`, `workflow_type`, `executor_config`, `message`, `source`)
+SELECT IFNULL(`updated_at`, NOW()), IFNULL(`updated_by`, `created_by`), `id`, IFNULL(`version`, 1), `name`, `definition`, `workflow_type`, `executor_config`, '', 'update'
+FROM `t_workflow` WHERE `is_delete` = 0;
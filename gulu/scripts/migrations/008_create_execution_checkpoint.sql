@@ -0,0 +1,22 @@
+-- ============================================
+-- 008: 执行检查点
+-- 新增 t_execution_checkpoint 表（每个顶层步骤完成后记录变量与结果），执行记录增加执行快照，
+-- 用于从失败步骤恢复执行
+-- 执行: mysql -u <user> -p <database> < 008_create_execution_checkpoint.sql
+-- ============================================
+
+ALTER TABLE `t_execution`
+ADD COLUMN `snapshot` LONGTEXT DEFAULT NULL COMMENT '执行快照(工作流定义与选中步骤, 用于从失败步骤恢复)' AFTER `workflow_version`;
+
+CREATE TABLE IF NOT EXISTS `t_execution_checkpoint` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `execution_id` VARCHAR(100) NOT NULL COMMENT '执行ID',
+    `step_index` INT NOT NULL COMMENT '顶层步骤序号(从0开始)',
+    `step_id` VARCHAR(100) NOT NULL COMMENT '步骤ID',
+    `status` VARCHAR(20) NOT NULL COMMENT '步骤状态: success, failed, skipped, timeout',
+    `variables` LONGTEXT DEFAULT NULL COMMENT '步骤完成后的变量快照(JSON)',
+    `results` LONGTEXT DEFAULT NULL COMMENT '该步骤(含子步骤)产生的结果(JSON)',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_t_execution_checkpoint_step` (`execution_id`, `step_index`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='执行检查点表';
@@ -0,0 +1,46 @@
+-- ============================================
+-- 009: AI 用量计费与预算
+-- AI 模型增加输入/输出/缓存命中单价，新增 t_ai_usage 用量记录表与 t_ai_budget 项目预算表
+-- 执行: mysql -u <user> -p <database> < 009_create_ai_usage.sql
+-- ============================================
+
+ALTER TABLE `t_ai_model`
+ADD COLUMN `input_price` DECIMAL(12,6) DEFAULT NULL COMMENT '输入单价(每百万Token)' AFTER `custom_tags`,
+ADD COLUMN `output_price` DECIMAL(12,6) DEFAULT NULL COMMENT '输出单价(每百万Token)' AFTER `input_price`,
+ADD COLUMN `cached_price` DECIMAL(12,6) DEFAULT NULL COMMENT '缓存命中输入单价(每百万Token), 为空时按输入单价计' AFTER `output_price`;
+
+CREATE TABLE IF NOT EXISTS `t_ai_usage` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `project_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '项目ID',
+    `user_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '用户ID',
+    `source` VARCHAR(20) NOT NULL COMMENT '来源: workflow, chat',
+    `execution_id` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '执行ID',
+    `step_id` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '步骤ID',
+    `conversation_id` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '会话ID',
+    `ai_model_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'AI模型ID, 0 表示未托管的模型',
+    `model` VARCHAR(200) NOT NULL DEFAULT '' COMMENT '模型标识符',
+    `prompt_tokens` BIGINT NOT NULL DEFAULT 0 COMMENT '输入Token(含缓存命中)',
+    `cached_tokens` BIGINT NOT NULL DEFAULT 0 COMMENT '缓存命中输入Token',
+    `completion_tokens` BIGINT NOT NULL DEFAULT 0 COMMENT '输出Token',
+    `total_tokens` BIGINT NOT NULL DEFAULT 0 COMMENT '总Token',
+    `cost` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '费用',
+    PRIMARY KEY (`id`),
+    INDEX `idx_t_ai_usage_project_time` (`project_id`, `created_at`),
+    INDEX `idx_t_ai_usage_user_id` (`user_id`),
+    INDEX `idx_t_ai_usage_execution_id` (`execution_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='AI用量记录表';
+
+CREATE TABLE IF NOT EXISTS `t_ai_budget` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `updated_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '更新人ID',
+    `project_id` BIGINT UNSIGNED NOT NULL COMMENT '项目ID',
+    `period` VARCHAR(20) NOT NULL DEFAULT 'monthly' COMMENT '预算周期: daily, monthly',
+    `soft_limit` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '软上限(超出告警), 0 表示不限制',
+    `hard_limit` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '硬上限(超出停止调用模型), 0 表示不限制',
+    `status` TINYINT DEFAULT 1 COMMENT '状态: 1-启用 0-禁用',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_t_ai_budget_project_id` (`project_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='项目AI预算表';
@@ -0,0 +1,16 @@
+-- ============================================
+-- 010: AI 工具沙箱策略
+-- 新增 t_ai_sandbox_policy 表，保存项目级 shell_exec / code_execute 沙箱策略
+-- 执行: mysql -u <user> -p <database> < 010_create_ai_sandbox_policy.sql
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS `t_ai_sandbox_policy` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `updated_by` BIGINT UNSIGNED DEFAULT NULL COMMENT '更新人ID',
+    `project_id` BIGINT UNSIGNED NOT NULL COMMENT '项目ID',
+    `policy` TEXT NOT NULL COMMENT '沙箱策略(JSON)',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_t_ai_sandbox_policy_project_id` (`project_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='项目AI工具沙箱策略表';
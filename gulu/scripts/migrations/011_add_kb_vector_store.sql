@@ -0,0 +1,8 @@
+-- ============================================
+-- 011: 知识库向量库后端
+-- t_knowledge_base 新增 vector_store 字段，按知识库选择 qdrant / embedded / pgvector
+-- 执行: mysql -u <user> -p <database> < 011_add_kb_vector_store.sql
+-- ============================================
+
+ALTER TABLE `t_knowledge_base`
+ADD COLUMN `vector_store` VARCHAR(20) NOT NULL DEFAULT 'qdrant' COMMENT '向量库后端: qdrant / embedded / pgvector' AFTER `graph_extract_model_id`;
@@ -0,0 +1,35 @@
+-- ============================================
+-- 012: 知识库文档入库队列
+-- 新增 t_knowledge_ingest_job 表，文档解析/向量化由数据库队列驱动，
+-- 工作协程通过行锁认领任务，按阶段记录检查点，服务重启后从检查点恢复
+-- 执行: mysql -u <user> -p <database> < 012_create_knowledge_ingest_job.sql
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS `t_knowledge_ingest_job` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+    `document_id` BIGINT UNSIGNED NOT NULL,
+    `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT 'pending / running / succeeded / failed / canceled',
+    `stage` VARCHAR(20) NOT NULL DEFAULT 'parse' COMMENT '当前阶段: parse / embed / finalize',
+    `attempts` INT NOT NULL DEFAULT 0 COMMENT '已执行次数',
+    `max_attempts` INT NOT NULL DEFAULT 5 COMMENT '最大执行次数',
+    `next_run_at` DATETIME NOT NULL COMMENT '最早执行时间（重试退避）',
+    `locked_by` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '持有任务的实例',
+    `lease_until` DATETIME DEFAULT NULL COMMENT '租约到期时间，到期未续约视为实例失联',
+    `cancel_requested` TINYINT(1) NOT NULL DEFAULT 0,
+    `total_chunks` INT NOT NULL DEFAULT 0,
+    `embedded_chunks` INT NOT NULL DEFAULT 0 COMMENT '已写入向量库的文本分块数',
+    `progress` INT NOT NULL DEFAULT 0 COMMENT '0-100',
+    `error_message` TEXT DEFAULT NULL,
+    `started_at` DATETIME DEFAULT NULL,
+    `finished_at` DATETIME DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_ingest_status_next` (`status`, `next_run_at`),
+    INDEX `idx_ingest_kb_id` (`knowledge_base_id`),
+    INDEX `idx_ingest_doc_id` (`document_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库文档入库任务表';
+
+ALTER TABLE `t_knowledge_document`
+MODIFY COLUMN `indexing_status` VARCHAR(32) DEFAULT 'waiting' COMMENT 'waiting/parsing/cleaning/splitting/indexing/completed/error/canceled';
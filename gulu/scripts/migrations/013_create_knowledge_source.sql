@@ -0,0 +1,33 @@
+-- ============================================
+-- 013: 知识库数据源
+-- 新增 t_knowledge_source 表（Git 仓库 / 网站 / 服务器目录），按 cron 定时增量同步；
+-- t_knowledge_document 新增 source_id / source_key / content_hash，同步时按内容哈希跳过未变化的文档
+-- 执行: mysql -u <user> -p <database> < 013_create_knowledge_source.sql
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS `t_knowledge_source` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `is_delete` TINYINT(1) DEFAULT 0,
+    `created_by` BIGINT UNSIGNED DEFAULT NULL,
+    `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    `type` VARCHAR(20) NOT NULL COMMENT 'git / website / folder',
+    `config` JSON DEFAULT NULL COMMENT '数据源配置（仓库地址、分支、glob、种子 URL 等）',
+    `cron_expression` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '同步 cron（6 段含秒），为空时仅手动同步',
+    `status` TINYINT DEFAULT 1 COMMENT '1 启用定时同步 0 暂停',
+    `sync_status` VARCHAR(20) NOT NULL DEFAULT 'idle' COMMENT 'idle / running / success / failed',
+    `sync_started_at` DATETIME DEFAULT NULL,
+    `last_sync_at` DATETIME DEFAULT NULL,
+    `last_sync_stats` JSON DEFAULT NULL COMMENT '最近一次同步统计：新增/更新/未变化/删除/跳过',
+    `last_error` TEXT DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_source_kb_id` (`knowledge_base_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库数据源表';
+
+ALTER TABLE `t_knowledge_document`
+ADD COLUMN `source_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '所属数据源，手动上传的文档为空' AFTER `indexing_completed_at`,
+ADD COLUMN `source_key` VARCHAR(1024) DEFAULT NULL COMMENT '数据源内的文档标识（文件路径 / URL）' AFTER `source_id`,
+ADD COLUMN `content_hash` VARCHAR(64) DEFAULT NULL COMMENT '内容 SHA-256' AFTER `source_key`,
+ADD INDEX `idx_doc_source` (`source_id`);
@@ -0,0 +1,71 @@
+-- ============================================
+-- 014: 知识库检索评测
+-- 新增评测集 / 评测题目 / 评测运行 / 评测结果表：
+-- 题目记录期望命中的分块或文档，运行时按快照的检索参数逐题检索并计算 recall@k、MRR、nDCG 与延迟
+-- 执行: mysql -u <user> -p <database> < 014_create_knowledge_eval.sql
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS `t_knowledge_eval_dataset` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `is_delete` TINYINT(1) DEFAULT 0,
+    `created_by` BIGINT UNSIGNED DEFAULT NULL,
+    `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    `description` VARCHAR(500) DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_eval_dataset_kb_id` (`knowledge_base_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库检索评测集表';
+
+CREATE TABLE IF NOT EXISTS `t_knowledge_eval_item` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+    `dataset_id` BIGINT UNSIGNED NOT NULL,
+    `question` TEXT NOT NULL,
+    `expected` JSON DEFAULT NULL COMMENT '期望命中的分块 / 文档：[{document_id, segment_id, content}]',
+    `reference_answer` TEXT DEFAULT NULL,
+    `status` VARCHAR(20) NOT NULL DEFAULT 'active' COMMENT 'active 参与评测 / candidate 待确认',
+    `source` VARCHAR(20) NOT NULL DEFAULT 'manual' COMMENT 'manual / generated',
+    PRIMARY KEY (`id`),
+    INDEX `idx_eval_item_kb_id` (`knowledge_base_id`),
+    INDEX `idx_eval_item_dataset_id` (`dataset_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库检索评测题目表';
+
+CREATE TABLE IF NOT EXISTS `t_knowledge_eval_run` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `updated_at` DATETIME DEFAULT NULL,
+    `created_by` BIGINT UNSIGNED DEFAULT NULL,
+    `knowledge_base_id` BIGINT UNSIGNED NOT NULL,
+    `dataset_id` BIGINT UNSIGNED NOT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    `config` JSON DEFAULT NULL COMMENT '检索参数与知识库配置快照',
+    `status` VARCHAR(20) NOT NULL DEFAULT 'running' COMMENT 'running / success / failed',
+    `item_count` INT NOT NULL DEFAULT 0,
+    `metrics` JSON DEFAULT NULL COMMENT '汇总指标：recall / hit_rate / mrr / ndcg / 延迟',
+    `error_message` TEXT DEFAULT NULL,
+    `started_at` DATETIME DEFAULT NULL,
+    `finished_at` DATETIME DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_eval_run_kb_id` (`knowledge_base_id`),
+    INDEX `idx_eval_run_dataset_id` (`dataset_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库检索评测运行表';
+
+CREATE TABLE IF NOT EXISTS `t_knowledge_eval_result` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `created_at` DATETIME DEFAULT NULL,
+    `run_id` BIGINT UNSIGNED NOT NULL,
+    `item_id` BIGINT UNSIGNED NOT NULL,
+    `recall` DOUBLE NOT NULL DEFAULT 0,
+    `reciprocal_rank` DOUBLE NOT NULL DEFAULT 0,
+    `ndcg` DOUBLE NOT NULL DEFAULT 0,
+    `hit` TINYINT(1) NOT NULL DEFAULT 0,
+    `latency_ms` BIGINT NOT NULL DEFAULT 0,
+    `retrieved` JSON DEFAULT NULL COMMENT '召回结果（文档、分块位置、分数、是否命中）',
+    `error_message` TEXT DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_eval_result_run_id` (`run_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='知识库检索评测结果表';
@@ -0,0 +1,12 @@
+-- ============================================
+-- 015: 知识库文档元数据与访问控制
+-- 文档新增用户元数据、自动提取的元数据（文件路径 / front-matter）与文档级访问控制，
+-- 检索时可按元数据过滤，并只返回当前用户有权查看的文档分块
+-- 已索引的文档需重新处理（或修改一次元数据）后向量才带有元数据
+-- 执行: mysql -u <user> -p <database> < 015_add_knowledge_document_metadata.sql
+-- ============================================
+
+ALTER TABLE `t_knowledge_document`
+ADD COLUMN `metadata` JSON DEFAULT NULL COMMENT '用户元数据（键值）' AFTER `content_hash`,
+ADD COLUMN `auto_metadata` JSON DEFAULT NULL COMMENT '自动提取的元数据：文件路径 / front-matter' AFTER `metadata`,
+ADD COLUMN `acl` JSON DEFAULT NULL COMMENT '访问控制 {"users":[...]}，为空表示项目成员均可检索' AFTER `auto_metadata`;
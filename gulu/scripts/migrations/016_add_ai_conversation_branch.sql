@@ -0,0 +1,30 @@
+-- ============================================
+-- 016: AI 会话分支
+-- 消息增加 parent_id 组成消息树（编辑 / 重新生成时创建同级分支），会话记录当前分支的末条消息与派生来源；
+-- 已有会话的消息按 ID 顺序串成一条分支
+-- 执行: mysql -u <user> -p <database> < 016_add_ai_conversation_branch.sql
+-- ============================================
+
+ALTER TABLE `t_ai_conversation_message`
+ADD COLUMN `parent_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '父消息ID，为空表示首条消息' AFTER `conversation_id`,
+ADD INDEX `idx_ai_conv_msg_parent_id` (`parent_id`);
+
+ALTER TABLE `t_ai_conversation`
+ADD COLUMN `active_message_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '当前分支的末条消息ID' AFTER `created_by`,
+ADD COLUMN `forked_from_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '派生来源会话ID' AFTER `active_message_id`,
+ADD COLUMN `forked_from_message_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '派生来源消息ID' AFTER `forked_from_id`;
+
+-- 已有消息：父消息为同一会话中的上一条消息（需要 MySQL 8.0 窗口函数）
+UPDATE `t_ai_conversation_message` m
+JOIN (
+    SELECT `id`, LAG(`id`) OVER (PARTITION BY `conversation_id` ORDER BY `id`) AS `prev_id`
+    FROM `t_ai_conversation_message`
+) p ON m.`id` = p.`id`
+SET m.`parent_id` = p.`prev_id`
+WHERE m.`parent_id` IS NULL;
+
+UPDATE `t_ai_conversation` c
+SET c.`active_message_id` = (
+    SELECT MAX(m.`id`) FROM `t_ai_conversation_message` m WHERE m.`conversation_id` = c.`id`
+)
+WHERE c.`active_message_id` IS NULL;
@@ -0,0 +1,10 @@
+-- ============================================
+-- 017: AI 预算账本
+-- 项目预算增加本周期已用金额与周期起点，执行引擎每次调用模型前在该行上原子预留费用，并发执行共享同一预算
+-- 升级后首次扣费时按 t_ai_usage 重新统计本周期已用金额
+-- 执行: mysql -u <user> -p <database> < 017_add_ai_budget_ledger.sql
+-- ============================================
+
+ALTER TABLE `t_ai_budget`
+ADD COLUMN `period_start` DATETIME DEFAULT NULL COMMENT '已用金额对应的预算周期起点' AFTER `hard_limit`,
+ADD COLUMN `spent` DECIMAL(16,6) NOT NULL DEFAULT 0 COMMENT '本周期已用金额(含调用中的预留)' AFTER `period_start`;
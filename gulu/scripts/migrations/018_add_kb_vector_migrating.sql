@@ -0,0 +1,9 @@
+-- ============================================
+-- 018: 知识库向量库迁移租约
+-- 迁移向量库期间在知识库上持有租约，入库队列不认领该知识库的任务，文档删除与分块编辑被拒绝；
+-- 迁移进程异常退出时租约到期自动解除
+-- 执行: mysql -u <user> -p <database> < 018_add_kb_vector_migrating.sql
+-- ============================================
+
+ALTER TABLE `t_knowledge_base`
+ADD COLUMN `vector_migrating_until` DATETIME DEFAULT NULL COMMENT '向量库迁移租约到期时间' AFTER `vector_store`;
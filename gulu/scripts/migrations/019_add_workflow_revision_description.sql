@@ -0,0 +1,13 @@
+-- ============================================
+-- 019: 工作流版本记录描述
+-- t_workflow_revision 增加 description，版本快照覆盖名称、描述、定义、类型与执行机配置
+-- 执行: mysql -u <user> -p <database> < 019_add_workflow_revision_description.sql
+-- ============================================
+
+ALTER TABLE `t_workflow_revision`
+ADD COLUMN `description` VARCHAR(500) DEFAULT NULL COMMENT '工作流描述' AFTER `name`;
+
+-- 为各工作流的当前版本补充描述
+UPDATE `t_workflow_revision` r
+JOIN `t_workflow` w ON w.`id` = r.`workflow_id` AND w.`version` = r.`version`
+SET r.`description` = w.`description`;
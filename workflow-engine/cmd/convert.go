@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"yqhp/workflow-engine/internal/converter"
+	"yqhp/workflow-engine/internal/parser"
+)
+
+var (
+	// convert 命令的 flags
+	convertOutput string
+	convertFrom   string
+	convertReport string
+)
+
+// convertCmd 是 convert 子命令
+var convertCmd = &cobra.Command{
+	Use:   "convert <test-plan.jmx|script.js>",
+	Short: "将 JMeter 测试计划或 k6 脚本转换为工作流",
+	Long: `将 JMeter JMX 测试计划或 k6 脚本转换为工作流 YAML。
+
+JMeter：每个线程组生成一个工作流，线程组转换为执行模式与阶段，
+HTTP 取样器、CSV 数据集、提取器、断言与定时器转换为步骤及前后置处理器。
+
+k6：options / scenarios 与 thresholds 转换为执行选项，默认函数中的
+http.* 调用转换为 http 步骤，check 转换为断言，sleep 转换为等待。
+
+无法转换的内容会列在转换报告中（输出到标准错误），请逐项确认。`,
+	Example: `  # 转换并输出到标准输出
+  workflow-engine convert plan.jmx
+
+  # 输出到文件（多个线程组时文件名追加工作流 ID）
+  workflow-engine convert -o workflow.yaml plan.jmx
+
+  # 转换 k6 脚本并保存 JSON 格式的转换报告
+  workflow-engine convert -o workflow.yaml --report report.json script.js`,
+	Args: cobra.ExactArgs(1),
+	RunE: convertWorkflow,
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "输出文件路径，默认输出到标准输出")
+	convertCmd.Flags().StringVar(&convertFrom, "from", "", "源格式 (jmx, k6)，默认按扩展名识别")
+	convertCmd.Flags().StringVar(&convertReport, "report", "", "转换报告输出路径 (JSON)")
+}
+
+func convertWorkflow(cmd *cobra.Command, args []string) error {
+	result, err := converter.ConvertFile(args[0], convertFrom)
+	if err != nil {
+		return err
+	}
+
+	printer := parser.NewYAMLPrinter()
+	var docs [][]byte
+	for _, wf := range result.Workflows {
+		data, err := printer.Print(wf)
+		if err != nil {
+			return fmt.Errorf("输出工作流 %s 失败: %w", wf.ID, err)
+		}
+		docs = append(docs, data)
+	}
+
+	if convertOutput == "" {
+		for i, data := range docs {
+			if i > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "---")
+			}
+			cmd.OutOrStdout().Write(data)
+		}
+	} else {
+		for i, data := range docs {
+			path := convertOutput
+			if len(docs) > 1 {
+				ext := filepath.Ext(convertOutput)
+				path = strings.TrimSuffix(convertOutput, ext) + "_" + result.Workflows[i].ID + ext
+			}
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				return fmt.Errorf("写入文件失败: %w", err)
+			}
+			if !quiet {
+				fmt.Fprintf(cmd.ErrOrStderr(), "已生成 %s\n", path)
+			}
+		}
+	}
+
+	if convertReport != "" {
+		data, err := json.MarshalIndent(result.Report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("序列化转换报告失败: %w", err)
+		}
+		if err := os.WriteFile(convertReport, data, 0644); err != nil {
+			return fmt.Errorf("写入转换报告失败: %w", err)
+		}
+	}
+	if !quiet || result.Report.Count(converter.LevelWarning) > 0 {
+		fmt.Fprint(cmd.ErrOrStderr(), result.Report.String())
+	}
+	return nil
+}
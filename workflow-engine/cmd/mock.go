@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"yqhp/workflow-engine/internal/executor/mock"
+)
+
+var (
+	// mock 命令的 flags
+	mockHost string
+	mockPort int
+)
+
+// mockCmd 是 mock 子命令
+var mockCmd = &cobra.Command{
+	Use:   "mock <stubs.yaml>",
+	Short: "启动 HTTP Mock 服务",
+	Long: `按桩定义文件启动 HTTP Mock 服务，用于在依赖服务不可用时进行压测或调试。
+
+桩按优先级匹配请求的方法、路径（支持 {param} 与通配符）、查询参数、
+请求头与 JSON 请求体，响应支持变量模板、延迟分布、故障注入与有状态场景。
+
+管理接口位于 /__admin/ 下，可查看请求记录、动态添加桩、重置状态与校验请求。`,
+	Example: `  # 随机端口启动
+  workflow-engine mock stubs.yaml
+
+  # 指定监听地址与端口
+  workflow-engine mock --host 0.0.0.0 --port 8089 stubs.yaml
+
+  # 查看请求记录
+  curl http://127.0.0.1:8089/__admin/requests`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMock,
+}
+
+func init() {
+	rootCmd.AddCommand(mockCmd)
+
+	mockCmd.Flags().StringVar(&mockHost, "host", "127.0.0.1", "监听地址")
+	mockCmd.Flags().IntVarP(&mockPort, "port", "p", 0, "监听端口，默认使用定义文件中的端口，均未配置时随机分配")
+}
+
+func runMock(cmd *cobra.Command, args []string) error {
+	def, err := mock.LoadDefinition(args[0])
+	if err != nil {
+		return err
+	}
+
+	port := def.Port
+	if cmd.Flags().Changed("port") {
+		port = mockPort
+	}
+	opts := mock.Options{
+		Name:    def.Name,
+		Address: net.JoinHostPort(mockHost, strconv.Itoa(port)),
+	}
+	if debug {
+		opts.OnRequest = func(entry mock.JournalEntry) {
+			stub := entry.StubID
+			if stub == "" {
+				stub = "-"
+			}
+			fmt.Printf("%s %s %s -> %d (%s)\n", entry.Time.Format("15:04:05"), entry.Request.Method, entry.Request.Path, entry.Status, stub)
+		}
+	}
+
+	srv := mock.NewServer(def.Stubs, opts)
+	if err := srv.Start(); err != nil {
+		return err
+	}
+
+	// 处理关闭信号
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	if !quiet {
+		fmt.Printf(Banner, Version)
+		fmt.Println()
+		fmt.Printf("  Mock 服务已启动\n")
+		fmt.Printf("  地址: %s\n", srv.URL())
+		fmt.Printf("  桩数量: %d\n", len(def.Stubs))
+		fmt.Printf("  管理接口: %s/__admin/\n", srv.URL())
+		fmt.Println()
+		fmt.Println("按 Ctrl+C 停止。")
+	}
+
+	select {
+	case <-sigCh:
+	case <-srv.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Stop(shutdownCtx); err != nil {
+		return fmt.Errorf("停止 Mock 服务失败: %w", err)
+	}
+
+	if !quiet {
+		fmt.Printf("\nMock 服务已停止，共处理 %d 个请求。\n", len(srv.Requests()))
+	}
+	return nil
+}
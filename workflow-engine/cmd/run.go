@@ -11,6 +11,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"yqhp/workflow-engine/internal/executor/ai"
 	"yqhp/workflow-engine/internal/master"
 	"yqhp/workflow-engine/internal/parser"
 	"yqhp/workflow-engine/pkg/logger"
@@ -26,6 +27,12 @@ var (
 	runMode       string
 	runJSONOutput string
 	runOutputs    []string
+
+	// AI 步骤录制回放
+	runAICassette       string
+	runAICassetteDir    string
+	runAICassetteStrict bool
+	runAICassetteTools  bool
 )
 
 // runCmd 是 run 子命令
@@ -52,7 +59,10 @@ var runCmd = &cobra.Command{
   workflow-engine run --out json=metrics.json workflow.yaml
 
   # 多个输出目标
-  workflow-engine run --out json=metrics.json --out console workflow.yaml`,
+  workflow-engine run --out json=metrics.json --out console workflow.yaml
+
+  # CI 中回放 AI 步骤的录制结果（不访问模型服务）
+  workflow-engine run --ai-cassette replay --ai-cassette-strict workflow.yaml`,
 	Args: cobra.ExactArgs(1),
 	RunE: runWorkflow,
 }
@@ -67,6 +77,10 @@ func init() {
 	runCmd.Flags().StringVar(&runMode, "mode", "", "执行模式 (constant-vus, ramping-vus, per-vu-iterations, shared-iterations)")
 	runCmd.Flags().StringVar(&runJSONOutput, "out-json", "", "输出 JSON 结果到文件")
 	runCmd.Flags().StringArrayVarP(&runOutputs, "out", "o", nil, "指标输出目标 (可多次指定)，格式: type=config")
+	runCmd.Flags().StringVar(&runAICassette, "ai-cassette", "", "AI 步骤录制回放模式 (record, replay)")
+	runCmd.Flags().StringVar(&runAICassetteDir, "ai-cassette-dir", "", "AI 录制文件根目录 (默认 cassettes)")
+	runCmd.Flags().BoolVar(&runAICassetteStrict, "ai-cassette-strict", false, "回放未命中录制时失败")
+	runCmd.Flags().BoolVar(&runAICassetteTools, "ai-cassette-tools", false, "回放时工具调用结果也从录制文件读取")
 }
 
 func runWorkflow(cmd *cobra.Command, args []string) error {
@@ -97,6 +111,17 @@ func runWorkflow(cmd *cobra.Command, args []string) error {
 	if runMode != "" {
 		workflow.Options.ExecutionMode = types.ExecutionMode(runMode)
 	}
+	if runAICassette != "" {
+		if runAICassette != ai.CassetteModeRecord && runAICassette != ai.CassetteModeReplay {
+			return fmt.Errorf("--ai-cassette 仅支持 record/replay，当前值: %s", runAICassette)
+		}
+		ai.SetCassetteRoot(runAICassetteDir)
+		ai.SetCassetteOverride(&ai.CassetteConfig{
+			Mode:        runAICassette,
+			Strict:      runAICassetteStrict,
+			ReplayTools: runAICassetteTools,
+		})
+	}
 
 	// 设置默认值
 	if workflow.Options.VUs <= 0 {
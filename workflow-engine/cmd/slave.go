@@ -15,6 +15,7 @@ import (
 	"yqhp/workflow-engine/internal/config"
 	"yqhp/workflow-engine/internal/executor"
 	_ "yqhp/workflow-engine/internal/executor/ai"    // 注册 AI 执行器
+	_ "yqhp/workflow-engine/internal/executor/mock"  // 注册 Mock 执行器
 	_ "yqhp/workflow-engine/internal/executor/tools" // 注册内置工具
 	"yqhp/workflow-engine/internal/slave"
 	"yqhp/workflow-engine/pkg/types"
@@ -0,0 +1,166 @@
+// Package converter 将 JMeter JMX 测试计划与 k6 脚本转换为工作流定义。
+// 线程组与 k6 options 转换为执行选项，HTTP 请求转换为 http 步骤，提取器、断言与定时器转换为前后置处理器；
+// 无法等价转换的内容不会静默丢弃，而是逐项记入转换报告。
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// 支持的源格式
+const (
+	FormatJMX = "jmx"
+	FormatK6  = "k6"
+)
+
+// Result 转换结果。JMX 中每个线程组生成一个工作流，k6 脚本生成一个工作流
+type Result struct {
+	Workflows []*types.Workflow
+	Report    *Report
+}
+
+// DetectFormat 根据文件扩展名识别源格式
+func DetectFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jmx":
+		return FormatJMX, nil
+	case ".js", ".mjs":
+		return FormatK6, nil
+	default:
+		return "", fmt.Errorf("无法识别文件格式: %s，请通过 --from 指定 jmx 或 k6", path)
+	}
+}
+
+// ConvertFile 读取并转换文件，format 为空时按扩展名识别
+func ConvertFile(path, format string) (*Result, error) {
+	if format == "" {
+		var err error
+		if format, err = DetectFormat(path); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	switch format {
+	case FormatJMX:
+		return ConvertJMX(data, path)
+	case FormatK6:
+		return ConvertK6(data, path)
+	default:
+		return nil, fmt.Errorf("不支持的源格式: %s", format)
+	}
+}
+
+// idAllocator 根据名称生成在整个工作流内唯一的步骤与处理器 ID
+type idAllocator struct {
+	used map[string]int
+}
+
+func newIDAllocator() *idAllocator {
+	return &idAllocator{used: make(map[string]int)}
+}
+
+// next 将名称转换为 snake_case 标识，重复时追加序号；名称中没有可用字符时使用 fallback
+func (a *idAllocator) next(name, fallback string) string {
+	id := slugify(name)
+	if id == "" {
+		id = fallback
+	}
+	a.used[id]++
+	if n := a.used[id]; n > 1 {
+		return fmt.Sprintf("%s_%d", id, n)
+	}
+	return id
+}
+
+// slugify 保留 ASCII 字母与数字，其余字符折叠为下划线
+func slugify(name string) string {
+	var b strings.Builder
+	pendingSep := false
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if pendingSep && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			pendingSep = false
+			continue
+		}
+		pendingSep = true
+	}
+	id := b.String()
+	if len(id) > 48 {
+		id = strings.TrimRight(id[:48], "_")
+	}
+	if id != "" && unicode.IsDigit(rune(id[0])) {
+		id = "step_" + id
+	}
+	return id
+}
+
+// processors 复制处理器并分配 ID；作用域内的处理器会应用到多个步骤，每个步骤持有独立副本
+func (a *idAllocator) processors(list []types.Processor) []types.Processor {
+	if len(list) == 0 {
+		return nil
+	}
+	out := make([]types.Processor, len(list))
+	for i, p := range list {
+		p.ID = a.next(p.Type, p.Type)
+		out[i] = p
+	}
+	return out
+}
+
+func newProcessor(typ, name string, config map[string]any) types.Processor {
+	return types.Processor{Type: typ, Enabled: true, Name: name, Config: config}
+}
+
+func newAssertion(name, assertType, operator, expression, expected string) types.Processor {
+	config := map[string]any{"assertType": assertType, "operator": operator, "expected": expected}
+	if expression != "" {
+		config["expression"] = expression
+	}
+	return newProcessor("assertion", name, config)
+}
+
+func newExtract(name, extractType, expression, variable string) types.Processor {
+	return newProcessor("extract_param", name, map[string]any{
+		"extractType":  extractType,
+		"expression":   expression,
+		"variableName": variable,
+	})
+}
+
+func newWait(name string, ms int) types.Processor {
+	return newProcessor("wait", name, map[string]any{"duration": ms})
+}
+
+func newScriptProcessor(name, code string) types.Processor {
+	return newProcessor("js_script", name, map[string]any{"script": code})
+}
+
+// scriptStep 生成 JavaScript 脚本步骤
+func scriptStep(id, name, code string) types.Step {
+	return types.Step{
+		ID:     id,
+		Name:   name,
+		Type:   "script",
+		Config: map[string]any{"language": "javascript", "script": code},
+	}
+}
+
+// jsString 将字符串编码为 JavaScript 字符串字面量
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
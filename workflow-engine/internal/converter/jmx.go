@@ -0,0 +1,1280 @@
+package converter
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"yqhp/workflow-engine/internal/expression"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// csvMaxRows CSV 数据集内联到脚本中的最大行数
+const csvMaxRows = 10000
+
+// jmxNode JMX 文件中的通用 XML 节点
+type jmxNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []*jmxNode `xml:",any"`
+}
+
+func (n *jmxNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// child 返回 name 属性等于 name 的直接子节点
+func (n *jmxNode) child(name string) *jmxNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.Nodes {
+		if c.attr("name") == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// rawProp 返回 stringProp / boolProp / intProp / longProp 属性的原始文本
+func (n *jmxNode) rawProp(name string) string {
+	if c := n.child(name); c != nil {
+		return c.Text
+	}
+	return ""
+}
+
+// prop 返回去除首尾空白的属性值
+func (n *jmxNode) prop(name string) string {
+	return strings.TrimSpace(n.rawProp(name))
+}
+
+func (n *jmxNode) boolProp(name string, def bool) bool {
+	switch strings.ToLower(n.prop(name)) {
+	case "true":
+		return true
+	case "false":
+		return false
+	default:
+		return def
+	}
+}
+
+// collection 返回 collectionProp 的元素
+func (n *jmxNode) collection(name string) []*jmxNode {
+	if c := n.child(name); c != nil && c.XMLName.Local == "collectionProp" {
+		return c.Nodes
+	}
+	return nil
+}
+
+// arguments 读取 Arguments 类型 elementProp 中的参数列表
+func (n *jmxNode) arguments(name string) []*jmxNode {
+	return n.child(name).collection("Arguments.arguments")
+}
+
+// jmxElement 测试元件及其子元件（JMX 中元件后紧跟的 hashTree 保存其子元件）
+type jmxElement struct {
+	*jmxNode
+	children []*jmxElement
+}
+
+func buildElements(tree *jmxNode) []*jmxElement {
+	var out []*jmxElement
+	for i := 0; i < len(tree.Nodes); i++ {
+		n := tree.Nodes[i]
+		if n.XMLName.Local == "hashTree" {
+			continue
+		}
+		el := &jmxElement{jmxNode: n}
+		if i+1 < len(tree.Nodes) && tree.Nodes[i+1].XMLName.Local == "hashTree" {
+			el.children = buildElements(tree.Nodes[i+1])
+			i++
+		}
+		out = append(out, el)
+	}
+	return out
+}
+
+// class 返回元件类名（去除包名）
+func (e *jmxElement) class() string {
+	class := e.attr("testclass")
+	if class == "" {
+		class = e.XMLName.Local
+	}
+	return class[strings.LastIndex(class, ".")+1:]
+}
+
+func (e *jmxElement) name() string {
+	if name := strings.TrimSpace(e.attr("testname")); name != "" {
+		return name
+	}
+	return e.class()
+}
+
+func (e *jmxElement) enabled() bool {
+	return e.attr("enabled") != "false"
+}
+
+// jmxScope 作用域内对采样器生效的元件。JMeter 中配置元件、定时器、前后置处理器与断言
+// 作用于同级及下级的所有采样器，执行顺序为：前置处理器、定时器、采样器、后置处理器、断言
+type jmxScope struct {
+	path       string
+	defaults   map[string]string // HTTP 请求默认值（HTTPSampler.* 属性）
+	headers    [][2]string
+	preProcs   []types.Processor
+	timers     []types.Processor
+	postProcs  []types.Processor
+	assertions []types.Processor
+	datasets   []*jmxElement // 在该作用域开始处生成取数步骤
+}
+
+// inherit 创建子作用域，复制父作用域中的元件；数据集只在声明它的作用域生成步骤
+func (s *jmxScope) inherit(path string) *jmxScope {
+	defaults := make(map[string]string, len(s.defaults))
+	for k, v := range s.defaults {
+		defaults[k] = v
+	}
+	return &jmxScope{
+		path:       path,
+		defaults:   defaults,
+		headers:    append([][2]string(nil), s.headers...),
+		preProcs:   append([]types.Processor(nil), s.preProcs...),
+		timers:     append([]types.Processor(nil), s.timers...),
+		postProcs:  append([]types.Processor(nil), s.postProcs...),
+		assertions: append([]types.Processor(nil), s.assertions...),
+	}
+}
+
+func (s *jmxScope) setHeader(name, value string) {
+	for i, h := range s.headers {
+		if strings.EqualFold(h[0], name) {
+			s.headers[i][1] = value
+			return
+		}
+	}
+	s.headers = append(s.headers, [2]string{name, value})
+}
+
+// jmxConverter JMX 转换状态；ids、vars 与 funcs 在每个线程组（工作流）开始时重置
+type jmxConverter struct {
+	report   *Report
+	baseDir  string
+	planVars map[string]any
+	ids      *idAllocator
+	vars     map[string]any
+	funcs    *jmxFuncs
+}
+
+// ConvertJMX 转换 JMeter 测试计划，每个启用的线程组生成一个工作流；source 为源文件路径，
+// 用于报告与解析 CSV 数据集的相对路径
+func ConvertJMX(data []byte, source string) (*Result, error) {
+	var root jmxNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("解析 JMX 失败: %w", err)
+	}
+	if root.XMLName.Local != "jmeterTestPlan" {
+		return nil, errors.New("不是有效的 JMeter 测试计划：缺少 jmeterTestPlan 根节点")
+	}
+
+	var top []*jmxElement
+	for _, n := range root.Nodes {
+		if n.XMLName.Local == "hashTree" {
+			top = buildElements(n)
+			break
+		}
+	}
+	var plan *jmxElement
+	for _, el := range top {
+		if el.class() == "TestPlan" {
+			plan = el
+			break
+		}
+	}
+	if plan == nil {
+		return nil, errors.New("JMX 中未找到 TestPlan")
+	}
+
+	c := &jmxConverter{
+		report:   &Report{Source: source, Format: FormatJMX},
+		baseDir:  filepath.Dir(source),
+		planVars: make(map[string]any),
+	}
+	c.resetWorkflow()
+	planPath := plan.name()
+	for _, arg := range plan.arguments("TestPlan.user_defined_variables") {
+		if name := arg.prop("Argument.name"); name != "" {
+			c.setVar(name, arg.rawProp("Argument.value"), planPath)
+		}
+	}
+	if plan.boolProp("TestPlan.serialize_threadgroups", false) {
+		c.report.warn(planPath, "TestPlan", "线程组按顺序执行的设置未转换，各线程组生成独立的工作流，请按需依次运行")
+	}
+
+	planScope := &jmxScope{path: planPath, defaults: make(map[string]string)}
+	for _, el := range plan.children {
+		if el.enabled() && !isThreadGroup(el) {
+			c.collectScopeElement(el, planScope, planPath+" / "+el.name())
+		}
+	}
+	// 测试计划级的用户变量对所有线程组生效
+	c.planVars = c.vars
+
+	result := &Result{Report: c.report}
+	usedIDs := make(map[string]int)
+	for _, el := range plan.children {
+		loc := planPath + " / " + el.name()
+		if !isThreadGroup(el) {
+			if el.enabled() && !c.isScopeClass(el) {
+				c.report.warn(loc, el.class(), "测试计划下的元件不属于任何线程组，未转换")
+			}
+			continue
+		}
+		if !el.enabled() {
+			c.report.info(loc, el.class(), "线程组已禁用，未转换")
+			continue
+		}
+		wf := c.convertThreadGroup(el, planScope, plan.name())
+		if wf == nil {
+			continue
+		}
+		usedIDs[wf.ID]++
+		if n := usedIDs[wf.ID]; n > 1 {
+			wf.ID = fmt.Sprintf("%s_%d", wf.ID, n)
+		}
+		result.Workflows = append(result.Workflows, wf)
+	}
+	if len(result.Workflows) == 0 {
+		return nil, errors.New("JMX 中没有可转换的线程组")
+	}
+	return result, nil
+}
+
+// setVar 定义用户变量；name = ${__P(name,default)} 形式的变量直接取属性默认值，避免自引用
+func (c *jmxConverter) setVar(name, raw, loc string) {
+	if value := c.expand(raw, loc); value != "${"+name+"}" {
+		c.vars[name] = value
+	}
+}
+
+func (c *jmxConverter) resetWorkflow() {
+	c.ids = newIDAllocator()
+	c.funcs = newJMXFuncs()
+	c.vars = make(map[string]any, len(c.planVars))
+	for k, v := range c.planVars {
+		c.vars[k] = v
+	}
+}
+
+func isThreadGroup(el *jmxElement) bool {
+	return strings.HasSuffix(el.class(), "ThreadGroup")
+}
+
+// convertThreadGroup 将线程组转换为工作流：线程数、循环次数、调度器转换为执行选项，子元件转换为步骤
+func (c *jmxConverter) convertThreadGroup(tg *jmxElement, plan *jmxScope, planName string) *types.Workflow {
+	loc := plan.path + " / " + tg.name()
+	c.resetWorkflow()
+
+	opts, ok := c.threadGroupOptions(tg, loc)
+	if !ok {
+		return nil
+	}
+
+	scope := plan.inherit(loc)
+	steps := c.datasetSteps(plan.datasets, loc)
+	steps = append(steps, c.convertChildren(tg.children, scope)...)
+	if len(steps) == 0 {
+		c.report.warn(loc, tg.class(), "线程组中没有可转换的请求，未生成工作流")
+		return nil
+	}
+
+	name := tg.name()
+	if planName != "" && planName != "Test Plan" {
+		name = planName + " - " + name
+	}
+	id := slugify(name)
+	if id == "" {
+		id = "jmeter_workflow"
+	}
+	wf := &types.Workflow{
+		ID:          id,
+		Name:        name,
+		Description: "由 JMeter 测试计划转换",
+		Steps:       steps,
+		Options:     opts,
+	}
+	if len(c.vars) > 0 {
+		wf.Variables = c.vars
+	}
+	return wf
+}
+
+// threadGroupOptions 转换线程组的执行参数
+func (c *jmxConverter) threadGroupOptions(tg *jmxElement, loc string) (types.ExecutionOptions, bool) {
+	var opts types.ExecutionOptions
+	class := tg.class()
+
+	switch class {
+	case "ThreadGroup", "SetupThreadGroup", "PostThreadGroup":
+		if class != "ThreadGroup" {
+			c.report.warn(loc, class, "setUp/tearDown 线程组转换为独立的工作流，需在主工作流前后单独运行")
+		}
+		threads := c.intValue(tg.prop("ThreadGroup.num_threads"), loc, "线程数", 1)
+		ramp := c.intValue(tg.prop("ThreadGroup.ramp_time"), loc, "Ramp-Up 时间", 0)
+		main := tg.child("ThreadGroup.main_controller")
+		loops := 1
+		if main != nil {
+			loops = c.intValue(main.prop("LoopController.loops"), loc, "循环次数", 1)
+			if main.boolProp("LoopController.continue_forever", false) && loops <= 0 {
+				loops = -1
+			}
+		}
+		duration := 0
+		if tg.boolProp("ThreadGroup.scheduler", false) {
+			duration = c.intValue(tg.prop("ThreadGroup.duration"), loc, "持续时间", 0)
+			if delay := c.intValue(tg.prop("ThreadGroup.delay"), loc, "启动延迟", 0); delay > 0 {
+				c.report.warn(loc, class, "启动延迟 %ds 未转换", delay)
+			}
+		}
+		if action := tg.prop("ThreadGroup.on_sample_error"); action != "" && action != "continue" {
+			c.report.warn(loc, class, "取样器错误后的动作 %s 未转换，引擎默认继续执行", action)
+		}
+
+		opts.VUs = threads
+		switch {
+		case duration > 0:
+			if loops > 0 {
+				c.report.warn(loc, class, "同时设置了循环次数 %d 与持续时间 %ds，已按持续时间转换", loops, duration)
+			}
+			if ramp > 0 && ramp < duration {
+				opts.ExecutionMode = types.ModeRampingVUs
+				opts.Stages = []types.Stage{
+					{Duration: time.Duration(ramp) * time.Second, Target: threads},
+					{Duration: time.Duration(duration-ramp) * time.Second, Target: threads},
+				}
+				opts.VUs = 0
+			} else {
+				opts.ExecutionMode = types.ModeConstantVUs
+				opts.Duration = time.Duration(duration) * time.Second
+			}
+		case loops > 0:
+			opts.ExecutionMode = types.ModePerVUIterations
+			opts.Iterations = loops
+			if ramp > 0 {
+				c.report.warn(loc, class, "按迭代次数执行时不支持 Ramp-Up，%ds 的 Ramp-Up 未转换", ramp)
+			}
+		default:
+			opts.ExecutionMode = types.ModeConstantVUs
+			c.report.warn(loc, class, "线程组无限循环且未设置持续时间，请运行时通过 --duration 指定")
+		}
+
+	case "ConcurrencyThreadGroup":
+		unit := time.Second
+		if tg.prop("Unit") == "M" {
+			unit = time.Minute
+		}
+		target := c.intValue(tg.prop("TargetLevel"), loc, "目标并发数", 1)
+		ramp := c.intValue(tg.prop("RampUp"), loc, "Ramp-Up 时间", 0)
+		hold := c.intValue(tg.prop("Hold"), loc, "持续时间", 0)
+		if steps := c.intValue(tg.prop("Steps"), loc, "阶梯数", 0); steps > 0 {
+			c.report.warn(loc, class, "%d 级阶梯加压已近似为线性加压", steps)
+		}
+		if iterations := c.intValue(tg.prop("Iterations"), loc, "迭代次数", 0); iterations > 0 {
+			c.report.warn(loc, class, "迭代次数上限 %d 未转换", iterations)
+		}
+		opts.ExecutionMode = types.ModeRampingVUs
+		if ramp > 0 {
+			opts.Stages = append(opts.Stages, types.Stage{Duration: time.Duration(ramp) * unit, Target: target})
+		}
+		if hold > 0 || ramp == 0 {
+			opts.Stages = append(opts.Stages, types.Stage{Duration: time.Duration(hold) * unit, Target: target})
+		}
+
+	default:
+		c.report.warn(loc, class, "不支持的线程组类型，未转换")
+		return opts, false
+	}
+	return opts, true
+}
+
+// intValue 解析整数参数，支持 ${__P(name,default)} 与用户变量，无法解析时返回 def
+func (c *jmxConverter) intValue(raw, loc, field string, def int) int {
+	if raw == "" {
+		return def
+	}
+	if n, err := strconv.Atoi(raw); err == nil {
+		return n
+	}
+	value := raw
+	if m := jmxFuncPattern.FindStringSubmatch(raw); m != nil && m[0] == raw && (m[1] == "P" || m[1] == "property") {
+		args := splitFuncArgs(m[2])
+		if m[1] == "P" {
+			value = funcArg(args, 1)
+		} else {
+			value = funcArg(args, 2)
+		}
+		c.report.info(loc, "__"+m[1], "%s使用属性 %s 的默认值 %q", field, funcArg(args, 0), value)
+	} else if strings.HasPrefix(raw, "${") && strings.HasSuffix(raw, "}") {
+		if v, ok := c.vars[raw[2:len(raw)-1]]; ok {
+			value = fmt.Sprint(v)
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return n
+	}
+	c.report.warn(loc, "", "%s %q 无法解析为整数，已使用 %d", field, raw, def)
+	return def
+}
+
+// isScopeClass 判断元件是否为作用域元件（配置元件、定时器、前后置处理器、断言、监听器）
+func (c *jmxConverter) isScopeClass(el *jmxElement) bool {
+	class := el.class()
+	if _, ok := jmxScopeClasses[class]; ok {
+		return true
+	}
+	for _, suffix := range []string{"Timer", "PreProcessor", "PostProcessor", "Extractor", "Assertion", "Manager", "Config", "ResultCollector", "Listener", "Visualizer"} {
+		if strings.HasSuffix(class, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+var jmxScopeClasses = map[string]bool{
+	"ConfigTestElement": true, "CSVDataSet": true, "Arguments": true, "Summariser": true,
+	"ResultAction": true, "DebugPostProcessor": true,
+}
+
+// collectScopeElement 将作用域元件加入 scope
+func (c *jmxConverter) collectScopeElement(el *jmxElement, scope *jmxScope, loc string) {
+	class := el.class()
+	switch class {
+	case "ConfigTestElement":
+		if el.child("HTTPSampler.domain") == nil && el.child("HTTPsampler.Arguments") == nil {
+			c.report.warn(loc, class, "未识别的配置元件，未转换")
+			return
+		}
+		for _, key := range []string{"HTTPSampler.domain", "HTTPSampler.port", "HTTPSampler.protocol", "HTTPSampler.path", "HTTPSampler.connect_timeout", "HTTPSampler.response_timeout"} {
+			if v := el.prop(key); v != "" {
+				scope.defaults[key] = v
+			}
+		}
+		if len(el.arguments("HTTPsampler.Arguments")) > 0 {
+			c.report.warn(loc, class, "HTTP 请求默认值中的参数未转换")
+		}
+	case "HeaderManager":
+		for _, h := range el.collection("HeaderManager.headers") {
+			if name := h.prop("Header.name"); name != "" {
+				scope.setHeader(name, h.rawProp("Header.value"))
+			}
+		}
+	case "Arguments":
+		for _, arg := range el.collection("Arguments.arguments") {
+			if name := arg.prop("Argument.name"); name != "" {
+				c.setVar(name, arg.rawProp("Argument.value"), loc)
+			}
+		}
+	case "CSVDataSet":
+		scope.datasets = append(scope.datasets, el)
+	case "CookieManager":
+		c.report.warn(loc, class, "引擎不会自动保存 Cookie，如依赖会话 Cookie，请使用 cookie 类型的提取参数后置处理器传递")
+	case "CacheManager", "DNSCacheManager":
+		c.report.info(loc, class, "缓存管理器已忽略")
+
+	case "ConstantTimer", "UniformRandomTimer", "GaussianRandomTimer", "PoissonRandomTimer":
+		if p, ok := c.timer(el, loc); ok {
+			scope.timers = append(scope.timers, p)
+		}
+
+	case "JSONPostProcessor":
+		scope.postProcs = append(scope.postProcs, c.jsonExtractors(el, loc)...)
+	case "RegexExtractor":
+		if p, ok := c.regexExtractor(el, loc); ok {
+			scope.postProcs = append(scope.postProcs, p)
+		}
+	case "BoundaryExtractor":
+		if p, ok := c.boundaryExtractor(el, loc); ok {
+			scope.postProcs = append(scope.postProcs, p)
+		}
+	case "DebugPostProcessor", "ResultAction":
+		c.report.info(loc, class, "调试元件已忽略")
+
+	case "ResponseAssertion":
+		if p, ok := c.responseAssertion(el, loc); ok {
+			scope.assertions = append(scope.assertions, p)
+		}
+	case "JSONPathAssertion":
+		if p, ok := c.jsonPathAssertion(el, loc); ok {
+			scope.assertions = append(scope.assertions, p)
+		}
+	case "DurationAssertion":
+		if ms := c.intValue(el.prop("DurationAssertion.duration"), loc, "响应时间上限", 0); ms > 0 {
+			scope.assertions = append(scope.assertions, newAssertion(el.name(), "response_time", "lte", "", strconv.Itoa(ms)))
+		}
+
+	default:
+		switch {
+		case strings.HasPrefix(class, "JSR223") || strings.HasPrefix(class, "BeanShell"):
+			c.report.warn(loc, class, "%s 脚本未转换，请改写为 JavaScript 脚本处理器", scriptLanguage(el))
+		case strings.HasSuffix(class, "ResultCollector") || strings.HasSuffix(class, "Listener") ||
+			strings.HasSuffix(class, "Visualizer") || class == "Summariser":
+			c.report.info(loc, class, "监听器已忽略，结果由引擎指标输出")
+		default:
+			c.report.warn(loc, class, "不支持的元件，未转换")
+		}
+	}
+}
+
+func scriptLanguage(el *jmxElement) string {
+	if strings.HasPrefix(el.class(), "BeanShell") {
+		return "BeanShell"
+	}
+	if lang := el.prop("scriptLanguage"); lang != "" {
+		return lang
+	}
+	return "groovy"
+}
+
+// convertChildren 转换控制器或线程组的子元件，返回按顺序排列的步骤
+func (c *jmxConverter) convertChildren(children []*jmxElement, parent *jmxScope) []types.Step {
+	scope := parent.inherit(parent.path)
+	for _, el := range children {
+		if el.enabled() && c.isScopeClass(el) {
+			c.collectScopeElement(el, scope, scope.path+" / "+el.name())
+		}
+	}
+
+	steps := c.datasetSteps(scope.datasets, scope.path)
+	var pendingWait []types.Processor // 思考时间附加到下一个步骤
+	for _, el := range children {
+		loc := scope.path + " / " + el.name()
+		if !el.enabled() {
+			c.report.info(loc, el.class(), "元件已禁用，未转换")
+			continue
+		}
+		if c.isScopeClass(el) {
+			continue
+		}
+		if el.class() == "TestAction" {
+			if p, ok := c.flowControlAction(el, loc); ok {
+				pendingWait = append(pendingWait, p)
+			}
+			continue
+		}
+		converted := c.convertElement(el, scope, loc)
+		if len(converted) > 0 && len(pendingWait) > 0 {
+			converted[0].PreProcessors = append(c.ids.processors(pendingWait), converted[0].PreProcessors...)
+			pendingWait = nil
+		}
+		steps = append(steps, converted...)
+	}
+	if len(pendingWait) > 0 && len(steps) > 0 {
+		last := &steps[len(steps)-1]
+		last.PostProcessors = append(last.PostProcessors, c.ids.processors(pendingWait)...)
+	}
+	return steps
+}
+
+// convertElement 转换采样器或控制器
+func (c *jmxConverter) convertElement(el *jmxElement, scope *jmxScope, loc string) []types.Step {
+	class := el.class()
+	switch class {
+	case "HTTPSamplerProxy", "HTTPSampler":
+		return []types.Step{c.httpStep(el, scope, loc)}
+
+	case "GenericController", "TransactionController":
+		return c.convertChildren(el.children, scope.inherit(loc))
+
+	case "LoopController":
+		count := c.intValue(el.prop("LoopController.loops"), loc, "循环次数", 1)
+		if count <= 0 {
+			c.report.warn(loc, class, "无限循环未转换，已按 1 次循环处理")
+			count = 1
+		}
+		return c.loopStep(el, scope, loc, &types.Loop{Mode: "for", Count: count})
+
+	case "WhileController":
+		cond := el.prop("WhileController.condition")
+		if cond == "" || cond == "LAST" {
+			c.report.warn(loc, class, "按上一个采样器结果循环的条件未转换，已按 1 次循环处理")
+			return c.loopStep(el, scope, loc, &types.Loop{Mode: "for", Count: 1})
+		}
+		return c.loopStep(el, scope, loc, &types.Loop{Mode: "while", Condition: c.condition(cond, loc, class)})
+
+	case "ForeachController":
+		input := el.prop("ForeachController.inputVal")
+		c.report.warn(loc, class, "JMeter 遍历的是 %s_1…%s_N 形式的变量，已转换为遍历变量 %s，请确保该变量为数组", input, input, input)
+		return c.loopStep(el, scope, loc, &types.Loop{
+			Mode:    "foreach",
+			Items:   "${" + input + "}",
+			ItemVar: el.prop("ForeachController.returnVal"),
+		})
+
+	case "IfController":
+		cond := el.prop("IfController.condition")
+		if el.boolProp("IfController.evaluateAll", false) {
+			c.report.warn(loc, class, "对每个子元件重新判断条件的设置未转换")
+		}
+		children := c.convertChildren(el.children, scope.inherit(loc))
+		if len(children) == 0 {
+			return nil
+		}
+		id := c.ids.next(el.name(), "condition")
+		return []types.Step{{
+			ID:   id,
+			Name: el.name(),
+			Type: "condition",
+			Branches: []types.ConditionBranch{{
+				ID:         id + "_if",
+				Kind:       types.ConditionTypeIf,
+				Expression: c.condition(cond, loc, class),
+				Steps:      children,
+			}},
+		}}
+
+	case "OnceOnlyController", "RandomController", "RandomOrderController", "InterleaveControl",
+		"ThroughputController", "SwitchController", "RunTime", "CriticalSectionController":
+		c.report.warn(loc, class, "控制器语义未转换，其中的元件按顺序展开执行")
+		return c.convertChildren(el.children, scope.inherit(loc))
+
+	case "DebugSampler":
+		c.report.info(loc, class, "调试采样器已忽略")
+		return nil
+
+	default:
+		switch {
+		case strings.HasSuffix(class, "Controller"):
+			c.report.warn(loc, class, "不支持的控制器，其中的元件按顺序展开执行")
+			return c.convertChildren(el.children, scope.inherit(loc))
+		case strings.HasSuffix(class, "Sampler") || strings.HasSuffix(class, "SamplerProxy"):
+			c.report.warn(loc, class, "不支持的采样器，未转换")
+		default:
+			c.report.warn(loc, class, "不支持的元件，未转换")
+		}
+		return nil
+	}
+}
+
+// loopStep 生成循环步骤，子元件转换为循环体
+func (c *jmxConverter) loopStep(el *jmxElement, scope *jmxScope, loc string, loop *types.Loop) []types.Step {
+	loop.Steps = c.convertChildren(el.children, scope.inherit(loc))
+	if len(loop.Steps) == 0 {
+		return nil
+	}
+	return []types.Step{{
+		ID:   c.ids.next(el.name(), "loop"),
+		Name: el.name(),
+		Type: "loop",
+		Loop: loop,
+	}}
+}
+
+// jmxConditionFunc 匹配 If/While 控制器中的 ${__jexl3(...)} 等表达式函数
+var jmxConditionFunc = regexp.MustCompile(`^\$\{__(jexl2|jexl3|groovy|javaScript)\((.*)\)\}$`)
+
+// jmxQuotedVar 匹配 "${var}" 形式的带引号变量引用
+var jmxQuotedVar = regexp.MustCompile(`"(\$\{[^}]+\})"`)
+
+// condition 将 JMeter 条件转换为引擎表达式；无法解析的条件原样保留并记录
+func (c *jmxConverter) condition(raw, loc, element string) string {
+	expr := raw
+	if m := jmxConditionFunc.FindStringSubmatch(raw); m != nil {
+		expr = m[2]
+		// 去掉函数的第二个参数（结果变量名）
+		if i := strings.LastIndex(expr, ","); i > 0 && !strings.ContainsAny(expr[i:], "\"'()=<>") {
+			expr = expr[:i]
+		}
+	}
+	expr = jmxQuotedVar.ReplaceAllString(expr, "$1")
+	expr = strings.NewReplacer("&&", " and ", "||", " or ").Replace(expr)
+	expr = strings.Join(strings.Fields(expr), " ")
+	if _, err := expression.ParseExpression(expr); err != nil {
+		c.report.warn(loc, element, "条件 %q 无法转换为引擎表达式，已原样保留，请手动修改", raw)
+		return raw
+	}
+	if expr != raw {
+		c.report.info(loc, element, "条件 %q 已转换为 %q", raw, expr)
+	}
+	return expr
+}
+
+// httpStep 将 HTTP 采样器转换为 http 步骤
+func (c *jmxConverter) httpStep(el *jmxElement, parent *jmxScope, loc string) types.Step {
+	scope := parent.inherit(loc)
+	for _, child := range el.children {
+		childLoc := loc + " / " + child.name()
+		if !child.enabled() {
+			c.report.info(childLoc, child.class(), "元件已禁用，未转换")
+			continue
+		}
+		if c.isScopeClass(child) {
+			c.collectScopeElement(child, scope, childLoc)
+		} else {
+			c.report.warn(childLoc, child.class(), "采样器下的元件未转换")
+		}
+	}
+	if len(scope.datasets) > 0 {
+		c.report.warn(loc, "CSVDataSet", "采样器下的 CSV 数据集未转换，请移动到线程组或控制器下")
+	}
+
+	prop := func(key string) string {
+		if v := el.prop(key); v != "" {
+			return v
+		}
+		return scope.defaults[key]
+	}
+	c.funcs.begin()
+
+	method := strings.ToUpper(el.prop("HTTPSampler.method"))
+	if method == "" {
+		method = "GET"
+	}
+	config := map[string]any{
+		"method": method,
+		"url":    c.expand(jmxURL(prop("HTTPSampler.protocol"), prop("HTTPSampler.domain"), prop("HTTPSampler.port"), el.prop("HTTPSampler.path"), scope.defaults["HTTPSampler.path"]), loc),
+	}
+
+	headers := make(map[string]any, len(scope.headers))
+	for _, h := range scope.headers {
+		headers[h[0]] = c.expand(h[1], loc)
+	}
+
+	args := el.arguments("HTTPsampler.Arguments")
+	switch {
+	case el.boolProp("HTTPSampler.postBodyRaw", false):
+		raw := ""
+		if len(args) > 0 {
+			raw = c.expand(args[0].rawProp("Argument.value"), loc)
+		}
+		config["body"] = map[string]any{"type": rawBodyType(scope.headers, raw), "raw": raw}
+	case len(args) > 0:
+		pairs := make(map[string]any, len(args))
+		for _, arg := range args {
+			name, value := arg.prop("Argument.name"), arg.rawProp("Argument.value")
+			if name == "" {
+				continue
+			}
+			pairs[c.expand(name, loc)] = c.expand(value, loc)
+		}
+		switch {
+		case method == "GET" || method == "DELETE" || method == "HEAD" || method == "OPTIONS":
+			config["params"] = pairs
+		case el.boolProp("HTTPSampler.DO_MULTIPART_POST", false):
+			config["body"] = map[string]any{"type": "form-data", "formData": pairs}
+		default:
+			config["body"] = map[string]any{"type": "x-www-form-urlencoded", "urlencoded": pairs}
+		}
+	}
+	if files := el.child("HTTPsampler.Files").collection("HTTPFileArgs.files"); len(files) > 0 {
+		c.report.warn(loc, "HTTPSamplerProxy", "上传文件 %s 未转换，请手动配置请求体", files[0].prop("File.path"))
+	}
+	if el.boolProp("HTTPSampler.image_parser", false) {
+		c.report.warn(loc, "HTTPSamplerProxy", "下载内嵌资源的设置未转换")
+	}
+	if !el.boolProp("HTTPSampler.follow_redirects", true) && !el.boolProp("HTTPSampler.auto_redirects", false) {
+		config["redirect"] = map[string]any{"follow": false}
+	}
+	if len(headers) > 0 {
+		config["headers"] = headers
+	}
+
+	step := types.Step{
+		ID:     c.ids.next(el.name(), "http"),
+		Name:   el.name(),
+		Type:   "http",
+		Config: config,
+	}
+	if ms := c.intValue(prop("HTTPSampler.response_timeout"), loc, "响应超时", 0); ms > 0 {
+		step.Timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	pre := append(append([]types.Processor(nil), scope.preProcs...), c.funcs.end()...)
+	step.PreProcessors = c.ids.processors(append(pre, scope.timers...))
+	step.PostProcessors = c.ids.processors(append(append([]types.Processor(nil), scope.postProcs...), scope.assertions...))
+	return step
+}
+
+// jmxURL 拼接请求地址；路径本身为完整地址时直接使用
+func jmxURL(protocol, domain, port, path, defaultPath string) string {
+	if path == "" {
+		path = defaultPath
+	}
+	lower := strings.ToLower(path)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return path
+	}
+	if domain == "" {
+		return path
+	}
+	if protocol == "" {
+		protocol = "http"
+	}
+	host := domain
+	if port != "" && !(protocol == "http" && port == "80") && !(protocol == "https" && port == "443") {
+		host += ":" + port
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return strings.ToLower(protocol) + "://" + host + path
+}
+
+// rawBodyType 根据 Content-Type 或内容推断原始请求体类型
+func rawBodyType(headers [][2]string, raw string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h[0], "Content-Type") {
+			ct := strings.ToLower(h[1])
+			switch {
+			case strings.Contains(ct, "json"):
+				return "json"
+			case strings.Contains(ct, "xml"):
+				return "xml"
+			default:
+				return "text"
+			}
+		}
+	}
+	if trimmed := strings.TrimSpace(raw); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return "json"
+	}
+	return "text"
+}
+
+// datasetSteps 将 CSV 数据集转换为脚本步骤：文件内容内联到脚本中，每次迭代随机取一行写入变量
+func (c *jmxConverter) datasetSteps(datasets []*jmxElement, scopePath string) []types.Step {
+	var steps []types.Step
+	for _, el := range datasets {
+		loc := scopePath + " / " + el.name()
+		names, rows, err := c.readDataset(el)
+		for _, name := range names {
+			if _, ok := c.vars[name]; !ok {
+				c.vars[name] = ""
+			}
+		}
+		if err != nil {
+			c.report.warn(loc, "CSVDataSet", "%s，已将变量 %s 定义为空值，请手动提供数据", err.Error(), strings.Join(names, ", "))
+			continue
+		}
+		if len(rows) > csvMaxRows {
+			c.report.warn(loc, "CSVDataSet", "数据共 %d 行，仅内联前 %d 行", len(rows), csvMaxRows)
+			rows = rows[:csvMaxRows]
+		}
+		c.report.warn(loc, "CSVDataSet", "已改为每次迭代随机取一行（JMeter 按顺序读取），共 %d 行", len(rows))
+
+		var b strings.Builder
+		b.WriteString("var rows = [\n")
+		for _, row := range rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = jsString(cell)
+			}
+			b.WriteString("  [" + strings.Join(cells, ", ") + "],\n")
+		}
+		b.WriteString("];\nvar row = rows[Math.floor(Math.random() * rows.length)] || [];\n")
+		for i, name := range names {
+			fmt.Fprintf(&b, "vars.set(%s, row[%d] === undefined ? \"\" : row[%d]);\n", jsString(name), i, i)
+		}
+		steps = append(steps, scriptStep(c.ids.next(el.name(), "csv_data"), el.name(), b.String()))
+	}
+	return steps
+}
+
+// readDataset 读取 CSV 数据集，返回变量名与数据行
+func (c *jmxConverter) readDataset(el *jmxElement) ([]string, [][]string, error) {
+	filename := el.prop("filename")
+	var names []string
+	for _, n := range strings.Split(el.prop("variableNames"), ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	if filename == "" || strings.Contains(filename, "${") {
+		return names, nil, fmt.Errorf("CSV 文件路径 %q 无法在转换时解析", filename)
+	}
+	path := filename
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.baseDir, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return names, nil, fmt.Errorf("无法读取 CSV 文件 %s", filename)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+	switch d := el.prop("delimiter"); d {
+	case "", ",":
+	case "\\t":
+		r.Comma = '\t'
+	default:
+		r.Comma = []rune(d)[0]
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		return names, nil, fmt.Errorf("CSV 文件 %s 解析失败: %s", filename, err.Error())
+	}
+	if len(names) == 0 && len(rows) > 0 {
+		names, rows = rows[0], rows[1:]
+	} else if el.boolProp("ignoreFirstLine", false) && len(rows) > 0 {
+		rows = rows[1:]
+	}
+	if len(rows) == 0 {
+		return names, nil, fmt.Errorf("CSV 文件 %s 中没有数据", filename)
+	}
+	return names, rows, nil
+}
+
+// timer 将定时器转换为 wait 前置处理器，随机定时器按平均值近似
+func (c *jmxConverter) timer(el *jmxElement, loc string) (types.Processor, bool) {
+	class := el.class()
+	delay := c.intValue(el.prop("ConstantTimer.delay"), loc, "延迟", 0)
+	rng := c.intValue(el.prop("RandomTimer.range"), loc, "随机范围", 0)
+	switch class {
+	case "UniformRandomTimer":
+		c.report.warn(loc, class, "随机延迟 %d~%dms 已近似为固定延迟 %dms", delay, delay+rng, delay+rng/2)
+		delay += rng / 2
+	case "GaussianRandomTimer":
+		c.report.warn(loc, class, "高斯随机延迟已近似为固定延迟 %dms", delay)
+	case "PoissonRandomTimer":
+		c.report.warn(loc, class, "泊松随机延迟已近似为固定延迟 %dms", delay+rng)
+		delay += rng
+	}
+	if delay <= 0 {
+		return types.Processor{}, false
+	}
+	return newWait(el.name(), delay), true
+}
+
+// flowControlAction 转换 Flow Control Action：仅支持暂停（思考时间）
+func (c *jmxConverter) flowControlAction(el *jmxElement, loc string) (types.Processor, bool) {
+	if el.prop("ActionProcessor.action") != "1" {
+		c.report.warn(loc, "TestAction", "仅支持暂停动作，其余流程控制动作未转换")
+		return types.Processor{}, false
+	}
+	ms := c.intValue(el.prop("ActionProcessor.duration"), loc, "暂停时间", 0)
+	if ms <= 0 {
+		return types.Processor{}, false
+	}
+	return newWait(el.name(), ms), true
+}
+
+// jsonExtractors 转换 JSON 提取器，每个变量生成一个提取参数处理器
+func (c *jmxConverter) jsonExtractors(el *jmxElement, loc string) []types.Processor {
+	names := strings.Split(el.prop("JSONPostProcessor.referenceNames"), ";")
+	exprs := strings.Split(el.prop("JSONPostProcessor.jsonPathExprs"), ";")
+	matches := strings.Split(el.prop("JSONPostProcessor.match_numbers"), ";")
+	if el.prop("JSONPostProcessor.defaultValues") != "" {
+		c.report.warn(loc, "JSONPostProcessor", "提取失败时的默认值未转换")
+	}
+
+	var out []types.Processor
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || i >= len(exprs) {
+			continue
+		}
+		expr := strings.TrimSpace(exprs[i])
+		if strings.Contains(expr, "..") || strings.Contains(expr, "[*]") || strings.Contains(expr, "?(") {
+			c.report.warn(loc, "JSONPostProcessor", "JSONPath %s 使用了深度扫描、通配符或过滤器，引擎仅支持逐级路径，请手动调整", expr)
+		}
+		if i < len(matches) {
+			switch m := strings.TrimSpace(matches[i]); m {
+			case "", "1":
+			case "0":
+				c.report.warn(loc, "JSONPostProcessor", "变量 %s 随机取一个匹配的设置未转换，已改为提取路径对应的值", name)
+			case "-1":
+				c.report.warn(loc, "JSONPostProcessor", "变量 %s 提取全部匹配（%s_1…%s_N）的设置未转换，已改为提取路径对应的值", name, name, name)
+			default:
+				c.report.warn(loc, "JSONPostProcessor", "变量 %s 取第 %s 个匹配的设置未转换", name, m)
+			}
+		}
+		out = append(out, newExtract(el.name(), "jsonpath", expr, name))
+	}
+	return out
+}
+
+// regexExtractor 转换正则提取器
+func (c *jmxConverter) regexExtractor(el *jmxElement, loc string) (types.Processor, bool) {
+	field := el.prop("RegexExtractor.useHeaders")
+	name := el.prop("RegexExtractor.refname")
+	pattern := el.rawProp("RegexExtractor.regex")
+	template := el.prop("RegexExtractor.template")
+	return c.regexProcessor(el, loc, field, name, pattern, template, el.prop("RegexExtractor.match_number"), el.prop("RegexExtractor.default"))
+}
+
+// boundaryExtractor 将边界提取器转换为等价的正则提取
+func (c *jmxConverter) boundaryExtractor(el *jmxElement, loc string) (types.Processor, bool) {
+	pattern := "(?s)" + regexp.QuoteMeta(el.rawProp("BoundaryExtractor.lboundary")) + "(.*?)" + regexp.QuoteMeta(el.rawProp("BoundaryExtractor.rboundary"))
+	return c.regexProcessor(el, loc, el.prop("BoundaryExtractor.useHeaders"), el.prop("BoundaryExtractor.refname"), pattern, "$1$",
+		el.prop("BoundaryExtractor.match_number"), el.prop("BoundaryExtractor.default"))
+}
+
+func (c *jmxConverter) regexProcessor(el *jmxElement, loc, field, name, pattern, template, match, def string) (types.Processor, bool) {
+	class := el.class()
+	if field != "" && field != "false" {
+		c.report.warn(loc, class, "仅支持从响应体提取，提取范围 %s 未转换", field)
+		return types.Processor{}, false
+	}
+	switch template {
+	case "$1$", "":
+	case "$0$":
+		pattern = "(" + pattern + ")"
+	default:
+		c.report.warn(loc, class, "模板 %s 未转换，已改为提取第一个捕获组", template)
+	}
+	if match != "" && match != "1" {
+		c.report.warn(loc, class, "变量 %s 取第 %s 个匹配的设置未转换，已改为提取第一个匹配", name, match)
+	}
+	if def != "" {
+		c.report.warn(loc, class, "提取失败时的默认值未转换")
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		c.report.warn(loc, class, "正则表达式 %s 不兼容: %s", pattern, err.Error())
+		return types.Processor{}, false
+	}
+	return newExtract(el.name(), "regex", pattern, name), true
+}
+
+// JMeter 响应断言的匹配规则位
+const (
+	jmxMatch     = 1
+	jmxContains  = 2
+	jmxNot       = 4
+	jmxEquals    = 8
+	jmxSubstring = 16
+	jmxOr        = 32
+)
+
+// responseAssertion 转换响应断言：状态码与响应体的包含、匹配、相等、子串规则
+func (c *jmxConverter) responseAssertion(el *jmxElement, loc string) (types.Processor, bool) {
+	class := el.class()
+	var assertType string
+	switch field := el.prop("Assertion.test_field"); field {
+	case "Assertion.response_code":
+		assertType = "status_code"
+	case "Assertion.response_data", "":
+		assertType = "response_body"
+	default:
+		c.report.warn(loc, class, "断言字段 %s 未转换，仅支持响应码与响应体", strings.TrimPrefix(field, "Assertion."))
+		return types.Processor{}, false
+	}
+	if el.boolProp("Assertion.assume_success", false) {
+		c.report.warn(loc, class, "忽略状态的设置未转换")
+	}
+
+	var patterns []string
+	for _, s := range el.collection("Asserion.test_strings") {
+		patterns = append(patterns, c.expand(s.Text, loc))
+	}
+	if len(patterns) == 0 {
+		return types.Processor{}, false
+	}
+
+	rule := c.intValue(el.prop("Assertion.test_type"), loc, "匹配规则", jmxSubstring)
+	negate := rule&jmxNot != 0
+	if len(patterns) > 1 && rule&jmxOr == 0 {
+		c.report.warn(loc, class, "多个匹配模式已合并为任一匹配，JMeter 要求全部匹配")
+	}
+
+	operator, expected := "", ""
+	switch {
+	case len(patterns) == 1 && (rule&jmxSubstring != 0 || (rule&jmxContains != 0 && !hasRegexMeta(patterns[0]))):
+		operator, expected = "contains", patterns[0]
+		if assertType == "status_code" {
+			operator = "eq"
+		}
+	case len(patterns) == 1 && (rule&jmxEquals != 0 || (rule&jmxMatch != 0 && !hasRegexMeta(patterns[0]))):
+		operator, expected = "eq", patterns[0]
+	default:
+		alts := make([]string, len(patterns))
+		for i, p := range patterns {
+			if rule&(jmxSubstring|jmxEquals) != 0 {
+				p = regexp.QuoteMeta(p)
+			}
+			alts[i] = "(?:" + p + ")"
+		}
+		expected = strings.Join(alts, "|")
+		if rule&(jmxMatch|jmxEquals) != 0 {
+			expected = "^(?:" + expected + ")$"
+		}
+		if _, err := regexp.Compile(expected); err != nil {
+			c.report.warn(loc, class, "正则表达式 %s 不兼容: %s", expected, err.Error())
+			return types.Processor{}, false
+		}
+		operator = "matches"
+	}
+	if negate {
+		operator = map[string]string{"contains": "not_contains", "eq": "ne", "matches": "not_matches"}[operator]
+	}
+	return newAssertion(el.name(), assertType, operator, "", expected), true
+}
+
+// jsonPathAssertion 转换 JSON 断言；未启用值校验时断言路径存在
+func (c *jmxConverter) jsonPathAssertion(el *jmxElement, loc string) (types.Processor, bool) {
+	path := el.prop("JSON_PATH")
+	invert := el.boolProp("INVERT", false)
+	if !el.boolProp("JSONVALIDATION", false) {
+		operator := "ne"
+		if invert {
+			operator = "eq"
+		}
+		return newAssertion(el.name(), "jsonpath", operator, path, ""), true
+	}
+	if el.boolProp("EXPECT_NULL", false) {
+		c.report.warn(loc, "JSONPathAssertion", "期望值为 null 的断言未转换")
+		return types.Processor{}, false
+	}
+
+	expected := c.expand(el.rawProp("EXPECTED_VALUE"), loc)
+	operator := "eq"
+	if el.boolProp("ISREGEX", true) && hasRegexMeta(expected) {
+		operator, expected = "matches", "^(?:"+expected+")$"
+	}
+	if invert {
+		operator = map[string]string{"eq": "ne", "matches": "not_matches"}[operator]
+	}
+	return newAssertion(el.name(), "jsonpath", operator, path, expected), true
+}
+
+func hasRegexMeta(s string) bool {
+	return regexp.QuoteMeta(s) != s
+}
+
+// jmxFuncPattern 匹配不含嵌套的 JMeter 函数调用 ${__name(args)}
+var jmxFuncPattern = regexp.MustCompile(`\$\{__(\w+)\(([^()]*)\)\}`)
+
+// jmxFuncs 收集当前采样器中 JMeter 函数对应的前置脚本
+type jmxFuncs struct {
+	active bool
+	seen   map[string]bool
+	procs  []types.Processor
+}
+
+func newJMXFuncs() *jmxFuncs {
+	return &jmxFuncs{}
+}
+
+func (f *jmxFuncs) begin() {
+	f.active, f.seen, f.procs = true, make(map[string]bool), nil
+}
+
+func (f *jmxFuncs) end() []types.Processor {
+	procs := f.procs
+	f.active, f.seen, f.procs = false, nil, nil
+	return procs
+}
+
+// add 登记生成变量的前置脚本，同一采样器内同名变量只生成一次
+func (f *jmxFuncs) add(variable, name, code string) bool {
+	if !f.active {
+		return false
+	}
+	if !f.seen[variable] {
+		f.seen[variable] = true
+		f.procs = append(f.procs, newScriptProcessor(name, code))
+	}
+	return true
+}
+
+// expand 转换字符串中的 JMeter 函数：__P / __property 转换为工作流变量，
+// __Random / __UUID / __time / __RandomString 在采样器中转换为前置脚本生成的变量，其余函数原样保留
+func (c *jmxConverter) expand(s, loc string) string {
+	if !strings.Contains(s, "${__") {
+		return s
+	}
+	return jmxFuncPattern.ReplaceAllStringFunc(s, func(match string) string {
+		m := jmxFuncPattern.FindStringSubmatch(match)
+		fn, args := m[1], splitFuncArgs(m[2])
+		var variable, code string
+		switch fn {
+		case "P", "property":
+			name, def := funcArg(args, 0), funcArg(args, 1)
+			if fn == "property" {
+				def = funcArg(args, 2)
+			}
+			if _, ok := c.vars[name]; !ok {
+				c.vars[name] = def
+			}
+			c.report.info(loc, "__"+fn, "属性 %s 已转换为工作流变量（默认值 %q）", name, def)
+			return "${" + name + "}"
+		case "Random":
+			lo, hi := funcArg(args, 0), funcArg(args, 1)
+			variable = funcArg(args, 2)
+			if variable == "" {
+				variable = "random_" + slugify(lo+"_"+hi)
+			}
+			code = fmt.Sprintf("vars.set(%s, String(Math.floor(Math.random() * ((%s) - (%s) + 1)) + (%s)));", jsString(variable), hi, lo, lo)
+		case "UUID":
+			variable = "uuid"
+			code = `vars.set("uuid", "xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx".replace(/[xy]/g, function (ch) {
+  var r = Math.random() * 16 | 0;
+  return (ch === "x" ? r : (r & 0x3 | 0x8)).toString(16);
+}));`
+		case "time":
+			switch funcArg(args, 0) {
+			case "":
+				variable, code = "timestamp", `vars.set("timestamp", String(Date.now()));`
+			case "/1000":
+				variable, code = "timestamp_s", `vars.set("timestamp_s", String(Math.floor(Date.now() / 1000)));`
+			}
+		case "RandomString":
+			variable = funcArg(args, 2)
+			if variable == "" {
+				variable = "random_string"
+			}
+			chars := funcArg(args, 1)
+			if chars == "" {
+				chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+			}
+			code = fmt.Sprintf(`var s = "", chars = %s;
+for (var i = 0; i < (%s); i++) s += chars.charAt(Math.floor(Math.random() * chars.length));
+vars.set(%s, s);`, jsString(chars), funcArg(args, 0), jsString(variable))
+		}
+		if code != "" && c.funcs.add(variable, "__"+fn, code) {
+			return "${" + variable + "}"
+		}
+		c.report.warn(loc, "__"+fn, "JMeter 函数 %s 未转换，已原样保留", match)
+		return match
+	})
+}
+
+// splitFuncArgs 拆分函数参数，支持 \, 转义
+func splitFuncArgs(s string) []string {
+	var args []string
+	var cur strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == ',':
+			cur.WriteByte(',')
+			i++
+		case s[i] == ',':
+			args = append(args, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(s[i])
+		}
+	}
+	return append(args, cur.String())
+}
+
+func funcArg(args []string, i int) string {
+	if i < len(args) {
+		return strings.TrimSpace(args[i])
+	}
+	return ""
+}
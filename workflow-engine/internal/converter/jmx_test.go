@@ -0,0 +1,233 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/internal/parser"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+const testPlan = `<?xml version="1.0" encoding="UTF-8"?>
+<jmeterTestPlan version="1.2" properties="5.0" jmeter="5.6.3">
+  <hashTree>
+    <TestPlan guiclass="TestPlanGui" testclass="TestPlan" testname="Shop" enabled="true">
+      <elementProp name="TestPlan.user_defined_variables" elementType="Arguments">
+        <collectionProp name="Arguments.arguments">
+          <elementProp name="host" elementType="Argument">
+            <stringProp name="Argument.name">host</stringProp>
+            <stringProp name="Argument.value">${__P(host,shop.example.com)}</stringProp>
+          </elementProp>
+        </collectionProp>
+      </elementProp>
+    </TestPlan>
+    <hashTree>
+      <HeaderManager guiclass="HeaderPanel" testclass="HeaderManager" testname="Headers" enabled="true">
+        <collectionProp name="HeaderManager.headers">
+          <elementProp name="" elementType="Header">
+            <stringProp name="Header.name">Content-Type</stringProp>
+            <stringProp name="Header.value">application/json</stringProp>
+          </elementProp>
+        </collectionProp>
+      </HeaderManager>
+      <hashTree/>
+      <ThreadGroup guiclass="ThreadGroupGui" testclass="ThreadGroup" testname="Buyers" enabled="true">
+        <stringProp name="ThreadGroup.num_threads">20</stringProp>
+        <stringProp name="ThreadGroup.ramp_time">10</stringProp>
+        <boolProp name="ThreadGroup.scheduler">true</boolProp>
+        <stringProp name="ThreadGroup.duration">60</stringProp>
+        <elementProp name="ThreadGroup.main_controller" elementType="LoopController">
+          <intProp name="LoopController.loops">-1</intProp>
+          <boolProp name="LoopController.continue_forever">false</boolProp>
+        </elementProp>
+      </ThreadGroup>
+      <hashTree>
+        <CSVDataSet guiclass="TestBeanGUI" testclass="CSVDataSet" testname="Users" enabled="true">
+          <stringProp name="filename">users.csv</stringProp>
+          <stringProp name="variableNames">user,password</stringProp>
+        </CSVDataSet>
+        <hashTree/>
+        <HTTPSamplerProxy guiclass="HttpTestSampleGui" testclass="HTTPSamplerProxy" testname="Login" enabled="true">
+          <stringProp name="HTTPSampler.domain">${host}</stringProp>
+          <stringProp name="HTTPSampler.protocol">https</stringProp>
+          <stringProp name="HTTPSampler.path">/api/login</stringProp>
+          <stringProp name="HTTPSampler.method">POST</stringProp>
+          <boolProp name="HTTPSampler.postBodyRaw">true</boolProp>
+          <elementProp name="HTTPsampler.Arguments" elementType="Arguments">
+            <collectionProp name="Arguments.arguments">
+              <elementProp name="" elementType="HTTPArgument">
+                <stringProp name="Argument.value">{"user": "${user}", "nonce": "${__UUID()}"}</stringProp>
+              </elementProp>
+            </collectionProp>
+          </elementProp>
+        </HTTPSamplerProxy>
+        <hashTree>
+          <JSONPostProcessor guiclass="JSONPostProcessorGui" testclass="JSONPostProcessor" testname="Token" enabled="true">
+            <stringProp name="JSONPostProcessor.referenceNames">token</stringProp>
+            <stringProp name="JSONPostProcessor.jsonPathExprs">$.data.token</stringProp>
+          </JSONPostProcessor>
+          <hashTree/>
+          <ResponseAssertion guiclass="AssertionGui" testclass="ResponseAssertion" testname="Status" enabled="true">
+            <collectionProp name="Asserion.test_strings">
+              <stringProp name="49586">200</stringProp>
+            </collectionProp>
+            <stringProp name="Assertion.test_field">Assertion.response_code</stringProp>
+            <intProp name="Assertion.test_type">8</intProp>
+          </ResponseAssertion>
+          <hashTree/>
+        </hashTree>
+        <LoopController guiclass="LoopControlPanel" testclass="LoopController" testname="Browse" enabled="true">
+          <stringProp name="LoopController.loops">3</stringProp>
+        </LoopController>
+        <hashTree>
+          <ConstantTimer guiclass="ConstantTimerGui" testclass="ConstantTimer" testname="Think" enabled="true">
+            <stringProp name="ConstantTimer.delay">500</stringProp>
+          </ConstantTimer>
+          <hashTree/>
+          <HTTPSamplerProxy guiclass="HttpTestSampleGui" testclass="HTTPSamplerProxy" testname="List" enabled="true">
+            <stringProp name="HTTPSampler.domain">${host}</stringProp>
+            <stringProp name="HTTPSampler.protocol">https</stringProp>
+            <stringProp name="HTTPSampler.path">/api/items</stringProp>
+            <stringProp name="HTTPSampler.method">GET</stringProp>
+            <elementProp name="HTTPsampler.Arguments" elementType="Arguments">
+              <collectionProp name="Arguments.arguments">
+                <elementProp name="page" elementType="HTTPArgument">
+                  <stringProp name="Argument.name">page</stringProp>
+                  <stringProp name="Argument.value">1</stringProp>
+                </elementProp>
+              </collectionProp>
+            </elementProp>
+          </HTTPSamplerProxy>
+          <hashTree>
+            <JSR223PostProcessor guiclass="TestBeanGUI" testclass="JSR223PostProcessor" testname="Groovy" enabled="true">
+              <stringProp name="scriptLanguage">groovy</stringProp>
+              <stringProp name="script">log.info("x")</stringProp>
+            </JSR223PostProcessor>
+            <hashTree/>
+          </hashTree>
+        </hashTree>
+        <IfController guiclass="IfControllerPanel" testclass="IfController" testname="Has token" enabled="true">
+          <stringProp name="IfController.condition">${__jexl3("${token}" != "")}</stringProp>
+          <boolProp name="IfController.useExpression">true</boolProp>
+        </IfController>
+        <hashTree>
+          <HTTPSamplerProxy guiclass="HttpTestSampleGui" testclass="HTTPSamplerProxy" testname="Checkout" enabled="true">
+            <stringProp name="HTTPSampler.domain">${host}</stringProp>
+            <stringProp name="HTTPSampler.path">/api/checkout</stringProp>
+            <stringProp name="HTTPSampler.method">POST</stringProp>
+          </HTTPSamplerProxy>
+          <hashTree/>
+        </hashTree>
+        <ResultCollector guiclass="ViewResultsFullVisualizer" testclass="ResultCollector" testname="View Results Tree" enabled="true"/>
+        <hashTree/>
+      </hashTree>
+      <ThreadGroup guiclass="ThreadGroupGui" testclass="ThreadGroup" testname="Disabled" enabled="false">
+        <stringProp name="ThreadGroup.num_threads">1</stringProp>
+      </ThreadGroup>
+      <hashTree/>
+    </hashTree>
+  </hashTree>
+</jmeterTestPlan>
+`
+
+func convertTestPlan(t *testing.T) *Result {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users.csv"), []byte("alice,secret\nbob,hunter2\n"), 0644))
+	path := filepath.Join(dir, "shop.jmx")
+	require.NoError(t, os.WriteFile(path, []byte(testPlan), 0644))
+
+	result, err := ConvertFile(path, "")
+	require.NoError(t, err)
+	return result
+}
+
+func TestConvertJMX_ThreadGroup(t *testing.T) {
+	result := convertTestPlan(t)
+	require.Len(t, result.Workflows, 1)
+
+	wf := result.Workflows[0]
+	assert.Equal(t, "shop_buyers", wf.ID)
+	assert.Equal(t, "Shop - Buyers", wf.Name)
+	assert.Equal(t, types.ModeRampingVUs, wf.Options.ExecutionMode)
+	assert.Equal(t, []types.Stage{
+		{Duration: 10 * time.Second, Target: 20},
+		{Duration: 50 * time.Second, Target: 20},
+	}, wf.Options.Stages)
+	assert.Equal(t, "shop.example.com", wf.Variables["host"])
+}
+
+func TestConvertJMX_Steps(t *testing.T) {
+	wf := convertTestPlan(t).Workflows[0]
+	require.Len(t, wf.Steps, 4)
+
+	csv := wf.Steps[0]
+	assert.Equal(t, "script", csv.Type)
+	assert.Contains(t, csv.Config["script"], `["bob", "hunter2"]`)
+
+	login := wf.Steps[1]
+	assert.Equal(t, "https://${host}/api/login", login.Config["url"])
+	assert.Equal(t, map[string]any{"Content-Type": "application/json"}, login.Config["headers"])
+	body := login.Config["body"].(map[string]any)
+	assert.Equal(t, "json", body["type"])
+	assert.Equal(t, `{"user": "${user}", "nonce": "${uuid}"}`, body["raw"])
+	require.Len(t, login.PreProcessors, 1)
+	assert.Equal(t, "js_script", login.PreProcessors[0].Type)
+	require.Len(t, login.PostProcessors, 2)
+	assert.Equal(t, "$.data.token", login.PostProcessors[0].Config["expression"])
+	assert.Equal(t, map[string]any{"assertType": "status_code", "operator": "eq", "expected": "200"}, login.PostProcessors[1].Config)
+
+	browse := wf.Steps[2]
+	require.NotNil(t, browse.Loop)
+	assert.Equal(t, 3, browse.Loop.Count)
+	list := browse.Loop.Steps[0]
+	assert.Equal(t, map[string]any{"page": "1"}, list.Config["params"])
+	require.Len(t, list.PreProcessors, 1)
+	assert.Equal(t, 500, list.PreProcessors[0].Config["duration"])
+
+	checkout := wf.Steps[3]
+	assert.Equal(t, "condition", checkout.Type)
+	require.Len(t, checkout.Branches, 1)
+	assert.Equal(t, `${token} != ""`, checkout.Branches[0].Expression)
+}
+
+func TestConvertJMX_Report(t *testing.T) {
+	report := convertTestPlan(t).Report
+
+	var warnings, infos []string
+	for _, it := range report.Items {
+		if it.Level == LevelWarning {
+			warnings = append(warnings, it.Element)
+		} else {
+			infos = append(infos, it.Element)
+		}
+	}
+	assert.Contains(t, warnings, "JSR223PostProcessor")
+	assert.Contains(t, warnings, "CSVDataSet")
+	assert.Contains(t, infos, "ResultCollector")
+	assert.Contains(t, infos, "ThreadGroup")
+	assert.Contains(t, report.String(), "Shop / Buyers / Browse / List / Groovy")
+}
+
+func TestConvertJMX_OutputParses(t *testing.T) {
+	printer := parser.NewYAMLPrinter()
+	for _, wf := range convertTestPlan(t).Workflows {
+		data, err := printer.Print(wf)
+		require.NoError(t, err)
+		_, err = parser.NewYAMLParser().Parse(data)
+		require.NoError(t, err, string(data))
+	}
+}
+
+func TestConvertJMX_Invalid(t *testing.T) {
+	_, err := ConvertJMX([]byte(`<root/>`), "x.jmx")
+	assert.Error(t, err)
+
+	_, err = ConvertJMX([]byte(strings.Replace(testPlan, `testname="Buyers" enabled="true"`, `testname="Buyers" enabled="false"`, 1)), "x.jmx")
+	assert.Error(t, err)
+}
@@ -0,0 +1,1641 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/parser"
+	"github.com/dop251/goja/token"
+
+	"yqhp/workflow-engine/pkg/script"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// k6DefaultFunc 预处理后默认导出函数绑定的变量名
+const k6DefaultFunc = "__k6default"
+
+var (
+	// k6ImportPattern 匹配 ES 模块导入语句：默认导入、命名空间导入与具名导入
+	k6ImportPattern = regexp.MustCompile(`(?m)^[ \t]*import\s+([^'";]*?)\s*from\s*['"]([^'"]+)['"][ \t]*;?|^[ \t]*import\s*['"]([^'"]+)['"][ \t]*;?`)
+	// k6ExportDefault 匹配默认导出
+	k6ExportDefault = regexp.MustCompile(`\bexport\s+default\s+`)
+	// k6ExportNamed 匹配具名导出声明
+	k6ExportNamed = regexp.MustCompile(`\bexport\s+((?:async\s+)?function|const|let|var)\b`)
+)
+
+// k6ThresholdMetrics 引擎阈值支持的 k6 指标
+var k6ThresholdMetrics = map[string]bool{
+	"http_req_duration":  true,
+	"http_req_failed":    true,
+	"iteration_duration": true,
+}
+
+// k6Converter k6 脚本转换状态
+type k6Converter struct {
+	report  *Report
+	source  string
+	src     string
+	file    *file.File
+	ids     *idAllocator
+	imports map[string]string         // 本地名称 → 模块成员，如 http → k6/http、check → k6.check
+	globals map[string]ast.Expression // 顶层常量
+	vars    map[string]any            // 工作流变量
+}
+
+// k6Scope 函数体内的转换状态
+type k6Scope struct {
+	locals    map[string]ast.Expression // 局部常量，使用时内联
+	runtime   map[string]string         // 运行时变量：提取结果、循环变量等，映射为 ${name}
+	responses map[string]k6StepRef      // 响应变量 → 对应步骤
+	pending   []types.Processor         // 思考时间，附加到下一个请求
+	prefix    string                    // group 名称前缀
+}
+
+// k6StepRef 指向步骤列表中的步骤；列表扩容不影响索引
+type k6StepRef struct {
+	list  *[]types.Step
+	index int
+}
+
+func (r k6StepRef) step() *types.Step {
+	return &(*r.list)[r.index]
+}
+
+func (s *k6Scope) child(prefix string) *k6Scope {
+	c := &k6Scope{
+		locals:    make(map[string]ast.Expression, len(s.locals)),
+		runtime:   make(map[string]string, len(s.runtime)),
+		responses: make(map[string]k6StepRef, len(s.responses)),
+		prefix:    prefix,
+	}
+	for k, v := range s.locals {
+		c.locals[k] = v
+	}
+	for k, v := range s.runtime {
+		c.runtime[k] = v
+	}
+	for k, v := range s.responses {
+		c.responses[k] = v
+	}
+	return c
+}
+
+// ConvertK6 转换 k6 脚本：options 转换为执行选项，默认导出函数中的 http 请求转换为步骤，
+// check 转换为断言，sleep 转换为等待，group 展开为带前缀的步骤
+func ConvertK6(data []byte, source string) (*Result, error) {
+	c := &k6Converter{
+		report:  &Report{Source: source, Format: FormatK6},
+		source:  filepath.Base(source),
+		ids:     newIDAllocator(),
+		imports: make(map[string]string),
+		globals: make(map[string]ast.Expression),
+		vars:    make(map[string]any),
+	}
+	c.src = c.preprocess(string(data))
+
+	program, err := parser.ParseFile(nil, source, c.src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("解析 k6 脚本失败: %w", err)
+	}
+	c.file = program.File
+
+	var options *ast.ObjectLiteral
+	var main ast.Expression
+	for _, stmt := range program.Body {
+		switch s := stmt.(type) {
+		case *ast.VariableStatement:
+			c.topLevelBindings(s.List, &options, &main)
+		case *ast.LexicalDeclaration:
+			c.topLevelBindings(s.List, &options, &main)
+		case *ast.FunctionDeclaration:
+			c.topLevelFunction(s.Function)
+		case *ast.EmptyStatement:
+		default:
+			c.report.warn(c.loc(stmt), c.snippet(stmt), "顶层语句未转换")
+		}
+	}
+
+	fn, ok := main.(*ast.FunctionLiteral)
+	var body []ast.Statement
+	switch {
+	case ok:
+		body = fn.Body.List
+	default:
+		if arrow, isArrow := main.(*ast.ArrowFunctionLiteral); isArrow {
+			body = arrowBody(arrow)
+		}
+	}
+	if body == nil {
+		return nil, errors.New("k6 脚本中未找到默认导出函数（export default function）")
+	}
+
+	wf := &types.Workflow{
+		ID:          slugify(strings.TrimSuffix(c.source, filepath.Ext(c.source))),
+		Name:        strings.TrimSuffix(c.source, filepath.Ext(c.source)),
+		Description: "由 k6 脚本转换",
+	}
+	if wf.ID == "" {
+		wf.ID = "k6_workflow"
+	}
+	if options != nil {
+		wf.Options = c.options(options)
+	}
+
+	scope := &k6Scope{
+		locals:    make(map[string]ast.Expression),
+		runtime:   make(map[string]string),
+		responses: make(map[string]k6StepRef),
+	}
+	c.block(body, &wf.Steps, scope)
+	c.flushWait(&wf.Steps, scope)
+	if len(wf.Steps) == 0 {
+		return nil, errors.New("k6 脚本的默认函数中没有可转换的请求")
+	}
+	if len(c.vars) > 0 {
+		wf.Variables = c.vars
+	}
+	return &Result{Workflows: []*types.Workflow{wf}, Report: c.report}, nil
+}
+
+// preprocess 去除 goja 不支持的 import / export 语法并记录导入绑定，保持行号不变
+func (c *k6Converter) preprocess(src string) string {
+	src = k6ImportPattern.ReplaceAllStringFunc(src, func(stmt string) string {
+		m := k6ImportPattern.FindStringSubmatch(stmt)
+		if m[3] != "" {
+			c.report.warn(c.lineOf(src, stmt), "import", "副作用导入 %s 未转换", m[3])
+		} else {
+			c.bindImports(m[1], m[2], c.lineOf(src, stmt))
+		}
+		return strings.Repeat("\n", strings.Count(stmt, "\n"))
+	})
+	src = k6ExportDefault.ReplaceAllString(src, "var "+k6DefaultFunc+" = ")
+	return k6ExportNamed.ReplaceAllString(src, "$1")
+}
+
+// lineOf 返回语句在源码中的位置描述
+func (c *k6Converter) lineOf(src, stmt string) string {
+	return fmt.Sprintf("%s:%d", c.source, strings.Count(src[:strings.Index(src, stmt)], "\n")+1)
+}
+
+// bindImports 记录导入的绑定：默认导入绑定模块本身，具名导入绑定 模块.成员
+func (c *k6Converter) bindImports(clause, module, loc string) {
+	switch module {
+	case "k6", "k6/http":
+	default:
+		c.report.warn(loc, "import", "模块 %s 未转换，依赖它的语句将被跳过", module)
+	}
+	clause = strings.TrimSpace(clause)
+	if i := strings.Index(clause, "{"); i >= 0 {
+		named := strings.TrimSuffix(strings.TrimSpace(clause[i+1:]), "}")
+		for _, item := range strings.Split(named, ",") {
+			parts := strings.Fields(item)
+			switch {
+			case len(parts) == 1:
+				c.imports[parts[0]] = module + "." + parts[0]
+			case len(parts) == 3 && parts[1] == "as":
+				c.imports[parts[2]] = module + "." + parts[0]
+			}
+		}
+		clause = strings.TrimSuffix(strings.TrimSpace(clause[:i]), ",")
+	}
+	if clause = strings.TrimSpace(strings.TrimPrefix(clause, "* as ")); clause != "" {
+		c.imports[clause] = module
+	}
+}
+
+func (c *k6Converter) topLevelBindings(list []*ast.Binding, options **ast.ObjectLiteral, main *ast.Expression) {
+	for _, b := range list {
+		id, ok := b.Target.(*ast.Identifier)
+		if !ok || b.Initializer == nil {
+			continue
+		}
+		switch name := string(id.Name); name {
+		case k6DefaultFunc:
+			*main = b.Initializer
+		case "options":
+			if obj, ok := b.Initializer.(*ast.ObjectLiteral); ok {
+				*options = obj
+			} else {
+				c.report.warn(c.loc(b.Initializer), "options", "options 不是对象字面量，未转换")
+			}
+		default:
+			c.globals[name] = b.Initializer
+			if call, ok := b.Initializer.(*ast.NewExpression); ok {
+				c.report.warn(c.loc(call), c.snippet(call), "顶层对象 %s 未转换，引用它的语句将被跳过", name)
+			}
+		}
+	}
+}
+
+func (c *k6Converter) topLevelFunction(fn *ast.FunctionLiteral) {
+	if fn.Name == nil {
+		return
+	}
+	switch name := string(fn.Name.Name); name {
+	case "setup", "teardown":
+		c.report.warn(c.loc(fn), name, "%s 阶段未转换，请将其中的请求拆分为独立工作流", name)
+	case "handleSummary":
+		c.report.info(c.loc(fn), name, "自定义结果摘要已忽略，结果由引擎报告输出")
+	default:
+		c.report.warn(c.loc(fn), name, "自定义函数 %s 未转换，调用它的语句将被跳过", name)
+	}
+}
+
+// options 转换 k6 options：vus / duration / iterations / stages / scenarios / thresholds / tags
+func (c *k6Converter) options(obj *ast.ObjectLiteral) types.ExecutionOptions {
+	var opts types.ExecutionOptions
+	for _, p := range objectProps(obj) {
+		loc := c.loc(p.value)
+		switch p.key {
+		case "vus":
+			opts.VUs = c.intOption(p.value, "vus")
+		case "duration":
+			opts.Duration = c.durationOption(p.value, "duration")
+		case "iterations":
+			opts.Iterations = c.intOption(p.value, "iterations")
+		case "stages":
+			opts.Stages = c.stages(p.value)
+		case "thresholds":
+			opts.Thresholds = c.thresholds(p.value)
+		case "scenarios":
+			c.scenario(p.value, &opts)
+		case "tags":
+			opts.Tags = c.stringMap(p.value)
+		case "summaryTrendStats", "summaryTimeUnit", "discardResponseBodies", "noUsageReport", "ext", "cloud":
+			c.report.info(loc, "options."+p.key, "选项已忽略")
+		default:
+			c.report.warn(loc, "options."+p.key, "选项未转换")
+		}
+	}
+
+	if opts.ExecutionMode == "" {
+		switch {
+		case len(opts.Stages) > 0:
+			opts.ExecutionMode = types.ModeRampingVUs
+		case opts.Iterations > 0:
+			opts.ExecutionMode = types.ModeSharedIterations
+			if opts.Duration > 0 {
+				c.report.warn(c.loc(obj), "options", "同时设置了 iterations 与 duration，duration 作为最长执行时间的语义未转换")
+			}
+		case opts.Duration > 0:
+			opts.ExecutionMode = types.ModeConstantVUs
+		}
+	}
+	if opts.VUs == 0 && opts.ExecutionMode != types.ModeRampingVUs && opts.ExecutionMode != "" {
+		opts.VUs = 1
+	}
+	return opts
+}
+
+// scenario 转换场景，仅支持第一个场景
+func (c *k6Converter) scenario(expr ast.Expression, opts *types.ExecutionOptions) {
+	obj, ok := expr.(*ast.ObjectLiteral)
+	if !ok {
+		c.report.warn(c.loc(expr), "options.scenarios", "scenarios 不是对象字面量，未转换")
+		return
+	}
+	props := objectProps(obj)
+	if len(props) == 0 {
+		return
+	}
+	for _, p := range props[1:] {
+		c.report.warn(c.loc(p.value), "scenarios."+p.key, "引擎每个工作流仅支持一个场景，已转换第一个场景 %s", props[0].key)
+	}
+	name := props[0].key
+	sc, ok := props[0].value.(*ast.ObjectLiteral)
+	if !ok {
+		c.report.warn(c.loc(props[0].value), "scenarios."+name, "场景不是对象字面量，未转换")
+		return
+	}
+
+	var executor string
+	var rate, startRate int
+	timeUnit := time.Second
+	var rateLoc ast.Node
+	for _, p := range objectProps(sc) {
+		field := "scenarios." + name + "." + p.key
+		switch p.key {
+		case "executor":
+			executor, _ = c.str(p.value, nil)
+		case "vus":
+			opts.VUs = c.intOption(p.value, field)
+		case "duration":
+			opts.Duration = c.durationOption(p.value, field)
+		case "iterations":
+			opts.Iterations = c.intOption(p.value, field)
+		case "stages":
+			opts.Stages = c.stages(p.value)
+		case "rate":
+			rate, rateLoc = c.intOption(p.value, field), p.value
+		case "startRate":
+			startRate = c.intOption(p.value, field)
+		case "timeUnit":
+			timeUnit = c.durationOption(p.value, field)
+		case "startVUs":
+			if n := c.intOption(p.value, field); n > 0 {
+				c.report.warn(c.loc(p.value), field, "起始 VU 数 %d 未转换，阶段从 0 开始加压", n)
+			}
+		case "exec":
+			if fn, _ := c.str(p.value, nil); fn != "default" {
+				c.report.warn(c.loc(p.value), field, "场景执行函数 %s 未转换，已使用默认函数", fn)
+			}
+		case "tags":
+			opts.Tags = c.stringMap(p.value)
+		case "preAllocatedVUs", "maxVUs", "gracefulStop", "gracefulRampDown":
+			c.report.info(c.loc(p.value), field, "由引擎自动管理，已忽略")
+		default:
+			c.report.warn(c.loc(p.value), field, "场景选项未转换")
+		}
+	}
+
+	// 引擎的到达率以每秒迭代数计算
+	perSecond := func(n int, loc ast.Node, field string) int {
+		if timeUnit == time.Second || n == 0 {
+			return n
+		}
+		v := float64(n) / timeUnit.Seconds()
+		r := int(math.Round(v))
+		if r < 1 {
+			r = 1
+		}
+		if float64(r) != v {
+			c.report.warn(c.loc(loc), field, "到达率 %d/%s 已近似为 %d/s", n, timeUnit, r)
+		}
+		return r
+	}
+
+	switch executor {
+	case "constant-vus", "ramping-vus", "per-vu-iterations", "shared-iterations", "externally-controlled":
+		opts.ExecutionMode = types.ExecutionMode(executor)
+	case "constant-arrival-rate":
+		opts.ExecutionMode = types.ModeConstantArrivalRate
+		opts.VUs = perSecond(rate, rateLoc, "scenarios."+name+".rate")
+	case "ramping-arrival-rate":
+		opts.ExecutionMode = types.ModeRampingArrivalRate
+		opts.VUs = 0
+		if startRate > 0 {
+			c.report.warn(c.loc(sc), "scenarios."+name+".startRate", "起始到达率 %d 未转换，阶段从 0 开始", startRate)
+		}
+		for i := range opts.Stages {
+			opts.Stages[i].Target = perSecond(opts.Stages[i].Target, sc, "scenarios."+name+".stages")
+		}
+	default:
+		c.report.warn(c.loc(sc), "scenarios."+name, "不支持的执行器 %q，已按 constant-vus 转换", executor)
+		opts.ExecutionMode = types.ModeConstantVUs
+	}
+}
+
+// stages 转换 [{ duration, target }] 阶段列表
+func (c *k6Converter) stages(expr ast.Expression) []types.Stage {
+	arr, ok := expr.(*ast.ArrayLiteral)
+	if !ok {
+		c.report.warn(c.loc(expr), "stages", "stages 不是数组字面量，未转换")
+		return nil
+	}
+	var stages []types.Stage
+	for _, item := range arr.Value {
+		obj, ok := item.(*ast.ObjectLiteral)
+		if !ok {
+			continue
+		}
+		var st types.Stage
+		for _, p := range objectProps(obj) {
+			switch p.key {
+			case "duration":
+				st.Duration = c.durationOption(p.value, "stages.duration")
+			case "target":
+				st.Target = c.intOption(p.value, "stages.target")
+			}
+		}
+		stages = append(stages, st)
+	}
+	return stages
+}
+
+// thresholds 转换阈值，条件格式与引擎一致（如 p(95) < 500）
+func (c *k6Converter) thresholds(expr ast.Expression) []types.Threshold {
+	obj, ok := expr.(*ast.ObjectLiteral)
+	if !ok {
+		c.report.warn(c.loc(expr), "thresholds", "thresholds 不是对象字面量，未转换")
+		return nil
+	}
+	var out []types.Threshold
+	for _, p := range objectProps(obj) {
+		field := "thresholds." + p.key
+		if !k6ThresholdMetrics[p.key] {
+			c.report.warn(c.loc(p.value), field, "引擎阈值仅支持 http_req_duration、http_req_failed 与 iteration_duration，未转换")
+			continue
+		}
+		var items []ast.Expression
+		if arr, ok := p.value.(*ast.ArrayLiteral); ok {
+			items = arr.Value
+		} else {
+			items = []ast.Expression{p.value}
+		}
+		for _, item := range items {
+			cond, ok := c.str(item, nil)
+			if obj, isObj := item.(*ast.ObjectLiteral); isObj {
+				for _, tp := range objectProps(obj) {
+					switch tp.key {
+					case "threshold":
+						cond, ok = c.str(tp.value, nil)
+					case "abortOnFail", "delayAbortEval":
+						c.report.warn(c.loc(tp.value), field+"."+tp.key, "阈值触发中止的设置未转换")
+					}
+				}
+			}
+			if !ok || cond == "" {
+				c.report.warn(c.loc(item), field, "无法解析阈值条件，未转换")
+				continue
+			}
+			out = append(out, types.Threshold{Metric: p.key, Condition: normalizeCondition(cond)})
+		}
+	}
+	return out
+}
+
+// k6ConditionPattern 匹配阈值条件中的比较运算符
+var k6ConditionPattern = regexp.MustCompile(`\s*(<=|>=|==|!=|<|>)\s*`)
+
+func normalizeCondition(cond string) string {
+	return k6ConditionPattern.ReplaceAllString(strings.TrimSpace(cond), " $1 ")
+}
+
+func (c *k6Converter) intOption(expr ast.Expression, field string) int {
+	if n, ok := c.number(expr, nil); ok {
+		return int(n)
+	}
+	c.report.warn(c.loc(expr), field, "无法解析为整数，未转换")
+	return 0
+}
+
+func (c *k6Converter) durationOption(expr ast.Expression, field string) time.Duration {
+	s, ok := c.str(expr, nil)
+	if ok {
+		if d, err := parseK6Duration(s); err == nil {
+			return d
+		}
+	}
+	c.report.warn(c.loc(expr), field, "无法解析为时长，未转换")
+	return 0
+}
+
+// parseK6Duration 解析 k6 时长：Go 时长字符串、带 d 的天数或毫秒数
+func parseK6Duration(s string) (time.Duration, error) {
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(n * float64(time.Millisecond)), nil
+	}
+	if i := strings.Index(s, "d"); i > 0 {
+		days, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, err
+		}
+		rest := time.Duration(0)
+		if s[i+1:] != "" {
+			if rest, err = time.ParseDuration(s[i+1:]); err != nil {
+				return 0, err
+			}
+		}
+		return time.Duration(days)*24*time.Hour + rest, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func (c *k6Converter) stringMap(expr ast.Expression) map[string]string {
+	obj, ok := expr.(*ast.ObjectLiteral)
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string)
+	for _, p := range objectProps(obj) {
+		if v, ok := c.str(p.value, nil); ok {
+			out[p.key] = v
+		}
+	}
+	return out
+}
+
+// block 转换语句列表，生成的步骤追加到 out
+func (c *k6Converter) block(stmts []ast.Statement, out *[]types.Step, scope *k6Scope) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.VariableStatement:
+			c.bindings(s.List, out, scope)
+		case *ast.LexicalDeclaration:
+			c.bindings(s.List, out, scope)
+		case *ast.ExpressionStatement:
+			c.expressionStatement(s.Expression, out, scope)
+		case *ast.BlockStatement:
+			c.block(s.List, out, scope)
+		case *ast.ForStatement:
+			c.forLoop(s, out, scope)
+		case *ast.ForOfStatement:
+			c.forOfLoop(s, out, scope)
+		case *ast.EmptyStatement:
+		case *ast.ReturnStatement:
+			if s.Argument != nil {
+				c.report.warn(c.loc(s), c.snippet(s), "返回值未转换")
+			}
+		case *ast.IfStatement:
+			c.report.warn(c.loc(s), c.snippet(s), "条件分支未转换，请使用条件步骤手动实现")
+		default:
+			c.report.warn(c.loc(s), c.snippet(s), "语句未转换")
+		}
+	}
+}
+
+func (c *k6Converter) bindings(list []*ast.Binding, out *[]types.Step, scope *k6Scope) {
+	for _, b := range list {
+		id, ok := b.Target.(*ast.Identifier)
+		if !ok {
+			c.report.warn(c.loc(b.Target), c.snippet(b.Target), "解构赋值未转换")
+			continue
+		}
+		if b.Initializer == nil {
+			continue
+		}
+		c.assign(string(id.Name), b.Initializer, out, scope)
+	}
+}
+
+// assign 处理 name = expr：请求结果记录为响应变量，响应提取转换为提取参数，其余作为局部常量内联
+func (c *k6Converter) assign(name string, expr ast.Expression, out *[]types.Step, scope *k6Scope) {
+	if call, ok := expr.(*ast.CallExpression); ok {
+		if c.isHTTPCall(call) {
+			if ref, ok := c.request(call, out, scope); ok {
+				scope.responses[name] = ref
+			}
+			return
+		}
+		if c.callee(call) == "k6.check" {
+			c.check(call, out, scope)
+			return
+		}
+	}
+	if respVar, subject, ok := c.responseSubject(expr, scope); ok {
+		ref := scope.responses[respVar]
+		var extractType, expression string
+		switch subject.kind {
+		case "jsonpath":
+			extractType, expression = "jsonpath", subject.expression
+		case "header":
+			extractType, expression = "header", subject.expression
+		case "cookie":
+			extractType, expression = "cookie", subject.expression
+		default:
+			c.report.warn(c.loc(expr), c.snippet(expr), "仅支持从响应中提取 JSON 字段、响应头与 Cookie，未转换")
+			return
+		}
+		step := ref.step()
+		step.PostProcessors = append(step.PostProcessors, c.ids.processors([]types.Processor{newExtract("提取 "+name, extractType, expression, name)})...)
+		scope.runtime[name] = name
+		return
+	}
+	scope.locals[name] = expr
+	delete(scope.runtime, name)
+}
+
+func (c *k6Converter) expressionStatement(expr ast.Expression, out *[]types.Step, scope *k6Scope) {
+	switch e := expr.(type) {
+	case *ast.CallExpression:
+		if c.isHTTPCall(e) {
+			c.request(e, out, scope)
+			return
+		}
+		switch callee := c.callee(e); callee {
+		case "k6.check":
+			c.check(e, out, scope)
+		case "k6.sleep":
+			c.sleep(e, out, scope)
+		case "k6.group":
+			c.group(e, out, scope)
+		case "console.log", "console.info", "console.warn", "console.error", "console.debug":
+			c.report.info(c.loc(e), c.snippet(e), "日志输出已忽略")
+		default:
+			c.report.warn(c.loc(e), c.snippet(e), "函数调用未转换")
+		}
+	case *ast.AssignExpression:
+		if id, ok := e.Left.(*ast.Identifier); ok && e.Operator == token.ASSIGN {
+			c.assign(string(id.Name), e.Right, out, scope)
+			return
+		}
+		c.report.warn(c.loc(e), c.snippet(e), "赋值语句未转换")
+	default:
+		c.report.warn(c.loc(expr), c.snippet(expr), "表达式未转换")
+	}
+}
+
+// callee 返回被调用函数的限定名：导入的函数解析为 模块.成员，其余返回源码中的点分名称
+func (c *k6Converter) callee(call *ast.CallExpression) string {
+	switch fn := call.Callee.(type) {
+	case *ast.Identifier:
+		if mod, ok := c.imports[string(fn.Name)]; ok {
+			return mod
+		}
+		return string(fn.Name)
+	case *ast.DotExpression:
+		if obj, ok := fn.Left.(*ast.Identifier); ok {
+			base := string(obj.Name)
+			if mod, ok := c.imports[base]; ok {
+				base = mod
+			}
+			return base + "." + string(fn.Identifier.Name)
+		}
+	}
+	return ""
+}
+
+func (c *k6Converter) isHTTPCall(call *ast.CallExpression) bool {
+	return strings.HasPrefix(c.callee(call), "k6/http.")
+}
+
+// request 将 http.get / post / put / patch / del / head / options / request 调用转换为 http 步骤
+func (c *k6Converter) request(call *ast.CallExpression, out *[]types.Step, scope *k6Scope) (k6StepRef, bool) {
+	fn := strings.TrimPrefix(c.callee(call), "k6/http.")
+	args := call.ArgumentList
+	arg := func(i int) ast.Expression {
+		if i < len(args) {
+			return args[i]
+		}
+		return nil
+	}
+
+	var method string
+	var urlExpr, bodyExpr, paramsExpr ast.Expression
+	switch fn {
+	case "get", "head":
+		method, urlExpr, paramsExpr = strings.ToUpper(fn), arg(0), arg(1)
+	case "post", "put", "patch", "del", "options":
+		method, urlExpr, bodyExpr, paramsExpr = strings.ToUpper(fn), arg(0), arg(1), arg(2)
+		if fn == "del" {
+			method = "DELETE"
+		}
+	case "request":
+		m, ok := c.str(arg(0), scope)
+		if !ok {
+			c.report.warn(c.loc(call), c.snippet(call), "无法解析请求方法，未转换")
+			return k6StepRef{}, false
+		}
+		method, urlExpr, bodyExpr, paramsExpr = strings.ToUpper(m), arg(1), arg(2), arg(3)
+	default:
+		c.report.warn(c.loc(call), c.snippet(call), "http.%s 未转换", fn)
+		return k6StepRef{}, false
+	}
+
+	url, ok := c.str(urlExpr, scope)
+	if !ok {
+		c.report.warn(c.loc(call), c.snippet(call), "无法解析请求地址，未转换")
+		return k6StepRef{}, false
+	}
+
+	config := map[string]any{"method": method, "url": url}
+	headers := make(map[string]any)
+	name := method + " " + url
+	var timeout time.Duration
+
+	if params, ok := c.resolve(paramsExpr, scope).(*ast.ObjectLiteral); ok {
+		for _, p := range objectProps(params) {
+			field := "params." + p.key
+			switch p.key {
+			case "headers":
+				if obj, ok := c.resolve(p.value, scope).(*ast.ObjectLiteral); ok {
+					for _, h := range objectProps(obj) {
+						if v, ok := c.str(h.value, scope); ok {
+							headers[h.key] = v
+						} else {
+							c.report.warn(c.loc(h.value), c.snippet(h.value), "无法解析请求头 %s，未转换", h.key)
+						}
+					}
+				}
+			case "tags":
+				if obj, ok := c.resolve(p.value, scope).(*ast.ObjectLiteral); ok {
+					for _, t := range objectProps(obj) {
+						if t.key == "name" {
+							if v, ok := c.str(t.value, scope); ok {
+								name = v
+							}
+						}
+					}
+				}
+			case "timeout":
+				if s, ok := c.str(p.value, scope); ok {
+					if d, err := parseK6Duration(s); err == nil {
+						timeout = d
+					}
+				}
+			case "redirects":
+				if n, ok := c.number(p.value, scope); ok && n == 0 {
+					config["redirect"] = map[string]any{"follow": false}
+				} else if ok {
+					config["redirect"] = map[string]any{"follow": true, "max_redirects": int(n)}
+				}
+			case "cookies":
+				if obj, ok := c.resolve(p.value, scope).(*ast.ObjectLiteral); ok {
+					var pairs []string
+					for _, ck := range objectProps(obj) {
+						if v, ok := c.str(ck.value, scope); ok {
+							pairs = append(pairs, ck.key+"="+v)
+						}
+					}
+					headers["Cookie"] = strings.Join(pairs, "; ")
+				}
+			case "responseType":
+				c.report.info(c.loc(p.value), field, "响应类型设置已忽略")
+			default:
+				c.report.warn(c.loc(p.value), field, "请求参数未转换")
+			}
+		}
+	} else if paramsExpr != nil {
+		c.report.warn(c.loc(paramsExpr), c.snippet(paramsExpr), "无法解析请求参数，未转换")
+	}
+
+	if bodyExpr != nil {
+		if body, ok := c.body(bodyExpr, headers, scope); ok {
+			if body != nil {
+				config["body"] = body
+			}
+		} else {
+			c.report.warn(c.loc(bodyExpr), c.snippet(bodyExpr), "无法解析请求体，未转换")
+		}
+	}
+	if len(headers) > 0 {
+		config["headers"] = headers
+	}
+
+	step := types.Step{
+		ID:      c.ids.next(name, "http"),
+		Name:    scope.prefix + name,
+		Type:    "http",
+		Config:  config,
+		Timeout: timeout,
+	}
+	if len(scope.pending) > 0 {
+		step.PreProcessors = c.ids.processors(scope.pending)
+		scope.pending = nil
+	}
+	*out = append(*out, step)
+	return k6StepRef{list: out, index: len(*out) - 1}, true
+}
+
+// body 转换请求体：字符串为原始请求体，JSON.stringify 为 JSON，对象字面量为表单
+func (c *k6Converter) body(expr ast.Expression, headers map[string]any, scope *k6Scope) (map[string]any, bool) {
+	expr = c.resolve(expr, scope)
+	switch e := expr.(type) {
+	case *ast.NullLiteral:
+		return nil, true
+	case *ast.Identifier:
+		if e.Name == "undefined" {
+			return nil, true
+		}
+	case *ast.ObjectLiteral:
+		form := make(map[string]any)
+		for _, p := range objectProps(e) {
+			v, ok := c.str(p.value, scope)
+			if !ok {
+				return nil, false
+			}
+			form[p.key] = v
+		}
+		return map[string]any{"type": "x-www-form-urlencoded", "urlencoded": form}, true
+	case *ast.CallExpression:
+		if c.callee(e) == "JSON.stringify" && len(e.ArgumentList) > 0 {
+			raw, ok := c.jsonText(e.ArgumentList[0], scope, "")
+			if !ok {
+				return nil, false
+			}
+			return map[string]any{"type": "json", "raw": raw}, true
+		}
+	}
+
+	raw, ok := c.str(expr, scope)
+	if !ok {
+		return nil, false
+	}
+	var kv [][2]string
+	for k, v := range headers {
+		s, _ := v.(string)
+		kv = append(kv, [2]string{k, s})
+	}
+	return map[string]any{"type": rawBodyType(kv, raw), "raw": raw}, true
+}
+
+// jsonText 按源码顺序将字面量序列化为格式化的 JSON，变量引用以 ${name} 字符串表示
+func (c *k6Converter) jsonText(expr ast.Expression, scope *k6Scope, indent string) (string, bool) {
+	expr = c.resolve(expr, scope)
+	inner := indent + "  "
+	switch e := expr.(type) {
+	case *ast.ObjectLiteral:
+		props := objectProps(e)
+		if len(props) == 0 {
+			return "{}", true
+		}
+		parts := make([]string, 0, len(props))
+		for _, p := range props {
+			v, ok := c.jsonText(p.value, scope, inner)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, inner+jsString(p.key)+": "+v)
+		}
+		return "{\n" + strings.Join(parts, ",\n") + "\n" + indent + "}", true
+	case *ast.ArrayLiteral:
+		if len(e.Value) == 0 {
+			return "[]", true
+		}
+		parts := make([]string, 0, len(e.Value))
+		for _, item := range e.Value {
+			v, ok := c.jsonText(item, scope, inner)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, inner+v)
+		}
+		return "[\n" + strings.Join(parts, ",\n") + "\n" + indent + "]", true
+	case *ast.NumberLiteral:
+		return e.Literal, true
+	case *ast.BooleanLiteral:
+		return e.Literal, true
+	case *ast.NullLiteral:
+		return "null", true
+	}
+	s, ok := c.str(expr, scope)
+	if !ok {
+		return "", false
+	}
+	return jsString(s), true
+}
+
+// resolve 将引用局部或顶层常量的标识符替换为其初始化表达式
+func (c *k6Converter) resolve(expr ast.Expression, scope *k6Scope) ast.Expression {
+	for i := 0; i < 16; i++ {
+		id, ok := expr.(*ast.Identifier)
+		if !ok {
+			return expr
+		}
+		name := string(id.Name)
+		if scope != nil {
+			if _, ok := scope.runtime[name]; ok {
+				return expr
+			}
+			if v, ok := scope.locals[name]; ok {
+				expr = v
+				continue
+			}
+		}
+		if v, ok := c.globals[name]; ok {
+			expr = v
+			continue
+		}
+		return expr
+	}
+	return expr
+}
+
+// str 将表达式转换为引擎字符串：字面量与模板字符串直接展开，运行时变量转换为 ${name}，
+// 顶层标量常量转换为工作流变量，__ENV.X 转换为工作流变量 X
+func (c *k6Converter) str(expr ast.Expression, scope *k6Scope) (string, bool) {
+	switch e := expr.(type) {
+	case nil:
+		return "", false
+	case *ast.StringLiteral:
+		return string(e.Value), true
+	case *ast.NumberLiteral:
+		return e.Literal, true
+	case *ast.BooleanLiteral:
+		return e.Literal, true
+	case *ast.TemplateLiteral:
+		if e.Tag != nil && c.callee(&ast.CallExpression{Callee: e.Tag}) != "k6/http.url" {
+			return "", false
+		}
+		var b strings.Builder
+		for i, el := range e.Elements {
+			b.WriteString(string(el.Parsed))
+			if i < len(e.Expressions) {
+				s, ok := c.str(e.Expressions[i], scope)
+				if !ok {
+					return "", false
+				}
+				b.WriteString(s)
+			}
+		}
+		return b.String(), true
+	case *ast.BinaryExpression:
+		switch e.Operator {
+		case token.PLUS:
+			l, ok1 := c.str(e.Left, scope)
+			r, ok2 := c.str(e.Right, scope)
+			return l + r, ok1 && ok2
+		case token.LOGICAL_OR, token.COALESCE:
+			if name, ok := c.envName(e.Left); ok {
+				def, _ := c.str(e.Right, scope)
+				c.defineVar(name, def)
+				c.report.info(c.loc(e), c.snippet(e), "环境变量 %s 已转换为工作流变量，默认值 %q", name, def)
+				return "${" + name + "}", true
+			}
+		}
+	case *ast.Identifier:
+		name := string(e.Name)
+		if scope != nil {
+			if v, ok := scope.runtime[name]; ok {
+				return "${" + v + "}", true
+			}
+			if v, ok := scope.locals[name]; ok {
+				return c.str(v, scope)
+			}
+		}
+		if v, ok := c.globals[name]; ok {
+			if s, ok := c.str(v, nil); ok {
+				c.defineVar(name, s)
+				return "${" + name + "}", true
+			}
+		}
+	case *ast.DotExpression:
+		if name, ok := c.envName(e); ok {
+			if _, exists := c.vars[name]; !exists {
+				c.defineVar(name, "")
+				c.report.warn(c.loc(e), c.snippet(e), "环境变量 %s 已转换为工作流变量，请填写其值", name)
+			}
+			return "${" + name + "}", true
+		}
+		field := string(e.Identifier.Name)
+		if base, ok := e.Left.(*ast.Identifier); ok && scope != nil {
+			if v, ok := scope.runtime[string(base.Name)]; ok {
+				return "${" + v + "." + field + "}", true
+			}
+		}
+		if obj, ok := c.resolve(e.Left, scope).(*ast.ObjectLiteral); ok {
+			for _, p := range objectProps(obj) {
+				if p.key == field {
+					return c.str(p.value, scope)
+				}
+			}
+		}
+	case *ast.CallExpression:
+		switch c.callee(e) {
+		case "encodeURIComponent", "encodeURI", "String":
+			if len(e.ArgumentList) == 1 {
+				return c.str(e.ArgumentList[0], scope)
+			}
+		case "JSON.stringify":
+			if len(e.ArgumentList) > 0 {
+				return c.jsonText(e.ArgumentList[0], scope, "")
+			}
+		}
+	}
+	return "", false
+}
+
+// envName 识别 __ENV.NAME 与 __ENV['NAME']
+func (c *k6Converter) envName(expr ast.Expression) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.DotExpression:
+		if id, ok := e.Left.(*ast.Identifier); ok && id.Name == "__ENV" {
+			return string(e.Identifier.Name), true
+		}
+	case *ast.BracketExpression:
+		if id, ok := e.Left.(*ast.Identifier); ok && id.Name == "__ENV" {
+			if s, ok := e.Member.(*ast.StringLiteral); ok {
+				return string(s.Value), true
+			}
+		}
+	}
+	return "", false
+}
+
+func (c *k6Converter) defineVar(name string, value any) {
+	if existing, ok := c.vars[name]; !ok || existing == "" {
+		c.vars[name] = value
+	}
+}
+
+// number 计算数值常量表达式
+func (c *k6Converter) number(expr ast.Expression, scope *k6Scope) (float64, bool) {
+	switch e := c.resolve(expr, scope).(type) {
+	case *ast.NumberLiteral:
+		switch v := e.Value.(type) {
+		case int64:
+			return float64(v), true
+		case float64:
+			return v, true
+		}
+	case *ast.UnaryExpression:
+		if e.Operator == token.MINUS {
+			v, ok := c.number(e.Operand, scope)
+			return -v, ok
+		}
+	case *ast.BinaryExpression:
+		l, ok1 := c.number(e.Left, scope)
+		r, ok2 := c.number(e.Right, scope)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		switch e.Operator {
+		case token.PLUS:
+			return l + r, true
+		case token.MINUS:
+			return l - r, true
+		case token.MULTIPLY:
+			return l * r, true
+		case token.SLASH:
+			if r != 0 {
+				return l / r, true
+			}
+		}
+	case *ast.StringLiteral:
+		v, err := strconv.ParseFloat(string(e.Value), 64)
+		return v, err == nil
+	}
+	return 0, false
+}
+
+// sleep 将 sleep(seconds) 转换为下一个请求的等待前置处理器；随机时长按平均值近似
+func (c *k6Converter) sleep(call *ast.CallExpression, out *[]types.Step, scope *k6Scope) {
+	if len(call.ArgumentList) == 0 {
+		return
+	}
+	arg := call.ArgumentList[0]
+	seconds, ok := c.number(arg, scope)
+	if !ok {
+		seconds, ok = c.randomAverage(arg, scope)
+		if !ok {
+			c.report.warn(c.loc(call), c.snippet(call), "无法解析等待时长，未转换")
+			return
+		}
+		c.report.warn(c.loc(call), c.snippet(call), "随机等待已近似为固定等待 %gs", seconds)
+	}
+	if ms := int(math.Round(seconds * 1000)); ms > 0 {
+		scope.pending = append(scope.pending, newWait(fmt.Sprintf("sleep(%g)", seconds), ms))
+	}
+}
+
+// randomAverage 计算 Math.random() * a + b 与 randomIntBetween(a, b) 的平均值
+func (c *k6Converter) randomAverage(expr ast.Expression, scope *k6Scope) (float64, bool) {
+	switch e := expr.(type) {
+	case *ast.CallExpression:
+		switch c.callee(e) {
+		case "Math.random":
+			return 0.5, true
+		case "randomIntBetween":
+		default:
+			// 从 jslib 导入时限定名为 模块地址.randomIntBetween
+			if !strings.HasSuffix(c.callee(e), ".randomIntBetween") {
+				return 0, false
+			}
+		}
+		if len(e.ArgumentList) == 2 {
+			lo, ok1 := c.number(e.ArgumentList[0], scope)
+			hi, ok2 := c.number(e.ArgumentList[1], scope)
+			return (lo + hi) / 2, ok1 && ok2
+		}
+	case *ast.BinaryExpression:
+		l, ok1 := c.number(e.Left, scope)
+		if !ok1 {
+			l, ok1 = c.randomAverage(e.Left, scope)
+		}
+		r, ok2 := c.number(e.Right, scope)
+		if !ok2 {
+			r, ok2 = c.randomAverage(e.Right, scope)
+		}
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		switch e.Operator {
+		case token.PLUS:
+			return l + r, true
+		case token.MINUS:
+			return l - r, true
+		case token.MULTIPLY:
+			return l * r, true
+		}
+	}
+	return 0, false
+}
+
+// flushWait 将末尾的等待附加到最后一个请求；循环等步骤不执行处理器，此时移至首个请求之前
+func (c *k6Converter) flushWait(out *[]types.Step, scope *k6Scope) {
+	if len(scope.pending) == 0 || len(*out) == 0 {
+		return
+	}
+	steps := *out
+	switch {
+	case steps[len(steps)-1].Type == "http":
+		last := &steps[len(steps)-1]
+		last.PostProcessors = append(last.PostProcessors, c.ids.processors(scope.pending)...)
+	case steps[0].Type == "http":
+		first := &steps[0]
+		first.PreProcessors = append(c.ids.processors(scope.pending), first.PreProcessors...)
+		c.report.info(c.source, "sleep", "末尾的等待已移至首个请求之前，每次迭代的总等待时间不变")
+	default:
+		c.report.warn(c.source, "sleep", "末尾的等待无法附加到请求，未转换")
+	}
+	scope.pending = nil
+}
+
+// group 展开 group(name, fn)，组内步骤名称带上组名前缀
+func (c *k6Converter) group(call *ast.CallExpression, out *[]types.Step, scope *k6Scope) {
+	if len(call.ArgumentList) < 2 {
+		return
+	}
+	name, ok := c.str(call.ArgumentList[0], scope)
+	body := functionBody(call.ArgumentList[1])
+	if !ok || body == nil {
+		c.report.warn(c.loc(call), c.snippet(call), "无法解析 group，未转换")
+		return
+	}
+	inner := scope.child(scope.prefix + name + " / ")
+	inner.pending = scope.pending
+	c.block(body, out, inner)
+	scope.pending = inner.pending
+}
+
+// forLoop 将 for (let i = 0; i < N; i++) 转换为计数循环，循环变量映射为 loop.index
+func (c *k6Converter) forLoop(s *ast.ForStatement, out *[]types.Step, scope *k6Scope) {
+	varName, start := "", -1.0
+	switch init := s.Initializer.(type) {
+	case *ast.ForLoopInitializerLexicalDecl:
+		if len(init.LexicalDeclaration.List) == 1 {
+			varName, start = c.loopInit(init.LexicalDeclaration.List[0], scope)
+		}
+	case *ast.ForLoopInitializerVarDeclList:
+		if len(init.List) == 1 {
+			varName, start = c.loopInit(init.List[0], scope)
+		}
+	}
+	test, ok := s.Test.(*ast.BinaryExpression)
+	if varName == "" || start != 0 || !ok {
+		c.report.warn(c.loc(s), c.snippet(s), "仅支持 for (let i = 0; i < N; i++) 形式的循环，未转换")
+		return
+	}
+	id, isIdent := test.Left.(*ast.Identifier)
+	limit, isNum := c.number(test.Right, scope)
+	if !isIdent || string(id.Name) != varName || !isNum || (test.Operator != token.LESS && test.Operator != token.LESS_OR_EQUAL) {
+		c.report.warn(c.loc(s), c.snippet(s), "仅支持 for (let i = 0; i < N; i++) 形式的循环，未转换")
+		return
+	}
+	count := int(limit)
+	if test.Operator == token.LESS_OR_EQUAL {
+		count++
+	}
+
+	inner := scope.child(scope.prefix)
+	inner.runtime[varName] = "loop.index"
+	delete(inner.locals, varName)
+	c.loopStep(fmt.Sprintf("循环 %d 次", count), "loop", &types.Loop{Mode: "for", Count: count}, s.Body, out, scope, inner)
+}
+
+func (c *k6Converter) loopInit(b *ast.Binding, scope *k6Scope) (string, float64) {
+	id, ok := b.Target.(*ast.Identifier)
+	if !ok || b.Initializer == nil {
+		return "", -1
+	}
+	n, ok := c.number(b.Initializer, scope)
+	if !ok {
+		return "", -1
+	}
+	return string(id.Name), n
+}
+
+// forOfLoop 将 for (const item of items) 转换为遍历循环，items 须为顶层数组常量
+func (c *k6Converter) forOfLoop(s *ast.ForOfStatement, out *[]types.Step, scope *k6Scope) {
+	var itemVar string
+	switch into := s.Into.(type) {
+	case *ast.ForDeclaration:
+		if id, ok := into.Target.(*ast.Identifier); ok {
+			itemVar = string(id.Name)
+		}
+	case *ast.ForIntoVar:
+		if id, ok := into.Binding.Target.(*ast.Identifier); ok {
+			itemVar = string(id.Name)
+		}
+	}
+	source, ok := s.Source.(*ast.Identifier)
+	if itemVar == "" || !ok {
+		c.report.warn(c.loc(s), c.snippet(s), "仅支持遍历顶层数组常量，未转换")
+		return
+	}
+	items, ok := c.value(c.resolve(source, scope))
+	if _, isArray := items.([]any); !ok || !isArray {
+		c.report.warn(c.loc(s), c.snippet(s), "仅支持遍历顶层数组常量，未转换")
+		return
+	}
+	c.defineVar(string(source.Name), items)
+
+	inner := scope.child(scope.prefix)
+	inner.runtime[itemVar] = itemVar
+	delete(inner.locals, itemVar)
+	c.loopStep("遍历 "+string(source.Name), "foreach "+string(source.Name), &types.Loop{Mode: "foreach", Items: "${" + string(source.Name) + "}", ItemVar: itemVar}, s.Body, out, scope, inner)
+}
+
+func (c *k6Converter) loopStep(name, idName string, loop *types.Loop, body ast.Statement, out *[]types.Step, scope, inner *k6Scope) {
+	inner.pending = scope.pending
+	scope.pending = nil
+	if block, ok := body.(*ast.BlockStatement); ok {
+		c.block(block.List, &loop.Steps, inner)
+	} else {
+		c.block([]ast.Statement{body}, &loop.Steps, inner)
+	}
+	c.flushWait(&loop.Steps, inner)
+	if len(loop.Steps) == 0 {
+		return
+	}
+	*out = append(*out, types.Step{
+		ID:   c.ids.next(idName, "loop"),
+		Name: scope.prefix + name,
+		Type: "loop",
+		Loop: loop,
+	})
+}
+
+// value 将字面量转换为 Go 值
+func (c *k6Converter) value(expr ast.Expression) (any, bool) {
+	switch e := expr.(type) {
+	case *ast.ObjectLiteral:
+		m := make(map[string]any)
+		for _, p := range objectProps(e) {
+			v, ok := c.value(p.value)
+			if !ok {
+				return nil, false
+			}
+			m[p.key] = v
+		}
+		return m, true
+	case *ast.ArrayLiteral:
+		arr := make([]any, 0, len(e.Value))
+		for _, item := range e.Value {
+			v, ok := c.value(item)
+			if !ok {
+				return nil, false
+			}
+			arr = append(arr, v)
+		}
+		return arr, true
+	case *ast.NumberLiteral:
+		return e.Value, true
+	case *ast.BooleanLiteral:
+		return e.Value, true
+	case *ast.NullLiteral:
+		return nil, true
+	}
+	return c.str(expr, nil)
+}
+
+// k6Subject check 或提取语句引用的响应字段
+type k6Subject struct {
+	kind       string // status_code / response_time / response_body / jsonpath / header / cookie
+	expression string
+}
+
+// responseSubject 识别对响应变量的字段访问：r.status、r.timings.duration、r.body、r.json('a.b')、
+// r.json().a.b、JSON.parse(r.body).a、r.headers['X']、r.cookies.name[0].value
+func (c *k6Converter) responseSubject(expr ast.Expression, scope *k6Scope) (string, k6Subject, bool) {
+	var path []string
+	for {
+		switch e := expr.(type) {
+		case *ast.DotExpression:
+			path = append([]string{string(e.Identifier.Name)}, path...)
+			expr = e.Left
+			continue
+		case *ast.BracketExpression:
+			switch m := e.Member.(type) {
+			case *ast.StringLiteral:
+				path = append([]string{string(m.Value)}, path...)
+			case *ast.NumberLiteral:
+				path = append([]string{"[" + m.Literal + "]"}, path...)
+			default:
+				return "", k6Subject{}, false
+			}
+			expr = e.Left
+			continue
+		case *ast.CallExpression:
+			// r.json(selector)
+			if dot, ok := e.Callee.(*ast.DotExpression); ok && dot.Identifier.Name == "json" {
+				if id, ok := dot.Left.(*ast.Identifier); ok {
+					if _, isResp := scope.responses[string(id.Name)]; isResp {
+						var selector []string
+						if len(e.ArgumentList) > 0 {
+							sel, ok := e.ArgumentList[0].(*ast.StringLiteral)
+							if !ok {
+								return "", k6Subject{}, false
+							}
+							for _, part := range strings.Split(string(sel.Value), ".") {
+								if _, err := strconv.Atoi(part); err == nil {
+									part = "[" + part + "]"
+								}
+								selector = append(selector, part)
+							}
+						}
+						return jsonSubject(string(id.Name), append(selector, path...))
+					}
+				}
+			}
+			// JSON.parse(r.body)
+			if c.callee(e) == "JSON.parse" && len(e.ArgumentList) == 1 {
+				if dot, ok := e.ArgumentList[0].(*ast.DotExpression); ok && dot.Identifier.Name == "body" {
+					if id, ok := dot.Left.(*ast.Identifier); ok {
+						if _, isResp := scope.responses[string(id.Name)]; isResp {
+							return jsonSubject(string(id.Name), path)
+						}
+					}
+				}
+			}
+			return "", k6Subject{}, false
+		case *ast.Identifier:
+			if _, isResp := scope.responses[string(e.Name)]; !isResp || len(path) == 0 {
+				return "", k6Subject{}, false
+			}
+			return string(e.Name), responseField(path), true
+		default:
+			return "", k6Subject{}, false
+		}
+	}
+}
+
+// responseField 将响应对象上的访问路径映射为断言或提取类型
+func responseField(path []string) k6Subject {
+	switch {
+	case len(path) == 1 && path[0] == "status":
+		return k6Subject{kind: "status_code"}
+	case len(path) == 1 && path[0] == "body":
+		return k6Subject{kind: "response_body"}
+	case len(path) == 2 && path[0] == "timings" && path[1] == "duration":
+		return k6Subject{kind: "response_time"}
+	case len(path) == 2 && path[0] == "headers":
+		return k6Subject{kind: "header", expression: path[1]}
+	case len(path) >= 2 && path[0] == "cookies":
+		return k6Subject{kind: "cookie", expression: path[1]}
+	}
+	return k6Subject{}
+}
+
+// jsonSubject 生成 JSON 字段引用；JSONPath 不支持 length 等属性访问
+func jsonSubject(respVar string, path []string) (string, k6Subject, bool) {
+	for _, p := range path {
+		if p == "length" {
+			return "", k6Subject{}, false
+		}
+	}
+	return respVar, k6Subject{kind: "jsonpath", expression: jsonPathOf(path)}, true
+}
+
+func jsonPathOf(path []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, p := range path {
+		if strings.HasPrefix(p, "[") {
+			b.WriteString(p)
+		} else {
+			b.WriteString("." + p)
+		}
+	}
+	return b.String()
+}
+
+// k6Operators JavaScript 比较运算符 → 断言操作符
+var k6Operators = map[token.Token]string{
+	token.STRICT_EQUAL:     "eq",
+	token.EQUAL:            "eq",
+	token.STRICT_NOT_EQUAL: "ne",
+	token.NOT_EQUAL:        "ne",
+	token.LESS:             "lt",
+	token.LESS_OR_EQUAL:    "lte",
+	token.GREATER:          "gt",
+	token.GREATER_OR_EQUAL: "gte",
+}
+
+// k6Flipped 交换操作数后的操作符
+var k6Flipped = map[string]string{"eq": "eq", "ne": "ne", "lt": "gt", "lte": "gte", "gt": "lt", "gte": "lte"}
+
+// check 转换 check(res, { name: fn })：可识别的判断转换为断言，其余以 k6 兼容脚本执行
+func (c *k6Converter) check(call *ast.CallExpression, out *[]types.Step, scope *k6Scope) {
+	if len(call.ArgumentList) < 2 {
+		return
+	}
+	target := call.ArgumentList[0]
+	var respVar string
+	var ref k6StepRef
+	switch t := target.(type) {
+	case *ast.Identifier:
+		r, ok := scope.responses[string(t.Name)]
+		if !ok {
+			c.report.warn(c.loc(call), c.snippet(call), "check 的对象不是请求响应，未转换")
+			return
+		}
+		respVar, ref = string(t.Name), r
+	case *ast.CallExpression:
+		if !c.isHTTPCall(t) {
+			c.report.warn(c.loc(call), c.snippet(call), "check 的对象不是请求响应，未转换")
+			return
+		}
+		r, ok := c.request(t, out, scope)
+		if !ok {
+			return
+		}
+		respVar, ref = "res", r
+	default:
+		c.report.warn(c.loc(call), c.snippet(call), "check 的对象不是请求响应，未转换")
+		return
+	}
+	sets, ok := call.ArgumentList[1].(*ast.ObjectLiteral)
+	if !ok {
+		c.report.warn(c.loc(call), c.snippet(call), "check 条件不是对象字面量，未转换")
+		return
+	}
+
+	var procs []types.Processor
+	var fallback []string
+	for _, p := range objectProps(sets) {
+		if a, ok := c.checkAssertion(p.key, p.value, scope); ok {
+			procs = append(procs, a)
+			continue
+		}
+		fallback = append(fallback, "  "+jsString(p.key)+": "+c.snippet(p.value))
+	}
+	if len(fallback) > 0 {
+		code := "check(" + respVar + ", {\n" + strings.Join(fallback, ",\n") + "\n});"
+		procs = append(procs, newProcessor("js_script", "k6 check", map[string]any{
+			"script":      code,
+			"compat":      script.CompatK6,
+			"responseVar": respVar,
+		}))
+		c.report.warn(c.loc(call), "check", "%d 个检查无法转换为断言，已以 k6 兼容脚本执行，引用的脚本变量需改为 vars.get", len(fallback))
+	}
+	step := ref.step()
+	step.PostProcessors = append(step.PostProcessors, c.ids.processors(procs)...)
+}
+
+// checkAssertion 将形如 r => r.status === 200 的检查函数转换为断言
+func (c *k6Converter) checkAssertion(name string, fnExpr ast.Expression, scope *k6Scope) (types.Processor, bool) {
+	var param string
+	var body ast.Expression
+	switch fn := fnExpr.(type) {
+	case *ast.ArrowFunctionLiteral:
+		param = firstParam(fn.ParameterList)
+		if eb, ok := fn.Body.(*ast.ExpressionBody); ok {
+			body = eb.Expression
+		} else {
+			body = returnedExpression(arrowBody(fn))
+		}
+	case *ast.FunctionLiteral:
+		param = firstParam(fn.ParameterList)
+		body = returnedExpression(fn.Body.List)
+	}
+	if param == "" || body == nil {
+		return types.Processor{}, false
+	}
+
+	inner := scope.child(scope.prefix)
+	inner.responses[param] = k6StepRef{}
+	delete(inner.locals, param)
+
+	negate := false
+	if u, ok := body.(*ast.UnaryExpression); ok && u.Operator == token.NOT {
+		negate, body = true, u.Operand
+	}
+
+	switch e := body.(type) {
+	case *ast.BinaryExpression:
+		op, ok := k6Operators[e.Operator]
+		if !ok || negate {
+			return types.Processor{}, false
+		}
+		left, right := e.Left, e.Right
+		_, subject, ok := c.responseSubject(left, inner)
+		if !ok || subject.kind == "" {
+			if _, subject, ok = c.responseSubject(right, inner); !ok || subject.kind == "" {
+				return types.Processor{}, false
+			}
+			op, right = k6Flipped[op], left
+		}
+		expected, ok := c.str(right, scope)
+		if !ok {
+			if _, isNull := right.(*ast.NullLiteral); !isNull {
+				return types.Processor{}, false
+			}
+		}
+		if subject.kind == "response_body" && op != "eq" && op != "ne" {
+			return types.Processor{}, false
+		}
+		return newAssertion(name, subject.kind, op, subject.expression, expected), true
+
+	case *ast.CallExpression:
+		// r.body.includes('x')
+		dot, ok := e.Callee.(*ast.DotExpression)
+		if !ok || dot.Identifier.Name != "includes" || len(e.ArgumentList) != 1 {
+			return types.Processor{}, false
+		}
+		_, subject, ok := c.responseSubject(dot.Left, inner)
+		if !ok || (subject.kind != "response_body" && subject.kind != "header") {
+			return types.Processor{}, false
+		}
+		expected, ok := c.str(e.ArgumentList[0], scope)
+		if !ok {
+			return types.Processor{}, false
+		}
+		op := "contains"
+		if negate {
+			op = "not_contains"
+		}
+		return newAssertion(name, subject.kind, op, subject.expression, expected), true
+	}
+	return types.Processor{}, false
+}
+
+func firstParam(list *ast.ParameterList) string {
+	if list == nil || len(list.List) == 0 {
+		return ""
+	}
+	if id, ok := list.List[0].Target.(*ast.Identifier); ok {
+		return string(id.Name)
+	}
+	return ""
+}
+
+func returnedExpression(stmts []ast.Statement) ast.Expression {
+	if len(stmts) != 1 {
+		return nil
+	}
+	if ret, ok := stmts[0].(*ast.ReturnStatement); ok {
+		return ret.Argument
+	}
+	return nil
+}
+
+func arrowBody(fn *ast.ArrowFunctionLiteral) []ast.Statement {
+	switch b := fn.Body.(type) {
+	case *ast.BlockStatement:
+		return b.List
+	case *ast.ExpressionBody:
+		return []ast.Statement{&ast.ExpressionStatement{Expression: b.Expression}}
+	}
+	return nil
+}
+
+// functionBody 返回函数字面量或箭头函数的语句列表
+func functionBody(expr ast.Expression) []ast.Statement {
+	switch fn := expr.(type) {
+	case *ast.FunctionLiteral:
+		return fn.Body.List
+	case *ast.ArrowFunctionLiteral:
+		return arrowBody(fn)
+	}
+	return nil
+}
+
+// k6Prop 对象字面量的属性，保持源码顺序
+type k6Prop struct {
+	key   string
+	value ast.Expression
+}
+
+func objectProps(obj *ast.ObjectLiteral) []k6Prop {
+	var props []k6Prop
+	for _, p := range obj.Value {
+		switch prop := p.(type) {
+		case *ast.PropertyKeyed:
+			if prop.Computed {
+				continue
+			}
+			var key string
+			switch k := prop.Key.(type) {
+			case *ast.StringLiteral:
+				key = string(k.Value)
+			case *ast.Identifier:
+				key = string(k.Name)
+			case *ast.NumberLiteral:
+				key = k.Literal
+			default:
+				continue
+			}
+			props = append(props, k6Prop{key: key, value: prop.Value})
+		case *ast.PropertyShort:
+			props = append(props, k6Prop{key: string(prop.Name.Name), value: &prop.Name})
+		}
+	}
+	return props
+}
+
+// loc 返回节点所在行
+func (c *k6Converter) loc(node ast.Node) string {
+	if node == nil || c.file == nil {
+		return c.source
+	}
+	pos := c.file.Position(int(node.Idx0()) - c.file.Base())
+	return fmt.Sprintf("%s:%d", c.source, pos.Line)
+}
+
+// snippet 返回节点的源码片段
+func (c *k6Converter) snippet(node ast.Node) string {
+	start, end := int(node.Idx0())-c.file.Base(), int(node.Idx1())-c.file.Base()
+	if start < 0 || end > len(c.src) || start >= end {
+		return ""
+	}
+	return c.src[start:end]
+}
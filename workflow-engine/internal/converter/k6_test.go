@@ -0,0 +1,165 @@
+package converter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/internal/parser"
+	"yqhp/workflow-engine/pkg/script"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+const testK6Script = `import http from 'k6/http';
+import { check, sleep, group } from 'k6';
+import { Trend } from 'k6/metrics';
+
+const BASE_URL = __ENV.BASE_URL || 'https://api.example.com';
+const ids = [1, 2, 3];
+const latency = new Trend('latency');
+
+export const options = {
+  stages: [
+    { duration: '30s', target: 20 },
+    { duration: '1m', target: 20 },
+    { duration: '10s', target: 0 },
+  ],
+  thresholds: {
+    http_req_duration: ['p(95)<500', 'p(99)<1000'],
+    http_req_failed: ['rate<0.01'],
+    checks: ['rate>0.99'],
+  },
+};
+
+export default function () {
+  group('auth', function () {
+    const res = http.post(` + "`${BASE_URL}/login`" + `, JSON.stringify({ user: 'alice', remember: true }), {
+      headers: { 'Content-Type': 'application/json' },
+      tags: { name: 'login' },
+    });
+    check(res, {
+      'status is 200': (r) => r.status === 200,
+      'has token': (r) => r.json('data.token') !== '',
+      'few roles': (r) => r.json('data.roles').length < 5,
+    });
+    const token = res.json('data.token');
+    sleep(1);
+    http.get(` + "`${BASE_URL}/me`" + `, { headers: { Authorization: ` + "`Bearer ${token}`" + ` } });
+  });
+
+  for (const id of ids) {
+    const item = http.get(` + "`${BASE_URL}/items/${id}`" + `);
+    check(item, { 'fast': (r) => r.timings.duration < 200 });
+  }
+  latency.add(1);
+  sleep(2);
+}
+`
+
+func convertTestK6(t *testing.T) *Result {
+	result, err := ConvertK6([]byte(testK6Script), "scripts/load-test.js")
+	require.NoError(t, err)
+	require.Len(t, result.Workflows, 1)
+	return result
+}
+
+func TestConvertK6_Options(t *testing.T) {
+	wf := convertTestK6(t).Workflows[0]
+	assert.Equal(t, "load_test", wf.ID)
+	assert.Equal(t, types.ModeRampingVUs, wf.Options.ExecutionMode)
+	assert.Equal(t, []types.Stage{
+		{Duration: 30 * time.Second, Target: 20},
+		{Duration: time.Minute, Target: 20},
+		{Duration: 10 * time.Second, Target: 0},
+	}, wf.Options.Stages)
+	assert.Equal(t, []types.Threshold{
+		{Metric: "http_req_duration", Condition: "p(95) < 500"},
+		{Metric: "http_req_duration", Condition: "p(99) < 1000"},
+		{Metric: "http_req_failed", Condition: "rate < 0.01"},
+	}, wf.Options.Thresholds)
+	assert.Equal(t, "https://api.example.com", wf.Variables["BASE_URL"])
+}
+
+func TestConvertK6_Scenario(t *testing.T) {
+	src := `import http from 'k6/http';
+export const options = {
+  scenarios: {
+    spike: { executor: 'constant-arrival-rate', rate: 600, timeUnit: '1m', duration: '2m', preAllocatedVUs: 50 },
+    other: { executor: 'constant-vus', vus: 1, duration: '1m' },
+  },
+};
+export default () => http.get('https://example.com/');
+`
+	result, err := ConvertK6([]byte(src), "spike.js")
+	require.NoError(t, err)
+	opts := result.Workflows[0].Options
+	assert.Equal(t, types.ModeConstantArrivalRate, opts.ExecutionMode)
+	assert.Equal(t, 10, opts.VUs)
+	assert.Equal(t, 2*time.Minute, opts.Duration)
+	assert.Equal(t, 1, result.Report.Count(LevelWarning))
+}
+
+func TestConvertK6_Steps(t *testing.T) {
+	wf := convertTestK6(t).Workflows[0]
+	require.Len(t, wf.Steps, 3)
+
+	login := wf.Steps[0]
+	assert.Equal(t, "auth / login", login.Name)
+	assert.Equal(t, "${BASE_URL}/login", login.Config["url"])
+	assert.Equal(t, map[string]any{"type": "json", "raw": "{\n  \"user\": \"alice\",\n  \"remember\": true\n}"}, login.Config["body"])
+	require.Len(t, login.PostProcessors, 4)
+	assert.Equal(t, map[string]any{"assertType": "status_code", "operator": "eq", "expected": "200"}, login.PostProcessors[0].Config)
+	assert.Equal(t, map[string]any{"assertType": "jsonpath", "operator": "ne", "expression": "$.data.token", "expected": ""}, login.PostProcessors[1].Config)
+	assert.Equal(t, "js_script", login.PostProcessors[2].Type)
+	assert.Equal(t, script.CompatK6, login.PostProcessors[2].Config["compat"])
+	assert.Equal(t, "res", login.PostProcessors[2].Config["responseVar"])
+	assert.Equal(t, "token", login.PostProcessors[3].Config["variableName"])
+
+	me := wf.Steps[1]
+	assert.Equal(t, "auth / GET ${BASE_URL}/me", me.Name)
+	assert.Equal(t, map[string]any{"Authorization": "Bearer ${token}"}, me.Config["headers"])
+	require.Len(t, me.PreProcessors, 1)
+	assert.Equal(t, 1000, me.PreProcessors[0].Config["duration"])
+
+	loop := wf.Steps[2]
+	require.NotNil(t, loop.Loop)
+	assert.Equal(t, "foreach", loop.Loop.Mode)
+	assert.Equal(t, "${ids}", loop.Loop.Items)
+	assert.Equal(t, "id", loop.Loop.ItemVar)
+	assert.Equal(t, []any{int64(1), int64(2), int64(3)}, wf.Variables["ids"])
+	item := loop.Loop.Steps[0]
+	assert.Equal(t, "${BASE_URL}/items/${id}", item.Config["url"])
+	assert.Equal(t, "response_time", item.PostProcessors[0].Config["assertType"])
+
+	// 末尾的 sleep 无法附加到循环步骤，移至首个请求之前
+	require.Len(t, login.PreProcessors, 1)
+	assert.Equal(t, 2000, login.PreProcessors[0].Config["duration"])
+}
+
+func TestConvertK6_Report(t *testing.T) {
+	report := convertTestK6(t).Report
+
+	var warnings []string
+	for _, it := range report.Items {
+		if it.Level == LevelWarning {
+			warnings = append(warnings, it.Location)
+		}
+	}
+	// k6/metrics 导入、Trend 对象、checks 阈值、无法转换的 check 与 latency.add
+	assert.ElementsMatch(t, []string{"load-test.js:3", "load-test.js:7", "load-test.js:18", "load-test.js:28", "load-test.js:42"}, warnings)
+}
+
+func TestConvertK6_OutputParses(t *testing.T) {
+	data, err := parser.NewYAMLPrinter().Print(convertTestK6(t).Workflows[0])
+	require.NoError(t, err)
+	_, err = parser.NewYAMLParser().Parse(data)
+	require.NoError(t, err, string(data))
+}
+
+func TestConvertK6_NoDefaultFunction(t *testing.T) {
+	_, err := ConvertK6([]byte(`import http from 'k6/http';
+export function setup() { http.get('https://example.com'); }`), "setup.js")
+	assert.Error(t, err)
+}
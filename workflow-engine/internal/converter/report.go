@@ -0,0 +1,85 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level 报告条目级别
+type Level string
+
+const (
+	// LevelWarning 未转换或近似转换，需要人工确认
+	LevelWarning Level = "warning"
+	// LevelInfo 有意忽略的内容（监听器、禁用的元件等），不影响执行语义
+	LevelInfo Level = "info"
+)
+
+// ReportItem 转换报告条目
+type ReportItem struct {
+	Level    Level  `json:"level"`
+	Location string `json:"location"` // JMX 元件路径或 k6 脚本行号
+	Element  string `json:"element"`  // JMX 元件类型或 k6 语句
+	Message  string `json:"message"`
+}
+
+// Report 转换报告，记录所有未转换或近似转换的内容
+type Report struct {
+	Source string       `json:"source"`
+	Format string       `json:"format"`
+	Items  []ReportItem `json:"items"`
+}
+
+// warn 记录未转换或近似转换的内容
+func (r *Report) warn(location, element, format string, args ...interface{}) {
+	r.add(LevelWarning, location, element, fmt.Sprintf(format, args...))
+}
+
+// info 记录有意忽略的内容
+func (r *Report) info(location, element, format string, args ...interface{}) {
+	r.add(LevelInfo, location, element, fmt.Sprintf(format, args...))
+}
+
+func (r *Report) add(level Level, location, element, message string) {
+	for _, it := range r.Items {
+		if it.Level == level && it.Location == location && it.Element == element && it.Message == message {
+			return
+		}
+	}
+	r.Items = append(r.Items, ReportItem{Level: level, Location: location, Element: element, Message: message})
+}
+
+// Count 返回指定级别的条目数
+func (r *Report) Count(level Level) int {
+	n := 0
+	for _, it := range r.Items {
+		if it.Level == level {
+			n++
+		}
+	}
+	return n
+}
+
+// String 以文本形式输出报告，警告在前
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "转换报告: %s (%s)\n", r.Source, r.Format)
+	if len(r.Items) == 0 {
+		b.WriteString("  全部内容均已转换\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "  %d 项未转换或近似转换，%d 项已忽略\n", r.Count(LevelWarning), r.Count(LevelInfo))
+	for _, level := range []Level{LevelWarning, LevelInfo} {
+		for _, it := range r.Items {
+			if it.Level != level {
+				continue
+			}
+			fmt.Fprintf(&b, "  [%s] %s", it.Level, it.Location)
+			if it.Element != "" {
+				fmt.Fprintf(&b, " (%s)", it.Element)
+			}
+			fmt.Fprintf(&b, ": %s\n", it.Message)
+		}
+	}
+	return b.String()
+}
@@ -32,6 +32,7 @@ type AgentRequest struct {
 	ExecCtx      *executor.ExecutionContext
 	Callbacks    *AgentCallbacks
 	MaxRounds    int
+	Meter        *usageMeter
 }
 
 // AgentCallbacks 聚合 AI 流式回调和 blockID 生成器
@@ -96,14 +97,18 @@ func RunAgent(ctx context.Context, req *AgentRequest) (*AIOutput, error) {
 		logger.Debug("[Agent] ===== 第 %d/%d 轮开始 (stepID=%s, 当前messages数=%d) =====",
 			round, maxRounds, req.StepID, len(messages))
 
+		if err := req.Meter.check(ctx, output, messages); err != nil {
+			return output, err
+		}
 		resp, err := callLLM(ctx, req.ChatModel, messages, req.SchemaTools, req.Config, req.StepID, req.Callbacks)
 
 		if err != nil {
+			req.Meter.release(ctx)
 			logger.Debug("[Agent] 第 %d 轮 LLM 调用失败, 总耗时=%v: %v", round, time.Since(startTime), err)
-			return nil, err
+			return output, err
 		}
 
-		updateTokenUsage(output, resp)
+		req.Meter.record(ctx, output, resp)
 		roundThinking := strings.TrimSpace(resp.Content)
 		if roundThinking == "" {
 			roundThinking = strings.TrimSpace(resp.ReasoningContent)
@@ -113,6 +118,9 @@ func RunAgent(ctx context.Context, req *AgentRequest) (*AIOutput, error) {
 			logger.Debug("[Agent] 第 %d 轮 LLM 未返回工具调用，直接输出 (长度=%d), 总耗时=%v",
 				round, len([]rune(resp.Content)), time.Since(startTime))
 			output.Content = resp.Content
+			if err := enforceOutputSchema(ctx, req, messages, resp, output); err != nil {
+				return output, err
+			}
 			if req.Callbacks.Stream != nil {
 				req.Callbacks.Stream.OnMessageComplete(ctx, req.StepID, toAIResult(output))
 			}
@@ -169,13 +177,20 @@ func RunAgent(ctx context.Context, req *AgentRequest) (*AIOutput, error) {
 	}
 
 	logger.Warn("[Agent] 轮次达到最大值 %d，生成最终回复 (stepID=%s)", maxRounds, req.StepID)
+	if err := req.Meter.check(ctx, output, messages); err != nil {
+		return output, err
+	}
 	resp, err := callLLM(ctx, req.ChatModel, messages, nil, req.Config, req.StepID, nil)
 	if err != nil {
+		req.Meter.release(ctx)
 		logger.Debug("[Agent] 最终回复生成失败, stepID=%s: %v", req.StepID, err)
 		return output, fmt.Errorf("最终回复生成失败: %w", err)
 	}
 	output.Content = resp.Content
-	updateTokenUsage(output, resp)
+	req.Meter.record(ctx, output, resp)
+	if err := enforceOutputSchema(ctx, req, messages, resp, output); err != nil {
+		return output, err
+	}
 	logger.Debug("[Agent] 执行完成, stepID=%s, 总轮次=%d, 总耗时=%v, tokens=%d",
 		req.StepID, maxRounds, time.Since(startTime), output.TotalTokens)
 	if req.Callbacks.Stream != nil {
@@ -483,9 +498,16 @@ func streamGenerate(ctx context.Context, chatModel model.ToolCallingChatModel, m
 				resp.ResponseMeta = &schema.ResponseMeta{Usage: &schema.TokenUsage{}}
 			}
 			resp.ResponseMeta.Usage.PromptTokens += chunk.ResponseMeta.Usage.PromptTokens
+			resp.ResponseMeta.Usage.PromptTokenDetails.CachedTokens += chunk.ResponseMeta.Usage.PromptTokenDetails.CachedTokens
 			resp.ResponseMeta.Usage.CompletionTokens += chunk.ResponseMeta.Usage.CompletionTokens
 			resp.ResponseMeta.Usage.TotalTokens += chunk.ResponseMeta.Usage.TotalTokens
 		}
+		if chunk.ResponseMeta != nil && chunk.ResponseMeta.FinishReason != "" {
+			if resp.ResponseMeta == nil {
+				resp.ResponseMeta = &schema.ResponseMeta{}
+			}
+			resp.ResponseMeta.FinishReason = chunk.ResponseMeta.FinishReason
+		}
 	}
 	resp.Content = contentBuilder.String()
 	return resp, nil
@@ -638,6 +660,7 @@ func updateTokenUsage(output *AIOutput, resp *schema.Message) {
 	if resp.ResponseMeta != nil {
 		if resp.ResponseMeta.Usage != nil {
 			output.PromptTokens += resp.ResponseMeta.Usage.PromptTokens
+			output.CachedTokens += resp.ResponseMeta.Usage.PromptTokenDetails.CachedTokens
 			output.CompletionTokens += resp.ResponseMeta.Usage.CompletionTokens
 			output.TotalTokens += resp.ResponseMeta.Usage.TotalTokens
 		}
@@ -674,8 +697,10 @@ func toAIResult(output *AIOutput) *types.AIResult {
 	return &types.AIResult{
 		Content:          output.Content,
 		PromptTokens:     output.PromptTokens,
+		CachedTokens:     output.CachedTokens,
 		CompletionTokens: output.CompletionTokens,
 		TotalTokens:      output.TotalTokens,
+		Cost:             output.Cost,
 		Model:            output.Model,
 		FinishReason:     output.FinishReason,
 	}
@@ -0,0 +1,830 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/pkg/logger"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// 录制回放模式
+const (
+	CassetteModeRecord = "record"
+	CassetteModeReplay = "replay"
+)
+
+const (
+	cassetteVersion        = 1
+	defaultCassetteDir     = "cassettes"
+	defaultCassetteProject = "default"
+)
+
+// CassetteRootEnv 录制文件根目录，录制文件只能位于其下的 <项目>/<录制ID>.json
+const CassetteRootEnv = "WORKFLOW_ENGINE_CASSETTE_DIR"
+
+// CassetteConfig AI 步骤录制回放配置
+type CassetteConfig struct {
+	Mode           string   `json:"mode"`                      // record / replay
+	ID             string   `json:"id,omitempty"`              // 录制 ID，默认步骤 ID，可用 / 分层
+	Project        string   `json:"project,omitempty"`         // 录制文件所属项目，在 Gulu 中执行时由服务端注入
+	Strict         bool     `json:"strict,omitempty"`          // 回放未命中时失败；否则回退真实调用并补录
+	ReplayTools    bool     `json:"replay_tools,omitempty"`    // 回放时工具结果也从录制文件读取
+	IgnorePatterns []string `json:"ignore_patterns,omitempty"` // 计算请求指纹前屏蔽的易变内容（正则）
+}
+
+// builtinIgnorePatterns 系统提示词中的当前时间每次都不同，始终屏蔽
+var builtinIgnorePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`当前时间: \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`),
+}
+
+var (
+	cassetteOverrideMu sync.RWMutex
+	cassetteOverride   *CassetteConfig
+	cassetteRootDir    string
+)
+
+// SetCassetteRoot 设置录制文件根目录（CLI 使用），为空时使用 CassetteRootEnv，未设置环境变量时为 cassettes
+func SetCassetteRoot(dir string) {
+	cassetteOverrideMu.Lock()
+	defer cassetteOverrideMu.Unlock()
+	cassetteRootDir = dir
+}
+
+func cassetteRoot() string {
+	cassetteOverrideMu.RLock()
+	dir := cassetteRootDir
+	cassetteOverrideMu.RUnlock()
+	if dir == "" {
+		dir = os.Getenv(CassetteRootEnv)
+	}
+	if dir == "" {
+		dir = defaultCassetteDir
+	}
+	return dir
+}
+
+// SetCassetteOverride 设置全局录制回放配置（CLI 使用），对所有 AI 步骤生效，
+// 步骤自身配置的 id/project/ignore_patterns 仍然保留；传 nil 取消
+func SetCassetteOverride(cfg *CassetteConfig) {
+	cassetteOverrideMu.Lock()
+	defer cassetteOverrideMu.Unlock()
+	cassetteOverride = cfg
+}
+
+// applyCassetteOverride 合并全局录制回放配置
+func (c *AIConfig) applyCassetteOverride() {
+	cassetteOverrideMu.RLock()
+	override := cassetteOverride
+	cassetteOverrideMu.RUnlock()
+	if override == nil || override.Mode == "" {
+		return
+	}
+
+	merged := *override
+	if c.Cassette != nil {
+		merged.ID = c.Cassette.ID
+		if c.Cassette.Project != "" {
+			merged.Project = c.Cassette.Project
+		}
+		merged.IgnorePatterns = append(append([]string{}, c.Cassette.IgnorePatterns...), override.IgnorePatterns...)
+	}
+	c.Cassette = &merged
+}
+
+// replaying 是否处于回放模式（回放时不需要真实凭证）
+func (c *CassetteConfig) replaying() bool {
+	return c != nil && c.Mode == CassetteModeReplay
+}
+
+func (c *CassetteConfig) validate() error {
+	switch c.Mode {
+	case "", CassetteModeRecord, CassetteModeReplay:
+	default:
+		return executor.NewConfigError(fmt.Sprintf("cassette.mode 仅支持 record/replay，当前值: %s", c.Mode), nil)
+	}
+	for _, p := range c.IgnorePatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return executor.NewConfigError(fmt.Sprintf("cassette.ignore_patterns 正则无效: %s", p), err)
+		}
+	}
+	if c.ID != "" {
+		if err := validateCassetteName(c.ID, true); err != nil {
+			return executor.NewConfigError("cassette.id "+err.Error(), err)
+		}
+	}
+	if c.Project != "" {
+		if err := validateCassetteName(c.Project, false); err != nil {
+			return executor.NewConfigError("cassette.project "+err.Error(), err)
+		}
+	}
+	return nil
+}
+
+// cassetteNamePattern 录制 ID 与项目的每一级只允许字母、数字、下划线、点与短横线
+var cassetteNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// validateCassetteName 校验录制 ID 或项目名：不能是绝对路径，不能包含 .. 或空的层级；nested 为 false 时不允许 /
+func validateCassetteName(name string, nested bool) error {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return fmt.Errorf("不能是绝对路径: %s", name)
+	}
+	segments := []string{name}
+	if nested {
+		segments = strings.Split(name, "/")
+	}
+	for _, seg := range segments {
+		if seg == "." || seg == ".." {
+			return fmt.Errorf("不能包含 . 或 ..: %s", name)
+		}
+		if !cassetteNamePattern.MatchString(seg) {
+			return fmt.Errorf("只能包含字母、数字、_ . - 与层级分隔符 /: %s", name)
+		}
+	}
+	return nil
+}
+
+// filePath 录制文件路径 <根目录>/<项目>/<录制ID>.json，录制 ID 默认为步骤 ID
+func (c *CassetteConfig) filePath(stepID string) (string, error) {
+	id := c.ID
+	if id == "" {
+		id = stepID
+	}
+	if err := validateCassetteName(id, true); err != nil {
+		return "", fmt.Errorf("录制 ID %w", err)
+	}
+	project := c.Project
+	if project == "" {
+		project = defaultCassetteProject
+	}
+	if err := validateCassetteName(project, false); err != nil {
+		return "", fmt.Errorf("录制项目 %w", err)
+	}
+	root := cassetteRoot()
+	path := filepath.Join(root, project, filepath.FromSlash(id)+".json")
+	if rel, err := filepath.Rel(root, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("录制文件超出根目录: %s", path)
+	}
+	return path, nil
+}
+
+// ========== 录制文件 ==========
+
+// Cassette 录制文件：LLM 请求/响应与工具调用结果
+type Cassette struct {
+	Version      int                     `json:"version"`
+	RecordedAt   time.Time               `json:"recorded_at"`
+	Interactions []*CassetteInteraction  `json:"interactions"`
+	ToolCalls    []*CassetteToolCall     `json:"tool_calls,omitempty"`
+	Tools        []*types.ToolDefinition `json:"tools,omitempty"`
+
+	mu        sync.RWMutex
+	index     map[string][]*CassetteInteraction
+	toolIndex map[string][]*CassetteToolCall
+}
+
+// CassetteRequest 录制的 LLM 请求（指纹基于此计算）
+type CassetteRequest struct {
+	Model       string             `json:"model"`
+	Messages    []*schema.Message  `json:"messages"`
+	Tools       []*CassetteToolDef `json:"tools,omitempty"`
+	Temperature *float32           `json:"temperature,omitempty"`
+	TopP        *float32           `json:"top_p,omitempty"`
+	MaxTokens   *int               `json:"max_tokens,omitempty"`
+	Stop        []string           `json:"stop,omitempty"`
+}
+
+// CassetteToolDef 请求中的工具定义
+type CassetteToolDef struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// CassetteInteraction 一次 LLM 调用：同一指纹按出现顺序区分
+type CassetteInteraction struct {
+	Fingerprint string            `json:"fingerprint"`
+	Occurrence  int               `json:"occurrence"`
+	Request     *CassetteRequest  `json:"request"`
+	Chunks      []*schema.Message `json:"chunks,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// CassetteToolCall 一次工具调用结果
+type CassetteToolCall struct {
+	Fingerprint string            `json:"fingerprint"`
+	Occurrence  int               `json:"occurrence"`
+	Name        string            `json:"name"`
+	Arguments   string            `json:"arguments"`
+	Result      *types.ToolResult `json:"result,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+func newCassette() *Cassette {
+	c := &Cassette{Version: cassetteVersion}
+	c.reindex()
+	return c
+}
+
+func (c *Cassette) reindex() {
+	c.index = make(map[string][]*CassetteInteraction)
+	for _, it := range c.Interactions {
+		c.index[it.Fingerprint] = append(c.index[it.Fingerprint], it)
+	}
+	c.toolIndex = make(map[string][]*CassetteToolCall)
+	for _, tc := range c.ToolCalls {
+		c.toolIndex[tc.Fingerprint] = append(c.toolIndex[tc.Fingerprint], tc)
+	}
+}
+
+// LoadCassette 读取录制文件
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("录制文件解析失败 (%s): %w", path, err)
+	}
+	if c.Version > cassetteVersion {
+		return nil, fmt.Errorf("录制文件版本 %d 不受支持 (%s)", c.Version, path)
+	}
+	c.reindex()
+	return c, nil
+}
+
+// Save 写入录制文件（先写临时文件再重命名，避免中途失败留下半个文件）
+func (c *Cassette) Save(path string) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	c.mu.RLock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// lookup 按指纹与出现序号查找；序号超出录制次数时复用最后一次（多次迭代重复执行同一步骤）
+func (c *Cassette) lookup(fingerprint string, occurrence int) *CassetteInteraction {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.find(fingerprint, occurrence)
+}
+
+func (c *Cassette) lookupTool(fingerprint string, occurrence int) *CassetteToolCall {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.findTool(fingerprint, occurrence)
+}
+
+func (c *Cassette) empty() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.Interactions) == 0 && len(c.ToolCalls) == 0
+}
+
+func (c *Cassette) find(fingerprint string, occurrence int) *CassetteInteraction {
+	list := c.index[fingerprint]
+	if len(list) == 0 {
+		return nil
+	}
+	for _, it := range list {
+		if it.Occurrence == occurrence {
+			return it
+		}
+	}
+	return list[len(list)-1]
+}
+
+func (c *Cassette) findTool(fingerprint string, occurrence int) *CassetteToolCall {
+	list := c.toolIndex[fingerprint]
+	if len(list) == 0 {
+		return nil
+	}
+	for _, tc := range list {
+		if tc.Occurrence == occurrence {
+			return tc
+		}
+	}
+	return list[len(list)-1]
+}
+
+// merge 合并一次步骤执行录到的内容，已存在的（指纹+序号相同）不重复追加
+func (c *Cassette) merge(interactions []*CassetteInteraction, toolCalls []*CassetteToolCall, tools []*types.ToolDefinition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.RecordedAt = time.Now()
+	for _, it := range interactions {
+		if existing := c.find(it.Fingerprint, it.Occurrence); existing != nil && existing.Occurrence == it.Occurrence {
+			continue
+		}
+		c.Interactions = append(c.Interactions, it)
+		c.index[it.Fingerprint] = append(c.index[it.Fingerprint], it)
+	}
+	for _, tc := range toolCalls {
+		if existing := c.findTool(tc.Fingerprint, tc.Occurrence); existing != nil && existing.Occurrence == tc.Occurrence {
+			continue
+		}
+		c.ToolCalls = append(c.ToolCalls, tc)
+		c.toolIndex[tc.Fingerprint] = append(c.toolIndex[tc.Fingerprint], tc)
+	}
+
+	known := make(map[string]bool, len(c.Tools))
+	for _, def := range c.Tools {
+		known[def.Name] = true
+	}
+	for _, def := range tools {
+		if !known[def.Name] {
+			known[def.Name] = true
+			c.Tools = append(c.Tools, def)
+		}
+	}
+	sort.Slice(c.Tools, func(i, j int) bool { return c.Tools[i].Name < c.Tools[j].Name })
+}
+
+// ========== 录制文件仓库 ==========
+
+// cassetteStore 进程内共享的录制文件：同一路径只加载一次，录制模式首次使用时从空白开始；
+// 多个 VU 并发执行同一步骤时写入串行化。无会话使用且闲置超过 cassetteIdleTTL 的录制文件从内存中淘汰，
+// 内存中的录制文件超过 maxCachedCassettes 时优先淘汰最久未使用的
+type cassetteStore struct {
+	mu        sync.Mutex
+	cassettes map[string]*cassetteEntry
+}
+
+type cassetteEntry struct {
+	cassette *Cassette
+	refs     int
+	lastUsed time.Time
+}
+
+const (
+	cassetteIdleTTL    = 10 * time.Minute
+	maxCachedCassettes = 128
+)
+
+// cassetteProcessStart 录制模式下，本进程写入过的录制文件被淘汰后重新打开时从文件继续录制，而不是从空白开始
+var cassetteProcessStart = time.Now()
+
+func newCassetteStore() *cassetteStore {
+	return &cassetteStore{cassettes: make(map[string]*cassetteEntry)}
+}
+
+var defaultCassetteStore = newCassetteStore()
+
+// open 打开录制文件并增加引用，会话结束时须调用 release
+func (s *cassetteStore) open(path, mode string) (*Cassette, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+	now := time.Now()
+	s.evict(now)
+	if e, ok := s.cassettes[key]; ok {
+		e.refs++
+		e.lastUsed = now
+		return e.cassette, nil
+	}
+
+	c := newCassette()
+	info, statErr := os.Stat(path)
+	if mode != CassetteModeRecord || (statErr == nil && !info.ModTime().Before(cassetteProcessStart)) {
+		loaded, err := LoadCassette(path)
+		if err == nil {
+			c = loaded
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	s.cassettes[key] = &cassetteEntry{cassette: c, refs: 1, lastUsed: now}
+	return c, nil
+}
+
+// release 会话结束，减少引用
+func (s *cassetteStore) release(c *Cassette) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.cassettes {
+		if e.cassette == c {
+			if e.refs > 0 {
+				e.refs--
+			}
+			e.lastUsed = time.Now()
+			return
+		}
+	}
+}
+
+// evict 淘汰无引用且闲置超时的录制文件，仍超过上限时按最久未使用淘汰无引用的录制文件
+func (s *cassetteStore) evict(now time.Time) {
+	for key, e := range s.cassettes {
+		if e.refs == 0 && now.Sub(e.lastUsed) > cassetteIdleTTL {
+			delete(s.cassettes, key)
+		}
+	}
+	for len(s.cassettes) >= maxCachedCassettes {
+		oldestKey := ""
+		var oldest time.Time
+		for key, e := range s.cassettes {
+			if e.refs == 0 && (oldestKey == "" || e.lastUsed.Before(oldest)) {
+				oldestKey, oldest = key, e.lastUsed
+			}
+		}
+		if oldestKey == "" {
+			return
+		}
+		delete(s.cassettes, oldestKey)
+	}
+}
+
+func (s *cassetteStore) commit(c *Cassette, path string, interactions []*CassetteInteraction, toolCalls []*CassetteToolCall, tools []*types.ToolDefinition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.merge(interactions, toolCalls, tools)
+	return c.Save(path)
+}
+
+// ========== 单次步骤执行的录制回放会话 ==========
+
+// cassetteSession 一次 AI 步骤执行的录制回放状态：出现序号按会话计数，录到的内容在步骤结束时统一落盘
+type cassetteSession struct {
+	config   *CassetteConfig
+	path     string
+	cassette *Cassette
+	store    *cassetteStore
+	ignore   []*regexp.Regexp
+
+	mu           sync.Mutex
+	seen         map[string]int
+	toolSeen     map[string]int
+	interactions []*CassetteInteraction
+	toolCalls    []*CassetteToolCall
+	tools        []*types.ToolDefinition
+}
+
+// newCassetteSession 根据配置创建会话，未开启录制回放时返回 nil
+func newCassetteSession(config *CassetteConfig, stepID string) (*cassetteSession, error) {
+	if config == nil || config.Mode == "" {
+		return nil, nil
+	}
+	path, err := config.filePath(stepID)
+	if err != nil {
+		return nil, err
+	}
+	ignore := append([]*regexp.Regexp{}, builtinIgnorePatterns...)
+	for _, p := range config.IgnorePatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		ignore = append(ignore, re)
+	}
+
+	c, err := defaultCassetteStore.open(path, config.Mode)
+	if err != nil {
+		return nil, err
+	}
+	if config.Mode == CassetteModeReplay && config.Strict && c.empty() {
+		defaultCassetteStore.release(c)
+		return nil, fmt.Errorf("录制文件不存在或为空: %s", path)
+	}
+
+	return &cassetteSession{
+		config:   config,
+		path:     path,
+		cassette: c,
+		store:    defaultCassetteStore,
+		ignore:   ignore,
+		seen:     make(map[string]int),
+		toolSeen: make(map[string]int),
+	}, nil
+}
+
+func (s *cassetteSession) replaying() bool {
+	return s.config.Mode == CassetteModeReplay
+}
+
+// replayingTools 工具结果是否从录制文件读取
+func (s *cassetteSession) replayingTools() bool {
+	return s.replaying() && s.config.ReplayTools
+}
+
+// fingerprint 屏蔽易变内容后计算 SHA-256
+func (s *cassetteSession) fingerprint(v any) string {
+	data, _ := json.Marshal(v)
+	text := string(data)
+	for _, re := range s.ignore {
+		text = re.ReplaceAllString(text, "<ignored>")
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// next 返回指纹本次出现的序号（从 0 开始）
+func (s *cassetteSession) next(seen map[string]int, fingerprint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := seen[fingerprint]
+	seen[fingerprint] = n + 1
+	return n
+}
+
+func (s *cassetteSession) record(it *CassetteInteraction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interactions = append(s.interactions, it)
+}
+
+func (s *cassetteSession) recordTool(tc *CassetteToolCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toolCalls = append(s.toolCalls, tc)
+}
+
+// Commit 步骤结束时写入录制文件；回放全部命中时无需写入
+func (s *cassetteSession) Commit() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	interactions, toolCalls, tools := s.interactions, s.toolCalls, s.tools
+	s.mu.Unlock()
+	if len(interactions) == 0 && len(toolCalls) == 0 {
+		return nil
+	}
+	if err := s.store.commit(s.cassette, s.path, interactions, toolCalls, tools); err != nil {
+		return fmt.Errorf("写入录制文件失败 (%s): %w", s.path, err)
+	}
+	logger.Debug("[Cassette] 已写入录制文件 %s, 新增 LLM 调用=%d, 工具调用=%d", s.path, len(interactions), len(toolCalls))
+	return nil
+}
+
+// Close 步骤结束时释放录制文件，之后录制文件可从内存中淘汰
+func (s *cassetteSession) Close() {
+	if s == nil {
+		return
+	}
+	s.store.release(s.cassette)
+}
+
+// ========== 模型包装 ==========
+
+// cassetteChatModel 录制回放 ChatModel：回放时按请求指纹返回录制的响应，录制时透传并记录
+type cassetteChatModel struct {
+	inner   einomodel.ToolCallingChatModel
+	session *cassetteSession
+	tools   []*schema.ToolInfo
+}
+
+func (s *cassetteSession) wrapModel(inner einomodel.ToolCallingChatModel) einomodel.ToolCallingChatModel {
+	return &cassetteChatModel{inner: inner, session: s}
+}
+
+func (m *cassetteChatModel) WithTools(tools []*schema.ToolInfo) (einomodel.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &cassetteChatModel{inner: inner, session: m.session, tools: tools}, nil
+}
+
+func (m *cassetteChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	it, req, fp, occ, err := m.match(input, opts)
+	if err != nil {
+		return nil, err
+	}
+	if it != nil {
+		return replayGenerate(it)
+	}
+
+	resp, err := m.inner.Generate(ctx, input, opts...)
+	recorded := &CassetteInteraction{Fingerprint: fp, Occurrence: occ, Request: req}
+	if err != nil {
+		recorded.Error = err.Error()
+	} else {
+		recorded.Chunks = []*schema.Message{resp}
+	}
+	m.session.record(recorded)
+	return resp, err
+}
+
+func (m *cassetteChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	it, req, fp, occ, err := m.match(input, opts)
+	if err != nil {
+		return nil, err
+	}
+	if it != nil {
+		if it.Error != "" {
+			return nil, errors.New(it.Error)
+		}
+		return schema.StreamReaderFromArray(it.Chunks), nil
+	}
+
+	recorded := &CassetteInteraction{Fingerprint: fp, Occurrence: occ, Request: req}
+	stream, err := m.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		recorded.Error = err.Error()
+		m.session.record(recorded)
+		return nil, err
+	}
+
+	// 透传流式分块，读完（或出错）后记录
+	sr, sw := schema.Pipe[*schema.Message](16)
+	go func() {
+		defer stream.Close()
+		defer sw.Close()
+		for {
+			chunk, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				m.session.record(recorded)
+				return
+			}
+			if err != nil {
+				recorded.Error = err.Error()
+				m.session.record(recorded)
+				sw.Send(nil, err)
+				return
+			}
+			recorded.Chunks = append(recorded.Chunks, chunk)
+			if sw.Send(chunk, nil) {
+				return
+			}
+		}
+	}()
+	return sr, nil
+}
+
+// match 计算请求指纹并在回放模式下查找录制的响应；
+// 未命中时严格模式返回错误，否则返回 nil 交给真实模型调用并补录
+func (m *cassetteChatModel) match(input []*schema.Message, opts []einomodel.Option) (*CassetteInteraction, *CassetteRequest, string, int, error) {
+	req := m.buildRequest(input, opts)
+	fp := m.session.fingerprint(req)
+	occ := m.session.next(m.session.seen, fp)
+
+	if !m.session.replaying() {
+		return nil, req, fp, occ, nil
+	}
+	if it := m.session.cassette.lookup(fp, occ); it != nil {
+		logger.Debug("[Cassette] 回放 LLM 响应, fingerprint=%s, occurrence=%d", fp[:12], occ)
+		return it, req, fp, occ, nil
+	}
+	if m.session.config.Strict {
+		return nil, nil, "", 0, fmt.Errorf("录制回放未命中 LLM 请求 (fingerprint=%s, 录制文件=%s)，请重新录制", fp[:12], m.session.path)
+	}
+	logger.Warn("[Cassette] 回放未命中 LLM 请求 (fingerprint=%s)，回退真实调用", fp[:12])
+	return nil, req, fp, occ, nil
+}
+
+func (m *cassetteChatModel) buildRequest(input []*schema.Message, opts []einomodel.Option) *CassetteRequest {
+	options := einomodel.GetCommonOptions(&einomodel.Options{Tools: m.tools}, opts...)
+	req := &CassetteRequest{
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		MaxTokens:   options.MaxTokens,
+		Stop:        options.Stop,
+	}
+	if options.Model != nil {
+		req.Model = *options.Model
+	}
+	for _, msg := range input {
+		req.Messages = append(req.Messages, cassetteMessage(msg))
+	}
+	for _, tool := range options.Tools {
+		def := &CassetteToolDef{Name: tool.Name, Description: tool.Desc}
+		if params, err := toolParameters(tool); err == nil {
+			def.Parameters, _ = json.Marshal(params)
+		}
+		req.Tools = append(req.Tools, def)
+	}
+	return req
+}
+
+// cassetteMessage 复制参与指纹计算的消息字段，去掉供应商私有数据（Extra、用量、思考内容）
+func cassetteMessage(msg *schema.Message) *schema.Message {
+	out := &schema.Message{
+		Role:                  msg.Role,
+		Content:               msg.Content,
+		UserInputMultiContent: msg.UserInputMultiContent,
+		ToolCallID:            msg.ToolCallID,
+		ToolName:              msg.ToolName,
+		Name:                  msg.Name,
+	}
+	for _, tc := range msg.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, schema.ToolCall{
+			ID:       tc.ID,
+			Type:     tc.Type,
+			Function: tc.Function,
+		})
+	}
+	return out
+}
+
+func replayGenerate(it *CassetteInteraction) (*schema.Message, error) {
+	if it.Error != "" {
+		return nil, errors.New(it.Error)
+	}
+	return generateFromStream(schema.StreamReaderFromArray(it.Chunks), nil)
+}
+
+// ========== 工具包装 ==========
+
+// cassetteTool 录制回放工具：录制时记录执行结果，开启 replay_tools 回放时直接返回录制结果
+type cassetteTool struct {
+	executor.Tool
+	session *cassetteSession
+}
+
+// wrapTools 包装注册表中的工具；回放工具时，录制文件中有而当前不可用的工具（如 MCP）以录制定义补齐
+func (s *cassetteSession) wrapTools(reg *executor.ToolRegistry) *executor.ToolRegistry {
+	s.tools = reg.List()
+	wrapped := executor.NewToolRegistry()
+	for _, def := range s.tools {
+		tool, _ := reg.Get(def.Name)
+		wrapped.Register(&cassetteTool{Tool: tool, session: s})
+	}
+	if s.replayingTools() {
+		s.cassette.mu.RLock()
+		recorded := append([]*types.ToolDefinition{}, s.cassette.Tools...)
+		s.cassette.mu.RUnlock()
+		for _, def := range recorded {
+			if !wrapped.Has(def.Name) {
+				wrapped.Register(&cassetteTool{Tool: &recordedTool{def: def}, session: s})
+			}
+		}
+	}
+	return wrapped
+}
+
+func (t *cassetteTool) Execute(ctx context.Context, arguments string, execCtx *executor.ExecutionContext) (*types.ToolResult, error) {
+	name := t.Definition().Name
+	fp := t.session.fingerprint([]string{name, arguments})
+	occ := t.session.next(t.session.toolSeen, fp)
+
+	if t.session.replayingTools() {
+		if tc := t.session.cassette.lookupTool(fp, occ); tc != nil {
+			logger.Debug("[Cassette] 回放工具结果, tool=%s, occurrence=%d", name, occ)
+			if tc.Error != "" {
+				return nil, errors.New(tc.Error)
+			}
+			result := *tc.Result
+			return &result, nil
+		}
+		if t.session.config.Strict {
+			return nil, fmt.Errorf("录制回放未命中工具调用 %s (参数: %s)", name, arguments)
+		}
+	}
+
+	result, err := t.Tool.Execute(ctx, arguments, execCtx)
+	if t.session.replaying() && !t.session.config.ReplayTools {
+		return result, err
+	}
+	recorded := &CassetteToolCall{Fingerprint: fp, Occurrence: occ, Name: name, Arguments: arguments}
+	if err != nil {
+		recorded.Error = err.Error()
+	} else if result != nil {
+		copied := *result
+		recorded.Result = &copied
+	}
+	t.session.recordTool(recorded)
+	return result, err
+}
+
+// recordedTool 只存在于录制文件中的工具，仅供回放
+type recordedTool struct {
+	def *types.ToolDefinition
+}
+
+func (t *recordedTool) Definition() *types.ToolDefinition { return t.def }
+
+func (t *recordedTool) Execute(ctx context.Context, arguments string, execCtx *executor.ExecutionContext) (*types.ToolResult, error) {
+	return types.NewErrorResult(fmt.Sprintf("工具 %s 仅存在于录制文件中，且未录制本次调用", t.def.Name)), nil
+}
@@ -0,0 +1,359 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// scriptedChatModel 按顺序返回预设响应的模型，记录调用次数
+type scriptedChatModel struct {
+	responses []*schema.Message
+	calls     int32
+}
+
+func (m *scriptedChatModel) next() (*schema.Message, error) {
+	n := int(atomic.AddInt32(&m.calls, 1)) - 1
+	if n >= len(m.responses) {
+		return nil, errors.New("no scripted response")
+	}
+	return m.responses[n], nil
+}
+
+func (m *scriptedChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	return m.next()
+}
+
+func (m *scriptedChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	resp, err := m.next()
+	if err != nil {
+		return nil, err
+	}
+	half := len(resp.Content) / 2
+	return schema.StreamReaderFromArray([]*schema.Message{
+		{Role: schema.Assistant, Content: resp.Content[:half]},
+		{Role: schema.Assistant, Content: resp.Content[half:], ResponseMeta: resp.ResponseMeta},
+	}), nil
+}
+
+func (m *scriptedChatModel) WithTools(tools []*schema.ToolInfo) (einomodel.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// countingTool 记录执行次数的工具
+type countingTool struct {
+	name  string
+	calls int32
+}
+
+func (t *countingTool) Definition() *types.ToolDefinition {
+	return &types.ToolDefinition{Name: t.name, Description: "测试工具", Parameters: json.RawMessage(`{"type":"object","properties":{}}`)}
+}
+
+func (t *countingTool) Execute(ctx context.Context, arguments string, execCtx *executor.ExecutionContext) (*types.ToolResult, error) {
+	n := atomic.AddInt32(&t.calls, 1)
+	return types.NewToolResult(strings.Repeat("x", int(n))), nil
+}
+
+func resetCassetteStore(t *testing.T) {
+	defaultCassetteStore = newCassetteStore()
+	t.Cleanup(func() { defaultCassetteStore = newCassetteStore() })
+}
+
+// useCassetteRoot 以临时目录作为录制文件根目录
+func useCassetteRoot(t *testing.T) string {
+	root := t.TempDir()
+	SetCassetteRoot(root)
+	t.Cleanup(func() { SetCassetteRoot("") })
+	return root
+}
+
+func cassetteInput(now string) []*schema.Message {
+	return []*schema.Message{
+		schema.SystemMessage("你是助手\n[当前环境]\n- 当前时间: " + now),
+		schema.UserMessage("你好"),
+	}
+}
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	resetCassetteStore(t)
+	path := filepath.Join(useCassetteRoot(t), defaultCassetteProject, "ai.json")
+	usage := &schema.ResponseMeta{FinishReason: "stop", Usage: &schema.TokenUsage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}}
+
+	live := &scriptedChatModel{responses: []*schema.Message{
+		{Role: schema.Assistant, Content: "第一次回答", ResponseMeta: usage},
+		{Role: schema.Assistant, Content: "流式回答", ResponseMeta: usage},
+	}}
+	session, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeRecord, ID: "ai"}, "step1")
+	require.NoError(t, err)
+	m := session.wrapModel(live)
+
+	resp, err := m.Generate(context.Background(), cassetteInput("2026-01-01 10:00:00"))
+	require.NoError(t, err)
+	assert.Equal(t, "第一次回答", resp.Content)
+
+	// 相同请求第二次出现：按出现序号区分
+	sr, err := m.Stream(context.Background(), cassetteInput("2026-01-01 10:00:01"))
+	require.NoError(t, err)
+	streamed := collectStream(t, sr, nil)
+	require.Len(t, streamed, 2)
+	require.NoError(t, session.Commit())
+
+	loaded, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Len(t, loaded.Interactions, 2)
+	assert.Equal(t, loaded.Interactions[0].Fingerprint, loaded.Interactions[1].Fingerprint)
+	assert.Equal(t, 1, loaded.Interactions[1].Occurrence)
+
+	// 回放：不访问真实模型，当前时间变化不影响命中
+	resetCassetteStore(t)
+	offline := &scriptedChatModel{}
+	session, err = newCassetteSession(&CassetteConfig{Mode: CassetteModeReplay, ID: "ai", Strict: true}, "step1")
+	require.NoError(t, err)
+	m = session.wrapModel(offline)
+
+	resp, err = m.Generate(context.Background(), cassetteInput("2027-06-30 23:59:59"))
+	require.NoError(t, err)
+	assert.Equal(t, "第一次回答", resp.Content)
+	assert.Equal(t, 5, resp.ResponseMeta.Usage.TotalTokens)
+
+	sr, err = m.Stream(context.Background(), cassetteInput("2027-06-30 23:59:59"))
+	require.NoError(t, err)
+	replayed := collectStream(t, sr, nil)
+	require.Len(t, replayed, 2)
+	assert.Equal(t, streamed[0].Content, replayed[0].Content)
+	assert.Equal(t, "stop", replayed[1].ResponseMeta.FinishReason)
+	assert.Zero(t, offline.calls)
+	require.NoError(t, session.Commit())
+}
+
+func TestCassette_ReplayMiss(t *testing.T) {
+	resetCassetteStore(t)
+	path := filepath.Join(useCassetteRoot(t), defaultCassetteProject, "ai.json")
+
+	session, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeRecord, ID: "ai"}, "step1")
+	require.NoError(t, err)
+	_, err = session.wrapModel(&scriptedChatModel{responses: []*schema.Message{{Role: schema.Assistant, Content: "a"}}}).
+		Generate(context.Background(), []*schema.Message{schema.UserMessage("问题A")})
+	require.NoError(t, err)
+	require.NoError(t, session.Commit())
+
+	// 严格模式：未命中直接失败
+	resetCassetteStore(t)
+	strict, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeReplay, ID: "ai", Strict: true}, "step1")
+	require.NoError(t, err)
+	_, err = strict.wrapModel(&scriptedChatModel{}).Generate(context.Background(), []*schema.Message{schema.UserMessage("问题B")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "未命中")
+
+	// 非严格模式：回退真实调用并补录
+	resetCassetteStore(t)
+	loose, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeReplay, ID: "ai"}, "step1")
+	require.NoError(t, err)
+	live := &scriptedChatModel{responses: []*schema.Message{{Role: schema.Assistant, Content: "b"}}}
+	resp, err := loose.wrapModel(live).Generate(context.Background(), []*schema.Message{schema.UserMessage("问题B")})
+	require.NoError(t, err)
+	assert.Equal(t, "b", resp.Content)
+	require.NoError(t, loose.Commit())
+
+	loaded, err := LoadCassette(path)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Interactions, 2)
+}
+
+func TestCassette_StrictReplayWithoutFile(t *testing.T) {
+	resetCassetteStore(t)
+	useCassetteRoot(t)
+	_, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeReplay, ID: "none", Strict: true}, "step1")
+	require.Error(t, err)
+}
+
+func TestCassette_ReplayTools(t *testing.T) {
+	resetCassetteStore(t)
+	useCassetteRoot(t)
+
+	tool := &countingTool{name: "lookup"}
+	mcpTool := &countingTool{name: "mcp_search"}
+	reg := executor.NewToolRegistry()
+	reg.Register(tool)
+	reg.Register(mcpTool)
+
+	session, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeRecord, ID: "ai"}, "step1")
+	require.NoError(t, err)
+	wrapped := session.wrapTools(reg)
+	result, err := wrapped.Execute(context.Background(), "lookup", `{"q":1}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "x", result.GetLLMContent())
+	result, err = wrapped.Execute(context.Background(), "lookup", `{"q":1}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "xx", result.GetLLMContent())
+	_, err = wrapped.Execute(context.Background(), "mcp_search", `{}`, nil)
+	require.NoError(t, err)
+	require.NoError(t, session.Commit())
+
+	// 回放工具：不执行真实工具，按出现顺序返回录制结果；MCP 工具不可用时以录制定义补齐
+	resetCassetteStore(t)
+	offlineReg := executor.NewToolRegistry()
+	offlineReg.Register(tool)
+	session, err = newCassetteSession(&CassetteConfig{Mode: CassetteModeReplay, ID: "ai", Strict: true, ReplayTools: true}, "step1")
+	require.NoError(t, err)
+	wrapped = session.wrapTools(offlineReg)
+	assert.True(t, wrapped.Has("mcp_search"))
+
+	result, err = wrapped.Execute(context.Background(), "lookup", `{"q":1}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "x", result.GetLLMContent())
+	result, err = wrapped.Execute(context.Background(), "lookup", `{"q":1}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "xx", result.GetLLMContent())
+	_, err = wrapped.Execute(context.Background(), "mcp_search", `{}`, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, tool.calls)
+	assert.EqualValues(t, 1, mcpTool.calls)
+
+	_, err = wrapped.Execute(context.Background(), "lookup", `{"q":2}`, nil)
+	require.Error(t, err)
+}
+
+func TestAIConfig_CassetteOverride(t *testing.T) {
+	root := useCassetteRoot(t)
+	SetCassetteOverride(&CassetteConfig{Mode: CassetteModeReplay, Strict: true})
+	defer SetCassetteOverride(nil)
+
+	cfg, err := parseAIConfig(map[string]any{
+		"model":    "gpt-test",
+		"prompt":   "hi",
+		"cassette": map[string]any{"mode": "record", "id": "suite/custom", "project": "project-3"},
+	})
+	require.NoError(t, err, "回放模式下不要求 api_key")
+	assert.Equal(t, CassetteModeReplay, cfg.Cassette.Mode)
+	assert.True(t, cfg.Cassette.Strict)
+	path, err := cfg.Cassette.filePath("s1")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "project-3", "suite", "custom.json"), path)
+
+	SetCassetteOverride(nil)
+	_, err = parseAIConfig(map[string]any{"model": "m", "prompt": "hi", "api_key": "k", "cassette": map[string]any{"mode": "bogus"}})
+	require.Error(t, err)
+}
+
+func TestCassette_PathConfinedToRoot(t *testing.T) {
+	root := useCassetteRoot(t)
+
+	for _, id := range []string{"/etc/passwd", "../escape", "a/../../b", "a//b", "a\\b", "./a"} {
+		_, err := parseAIConfig(map[string]any{"model": "m", "prompt": "hi", "api_key": "k",
+			"cassette": map[string]any{"mode": "record", "id": id}})
+		assert.Error(t, err, id)
+	}
+	_, err := parseAIConfig(map[string]any{"model": "m", "prompt": "hi", "api_key": "k",
+		"cassette": map[string]any{"mode": "record", "project": "a/b"}})
+	assert.Error(t, err)
+
+	// 变量解析后的录制 ID 与步骤 ID 同样校验
+	_, err = (&CassetteConfig{Mode: CassetteModeRecord, ID: "../../tmp/x"}).filePath("s1")
+	assert.Error(t, err)
+	_, err = (&CassetteConfig{Mode: CassetteModeRecord}).filePath("../s1")
+	assert.Error(t, err)
+
+	path, err := (&CassetteConfig{Mode: CassetteModeRecord}).filePath("s1")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, defaultCassetteProject, "s1.json"), path)
+}
+
+func TestCassetteStore_Evict(t *testing.T) {
+	resetCassetteStore(t)
+	root := useCassetteRoot(t)
+	store := defaultCassetteStore
+
+	session, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeRecord, ID: "busy"}, "s1")
+	require.NoError(t, err)
+	idle, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeRecord, ID: "idle"}, "s1")
+	require.NoError(t, err)
+	idle.Close()
+
+	// 闲置超时的录制文件被淘汰，仍在使用的保留
+	for _, e := range store.cassettes {
+		e.lastUsed = time.Now().Add(-2 * cassetteIdleTTL)
+	}
+	store.mu.Lock()
+	store.evict(time.Now())
+	store.mu.Unlock()
+	require.Len(t, store.cassettes, 1)
+	session.Close()
+
+	// 超过上限时淘汰最久未使用的
+	for i := 0; i < maxCachedCassettes+10; i++ {
+		s, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeReplay, ID: fmt.Sprintf("c%d", i)}, "s1")
+		require.NoError(t, err)
+		s.Close()
+	}
+	assert.LessOrEqual(t, len(store.cassettes), maxCachedCassettes)
+
+	// 本进程录制过的文件被淘汰后重新以录制模式打开时保留已录内容
+	rec, err := newCassetteSession(&CassetteConfig{Mode: CassetteModeRecord, ID: "kept"}, "s1")
+	require.NoError(t, err)
+	rec.record(&CassetteInteraction{Fingerprint: "fp"})
+	require.NoError(t, rec.Commit())
+	rec.Close()
+	resetCassetteStore(t)
+	rec, err = newCassetteSession(&CassetteConfig{Mode: CassetteModeRecord, ID: "kept"}, "s1")
+	require.NoError(t, err)
+	assert.False(t, rec.cassette.empty())
+	assert.FileExists(t, filepath.Join(root, defaultCassetteProject, "kept.json"))
+}
+
+// TestAgentExecutor_CassetteReplay 端到端：录制时访问本地模型替身，回放时替身已关闭仍得到相同输出
+func TestAgentExecutor_CassetteReplay(t *testing.T) {
+	resetCassetteStore(t)
+	useCassetteRoot(t)
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(`{"message":{"role":"assistant","content":"录制的回答"},"done":true,"done_reason":"stop","prompt_eval_count":9,"eval_count":4}` + "\n"))
+	}))
+
+	step := &types.Step{ID: "ask", Type: AgentType, Config: map[string]any{
+		"provider": providerOllama,
+		"model":    "qwen3",
+		"base_url": server.URL,
+		"prompt":   "总结一下",
+		"cassette": map[string]any{"mode": "record"},
+	}}
+	exec := NewAgentExecutor()
+
+	result, err := exec.Execute(context.Background(), step, executor.NewExecutionContext())
+	require.NoError(t, err)
+	require.Equal(t, types.ResultStatusSuccess, result.Status, "%v", result.Error)
+	recorded := result.Output.(*AIOutput)
+	assert.Equal(t, "录制的回答", recorded.Content)
+	server.Close()
+	require.EqualValues(t, 1, hits)
+
+	resetCassetteStore(t)
+	step.Config["cassette"] = map[string]any{"mode": "replay", "strict": true}
+	result, err = exec.Execute(context.Background(), step, executor.NewExecutionContext())
+	require.NoError(t, err)
+	require.Equal(t, types.ResultStatusSuccess, result.Status, "%v", result.Error)
+	replayed := result.Output.(*AIOutput)
+	assert.Equal(t, recorded.Content, replayed.Content)
+	assert.Equal(t, 13, replayed.TotalTokens)
+	assert.Equal(t, "stop", replayed.FinishReason)
+	assert.EqualValues(t, 1, hits)
+}
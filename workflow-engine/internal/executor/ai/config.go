@@ -1,6 +1,11 @@
 package ai
 
-import "yqhp/workflow-engine/internal/executor"
+import (
+	"encoding/json"
+
+	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/pkg/types"
+)
 
 // AIConfig 统一 AI Agent 配置
 type AIConfig struct {
@@ -20,6 +25,11 @@ type AIConfig struct {
 	Streaming          bool          `json:"streaming"`
 	Timeout            int           `json:"timeout,omitempty"`
 
+	// ===== 供应商高级配置 =====
+	ThinkingBudget int             `json:"thinking_budget,omitempty"` // 思考 Token 预算（anthropic/gemini），ollama 大于 0 时开启 think
+	PromptCache    bool            `json:"prompt_cache,omitempty"`    // anthropic 提示词缓存
+	SafetySettings []SafetySetting `json:"safety_settings,omitempty"` // gemini 安全设置
+
 	// ===== 工具配置 =====
 	Tools              []string             `json:"tools,omitempty"`
 	MCPServers         []*MCPServerConfig   `json:"mcp_servers,omitempty"`
@@ -30,6 +40,20 @@ type AIConfig struct {
 	KnowledgeBases     []*KnowledgeBaseInfo `json:"knowledge_bases,omitempty"`
 	KBTopK             int                  `json:"kb_top_k,omitempty"`
 	KBScoreThreshold   float32              `json:"kb_score_threshold,omitempty"`
+	KBFilter           json.RawMessage      `json:"kb_filter,omitempty"` // 节点级元数据过滤，与模型传入的 filter 同时生效
+	Sandbox            *types.SandboxPolicy `json:"sandbox,omitempty"`   // shell_exec / code_execute 沙箱策略
+
+	// ===== 结构化输出 =====
+	OutputSchema       map[string]any `json:"output_schema,omitempty"`        // 最终回复的 JSON Schema，解析结果作为步骤输出 data
+	OutputRepairRounds int            `json:"output_repair_rounds,omitempty"` // 校验失败后的修复轮数，默认 2
+
+	// ===== 计费与预算 =====
+	Pricing     *ModelPricing      `json:"pricing,omitempty"`      // 模型单价，用于计算步骤费用
+	Budget      *BudgetConfig      `json:"budget,omitempty"`       // 费用预算，未接入用量账本时每次调用模型前检查
+	UsageLedger *UsageLedgerConfig `json:"usage_ledger,omitempty"` // 用量账本，接入后预算与用量记录由账本负责
+
+	// ===== 录制回放 =====
+	Cassette *CassetteConfig `json:"cassette,omitempty"`
 
 	// ===== Fallback 配置 =====
 	FallbackModels []FallbackModelConfig `json:"fallback_models,omitempty"`
@@ -48,6 +72,12 @@ type FallbackModelConfig struct {
 	BaseURL  string `json:"base_url,omitempty"`
 }
 
+// SafetySetting Gemini 安全过滤设置
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
 // SkillInfo Skill 能力信息
 type SkillInfo struct {
 	ID          int64  `json:"id"`
@@ -61,6 +91,7 @@ type KnowledgeBaseInfo struct {
 	ID                 int64   `json:"id"`
 	Name               string  `json:"name"`
 	Type               string  `json:"type"`
+	VectorStore        string  `json:"vector_store,omitempty"` // qdrant（默认）/ embedded / pgvector
 	QdrantCollection   string  `json:"qdrant_collection,omitempty"`
 	Neo4jDatabase      string  `json:"neo4j_database,omitempty"`
 	EmbeddingModel     string  `json:"embedding_model,omitempty"`
@@ -71,6 +102,9 @@ type KnowledgeBaseInfo struct {
 	EmbeddingAPIKey    string  `json:"embedding_api_key,omitempty"`
 	EmbeddingBaseURL   string  `json:"embedding_base_url,omitempty"`
 	EmbeddingDimension int     `json:"embedding_dimension,omitempty"`
+	// ACLUserID 按该用户的文档访问控制检索（调用工作流的用户），为空表示不做访问控制
+	ACLUserID    *int64   `json:"acl_user_id,omitempty"`
+	MetadataKeys []string `json:"metadata_keys,omitempty"` // 可用于过滤的元数据键
 }
 
 // MCPServerConfig MCP 服务器连接配置
@@ -36,6 +36,7 @@ func (c *AIConfig) applyDefaults() {
 	if c.Provider == "" {
 		c.Provider = "openai"
 	}
+	c.applyCassetteOverride()
 }
 
 // Validate 校验必填字段和值范围
@@ -43,7 +44,7 @@ func (c *AIConfig) Validate() error {
 	if c.Model == "" {
 		return executor.NewConfigError("AI 节点需要配置 'model'", nil)
 	}
-	if c.APIKey == "" {
+	if c.APIKey == "" && c.Provider != providerOllama && !c.Cassette.replaying() {
 		return executor.NewConfigError("AI 节点需要配置 'api_key'", nil)
 	}
 	if c.Prompt == "" {
@@ -59,6 +60,33 @@ func (c *AIConfig) Validate() error {
 			return executor.NewConfigError(fmt.Sprintf("top_p 应在 0~1 之间，当前值: %.2f", *c.TopP), nil)
 		}
 	}
+	if c.ThinkingBudget < 0 {
+		return executor.NewConfigError(fmt.Sprintf("thinking_budget 不能为负数，当前值: %d", c.ThinkingBudget), nil)
+	}
+	if err := c.validateOutputSchema(); err != nil {
+		return err
+	}
+	if c.Cassette != nil {
+		if err := c.Cassette.validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.Sandbox.Validate(); err != nil {
+		return executor.NewConfigError(err.Error(), err)
+	}
+	if p := c.Pricing; p != nil {
+		if p.InputPrice < 0 || p.OutputPrice < 0 || (p.CachedPrice != nil && *p.CachedPrice < 0) {
+			return executor.NewConfigError("pricing 单价不能为负数", nil)
+		}
+	}
+	if b := c.Budget; b != nil {
+		if b.SoftLimit < 0 || b.HardLimit < 0 {
+			return executor.NewConfigError("budget 上限不能为负数", nil)
+		}
+	}
+	if l := c.UsageLedger; l != nil && l.URL == "" {
+		return executor.NewConfigError("usage_ledger.url 不能为空", nil)
+	}
 	return nil
 }
 
@@ -295,6 +323,9 @@ func resolveConfigVariables(config *AIConfig, execCtx *executor.ExecutionContext
 	config.BaseURL = resolver.ResolveString(config.BaseURL, evalCtx)
 	config.QdrantHost = resolver.ResolveString(config.QdrantHost, evalCtx)
 	config.GuluHost = resolver.ResolveString(config.GuluHost, evalCtx)
+	if config.Cassette != nil {
+		config.Cassette.ID = resolver.ResolveString(config.Cassette.ID, evalCtx)
+	}
 
 	return config
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/cloudwego/eino/schema"
 
 	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/internal/executor/sandbox"
 	"yqhp/workflow-engine/pkg/logger"
 	"yqhp/workflow-engine/pkg/types"
 )
@@ -40,8 +41,12 @@ func (e *AgentExecutor) Execute(ctx context.Context, step *types.Step, execCtx *
 	defer cleanup()
 
 	output, err := RunAgent(req.ctx, req.agentReq)
+	if commitErr := req.cassette.Commit(); commitErr != nil {
+		return executor.CreateFailedResult(step.ID, req.startTime,
+			executor.NewExecutionError(step.ID, "AI 录制回放失败", commitErr)), nil
+	}
 	if err != nil {
-		return handleAgentError(step, req, err)
+		return handleAgentError(step, req, output, err)
 	}
 
 	return buildAgentResult(step, req, output)
@@ -70,6 +75,7 @@ type preparedRequest struct {
 	config     *AIConfig
 	agentReq   *AgentRequest
 	mcpClients []mcpCloser
+	cassette   *cassetteSession
 	startTime  time.Time
 }
 
@@ -91,6 +97,14 @@ func buildAgentRequest(ctx context.Context, step *types.Step, execCtx *executor.
 		return nil, func() {}, executor.NewExecutionError(step.ID, "创建 AI 模型失败", err)
 	}
 
+	cassette, err := newCassetteSession(config.Cassette, step.ID)
+	if err != nil {
+		return nil, func() {}, executor.NewExecutionError(step.ID, "加载 AI 录制文件失败", err)
+	}
+	if cassette != nil {
+		chatModel = cassette.wrapModel(chatModel)
+	}
+
 	timeout := step.Timeout
 	if timeout <= 0 && config.Timeout > 0 {
 		timeout = time.Duration(config.Timeout) * time.Second
@@ -103,11 +117,15 @@ func buildAgentRequest(ctx context.Context, step *types.Step, execCtx *executor.
 	callbacks := NewAgentCallbacks(execCtx.Callback, step.ID)
 
 	ctx = WithExecCtx(ctx, execCtx)
+	ctx = sandbox.WithPolicy(ctx, config.Sandbox)
 	if callbacks.Stream != nil {
 		ctx = WithAICallback(ctx, callbacks.Stream)
 	}
 
-	toolRegistry, mcpClients := buildToolRegistry(ctx, config, execCtx, step.ID, callbacks.Stream)
+	toolRegistry, mcpClients := buildToolRegistry(ctx, config, execCtx, step.ID, callbacks.Stream, cassette)
+	if cassette != nil {
+		toolRegistry = cassette.wrapTools(toolRegistry)
+	}
 
 	allToolDefs := toolRegistry.List()
 	schemaTools := toSchemaTools(allToolDefs)
@@ -133,6 +151,7 @@ func buildAgentRequest(ctx context.Context, step *types.Step, execCtx *executor.
 		ExecCtx:      execCtx,
 		Callbacks:    callbacks,
 		MaxRounds:    maxRounds,
+		Meter:        newUsageMeter(config, step.ID, execCtx),
 	}
 
 	cleanup := func() {
@@ -140,6 +159,7 @@ func buildAgentRequest(ctx context.Context, step *types.Step, execCtx *executor.
 		if len(mcpClients) > 0 {
 			closeMCPClients(mcpClients)
 		}
+		cassette.Close()
 	}
 
 	return &preparedRequest{
@@ -148,11 +168,13 @@ func buildAgentRequest(ctx context.Context, step *types.Step, execCtx *executor.
 		config:     config,
 		agentReq:   agentReq,
 		mcpClients: mcpClients,
+		cassette:   cassette,
 		startTime:  startTime,
 	}, cleanup, nil
 }
 
-func handleAgentError(step *types.Step, req *preparedRequest, err error) (*types.StepResult, error) {
+// handleAgentError 失败结果同样保留已产生的 Token 用量与费用，便于统计超出预算前的消耗
+func handleAgentError(step *types.Step, req *preparedRequest, output *AIOutput, err error) (*types.StepResult, error) {
 	if req.ctx.Err() == context.DeadlineExceeded {
 		timeout := step.Timeout
 		if timeout <= 0 && req.config.Timeout > 0 {
@@ -166,8 +188,13 @@ func handleAgentError(step *types.Step, req *preparedRequest, err error) (*types
 	if req.agentReq.Callbacks.Stream != nil {
 		req.agentReq.Callbacks.Stream.OnAIError(req.ctx, step.ID, err)
 	}
-	return executor.CreateFailedResult(step.ID, req.startTime,
-		executor.NewExecutionError(step.ID, "AI Agent 调用失败", err)), nil
+	result := executor.CreateFailedResult(step.ID, req.startTime,
+		executor.NewExecutionError(step.ID, "AI Agent 调用失败", err))
+	if output != nil {
+		result.Output = output
+		setUsageMetrics(result, output)
+	}
+	return result, nil
 }
 
 func buildAgentResult(step *types.Step, req *preparedRequest, output *AIOutput) (*types.StepResult, error) {
@@ -177,15 +204,21 @@ func buildAgentResult(step *types.Step, req *preparedRequest, output *AIOutput)
 	executePostProcessors(req.ctx, step, req.agentReq.ExecCtx, output, req.startTime)
 
 	result := executor.CreateSuccessResult(step.ID, req.startTime, output)
+	setUsageMetrics(result, output)
+	return result, nil
+}
+
+func setUsageMetrics(result *types.StepResult, output *AIOutput) {
 	result.Metrics["ai_prompt_tokens"] = float64(output.PromptTokens)
+	result.Metrics["ai_cached_tokens"] = float64(output.CachedTokens)
 	result.Metrics["ai_completion_tokens"] = float64(output.CompletionTokens)
 	result.Metrics["ai_total_tokens"] = float64(output.TotalTokens)
-	return result, nil
+	result.Metrics["ai_cost"] = output.Cost
 }
 
 // --- 工具注册表 ---
 
-func buildToolRegistry(ctx context.Context, config *AIConfig, execCtx *executor.ExecutionContext, stepID string, aiCallback types.AIStreamCallback) (*executor.ToolRegistry, []mcpCloser) {
+func buildToolRegistry(ctx context.Context, config *AIConfig, execCtx *executor.ExecutionContext, stepID string, aiCallback types.AIStreamCallback, cassette *cassetteSession) (*executor.ToolRegistry, []mcpCloser) {
 	reg := executor.DefaultToolRegistry.Clone()
 
 	if len(config.Tools) > 0 {
@@ -233,8 +266,9 @@ func buildToolRegistry(ctx context.Context, config *AIConfig, execCtx *executor.
 		reg.Register(NewInstallSkillTool(config))
 	}
 
+	// 回放工具结果时不连接 MCP Server，其工具以录制文件中的定义补齐
 	var mcpClients []mcpCloser
-	if len(config.MCPServers) > 0 {
+	if len(config.MCPServers) > 0 && (cassette == nil || !cassette.replayingTools()) {
 		for _, serverCfg := range config.MCPServers {
 			tools, cli, err := loadMCPTools(ctx, serverCfg)
 			if err != nil {
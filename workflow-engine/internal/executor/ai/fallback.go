@@ -78,6 +78,8 @@ func (fc *FallbackChain) Execute(
 			Model:    fb.Model,
 			APIKey:   fb.APIKey,
 			BaseURL:  fb.BaseURL,
+
+			OutputSchema: primaryConfig.OutputSchema,
 		}
 
 		logger.Debug("[FallbackChain] 尝试降级模型[%d] %s (provider=%s)", i, fb.Model, fb.Provider)
@@ -42,7 +42,12 @@ func executePostProcessors(ctx context.Context, step *types.Step, execCtx *execu
 
 	responseBody := output.Content
 	var jsonTest json.RawMessage
-	if json.Unmarshal([]byte(output.Content), &jsonTest) != nil {
+	if output.Data != nil {
+		// 结构化输出：响应体即解析后的对象，json_path 直接作用于其字段
+		if data, err := json.Marshal(output.Data); err == nil {
+			responseBody = string(data)
+		}
+	} else if json.Unmarshal([]byte(output.Content), &jsonTest) != nil {
 		wrapped := map[string]interface{}{
 			"content":           output.Content,
 			"model":             output.Model,
@@ -62,8 +67,10 @@ func executePostProcessors(ctx context.Context, step *types.Step, execCtx *execu
 		"model":             output.Model,
 		"finish_reason":     output.FinishReason,
 		"prompt_tokens":     output.PromptTokens,
+		"cached_tokens":     output.CachedTokens,
 		"completion_tokens": output.CompletionTokens,
 		"total_tokens":      output.TotalTokens,
+		"cost":              output.Cost,
 		"tool_calls":        toolCallsJSON,
 		"duration":          time.Since(startTime).Milliseconds(),
 	})
@@ -41,6 +41,10 @@ func (pb *PromptBuilder) Build() string {
 		sections = append(sections, pb.buildSkillsSection())
 	}
 
+	if pb.config.OutputSchema != nil {
+		sections = append(sections, pb.buildOutputSchemaSection())
+	}
+
 	sections = append(sections, pb.buildDynamicContext())
 
 	return strings.Join(sections, "\n")
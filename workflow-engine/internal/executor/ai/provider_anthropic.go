@@ -0,0 +1,392 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	anthropicDefaultBaseURL   = "https://api.anthropic.com"
+	anthropicVersion          = "2023-06-01"
+	anthropicDefaultMaxTokens = 4096
+
+	// anthropicThinkingExtraKey 助手消息 Extra 中保存的思考块（含签名），工具调用续轮时必须原样回传
+	anthropicThinkingExtraKey = "anthropic_thinking"
+)
+
+// anthropicChatModel Anthropic Messages API 原生实现
+type anthropicChatModel struct {
+	nativeChatModel
+}
+
+func newAnthropicChatModel(config *AIConfig) *anthropicChatModel {
+	return &anthropicChatModel{nativeChatModel: newNativeChatModel(config)}
+}
+
+type anthropicRequest struct {
+	Model         string              `json:"model"`
+	MaxTokens     int                 `json:"max_tokens"`
+	System        []*anthropicBlock   `json:"system,omitempty"`
+	Messages      []*anthropicMessage `json:"messages"`
+	Tools         []*anthropicTool    `json:"tools,omitempty"`
+	ToolChoice    map[string]any      `json:"tool_choice,omitempty"`
+	Temperature   *float32            `json:"temperature,omitempty"`
+	TopP          *float32            `json:"top_p,omitempty"`
+	StopSequences []string            `json:"stop_sequences,omitempty"`
+	Thinking      *anthropicThinking  `json:"thinking,omitempty"`
+	Stream        bool                `json:"stream"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type anthropicMessage struct {
+	Role    string            `json:"role"`
+	Content []*anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// image / document
+	Source *anthropicSource `json:"source,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+
+	// thinking / redacted_thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+type anthropicTool struct {
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]any         `json:"input_schema"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// anthropicEvent 流式事件（message_start / content_block_* / message_delta / error）
+type anthropicEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message *struct {
+		Usage *anthropicUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock *anthropicBlock `json:"content_block"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (m *anthropicChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	return generateFromStream(m.Stream(ctx, input, opts...))
+}
+
+func (m *anthropicChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	req, err := m.buildRequest(input, m.options(opts))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.post(ctx, "Anthropic", m.endpoint(anthropicDefaultBaseURL, "/v1/messages"), map[string]string{
+		"x-api-key":         m.config.APIKey,
+		"anthropic-version": anthropicVersion,
+	}, req)
+	if err != nil {
+		return nil, err
+	}
+	return streamResponse(resp, parseAnthropicStream), nil
+}
+
+func (m *anthropicChatModel) WithTools(tools []*schema.ToolInfo) (einomodel.ToolCallingChatModel, error) {
+	clone := *m
+	clone.tools = tools
+	return &clone, nil
+}
+
+func (m *anthropicChatModel) buildRequest(input []*schema.Message, options *einomodel.Options) (*anthropicRequest, error) {
+	req := &anthropicRequest{
+		Model:         *options.Model,
+		MaxTokens:     anthropicDefaultMaxTokens,
+		StopSequences: options.Stop,
+		Stream:        true,
+	}
+	if options.MaxTokens != nil && *options.MaxTokens > 0 {
+		req.MaxTokens = *options.MaxTokens
+	}
+	if m.config.ThinkingBudget > 0 {
+		// 开启扩展思考时不允许调整 temperature/top_p，且 max_tokens 必须大于思考预算
+		req.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: m.config.ThinkingBudget}
+		if req.MaxTokens <= m.config.ThinkingBudget {
+			req.MaxTokens = m.config.ThinkingBudget + anthropicDefaultMaxTokens
+		}
+	} else {
+		req.Temperature = options.Temperature
+		req.TopP = options.TopP
+	}
+
+	for _, tool := range options.Tools {
+		params, err := toolParameters(tool)
+		if err != nil {
+			return nil, err
+		}
+		req.Tools = append(req.Tools, &anthropicTool{Name: tool.Name, Description: tool.Desc, InputSchema: params})
+	}
+	if len(req.Tools) > 0 && options.ToolChoice != nil {
+		switch *options.ToolChoice {
+		case schema.ToolChoiceForbidden:
+			req.ToolChoice = map[string]any{"type": "none"}
+		case schema.ToolChoiceForced:
+			req.ToolChoice = map[string]any{"type": "any"}
+		}
+	}
+
+	for _, msg := range input {
+		switch msg.Role {
+		case schema.System:
+			if msg.Content != "" {
+				req.System = append(req.System, &anthropicBlock{Type: "text", Text: msg.Content})
+			}
+		case schema.User:
+			req.appendBlocks("user", anthropicUserBlocks(msg)...)
+		case schema.Assistant:
+			req.appendBlocks("assistant", anthropicAssistantBlocks(msg)...)
+		case schema.Tool:
+			req.appendBlocks("user", &anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content})
+		}
+	}
+
+	if m.config.PromptCache {
+		req.markCacheBreakpoints()
+	}
+	return req, nil
+}
+
+// appendBlocks 追加内容块，相邻同角色消息合并（Messages API 要求 user/assistant 交替）
+func (r *anthropicRequest) appendBlocks(role string, blocks ...*anthropicBlock) {
+	if len(blocks) == 0 {
+		return
+	}
+	if n := len(r.Messages); n > 0 && r.Messages[n-1].Role == role {
+		r.Messages[n-1].Content = append(r.Messages[n-1].Content, blocks...)
+		return
+	}
+	r.Messages = append(r.Messages, &anthropicMessage{Role: role, Content: blocks})
+}
+
+// markCacheBreakpoints 在系统提示词、工具定义和最后一条消息处设置缓存断点，多轮工具调用时复用已缓存前缀
+func (r *anthropicRequest) markCacheBreakpoints() {
+	ephemeral := &anthropicCacheControl{Type: "ephemeral"}
+	if n := len(r.System); n > 0 {
+		r.System[n-1].CacheControl = ephemeral
+	}
+	if n := len(r.Tools); n > 0 {
+		r.Tools[n-1].CacheControl = ephemeral
+	}
+	if n := len(r.Messages); n > 0 {
+		blocks := r.Messages[n-1].Content
+		for i := len(blocks) - 1; i >= 0; i-- {
+			if blocks[i].Type != "thinking" && blocks[i].Type != "redacted_thinking" {
+				blocks[i].CacheControl = ephemeral
+				break
+			}
+		}
+	}
+}
+
+func anthropicUserBlocks(msg *schema.Message) []*anthropicBlock {
+	var blocks []*anthropicBlock
+	messageParts(msg, func(partType schema.ChatMessagePartType, text string, media *mediaPart) {
+		if media == nil {
+			blocks = append(blocks, &anthropicBlock{Type: "text", Text: text})
+			return
+		}
+		blockType := "image"
+		if partType == schema.ChatMessagePartTypeFileURL {
+			blockType = "document"
+		} else if partType != schema.ChatMessagePartTypeImageURL {
+			// 音视频不受支持，以链接形式提供给模型
+			if media.URL != "" {
+				blocks = append(blocks, &anthropicBlock{Type: "text", Text: media.URL})
+			}
+			return
+		}
+		source := &anthropicSource{Type: "url", URL: media.URL}
+		if media.Data != "" {
+			source = &anthropicSource{Type: "base64", MediaType: media.MIMEType, Data: media.Data}
+		}
+		blocks = append(blocks, &anthropicBlock{Type: blockType, Source: source})
+	})
+	return blocks
+}
+
+func anthropicAssistantBlocks(msg *schema.Message) []*anthropicBlock {
+	var blocks []*anthropicBlock
+	if thinking, ok := msg.Extra[anthropicThinkingExtraKey].([]*anthropicBlock); ok {
+		blocks = append(blocks, thinking...)
+	}
+	if msg.Content != "" {
+		blocks = append(blocks, &anthropicBlock{Type: "text", Text: msg.Content})
+	}
+	for _, tc := range msg.ToolCalls {
+		input, _ := json.Marshal(parseToolArguments(tc.Function.Arguments))
+		blocks = append(blocks, &anthropicBlock{Type: "tool_use", ID: tc.ID, Name: tc.Function.Name, Input: input})
+	}
+	return blocks
+}
+
+// parseAnthropicStream 解析 Messages API 流式事件：文本与思考增量直接输出，
+// 工具调用在内容块结束时整体输出，用量、结束原因和思考签名在消息结束时输出
+func parseAnthropicStream(body io.Reader, emit func(*schema.Message) bool) error {
+	var (
+		usage      anthropicUsage
+		stopReason string
+		blocks     = make(map[int]*anthropicBlock)
+		toolArgs   = make(map[int]*strings.Builder)
+		thinking   []*anthropicBlock
+	)
+
+	return readSSE(body, func(event, data string) (bool, error) {
+		var ev anthropicEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return false, fmt.Errorf("Anthropic 流式响应解析失败: %w", err)
+		}
+
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil && ev.Message.Usage != nil {
+				usage = *ev.Message.Usage
+			}
+		case "content_block_start":
+			if ev.ContentBlock != nil {
+				blocks[ev.Index] = ev.ContentBlock
+				if ev.ContentBlock.Type == "tool_use" {
+					toolArgs[ev.Index] = &strings.Builder{}
+				}
+			}
+		case "content_block_delta":
+			if ev.Delta == nil {
+				return true, nil
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				return emit(&schema.Message{Role: schema.Assistant, Content: ev.Delta.Text}), nil
+			case "thinking_delta":
+				if block := blocks[ev.Index]; block != nil {
+					block.Thinking += ev.Delta.Thinking
+				}
+				return emit(&schema.Message{Role: schema.Assistant, ReasoningContent: ev.Delta.Thinking}), nil
+			case "signature_delta":
+				if block := blocks[ev.Index]; block != nil {
+					block.Signature += ev.Delta.Signature
+				}
+			case "input_json_delta":
+				if args := toolArgs[ev.Index]; args != nil {
+					args.WriteString(ev.Delta.PartialJSON)
+				}
+			}
+		case "content_block_stop":
+			block := blocks[ev.Index]
+			if block == nil {
+				return true, nil
+			}
+			switch block.Type {
+			case "thinking", "redacted_thinking":
+				thinking = append(thinking, block)
+			case "tool_use":
+				args := strings.TrimSpace(toolArgs[ev.Index].String())
+				if args == "" {
+					args = "{}"
+				}
+				return emit(&schema.Message{Role: schema.Assistant, ToolCalls: []schema.ToolCall{{
+					ID:       block.ID,
+					Type:     "function",
+					Function: schema.FunctionCall{Name: block.Name, Arguments: args},
+				}}}), nil
+			}
+		case "message_delta":
+			if ev.Delta != nil && ev.Delta.StopReason != "" {
+				stopReason = ev.Delta.StopReason
+			}
+			if ev.Usage != nil {
+				usage.OutputTokens = ev.Usage.OutputTokens
+				if ev.Usage.InputTokens > 0 {
+					usage.InputTokens = ev.Usage.InputTokens
+				}
+			}
+		case "message_stop":
+			final := &schema.Message{
+				Role:         schema.Assistant,
+				ResponseMeta: &schema.ResponseMeta{FinishReason: stopReason, Usage: anthropicTokenUsage(usage)},
+			}
+			if len(thinking) > 0 {
+				final.Extra = map[string]any{anthropicThinkingExtraKey: thinking}
+			}
+			emit(final)
+			return false, nil
+		case "error":
+			if ev.Error != nil {
+				return false, fmt.Errorf("Anthropic 流式响应错误 (%s): %s", ev.Error.Type, ev.Error.Message)
+			}
+			return false, fmt.Errorf("Anthropic 流式响应错误: %s", data)
+		}
+		return true, nil
+	})
+}
+
+// anthropicTokenUsage 输入 Token 包含缓存写入与缓存命中部分，缓存命中单独计入 CachedTokens
+func anthropicTokenUsage(usage anthropicUsage) *schema.TokenUsage {
+	prompt := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+	return &schema.TokenUsage{
+		PromptTokens:       prompt,
+		PromptTokenDetails: schema.PromptTokenDetails{CachedTokens: usage.CacheReadInputTokens},
+		CompletionTokens:   usage.OutputTokens,
+		TotalTokens:        prompt + usage.OutputTokens,
+	}
+}
@@ -0,0 +1,346 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+	// geminiSignatureExtraKey 工具调用 Extra 中保存的思考签名，续轮时需随 functionCall 回传
+	geminiSignatureExtraKey = "gemini_thought_signature"
+)
+
+// geminiChatModel Gemini generateContent API 原生实现
+type geminiChatModel struct {
+	nativeChatModel
+}
+
+func newGeminiChatModel(config *AIConfig) *geminiChatModel {
+	return &geminiChatModel{nativeChatModel: newNativeChatModel(config)}
+}
+
+type geminiRequest struct {
+	Contents          []*geminiContent        `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []*geminiTool           `json:"tools,omitempty"`
+	ToolConfig        map[string]any          `json:"toolConfig,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+	SafetySettings    []SafetySetting         `json:"safetySettings,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string        `json:"role,omitempty"`
+	Parts []*geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MIMEType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MIMEType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []*geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float32              `json:"temperature,omitempty"`
+	TopP            *float32              `json:"topP,omitempty"`
+	MaxOutputTokens *int                  `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string              `json:"stopSequences,omitempty"`
+	PresencePenalty *float32              `json:"presencePenalty,omitempty"`
+	ThinkingConfig  *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+
+	ResponseMIMEType   string         `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema map[string]any `json:"responseJsonSchema,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	IncludeThoughts bool `json:"includeThoughts"`
+	ThinkingBudget  int  `json:"thinkingBudget"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      *geminiContent `json:"content"`
+		FinishReason string         `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+		TotalTokenCount         int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+func (m *geminiChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	return generateFromStream(m.Stream(ctx, input, opts...))
+}
+
+func (m *geminiChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	options := m.options(opts)
+	req, err := m.buildRequest(input, options)
+	if err != nil {
+		return nil, err
+	}
+	path := "/models/" + url.PathEscape(*options.Model) + ":streamGenerateContent?alt=sse"
+	resp, err := m.post(ctx, "Gemini", m.endpoint(geminiDefaultBaseURL, path), map[string]string{
+		"x-goog-api-key": m.config.APIKey,
+	}, req)
+	if err != nil {
+		return nil, err
+	}
+	return streamResponse(resp, parseGeminiStream), nil
+}
+
+func (m *geminiChatModel) WithTools(tools []*schema.ToolInfo) (einomodel.ToolCallingChatModel, error) {
+	clone := *m
+	clone.tools = tools
+	return &clone, nil
+}
+
+func (m *geminiChatModel) buildRequest(input []*schema.Message, options *einomodel.Options) (*geminiRequest, error) {
+	req := &geminiRequest{
+		GenerationConfig: &geminiGenerationConfig{
+			Temperature:     options.Temperature,
+			TopP:            options.TopP,
+			MaxOutputTokens: options.MaxTokens,
+			StopSequences:   options.Stop,
+			PresencePenalty: m.config.PresencePenalty,
+		},
+		SafetySettings: m.config.SafetySettings,
+	}
+	if m.config.ThinkingBudget > 0 {
+		req.GenerationConfig.ThinkingConfig = &geminiThinkingConfig{IncludeThoughts: true, ThinkingBudget: m.config.ThinkingBudget}
+	}
+
+	if len(options.Tools) > 0 {
+		decls := make([]*geminiFunctionDeclaration, 0, len(options.Tools))
+		for _, tool := range options.Tools {
+			params, err := toolParameters(tool)
+			if err != nil {
+				return nil, err
+			}
+			decls = append(decls, &geminiFunctionDeclaration{Name: tool.Name, Description: tool.Desc, Parameters: params})
+		}
+		req.Tools = []*geminiTool{{FunctionDeclarations: decls}}
+		if options.ToolChoice != nil {
+			switch *options.ToolChoice {
+			case schema.ToolChoiceForbidden:
+				req.ToolConfig = map[string]any{"functionCallingConfig": map[string]any{"mode": "NONE"}}
+			case schema.ToolChoiceForced:
+				req.ToolConfig = map[string]any{"functionCallingConfig": map[string]any{"mode": "ANY"}}
+			}
+		}
+	} else if m.config.OutputSchema != nil {
+		// JSON 响应模式不能与函数调用同时使用，仅在无工具的请求上开启
+		req.GenerationConfig.ResponseMIMEType = "application/json"
+		req.GenerationConfig.ResponseJSONSchema = m.config.OutputSchema
+	}
+
+	names := toolCallNames(input)
+	for _, msg := range input {
+		switch msg.Role {
+		case schema.System:
+			if msg.Content == "" {
+				continue
+			}
+			if req.SystemInstruction == nil {
+				req.SystemInstruction = &geminiContent{}
+			}
+			req.SystemInstruction.Parts = append(req.SystemInstruction.Parts, &geminiPart{Text: msg.Content})
+		case schema.User:
+			req.appendParts("user", geminiUserParts(msg)...)
+		case schema.Assistant:
+			req.appendParts("model", geminiModelParts(msg)...)
+		case schema.Tool:
+			req.appendParts("user", &geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     toolResultName(msg, names),
+				Response: geminiToolResponse(msg.Content),
+			}})
+		}
+	}
+	return req, nil
+}
+
+// appendParts 追加内容片段，相邻同角色内容合并（并行工具调用的结果需放在同一轮中）
+func (r *geminiRequest) appendParts(role string, parts ...*geminiPart) {
+	if len(parts) == 0 {
+		return
+	}
+	if n := len(r.Contents); n > 0 && r.Contents[n-1].Role == role {
+		r.Contents[n-1].Parts = append(r.Contents[n-1].Parts, parts...)
+		return
+	}
+	r.Contents = append(r.Contents, &geminiContent{Role: role, Parts: parts})
+}
+
+func geminiUserParts(msg *schema.Message) []*geminiPart {
+	var parts []*geminiPart
+	messageParts(msg, func(partType schema.ChatMessagePartType, text string, media *mediaPart) {
+		switch {
+		case media == nil:
+			parts = append(parts, &geminiPart{Text: text})
+		case media.Data != "":
+			parts = append(parts, &geminiPart{InlineData: &geminiBlob{MIMEType: media.MIMEType, Data: media.Data}})
+		default:
+			parts = append(parts, &geminiPart{FileData: &geminiFileData{MIMEType: media.MIMEType, FileURI: media.URL}})
+		}
+	})
+	return parts
+}
+
+func geminiModelParts(msg *schema.Message) []*geminiPart {
+	var parts []*geminiPart
+	if msg.Content != "" {
+		parts = append(parts, &geminiPart{Text: msg.Content})
+	}
+	for _, tc := range msg.ToolCalls {
+		part := &geminiPart{FunctionCall: &geminiFunctionCall{Name: tc.Function.Name, Args: parseToolArguments(tc.Function.Arguments)}}
+		if signature, ok := tc.Extra[geminiSignatureExtraKey].(string); ok {
+			part.ThoughtSignature = signature
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// geminiToolResponse functionResponse.response 必须是对象，非 JSON 对象的结果包装为 {"result": ...}
+func geminiToolResponse(content string) map[string]any {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(content), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]any{"result": content}
+}
+
+// parseGeminiStream 解析 streamGenerateContent 的 SSE 响应：思考片段输出为 ReasoningContent，
+// 函数调用整体输出（Gemini 不返回调用 ID 时按序生成），用量与结束原因在流结束时输出
+func parseGeminiStream(body io.Reader, emit func(*schema.Message) bool) error {
+	var (
+		finishReason string
+		usage        *schema.TokenUsage
+		callSeq      int
+	)
+
+	err := readSSE(body, func(event, data string) (bool, error) {
+		var resp geminiResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			return false, fmt.Errorf("Gemini 流式响应解析失败: %w", err)
+		}
+		if resp.Error != nil {
+			return false, fmt.Errorf("Gemini 流式响应错误 (%d %s): %s", resp.Error.Code, resp.Error.Status, resp.Error.Message)
+		}
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			return false, fmt.Errorf("Gemini 拒绝了请求: %s", resp.PromptFeedback.BlockReason)
+		}
+		if u := resp.UsageMetadata; u != nil {
+			usage = &schema.TokenUsage{
+				PromptTokens:            u.PromptTokenCount,
+				PromptTokenDetails:      schema.PromptTokenDetails{CachedTokens: u.CachedContentTokenCount},
+				CompletionTokens:        u.CandidatesTokenCount + u.ThoughtsTokenCount,
+				CompletionTokensDetails: schema.CompletionTokensDetails{ReasoningTokens: u.ThoughtsTokenCount},
+				TotalTokens:             u.TotalTokenCount,
+			}
+		}
+		if len(resp.Candidates) == 0 {
+			return true, nil
+		}
+
+		candidate := resp.Candidates[0]
+		if candidate.FinishReason != "" {
+			finishReason = candidate.FinishReason
+		}
+		if candidate.Content == nil {
+			return true, nil
+		}
+		for _, part := range candidate.Content.Parts {
+			msg := &schema.Message{Role: schema.Assistant}
+			switch {
+			case part.FunctionCall != nil:
+				callSeq++
+				id := part.FunctionCall.ID
+				if id == "" {
+					id = fmt.Sprintf("call_%d", callSeq)
+				}
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				if part.FunctionCall.Args == nil {
+					args = []byte("{}")
+				}
+				tc := schema.ToolCall{ID: id, Type: "function", Function: schema.FunctionCall{Name: part.FunctionCall.Name, Arguments: string(args)}}
+				if part.ThoughtSignature != "" {
+					tc.Extra = map[string]any{geminiSignatureExtraKey: part.ThoughtSignature}
+				}
+				msg.ToolCalls = []schema.ToolCall{tc}
+			case part.Thought:
+				msg.ReasoningContent = part.Text
+			case part.Text != "":
+				msg.Content = part.Text
+			default:
+				continue
+			}
+			if !emit(msg) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	emit(&schema.Message{
+		Role:         schema.Assistant,
+		ResponseMeta: &schema.ResponseMeta{FinishReason: finishReason, Usage: usage},
+	})
+	return nil
+}
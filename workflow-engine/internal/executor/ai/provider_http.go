@@ -0,0 +1,334 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// 原生供应商标识（AIConfig.Provider）
+const (
+	providerAnthropic = "anthropic"
+	providerGemini    = "gemini"
+	providerOllama    = "ollama"
+)
+
+// maxStreamLineSize 流式响应单行最大长度（工具参数、Base64 图片等可能很长）
+const maxStreamLineSize = 16 * 1024 * 1024
+
+// nativeChatModel 原生供应商 ChatModel 的公共部分：配置、绑定的工具与 HTTP 客户端
+type nativeChatModel struct {
+	config *AIConfig
+	tools  []*schema.ToolInfo
+	client *http.Client
+}
+
+func newNativeChatModel(config *AIConfig) nativeChatModel {
+	// 流式响应可能持续很久，超时交给调用方的 context 控制
+	return nativeChatModel{config: config, client: &http.Client{}}
+}
+
+// options 合并节点配置与调用时传入的选项
+func (m *nativeChatModel) options(opts []einomodel.Option) *einomodel.Options {
+	model := m.config.Model
+	return einomodel.GetCommonOptions(&einomodel.Options{
+		Temperature: m.config.Temperature,
+		MaxTokens:   m.config.MaxTokens,
+		TopP:        m.config.TopP,
+		Model:       &model,
+		Tools:       m.tools,
+	}, opts...)
+}
+
+// endpoint 拼接请求地址：未配置 base_url 时使用供应商默认地址；
+// base_url 按 OpenAI 兼容习惯以 /v1 结尾时先去掉，避免与原生路径重复
+func (m *nativeChatModel) endpoint(defaultBase, path string) string {
+	base := m.config.BaseURL
+	if base == "" {
+		base = defaultBase
+	}
+	base = strings.TrimRight(base, "/")
+	if strings.HasPrefix(path, "/v1/") || strings.HasPrefix(path, "/api/") {
+		base = strings.TrimSuffix(base, "/v1")
+	}
+	return base + path
+}
+
+// post 发送 JSON 请求，非 2xx 响应转换为带状态码的错误（便于 Fallback 按错误类型降级）
+func (m *nativeChatModel) post(ctx context.Context, provider, url string, headers map[string]string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s 请求序列化失败: %w", provider, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s 请求失败: %w", provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s 请求失败 (HTTP %d): %s", provider, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// streamResponse 在后台解析响应体并通过 StreamReader 逐块输出消息，读取方关闭流时停止解析
+func streamResponse(resp *http.Response, parse func(body io.Reader, emit func(*schema.Message) bool) error) *schema.StreamReader[*schema.Message] {
+	sr, sw := schema.Pipe[*schema.Message](16)
+	go func() {
+		defer resp.Body.Close()
+		defer sw.Close()
+		err := parse(resp.Body, func(msg *schema.Message) bool {
+			return !sw.Send(msg, nil)
+		})
+		if err != nil {
+			sw.Send(nil, err)
+		}
+	}()
+	return sr
+}
+
+// generateFromStream 读取完整的流并合并为一条消息，非流式调用复用流式协议解析
+func generateFromStream(sr *schema.StreamReader[*schema.Message], err error) (*schema.Message, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer sr.Close()
+
+	var chunks []*schema.Message
+	var usage *schema.TokenUsage
+	for {
+		chunk, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk.ResponseMeta != nil && chunk.ResponseMeta.Usage != nil {
+			usage = chunk.ResponseMeta.Usage
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) == 0 {
+		return &schema.Message{Role: schema.Assistant}, nil
+	}
+	msg, err := schema.ConcatMessages(chunks)
+	if err != nil {
+		return nil, err
+	}
+	// 用量只在最后一块完整输出，直接采用以保留 ConcatMessages 不合并的明细（如推理 Token）
+	if usage != nil {
+		msg.ResponseMeta.Usage = usage
+	}
+	return msg, nil
+}
+
+// readSSE 逐个读取 Server-Sent Events，回调返回 false 时停止
+func readSSE(body io.Reader, handle func(event, data string) (bool, error)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLineSize)
+
+	var event string
+	var data strings.Builder
+	dispatch := func() (bool, error) {
+		if data.Len() == 0 {
+			event = ""
+			return true, nil
+		}
+		ok, err := handle(event, data.String())
+		event = ""
+		data.Reset()
+		return ok, err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if ok, err := dispatch(); err != nil || !ok {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// 注释行（心跳）
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	_, err := dispatch()
+	return err
+}
+
+// readNDJSON 逐行读取 JSON 流，回调返回 false 时停止
+func readNDJSON(body io.Reader, handle func(line []byte) (bool, error)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		ok, err := handle(line)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// toolParameters 将工具参数定义转换为 JSON Schema 对象
+func toolParameters(tool *schema.ToolInfo) (map[string]any, error) {
+	params := map[string]any{"type": "object", "properties": map[string]any{}}
+	if tool.ParamsOneOf == nil {
+		return params, nil
+	}
+	js, err := tool.ParamsOneOf.ToJSONSchema()
+	if err != nil {
+		return nil, fmt.Errorf("工具 %s 参数定义转换失败: %w", tool.Name, err)
+	}
+	if js == nil {
+		return params, nil
+	}
+	data, err := json.Marshal(js)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, err
+	}
+	if _, ok := params["properties"]; !ok {
+		params["properties"] = map[string]any{}
+	}
+	return params, nil
+}
+
+// parseToolArguments 将工具调用参数（JSON 字符串）解析为对象，空参数返回空对象
+func parseToolArguments(arguments string) map[string]any {
+	args := map[string]any{}
+	if strings.TrimSpace(arguments) == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return map[string]any{}
+	}
+	return args
+}
+
+// toolCallNames 收集历史消息中工具调用 ID 与工具名的对应关系（部分协议的工具结果需要回填工具名）
+func toolCallNames(messages []*schema.Message) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, tc := range msg.ToolCalls {
+			names[tc.ID] = tc.Function.Name
+		}
+	}
+	return names
+}
+
+// toolResultName 获取工具结果消息对应的工具名
+func toolResultName(msg *schema.Message, names map[string]string) string {
+	if msg.ToolName != "" {
+		return msg.ToolName
+	}
+	return names[msg.ToolCallID]
+}
+
+// mediaPart 多模态输入的数据：内联数据（Base64）或远程 URL 二选一
+type mediaPart struct {
+	MIMEType string
+	Data     string
+	URL      string
+}
+
+// inputMedia 解析多模态输入：data URL 与 Base64Data 解析为内联数据，其余视为远程 URL
+func inputMedia(common schema.MessagePartCommon) (*mediaPart, bool) {
+	media := &mediaPart{MIMEType: common.MIMEType}
+	if common.Base64Data != nil && *common.Base64Data != "" {
+		media.Data = *common.Base64Data
+		return media, true
+	}
+	if common.URL == nil || *common.URL == "" {
+		return nil, false
+	}
+
+	url := *common.URL
+	if strings.HasPrefix(url, "data:") {
+		header, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, false
+		}
+		media.MIMEType = strings.TrimSuffix(header, ";base64")
+		media.Data = data
+		return media, true
+	}
+	media.URL = url
+	return media, true
+}
+
+// messageParts 遍历用户消息的多模态内容，返回媒体类型与数据
+func messageParts(msg *schema.Message, handle func(partType schema.ChatMessagePartType, text string, media *mediaPart)) {
+	if len(msg.UserInputMultiContent) == 0 {
+		if msg.Content != "" {
+			handle(schema.ChatMessagePartTypeText, msg.Content, nil)
+		}
+		return
+	}
+	for _, part := range msg.UserInputMultiContent {
+		var common *schema.MessagePartCommon
+		switch part.Type {
+		case schema.ChatMessagePartTypeText:
+			if part.Text != "" {
+				handle(part.Type, part.Text, nil)
+			}
+			continue
+		case schema.ChatMessagePartTypeImageURL:
+			if part.Image != nil {
+				common = &part.Image.MessagePartCommon
+			}
+		case schema.ChatMessagePartTypeAudioURL:
+			if part.Audio != nil {
+				common = &part.Audio.MessagePartCommon
+			}
+		case schema.ChatMessagePartTypeVideoURL:
+			if part.Video != nil {
+				common = &part.Video.MessagePartCommon
+			}
+		case schema.ChatMessagePartTypeFileURL:
+			if part.File != nil {
+				common = &part.File.MessagePartCommon
+			}
+		}
+		if common == nil {
+			continue
+		}
+		if media, ok := inputMedia(*common); ok {
+			handle(part.Type, "", media)
+		}
+	}
+}
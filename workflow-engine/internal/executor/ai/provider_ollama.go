@@ -0,0 +1,267 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	ollamaDefaultBaseURL = "http://127.0.0.1:11434"
+
+	// ollamaMaxImageSize 远程图片下载大小上限（Ollama 只接受内联 Base64 图片）
+	ollamaMaxImageSize = 20 * 1024 * 1024
+)
+
+// ollamaChatModel Ollama /api/chat 原生实现
+type ollamaChatModel struct {
+	nativeChatModel
+}
+
+func newOllamaChatModel(config *AIConfig) *ollamaChatModel {
+	return &ollamaChatModel{nativeChatModel: newNativeChatModel(config)}
+}
+
+type ollamaRequest struct {
+	Model    string           `json:"model"`
+	Messages []*ollamaMessage `json:"messages"`
+	Tools    []*ollamaTool    `json:"tools,omitempty"`
+	Stream   bool             `json:"stream"`
+	Think    *bool            `json:"think,omitempty"`
+	Format   map[string]any   `json:"format,omitempty"`
+	Options  map[string]any   `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+type ollamaResponse struct {
+	Message         *ollamaMessage `json:"message"`
+	Done            bool           `json:"done"`
+	DoneReason      string         `json:"done_reason"`
+	PromptEvalCount int            `json:"prompt_eval_count"`
+	EvalCount       int            `json:"eval_count"`
+	Error           string         `json:"error"`
+}
+
+func (m *ollamaChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	return generateFromStream(m.Stream(ctx, input, opts...))
+}
+
+func (m *ollamaChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	req, err := m.buildRequest(ctx, input, m.options(opts))
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{}
+	if m.config.APIKey != "" {
+		// 本地服务无需鉴权，经反向代理暴露时使用 Bearer Token
+		headers["Authorization"] = "Bearer " + m.config.APIKey
+	}
+	resp, err := m.post(ctx, "Ollama", m.endpoint(ollamaDefaultBaseURL, "/api/chat"), headers, req)
+	if err != nil {
+		return nil, err
+	}
+	return streamResponse(resp, parseOllamaStream), nil
+}
+
+func (m *ollamaChatModel) WithTools(tools []*schema.ToolInfo) (einomodel.ToolCallingChatModel, error) {
+	clone := *m
+	clone.tools = tools
+	return &clone, nil
+}
+
+func (m *ollamaChatModel) buildRequest(ctx context.Context, input []*schema.Message, options *einomodel.Options) (*ollamaRequest, error) {
+	req := &ollamaRequest{
+		Model:   *options.Model,
+		Stream:  true,
+		Options: map[string]any{},
+	}
+	if options.Temperature != nil {
+		req.Options["temperature"] = *options.Temperature
+	}
+	if options.TopP != nil {
+		req.Options["top_p"] = *options.TopP
+	}
+	if options.MaxTokens != nil {
+		req.Options["num_predict"] = *options.MaxTokens
+	}
+	if m.config.PresencePenalty != nil {
+		req.Options["presence_penalty"] = *m.config.PresencePenalty
+	}
+	if len(options.Stop) > 0 {
+		req.Options["stop"] = options.Stop
+	}
+	if m.config.ThinkingBudget > 0 {
+		think := true
+		req.Think = &think
+	}
+
+	for _, tool := range options.Tools {
+		params, err := toolParameters(tool)
+		if err != nil {
+			return nil, err
+		}
+		t := &ollamaTool{Type: "function"}
+		t.Function.Name = tool.Name
+		t.Function.Description = tool.Desc
+		t.Function.Parameters = params
+		req.Tools = append(req.Tools, t)
+	}
+	if len(req.Tools) == 0 && m.config.OutputSchema != nil {
+		// 约束解码会抑制工具调用，仅在无工具的请求上开启
+		req.Format = m.config.OutputSchema
+	}
+
+	names := toolCallNames(input)
+	for _, msg := range input {
+		out := &ollamaMessage{Role: string(msg.Role), Content: msg.Content}
+		switch msg.Role {
+		case schema.User:
+			if err := m.fillUserContent(ctx, msg, out); err != nil {
+				return nil, err
+			}
+		case schema.Assistant:
+			for _, tc := range msg.ToolCalls {
+				var call ollamaToolCall
+				call.Function.Name = tc.Function.Name
+				call.Function.Arguments = parseToolArguments(tc.Function.Arguments)
+				out.ToolCalls = append(out.ToolCalls, call)
+			}
+		case schema.Tool:
+			out.ToolName = toolResultName(msg, names)
+		}
+		req.Messages = append(req.Messages, out)
+	}
+	return req, nil
+}
+
+// fillUserContent 多模态消息：文本拼接为 content，图片转为 Base64 放入 images，其余媒体以链接形式附在文本后
+func (m *ollamaChatModel) fillUserContent(ctx context.Context, msg *schema.Message, out *ollamaMessage) error {
+	if len(msg.UserInputMultiContent) == 0 {
+		return nil
+	}
+	out.Content = ""
+	var firstErr error
+	messageParts(msg, func(partType schema.ChatMessagePartType, text string, media *mediaPart) {
+		switch {
+		case media == nil:
+			out.Content = joinText(out.Content, text)
+		case partType != schema.ChatMessagePartTypeImageURL:
+			if media.URL != "" {
+				out.Content = joinText(out.Content, media.URL)
+			}
+		case media.Data != "":
+			out.Images = append(out.Images, media.Data)
+		default:
+			data, err := m.downloadImage(ctx, media.URL)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			out.Images = append(out.Images, data)
+		}
+	})
+	return firstErr
+}
+
+func (m *ollamaChatModel) downloadImage(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("Ollama 图片地址无效: %w", err)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Ollama 下载图片失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Ollama 下载图片失败 (HTTP %d): %s", resp.StatusCode, url)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, ollamaMaxImageSize))
+	if err != nil {
+		return "", fmt.Errorf("Ollama 下载图片失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func joinText(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "\n" + b
+}
+
+// parseOllamaStream 解析 NDJSON 响应：工具调用参数为 JSON 对象，转换为字符串；
+// Ollama 不返回调用 ID，按序生成；用量在 done 块中输出
+func parseOllamaStream(body io.Reader, emit func(*schema.Message) bool) error {
+	callSeq := 0
+	return readNDJSON(body, func(line []byte) (bool, error) {
+		var resp ollamaResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			return false, fmt.Errorf("Ollama 流式响应解析失败: %w", err)
+		}
+		if resp.Error != "" {
+			return false, fmt.Errorf("Ollama 流式响应错误: %s", resp.Error)
+		}
+
+		msg := &schema.Message{Role: schema.Assistant}
+		if resp.Message != nil {
+			msg.Content = resp.Message.Content
+			msg.ReasoningContent = resp.Message.Thinking
+			for _, call := range resp.Message.ToolCalls {
+				callSeq++
+				args, _ := json.Marshal(call.Function.Arguments)
+				if call.Function.Arguments == nil {
+					args = []byte("{}")
+				}
+				msg.ToolCalls = append(msg.ToolCalls, schema.ToolCall{
+					ID:       fmt.Sprintf("call_%d", callSeq),
+					Type:     "function",
+					Function: schema.FunctionCall{Name: call.Function.Name, Arguments: string(args)},
+				})
+			}
+		}
+		if resp.Done {
+			msg.ResponseMeta = &schema.ResponseMeta{
+				FinishReason: resp.DoneReason,
+				Usage: &schema.TokenUsage{
+					PromptTokens:     resp.PromptEvalCount,
+					CompletionTokens: resp.EvalCount,
+					TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+				},
+			}
+		}
+		if msg.Content == "" && msg.ReasoningContent == "" && len(msg.ToolCalls) == 0 && msg.ResponseMeta == nil {
+			return true, nil
+		}
+		return emit(msg) && !resp.Done, nil
+	})
+}
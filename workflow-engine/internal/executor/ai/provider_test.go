@@ -0,0 +1,389 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// providerStub 本地 HTTP 替身：记录请求体并按顺序返回预设响应
+type providerStub struct {
+	t         *testing.T
+	requests  []map[string]any
+	headers   []http.Header
+	paths     []string
+	responses []string
+}
+
+func newProviderStub(t *testing.T, responses ...string) (*providerStub, *httptest.Server) {
+	stub := &providerStub{t: t, responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]any
+		require.NoError(t, json.Unmarshal(body, &req))
+		stub.requests = append(stub.requests, req)
+		stub.headers = append(stub.headers, r.Header.Clone())
+		stub.paths = append(stub.paths, r.URL.RequestURI())
+
+		n := len(stub.requests) - 1
+		if n >= len(stub.responses) {
+			http.Error(w, "unexpected request", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(stub.responses[n]))
+	}))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func sseEvents(events ...string) string {
+	var sb strings.Builder
+	for _, e := range events {
+		var probe struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal([]byte(e), &probe)
+		if probe.Type != "" {
+			fmt.Fprintf(&sb, "event: %s\n", probe.Type)
+		}
+		fmt.Fprintf(&sb, "data: %s\n\n", e)
+	}
+	return sb.String()
+}
+
+func weatherTool() *schema.ToolInfo {
+	return &schema.ToolInfo{
+		Name: "get_weather",
+		Desc: "查询天气",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"city": {Type: schema.String, Desc: "城市", Required: true},
+		}),
+	}
+}
+
+func collectStream(t *testing.T, sr *schema.StreamReader[*schema.Message], err error) []*schema.Message {
+	require.NoError(t, err)
+	defer sr.Close()
+	var chunks []*schema.Message
+	for {
+		chunk, err := sr.Recv()
+		if err == io.EOF {
+			return chunks
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestCreateChatModelFromConfig_Provider(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     any
+	}{
+		{providerAnthropic, &anthropicChatModel{}},
+		{providerGemini, &geminiChatModel{}},
+		{providerOllama, &ollamaChatModel{}},
+	}
+	for _, tt := range tests {
+		m, err := createChatModelFromConfig(context.Background(), &AIConfig{Provider: tt.provider, Model: "m", APIKey: "k"})
+		require.NoError(t, err)
+		assert.IsType(t, tt.want, m)
+	}
+}
+
+func TestAIConfig_Validate_OllamaWithoutAPIKey(t *testing.T) {
+	cfg := &AIConfig{Provider: providerOllama, Model: "qwen3", Prompt: "hi"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Provider = providerAnthropic
+	assert.Error(t, cfg.Validate())
+}
+
+func TestAnthropicChatModel_StreamToolCall(t *testing.T) {
+	first := sseEvents(
+		`{"type":"message_start","message":{"usage":{"input_tokens":10,"cache_creation_input_tokens":5,"cache_read_input_tokens":20,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"需要查天气"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-1"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"我来"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"查询"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"\"北京\"}"}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+		`{"type":"message_stop"}`,
+	)
+	second := sseEvents(
+		`{"type":"message_start","message":{"usage":{"input_tokens":50,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"晴"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+		`{"type":"message_stop"}`,
+	)
+	stub, server := newProviderStub(t, first, second)
+
+	m := newAnthropicChatModel(&AIConfig{
+		Provider: providerAnthropic, Model: "claude-test", APIKey: "sk-test", BaseURL: server.URL,
+		ThinkingBudget: 1024, PromptCache: true,
+	})
+	input := []*schema.Message{schema.SystemMessage("你是助手"), schema.UserMessage("北京天气")}
+
+	resp, err := m.Generate(context.Background(), input, einomodel.WithTools([]*schema.ToolInfo{weatherTool()}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "/v1/messages", stub.paths[0])
+	assert.Equal(t, "sk-test", stub.headers[0].Get("x-api-key"))
+	assert.Equal(t, anthropicVersion, stub.headers[0].Get("anthropic-version"))
+
+	req := stub.requests[0]
+	assert.Equal(t, "claude-test", req["model"])
+	assert.Equal(t, true, req["stream"])
+	assert.Greater(t, req["max_tokens"].(float64), float64(1024))
+	assert.Equal(t, map[string]any{"type": "enabled", "budget_tokens": float64(1024)}, req["thinking"])
+	system := req["system"].([]any)[0].(map[string]any)
+	assert.Equal(t, "你是助手", system["text"])
+	assert.Equal(t, map[string]any{"type": "ephemeral"}, system["cache_control"])
+	tool := req["tools"].([]any)[0].(map[string]any)
+	assert.Equal(t, "get_weather", tool["name"])
+	assert.Equal(t, "object", tool["input_schema"].(map[string]any)["type"])
+
+	assert.Equal(t, "我来查询", resp.Content)
+	assert.Equal(t, "需要查天气", resp.ReasoningContent)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", resp.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", resp.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"北京"}`, resp.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "tool_use", resp.ResponseMeta.FinishReason)
+	assert.Equal(t, 35, resp.ResponseMeta.Usage.PromptTokens)
+	assert.Equal(t, 20, resp.ResponseMeta.Usage.PromptTokenDetails.CachedTokens)
+	assert.Equal(t, 30, resp.ResponseMeta.Usage.CompletionTokens)
+	assert.Equal(t, 65, resp.ResponseMeta.Usage.TotalTokens)
+
+	// 续轮：思考块带签名原样回传，工具结果以 user 角色的 tool_result 发送
+	resp.ReasoningContent = ""
+	input = append(input, resp, schema.ToolMessage(`{"weather":"晴"}`, "toolu_1"))
+	sr, err := m.Stream(context.Background(), input)
+	chunks := collectStream(t, sr, err)
+	require.NotEmpty(t, chunks)
+
+	messages := stub.requests[1]["messages"].([]any)
+	require.Len(t, messages, 3)
+	assistant := messages[1].(map[string]any)["content"].([]any)
+	assert.Equal(t, "thinking", assistant[0].(map[string]any)["type"])
+	assert.Equal(t, "需要查天气", assistant[0].(map[string]any)["thinking"])
+	assert.Equal(t, "sig-1", assistant[0].(map[string]any)["signature"])
+	assert.Equal(t, "tool_use", assistant[2].(map[string]any)["type"])
+	assert.Equal(t, map[string]any{"city": "北京"}, assistant[2].(map[string]any)["input"])
+	result := messages[2].(map[string]any)
+	assert.Equal(t, "user", result["role"])
+	block := result["content"].([]any)[0].(map[string]any)
+	assert.Equal(t, "tool_result", block["type"])
+	assert.Equal(t, "toolu_1", block["tool_use_id"])
+	assert.Equal(t, map[string]any{"type": "ephemeral"}, block["cache_control"])
+
+	final, err := schema.ConcatMessages(chunks)
+	require.NoError(t, err)
+	assert.Equal(t, "晴", final.Content)
+	assert.Equal(t, "end_turn", final.ResponseMeta.FinishReason)
+	assert.Equal(t, 50, final.ResponseMeta.Usage.PromptTokens)
+	assert.Equal(t, 2, final.ResponseMeta.Usage.CompletionTokens)
+}
+
+func TestAnthropicChatModel_StreamError(t *testing.T) {
+	_, server := newProviderStub(t, sseEvents(
+		`{"type":"message_start","message":{"usage":{"input_tokens":1}}}`,
+		`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+	))
+	m := newAnthropicChatModel(&AIConfig{Provider: providerAnthropic, Model: "claude-test", APIKey: "k", BaseURL: server.URL})
+
+	_, err := m.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Overloaded")
+}
+
+func TestNativeChatModel_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"rate limited"}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	m := newGeminiChatModel(&AIConfig{Provider: providerGemini, Model: "gemini-test", APIKey: "k", BaseURL: server.URL})
+	_, err := m.Stream(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 429")
+	assert.Equal(t, llmErrorRateLimit, classifyLLMError(err))
+}
+
+func TestGeminiChatModel_StreamToolCall(t *testing.T) {
+	first := sseEvents(
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"先想想","thought":true}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"好的"},{"functionCall":{"name":"get_weather","args":{"city":"上海"}},"thoughtSignature":"c2ln"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":8,"thoughtsTokenCount":4,"cachedContentTokenCount":6,"totalTokenCount":24}}`,
+	)
+	second := sseEvents(
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"多云"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":30,"candidatesTokenCount":2,"totalTokenCount":32}}`,
+	)
+	stub, server := newProviderStub(t, first, second)
+
+	m := newGeminiChatModel(&AIConfig{
+		Provider: providerGemini, Model: "gemini-test", APIKey: "g-key", BaseURL: server.URL,
+		ThinkingBudget: 512,
+		SafetySettings: []SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"}},
+	})
+	withTools, err := m.WithTools([]*schema.ToolInfo{weatherTool()})
+	require.NoError(t, err)
+
+	input := []*schema.Message{schema.SystemMessage("你是助手"), schema.UserMessage("上海天气")}
+	resp, err := withTools.Generate(context.Background(), input)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/models/gemini-test:streamGenerateContent?alt=sse", stub.paths[0])
+	assert.Equal(t, "g-key", stub.headers[0].Get("x-goog-api-key"))
+	req := stub.requests[0]
+	assert.Equal(t, "你是助手", req["systemInstruction"].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"])
+	assert.Equal(t, map[string]any{"includeThoughts": true, "thinkingBudget": float64(512)},
+		req["generationConfig"].(map[string]any)["thinkingConfig"])
+	assert.Len(t, req["safetySettings"], 1)
+	decl := req["tools"].([]any)[0].(map[string]any)["functionDeclarations"].([]any)[0].(map[string]any)
+	assert.Equal(t, "get_weather", decl["name"])
+
+	assert.Equal(t, "好的", resp.Content)
+	assert.Equal(t, "先想想", resp.ReasoningContent)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.NotEmpty(t, resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"city":"上海"}`, resp.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "STOP", resp.ResponseMeta.FinishReason)
+	usage := resp.ResponseMeta.Usage
+	assert.Equal(t, 12, usage.PromptTokens)
+	assert.Equal(t, 6, usage.PromptTokenDetails.CachedTokens)
+	assert.Equal(t, 12, usage.CompletionTokens)
+	assert.Equal(t, 4, usage.CompletionTokensDetails.ReasoningTokens)
+	assert.Equal(t, 24, usage.TotalTokens)
+
+	// 续轮：functionCall 携带思考签名，functionResponse 通过调用 ID 回填工具名
+	input = append(input, resp, schema.ToolMessage("多云", resp.ToolCalls[0].ID))
+	resp, err = withTools.Generate(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, "多云", resp.Content)
+
+	contents := stub.requests[1]["contents"].([]any)
+	require.Len(t, contents, 3)
+	modelParts := contents[1].(map[string]any)["parts"].([]any)
+	assert.Equal(t, "model", contents[1].(map[string]any)["role"])
+	assert.Equal(t, "c2ln", modelParts[1].(map[string]any)["thoughtSignature"])
+	fnResp := contents[2].(map[string]any)["parts"].([]any)[0].(map[string]any)["functionResponse"].(map[string]any)
+	assert.Equal(t, "get_weather", fnResp["name"])
+	assert.Equal(t, map[string]any{"result": "多云"}, fnResp["response"])
+}
+
+func TestGeminiChatModel_BlockedPrompt(t *testing.T) {
+	_, server := newProviderStub(t, sseEvents(`{"promptFeedback":{"blockReason":"SAFETY"}}`))
+	m := newGeminiChatModel(&AIConfig{Provider: providerGemini, Model: "gemini-test", APIKey: "k", BaseURL: server.URL})
+
+	_, err := m.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SAFETY")
+}
+
+func TestOllamaChatModel_StreamToolCall(t *testing.T) {
+	first := strings.Join([]string{
+		`{"message":{"role":"assistant","content":"","thinking":"查一下"},"done":false}`,
+		`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"广州"}}}]},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":15,"eval_count":7}`,
+	}, "\n")
+	second := strings.Join([]string{
+		`{"message":{"role":"assistant","content":"下"},"done":false}`,
+		`{"message":{"role":"assistant","content":"雨"},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":40,"eval_count":2}`,
+	}, "\n")
+	stub, server := newProviderStub(t, first, second)
+
+	temperature := float32(0.2)
+	m := newOllamaChatModel(&AIConfig{
+		Provider: providerOllama, Model: "qwen3", BaseURL: server.URL,
+		Temperature: &temperature, ThinkingBudget: 1,
+	})
+	withTools, err := m.WithTools([]*schema.ToolInfo{weatherTool()})
+	require.NoError(t, err)
+
+	input := []*schema.Message{schema.UserMessage("广州天气")}
+	resp, err := withTools.Generate(context.Background(), input)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/api/chat", stub.paths[0])
+	assert.Empty(t, stub.headers[0].Get("Authorization"))
+	req := stub.requests[0]
+	assert.Equal(t, "qwen3", req["model"])
+	assert.Equal(t, true, req["think"])
+	assert.InDelta(t, 0.2, req["options"].(map[string]any)["temperature"], 1e-6)
+
+	assert.Equal(t, "查一下", resp.ReasoningContent)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.JSONEq(t, `{"city":"广州"}`, resp.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "stop", resp.ResponseMeta.FinishReason)
+	assert.Equal(t, 15, resp.ResponseMeta.Usage.PromptTokens)
+	assert.Equal(t, 7, resp.ResponseMeta.Usage.CompletionTokens)
+	assert.Equal(t, 22, resp.ResponseMeta.Usage.TotalTokens)
+
+	// 续轮：工具调用参数以对象形式回传，工具结果带上工具名
+	input = append(input, resp, schema.ToolMessage("下雨", resp.ToolCalls[0].ID))
+	sr, err := withTools.Stream(context.Background(), input)
+	chunks := collectStream(t, sr, err)
+	final, err := schema.ConcatMessages(chunks)
+	require.NoError(t, err)
+	assert.Equal(t, "下雨", final.Content)
+	assert.Equal(t, 42, final.ResponseMeta.Usage.TotalTokens)
+
+	messages := stub.requests[1]["messages"].([]any)
+	require.Len(t, messages, 3)
+	call := messages[1].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)["function"].(map[string]any)
+	assert.Equal(t, map[string]any{"city": "广州"}, call["arguments"])
+	assert.Equal(t, "get_weather", messages[2].(map[string]any)["tool_name"])
+}
+
+func TestOllamaChatModel_InlineImage(t *testing.T) {
+	stub, server := newProviderStub(t, `{"message":{"role":"assistant","content":"猫"},"done":true,"prompt_eval_count":1,"eval_count":1}`)
+	m := newOllamaChatModel(&AIConfig{Provider: providerOllama, Model: "llava", BaseURL: server.URL})
+
+	url := "data:image/png;base64,aW1n"
+	msg := &schema.Message{Role: schema.User, UserInputMultiContent: []schema.MessageInputPart{
+		{Type: schema.ChatMessagePartTypeText, Text: "这是什么"},
+		{Type: schema.ChatMessagePartTypeImageURL, Image: &schema.MessageInputImage{MessagePartCommon: schema.MessagePartCommon{URL: &url}}},
+	}}
+	resp, err := m.Generate(context.Background(), []*schema.Message{msg})
+	require.NoError(t, err)
+	assert.Equal(t, "猫", resp.Content)
+
+	sent := stub.requests[0]["messages"].([]any)[0].(map[string]any)
+	assert.Equal(t, "这是什么", sent["content"])
+	assert.Equal(t, []any{"aW1n"}, sent["images"])
+}
+
+func TestNativeChatModel_Endpoint(t *testing.T) {
+	tests := []struct {
+		baseURL, defaultBase, path, want string
+	}{
+		{"", anthropicDefaultBaseURL, "/v1/messages", "https://api.anthropic.com/v1/messages"},
+		{"https://proxy.example.com/v1/", anthropicDefaultBaseURL, "/v1/messages", "https://proxy.example.com/v1/messages"},
+		{"http://localhost:11434/v1", ollamaDefaultBaseURL, "/api/chat", "http://localhost:11434/api/chat"},
+		{"", geminiDefaultBaseURL, "/models/g:streamGenerateContent", geminiDefaultBaseURL + "/models/g:streamGenerateContent"},
+	}
+	for _, tt := range tests {
+		m := newNativeChatModel(&AIConfig{BaseURL: tt.baseURL})
+		assert.Equal(t, tt.want, m.endpoint(tt.defaultBase, tt.path))
+	}
+}
@@ -1,7 +1,31 @@
 package ai
 
-import "yqhp/workflow-engine/internal/executor"
+import (
+	"context"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+
+	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/pkg/evaluator"
+)
 
 func init() {
 	executor.MustRegister(NewAgentExecutor())
+	evaluator.RegisterChatModelFactory(newJudgeChatModel)
+}
+
+// newJudgeChatModel 评审模型复用 AI 节点的供应商适配，温度固定为 0 以保证评分稳定
+func newJudgeChatModel(ctx context.Context, config evaluator.ModelConfig) (einomodel.BaseChatModel, error) {
+	provider := config.Provider
+	if provider == "" {
+		provider = "openai"
+	}
+	var temperature float32
+	return createChatModelFromConfig(ctx, &AIConfig{
+		Provider:    provider,
+		Model:       config.Model,
+		APIKey:      config.APIKey,
+		BaseURL:     config.BaseURL,
+		Temperature: &temperature,
+	})
 }
@@ -35,6 +35,19 @@ func WithAICallback(ctx context.Context, cb types.AIStreamCallback) context.Cont
 
 // createChatModelFromConfig 根据 AIConfig 创建 Eino ChatModel（包级函数，供各执行器复用）
 func createChatModelFromConfig(ctx context.Context, config *AIConfig) (einomodel.ToolCallingChatModel, error) {
+	// 原生协议供应商，其余按 OpenAI 兼容协议接入
+	switch config.Provider {
+	case providerAnthropic:
+		logger.Debug("[ModelCreate] 创建 Anthropic 模型, model=%s, baseURL=%s", config.Model, config.BaseURL)
+		return newAnthropicChatModel(config), nil
+	case providerGemini:
+		logger.Debug("[ModelCreate] 创建 Gemini 模型, model=%s, baseURL=%s", config.Model, config.BaseURL)
+		return newGeminiChatModel(config), nil
+	case providerOllama:
+		logger.Debug("[ModelCreate] 创建 Ollama 模型, model=%s, baseURL=%s", config.Model, config.BaseURL)
+		return newOllamaChatModel(config), nil
+	}
+
 	chatConfig := &openai.ChatModelConfig{
 		Model:  config.Model,
 		APIKey: config.APIKey,
@@ -72,6 +85,11 @@ func createChatModelFromConfig(ctx context.Context, config *AIConfig) (einomodel
 	if config.PresencePenalty != nil {
 		chatConfig.PresencePenalty = config.PresencePenalty
 	}
+	responseFormat, err := openAIResponseFormat(config)
+	if err != nil {
+		return nil, err
+	}
+	chatConfig.ResponseFormat = responseFormat
 
 	logger.Debug("[ModelCreate] 创建模型, provider=%s, model=%s, baseURL=%s, streaming=%v",
 		config.Provider, config.Model, baseURL, config.Streaming)
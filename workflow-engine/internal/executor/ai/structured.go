@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+
+	"yqhp/workflow-engine/internal/executor"
+	pkgExecutor "yqhp/workflow-engine/pkg/executor"
+	"yqhp/workflow-engine/pkg/logger"
+)
+
+const (
+	defaultOutputRepairRounds = 2
+	maxOutputRepairRounds     = 5
+)
+
+// validateOutputSchema 校验 output_schema 配置
+func (c *AIConfig) validateOutputSchema() error {
+	if c.OutputRepairRounds < 0 || c.OutputRepairRounds > maxOutputRepairRounds {
+		return executor.NewConfigError(fmt.Sprintf("output_repair_rounds 应在 0~%d 之间，当前值: %d", maxOutputRepairRounds, c.OutputRepairRounds), nil)
+	}
+	if c.OutputSchema == nil {
+		return nil
+	}
+	if _, ok := c.OutputSchema["type"]; !ok {
+		return executor.NewConfigError("output_schema 需要声明顶层 'type'", nil)
+	}
+	return nil
+}
+
+// outputRepairRounds 校验失败后的修复轮数，未配置时使用默认值
+func (c *AIConfig) outputRepairRounds() int {
+	if c.OutputRepairRounds > 0 {
+		return c.OutputRepairRounds
+	}
+	return defaultOutputRepairRounds
+}
+
+// openAIResponseFormat OpenAI 兼容协议的结构化输出：官方接口使用 json_schema，其余兼容服务多数只支持 json_object
+func openAIResponseFormat(config *AIConfig) (*openai.ChatCompletionResponseFormat, error) {
+	if config.OutputSchema == nil {
+		return nil, nil
+	}
+	if config.Provider != "openai" && config.Provider != "azure" {
+		return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}, nil
+	}
+
+	data, err := json.Marshal(config.OutputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("output_schema 序列化失败: %w", err)
+	}
+	js := &jsonschema.Schema{}
+	if err := json.Unmarshal(data, js); err != nil {
+		return nil, fmt.Errorf("output_schema 不是合法的 JSON Schema: %w", err)
+	}
+	return &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:       "output",
+			JSONSchema: js,
+		},
+	}, nil
+}
+
+// buildOutputSchemaSection 结构化输出的提示词约束，不支持原生结构化输出的供应商依赖此说明
+func (pb *PromptBuilder) buildOutputSchemaSection() string {
+	data, _ := json.MarshalIndent(pb.config.OutputSchema, "", "  ")
+	return "\n[输出格式]\n最终回答必须是一个符合以下 JSON Schema 的 JSON 值，不要包含 Markdown 代码块或任何额外文字：\n" + string(data)
+}
+
+// parseStructuredOutput 从模型回复中提取 JSON 并按 Schema 校验，返回解析结果和校验错误
+func parseStructuredOutput(content string, outputSchema map[string]any) (any, []string) {
+	text := extractJSONText(content)
+	if text == "" {
+		return nil, []string{"回复中没有找到 JSON"}
+	}
+	var data any
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		return nil, []string{fmt.Sprintf("JSON 解析失败: %v", err)}
+	}
+	if errs := pkgExecutor.ValidateJSONSchema(outputSchema, data); len(errs) > 0 {
+		return data, errs
+	}
+	return data, nil
+}
+
+// extractJSONText 去掉 Markdown 代码块和前后说明文字，截取最外层的 JSON 对象或数组
+func extractJSONText(content string) string {
+	text := strings.TrimSpace(content)
+	if start := strings.Index(text, "```"); start >= 0 {
+		body := text[start+3:]
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+			body = body[nl+1:]
+		}
+		if end := strings.Index(body, "```"); end >= 0 {
+			text = strings.TrimSpace(body[:end])
+		}
+	}
+	if json.Valid([]byte(text)) {
+		return text
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return ""
+	}
+	closer := byte('}')
+	if text[start] == '[' {
+		closer = ']'
+	}
+	end := strings.LastIndexByte(text, closer)
+	if end <= start {
+		return ""
+	}
+	return text[start : end+1]
+}
+
+// buildRepairPrompt 把校验错误反馈给模型，要求重新输出
+func buildRepairPrompt(errs []string) string {
+	var sb strings.Builder
+	sb.WriteString("[输出校验失败] 你的回答不符合要求的 JSON Schema：\n")
+	for _, e := range errs {
+		sb.WriteString("- " + e + "\n")
+	}
+	sb.WriteString("\n请修正后只输出完整的 JSON，不要包含任何其他内容。")
+	return sb.String()
+}
+
+// enforceOutputSchema 校验最终回复，不符合 Schema 时携带错误信息让模型修复，成功后写入 output.Data
+func enforceOutputSchema(ctx context.Context, req *AgentRequest, messages []*schema.Message, resp *schema.Message, output *AIOutput) error {
+	outputSchema := req.Config.OutputSchema
+	if outputSchema == nil {
+		return nil
+	}
+
+	rounds := req.Config.outputRepairRounds()
+	for attempt := 0; ; attempt++ {
+		data, errs := parseStructuredOutput(output.Content, outputSchema)
+		if len(errs) == 0 {
+			output.Data = data
+			output.RepairRounds = attempt
+			return nil
+		}
+		if attempt >= rounds {
+			return fmt.Errorf("输出不符合 output_schema（已修复 %d 轮）: %s", attempt, strings.Join(errs, "; "))
+		}
+
+		logger.Debug("[Agent] 结构化输出校验失败, 第 %d/%d 轮修复, stepID=%s: %v", attempt+1, rounds, req.StepID, errs)
+		messages = append(messages, resp, schema.UserMessage(buildRepairPrompt(errs)))
+		if err := req.Meter.check(ctx, output, messages); err != nil {
+			return err
+		}
+		next, err := callLLM(ctx, req.ChatModel, messages, nil, req.Config, req.StepID, nil)
+		if err != nil {
+			req.Meter.release(ctx)
+			return fmt.Errorf("结构化输出修复失败: %w", err)
+		}
+		req.Meter.record(ctx, output, next)
+		output.Content = next.Content
+		resp = next
+	}
+}
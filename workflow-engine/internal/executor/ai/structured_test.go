@@ -0,0 +1,126 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+func TestExtractJSONText(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"纯 JSON", `{"a":1}`, `{"a":1}`},
+		{"代码块", "结果如下：\n```json\n{\"a\":1}\n```\n以上", `{"a":1}`},
+		{"前后说明", `答案是 {"a":{"b":2}} 。`, `{"a":{"b":2}}`},
+		{"数组", `列表: [1,2,3]`, `[1,2,3]`},
+		{"无 JSON", `没有结构化内容`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, extractJSONText(tc.content))
+		})
+	}
+}
+
+func TestAIConfig_ValidateOutputSchema(t *testing.T) {
+	base := func() map[string]any {
+		return map[string]any{"model": "m", "api_key": "k", "prompt": "hi"}
+	}
+
+	cfg := base()
+	cfg["output_schema"] = map[string]any{"properties": map[string]any{}}
+	_, err := parseAIConfig(cfg)
+	require.Error(t, err)
+
+	cfg = base()
+	cfg["output_repair_rounds"] = 10
+	_, err = parseAIConfig(cfg)
+	require.Error(t, err)
+
+	cfg = base()
+	cfg["output_schema"] = map[string]any{"type": "object"}
+	parsed, err := parseAIConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, defaultOutputRepairRounds, parsed.outputRepairRounds())
+
+	format, err := openAIResponseFormat(parsed)
+	require.NoError(t, err)
+	require.NotNil(t, format.JSONSchema)
+	assert.Equal(t, "output", format.JSONSchema.Name)
+}
+
+// TestAgentExecutor_OutputSchemaRepair 首次回复缺少字段，携带校验错误修复一轮后得到结构化输出
+func TestAgentExecutor_OutputSchemaRepair(t *testing.T) {
+	stub, server := newProviderStub(t,
+		`{"message":{"role":"assistant","content":"评估完成：\n`+"```json"+`\n{\"verdict\":\"pass\"}\n`+"```"+`"},"done":true,"prompt_eval_count":10,"eval_count":5}`+"\n",
+		`{"message":{"role":"assistant","content":"{\"verdict\":\"pass\",\"score\":0.9}"},"done":true,"prompt_eval_count":20,"eval_count":6}`+"\n",
+	)
+
+	step := &types.Step{ID: "judge", Type: AgentType, Config: map[string]any{
+		"provider": providerOllama,
+		"model":    "qwen3",
+		"base_url": server.URL,
+		"prompt":   "评估这次响应",
+		"output_schema": map[string]any{
+			"type":     "object",
+			"required": []any{"verdict", "score"},
+			"properties": map[string]any{
+				"verdict": map[string]any{"type": "string", "enum": []any{"pass", "fail"}},
+				"score":   map[string]any{"type": "number"},
+			},
+		},
+	}, PostProcessors: []types.Processor{{
+		Type:    "extract_param",
+		Enabled: true,
+		Config:  map[string]any{"extractType": "jsonpath", "expression": "$.score", "variableName": "score"},
+	}}}
+	execCtx := executor.NewExecutionContext()
+
+	result, err := NewAgentExecutor().Execute(context.Background(), step, execCtx)
+	require.NoError(t, err)
+	require.Equal(t, types.ResultStatusSuccess, result.Status, "%v", result.Error)
+
+	output := result.Output.(*AIOutput)
+	assert.Equal(t, map[string]any{"verdict": "pass", "score": 0.9}, output.Data)
+	assert.Equal(t, 1, output.RepairRounds)
+	assert.Equal(t, 41, output.TotalTokens)
+
+	score, ok := execCtx.GetVariable("score")
+	require.True(t, ok)
+	assert.Equal(t, 0.9, score)
+
+	require.Len(t, stub.requests, 2)
+	assert.Nil(t, stub.requests[0]["format"], "带工具的请求不开启约束解码")
+	assert.NotNil(t, stub.requests[1]["format"])
+	messages := stub.requests[1]["messages"].([]any)
+	repair := messages[len(messages)-1].(map[string]any)
+	assert.Contains(t, repair["content"], "score")
+	assert.Contains(t, repair["content"], "[输出校验失败]")
+}
+
+func TestAgentExecutor_OutputSchemaExhausted(t *testing.T) {
+	invalid := `{"message":{"role":"assistant","content":"无法给出"},"done":true}` + "\n"
+	_, server := newProviderStub(t, invalid, invalid)
+
+	step := &types.Step{ID: "judge", Type: AgentType, Config: map[string]any{
+		"provider":             providerOllama,
+		"model":                "qwen3",
+		"base_url":             server.URL,
+		"prompt":               "评估这次响应",
+		"output_schema":        map[string]any{"type": "object"},
+		"output_repair_rounds": 1,
+	}}
+
+	result, err := NewAgentExecutor().Execute(context.Background(), step, executor.NewExecutionContext())
+	require.NoError(t, err)
+	assert.Equal(t, types.ResultStatusFailed, result.Status)
+	assert.Contains(t, result.Error.Error(), "output_schema")
+}
@@ -33,8 +33,13 @@ func (t *KnowledgeTool) Definition() *types.ToolDefinition {
 		if kb.Type == "graph" {
 			typeLabel = "图谱检索"
 		}
-		sb.WriteString(fmt.Sprintf("- %s（%s）\n", kb.Name, typeLabel))
+		if len(kb.MetadataKeys) > 0 {
+			sb.WriteString(fmt.Sprintf("- %s（%s，可过滤字段：%s）\n", kb.Name, typeLabel, strings.Join(kb.MetadataKeys, ", ")))
+		} else {
+			sb.WriteString(fmt.Sprintf("- %s（%s）\n", kb.Name, typeLabel))
+		}
 	}
+	sb.WriteString("\n可通过 filter 按文档元数据缩小检索范围，仅在用户明确限定范围（如版本、目录、标签）时使用。")
 	return &types.ToolDefinition{
 		Name:        "knowledge_search",
 		Description: sb.String(),
@@ -48,6 +53,15 @@ func (t *KnowledgeTool) Definition() *types.ToolDefinition {
 				"top_k": {
 					"type": "integer",
 					"description": "返回结果数量，默认为 5"
+				},
+				"filter": {
+					"type": "object",
+					"description": "可选的元数据过滤。must 中的条件全部满足、should 中至少满足一个、must_not 中的条件都不满足。条件格式：{\"key\":\"version\",\"op\":\"eq\",\"value\":\"2.0\"}；op 可选 eq（等于）、in（values 中任一）、range（gt/gte/lt/lte 数值范围）、exists（存在该字段）",
+					"properties": {
+						"must": {"type": "array", "items": {"type": "object"}},
+						"should": {"type": "array", "items": {"type": "object"}},
+						"must_not": {"type": "array", "items": {"type": "object"}}
+					}
 				}
 			},
 			"required": ["query"]
@@ -57,8 +71,9 @@ func (t *KnowledgeTool) Definition() *types.ToolDefinition {
 
 func (t *KnowledgeTool) Execute(ctx context.Context, arguments string, execCtx *executor.ExecutionContext) (*types.ToolResult, error) {
 	var args struct {
-		Query string `json:"query"`
-		TopK  int    `json:"top_k"`
+		Query  string          `json:"query"`
+		TopK   int             `json:"top_k"`
+		Filter json.RawMessage `json:"filter"`
 	}
 	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
 		return types.NewErrorResult(fmt.Sprintf("知识库检索参数解析失败: %v", err)), nil
@@ -74,15 +89,31 @@ func (t *KnowledgeTool) Execute(ctx context.Context, arguments string, execCtx *
 		topK = 5
 	}
 
+	// 节点配置的过滤与模型传入的过滤同时生效
+	var filters []json.RawMessage
+	for _, f := range []json.RawMessage{t.config.KBFilter, args.Filter} {
+		if len(f) > 0 && string(f) != "null" {
+			filters = append(filters, f)
+		}
+	}
+
 	var allResults []knowledgeChunk
 	qdrantHost := getQdrantHost(t.config)
 	guluHost := getGuluHost(t.config)
 	for _, kb := range t.knowledgeBases {
 		if kb.QdrantCollection != "" {
-			results := searchQdrant(ctx, kb, args.Query, topK, qdrantHost, t.config.KBScoreThreshold)
+			results, err := searchVectors(ctx, kb, args.Query, topK, qdrantHost, guluHost, t.config.KBScoreThreshold, filters)
+			if err != nil {
+				logger.Warn("[Knowledge] 知识库 %s 向量搜索失败: %v", kb.Name, err)
+				// 过滤表达式有误时告知模型，便于修正后重试
+				if len(args.Filter) > 0 && string(args.Filter) != "null" {
+					return types.NewErrorResult(fmt.Sprintf("知识库 %s 检索失败: %v", kb.Name, err)), nil
+				}
+			}
 			allResults = append(allResults, results...)
 		}
-		if kb.Type == "graph" {
+		// 图谱检索无法按元数据过滤，指定过滤时跳过
+		if kb.Type == "graph" && len(filters) == 0 {
 			graphResults := searchGraph(ctx, kb, args.Query, topK, guluHost)
 			allResults = append(allResults, graphResults...)
 		}
@@ -112,11 +143,13 @@ type knowledgeChunk struct {
 	ChunkIndex int     `json:"chunk_index"`
 }
 
-func searchQdrant(ctx context.Context, kb *KnowledgeBaseInfo, query string, topK int, qdrantHost string, fallbackScoreThreshold float32) []knowledgeChunk {
+// searchVectors 向量检索：Qdrant 知识库直连 Qdrant，其他向量库后端（内置 / pgvector）经 Gulu 内部接口检索。
+// 需要元数据过滤或文档访问控制时统一经 Gulu 检索，由 Gulu 负责过滤条件的校验与转换；
+// Gulu 下发的知识库总带有执行用户（acl_user_id），直连 Qdrant 仅用于独立运行引擎的场景。
+func searchVectors(ctx context.Context, kb *KnowledgeBaseInfo, query string, topK int, qdrantHost, guluHost string, fallbackScoreThreshold float32, filters []json.RawMessage) ([]knowledgeChunk, error) {
 	queryVector, err := callEmbeddingAPI(ctx, kb.EmbeddingBaseURL, kb.EmbeddingAPIKey, kb.EmbeddingModel, query)
 	if err != nil {
-		logger.Warn("[Knowledge] 知识库 %s 的查询向量化失败: %v", kb.Name, err)
-		return nil
+		return nil, fmt.Errorf("查询向量化失败: %w", err)
 	}
 
 	scoreThreshold := float32(kb.ScoreThreshold)
@@ -124,10 +157,15 @@ func searchQdrant(ctx context.Context, kb *KnowledgeBaseInfo, query string, topK
 		scoreThreshold = fallbackScoreThreshold
 	}
 
-	hits, err := searchQdrantREST(ctx, qdrantHost, kb.QdrantCollection, queryVector, topK, scoreThreshold)
+	var hits []vectorSearchHit
+	restricted := len(filters) > 0 || kb.ACLUserID != nil
+	if (kb.VectorStore == "" || kb.VectorStore == "qdrant") && !restricted {
+		hits, err = searchQdrantREST(ctx, qdrantHost, kb.QdrantCollection, queryVector, topK, scoreThreshold)
+	} else {
+		hits, err = searchGuluVectors(ctx, guluHost, kb.ID, queryVector, topK, scoreThreshold, filters, kb.ACLUserID)
+	}
 	if err != nil {
-		logger.Warn("[Knowledge] 知识库 %s 向量搜索失败: %v", kb.Name, err)
-		return nil
+		return nil, err
 	}
 
 	var chunks []knowledgeChunk
@@ -140,17 +178,17 @@ func searchQdrant(ctx context.Context, kb *KnowledgeBaseInfo, query string, topK
 			ChunkIndex: hit.ChunkIndex,
 		})
 	}
-	return chunks
+	return chunks, nil
 }
 
-type qdrantSearchHit struct {
+type vectorSearchHit struct {
 	Content    string
 	Score      float64
 	DocumentID int64
 	ChunkIndex int
 }
 
-func searchQdrantREST(ctx context.Context, qdrantHost, collection string, queryVector []float32, topK int, scoreThreshold float32) ([]qdrantSearchHit, error) {
+func searchQdrantREST(ctx context.Context, qdrantHost, collection string, queryVector []float32, topK int, scoreThreshold float32) ([]vectorSearchHit, error) {
 	reqBody := map[string]interface{}{
 		"query":           queryVector,
 		"using":           "text",
@@ -191,9 +229,9 @@ func searchQdrantREST(ctx context.Context, qdrantHost, collection string, queryV
 		return nil, fmt.Errorf("Qdrant 响应解析失败: %w", err)
 	}
 
-	var hits []qdrantSearchHit
+	var hits []vectorSearchHit
 	for _, p := range result.Result.Points {
-		hit := qdrantSearchHit{Score: p.Score}
+		hit := vectorSearchHit{Score: p.Score}
 		if v, ok := p.Payload["content"].(string); ok {
 			hit.Content = v
 		}
@@ -208,6 +246,69 @@ func searchQdrantREST(ctx context.Context, qdrantHost, collection string, queryV
 	return hits, nil
 }
 
+func searchGuluVectors(ctx context.Context, guluHost string, kbID int64, queryVector []float32, topK int, scoreThreshold float32, filters []json.RawMessage, aclUserID *int64) ([]vectorSearchHit, error) {
+	reqBody := map[string]interface{}{
+		"vector":          queryVector,
+		"field":           "text",
+		"top_k":           topK,
+		"score_threshold": scoreThreshold,
+	}
+	if len(filters) > 0 {
+		reqBody["filters"] = filters
+	}
+	if aclUserID != nil {
+		reqBody["acl_user_id"] = *aclUserID
+	}
+	bodyBytes, _ := json.Marshal(reqBody)
+	url := fmt.Sprintf("%s/api/internal/knowledge-bases/%d/vector-query", guluHost, kbID)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(types.GuluInternalTokenHeader, types.GuluInternalToken())
+
+	resp, err := (&http.Client{Timeout: 15 * time.Second}).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("Gulu 向量检索请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Gulu 向量检索返回错误 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    []struct {
+			Content    string  `json:"content"`
+			Score      float64 `json:"score"`
+			DocumentID int64   `json:"document_id"`
+			ChunkIndex int     `json:"chunk_index"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("Gulu 向量检索响应解析失败: %w", err)
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("Gulu 向量检索失败: %s", result.Message)
+	}
+
+	hits := make([]vectorSearchHit, 0, len(result.Data))
+	for _, d := range result.Data {
+		hits = append(hits, vectorSearchHit{
+			Content:    d.Content,
+			Score:      d.Score,
+			DocumentID: d.DocumentID,
+			ChunkIndex: d.ChunkIndex,
+		})
+	}
+	return hits, nil
+}
+
 func callEmbeddingAPI(ctx context.Context, baseURL, apiKey, model, text string) ([]float32, error) {
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+func TestKnowledgeTool_NonQdrantStoreQueriesGulu(t *testing.T) {
+	var query map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/embeddings":
+			w.Write([]byte(`{"data":[{"embedding":[0.1,0.2,0.3]}]}`))
+		case "/api/internal/knowledge-bases/7/vector-query":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+			w.Write([]byte(`{"code":0,"message":"success","data":[{"content":"内置向量库命中","score":0.91,"document_id":3,"chunk_index":1}]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kb := &KnowledgeBaseInfo{
+		ID:               7,
+		Name:             "手册",
+		VectorStore:      "embedded",
+		QdrantCollection: "kb_7",
+		EmbeddingBaseURL: server.URL,
+		ScoreThreshold:   0.4,
+	}
+	tool := NewKnowledgeTool([]*KnowledgeBaseInfo{kb}, &AIConfig{GuluHost: server.URL, QdrantHost: "http://127.0.0.1:1"})
+
+	result, err := tool.Execute(context.Background(), `{"query":"安装步骤","top_k":3}`, nil)
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Contains(t, result.Content, "内置向量库命中")
+	assert.Contains(t, result.Content, "来源: 手册")
+
+	assert.Equal(t, "text", query["field"])
+	assert.EqualValues(t, 3, query["top_k"])
+	assert.InDelta(t, 0.4, query["score_threshold"], 1e-6)
+	assert.Len(t, query["vector"], 3)
+}
+
+func TestKnowledgeTool_FilterAndACLRouteThroughGulu(t *testing.T) {
+	t.Setenv(types.GuluInternalTokenEnv, "internal-secret")
+	var query map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/embeddings":
+			w.Write([]byte(`{"data":[{"embedding":[0.1,0.2,0.3]}]}`))
+		case "/api/internal/knowledge-bases/8/vector-query":
+			assert.Equal(t, "internal-secret", r.Header.Get(types.GuluInternalTokenHeader))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+			if filters, _ := query["filters"].([]interface{}); len(filters) == 2 {
+				w.Write([]byte(`{"code":0,"message":"success","data":[{"content":"v2 安装说明","score":0.8,"document_id":5,"chunk_index":0}]}`))
+				return
+			}
+			w.Write([]byte(`{"code":1,"message":"不支持的过滤操作: like"}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	userID := int64(42)
+	kb := &KnowledgeBaseInfo{
+		ID:               8,
+		Name:             "产品文档",
+		QdrantCollection: "kb_8",
+		EmbeddingBaseURL: server.URL,
+		ACLUserID:        &userID,
+		MetadataKeys:     []string{"version", "dir"},
+	}
+	config := &AIConfig{
+		GuluHost:   server.URL,
+		QdrantHost: "http://127.0.0.1:1",
+		KBFilter:   json.RawMessage(`{"must":[{"key":"dir","op":"eq","value":"docs"}]}`),
+	}
+	tool := NewKnowledgeTool([]*KnowledgeBaseInfo{kb}, config)
+	assert.Contains(t, tool.Definition().Description, "可过滤字段：version, dir")
+
+	result, err := tool.Execute(context.Background(), `{"query":"安装","filter":{"must":[{"key":"version","op":"eq","value":"2.0"}]}}`, nil)
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Contains(t, result.Content, "v2 安装说明")
+	assert.EqualValues(t, 42, query["acl_user_id"])
+
+	// 模型传入的过滤有误时返回错误结果，便于其修正后重试
+	config.KBFilter = nil
+	result, err = tool.Execute(context.Background(), `{"query":"安装","filter":{"must":[{"key":"version","op":"like","value":"2"}]}}`, nil)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content, "不支持的过滤操作")
+}
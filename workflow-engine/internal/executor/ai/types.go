@@ -20,13 +20,18 @@ const (
 type AIOutput struct {
 	Content          string                  `json:"content"`
 	PromptTokens     int                     `json:"prompt_tokens"`
+	CachedTokens     int                     `json:"cached_tokens,omitempty"` // 缓存命中的输入 Token，已计入 PromptTokens
 	CompletionTokens int                     `json:"completion_tokens"`
 	TotalTokens      int                     `json:"total_tokens"`
+	Cost             float64                 `json:"cost,omitempty"`           // 按模型单价计算的费用
+	BudgetWarning    string                  `json:"budget_warning,omitempty"` // 超出预算软上限时的告警
 	Model            string                  `json:"model"`
 	FinishReason     string                  `json:"finish_reason"`
 	SystemPrompt     string                  `json:"system_prompt,omitempty"`
 	Prompt           string                  `json:"prompt"`
 	ToolCalls        []ToolCallRecord        `json:"tool_calls,omitempty"`
+	Data             any                     `json:"data,omitempty"`          // 配置 output_schema 时的解析结果
+	RepairRounds     int                     `json:"repair_rounds,omitempty"` // 结构化输出修复轮数
 	AgentTrace       *AgentTrace             `json:"agent_trace,omitempty"`
 	ConsoleLogs      []types.ConsoleLogEntry `json:"console_logs,omitempty"`
 }
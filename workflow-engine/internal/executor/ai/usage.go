@@ -0,0 +1,314 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+
+	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/pkg/logger"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// ErrBudgetExceeded AI 费用超出预算硬上限
+var ErrBudgetExceeded = errors.New("AI 费用已超出预算硬上限")
+
+// ledgerIdleTTL 进程内预算累计的闲置保留时间，超过后清理
+const ledgerIdleTTL = time.Hour
+
+// ModelPricing 模型单价，单位为每百万 Token 的金额，与预算使用同一币种
+type ModelPricing struct {
+	InputPrice  float64  `json:"input_price"`
+	OutputPrice float64  `json:"output_price"`
+	CachedPrice *float64 `json:"cached_price,omitempty"` // 缓存命中的输入 Token 单价，未配置时按输入单价计
+}
+
+// Cost 计算一次调用的费用，promptTokens 包含缓存命中部分
+func (p *ModelPricing) Cost(promptTokens, cachedTokens, completionTokens int) float64 {
+	if p == nil {
+		return 0
+	}
+	cachedPrice := p.InputPrice
+	if p.CachedPrice != nil {
+		cachedPrice = *p.CachedPrice
+	}
+	uncached := promptTokens - cachedTokens
+	if uncached < 0 {
+		uncached = 0
+	}
+	return (float64(uncached)*p.InputPrice + float64(cachedTokens)*cachedPrice + float64(completionTokens)*p.OutputPrice) / 1e6
+}
+
+// BudgetConfig 未接入用量账本时在引擎内检查的 AI 费用预算，Spent 为执行开始前预算周期内的已用金额
+type BudgetConfig struct {
+	Scope     string  `json:"scope,omitempty"`      // 预算范围标识，如 project:12
+	SoftLimit float64 `json:"soft_limit,omitempty"` // 软上限：超出后告警，继续执行
+	HardLimit float64 `json:"hard_limit,omitempty"` // 硬上限：超出后停止调用模型
+	Spent     float64 `json:"spent,omitempty"`
+}
+
+// UsageLedgerConfig 由 Gulu 注入的用量账本：每次调用模型前在账本中预留预估费用，调用后按实际用量结算并记录。
+// 预算在账本中按项目原子扣减，并发执行共享同一预算
+type UsageLedgerConfig struct {
+	URL        string         `json:"url"`
+	Attributes map[string]any `json:"attributes,omitempty"` // 用量归属（执行、用户、会话等），结算时原样回传
+}
+
+// reserveCompletionTokens 未配置 max_tokens 时按该输出 Token 数预估单次调用费用
+const reserveCompletionTokens = 4096
+
+// budgetLedger 未接入用量账本时进程内按预算范围累计的费用，同一范围的并发执行共享预算
+var budgetLedger = struct {
+	sync.Mutex
+	entries map[string]*ledgerEntry
+}{entries: make(map[string]*ledgerEntry)}
+
+type ledgerEntry struct {
+	spent   float64
+	touched time.Time
+}
+
+// ledgerSpent 预算范围内的已用金额，取进程内累计与执行开始前已用金额中的较大值
+func ledgerSpent(scope string, base float64) float64 {
+	budgetLedger.Lock()
+	defer budgetLedger.Unlock()
+	if e, ok := budgetLedger.entries[scope]; ok && e.spent > base {
+		return e.spent
+	}
+	return base
+}
+
+func ledgerAdd(scope string, base, cost float64) {
+	now := time.Now()
+	budgetLedger.Lock()
+	defer budgetLedger.Unlock()
+	for k, e := range budgetLedger.entries {
+		if now.Sub(e.touched) > ledgerIdleTTL {
+			delete(budgetLedger.entries, k)
+		}
+	}
+	e, ok := budgetLedger.entries[scope]
+	if !ok {
+		e = &ledgerEntry{}
+		budgetLedger.entries[scope] = e
+	}
+	if e.spent < base {
+		e.spent = base
+	}
+	e.spent += cost
+	e.touched = now
+}
+
+// usageMeter 统计单个 AI 步骤的 Token 用量与费用，并在每次调用模型前检查预算
+type usageMeter struct {
+	pricing   *ModelPricing
+	budget    *BudgetConfig
+	ledger    *UsageLedgerConfig
+	model     string
+	stepID    string
+	maxTokens int
+	execCtx   *executor.ExecutionContext
+
+	reserved float64 // 本次调用前在账本中预留、尚未结算的金额
+}
+
+func newUsageMeter(config *AIConfig, stepID string, execCtx *executor.ExecutionContext) *usageMeter {
+	m := &usageMeter{
+		pricing:   config.Pricing,
+		budget:    config.Budget,
+		ledger:    config.UsageLedger,
+		model:     config.Model,
+		stepID:    stepID,
+		maxTokens: reserveCompletionTokens,
+		execCtx:   execCtx,
+	}
+	if config.MaxTokens != nil && *config.MaxTokens > 0 {
+		m.maxTokens = *config.MaxTokens
+	}
+	// 接入账本后以账本中的项目预算为准
+	if m.ledger != nil {
+		m.budget = nil
+	}
+	return m
+}
+
+// check 调用模型前检查预算：接入账本时预留本次调用的预估费用，超出硬上限或账本不可用时返回错误；
+// 首次超出软上限时记录告警
+func (m *usageMeter) check(ctx context.Context, output *AIOutput, messages []*schema.Message) error {
+	if m == nil {
+		return nil
+	}
+	if m.ledger != nil {
+		return m.reserve(ctx, output, messages)
+	}
+	if m.budget == nil {
+		return nil
+	}
+	spent := ledgerSpent(m.budget.Scope, m.budget.Spent)
+	if m.budget.HardLimit > 0 && spent >= m.budget.HardLimit {
+		return fmt.Errorf("%w: 已用 %.4f，上限 %.4f", ErrBudgetExceeded, spent, m.budget.HardLimit)
+	}
+	m.warnSoftLimit(output, spent, m.budget.SoftLimit)
+	return nil
+}
+
+// record 累加一次调用的 Token 用量并按单价计费，接入账本时按实际费用结算预留金额
+func (m *usageMeter) record(ctx context.Context, output *AIOutput, resp *schema.Message) {
+	prompt, cached, completion := output.PromptTokens, output.CachedTokens, output.CompletionTokens
+	updateTokenUsage(output, resp)
+	if m == nil {
+		return
+	}
+	promptDelta, cachedDelta, completionDelta := output.PromptTokens-prompt, output.CachedTokens-cached, output.CompletionTokens-completion
+	cost := m.pricing.Cost(promptDelta, cachedDelta, completionDelta)
+	if cost > 0 {
+		output.Cost += cost
+	}
+	if m.ledger != nil {
+		m.settle(ctx, cost, &ledgerUsage{
+			PromptTokens:     promptDelta,
+			CachedTokens:     cachedDelta,
+			CompletionTokens: completionDelta,
+			TotalTokens:      promptDelta + completionDelta,
+		})
+		return
+	}
+	if m.budget != nil && cost > 0 {
+		ledgerAdd(m.budget.Scope, m.budget.Spent, cost)
+	}
+}
+
+// release 模型调用失败时释放预留金额
+func (m *usageMeter) release(ctx context.Context) {
+	if m == nil || m.ledger == nil || m.reserved == 0 {
+		return
+	}
+	m.settle(ctx, 0, nil)
+}
+
+func (m *usageMeter) warnSoftLimit(output *AIOutput, spent, softLimit float64) {
+	if softLimit <= 0 || spent < softLimit || output.BudgetWarning != "" {
+		return
+	}
+	output.BudgetWarning = fmt.Sprintf("AI 费用已超出预算软上限: 已用 %.4f，软上限 %.4f", spent, softLimit)
+	logger.Warn("[Budget] %s, step=%s", output.BudgetWarning, m.stepID)
+	if m.execCtx != nil {
+		m.execCtx.AppendLogs([]types.ConsoleLogEntry{types.NewWarnEntry(output.BudgetWarning)})
+	}
+}
+
+// estimateCost 预估一次调用的费用：输入按消息字符数计（不少于实际 Token 数），输出按 max_tokens 计
+func (m *usageMeter) estimateCost(messages []*schema.Message) float64 {
+	promptTokens := 0
+	for _, msg := range messages {
+		promptTokens += utf8.RuneCountInString(msg.Content) + utf8.RuneCountInString(msg.ReasoningContent)
+		for _, part := range msg.UserInputMultiContent {
+			promptTokens += utf8.RuneCountInString(part.Text)
+		}
+		for _, tc := range msg.ToolCalls {
+			promptTokens += utf8.RuneCountInString(tc.Function.Arguments)
+		}
+	}
+	return m.pricing.Cost(promptTokens, 0, m.maxTokens)
+}
+
+// ledgerUsage 结算时回传给账本的单次调用用量
+type ledgerUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CachedTokens     int `json:"cached_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ledgerResult 账本返回的预算状态，Allowed 为 false 表示预留后将超出硬上限，未预留
+type ledgerResult struct {
+	Allowed   bool    `json:"allowed"`
+	Spent     float64 `json:"spent"`
+	SoftLimit float64 `json:"soft_limit"`
+	HardLimit float64 `json:"hard_limit"`
+}
+
+func (m *usageMeter) reserve(ctx context.Context, output *AIOutput, messages []*schema.Message) error {
+	amount := m.estimateCost(messages)
+	result, err := m.callLedger(ctx, map[string]any{"phase": "reserve", "amount": amount})
+	if err != nil {
+		return fmt.Errorf("AI 预算校验失败: %w", err)
+	}
+	if !result.Allowed {
+		return fmt.Errorf("%w: 已用 %.4f，本次预估 %.4f，上限 %.4f", ErrBudgetExceeded, result.Spent, amount, result.HardLimit)
+	}
+	m.reserved = amount
+	m.warnSoftLimit(output, result.Spent, result.SoftLimit)
+	return nil
+}
+
+// settle 按实际费用结算本次调用的预留金额并记录用量，失败只记录日志：预留金额已计入预算，不会少计
+func (m *usageMeter) settle(ctx context.Context, cost float64, usage *ledgerUsage) {
+	body := map[string]any{
+		"phase":    "settle",
+		"amount":   cost,
+		"reserved": m.reserved,
+		"step_id":  m.stepID,
+		"model":    m.model,
+	}
+	if usage != nil {
+		body["usage"] = usage
+	}
+	m.reserved = 0
+	// 步骤被取消时仍需结算
+	if _, err := m.callLedger(context.WithoutCancel(ctx), body); err != nil {
+		logger.Warn("[Budget] AI 用量结算失败, step=%s: %v", m.stepID, err)
+	}
+}
+
+func (m *usageMeter) callLedger(ctx context.Context, body map[string]any) (*ledgerResult, error) {
+	for k, v := range m.ledger.Attributes {
+		if _, ok := body[k]; !ok {
+			body[k] = v
+		}
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.ledger.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(types.GuluInternalTokenHeader, types.GuluInternalToken())
+
+	resp, err := ledgerClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("用量账本返回错误 (HTTP %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Code    int          `json:"code"`
+		Message string       `json:"message"`
+		Data    ledgerResult `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("用量账本响应解析失败: %w", err)
+	}
+	if result.Code != 0 {
+		return nil, errors.New(result.Message)
+	}
+	return &result.Data, nil
+}
+
+var ledgerClient = &http.Client{Timeout: 10 * time.Second}
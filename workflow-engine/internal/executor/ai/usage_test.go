@@ -0,0 +1,190 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/internal/executor"
+)
+
+func usageResponse(content string, prompt, cached, completion int) *schema.Message {
+	return &schema.Message{Role: schema.Assistant, Content: content, ResponseMeta: &schema.ResponseMeta{
+		Usage: &schema.TokenUsage{
+			PromptTokens:       prompt,
+			PromptTokenDetails: schema.PromptTokenDetails{CachedTokens: cached},
+			CompletionTokens:   completion,
+			TotalTokens:        prompt + completion,
+		},
+	}}
+}
+
+func TestModelPricing_Cost(t *testing.T) {
+	cachedPrice := 0.5
+	pricing := &ModelPricing{InputPrice: 2, OutputPrice: 8, CachedPrice: &cachedPrice}
+	// 600k 未命中缓存 * 2 + 400k 缓存 * 0.5 + 100k 输出 * 8
+	assert.InDelta(t, 1.2+0.2+0.8, pricing.Cost(1_000_000, 400_000, 100_000), 1e-9)
+
+	pricing.CachedPrice = nil
+	assert.InDelta(t, 2+0.8, pricing.Cost(1_000_000, 400_000, 100_000), 1e-9)
+
+	var none *ModelPricing
+	assert.Zero(t, none.Cost(1000, 0, 1000))
+}
+
+func TestRunAgent_BudgetAcrossSteps(t *testing.T) {
+	config := &AIConfig{
+		Model:   "gpt-test",
+		Pricing: &ModelPricing{InputPrice: 1000, OutputPrice: 2000},
+		Budget:  &BudgetConfig{Scope: t.Name(), SoftLimit: 0.2, HardLimit: 0.3, Spent: 0.05},
+	}
+	execCtx := executor.NewExecutionContext().WithExecutionID(t.Name())
+
+	runStep := func(stepID string, chatModel *scriptedChatModel) (*AIOutput, error) {
+		return RunAgent(context.Background(), &AgentRequest{
+			Config:    config,
+			ChatModel: chatModel,
+			Messages:  []*schema.Message{schema.UserMessage("hi")},
+			StepID:    stepID,
+			ExecCtx:   execCtx,
+			Callbacks: NewAgentCallbacks(nil, stepID),
+			MaxRounds: 3,
+			Meter:     newUsageMeter(config, stepID, execCtx),
+		})
+	}
+
+	// 每次调用 100*1000/1e6 + 50*2000/1e6 = 0.2
+	first := &scriptedChatModel{responses: []*schema.Message{usageResponse("one", 100, 0, 50)}}
+	output, err := runStep("s1", first)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.2, output.Cost, 1e-9)
+	assert.Empty(t, output.BudgetWarning)
+
+	// 调用前已用 0.25，超过软上限只告警
+	second := &scriptedChatModel{responses: []*schema.Message{usageResponse("two", 100, 0, 50)}}
+	output, err = runStep("s2", second)
+	require.NoError(t, err)
+	assert.NotEmpty(t, output.BudgetWarning)
+	assert.Len(t, execCtx.FlushLogs(), 1)
+
+	// 已用 0.45，超过硬上限，不再调用模型
+	third := &scriptedChatModel{responses: []*schema.Message{usageResponse("three", 100, 0, 50)}}
+	output, err = runStep("s3", third)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+	assert.NotNil(t, output)
+	assert.EqualValues(t, 0, third.calls)
+
+	// 同一预算范围的其他执行共享已用金额
+	other := executor.NewExecutionContext().WithExecutionID(t.Name() + "-other")
+	meter := newUsageMeter(config, "s4", other)
+	assert.ErrorIs(t, meter.check(context.Background(), &AIOutput{}, nil), ErrBudgetExceeded)
+}
+
+// fakeLedger 模拟 Gulu 用量账本：预留时原子检查硬上限，结算时按实际费用修正
+type fakeLedger struct {
+	mu        sync.Mutex
+	spent     float64
+	hardLimit float64
+	softLimit float64
+	fail      bool
+	requests  []map[string]any
+}
+
+func (l *fakeLedger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, body)
+	if l.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	amount, _ := body["amount"].(float64)
+	allowed := true
+	switch body["phase"] {
+	case "reserve":
+		if l.hardLimit > 0 && l.spent+amount > l.hardLimit {
+			allowed = false
+		} else {
+			l.spent += amount
+		}
+	case "settle":
+		reserved, _ := body["reserved"].(float64)
+		l.spent += amount - reserved
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": map[string]any{
+		"allowed": allowed, "spent": l.spent, "soft_limit": l.softLimit, "hard_limit": l.hardLimit,
+	}})
+}
+
+func TestUsageMeter_Ledger(t *testing.T) {
+	ledger := &fakeLedger{hardLimit: 1}
+	server := httptest.NewServer(ledger)
+	defer server.Close()
+
+	maxTokens := 100
+	config := &AIConfig{
+		Model:       "gpt-test",
+		MaxTokens:   &maxTokens,
+		Pricing:     &ModelPricing{InputPrice: 1000, OutputPrice: 2000},
+		Budget:      &BudgetConfig{Scope: t.Name(), HardLimit: 0.001},
+		UsageLedger: &UsageLedgerConfig{URL: server.URL, Attributes: map[string]any{"execution_id": "exec-1"}},
+	}
+	meter := newUsageMeter(config, "s1", nil)
+	output := &AIOutput{}
+	messages := []*schema.Message{schema.UserMessage("hello")}
+
+	// 预留 5*1000/1e6 + 100*2000/1e6 = 0.205，结算为实际费用 0.2，账本中的预算优先于 budget
+	require.NoError(t, meter.check(context.Background(), output, messages))
+	meter.record(context.Background(), output, usageResponse("a", 100, 0, 50))
+	assert.InDelta(t, 0.2, output.Cost, 1e-9)
+	assert.InDelta(t, 0.2, ledger.spent, 1e-9)
+
+	require.Len(t, ledger.requests, 2)
+	reserve, settle := ledger.requests[0], ledger.requests[1]
+	assert.Equal(t, "reserve", reserve["phase"])
+	assert.InDelta(t, 0.205, reserve["amount"], 1e-9)
+	assert.Equal(t, "exec-1", reserve["execution_id"])
+	assert.Equal(t, "settle", settle["phase"])
+	assert.InDelta(t, 0.205, settle["reserved"], 1e-9)
+	assert.Equal(t, "s1", settle["step_id"])
+	assert.Equal(t, "gpt-test", settle["model"])
+	assert.EqualValues(t, 150, settle["usage"].(map[string]any)["total_tokens"])
+
+	// 调用失败时释放预留
+	require.NoError(t, meter.check(context.Background(), output, messages))
+	meter.release(context.Background())
+	assert.InDelta(t, 0.2, ledger.spent, 1e-9)
+
+	// 预留后将超出硬上限
+	ledger.spent = 0.9
+	assert.ErrorIs(t, meter.check(context.Background(), output, messages), ErrBudgetExceeded)
+
+	// 账本不可用时拒绝调用模型
+	ledger.spent, ledger.fail = 0, true
+	err := meter.check(context.Background(), output, messages)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrBudgetExceeded)
+}
+
+func TestUsageMeter_CachedTokens(t *testing.T) {
+	cachedPrice := 100.0
+	config := &AIConfig{Pricing: &ModelPricing{InputPrice: 1000, OutputPrice: 1000, CachedPrice: &cachedPrice}}
+	meter := newUsageMeter(config, "s1", nil)
+
+	output := &AIOutput{}
+	meter.record(context.Background(), output, usageResponse("a", 1000, 800, 0))
+	meter.record(context.Background(), output, usageResponse("b", 1000, 0, 0))
+	assert.Equal(t, 800, output.CachedTokens)
+	assert.InDelta(t, (200*1000+800*100+1000*1000)/1e6, output.Cost, 1e-9)
+}
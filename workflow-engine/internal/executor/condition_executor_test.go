@@ -491,3 +491,67 @@ func (e *trackingExecutor) Execute(ctx context.Context, step *types.Step, execCt
 	e.executed = append(e.executed, step.ID)
 	return CreateSuccessResult(step.ID, time.Now(), nil), nil
 }
+
+// debugCallback 记录步骤开始前的调试回调
+type debugCallback struct {
+	types.NoopCallback
+	frames map[string]*types.DebugFrame
+	stopAt string
+}
+
+func (c *debugCallback) OnBeforeStep(ctx context.Context, step *types.Step, frame *types.DebugFrame) error {
+	c.frames[step.ID] = frame
+	if step.ID == c.stopAt {
+		return context.Canceled
+	}
+	frame.Scope.SetVariable("edited", true)
+	return nil
+}
+
+func TestConditionExecutor_Execute_DebugCallback(t *testing.T) {
+	registry := NewRegistry()
+	mockExec := &trackingExecutor{
+		BaseExecutor: NewBaseExecutor("mock"),
+		executed:     make([]string, 0),
+	}
+	registry.MustRegister(mockExec)
+
+	executor := NewConditionExecutorWithRegistry(registry)
+	require.NoError(t, executor.Init(context.Background(), nil))
+
+	step := &types.Step{
+		ID:   "test-if",
+		Type: ConditionExecutorType,
+		Config: map[string]any{
+			"type":       "if",
+			"expression": "${value} > 5",
+		},
+		Children: []types.Step{
+			{ID: "child-1", Type: "mock"},
+			{ID: "child-2", Type: "mock"},
+		},
+	}
+
+	callback := &debugCallback{frames: make(map[string]*types.DebugFrame)}
+	execCtx := NewExecutionContext().WithCallback(callback)
+	execCtx.SetVariable("value", 10)
+
+	_, err := executor.Execute(context.Background(), step, execCtx)
+	require.NoError(t, err)
+	require.Contains(t, callback.frames, "child-1")
+	assert.Equal(t, "test-if", callback.frames["child-1"].ParentID)
+	assert.Equal(t, true, callback.frames["child-1"].Scope.Variables()["edited"])
+	ok, err := callback.frames["child-1"].Scope.Evaluate("${value} > 5")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"child-1", "child-2"}, mockExec.executed)
+
+	// 调试回调返回错误时中止后续步骤
+	mockExec.executed = mockExec.executed[:0]
+	callback = &debugCallback{frames: make(map[string]*types.DebugFrame), stopAt: "child-2"}
+	execCtx = NewExecutionContext().WithCallback(callback)
+	execCtx.SetVariable("value", 10)
+
+	executor.Execute(context.Background(), step, execCtx)
+	assert.Equal(t, []string{"child-1"}, mockExec.executed)
+}
@@ -4,6 +4,7 @@ package executor
 import (
 	"context"
 	"sync"
+	"time"
 
 	"yqhp/workflow-engine/internal/expression"
 	"yqhp/workflow-engine/pkg/types"
@@ -139,6 +140,63 @@ func (c *ExecutionContext) SetResult(stepID string, result *types.StepResult) {
 	c.evalCtxDirty = true
 }
 
+// SnapshotVariables 返回变量的浅拷贝。
+func (c *ExecutionContext) SnapshotVariables() map[string]any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	vars := make(map[string]any, len(c.Variables))
+	for k, v := range c.Variables {
+		vars[k] = v
+	}
+	return vars
+}
+
+// SnapshotResults 返回步骤结果的浅拷贝。
+func (c *ExecutionContext) SnapshotResults() map[string]*types.StepResult {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	results := make(map[string]*types.StepResult, len(c.Results))
+	for k, v := range c.Results {
+		results[k] = v
+	}
+	return results
+}
+
+// Restore 用检查点的变量与结果重建执行上下文（覆盖同名变量）。
+func (c *ExecutionContext) Restore(point *types.ResumePoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range point.Variables {
+		c.Variables[k] = v
+	}
+	for stepID, r := range point.Results {
+		if r != nil {
+			c.Results[stepID] = r.StepResult(stepID)
+		}
+	}
+	c.evalCtxDirty = true
+}
+
+// NewCheckpoint 生成顶层步骤完成后的检查点，before 为步骤开始前的结果快照，
+// 仅收录该步骤执行期间新增或更新的结果。
+func (c *ExecutionContext) NewCheckpoint(index int, stepID string, status types.ResultStatus, before map[string]*types.StepResult) *types.Checkpoint {
+	checkpoint := &types.Checkpoint{
+		StepIndex: index,
+		StepID:    stepID,
+		Status:    status,
+		Variables: c.SnapshotVariables(),
+		Results:   make(map[string]*types.CheckpointResult),
+		CreatedAt: time.Now(),
+	}
+	for id, r := range c.SnapshotResults() {
+		if prev, ok := before[id]; ok && prev == r {
+			continue
+		}
+		checkpoint.Results[id] = types.NewCheckpointResult(r)
+	}
+	return checkpoint
+}
+
 // GetResult 获取步骤结果。
 func (c *ExecutionContext) GetResult(stepID string) (*types.StepResult, bool) {
 	c.mu.RLock()
@@ -261,6 +319,36 @@ func (c *ExecutionContext) GetLoopIteration() int {
 	return c.LoopIteration
 }
 
+// WaitBeforeStep 回调实现 types.DebugCallback 时在步骤开始前调用，断点暂停期间阻塞。
+func (c *ExecutionContext) WaitBeforeStep(ctx context.Context, step *types.Step, parentID string, iteration int) error {
+	debugger, ok := c.GetCallback().(types.DebugCallback)
+	if !ok {
+		return nil
+	}
+	return debugger.OnBeforeStep(ctx, step, &types.DebugFrame{
+		ParentID:  parentID,
+		Iteration: iteration,
+		Scope:     &debugScope{execCtx: c},
+	})
+}
+
+// debugScope 基于执行上下文的调试变量作用域
+type debugScope struct {
+	execCtx *ExecutionContext
+}
+
+func (s *debugScope) Variables() map[string]any {
+	return s.execCtx.ToEvaluationContext()
+}
+
+func (s *debugScope) SetVariable(name string, value any) {
+	s.execCtx.SetVariable(name, value)
+}
+
+func (s *debugScope) Evaluate(expr string) (bool, error) {
+	return expression.NewEvaluator().EvaluateString(expr, s.execCtx.BuildEvaluationContext())
+}
+
 // ToEvaluationContext 将 ExecutionContext 转换为表达式求值上下文。
 func (c *ExecutionContext) ToEvaluationContext() map[string]any {
 	c.mu.RLock()
@@ -531,6 +619,11 @@ func (n *NestedExecutorBase) ExecuteNestedSteps(ctx context.Context, steps []typ
 		default:
 		}
 
+		// 断点调试：暂停期间阻塞
+		if err := execCtx.WaitBeforeStep(ctx, step, parentID, iteration); err != nil {
+			return results, err
+		}
+
 		// 发送步骤开始事件
 		if callback != nil {
 			callback.OnStepStart(ctx, step, parentID, iteration)
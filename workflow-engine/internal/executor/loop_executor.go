@@ -359,6 +359,11 @@ func (e *LoopExecutor) executeLoopBody(ctx context.Context, parentStep *types.St
 	for i := range steps {
 		step := &steps[i]
 
+		// 断点调试：暂停期间阻塞
+		if err := execCtx.WaitBeforeStep(ctx, step, parentStep.ID, iteration+1); err != nil {
+			return stepsExecuted, err
+		}
+
 		// 触发步骤开始回调
 		if callback != nil {
 			callback.OnStepStart(ctx, step, parentStep.ID, iteration+1) // iteration 从1开始
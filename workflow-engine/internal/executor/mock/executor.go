@@ -0,0 +1,270 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+const (
+	// MockExecutorType mock 步骤类型
+	MockExecutorType = "mock"
+	// defaultServerName 未配置 name 时的服务名称
+	defaultServerName = "default"
+	// defaultStopAfter 未显式停止的服务在启动后自动停止的时间，避免工作流异常退出时端口泄漏
+	defaultStopAfter = 10 * time.Minute
+)
+
+// Mock 步骤操作
+const (
+	ActionStart  = "start"
+	ActionStop   = "stop"
+	ActionVerify = "verify"
+	ActionReset  = "reset"
+)
+
+// MockExecutor 在工作流中启动、校验与停止 Mock 服务。
+// 服务按执行 ID、VU 与名称隔离，并发 VU 各自持有独立的服务实例。
+type MockExecutor struct {
+	*executor.BaseExecutor
+
+	mu      sync.Mutex
+	servers map[string]*runningServer
+}
+
+type runningServer struct {
+	server *Server
+	timer  *time.Timer
+}
+
+// NewMockExecutor 创建 Mock 步骤执行器
+func NewMockExecutor() *MockExecutor {
+	return &MockExecutor{
+		BaseExecutor: executor.NewBaseExecutor(MockExecutorType),
+		servers:      make(map[string]*runningServer),
+	}
+}
+
+// Execute 执行 Mock 步骤。config 示例：
+//
+//	action: start            # start / stop / verify / reset，默认 start
+//	name: payment            # 服务名称，启动后可通过 ${mock.payment.url} 引用地址
+//	host: 127.0.0.1          # 监听地址
+//	port: 0                  # 0 表示随机端口
+//	stubs: [...]             # 桩定义，或通过 stubs_file 指定定义文件
+//	stop_after: 10m          # 未显式停止时自动停止的时间
+//	verify: [...]            # verify 操作的校验规则
+func (e *MockExecutor) Execute(ctx context.Context, step *types.Step, execCtx *executor.ExecutionContext) (*types.StepResult, error) {
+	result := types.NewStepResult(step.ID)
+	defer result.Finish()
+
+	action, _ := step.Config["action"].(string)
+	if action == "" {
+		action = ActionStart
+	}
+	name, _ := step.Config["name"].(string)
+	if name == "" {
+		name = defaultServerName
+	}
+	if execCtx != nil {
+		name = executor.GetVariableResolver().ResolveString(name, execCtx.ToEvaluationContext())
+	}
+	output := map[string]any{"action": action, "name": name}
+	result.Output = output
+
+	var err error
+	switch strings.ToLower(action) {
+	case ActionStart:
+		err = e.start(step, execCtx, name, output)
+	case ActionStop:
+		err = e.stop(ctx, execCtx, name, output)
+	case ActionVerify:
+		err = e.verify(step, execCtx, name, output)
+	case ActionReset:
+		var srv *Server
+		if srv, err = e.lookup(execCtx, name); err == nil {
+			srv.Reset()
+		}
+	default:
+		err = executor.NewConfigError(fmt.Sprintf("未知的 Mock 操作: %s", action), nil)
+	}
+	if err != nil {
+		output["error"] = err.Error()
+		result.Fail(err)
+	}
+	return result, nil
+}
+
+func (e *MockExecutor) start(step *types.Step, execCtx *executor.ExecutionContext, name string, output map[string]any) error {
+	var stubs []Stub
+	if file, ok := step.Config["stubs_file"].(string); ok && file != "" {
+		def, err := LoadDefinition(file)
+		if err != nil {
+			return err
+		}
+		stubs = append(stubs, def.Stubs...)
+	}
+	if raw, ok := step.Config["stubs"]; ok {
+		list, err := ParseStubs(raw)
+		if err != nil {
+			return executor.NewConfigError(err.Error(), err)
+		}
+		stubs = append(stubs, list...)
+	}
+	if len(stubs) == 0 {
+		return executor.NewConfigError("Mock 步骤需要配置 'stubs' 或 'stubs_file'", nil)
+	}
+
+	host, _ := step.Config["host"].(string)
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(configInt(step.Config["port"])))
+	stopAfter := defaultStopAfter
+	if s, ok := step.Config["stop_after"].(string); ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return executor.NewConfigError(fmt.Sprintf("stop_after 格式无效: %s", s), err)
+		}
+		stopAfter = d
+	}
+
+	var vars map[string]any
+	if execCtx != nil {
+		vars = execCtx.ToEvaluationContext()
+	}
+	srv := NewServer(stubs, Options{Name: name, Address: address, Variables: vars})
+
+	key := serverKey(execCtx, name)
+	e.mu.Lock()
+	if _, exists := e.servers[key]; exists {
+		e.mu.Unlock()
+		return fmt.Errorf("Mock 服务 %s 已在运行", name)
+	}
+	if err := srv.Start(); err != nil {
+		e.mu.Unlock()
+		return err
+	}
+	running := &runningServer{server: srv}
+	running.timer = time.AfterFunc(stopAfter, func() {
+		e.remove(key, srv)
+		stopServer(srv)
+	})
+	e.servers[key] = running
+	e.mu.Unlock()
+
+	if execCtx != nil {
+		execCtx.SetVariable("mock."+name+".url", srv.URL())
+		execCtx.SetVariable("mock."+name+".port", srv.Port())
+	}
+	output["url"] = srv.URL()
+	output["port"] = srv.Port()
+	output["stubs"] = len(stubs)
+	return nil
+}
+
+func (e *MockExecutor) stop(ctx context.Context, execCtx *executor.ExecutionContext, name string, output map[string]any) error {
+	key := serverKey(execCtx, name)
+	e.mu.Lock()
+	running, ok := e.servers[key]
+	delete(e.servers, key)
+	e.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("Mock 服务 %s 未运行", name)
+	}
+	running.timer.Stop()
+	output["requests"] = len(running.server.Requests())
+	stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return running.server.Stop(stopCtx)
+}
+
+func (e *MockExecutor) verify(step *types.Step, execCtx *executor.ExecutionContext, name string, output map[string]any) error {
+	srv, err := e.lookup(execCtx, name)
+	if err != nil {
+		return err
+	}
+	raw, ok := step.Config["verify"]
+	if !ok {
+		return executor.NewConfigError("verify 操作需要配置 'verify'（校验规则）", nil)
+	}
+	list, err := ParseVerifications(raw)
+	if err != nil {
+		return executor.NewConfigError(err.Error(), err)
+	}
+	output["requests"] = len(srv.Requests())
+	if err := srv.Verify(list); err != nil {
+		output["passed"] = false
+		return err
+	}
+	output["passed"] = true
+	return nil
+}
+
+func (e *MockExecutor) lookup(execCtx *executor.ExecutionContext, name string) (*Server, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	running, ok := e.servers[serverKey(execCtx, name)]
+	if !ok {
+		return nil, fmt.Errorf("Mock 服务 %s 未运行", name)
+	}
+	return running.server, nil
+}
+
+// remove 仅在登记的仍是同一实例时移除，避免自动停止误删同名的新服务
+func (e *MockExecutor) remove(key string, srv *Server) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if running, ok := e.servers[key]; ok && running.server == srv {
+		delete(e.servers, key)
+	}
+}
+
+// Cleanup 停止所有仍在运行的 Mock 服务
+func (e *MockExecutor) Cleanup(ctx context.Context) error {
+	e.mu.Lock()
+	servers := e.servers
+	e.servers = make(map[string]*runningServer)
+	e.mu.Unlock()
+	for _, running := range servers {
+		running.timer.Stop()
+		running.server.Stop(ctx)
+	}
+	return nil
+}
+
+func serverKey(execCtx *executor.ExecutionContext, name string) string {
+	if execCtx == nil {
+		return name
+	}
+	vu := 0
+	if execCtx.VU != nil {
+		vu = execCtx.VU.ID
+	}
+	return fmt.Sprintf("%s/%d/%s", execCtx.ExecutionID, vu, name)
+}
+
+func stopServer(srv *Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Stop(ctx)
+}
+
+func configInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package mock
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+func TestMockExecutor_Registered(t *testing.T) {
+	exec, err := executor.GetOrError(MockExecutorType)
+	require.NoError(t, err)
+	assert.Equal(t, MockExecutorType, exec.Type())
+}
+
+func TestMockExecutor_Lifecycle(t *testing.T) {
+	exec := NewMockExecutor()
+	defer exec.Cleanup(context.Background())
+	execCtx := executor.NewExecutionContext()
+	execCtx.ExecutionID = "exec-1"
+	ctx := context.Background()
+
+	result, err := exec.Execute(ctx, &types.Step{ID: "start", Type: MockExecutorType, Config: map[string]any{
+		"name": "payment",
+		"stubs": []any{
+			map[string]any{
+				"request":  map[string]any{"method": "GET", "path": "/pay/{id}"},
+				"response": map[string]any{"body": "paid ${request.params.id}"},
+			},
+		},
+	}}, execCtx)
+	require.NoError(t, err)
+	require.Equal(t, types.ResultStatusSuccess, result.Status, result.Error)
+
+	url, ok := execCtx.GetVariable("mock.payment.url")
+	require.True(t, ok)
+	resolved := executor.GetVariableResolver().ResolveString("${mock.payment.url}/pay/7", execCtx.ToEvaluationContext())
+	assert.Equal(t, url.(string)+"/pay/7", resolved)
+
+	resp, err := http.Get(resolved)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "paid 7", string(body))
+
+	result, _ = exec.Execute(ctx, &types.Step{ID: "verify", Type: MockExecutorType, Config: map[string]any{
+		"action": "verify",
+		"name":   "payment",
+		"verify": map[string]any{"request": map[string]any{"path": "/pay/{id}"}, "count": 1},
+	}}, execCtx)
+	assert.Equal(t, types.ResultStatusSuccess, result.Status, result.Error)
+
+	result, _ = exec.Execute(ctx, &types.Step{ID: "verify_fail", Type: MockExecutorType, Config: map[string]any{
+		"action": "verify",
+		"name":   "payment",
+		"verify": map[string]any{"request": map[string]any{"method": "POST"}},
+	}}, execCtx)
+	assert.Equal(t, types.ResultStatusFailed, result.Status)
+	assert.Equal(t, false, result.Output.(map[string]any)["passed"])
+
+	result, _ = exec.Execute(ctx, &types.Step{ID: "stop", Type: MockExecutorType, Config: map[string]any{
+		"action": "stop",
+		"name":   "payment",
+	}}, execCtx)
+	assert.Equal(t, types.ResultStatusSuccess, result.Status, result.Error)
+	assert.Equal(t, 1, result.Output.(map[string]any)["requests"])
+
+	_, err = http.Get(url.(string) + "/pay/7")
+	assert.Error(t, err)
+}
+
+func TestMockExecutor_IsolatedByVU(t *testing.T) {
+	exec := NewMockExecutor()
+	defer exec.Cleanup(context.Background())
+	step := &types.Step{ID: "start", Type: MockExecutorType, Config: map[string]any{
+		"stubs": []any{map[string]any{"request": map[string]any{"path": "/"}, "response": map[string]any{}}},
+	}}
+
+	urls := make(map[string]bool)
+	for vu := 1; vu <= 2; vu++ {
+		execCtx := executor.NewExecutionContext()
+		execCtx.ExecutionID = "exec-1"
+		execCtx.VU = &types.VirtualUser{ID: vu}
+		result, _ := exec.Execute(context.Background(), step, execCtx)
+		require.Equal(t, types.ResultStatusSuccess, result.Status, result.Error)
+		url, _ := execCtx.GetVariable("mock.default.url")
+		urls[url.(string)] = true
+	}
+	assert.Len(t, urls, 2)
+}
+
+func TestMockExecutor_Errors(t *testing.T) {
+	exec := NewMockExecutor()
+	defer exec.Cleanup(context.Background())
+	execCtx := executor.NewExecutionContext()
+
+	cases := map[string]map[string]any{
+		"缺少桩":   {},
+		"未知操作":  {"action": "pause"},
+		"服务未运行": {"action": "stop", "name": "missing"},
+	}
+	for name, config := range cases {
+		t.Run(name, func(t *testing.T) {
+			result, err := exec.Execute(context.Background(), &types.Step{ID: "s", Type: MockExecutorType, Config: config}, execCtx)
+			require.NoError(t, err)
+			assert.Equal(t, types.ResultStatusFailed, result.Status)
+		})
+	}
+}
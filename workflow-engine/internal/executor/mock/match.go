@@ -0,0 +1,168 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	pkgExecutor "yqhp/workflow-engine/pkg/executor"
+)
+
+// RecordedRequest 收到的请求，用于匹配、模板渲染与请求记录
+type RecordedRequest struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Query   map[string][]string `json:"query,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    string              `json:"body,omitempty"`
+
+	json    any
+	jsonErr bool
+}
+
+func newRecordedRequest(r *http.Request, body []byte) *RecordedRequest {
+	return &RecordedRequest{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.Query(),
+		Headers: r.Header.Clone(),
+		Body:    string(body),
+	}
+}
+
+// JSON 返回解析后的请求体，不是合法 JSON 时返回 false
+func (r *RecordedRequest) JSON() (any, bool) {
+	if r.json == nil && !r.jsonErr {
+		if err := json.Unmarshal([]byte(r.Body), &r.json); err != nil {
+			r.jsonErr = true
+		}
+	}
+	return r.json, !r.jsonErr
+}
+
+// header 按不区分大小写的名称返回请求头
+func (r *RecordedRequest) header(name string) ([]string, bool) {
+	v, ok := r.Headers[http.CanonicalHeaderKey(name)]
+	return v, ok
+}
+
+// Match 判断请求是否满足规则，满足时返回路径参数
+func (p *RequestPattern) Match(req *RecordedRequest) (map[string]string, bool) {
+	if p.Method != "" && p.Method != "ANY" && p.Method != req.Method {
+		return nil, false
+	}
+	params, ok := p.matchPath(req.Path)
+	if !ok {
+		return nil, false
+	}
+	for name, m := range p.Query {
+		values, exists := req.Query[name]
+		if !m.matchValues(values, exists) {
+			return nil, false
+		}
+	}
+	for name, m := range p.Headers {
+		values, exists := req.header(name)
+		if !m.matchValues(values, exists) {
+			return nil, false
+		}
+	}
+	for i := range p.Body {
+		if !p.Body[i].match(req) {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// matchPath 匹配路径：path 与 path_regex 同时配置时均需满足，路径参数取自两者的命名段
+func (p *RequestPattern) matchPath(path string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for _, re := range []*regexp.Regexp{p.pathTemplate, p.pathRegex} {
+		if re == nil {
+			continue
+		}
+		m := re.FindStringSubmatch(path)
+		if m == nil {
+			return nil, false
+		}
+		for i, name := range re.SubexpNames() {
+			if name != "" {
+				params[name] = m[i]
+			}
+		}
+	}
+	return params, true
+}
+
+// matchValues 任一取值满足规则即匹配
+func (m *Matcher) matchValues(values []string, exists bool) bool {
+	if m == nil {
+		return true
+	}
+	if m.Absent {
+		return !exists
+	}
+	if !exists {
+		return false
+	}
+	for _, v := range values {
+		if m.match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matcher) match(v string) bool {
+	if m.Absent {
+		return false
+	}
+	if m.Equals != nil && v != *m.Equals {
+		return false
+	}
+	if m.NotEquals != nil && v == *m.NotEquals {
+		return false
+	}
+	if m.Contains != "" && !strings.Contains(v, m.Contains) {
+		return false
+	}
+	if m.regex != nil && !m.regex.MatchString(v) {
+		return false
+	}
+	return true
+}
+
+func (b *BodyMatcher) match(req *RecordedRequest) bool {
+	if b.JSONPath == "" {
+		return b.Matcher.match(req.Body)
+	}
+	data, ok := req.JSON()
+	if !ok {
+		return false
+	}
+	v, found := pkgExecutor.LookupJSONPath(data, b.JSONPath)
+	if b.Absent {
+		return !found
+	}
+	if !found {
+		return false
+	}
+	return b.Matcher.match(stringify(v))
+}
+
+// stringify 将 JSON 值转换为用于比较的字符串，对象与数组序列化为 JSON
+func stringify(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return val
+	case map[string]any, []any:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,7 @@
+package mock
+
+import "yqhp/workflow-engine/internal/executor"
+
+func init() {
+	executor.MustRegister(NewMockExecutor())
+}
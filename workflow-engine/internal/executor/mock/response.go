@@ -0,0 +1,202 @@
+package mock
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"yqhp/workflow-engine/internal/executor"
+)
+
+// templateContext 构建响应模板的变量上下文：
+// ${request.method}、${request.path}、${request.params.id}、${request.query.page}、
+// ${request.headers.Authorization}、${request.body}、${request.json.user.name}，
+// 以及服务变量与 ${now}、${timestamp}、${uuid}
+func templateContext(req *RecordedRequest, params map[string]string, vars map[string]any) map[string]any {
+	query := make(map[string]any, len(req.Query))
+	for k, v := range req.Query {
+		if len(v) > 0 {
+			query[k] = v[0]
+		}
+	}
+	headers := make(map[string]any, len(req.Headers))
+	for k, v := range req.Headers {
+		if len(v) > 0 {
+			headers[k] = v[0]
+			headers[strings.ToLower(k)] = v[0]
+		}
+	}
+	pathParams := make(map[string]any, len(params))
+	for k, v := range params {
+		pathParams[k] = v
+	}
+	request := map[string]any{
+		"method":  req.Method,
+		"path":    req.Path,
+		"params":  pathParams,
+		"query":   query,
+		"headers": headers,
+		"body":    req.Body,
+	}
+	if data, ok := req.JSON(); ok {
+		request["json"] = data
+	}
+
+	ctx := make(map[string]any, len(vars)+4)
+	for k, v := range vars {
+		ctx[k] = v
+	}
+	now := time.Now()
+	ctx["request"] = request
+	ctx["now"] = now.Format(time.RFC3339)
+	ctx["timestamp"] = now.UnixMilli()
+	ctx["uuid"] = uuid.NewString()
+	return ctx
+}
+
+// render 渲染响应状态、响应头与响应体
+func (r *ResponseDef) render(ctx map[string]any) (int, http.Header, []byte) {
+	resolver := executor.GetVariableResolver()
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	headers := make(http.Header, len(r.Headers)+1)
+	for k, v := range r.Headers {
+		headers.Set(k, resolver.ResolveString(v, ctx))
+	}
+
+	var body []byte
+	if r.JSON != nil {
+		body, _ = json.Marshal(renderValue(r.JSON, ctx))
+		if headers.Get("Content-Type") == "" {
+			headers.Set("Content-Type", "application/json")
+		}
+	} else {
+		body = []byte(resolver.ResolveString(r.Body, ctx))
+	}
+	return status, headers, body
+}
+
+// renderValue 解析 JSON 响应中的字符串；整个字符串为单个变量引用时保留变量的原始类型
+func renderValue(v any, ctx map[string]any) any {
+	resolver := executor.GetVariableResolver()
+	switch val := v.(type) {
+	case string:
+		if names := resolver.ExtractVariables(val); len(names) == 1 && val == "${"+names[0]+"}" {
+			if resolved := resolver.ResolveValue(names[0], ctx); resolved != nil {
+				return resolved
+			}
+		}
+		return resolver.ResolveString(val, ctx)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = renderValue(item, ctx)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = renderValue(item, ctx)
+		}
+		return out
+	}
+	return v
+}
+
+// sample 按分布采样延迟；normal 与 lognormal 的结果截断到 [0, max]
+func (d *Delay) sample(rng *rand.Rand) time.Duration {
+	if d == nil {
+		return 0
+	}
+	var ms float64
+	switch d.Distribution {
+	case DelayUniform:
+		ms = float64(d.Min)
+		if d.Max > d.Min {
+			ms += float64(rng.Intn(d.Max - d.Min + 1))
+		}
+	case DelayNormal:
+		ms = d.Mean + rng.NormFloat64()*d.StdDev
+	case DelayLogNormal:
+		ms = d.Median * math.Exp(rng.NormFloat64()*d.Sigma)
+	default:
+		ms = float64(d.Ms)
+	}
+	if d.Distribution == DelayNormal || d.Distribution == DelayLogNormal {
+		if d.Max > 0 && ms > float64(d.Max) {
+			ms = float64(d.Max)
+		}
+	}
+	if ms < 0 {
+		ms = 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// triggered 按概率判断本次请求是否注入故障
+func (f *Fault) triggered(rng *rand.Rand) bool {
+	if f == nil {
+		return false
+	}
+	if f.Rate == 0 || f.Rate >= 1 {
+		return true
+	}
+	return rng.Float64() < f.Rate
+}
+
+// inject 注入故障，返回实际写回客户端的状态码（连接类故障为 0）
+func (f *Fault) inject(w http.ResponseWriter) int {
+	switch f.Type {
+	case FaultError:
+		status := f.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		body := f.Body
+		if body == "" {
+			body = http.StatusText(status)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+		return status
+	case FaultMalformed:
+		if conn := hijack(w); conn != nil {
+			conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: " + strconv.Itoa(1<<20) + "\r\n\r\n\x00\xff garbage"))
+			conn.Close()
+		}
+	case FaultConnectionReset:
+		if conn := hijack(w); conn != nil {
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				// SO_LINGER=0 使关闭时发送 RST 而不是 FIN
+				tcp.SetLinger(0)
+			}
+			conn.Close()
+		}
+	case FaultEmptyResponse:
+		if conn := hijack(w); conn != nil {
+			conn.Close()
+		}
+	}
+	return 0
+}
+
+func hijack(w http.ResponseWriter) net.Conn {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return nil
+	}
+	return conn
+}
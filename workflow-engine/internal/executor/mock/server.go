@@ -0,0 +1,419 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPriority 未配置优先级的桩
+	defaultPriority = 5
+	// defaultJournalLimit 请求记录保留的最大条数，超出后丢弃最早的记录
+	defaultJournalLimit = 1000
+	// maxBodySize 请求体最大读取字节数
+	maxBodySize = 10 << 20
+	// adminPrefix 管理接口路径前缀
+	adminPrefix = "/__admin/"
+)
+
+// Options Mock 服务选项
+type Options struct {
+	Name         string
+	Address      string         // 监听地址，默认 127.0.0.1:0（随机端口）
+	Variables    map[string]any // 响应模板可引用的变量
+	JournalLimit int
+	OnRequest    func(JournalEntry) // 每个请求处理完成后回调
+}
+
+// JournalEntry 请求记录
+type JournalEntry struct {
+	Time    time.Time        `json:"time"`
+	Request *RecordedRequest `json:"request"`
+	StubID  string           `json:"stub_id,omitempty"` // 为空表示未匹配任何桩
+	Status  int              `json:"status"`            // 连接类故障为 0
+	Fault   string           `json:"fault,omitempty"`
+	DelayMs int64            `json:"delay_ms,omitempty"`
+}
+
+// Verification 请求校验：统计与规则匹配的请求数；未配置数量要求时至少 1 次
+type Verification struct {
+	Request RequestPattern `yaml:"request" json:"request"`
+	Count   *int           `yaml:"count,omitempty" json:"count,omitempty"`
+	AtLeast *int           `yaml:"at_least,omitempty" json:"at_least,omitempty"`
+	AtMost  *int           `yaml:"at_most,omitempty" json:"at_most,omitempty"`
+}
+
+// ParseVerifications 将步骤配置或管理接口请求中的校验规则转换为校验定义
+func ParseVerifications(raw any) ([]Verification, error) {
+	if m, ok := raw.(map[string]any); ok {
+		raw = []any{m}
+	}
+	var list []Verification
+	if err := decode(raw, &list); err != nil {
+		return nil, fmt.Errorf("解析校验规则失败: %w", err)
+	}
+	for i := range list {
+		if err := list[i].Request.compile(); err != nil {
+			return nil, fmt.Errorf("校验规则 %d: %w", i+1, err)
+		}
+	}
+	return list, nil
+}
+
+// String 返回校验规则的简要描述
+func (v *Verification) String() string {
+	method := v.Request.Method
+	if method == "" {
+		method = "ANY"
+	}
+	path := v.Request.Path
+	if path == "" {
+		path = v.Request.PathRegex
+	}
+	if path == "" {
+		path = "*"
+	}
+	return method + " " + path
+}
+
+// check 校验匹配次数
+func (v *Verification) check(n int) error {
+	switch {
+	case v.Count != nil && n != *v.Count:
+		return fmt.Errorf("%s 期望 %d 次，实际 %d 次", v, *v.Count, n)
+	case v.AtLeast != nil && n < *v.AtLeast:
+		return fmt.Errorf("%s 期望至少 %d 次，实际 %d 次", v, *v.AtLeast, n)
+	case v.AtMost != nil && n > *v.AtMost:
+		return fmt.Errorf("%s 期望至多 %d 次，实际 %d 次", v, *v.AtMost, n)
+	case v.Count == nil && v.AtLeast == nil && v.AtMost == nil && n == 0:
+		return fmt.Errorf("%s 未收到请求", v)
+	}
+	return nil
+}
+
+// Server HTTP Mock 服务
+type Server struct {
+	name         string
+	address      string
+	vars         map[string]any
+	journalLimit int
+	onRequest    func(JournalEntry)
+
+	mu        sync.Mutex
+	stubs     []*Stub
+	scenarios map[string]string
+	journal   []JournalEntry
+	rng       *rand.Rand
+
+	httpServer *http.Server
+	listener   net.Listener
+	done       chan struct{}
+}
+
+// NewServer 创建 Mock 服务
+func NewServer(stubs []Stub, opts Options) *Server {
+	if opts.Address == "" {
+		opts.Address = "127.0.0.1:0"
+	}
+	if opts.JournalLimit <= 0 {
+		opts.JournalLimit = defaultJournalLimit
+	}
+	s := &Server{
+		name:         opts.Name,
+		address:      opts.Address,
+		vars:         opts.Variables,
+		journalLimit: opts.JournalLimit,
+		onRequest:    opts.OnRequest,
+		scenarios:    make(map[string]string),
+		rng:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	s.AddStubs(stubs)
+	return s
+}
+
+// Start 开始监听，端口为 0 时随机分配
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("Mock 服务监听 %s 失败: %w", s.address, err)
+	}
+	s.listener = ln
+	s.httpServer = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.httpServer.Serve(ln)
+	}()
+	return nil
+}
+
+// Done 服务停止后关闭
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Stop 停止服务，等待进行中的请求完成直到 ctx 取消
+func (s *Server) Stop(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	err := s.httpServer.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		err = s.httpServer.Close()
+	}
+	return err
+}
+
+// Name 返回服务名称
+func (s *Server) Name() string {
+	return s.name
+}
+
+// URL 返回服务地址，如 http://127.0.0.1:38021
+func (s *Server) URL() string {
+	if s.listener == nil {
+		return ""
+	}
+	return "http://" + s.listener.Addr().String()
+}
+
+// Port 返回实际监听的端口
+func (s *Server) Port() int {
+	if s.listener == nil {
+		return 0
+	}
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// AddStubs 添加桩并按优先级排序；同优先级按添加顺序匹配
+func (s *Server) AddStubs(stubs []Stub) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range stubs {
+		stub := stubs[i]
+		if stub.Priority == 0 {
+			stub.Priority = defaultPriority
+		}
+		s.stubs = append(s.stubs, &stub)
+	}
+	sort.SliceStable(s.stubs, func(i, j int) bool {
+		return s.stubs[i].Priority < s.stubs[j].Priority
+	})
+}
+
+// Stubs 返回当前的桩定义
+func (s *Server) Stubs() []Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Stub, len(s.stubs))
+	for i, stub := range s.stubs {
+		out[i] = *stub
+	}
+	return out
+}
+
+// Requests 返回请求记录
+func (s *Server) Requests() []JournalEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]JournalEntry(nil), s.journal...)
+}
+
+// Scenarios 返回各场景的当前状态
+func (s *Server) Scenarios() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]string, len(s.scenarios))
+	for k, v := range s.scenarios {
+		out[k] = v
+	}
+	return out
+}
+
+// Reset 清空请求记录并将所有场景恢复到初始状态
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal = nil
+	s.scenarios = make(map[string]string)
+}
+
+// Verify 按规则校验请求记录，返回所有未满足的规则
+func (s *Server) Verify(list []Verification) error {
+	journal := s.Requests()
+	var errs []string
+	for i := range list {
+		v := &list[i]
+		n := 0
+		for _, entry := range journal {
+			if _, ok := v.Request.Match(entry.Request); ok {
+				n++
+			}
+		}
+		if err := v.check(n); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("请求校验失败: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ServeHTTP 匹配桩并返回响应；/__admin/ 下为管理接口
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, adminPrefix) {
+		s.serveAdmin(w, r)
+		return
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	req := newRecordedRequest(r, body)
+	entry := JournalEntry{Time: time.Now(), Request: req}
+	defer func() { s.record(entry) }()
+
+	stub, params, delay, fault := s.match(req)
+	if stub == nil {
+		entry.Status = http.StatusNotFound
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"error":  "没有匹配的桩",
+			"method": req.Method,
+			"path":   req.Path,
+		})
+		return
+	}
+	entry.StubID = stub.ID
+
+	if delay > 0 {
+		entry.DelayMs = delay.Milliseconds()
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fault {
+		entry.Fault = stub.Response.Fault.Type
+		entry.Status = stub.Response.Fault.inject(w)
+		return
+	}
+
+	status, headers, respBody := stub.Response.render(templateContext(req, params, s.vars))
+	for k, v := range headers {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(status)
+	w.Write(respBody)
+	entry.Status = status
+}
+
+// match 查找第一个匹配的桩并推进场景状态，同时采样延迟与故障
+func (s *Server) match(req *RecordedRequest) (*Stub, map[string]string, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stub := range s.stubs {
+		if sc := stub.Scenario; sc != nil && sc.RequiredState != "" && s.scenarioState(sc.Name) != sc.RequiredState {
+			continue
+		}
+		params, ok := stub.Request.Match(req)
+		if !ok {
+			continue
+		}
+		if sc := stub.Scenario; sc != nil && sc.NewState != "" {
+			s.scenarios[sc.Name] = sc.NewState
+		}
+		return stub, params, stub.Response.Delay.sample(s.rng), stub.Response.Fault.triggered(s.rng)
+	}
+	return nil, nil, 0, false
+}
+
+func (s *Server) scenarioState(name string) string {
+	if state, ok := s.scenarios[name]; ok {
+		return state
+	}
+	return ScenarioStarted
+}
+
+func (s *Server) record(entry JournalEntry) {
+	s.mu.Lock()
+	s.journal = append(s.journal, entry)
+	if over := len(s.journal) - s.journalLimit; over > 0 {
+		s.journal = append([]JournalEntry(nil), s.journal[over:]...)
+	}
+	s.mu.Unlock()
+	if s.onRequest != nil {
+		s.onRequest(entry)
+	}
+}
+
+// serveAdmin 管理接口：
+//
+//	GET    /__admin/requests   请求记录
+//	DELETE /__admin/requests   清空请求记录
+//	GET    /__admin/stubs      桩定义
+//	POST   /__admin/stubs      添加桩（桩列表或 Mock 定义）
+//	GET    /__admin/scenarios  场景状态
+//	POST   /__admin/reset      清空请求记录并重置场景
+//	POST   /__admin/verify     校验请求记录，未通过时返回 417
+func (s *Server) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	route := r.Method + " " + strings.TrimPrefix(r.URL.Path, adminPrefix)
+	switch route {
+	case "GET requests":
+		writeJSON(w, http.StatusOK, s.Requests())
+	case "DELETE requests":
+		s.mu.Lock()
+		s.journal = nil
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case "GET stubs":
+		writeJSON(w, http.StatusOK, s.Stubs())
+	case "POST stubs":
+		data, _ := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		def, err := ParseDefinition(data)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		s.AddStubs(def.Stubs)
+		writeJSON(w, http.StatusCreated, map[string]any{"added": len(def.Stubs)})
+	case "GET scenarios":
+		writeJSON(w, http.StatusOK, s.Scenarios())
+	case "POST reset":
+		s.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	case "POST verify":
+		var raw any
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&raw); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "请求体不是合法的 JSON"})
+			return
+		}
+		list, err := ParseVerifications(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		if err := s.Verify(list); err != nil {
+			writeJSON(w, http.StatusExpectationFailed, map[string]any{"passed": false, "error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"passed": true})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "未知的管理接口: " + route})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
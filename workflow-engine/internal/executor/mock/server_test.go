@@ -0,0 +1,296 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, definition string) *Server {
+	t.Helper()
+	def, err := ParseDefinition([]byte(definition))
+	require.NoError(t, err)
+	srv := NewServer(def.Stubs, Options{Name: "test", Variables: map[string]any{"region": "cn"}})
+	require.NoError(t, srv.Start())
+	t.Cleanup(func() { srv.Stop(context.Background()) })
+	return srv
+}
+
+func doRequest(t *testing.T, method, url, body string, headers map[string]string) (int, string, http.Header) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data), resp.Header
+}
+
+func TestParseDefinition(t *testing.T) {
+	def, err := ParseDefinition([]byte(`
+name: payment
+port: 8089
+stubs:
+  - request: {method: GET, path: /a}
+    response: {status: 200, body: ok}
+  - id: custom
+    request: {path: "/b/{id}"}
+    response: {status: 204}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "payment", def.Name)
+	assert.Equal(t, 8089, def.Port)
+	require.Len(t, def.Stubs, 2)
+	assert.Equal(t, "stub_1", def.Stubs[0].ID)
+	assert.Equal(t, "custom", def.Stubs[1].ID)
+
+	// 顶层为桩列表
+	def, err = ParseDefinition([]byte(`
+- request: {path: /a}
+  response: {body: ok}
+`))
+	require.NoError(t, err)
+	assert.Len(t, def.Stubs, 1)
+}
+
+func TestParseDefinition_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"重复 ID": `
+- {id: a, request: {path: /a}, response: {}}
+- {id: a, request: {path: /b}, response: {}}`,
+		"非法正则": `
+- request: {path_regex: "/a/(("}
+  response: {}`,
+		"未知故障类型": `
+- request: {path: /a}
+  response: {fault: {type: boom}}`,
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseDefinition([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestServer_Matching(t *testing.T) {
+	srv := startServer(t, `
+- id: user
+  request:
+    method: GET
+    path: /users/{id}
+  response:
+    json: {id: "${request.params.id}", region: "${region}"}
+- id: search_admin
+  priority: 1
+  request:
+    method: GET
+    path: /search
+    query: {role: admin}
+  response: {body: admin}
+- id: search
+  request:
+    method: GET
+    path: /search
+    query:
+      role: {absent: true}
+  response: {body: all}
+- id: create
+  request:
+    method: POST
+    path: /orders
+    headers:
+      Authorization: {regex: "^Bearer .+"}
+    body:
+      - jsonpath: $.amount
+        equals: "100"
+  response:
+    status: 201
+    headers: {X-Order: "${request.json.sku}"}
+    body: "created ${request.json.sku}"
+- id: files
+  request:
+    path: /files/*
+  response: {body: "${request.path}"}
+`)
+
+	status, body, header := doRequest(t, http.MethodGet, srv.URL()+"/users/42", "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.JSONEq(t, `{"id":"42","region":"cn"}`, body)
+
+	_, body, _ = doRequest(t, http.MethodGet, srv.URL()+"/search?role=admin", "", nil)
+	assert.Equal(t, "admin", body)
+	_, body, _ = doRequest(t, http.MethodGet, srv.URL()+"/search", "", nil)
+	assert.Equal(t, "all", body)
+	status, _, _ = doRequest(t, http.MethodGet, srv.URL()+"/search?role=user", "", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, body, header = doRequest(t, http.MethodPost, srv.URL()+"/orders", `{"amount":100,"sku":"A1"}`,
+		map[string]string{"Authorization": "Bearer token"})
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created A1", body)
+	assert.Equal(t, "A1", header.Get("X-Order"))
+
+	status, _, _ = doRequest(t, http.MethodPost, srv.URL()+"/orders", `{"amount":100}`, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	_, body, _ = doRequest(t, http.MethodDelete, srv.URL()+"/files/a/b.txt", "", nil)
+	assert.Equal(t, "/files/a/b.txt", body)
+}
+
+func TestServer_JSONKeepsValueType(t *testing.T) {
+	srv := startServer(t, `
+- request: {method: POST, path: /echo}
+  response:
+    json: {count: "${request.json.count}", label: "n=${request.json.count}"}
+`)
+	_, body, _ := doRequest(t, http.MethodPost, srv.URL()+"/echo", `{"count":3}`, nil)
+	assert.JSONEq(t, `{"count":3,"label":"n=3"}`, body)
+}
+
+func TestServer_Scenario(t *testing.T) {
+	srv := startServer(t, `
+- request: {method: GET, path: /order}
+  scenario: {name: order, required_state: started}
+  response: {body: pending}
+- request: {method: POST, path: /order/pay}
+  scenario: {name: order, new_state: paid}
+  response: {status: 204}
+- request: {method: GET, path: /order}
+  scenario: {name: order, required_state: paid}
+  response: {body: paid}
+`)
+	_, body, _ := doRequest(t, http.MethodGet, srv.URL()+"/order", "", nil)
+	assert.Equal(t, "pending", body)
+	doRequest(t, http.MethodPost, srv.URL()+"/order/pay", "", nil)
+	_, body, _ = doRequest(t, http.MethodGet, srv.URL()+"/order", "", nil)
+	assert.Equal(t, "paid", body)
+	assert.Equal(t, map[string]string{"order": "paid"}, srv.Scenarios())
+
+	srv.Reset()
+	_, body, _ = doRequest(t, http.MethodGet, srv.URL()+"/order", "", nil)
+	assert.Equal(t, "pending", body)
+}
+
+func TestServer_Faults(t *testing.T) {
+	srv := startServer(t, `
+- request: {path: /error}
+  response:
+    fault: {type: error, status: 503, body: down}
+- request: {path: /reset}
+  response:
+    fault: {type: connection_reset}
+- request: {path: /empty}
+  response:
+    fault: {type: empty_response}
+`)
+	status, body, _ := doRequest(t, http.MethodGet, srv.URL()+"/error", "", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "down", body)
+
+	for _, path := range []string{"/reset", "/empty"} {
+		_, err := http.Get(srv.URL() + path)
+		assert.Error(t, err, path)
+	}
+
+	// 客户端可能对幂等请求自动重试，只校验记录中的故障类型
+	faults := make(map[string]int)
+	for _, entry := range srv.Requests() {
+		faults[entry.Fault] = entry.Status
+	}
+	assert.Equal(t, map[string]int{
+		FaultError:           http.StatusServiceUnavailable,
+		FaultConnectionReset: 0,
+		FaultEmptyResponse:   0,
+	}, faults)
+}
+
+func TestServer_Delay(t *testing.T) {
+	srv := startServer(t, `
+- request: {path: /slow}
+  response:
+    delay: 50
+    body: ok
+`)
+	start := time.Now()
+	_, body, _ := doRequest(t, http.MethodGet, srv.URL()+"/slow", "", nil)
+	assert.Equal(t, "ok", body)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, int64(50), srv.Requests()[0].DelayMs)
+}
+
+func TestDelay_Sample(t *testing.T) {
+	srv := NewServer(nil, Options{})
+	uniform := &Delay{Distribution: DelayUniform, Min: 10, Max: 20}
+	normal := &Delay{Distribution: DelayNormal, Mean: 10, StdDev: 100, Max: 30}
+	for i := 0; i < 100; i++ {
+		d := uniform.sample(srv.rng)
+		assert.True(t, d >= 10*time.Millisecond && d <= 20*time.Millisecond, d)
+		d = normal.sample(srv.rng)
+		assert.True(t, d >= 0 && d <= 30*time.Millisecond, d)
+	}
+}
+
+func TestServer_Verify(t *testing.T) {
+	srv := startServer(t, `
+- request: {method: POST, path: /notify}
+  response: {status: 202}
+`)
+	doRequest(t, http.MethodPost, srv.URL()+"/notify", `{"type":"paid"}`, nil)
+	doRequest(t, http.MethodPost, srv.URL()+"/notify", `{"type":"refund"}`, nil)
+
+	list, err := ParseVerifications([]any{
+		map[string]any{"request": map[string]any{"method": "POST", "path": "/notify"}, "count": 2},
+		map[string]any{"request": map[string]any{"path": "/notify", "body": []any{
+			map[string]any{"jsonpath": "$.type", "equals": "paid"},
+		}}},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, srv.Verify(list))
+
+	list, err = ParseVerifications(map[string]any{"request": map[string]any{"path": "/other"}})
+	require.NoError(t, err)
+	err = srv.Verify(list)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/other")
+}
+
+func TestServer_Admin(t *testing.T) {
+	srv := startServer(t, `
+- request: {path: /a}
+  response: {body: a}
+`)
+	status, _, _ := doRequest(t, http.MethodPost, srv.URL()+"/__admin/stubs",
+		`[{"id":"b","request":{"path":"/b"},"response":{"body":"b"}}]`, nil)
+	assert.Equal(t, http.StatusCreated, status)
+	_, body, _ := doRequest(t, http.MethodGet, srv.URL()+"/b", "", nil)
+	assert.Equal(t, "b", body)
+
+	_, body, _ = doRequest(t, http.MethodGet, srv.URL()+"/__admin/requests", "", nil)
+	var journal []JournalEntry
+	require.NoError(t, json.Unmarshal([]byte(body), &journal))
+	require.Len(t, journal, 1)
+	assert.Equal(t, "b", journal[0].StubID)
+
+	status, _, _ = doRequest(t, http.MethodPost, srv.URL()+"/__admin/verify", `{"request":{"path":"/a"}}`, nil)
+	assert.Equal(t, http.StatusExpectationFailed, status)
+	status, _, _ = doRequest(t, http.MethodPost, srv.URL()+"/__admin/verify", `{"request":{"path":"/b"},"count":1}`, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _, _ = doRequest(t, http.MethodPost, srv.URL()+"/__admin/reset", "", nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Empty(t, srv.Requests())
+}
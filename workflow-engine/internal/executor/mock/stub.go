@@ -0,0 +1,341 @@
+// Package mock 提供内置的 HTTP Mock 服务：按方法、路径、查询参数、请求头与请求体匹配桩，
+// 使用与工作流相同的 ${...} 变量解析渲染响应，支持延迟与故障注入、有状态场景以及请求记录与校验。
+// 既可通过 workflow-engine mock 命令独立运行，也可作为 mock 步骤在工作流中启动和停止。
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScenarioStarted 场景的初始状态
+const ScenarioStarted = "started"
+
+// Stub 桩定义：请求匹配规则与响应
+type Stub struct {
+	ID       string          `yaml:"id" json:"id"`
+	Name     string          `yaml:"name,omitempty" json:"name,omitempty"`
+	Priority int             `yaml:"priority,omitempty" json:"priority,omitempty"` // 数值越小越优先，默认 5
+	Request  RequestPattern  `yaml:"request" json:"request"`
+	Response ResponseDef     `yaml:"response" json:"response"`
+	Scenario *ScenarioConfig `yaml:"scenario,omitempty" json:"scenario,omitempty"`
+}
+
+// ScenarioConfig 有状态场景：仅当场景处于 required_state 时匹配，匹配后切换到 new_state
+type ScenarioConfig struct {
+	Name          string `yaml:"name" json:"name"`
+	RequiredState string `yaml:"required_state,omitempty" json:"required_state,omitempty"`
+	NewState      string `yaml:"new_state,omitempty" json:"new_state,omitempty"`
+}
+
+// RequestPattern 请求匹配规则，未配置的字段不参与匹配
+type RequestPattern struct {
+	Method    string              `yaml:"method,omitempty" json:"method,omitempty"`         // 为空或 ANY 匹配任意方法
+	Path      string              `yaml:"path,omitempty" json:"path,omitempty"`             // 支持 {name} 路径参数与末尾 *
+	PathRegex string              `yaml:"path_regex,omitempty" json:"path_regex,omitempty"` // 命名捕获组作为路径参数
+	Query     map[string]*Matcher `yaml:"query,omitempty" json:"query,omitempty"`
+	Headers   map[string]*Matcher `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body      []BodyMatcher       `yaml:"body,omitempty" json:"body,omitempty"`
+
+	pathTemplate *regexp.Regexp
+	pathRegex    *regexp.Regexp
+}
+
+// Matcher 值匹配规则。直接写字符串等价于 equals
+type Matcher struct {
+	Equals    *string `yaml:"equals,omitempty" json:"equals,omitempty"`
+	NotEquals *string `yaml:"not_equals,omitempty" json:"not_equals,omitempty"`
+	Contains  string  `yaml:"contains,omitempty" json:"contains,omitempty"`
+	Regex     string  `yaml:"regex,omitempty" json:"regex,omitempty"`
+	Absent    bool    `yaml:"absent,omitempty" json:"absent,omitempty"` // 要求参数不存在
+
+	regex *regexp.Regexp
+}
+
+// UnmarshalJSON 支持字符串简写
+func (m *Matcher) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		m.Equals = &s
+		return nil
+	}
+	type plain Matcher
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// BodyMatcher 请求体匹配规则：配置 jsonpath 时匹配对应字段，否则匹配整个请求体
+type BodyMatcher struct {
+	JSONPath string `yaml:"jsonpath,omitempty" json:"jsonpath,omitempty"`
+	Matcher  `yaml:",inline"`
+}
+
+// UnmarshalJSON 展开内嵌的 Matcher 字段
+func (b *BodyMatcher) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		JSONPath string `json:"jsonpath"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	b.JSONPath = raw.JSONPath
+	return json.Unmarshal(data, &b.Matcher)
+}
+
+// ResponseDef 响应定义。body 与 json 二选一，字符串中的 ${...} 在请求时解析
+type ResponseDef struct {
+	Status  int               `yaml:"status,omitempty" json:"status,omitempty"` // 默认 200
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty" json:"body,omitempty"`
+	JSON    any               `yaml:"json,omitempty" json:"json,omitempty"`
+	Delay   *Delay            `yaml:"delay,omitempty" json:"delay,omitempty"`
+	Fault   *Fault            `yaml:"fault,omitempty" json:"fault,omitempty"`
+}
+
+// 延迟分布
+const (
+	DelayFixed     = "fixed"
+	DelayUniform   = "uniform"
+	DelayNormal    = "normal"
+	DelayLogNormal = "lognormal"
+)
+
+// Delay 响应延迟（毫秒）。直接写数字等价于固定延迟
+type Delay struct {
+	Distribution string  `yaml:"distribution,omitempty" json:"distribution,omitempty"` // fixed / uniform / normal / lognormal
+	Ms           int     `yaml:"ms,omitempty" json:"ms,omitempty"`                     // fixed
+	Min          int     `yaml:"min,omitempty" json:"min,omitempty"`                   // uniform
+	Max          int     `yaml:"max,omitempty" json:"max,omitempty"`                   // uniform；normal / lognormal 的上限
+	Mean         float64 `yaml:"mean,omitempty" json:"mean,omitempty"`                 // normal
+	StdDev       float64 `yaml:"stddev,omitempty" json:"stddev,omitempty"`             // normal
+	Median       float64 `yaml:"median,omitempty" json:"median,omitempty"`             // lognormal
+	Sigma        float64 `yaml:"sigma,omitempty" json:"sigma,omitempty"`               // lognormal
+}
+
+// UnmarshalJSON 支持数字简写
+func (d *Delay) UnmarshalJSON(data []byte) error {
+	var ms float64
+	if err := json.Unmarshal(data, &ms); err == nil {
+		*d = Delay{Distribution: DelayFixed, Ms: int(ms)}
+		return nil
+	}
+	type plain Delay
+	return json.Unmarshal(data, (*plain)(d))
+}
+
+// 故障类型
+const (
+	FaultError           = "error"            // 返回错误状态码
+	FaultConnectionReset = "connection_reset" // 重置连接
+	FaultEmptyResponse   = "empty_response"   // 不返回任何数据直接关闭连接
+	FaultMalformed       = "malformed"        // 返回无法解析的响应
+)
+
+// Fault 故障注入：按 rate 概率触发，未触发时返回正常响应
+type Fault struct {
+	Type   string  `yaml:"type" json:"type"`
+	Rate   float64 `yaml:"rate,omitempty" json:"rate,omitempty"`     // 0~1，默认 1
+	Status int     `yaml:"status,omitempty" json:"status,omitempty"` // error 类型的状态码，默认 500
+	Body   string  `yaml:"body,omitempty" json:"body,omitempty"`
+}
+
+// Definition Mock 服务定义文件
+type Definition struct {
+	Name  string `yaml:"name,omitempty" json:"name,omitempty"`
+	Port  int    `yaml:"port,omitempty" json:"port,omitempty"`
+	Stubs []Stub `yaml:"stubs" json:"stubs"`
+}
+
+// LoadDefinition 读取 YAML 或 JSON 格式的 Mock 定义文件
+func LoadDefinition(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 Mock 定义失败: %w", err)
+	}
+	return ParseDefinition(data)
+}
+
+// ParseDefinition 解析 Mock 定义，顶层可以是定义对象或桩列表
+func ParseDefinition(data []byte) (*Definition, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析 Mock 定义失败: %w", err)
+	}
+	if list, ok := raw.([]any); ok {
+		raw = map[string]any{"stubs": list}
+	}
+	def := &Definition{}
+	if err := decode(raw, def); err != nil {
+		return nil, fmt.Errorf("解析 Mock 定义失败: %w", err)
+	}
+	if err := compileStubs(def.Stubs); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// ParseStubs 将步骤配置中的桩列表转换为桩定义
+func ParseStubs(raw any) ([]Stub, error) {
+	var stubs []Stub
+	if err := decode(raw, &stubs); err != nil {
+		return nil, fmt.Errorf("解析桩定义失败: %w", err)
+	}
+	if err := compileStubs(stubs); err != nil {
+		return nil, err
+	}
+	return stubs, nil
+}
+
+// decode 经 JSON 中转将 YAML / 步骤配置中的通用结构转换为目标类型
+func decode(raw, out any) error {
+	data, err := json.Marshal(normalize(raw))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// normalize 将 YAML 解码出的 map[any]any 转换为可 JSON 序列化的 map[string]any
+func normalize(v any) any {
+	switch val := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = normalize(item)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(val))
+		for k, item := range val {
+			m[k] = normalize(item)
+		}
+		return m
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = normalize(item)
+		}
+		return out
+	}
+	return v
+}
+
+// compileStubs 校验桩定义并预编译正则表达式
+func compileStubs(stubs []Stub) error {
+	ids := make(map[string]bool, len(stubs))
+	for i := range stubs {
+		s := &stubs[i]
+		if s.ID == "" {
+			s.ID = fmt.Sprintf("stub_%d", i+1)
+		}
+		if ids[s.ID] {
+			return fmt.Errorf("桩 ID 重复: %s", s.ID)
+		}
+		ids[s.ID] = true
+		if err := s.Request.compile(); err != nil {
+			return fmt.Errorf("桩 %s: %w", s.ID, err)
+		}
+		if err := s.Response.validate(); err != nil {
+			return fmt.Errorf("桩 %s: %w", s.ID, err)
+		}
+		if s.Scenario != nil && s.Scenario.Name == "" {
+			return fmt.Errorf("桩 %s: 场景名称不能为空", s.ID)
+		}
+	}
+	return nil
+}
+
+func (p *RequestPattern) compile() error {
+	p.Method = strings.ToUpper(p.Method)
+	if p.Path != "" {
+		p.pathTemplate = compilePathTemplate(p.Path)
+	}
+	if p.PathRegex != "" {
+		re, err := regexp.Compile(p.PathRegex)
+		if err != nil {
+			return fmt.Errorf("path_regex 无效: %w", err)
+		}
+		p.pathRegex = re
+	}
+	for name, m := range p.Query {
+		if err := m.compile(); err != nil {
+			return fmt.Errorf("查询参数 %s: %w", name, err)
+		}
+	}
+	for name, m := range p.Headers {
+		if err := m.compile(); err != nil {
+			return fmt.Errorf("请求头 %s: %w", name, err)
+		}
+	}
+	for i := range p.Body {
+		if err := p.Body[i].compile(); err != nil {
+			return fmt.Errorf("请求体规则 %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// pathParam 匹配路径模板中的 {name} 段
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// compilePathTemplate 将 /users/{id}/* 形式的路径模板转换为正则：{name} 匹配单个路径段，末尾 * 匹配任意后缀
+func compilePathTemplate(path string) *regexp.Regexp {
+	prefix, wildcard := strings.CutSuffix(path, "*")
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, loc := range pathParam.FindAllStringSubmatchIndex(prefix, -1) {
+		b.WriteString(regexp.QuoteMeta(prefix[last:loc[0]]))
+		b.WriteString("(?P<" + prefix[loc[2]:loc[3]] + ">[^/]+)")
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(prefix[last:]))
+	if wildcard {
+		b.WriteString(".*")
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func (m *Matcher) compile() error {
+	if m == nil || m.Regex == "" {
+		return nil
+	}
+	re, err := regexp.Compile(m.Regex)
+	if err != nil {
+		return fmt.Errorf("正则表达式无效: %w", err)
+	}
+	m.regex = re
+	return nil
+}
+
+func (r *ResponseDef) validate() error {
+	if r.Body != "" && r.JSON != nil {
+		return fmt.Errorf("响应的 body 与 json 不能同时配置")
+	}
+	if d := r.Delay; d != nil {
+		switch d.Distribution {
+		case "", DelayFixed, DelayUniform, DelayNormal, DelayLogNormal:
+		default:
+			return fmt.Errorf("不支持的延迟分布: %s", d.Distribution)
+		}
+		if d.Distribution == DelayUniform && d.Max < d.Min {
+			return fmt.Errorf("均匀分布延迟的 max 不能小于 min")
+		}
+	}
+	if f := r.Fault; f != nil {
+		switch f.Type {
+		case FaultError, FaultConnectionReset, FaultEmptyResponse, FaultMalformed:
+		default:
+			return fmt.Errorf("不支持的故障类型: %s", f.Type)
+		}
+		if f.Rate < 0 || f.Rate > 1 {
+			return fmt.Errorf("故障概率必须在 0~1 之间")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// SandboxCgroupEnv 指定已委派 pids 控制器的 cgroup 目录，沙箱在其下为每次执行创建子 cgroup
+const SandboxCgroupEnv = "WORKFLOW_ENGINE_SANDBOX_CGROUP"
+
+const (
+	cgroupMount      = "/sys/fs/cgroup"
+	cgroupParentName = "workflow-engine-sandbox"
+)
+
+var cgroupParent = struct {
+	once sync.Once
+	dir  string
+	err  error
+}{}
+
+var cgroupSeq atomic.Uint64
+
+// pidsCgroup 一次沙箱执行的 cgroup，pids.max 限制其中所有进程（含线程）的总数，
+// 不受宿主机上同一用户其他进程的影响
+type pidsCgroup struct {
+	dir string
+}
+
+// newPidsCgroup 创建进程数上限为 maxProcs 的 cgroup，环境不支持或无权限时返回错误
+func newPidsCgroup(maxProcs int) (*pidsCgroup, error) {
+	cgroupParent.once.Do(func() {
+		cgroupParent.dir, cgroupParent.err = initCgroupParent()
+	})
+	if cgroupParent.err != nil {
+		return nil, cgroupParent.err
+	}
+	dir := filepath.Join(cgroupParent.dir, fmt.Sprintf("run-%d-%d", os.Getpid(), cgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pids.max"), []byte(strconv.Itoa(maxProcs)), 0o644); err != nil {
+		_ = os.Remove(dir)
+		return nil, err
+	}
+	return &pidsCgroup{dir: dir}, nil
+}
+
+// initCgroupParent 定位沙箱 cgroup 的父目录：优先使用 SandboxCgroupEnv；cgroup v1 建在 pids 层级中
+// 当前进程所在 cgroup 之下，cgroup v2 建在当前进程所在 cgroup 的同级（根 cgroup 时建在其下）
+func initCgroupParent() (string, error) {
+	if dir := os.Getenv(SandboxCgroupEnv); dir != "" {
+		if err := enablePidsController(dir); err != nil {
+			return "", err
+		}
+		pruneCgroups(dir)
+		return dir, nil
+	}
+
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	var v1Path, v2Path string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2Path = parts[2]
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			if controller == "pids" {
+				v1Path = parts[2]
+			}
+		}
+	}
+
+	// 容器内 /proc/self/cgroup 可能是宿主机视角的路径，挂载点下不存在时以挂载点为当前 cgroup
+	ownCgroup := func(mount, path string) string {
+		if dir := filepath.Join(mount, path); isDir(dir) {
+			return dir
+		}
+		return mount
+	}
+	var dir string
+	switch {
+	case v1Path != "" && isDir(filepath.Join(cgroupMount, "pids")):
+		dir = filepath.Join(ownCgroup(filepath.Join(cgroupMount, "pids"), v1Path), cgroupParentName)
+	case v2Path != "" && fileExists(filepath.Join(cgroupMount, "cgroup.controllers")):
+		base := ownCgroup(cgroupMount, v2Path)
+		if base != cgroupMount {
+			base = filepath.Dir(base)
+		}
+		if err := enablePidsController(base); err != nil {
+			return "", err
+		}
+		dir = filepath.Join(base, cgroupParentName)
+	default:
+		return "", errors.New("未找到 pids cgroup 控制器")
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", err
+	}
+	if err := enablePidsController(dir); err != nil {
+		return "", err
+	}
+	pruneCgroups(dir)
+	return dir, nil
+}
+
+// enablePidsController cgroup v2 下为子 cgroup 启用 pids 控制器，v1 无需启用
+func enablePidsController(dir string) error {
+	control := filepath.Join(dir, "cgroup.subtree_control")
+	if !fileExists(control) {
+		if !isDir(dir) {
+			return fmt.Errorf("cgroup 目录不存在: %s", dir)
+		}
+		return nil
+	}
+	if enabled, err := os.ReadFile(control); err == nil && containsField(string(enabled), "pids") {
+		return nil
+	}
+	if err := os.WriteFile(control, []byte("+pids"), 0o644); err != nil {
+		return fmt.Errorf("启用 pids 控制器失败: %w", err)
+	}
+	return nil
+}
+
+// pruneCgroups 清理引擎异常退出后遗留的空 cgroup
+func pruneCgroups(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), "run-") {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// add 将进程加入 cgroup，之后其派生的进程同样计入
+func (c *pidsCgroup) add(pid int) error {
+	return os.WriteFile(filepath.Join(c.dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0o644)
+}
+
+// limitHit 是否有创建进程的请求因达到上限被拒绝
+func (c *pidsCgroup) limitHit() bool {
+	data, err := os.ReadFile(filepath.Join(c.dir, "pids.events"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "max" {
+			n, _ := strconv.Atoi(fields[1])
+			return n > 0
+		}
+	}
+	return false
+}
+
+// destroy 终止 cgroup 中残留的进程并删除 cgroup
+func (c *pidsCgroup) destroy() {
+	for attempt := 0; attempt < 20; attempt++ {
+		err := os.Remove(c.dir)
+		if err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		c.kill()
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (c *pidsCgroup) kill() {
+	if os.WriteFile(filepath.Join(c.dir, "cgroup.kill"), []byte("1"), 0o644) == nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, "cgroup.procs"))
+	if err != nil {
+		return
+	}
+	for _, field := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func containsField(s, field string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package sandbox
+
+import "errors"
+
+// 非 Linux 平台没有 cgroup，不限制进程数
+type pidsCgroup struct{}
+
+func newPidsCgroup(maxProcs int) (*pidsCgroup, error) {
+	return nil, errors.New("当前平台不支持 cgroup")
+}
+
+func (c *pidsCgroup) add(pid int) error { return nil }
+
+func (c *pidsCgroup) limitHit() bool { return false }
+
+func (c *pidsCgroup) destroy() {}
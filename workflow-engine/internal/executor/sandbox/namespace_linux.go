@@ -0,0 +1,90 @@
+package sandbox
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"syscall"
+
+	"yqhp/workflow-engine/pkg/logger"
+)
+
+// namespacesUnavailable 创建命名空间失败后置位，之后不再尝试
+var namespacesUnavailable atomic.Bool
+
+func namespacesSupported() bool {
+	return !namespacesUnavailable.Load()
+}
+
+func markNamespacesUnsupported(err error) {
+	if namespacesUnavailable.CompareAndSwap(false, true) {
+		logger.Warn("[Sandbox] 无法创建命名空间，降级为仅资源限制: %v", err)
+	}
+}
+
+// isIsolationStartError 创建用户命名空间被内核或容器策略拒绝
+func isIsolationStartError(err error) bool {
+	return errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EINVAL) ||
+		errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EACCES)
+}
+
+// rootfsScript 在新的挂载命名空间中构建最小根文件系统并 pivot_root 进入：系统目录、PATH 中的目录与
+// 少量 /etc 文件只读绑定，/dev 仅含常用设备，/tmp 为独立的 tmpfs，只有工作区可写，宿主机其余文件不可见。
+// $1 为用作挂载点的空目录，$2 为工作区；任一步骤失败时输出 setupFailedMarker 并以 125 退出
+const rootfsScript = `r=$1 ws=$2 d=$PWD
+(
+set -e
+mount --make-rprivate /
+mount -t tmpfs -o mode=755,size=16m sandbox "$r"
+bind() {
+	if [ -L "$1" ]; then mkdir -p "$r${1%/*}"; ln -s "$(readlink "$1")" "$r$1"; return; fi
+	if [ -d "$1" ]; then mkdir -p "$r$1"; else mkdir -p "$r${1%/*}"; : > "$r$1"; fi
+	mount --rbind "$1" "$r$1"
+	[ "$2" = rw ] || mount -o remount,bind,ro,nosuid,nodev "$r$1"
+}
+for p in /usr /bin /sbin /lib /lib32 /lib64 /libx32 /etc/passwd /etc/group /etc/hosts /etc/resolv.conf \
+	/etc/nsswitch.conf /etc/ld.so.cache /etc/localtime /etc/ssl /etc/ca-certificates /etc/pki /etc/alternatives; do
+	if [ -e "$p" ] || [ -L "$p" ]; then bind "$p"; fi
+done
+IFS=:
+for p in $PATH; do
+	if [ -d "$p" ] && [ ! -e "$r$p" ]; then bind "$p"; fi
+done
+unset IFS
+mkdir -p "$r/dev" "$r/proc" "$r/tmp" "$r/.oldroot"
+for n in null zero full random urandom tty; do : > "$r/dev/$n"; mount --bind "/dev/$n" "$r/dev/$n"; done
+ln -s /proc/self/fd "$r/dev/fd"
+ln -s /proc/self/fd/0 "$r/dev/stdin"
+ln -s /proc/self/fd/1 "$r/dev/stdout"
+ln -s /proc/self/fd/2 "$r/dev/stderr"
+mount -t proc proc "$r/proc" 2>/dev/null || true
+mount -t tmpfs -o mode=1777,size=64m tmp "$r/tmp"
+bind "$ws" rw
+mount -o remount,bind,ro "$r"
+) || { echo "` + setupFailedMarker + `" >&2; exit 125; }
+cd "$r" && PATH=$PATH:/usr/sbin:/sbin pivot_root . .oldroot && umount -l /.oldroot && cd "$d" ||
+	{ echo "` + setupFailedMarker + `" >&2; exit 125; }
+`
+
+// dropCapsExec 以命名空间内的 root 完成挂载后丢弃全部能力再执行目标程序
+const dropCapsExec = `if command -v setpriv >/dev/null 2>&1; then exec setpriv --inh-caps=-all --bounding-set=-all -- "$@"; fi; exec "$@"`
+
+// sysProcAttr 隔离时创建独立的用户、挂载、PID、IPC、UTS 命名空间，禁止网络时另建空的网络命名空间。
+// 运行用户映射为命名空间内的 root，以便构建根文件系统，执行目标程序前丢弃能力
+func sysProcAttr(isolate, allowNetwork bool) *syscall.SysProcAttr {
+	attr := defaultProcAttr()
+	attr.Pdeathsig = syscall.SIGKILL
+	if !isolate {
+		return attr
+	}
+	attr.Setpgid = false
+	attr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+		syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !allowNetwork {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	attr.GidMappingsEnableSetgroups = false
+	return attr
+}
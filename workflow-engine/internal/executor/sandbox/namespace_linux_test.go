@@ -0,0 +1,56 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// withoutNamespaces 模拟内核或容器禁止创建命名空间的环境
+func withoutNamespaces(t *testing.T) {
+	prev := namespacesUnavailable.Swap(true)
+	t.Cleanup(func() { namespacesUnavailable.Store(prev) })
+}
+
+func TestRun_NamespacesUnavailable(t *testing.T) {
+	withoutNamespaces(t)
+	disabled := false
+
+	cases := []struct {
+		name    string
+		policy  *types.SandboxPolicy
+		refused bool
+	}{
+		{name: "默认策略拒绝执行", policy: nil, refused: true},
+		{name: "禁止网络且未启用命名空间拒绝执行", policy: &types.SandboxPolicy{Namespaces: &disabled}, refused: true},
+		{name: "允许网络但要求命名空间拒绝执行", policy: &types.SandboxPolicy{AllowNetwork: true}, refused: true},
+		{name: "显式允许降级", policy: &types.SandboxPolicy{AllowDegraded: true}},
+		{name: "关闭命名空间且允许网络", policy: &types.SandboxPolicy{Namespaces: &disabled, AllowNetwork: true}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sb, err := New(tc.policy, t.Name())
+			require.NoError(t, err)
+			defer os.RemoveAll(sb.WorkDir())
+
+			result := sb.Run(context.Background(), sb.WorkDir(), "bash", "-c", "echo ok > out.txt")
+			assert.False(t, result.Isolated)
+			if tc.refused {
+				assert.ErrorIs(t, result.Err, ErrIsolationUnavailable)
+				assert.Len(t, result.Violations, 1)
+				assert.NoFileExists(t, filepath.Join(sb.WorkDir(), "out.txt"), "拒绝执行时命令不应运行")
+				return
+			}
+			require.NoError(t, result.Err)
+			assert.Empty(t, result.Violations)
+			assert.FileExists(t, filepath.Join(sb.WorkDir(), "out.txt"))
+			assert.NotEmpty(t, result.Warnings)
+		})
+	}
+}
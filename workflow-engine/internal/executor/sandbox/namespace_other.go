@@ -0,0 +1,21 @@
+//go:build !linux
+
+package sandbox
+
+import "syscall"
+
+// 非 Linux 平台不支持命名空间隔离，仅使用资源限制
+func namespacesSupported() bool { return false }
+
+func markNamespacesUnsupported(err error) {}
+
+func isIsolationStartError(err error) bool { return false }
+
+const (
+	rootfsScript = ""
+	dropCapsExec = `exec "$@"`
+)
+
+func sysProcAttr(isolate, allowNetwork bool) *syscall.SysProcAttr {
+	return defaultProcAttr()
+}
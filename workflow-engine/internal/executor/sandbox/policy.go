@@ -0,0 +1,140 @@
+package sandbox
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// alwaysDeniedCommands 启用沙箱时始终禁止的命令
+var alwaysDeniedCommands = []string{
+	"sudo", "su", "doas", "shutdown", "reboot", "halt", "poweroff", "init",
+	"mount", "umount", "chroot", "nsenter", "unshare", "insmod", "rmmod", "modprobe",
+	"mkfs", "fdisk", "parted", "iptables", "nft",
+}
+
+// shellKeywords 出现在命令位置但不是命令本身的 Shell 关键字
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"for": true, "while": true, "until": true, "do": true, "done": true,
+	"case": true, "esac": true, "in": true, "select": true, "function": true,
+	"time": true, "!": true, "{": true, "}": true, "[[": true, "]]": true,
+}
+
+// CheckCommand 按命令允许/禁止列表检查 Shell 命令中出现的每个命令，返回违规说明。
+// 解析为尽力而为的词法切分，允许列表配合资源限制与命名空间隔离使用，而不是唯一防线。
+func (s *Sandbox) CheckCommand(command string) []string {
+	if !s.Enabled() {
+		return nil
+	}
+	allowed := make(map[string]bool, len(s.policy.AllowedCommands))
+	for _, name := range s.policy.AllowedCommands {
+		allowed[name] = true
+	}
+	denied := make(map[string]bool, len(alwaysDeniedCommands)+len(s.policy.DeniedCommands))
+	for _, name := range alwaysDeniedCommands {
+		denied[name] = true
+	}
+	for _, name := range s.policy.DeniedCommands {
+		denied[name] = true
+	}
+
+	var violations []string
+	seen := make(map[string]bool)
+	for _, name := range commandNames(command) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch {
+		case denied[name] || denied[strings.SplitN(name, ".", 2)[0]]:
+			violations = append(violations, fmt.Sprintf("命令 %s 被沙箱策略禁止", name))
+		case len(allowed) > 0 && !allowed[name]:
+			violations = append(violations, fmt.Sprintf("命令 %s 不在沙箱允许列表中", name))
+		}
+	}
+	return violations
+}
+
+// commandNames 提取 Shell 命令中处于命令位置的程序名（管道、列表、子 Shell、命令替换）
+func commandNames(command string) []string {
+	var names []string
+	for _, segment := range splitSegments(command) {
+		fields := strings.Fields(segment)
+		for _, field := range fields {
+			field = strings.Trim(field, "\"'")
+			if field == "for" || field == "select" || field == "case" {
+				break // 循环与分支头部不含命令
+			}
+			if field == "" || shellKeywords[field] || isAssignment(field) {
+				continue
+			}
+			if redirect := strings.TrimLeft(field, "0123456789&"); strings.HasPrefix(redirect, "<") || strings.HasPrefix(redirect, ">") {
+				break
+			}
+			names = append(names, filepath.Base(field))
+			break
+		}
+	}
+	return names
+}
+
+// splitSegments 按 Shell 控制符切分命令：单引号内不切分，双引号内仅按命令替换切分，跳过算术展开
+func splitSegments(command string) []string {
+	var segments []string
+	var cur strings.Builder
+	inSingle, inDouble := false, false
+	flush := func() {
+		if strings.TrimSpace(cur.String()) != "" {
+			segments = append(segments, cur.String())
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == '\'' && !inDouble:
+			inSingle = !inSingle
+			cur.WriteByte(c)
+		case inSingle:
+			cur.WriteByte(c)
+		case c == '"':
+			inDouble = !inDouble
+			cur.WriteByte(c)
+		case c == '$' && strings.HasPrefix(command[i:], "$(("):
+			end := strings.Index(command[i:], "))")
+			if end < 0 {
+				end = len(command) - i - 2
+			}
+			cur.WriteString(command[i : i+end+2])
+			i += end + 1
+		case c == '$' && strings.HasPrefix(command[i:], "$("):
+			flush()
+			i++
+		case c == '&' && (i > 0 && strings.IndexByte("<>", command[i-1]) >= 0 || strings.HasPrefix(command[i:], "&>")):
+			cur.WriteByte(c) // 重定向 2>&1、&>file
+		case c == '`':
+			flush()
+		case !inDouble && strings.IndexByte(";|&\n()", c) >= 0:
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return segments
+}
+
+// isAssignment 判断是否为命令前的环境变量赋值（FOO=bar）
+func isAssignment(field string) bool {
+	eq := strings.IndexByte(field, '=')
+	if eq <= 0 {
+		return false
+	}
+	for _, r := range field[:eq] {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
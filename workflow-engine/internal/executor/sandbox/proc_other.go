@@ -0,0 +1,25 @@
+//go:build !unix
+
+package sandbox
+
+import (
+	"os/exec"
+	"syscall"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+func defaultProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+func signalViolation(sig syscall.Signal, p *types.SandboxPolicy) string {
+	return ""
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// defaultProcAttr 沙箱进程单独成组，超时后整组终止
+func defaultProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup 终止沙箱进程及其派生的所有子进程
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// signalViolation 资源限制触发的信号对应的违规说明
+func signalViolation(sig syscall.Signal, p *types.SandboxPolicy) string {
+	switch sig {
+	case syscall.SIGXCPU:
+		return fmt.Sprintf("超出 CPU 时间限制 (%d 秒)", p.CPUSeconds)
+	case syscall.SIGXFSZ:
+		return fmt.Sprintf("超出文件大小限制 (%d MB)", p.MaxFileSizeMB)
+	}
+	return ""
+}
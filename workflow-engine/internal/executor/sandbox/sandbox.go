@@ -0,0 +1,482 @@
+// Package sandbox 为 shell_exec / code_execute 工具提供受限的进程执行环境：
+// 每次执行独立的工作区目录、CPU/内存/文件大小限制、按 cgroup 统计的进程数限制、默认禁止网络，
+// Linux 上使用命名空间隔离并切换到只有工作区可写的最小根文件系统；隔离不可用时，
+// 除非策略显式允许降级，否则拒绝执行。违反策略的情况记录在执行结果中。
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"yqhp/workflow-engine/pkg/logger"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+const (
+	// maxCaptureBytes 单个输出流的最大采集字节数，超出部分丢弃
+	maxCaptureBytes = 1024 * 1024
+	// workspaceIdleTTL 工作区闲置超过该时间后清理
+	workspaceIdleTTL = 24 * time.Hour
+	// killGracePeriod 取消后等待输出管道关闭的时间
+	killGracePeriod = 2 * time.Second
+	// setupFailedMarker 构建隔离根文件系统失败时输出到 stderr 的标记
+	setupFailedMarker = "sandbox: 文件系统隔离初始化失败"
+)
+
+// ErrPathOutsideWorkspace 路径不在沙箱工作区内
+var ErrPathOutsideWorkspace = errors.New("路径不在沙箱工作区内")
+
+// ErrIsolationUnavailable 策略要求命名空间隔离但当前环境不可用，且策略未允许降级
+var ErrIsolationUnavailable = errors.New("命名空间隔离不可用")
+
+// ========== Context 辅助函数 ==========
+
+type policyKeyType struct{}
+
+var policyKey = policyKeyType{}
+
+// WithPolicy 将沙箱策略注入到 context 中，nil 表示使用默认策略
+func WithPolicy(ctx context.Context, policy *types.SandboxPolicy) context.Context {
+	return context.WithValue(ctx, policyKey, policy)
+}
+
+// PolicyFromContext 获取 context 中的沙箱策略，未设置时返回 nil（默认策略）
+func PolicyFromContext(ctx context.Context) *types.SandboxPolicy {
+	policy, _ := ctx.Value(policyKey).(*types.SandboxPolicy)
+	return policy
+}
+
+// ========== 沙箱 ==========
+
+// Sandbox 一次执行的沙箱环境
+type Sandbox struct {
+	policy  *types.SandboxPolicy
+	workDir string
+}
+
+// Result 沙箱内进程的执行结果
+type Result struct {
+	Stdout     string
+	Stderr     string
+	ExitCode   int
+	Err        error
+	Duration   time.Duration
+	TimedOut   bool
+	Isolated   bool     // 是否使用了命名空间与文件系统隔离
+	Violations []string // 违反沙箱策略的情况
+	Warnings   []string // 沙箱降级等提示
+}
+
+// New 按策略创建沙箱，同一 executionID 共享工作区目录
+func New(policy *types.SandboxPolicy, executionID string) (*Sandbox, error) {
+	s := &Sandbox{policy: policy.WithDefaults()}
+	if s.policy.Disabled {
+		return s, nil
+	}
+	dir, err := workspaceDir(executionID)
+	if err != nil {
+		return nil, err
+	}
+	s.workDir = dir
+	return s, nil
+}
+
+// Enabled 是否启用沙箱
+func (s *Sandbox) Enabled() bool {
+	return !s.policy.Disabled
+}
+
+// Policy 填充默认值后的沙箱策略
+func (s *Sandbox) Policy() *types.SandboxPolicy {
+	return s.policy
+}
+
+// WorkDir 工作区目录，未启用沙箱时为空
+func (s *Sandbox) WorkDir() string {
+	return s.workDir
+}
+
+// ResolveDir 解析工作目录：相对路径基于工作区，绝对路径必须位于工作区内
+func (s *Sandbox) ResolveDir(dir string) (string, error) {
+	if !s.Enabled() {
+		return dir, nil
+	}
+	if dir == "" {
+		return s.workDir, nil
+	}
+	abs := dir
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(s.workDir, abs)
+	}
+	abs = filepath.Clean(abs)
+	rel, err := filepath.Rel(s.workDir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrPathOutsideWorkspace, dir)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return "", err
+	}
+	return abs, nil
+}
+
+// CheckLanguage 检查 code_execute 的语言是否在允许列表中
+func (s *Sandbox) CheckLanguage(language string) []string {
+	if !s.Enabled() || len(s.policy.AllowedLanguages) == 0 {
+		return nil
+	}
+	for _, lang := range s.policy.AllowedLanguages {
+		if lang == language {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("语言 %s 不在沙箱允许列表中 (%s)", language, strings.Join(s.policy.AllowedLanguages, ", "))}
+}
+
+// Run 在沙箱中执行命令，dir 为 ResolveDir 解析后的目录
+func (s *Sandbox) Run(ctx context.Context, dir string, name string, args ...string) *Result {
+	if !s.Enabled() {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Dir = dir
+		return run(ctx, cmd, nil, nil, false)
+	}
+
+	var warnings []string
+	cg, err := newPidsCgroup(s.policy.MaxProcesses)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("无法创建 cgroup，未限制进程数: %v", err))
+	} else {
+		defer cg.destroy()
+	}
+
+	isolate := *s.policy.Namespaces && namespacesSupported()
+	if !isolate && s.requiresIsolation() {
+		return s.refuse(warnings)
+	}
+	result := s.run(ctx, dir, name, args, isolate, cg)
+	if isolate && isIsolationFailure(result) {
+		// 内核或容器禁止创建命名空间或挂载，策略允许时降级为仅资源限制
+		markNamespacesUnsupported(isolationFailureCause(result))
+		isolate = false
+		if s.requiresIsolation() {
+			return s.refuse(warnings)
+		}
+		result = s.run(ctx, dir, name, args, false, cg)
+	}
+	if !isolate {
+		warning := "命名空间隔离不可用，沙箱进程可访问宿主机文件系统"
+		if !s.policy.AllowNetwork {
+			warning += "，且无法禁止网络访问"
+		}
+		warnings = append(warnings, warning)
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+	result.Violations = append(result.Violations, s.detectViolations(result)...)
+	if cg != nil && cg.limitHit() {
+		result.Violations = appendUnique(result.Violations, fmt.Sprintf("超出进程数限制 (%d)", s.policy.MaxProcesses))
+	}
+	return result
+}
+
+// requiresIsolation 策略要求命名空间隔离或禁止网络（只能通过网络命名空间实现），且未显式允许降级
+func (s *Sandbox) requiresIsolation() bool {
+	return (*s.policy.Namespaces || !s.policy.AllowNetwork) && !s.policy.AllowDegraded
+}
+
+// refuse 无法满足策略要求的隔离时不执行命令，以违规结果返回
+func (s *Sandbox) refuse(warnings []string) *Result {
+	violation := "命名空间隔离不可用，无法满足沙箱策略的文件系统与网络隔离要求，已拒绝执行"
+	if !*s.policy.Namespaces {
+		violation = "沙箱策略禁止网络访问，但未启用命名空间隔离，已拒绝执行"
+	}
+	return &Result{
+		Err:        ErrIsolationUnavailable,
+		ExitCode:   -1,
+		Violations: []string{violation},
+		Warnings:   warnings,
+	}
+}
+
+// run 构建并执行一次受限命令
+func (s *Sandbox) run(ctx context.Context, dir, name string, args []string, isolate bool, cg *pidsCgroup) *Result {
+	gateR, gateW, err := os.Pipe()
+	if err != nil {
+		return &Result{Err: err, ExitCode: -1}
+	}
+	cmd := s.command(ctx, dir, name, args, isolate)
+	cmd.ExtraFiles = []*os.File{gateR}
+	return run(ctx, cmd, gateW, cg, isolate)
+}
+
+// command 构建受限命令：等待加入 cgroup，隔离时切换到最小根文件系统，再通过 ulimit 设置资源限制并 exec 目标程序
+func (s *Sandbox) command(ctx context.Context, dir, name string, args []string, isolate bool) *exec.Cmd {
+	p := s.policy
+	script := "read -r -u 3 _ 2>/dev/null; exec 3<&-\n"
+	if isolate {
+		script += rootfsScript
+	}
+	script += fmt.Sprintf("shift 2; ulimit -t %d 2>/dev/null; ulimit -d %d 2>/dev/null; ulimit -f %d 2>/dev/null\n",
+		p.CPUSeconds, p.MemoryMB*1024, p.MaxFileSizeMB*1024)
+	if isolate {
+		script += dropCapsExec
+	} else {
+		script += `exec "$@"`
+	}
+	cmdArgs := append([]string{"-c", script, "sandbox", rootfsMountpoint(), s.workDir, name}, args...)
+	cmd := exec.CommandContext(ctx, "bash", cmdArgs...)
+	cmd.Dir = dir
+	cmd.Env = s.env()
+	cmd.SysProcAttr = sysProcAttr(isolate, p.AllowNetwork)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = killGracePeriod
+	return cmd
+}
+
+// isIsolationFailure 命名空间创建被拒绝，或命名空间内构建根文件系统失败
+func isIsolationFailure(r *Result) bool {
+	if r.Err != nil && isIsolationStartError(r.Err) {
+		return true
+	}
+	return r.ExitCode == 125 && strings.Contains(r.Stderr, setupFailedMarker)
+}
+
+func isolationFailureCause(r *Result) error {
+	if r.Err != nil && isIsolationStartError(r.Err) {
+		return r.Err
+	}
+	return errors.New(strings.TrimSpace(r.Stderr))
+}
+
+// env 沙箱进程的环境变量：仅保留 PATH 与语言设置，HOME/TMPDIR 指向工作区
+func (s *Sandbox) env() []string {
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + s.workDir,
+		"TMPDIR=" + s.workDir,
+		"PWD=" + s.workDir,
+	}
+	for _, key := range []string{"LANG", "LC_ALL", "TZ"} {
+		if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+	return env
+}
+
+// detectViolations 根据退出信号与错误输出识别资源超限与网络访问
+func (s *Sandbox) detectViolations(r *Result) []string {
+	p := s.policy
+	var violations []string
+	add := func(v string) {
+		violations = appendUnique(violations, v)
+	}
+	cpu := fmt.Sprintf("超出 CPU 时间限制 (%d 秒)", p.CPUSeconds)
+	fileSize := fmt.Sprintf("超出文件大小限制 (%d MB)", p.MaxFileSizeMB)
+
+	if r.TimedOut {
+		add("超出执行时间限制")
+	}
+	if v := signalViolation(signalOf(r), p); v != "" {
+		add(v)
+	}
+
+	// 子进程被信号终止时 bash 会继续执行后续命令，需要结合错误输出识别
+	stderr := r.Stderr
+	if containsAny(stderr, "CPU time limit exceeded", "Cputime limit exceeded") {
+		add(cpu)
+	}
+	if containsAny(stderr, "File size limit exceeded", "Filesize limit exceeded") {
+		add(fileSize)
+	}
+	if containsAny(stderr, "MemoryError", "Cannot allocate memory", "out of memory", "std::bad_alloc") {
+		add(fmt.Sprintf("超出内存限制 (%d MB)", p.MemoryMB))
+	}
+	if containsAny(stderr, "fork: retry", "fork: Resource temporarily unavailable", "can't start new thread") {
+		add(fmt.Sprintf("超出进程数限制 (%d)", p.MaxProcesses))
+	}
+	if r.Isolated && !p.AllowNetwork && containsAny(stderr,
+		"Network is unreachable", "Temporary failure in name resolution", "Could not resolve host",
+		"Name or service not known", "ENETUNREACH", "EAI_AGAIN") {
+		add("沙箱禁止访问网络")
+	}
+	return violations
+}
+
+// signalOf 进程或其子进程被信号终止时返回该信号（bash 以 128+信号值退出）
+func signalOf(r *Result) syscall.Signal {
+	if r.ExitCode > 128 && r.ExitCode < 128+65 {
+		return syscall.Signal(r.ExitCode - 128)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(r.Err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return status.Signal()
+		}
+	}
+	return 0
+}
+
+// run 执行命令。gate 不为 nil 时进程启动后先加入 cgroup，再关闭 gate 让其继续执行
+func run(ctx context.Context, cmd *exec.Cmd, gate *os.File, cg *pidsCgroup, isolated bool) *Result {
+	var stdout, stderr limitedBuffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Start()
+	for _, f := range cmd.ExtraFiles {
+		f.Close()
+	}
+	if err == nil && cg != nil {
+		if err = cg.add(cmd.Process.Pid); err != nil {
+			err = fmt.Errorf("加入 cgroup 失败: %w", err)
+			_ = killProcessGroup(cmd)
+		}
+	}
+	if gate != nil {
+		gate.Close()
+	}
+	if cmd.Process != nil {
+		if waitErr := cmd.Wait(); err == nil {
+			err = waitErr
+		}
+	}
+	result := &Result{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Err:      err,
+		Duration: time.Since(start),
+		TimedOut: ctx.Err() == context.DeadlineExceeded,
+		Isolated: isolated,
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	return result
+}
+
+// limitedBuffer 超过 maxCaptureBytes 后丢弃写入，避免输出撑爆内存
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := maxCaptureBytes - b.buf.Len(); remain > 0 {
+		if len(p) > remain {
+			b.buf.Write(p[:remain])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n...(输出超过采集上限，已丢弃)"
+	}
+	return b.buf.String()
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, existing := range list {
+		if existing == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// ========== 工作区 ==========
+
+var workspaces = struct {
+	sync.Mutex
+	lastPrune time.Time
+}{}
+
+// workspaceRoot 所有沙箱工作区的根目录
+func workspaceRoot() string {
+	return filepath.Join(os.TempDir(), "workflow-engine-sandbox")
+}
+
+// rootfsMountpoint 隔离时挂载最小根文件系统的空目录，挂载只在各自的命名空间内可见，可被并发执行共用
+func rootfsMountpoint() string {
+	dir := filepath.Join(workspaceRoot(), ".rootfs")
+	_ = os.MkdirAll(dir, 0o755)
+	return dir
+}
+
+// workspaceDir 获取（必要时创建）执行对应的工作区，并定期清理闲置的工作区
+func workspaceDir(executionID string) (string, error) {
+	root := workspaceRoot()
+	name := sanitizeName(executionID)
+	if name == "" {
+		name = "default"
+	}
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("创建沙箱工作区失败: %w", err)
+	}
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+
+	workspaces.Lock()
+	defer workspaces.Unlock()
+	if now.Sub(workspaces.lastPrune) > time.Hour {
+		workspaces.lastPrune = now
+		pruneWorkspaces(root, dir, now)
+	}
+	return dir, nil
+}
+
+func pruneWorkspaces(root, keep string, now time.Time) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		if path == keep || !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < workspaceIdleTTL {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			logger.Warn("[Sandbox] 清理工作区失败: %s, %v", path, err)
+		}
+	}
+}
+
+// sanitizeName 仅保留字母、数字、- 和 _，避免执行 ID 逃逸出工作区根目录
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+func TestCommandNames(t *testing.T) {
+	cases := map[string][]string{
+		"FOO=1 /usr/bin/env python3 x.py | wc -l": {"env", "wc"},
+		"ls -la":                               {"ls"},
+		"cd dir && make || echo 'a; sudo'":     {"cd", "make", "echo"},
+		"echo \"$(curl -s x)\" 2>&1 > out.txt": {"echo", "curl"},
+		"for f in *.txt; do cat $f; done":      {"cat"},
+		"echo $((1+2)) `whoami`":               {"echo", "whoami"},
+	}
+	for command, want := range cases {
+		assert.Equal(t, want, commandNames(command), command)
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	sb, err := New(&types.SandboxPolicy{AllowedCommands: []string{"ls", "grep"}, DeniedCommands: []string{"rm"}}, t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(sb.WorkDir())
+
+	assert.Empty(t, sb.CheckCommand("ls -la | grep go"))
+	assert.Equal(t, []string{"命令 cat 不在沙箱允许列表中"}, sb.CheckCommand("cat a.txt | grep x"))
+	assert.Equal(t, []string{"命令 rm 被沙箱策略禁止"}, sb.CheckCommand("ls; rm -r -f /"))
+	assert.Equal(t, []string{"命令 mkfs.ext4 被沙箱策略禁止"}, sb.CheckCommand("mkfs.ext4 /dev/sda"))
+
+	disabled, err := New(&types.SandboxPolicy{Disabled: true}, t.Name())
+	require.NoError(t, err)
+	assert.Empty(t, disabled.CheckCommand("sudo ls"))
+}
+
+func TestResolveDir(t *testing.T) {
+	sb, err := New(nil, t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(sb.WorkDir())
+
+	dir, err := sb.ResolveDir("")
+	require.NoError(t, err)
+	assert.Equal(t, sb.WorkDir(), dir)
+
+	dir, err = sb.ResolveDir("sub/dir")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(sb.WorkDir(), "sub", "dir"), dir)
+	assert.DirExists(t, dir)
+
+	_, err = sb.ResolveDir("../other")
+	assert.ErrorIs(t, err, ErrPathOutsideWorkspace)
+	_, err = sb.ResolveDir("/etc")
+	assert.ErrorIs(t, err, ErrPathOutsideWorkspace)
+}
+
+func TestRun_Limits(t *testing.T) {
+	sb, err := New(&types.SandboxPolicy{MaxFileSizeMB: 1, AllowDegraded: true}, t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(sb.WorkDir())
+
+	result := sb.Run(context.Background(), sb.WorkDir(), "bash", "-c", "pwd; echo $HOME")
+	require.NoError(t, result.Err)
+	assert.Equal(t, sb.WorkDir()+"\n"+sb.WorkDir()+"\n", result.Stdout)
+	assert.Empty(t, result.Violations)
+
+	result = sb.Run(context.Background(), sb.WorkDir(), "bash", "-c", "head -c 2000000 /dev/zero > big.bin")
+	assert.Contains(t, result.Violations, "超出文件大小限制 (1 MB)")
+	info, err := os.Stat(filepath.Join(sb.WorkDir(), "big.bin"))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(1024*1024))
+}
+
+func TestRun_FilesystemIsolation(t *testing.T) {
+	sb, err := New(nil, t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(sb.WorkDir())
+
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("host"), 0o644))
+
+	result := sb.Run(context.Background(), sb.WorkDir(), "bash", "-c",
+		"cat "+secret+"; touch /usr/sandbox-probe; echo ok > out.txt; ls /")
+	if !result.Isolated {
+		t.Skipf("命名空间不可用: %v", result.Warnings)
+	}
+	assert.Contains(t, result.Stderr, "No such file or directory")
+	assert.Contains(t, result.Stderr, "Read-only file system")
+	assert.NotContains(t, result.Stdout, "host")
+	assert.NoFileExists(t, "/usr/sandbox-probe")
+	data, err := os.ReadFile(filepath.Join(sb.WorkDir(), "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok\n", string(data))
+}
+
+func TestRun_ProcessLimitPerExecution(t *testing.T) {
+	sb, err := New(&types.SandboxPolicy{MaxProcesses: 4, AllowDegraded: true}, t.Name())
+	require.NoError(t, err)
+	defer os.RemoveAll(sb.WorkDir())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	result := sb.Run(ctx, sb.WorkDir(), "bash", "-c", "for i in 1 2 3 4 5 6; do sleep 5 & done; wait")
+	for _, w := range result.Warnings {
+		if strings.Contains(w, "cgroup") {
+			t.Skip(w)
+		}
+	}
+	assert.Contains(t, result.Violations, "超出进程数限制 (4)")
+}
+
+func TestWithDefaults(t *testing.T) {
+	var policy *types.SandboxPolicy
+	p := policy.WithDefaults()
+	assert.False(t, p.Disabled)
+	assert.False(t, p.AllowNetwork)
+	assert.Equal(t, types.DefaultSandboxCPUSeconds, p.CPUSeconds)
+	assert.True(t, *p.Namespaces)
+
+	assert.Error(t, (&types.SandboxPolicy{MemoryMB: -1}).Validate())
+	assert.Error(t, (&types.SandboxPolicy{AllowedLanguages: []string{"ruby"}}).Validate())
+}
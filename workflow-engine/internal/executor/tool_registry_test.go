@@ -6,7 +6,6 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 
 	"yqhp/workflow-engine/pkg/types"
 )
@@ -37,26 +36,22 @@ func TestNewToolRegistry(t *testing.T) {
 func TestToolRegistry_Register(t *testing.T) {
 	r := NewToolRegistry()
 
-	err := r.Register(&mockTool{name: "test_tool"})
-	require.NoError(t, err)
+	r.Register(&mockTool{name: "test_tool"})
 	assert.True(t, r.Has("test_tool"))
 }
 
 func TestToolRegistry_Register_NilTool(t *testing.T) {
 	r := NewToolRegistry()
-	err := r.Register(nil)
-	assert.Error(t, err)
+	r.Register(nil)
+	assert.Zero(t, r.Count())
 }
 
 func TestToolRegistry_Register_DuplicateName(t *testing.T) {
 	r := NewToolRegistry()
 
-	err := r.Register(&mockTool{name: "dup"})
-	require.NoError(t, err)
-
-	err = r.Register(&mockTool{name: "dup"})
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "已注册")
+	r.Register(&mockTool{name: "dup"})
+	r.Register(&mockTool{name: "dup"})
+	assert.Equal(t, 1, r.Count())
 }
 
 func TestToolRegistry_Get(t *testing.T) {
@@ -101,8 +96,7 @@ func TestDefaultToolRegistry_ConvenienceFunctions(t *testing.T) {
 	DefaultToolRegistry = NewToolRegistry()
 	defer func() { DefaultToolRegistry = original }()
 
-	err := RegisterTool(&mockTool{name: "global_tool"})
-	require.NoError(t, err)
+	RegisterTool(&mockTool{name: "global_tool"})
 
 	assert.True(t, HasTool("global_tool"))
 
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/internal/executor/sandbox"
+	"yqhp/workflow-engine/pkg/types"
 )
 
 func TestHTTPTool_Definition(t *testing.T) {
@@ -291,6 +294,41 @@ func TestJSONParseTool_ImplementsToolInterface(t *testing.T) {
 	var _ executor.Tool = (*JSONParseTool)(nil)
 }
 
+// ==================== 沙箱测试 ====================
+
+func TestShellExecTool_SandboxViolations(t *testing.T) {
+	policy := &types.SandboxPolicy{AllowedCommands: []string{"echo"}}
+	ctx := sandbox.WithPolicy(context.Background(), policy)
+	execCtx := executor.NewExecutionContext().WithExecutionID(t.Name())
+	tool := &ShellExecTool{}
+
+	result, err := tool.Execute(ctx, `{"command":"echo ok"}`, execCtx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError || !strings.Contains(result.Content, "ok") {
+		t.Fatalf("expected success, got: %s", result.Content)
+	}
+
+	result, _ = tool.Execute(ctx, `{"command":"echo ok | cat"}`, execCtx)
+	if !result.IsError || len(result.Violations) != 1 {
+		t.Fatalf("expected one violation, got %v: %s", result.Violations, result.Content)
+	}
+
+	result, _ = tool.Execute(ctx, `{"command":"echo ok","working_dir":"/"}`, execCtx)
+	if !result.IsError || len(result.Violations) != 1 {
+		t.Fatalf("expected working_dir violation, got %v", result.Violations)
+	}
+}
+
+func TestCodeExecuteTool_AllowedLanguages(t *testing.T) {
+	ctx := sandbox.WithPolicy(context.Background(), &types.SandboxPolicy{AllowedLanguages: []string{"python"}})
+	result, _ := (&CodeExecuteTool{}).Execute(ctx, `{"language":"javascript","code":"1+1"}`, nil)
+	if !result.IsError || len(result.Violations) != 1 {
+		t.Fatalf("expected language violation, got %v", result.Violations)
+	}
+}
+
 // ==================== RegisterAll 测试 ====================
 
 func TestRegisterAll(t *testing.T) {
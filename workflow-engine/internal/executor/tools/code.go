@@ -17,7 +17,7 @@ type CodeExecuteTool struct{}
 func (t *CodeExecuteTool) Definition() *types.ToolDefinition {
 	return &types.ToolDefinition{
 		Name:        "code_execute",
-		Description: "执行代码片段。支持 Python 和 JavaScript (Node.js)。用于数据计算、格式转换、文本处理等场景。代码在沙箱中运行，有 30 秒超时限制。代码的最后一个表达式的值会自动作为结果返回（无需手动 print/console.log）。",
+		Description: "执行代码片段。支持 Python 和 JavaScript (Node.js)。用于数据计算、格式转换、文本处理等场景。代码在沙箱中运行，默认禁止访问网络，有 30 秒超时限制。代码的最后一个表达式的值会自动作为结果返回（无需手动 print/console.log）。",
 		Parameters: json.RawMessage(`{
 			"type": "object",
 			"properties": {
@@ -64,24 +64,44 @@ func (t *CodeExecuteTool) Execute(ctx context.Context, arguments string, execCtx
 		return types.NewErrorResult(fmt.Sprintf("不支持的语言: %s，仅支持 python 和 javascript", args.Language)), nil
 	}
 
+	sb, err := newSandbox(ctx, execCtx)
+	if err != nil {
+		return types.NewErrorResult(fmt.Sprintf("沙箱初始化失败: %v", err)), nil
+	}
+	if violations := sb.CheckLanguage(args.Language); len(violations) > 0 {
+		return violationResult(violations), nil
+	}
+	dir, err := sb.ResolveDir("")
+	if err != nil {
+		return types.NewErrorResult(fmt.Sprintf("沙箱初始化失败: %v", err)), nil
+	}
+
 	logger.Debug("[CodeExecute] 准备执行 %s 代码, 命令: %s, 代码内容:\n%s", args.Language, cmd, args.Code)
 
 	execCtx2, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	execCmd := ExecCommandContext(execCtx2, cmd, cmdArgs...)
-	output, err := execCmd.CombinedOutput()
-	if err != nil {
-		result := string(output)
-		if result == "" {
-			result = err.Error()
+	run := sb.Run(execCtx2, dir, cmd, cmdArgs...)
+	output := run.Stdout + run.Stderr
+	if len(run.Violations) > 0 {
+		var result strings.Builder
+		writeSandboxInfo(&result, sb, run)
+		result.WriteString(output)
+		logger.Debug("[CodeExecute] %s 代码违反沙箱策略: %v", args.Language, run.Violations)
+		toolResult := types.NewErrorResult(fmt.Sprintf("执行失败:\n%s", result.String()))
+		toolResult.Violations = run.Violations
+		return toolResult, nil
+	}
+	if run.Err != nil {
+		if output == "" {
+			output = run.Err.Error()
 		}
-		logger.Debug("[CodeExecute] %s 代码执行失败: %s", args.Language, result)
-		return types.NewErrorResult(fmt.Sprintf("执行失败:\n%s", result)), nil
+		logger.Debug("[CodeExecute] %s 代码执行失败: %s", args.Language, output)
+		return types.NewErrorResult(fmt.Sprintf("执行失败:\n%s", output)), nil
 	}
 
-	logger.Debug("[CodeExecute] %s 代码执行成功, 输出:\n%s", args.Language, string(output))
-	return types.NewToolResult(string(output)), nil
+	logger.Debug("[CodeExecute] %s 代码执行成功, 输出:\n%s", args.Language, output)
+	return types.NewToolResult(output), nil
 }
 
 // wrapPythonCode wraps user Python code so that the last expression's value
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/internal/executor/sandbox"
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// newSandbox 按 context 中的沙箱策略创建沙箱，同一执行的工具调用共享工作区
+func newSandbox(ctx context.Context, execCtx *executor.ExecutionContext) (*sandbox.Sandbox, error) {
+	executionID := ""
+	if execCtx != nil {
+		executionID = execCtx.ExecutionID
+	}
+	return sandbox.New(sandbox.PolicyFromContext(ctx), executionID)
+}
+
+// violationResult 违反沙箱策略、未执行命令时的错误结果
+func violationResult(violations []string) *types.ToolResult {
+	result := types.NewErrorResult("沙箱策略拒绝执行:\n- " + strings.Join(violations, "\n- "))
+	result.Violations = violations
+	return result
+}
+
+// writeSandboxInfo 输出沙箱工作区、隔离方式以及违规与降级提示
+func writeSandboxInfo(b *strings.Builder, sb *sandbox.Sandbox, run *sandbox.Result) {
+	if !sb.Enabled() {
+		return
+	}
+	mode := "命名空间与文件系统隔离"
+	if !run.Isolated {
+		mode = "仅资源限制"
+	}
+	b.WriteString(fmt.Sprintf("沙箱: %s，工作区 %s\n", mode, sb.WorkDir()))
+	for _, v := range run.Violations {
+		b.WriteString(fmt.Sprintf("沙箱违规: %s\n", v))
+	}
+	for _, w := range run.Warnings {
+		b.WriteString(fmt.Sprintf("沙箱提示: %s\n", w))
+	}
+}
@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,6 +10,7 @@ import (
 	"time"
 
 	"yqhp/workflow-engine/internal/executor"
+	"yqhp/workflow-engine/internal/executor/sandbox"
 	"yqhp/workflow-engine/pkg/types"
 )
 
@@ -19,14 +19,14 @@ const (
 	shellMaxOutputBytes = 128 * 1024 // 128KB
 )
 
-// ShellExecTool 在受控环境中执行 Shell 命令，支持 bash。
+// ShellExecTool 在沙箱中执行 Shell 命令，支持 bash。
 // 参考 https://github.com/cloudwego/eino-ext/components/tool/commandline
 type ShellExecTool struct{}
 
 func (t *ShellExecTool) Definition() *types.ToolDefinition {
 	return &types.ToolDefinition{
 		Name:        "shell_exec",
-		Description: "在服务器上执行 Shell 命令（bash）。适用于：系统信息查询、文件操作、网络诊断、文本处理（grep/awk/sed）、调用 CLI 工具等。命令有 60 秒超时限制，输出上限 128KB。",
+		Description: "执行 Shell 命令（bash）。适用于：文件操作、文本处理（grep/awk/sed）、调用 CLI 工具等。命令在沙箱工作区中运行，默认禁止访问网络，有 60 秒超时限制，输出上限 128KB。",
 		Parameters: json.RawMessage(`{
 			"type": "object",
 			"properties": {
@@ -36,7 +36,7 @@ func (t *ShellExecTool) Definition() *types.ToolDefinition {
 				},
 				"working_dir": {
 					"type": "string",
-					"description": "工作目录（可选，默认为系统临时目录）"
+					"description": "工作目录（可选，相对沙箱工作区，默认为工作区根目录）"
 				},
 				"timeout": {
 					"type": "integer",
@@ -81,6 +81,19 @@ func (t *ShellExecTool) Execute(ctx context.Context, arguments string, execCtx *
 		}
 	}
 
+	sb, err := newSandbox(ctx, execCtx)
+	if err != nil {
+		return types.NewErrorResult(fmt.Sprintf("沙箱初始化失败: %v", err)), nil
+	}
+	if violations := sb.CheckCommand(args.Command); len(violations) > 0 {
+		return violationResult(violations), nil
+	}
+
+	dir, err := shellWorkingDir(sb, args.WorkingDir)
+	if err != nil {
+		return violationResult([]string{err.Error()}), nil
+	}
+
 	timeout := shellTimeout
 	if args.Timeout > 0 {
 		if args.Timeout > 300 {
@@ -92,39 +105,20 @@ func (t *ShellExecTool) Execute(ctx context.Context, arguments string, execCtx *
 	execCtx2, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	cmd := ExecCommandContext(execCtx2, "bash", "-c", args.Command)
-
-	if args.WorkingDir != "" {
-		absDir, err := filepath.Abs(args.WorkingDir)
-		if err == nil {
-			if info, statErr := os.Stat(absDir); statErr == nil && info.IsDir() {
-				cmd.Dir = absDir
-			}
-		}
-	}
-	if cmd.Dir == "" {
-		cmd.Dir = os.TempDir()
-	}
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	startTime := time.Now()
-	err := cmd.Run()
-	elapsed := time.Since(startTime)
+	run := sb.Run(execCtx2, dir, "bash", "-c", args.Command)
 
 	var result strings.Builder
-	result.WriteString(fmt.Sprintf("耗时: %s\n", elapsed.Round(time.Millisecond)))
+	result.WriteString(fmt.Sprintf("耗时: %s\n", run.Duration.Round(time.Millisecond)))
 
-	if err != nil {
-		result.WriteString(fmt.Sprintf("退出状态: 错误 (%v)\n", err))
+	if run.Err != nil {
+		result.WriteString(fmt.Sprintf("退出状态: 错误 (%v)\n", run.Err))
 	} else {
 		result.WriteString("退出状态: 成功\n")
 	}
+	writeSandboxInfo(&result, sb, run)
 
-	stdoutStr := stdout.String()
-	stderrStr := stderr.String()
+	stdoutStr := run.Stdout
+	stderrStr := run.Stderr
 
 	if len(stdoutStr) > shellMaxOutputBytes {
 		stdoutStr = stdoutStr[:shellMaxOutputBytes] + "\n...(stdout 已截断)"
@@ -146,5 +140,25 @@ func (t *ShellExecTool) Execute(ctx context.Context, arguments string, execCtx *
 		result.WriteString("\n(无输出)")
 	}
 
-	return types.NewToolResult(result.String()), nil
+	toolResult := types.NewToolResult(result.String())
+	if len(run.Violations) > 0 {
+		toolResult.IsError = true
+		toolResult.Violations = run.Violations
+	}
+	return toolResult, nil
+}
+
+// shellWorkingDir 启用沙箱时工作目录限定在工作区内；未启用时沿用宿主机目录，默认系统临时目录
+func shellWorkingDir(sb *sandbox.Sandbox, workingDir string) (string, error) {
+	if sb.Enabled() {
+		return sb.ResolveDir(workingDir)
+	}
+	if workingDir != "" {
+		if absDir, err := filepath.Abs(workingDir); err == nil {
+			if info, statErr := os.Stat(absDir); statErr == nil && info.IsDir() {
+				return absDir, nil
+			}
+		}
+	}
+	return os.TempDir(), nil
 }
@@ -1,8 +1,6 @@
 package tools
 
 import (
-	"context"
-	"os/exec"
 	"strings"
 
 	"yqhp/workflow-engine/internal/executor"
@@ -35,11 +33,6 @@ func RegisterAll(registry *executor.ToolRegistry) {
 	}
 }
 
-// ExecCommandContext 封装 exec.CommandContext，供 code/shell 工具使用。
-func ExecCommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
-	return exec.CommandContext(ctx, name, args...)
-}
-
 // StripTags 移除 HTML 标签并解码常见 HTML 实体。
 func StripTags(s string) string {
 	var result strings.Builder
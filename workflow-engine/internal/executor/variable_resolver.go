@@ -87,6 +87,14 @@ func (r *VariableResolver) ResolveMap(m map[string]string, ctx map[string]any) m
 	return result
 }
 
+// ResolveValue 按变量路径取值并保留原始类型，未找到时返回 nil
+func (r *VariableResolver) ResolveValue(path string, ctx map[string]any) any {
+	if ctx == nil {
+		return nil
+	}
+	return r.resolveVariablePath(path, ctx)
+}
+
 // resolveVariablePath 解析变量路径（支持嵌套访问如 obj.field.subfield）
 // 优先使用完整路径作为 key 匹配（如 "env.aaa"），支持命名空间变量；
 // 若完整 key 未命中，再按点号拆分做嵌套对象访问。
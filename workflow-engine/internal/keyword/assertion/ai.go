@@ -0,0 +1,133 @@
+package assertion
+
+import (
+	"context"
+	"fmt"
+
+	"yqhp/workflow-engine/internal/keyword"
+	"yqhp/workflow-engine/pkg/evaluator"
+)
+
+// LLMJudge creates an llm_judge assertion keyword.
+// A judge model scores the actual value against a rubric; the score, threshold and rationale are returned as result data.
+func LLMJudge() keyword.Keyword {
+	return &llmJudgeKeyword{
+		BaseKeyword: keyword.NewBaseKeyword("llm_judge", keyword.CategoryAssertion, "Asserts that a judge model scores actual at or above threshold against a rubric"),
+	}
+}
+
+type llmJudgeKeyword struct {
+	keyword.BaseKeyword
+}
+
+func (k *llmJudgeKeyword) Execute(ctx context.Context, execCtx *keyword.ExecutionContext, params map[string]any) (*keyword.Result, error) {
+	if err := k.Validate(params); err != nil {
+		return nil, err
+	}
+	threshold, _ := toFloat64(params["threshold"])
+
+	result, err := evaluator.Judge(ctx, &evaluator.JudgeRequest{
+		Model: evaluator.ModelConfig{
+			Provider: keyword.OptionalParam(params, "provider", ""),
+			Model:    keyword.OptionalParam(params, "model", ""),
+			APIKey:   keyword.OptionalParam(params, "api_key", ""),
+			BaseURL:  keyword.OptionalParam(params, "base_url", ""),
+		},
+		Rubric:    keyword.OptionalParam(params, "rubric", ""),
+		Input:     keyword.OptionalParam(params, "input", ""),
+		Response:  fmt.Sprintf("%v", params["actual"]),
+		Reference: keyword.OptionalParam(params, "reference", ""),
+		Threshold: threshold,
+	})
+	if err != nil {
+		return keyword.NewFailureResult(fmt.Sprintf("judge error: %v", err), err), nil
+	}
+	return scoreResult(k.Name(), result.Passed, result.Score, result.Threshold, result.Rationale, result, params), nil
+}
+
+func (k *llmJudgeKeyword) Validate(params map[string]any) error {
+	if _, ok := params["actual"]; !ok {
+		return fmt.Errorf("'actual' parameter is required")
+	}
+	if keyword.OptionalParam(params, "rubric", "") == "" {
+		return fmt.Errorf("'rubric' parameter is required")
+	}
+	if keyword.OptionalParam(params, "model", "") == "" {
+		return fmt.Errorf("'model' parameter is required")
+	}
+	return nil
+}
+
+// SemanticSimilarity creates a semantic_similarity assertion keyword.
+// Embeds actual and expected and asserts their cosine similarity is at or above threshold.
+func SemanticSimilarity() keyword.Keyword {
+	return &semanticSimilarityKeyword{
+		BaseKeyword: keyword.NewBaseKeyword("semantic_similarity", keyword.CategoryAssertion, "Asserts that actual is semantically similar to expected using embeddings"),
+	}
+}
+
+type semanticSimilarityKeyword struct {
+	keyword.BaseKeyword
+}
+
+func (k *semanticSimilarityKeyword) Execute(ctx context.Context, execCtx *keyword.ExecutionContext, params map[string]any) (*keyword.Result, error) {
+	if err := k.Validate(params); err != nil {
+		return nil, err
+	}
+	threshold, _ := toFloat64(params["threshold"])
+	kbID, _ := toFloat64(params["knowledge_base_id"])
+	modelID, _ := toFloat64(params["embedding_model_id"])
+
+	result, err := evaluator.Similarity(ctx, &evaluator.SimilarityRequest{
+		Embedding: evaluator.EmbeddingConfig{
+			KnowledgeBaseID: int64(kbID),
+			ModelID:         int64(modelID),
+			GuluHost:        keyword.OptionalParam(params, "gulu_host", ""),
+			Model:           keyword.OptionalParam(params, "model", ""),
+			APIKey:          keyword.OptionalParam(params, "api_key", ""),
+			BaseURL:         keyword.OptionalParam(params, "base_url", ""),
+		},
+		Actual:    fmt.Sprintf("%v", params["actual"]),
+		Reference: fmt.Sprintf("%v", params["expected"]),
+		Threshold: threshold,
+	})
+	if err != nil {
+		return keyword.NewFailureResult(fmt.Sprintf("similarity error: %v", err), err), nil
+	}
+	return scoreResult(k.Name(), result.Passed, result.Score, result.Threshold, "", result, params), nil
+}
+
+func (k *semanticSimilarityKeyword) Validate(params map[string]any) error {
+	if _, ok := params["actual"]; !ok {
+		return fmt.Errorf("'actual' parameter is required")
+	}
+	if _, ok := params["expected"]; !ok {
+		return fmt.Errorf("'expected' parameter is required")
+	}
+	return nil
+}
+
+// scoreResult builds a score-based assertion result carrying the evaluation details as data.
+func scoreResult(name string, passed bool, score, threshold float64, rationale string, data any, params map[string]any) *keyword.Result {
+	detail := fmt.Sprintf("score=%.4f, threshold=%.2f", score, threshold)
+	if rationale != "" {
+		detail += ", rationale=" + rationale
+	}
+	if passed {
+		return keyword.NewSuccessResult("assertion passed: "+detail, data)
+	}
+
+	failMsg := fmt.Sprintf("assertion '%s' failed: %s", name, detail)
+	if message := keyword.OptionalParam(params, "message", ""); message != "" {
+		failMsg = fmt.Sprintf("%s - %s", message, failMsg)
+	}
+	result := keyword.NewFailureResult(failMsg, nil)
+	result.Data = data
+	return result
+}
+
+// RegisterAIAssertions registers assertion keywords for generated text.
+func RegisterAIAssertions(registry *keyword.Registry) {
+	registry.MustRegister(LLMJudge())
+	registry.MustRegister(SemanticSimilarity())
+}
@@ -0,0 +1,76 @@
+package assertion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"yqhp/workflow-engine/internal/keyword"
+)
+
+func TestSemanticSimilarity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		data := make([]map[string]any, len(body.Input))
+		for i, text := range body.Input {
+			vec := []float32{1, 0}
+			if text == "unrelated" {
+				vec = []float32{0, 1}
+			}
+			data[i] = map[string]any{"index": i, "embedding": vec}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	kw := SemanticSimilarity()
+	ctx := context.Background()
+	execCtx := keyword.NewExecutionContext()
+
+	tests := []struct {
+		name   string
+		actual string
+		want   bool
+	}{
+		{"similar", "refund in 3 days", true},
+		{"unrelated", "unrelated", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := kw.Execute(ctx, execCtx, map[string]any{
+				"actual":   tt.actual,
+				"expected": "refund within three days",
+				"model":    "text-embedding-3-small",
+				"base_url": server.URL,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Success != tt.want {
+				t.Errorf("expected success=%v, got %v (%s)", tt.want, result.Success, result.Message)
+			}
+			if result.Data == nil {
+				t.Error("expected score data")
+			}
+		})
+	}
+}
+
+func TestLLMJudge_Validate(t *testing.T) {
+	kw := LLMJudge()
+	if err := kw.Validate(map[string]any{"actual": "x", "model": "gpt-4o"}); err == nil {
+		t.Error("expected error for missing rubric")
+	}
+	if err := kw.Validate(map[string]any{"actual": "x", "rubric": "r"}); err == nil {
+		t.Error("expected error for missing model")
+	}
+	if err := kw.Validate(map[string]any{"actual": "x", "rubric": "r", "model": "gpt-4o"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -16,6 +16,7 @@ func RegisterAllKeywords(registry *keyword.Registry) {
 	assertion.RegisterStringAssertions(registry)
 	assertion.RegisterCollectionAssertions(registry)
 	assertion.RegisterTypeAssertions(registry)
+	assertion.RegisterAIAssertions(registry)
 
 	// Register extractor keywords
 	extractor.RegisterAllExtractors(registry)
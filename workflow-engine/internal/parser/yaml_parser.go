@@ -173,6 +173,7 @@ func (p *YAMLParser) validateStep(step *types.Step, stepIDs map[string]bool, pat
 		"grpc":      true,
 		"condition": true,
 		"loop":      true,
+		"mock":      true,
 	}
 	if !validTypes[step.Type] {
 		return NewValidationError(path+".type", fmt.Sprintf("invalid step type: %s", step.Type))
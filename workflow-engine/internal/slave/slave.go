@@ -10,6 +10,7 @@ import (
 	httpclient "yqhp/workflow-engine/api/rest/client"
 	"yqhp/workflow-engine/internal/executor"
 	_ "yqhp/workflow-engine/internal/executor/ai"    // 注册 AI 执行器
+	_ "yqhp/workflow-engine/internal/executor/mock"  // 注册 Mock 执行器
 	_ "yqhp/workflow-engine/internal/executor/tools" // 注册内置工具
 	"yqhp/workflow-engine/pkg/types"
 )
@@ -933,6 +933,17 @@ func (e *TaskEngine) executeWorkflowIteration(ctx context.Context, task *types.T
 		}
 	}
 
+	// 从检查点恢复：用之前的变量与结果重建上下文，并跳过已完成的顶层步骤
+	start := 0
+	if workflow.Resume != nil {
+		idx, err := resumeStepIndex(workflow.Steps, workflow.Resume.StepID)
+		if err != nil {
+			return err
+		}
+		execCtx.Restore(workflow.Resume)
+		start = idx
+	}
+
 	// 获取执行选项
 	opts := &workflow.Options
 
@@ -942,7 +953,7 @@ func (e *TaskEngine) executeWorkflowIteration(ctx context.Context, task *types.T
 		workflow,
 		execCtx,
 		func(ctx context.Context, wf *types.Workflow, ec *executor.ExecutionContext) ([]*hook.StepExecutionResult, error) {
-			return e.executeStepsWithOptions(ctx, wf.Steps, ec, opts)
+			return e.executeStepsWithContext(ctx, wf.Steps, ec, opts, "", 0, start)
 		},
 	)
 
@@ -970,23 +981,45 @@ func (e *TaskEngine) executeSteps(ctx context.Context, steps []types.Step, execC
 
 // executeStepsWithOptions 执行步骤列表，支持执行选项。
 func (e *TaskEngine) executeStepsWithOptions(ctx context.Context, steps []types.Step, execCtx *executor.ExecutionContext, opts *types.ExecutionOptions) ([]*hook.StepExecutionResult, error) {
-	return e.executeStepsWithContext(ctx, steps, execCtx, opts, "", 0)
+	return e.executeStepsWithContext(ctx, steps, execCtx, opts, "", 0, 0)
 }
 
-// executeStepsWithContext 执行步骤列表，支持父步骤上下文（用于循环等嵌套场景）
-func (e *TaskEngine) executeStepsWithContext(ctx context.Context, steps []types.Step, execCtx *executor.ExecutionContext, opts *types.ExecutionOptions, parentID string, iteration int) ([]*hook.StepExecutionResult, error) {
+// resumeStepIndex 查找恢复起点在顶层步骤中的序号。
+func resumeStepIndex(steps []types.Step, stepID string) (int, error) {
+	for i := range steps {
+		if steps[i].ID == stepID {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("恢复起点步骤不存在: %s", stepID)
+}
+
+// executeStepsWithContext 执行步骤列表，支持父步骤上下文（用于循环等嵌套场景）。
+// start 之前的步骤视为已完成而跳过；顶层步骤（parentID 为空）完成后生成检查点。
+func (e *TaskEngine) executeStepsWithContext(ctx context.Context, steps []types.Step, execCtx *executor.ExecutionContext, opts *types.ExecutionOptions, parentID string, iteration int, start int) ([]*hook.StepExecutionResult, error) {
 	results := make([]*hook.StepExecutionResult, 0, len(steps))
 
 	// 从执行上下文获取回调
 	callback := execCtx.GetCallback()
 
+	// 顶层步骤的检查点回调
+	var checkpointer types.CheckpointCallback
+	if parentID == "" {
+		checkpointer, _ = callback.(types.CheckpointCallback)
+	}
+
 	logger.Debug("executeStepsWithContext] 开始执行 %d 个步骤, parentID=%s, iteration=%d\n", len(steps), parentID, iteration)
 
-	for i := range steps {
+	for i := start; i < len(steps); i++ {
 		step := &steps[i]
 
 		logger.Debug("executeStepsWithContext] 执行步骤[%d]: id=%s, type=%s, name=%s\n", i, step.ID, step.Type, step.Name)
 
+		// 断点调试：暂停期间阻塞
+		if err := execCtx.WaitBeforeStep(ctx, step, parentID, iteration); err != nil {
+			return results, err
+		}
+
 		// 触发步骤开始回调
 		if callback != nil {
 			callback.OnStepStart(ctx, step, parentID, iteration)
@@ -1012,6 +1045,11 @@ func (e *TaskEngine) executeStepsWithContext(ctx context.Context, steps []types.
 
 		logger.Debug("executeStepsWithContext] 找到执行器: type=%s\n", execType)
 
+		var resultsBefore map[string]*types.StepResult
+		if checkpointer != nil {
+			resultsBefore = execCtx.SnapshotResults()
+		}
+
 		// 使用钩子执行步骤
 		result := e.hookRunner.ExecuteStepWithHooks(
 			ctx,
@@ -1035,6 +1073,14 @@ func (e *TaskEngine) executeStepsWithContext(ctx context.Context, steps []types.
 			}
 		}
 
+		if checkpointer != nil {
+			status := types.ResultStatusFailed
+			if result.StepResult != nil {
+				status = result.StepResult.Status
+			}
+			checkpointer.OnCheckpoint(ctx, execCtx.NewCheckpoint(i, step.ID, status, resultsBefore))
+		}
+
 		// 处理错误策略
 		// 检查步骤是否失败（通过 error 或 status）
 		stepFailed := result.Error != nil ||
@@ -2,6 +2,7 @@ package slave
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -390,3 +391,124 @@ func TestTaskEngine_CalculateIterations(t *testing.T) {
 		})
 	}
 }
+
+// checkpointRecorder records top-level checkpoints.
+type checkpointRecorder struct {
+	mu          sync.Mutex
+	checkpoints []*types.Checkpoint
+}
+
+func (r *checkpointRecorder) OnStepStart(ctx context.Context, step *types.Step, parentID string, iteration int) {
+}
+
+func (r *checkpointRecorder) OnStepComplete(ctx context.Context, step *types.Step, result *types.StepResult, parentID string, iteration int) {
+}
+
+func (r *checkpointRecorder) OnExecutionComplete(ctx context.Context, summary *types.ExecutionSummary) {
+}
+
+func (r *checkpointRecorder) OnCheckpoint(ctx context.Context, checkpoint *types.Checkpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkpoints = append(r.checkpoints, checkpoint)
+}
+
+func newCheckpointTask(callback types.ExecutionCallback, resume *types.ResumePoint) *types.Task {
+	return &types.Task{
+		ID:          "task-1",
+		ExecutionID: "exec-1",
+		Segment:     types.ExecutionSegment{Start: 0, End: 1},
+		Workflow: &types.Workflow{
+			ID:       "checkpoint-workflow",
+			Callback: callback,
+			Resume:   resume,
+			Steps: []types.Step{
+				{ID: "step-1", Name: "Step 1", Type: "mock"},
+				{ID: "step-2", Name: "Step 2", Type: "mock"},
+				{ID: "step-3", Name: "Step 3", Type: "mock"},
+			},
+			Options: types.ExecutionOptions{VUs: 1, Iterations: 1},
+		},
+	}
+}
+
+func TestTaskEngine_Checkpoint_FailedStep(t *testing.T) {
+	registry := executor.NewRegistry()
+	mock := newMockExecutor("mock")
+	mock.executeFunc = func(ctx context.Context, step *types.Step, execCtx *executor.ExecutionContext) (*types.StepResult, error) {
+		if step.ID == "step-2" {
+			return executor.CreateFailedResult(step.ID, time.Now(), assert.AnError), nil
+		}
+		execCtx.SetVariable("last", step.ID)
+		return executor.CreateSuccessResult(step.ID, time.Now(), step.ID+"-output"), nil
+	}
+	require.NoError(t, registry.Register(mock))
+
+	recorder := &checkpointRecorder{}
+	_, err := NewTaskEngine(registry, 10).Execute(context.Background(), newCheckpointTask(recorder, nil))
+	require.NoError(t, err)
+
+	require.Len(t, recorder.checkpoints, 2)
+	first, second := recorder.checkpoints[0], recorder.checkpoints[1]
+
+	assert.Equal(t, 0, first.StepIndex)
+	assert.Equal(t, "step-1", first.StepID)
+	assert.Equal(t, types.ResultStatusSuccess, first.Status)
+	assert.Equal(t, "step-1", first.Variables["last"])
+	require.Contains(t, first.Results, "step-1")
+	assert.Equal(t, "step-1-output", first.Results["step-1"].Output)
+
+	assert.Equal(t, 1, second.StepIndex)
+	assert.Equal(t, types.ResultStatusFailed, second.Status)
+	assert.NotContains(t, second.Results, "step-1")
+	require.Contains(t, second.Results, "step-2")
+	assert.Equal(t, assert.AnError.Error(), second.Results["step-2"].Error)
+}
+
+func TestTaskEngine_Resume(t *testing.T) {
+	registry := executor.NewRegistry()
+	var executed []string
+	var seen any
+	mock := newMockExecutor("mock")
+	mock.executeFunc = func(ctx context.Context, step *types.Step, execCtx *executor.ExecutionContext) (*types.StepResult, error) {
+		executed = append(executed, step.ID)
+		if step.ID == "step-2" {
+			if prev, ok := execCtx.GetResult("step-1"); ok {
+				seen = prev.Output
+			}
+		}
+		return executor.CreateSuccessResult(step.ID, time.Now(), nil), nil
+	}
+	require.NoError(t, registry.Register(mock))
+
+	resume := &types.ResumePoint{
+		StepID:    "step-2",
+		Variables: map[string]any{"token": "abc"},
+		Results: map[string]*types.CheckpointResult{
+			"step-1": {Status: types.ResultStatusSuccess, Output: "step-1-output"},
+		},
+	}
+	recorder := &checkpointRecorder{}
+	task := newCheckpointTask(recorder, resume)
+	_, err := NewTaskEngine(registry, 10).Execute(context.Background(), task)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"step-2", "step-3"}, executed)
+	assert.Equal(t, "step-1-output", seen)
+	assert.Equal(t, "abc", task.Workflow.FinalVariables["token"])
+
+	require.Len(t, recorder.checkpoints, 2)
+	assert.Equal(t, 1, recorder.checkpoints[0].StepIndex)
+	assert.Equal(t, 2, recorder.checkpoints[1].StepIndex)
+}
+
+func TestResumeStepIndex(t *testing.T) {
+	steps := []types.Step{{ID: "step-1"}, {ID: "step-2"}}
+
+	idx, err := resumeStepIndex(steps, "step-2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, idx)
+
+	_, err = resumeStepIndex(steps, "missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"yqhp/workflow-engine/pkg/logger"
+)
+
+// maxJudgeCacheSize 内存中缓存的评审结果上限，超出时清空（磁盘缓存不受影响）
+const maxJudgeCacheSize = 1024
+
+// judgeCache 评审结果缓存：进程内存 + 磁盘，多次运行间复用；
+// 并发的相同请求只调用一次评审模型（压测场景下多个 VU 评审同一回复）
+type judgeCache struct {
+	mu       sync.Mutex
+	dir      string
+	entries  map[string]*judgeVerdict
+	inflight map[string]*judgeCall
+}
+
+type judgeCall struct {
+	done    chan struct{}
+	verdict *judgeVerdict
+	err     error
+}
+
+var defaultJudgeCache = newJudgeCache(defaultJudgeCacheDir())
+
+func newJudgeCache(dir string) *judgeCache {
+	return &judgeCache{
+		dir:      dir,
+		entries:  make(map[string]*judgeVerdict),
+		inflight: make(map[string]*judgeCall),
+	}
+}
+
+func defaultJudgeCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "workflow-engine", "judge")
+}
+
+// SetJudgeCacheDir 设置评审缓存目录，传空字符串仅使用内存缓存；同时清空内存缓存
+func SetJudgeCacheDir(dir string) {
+	defaultJudgeCache.mu.Lock()
+	defer defaultJudgeCache.mu.Unlock()
+	defaultJudgeCache.dir = dir
+	defaultJudgeCache.entries = make(map[string]*judgeVerdict)
+}
+
+// do 命中缓存直接返回，否则执行 fn 并缓存成功结果；第二个返回值表示是否命中缓存
+func (c *judgeCache) do(key string, fn func() (*judgeVerdict, error)) (*judgeVerdict, bool, error) {
+	c.mu.Lock()
+	if v, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		return v, true, nil
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.verdict, call.err == nil, call.err
+	}
+	dir := c.dir
+	if v := c.load(dir, key); v != nil {
+		c.put(key, v)
+		c.mu.Unlock()
+		return v, true, nil
+	}
+	call := &judgeCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.verdict, call.err = fn()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil {
+		c.put(key, call.verdict)
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	if call.err == nil {
+		c.store(dir, key, call.verdict)
+	}
+	return call.verdict, false, call.err
+}
+
+// put 写入内存缓存，调用方需持有锁
+func (c *judgeCache) put(key string, v *judgeVerdict) {
+	if len(c.entries) >= maxJudgeCacheSize {
+		c.entries = make(map[string]*judgeVerdict)
+	}
+	c.entries[key] = v
+}
+
+func (c *judgeCache) load(dir, key string) *judgeVerdict {
+	if dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return nil
+	}
+	var v judgeVerdict
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+	return &v
+}
+
+func (c *judgeCache) store(dir, key string, v *judgeVerdict) {
+	if dir == "" {
+		return
+	}
+	data, _ := json.Marshal(v)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		logger.Warn("[Judge] 创建缓存目录失败: %v", err)
+		return
+	}
+	tmp := filepath.Join(dir, key+".json.tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		logger.Warn("[Judge] 写入评审缓存失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, key+".json")); err != nil {
+		logger.Warn("[Judge] 写入评审缓存失败: %v", err)
+	}
+}
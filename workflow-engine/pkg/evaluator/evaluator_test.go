@@ -0,0 +1,201 @@
+package evaluator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+// fakeJudge 固定返回评审结果并统计调用次数
+type fakeJudge struct {
+	content string
+	calls   int32
+	prompts []string
+	mu      sync.Mutex
+}
+
+func (f *fakeJudge) Generate(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.Message, error) {
+	atomic.AddInt32(&f.calls, 1)
+	f.mu.Lock()
+	f.prompts = append(f.prompts, input[len(input)-1].Content)
+	f.mu.Unlock()
+	return schema.AssistantMessage(f.content, nil), nil
+}
+
+func (f *fakeJudge) Stream(ctx context.Context, input []*schema.Message, opts ...einomodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := f.Generate(ctx, input, opts...)
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), err
+}
+
+func useFakeJudge(t *testing.T, content string) *fakeJudge {
+	judge := &fakeJudge{content: content}
+	RegisterChatModelFactory(func(ctx context.Context, config ModelConfig) (einomodel.BaseChatModel, error) {
+		return judge, nil
+	})
+	defaultJudgeCache = newJudgeCache(t.TempDir())
+	t.Cleanup(func() { RegisterChatModelFactory(nil) })
+	return judge
+}
+
+func judgeRequest() *JudgeRequest {
+	return &JudgeRequest{
+		Model:     ModelConfig{Provider: "openai", Model: "gpt-judge"},
+		Rubric:    "回答需要给出退款时限",
+		Input:     "多久能退款？",
+		Response:  "提交申请后 3 个工作日内退款。",
+		Threshold: 0.8,
+	}
+}
+
+func TestJudge_ScoreAndCache(t *testing.T) {
+	judge := useFakeJudge(t, "```json\n{\"score\": 0.9, \"rationale\": \"给出了明确时限\"}\n```")
+
+	result, err := Judge(context.Background(), judgeRequest())
+	require.NoError(t, err)
+	assert.True(t, result.Passed)
+	assert.Equal(t, 0.9, result.Score)
+	assert.Equal(t, "给出了明确时限", result.Rationale)
+	assert.False(t, result.Cached)
+	assert.Contains(t, judge.prompts[0], "多久能退款")
+
+	// 相同输入命中缓存，阈值变化只影响是否通过
+	req := judgeRequest()
+	req.Threshold = 0.95
+	result, err = Judge(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, result.Cached)
+	assert.False(t, result.Passed)
+	assert.EqualValues(t, 1, judge.calls)
+
+	// 磁盘缓存：新进程（新的内存缓存）不再调用评审模型
+	defaultJudgeCache = newJudgeCache(defaultJudgeCache.dir)
+	result, err = Judge(context.Background(), judgeRequest())
+	require.NoError(t, err)
+	assert.True(t, result.Cached)
+	assert.EqualValues(t, 1, judge.calls)
+
+	req = judgeRequest()
+	req.Response = "不清楚"
+	_, err = Judge(context.Background(), req)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, judge.calls)
+}
+
+func TestJudge_ConcurrentSameInputCallsOnce(t *testing.T) {
+	judge := useFakeJudge(t, `{"score": 0.5, "rationale": "一般"}`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := Judge(context.Background(), judgeRequest())
+			assert.NoError(t, err)
+			assert.False(t, result.Passed)
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, judge.calls)
+}
+
+func TestJudgeCache_BoundedMemory(t *testing.T) {
+	c := newJudgeCache("")
+	for i := 0; i < maxJudgeCacheSize+10; i++ {
+		_, _, err := c.do(fmt.Sprintf("k%d", i), func() (*judgeVerdict, error) { return &judgeVerdict{}, nil })
+		require.NoError(t, err)
+	}
+	assert.LessOrEqual(t, len(c.entries), maxJudgeCacheSize)
+}
+
+func TestJudge_InvalidVerdict(t *testing.T) {
+	useFakeJudge(t, "我觉得不错")
+	_, err := Judge(context.Background(), judgeRequest())
+	require.Error(t, err)
+
+	useFakeJudge(t, `{"score": 8, "rationale": "满分 10 分"}`)
+	_, err = Judge(context.Background(), judgeRequest())
+	require.Error(t, err)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.Equal(t, 0.0, CosineSimilarity([]float32{1}, []float32{1, 2}))
+}
+
+func TestSimilarity_Direct(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		var body struct {
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		data := make([]map[string]any, len(body.Input))
+		for i, text := range body.Input {
+			vec := []float32{1, 0}
+			if text == "完全无关" {
+				vec = []float32{0, 1}
+			}
+			data[i] = map[string]any{"index": i, "embedding": vec}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	cfg := EmbeddingConfig{Model: "text-embedding-3-small", APIKey: "sk-test", BaseURL: server.URL + "/v1"}
+	result, err := Similarity(context.Background(), &SimilarityRequest{Embedding: cfg, Actual: "三天内退款", Reference: "3 个工作日退款"})
+	require.NoError(t, err)
+	assert.True(t, result.Passed)
+	assert.Equal(t, DefaultSimilarityThreshold, result.Threshold)
+
+	// 参考答案向量已缓存，只需计算新回复
+	result, err = Similarity(context.Background(), &SimilarityRequest{Embedding: cfg, Actual: "完全无关", Reference: "3 个工作日退款"})
+	require.NoError(t, err)
+	assert.False(t, result.Passed)
+	assert.EqualValues(t, 2, requests)
+}
+
+func TestSimilarity_ViaGulu(t *testing.T) {
+	t.Setenv(types.GuluInternalTokenEnv, "internal-secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/internal/embeddings", r.URL.Path)
+		assert.Equal(t, "internal-secret", r.Header.Get(types.GuluInternalTokenHeader))
+		var body struct {
+			KnowledgeBaseID int64    `json:"knowledge_base_id"`
+			Texts           []string `json:"texts"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.EqualValues(t, 7, body.KnowledgeBaseID)
+		vectors := make([][]float32, len(body.Texts))
+		for i := range vectors {
+			vectors[i] = []float32{1, float32(i)}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"code": 0, "data": map[string]any{"vectors": vectors}})
+	}))
+	defer server.Close()
+
+	result, err := Similarity(context.Background(), &SimilarityRequest{
+		Embedding: EmbeddingConfig{KnowledgeBaseID: 7, GuluHost: server.URL},
+		Actual:    "回复",
+		Reference: "参考",
+		Threshold: 0.5,
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 1/1.4142135, result.Score, 1e-6)
+	assert.True(t, result.Passed)
+}
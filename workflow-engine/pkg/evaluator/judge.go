@@ -0,0 +1,171 @@
+// Package evaluator 提供面向生成式文本的评估能力：LLM 评审打分与语义相似度。
+package evaluator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	einomodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// DefaultJudgeThreshold 评审通过的默认分数阈值
+const DefaultJudgeThreshold = 0.7
+
+// ModelConfig 评审模型配置
+type ModelConfig struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	APIKey   string `json:"-"`
+	BaseURL  string `json:"base_url,omitempty"`
+}
+
+// ChatModelFactory 根据配置创建评审模型，由 AI 执行器包注册以复用其供应商适配
+type ChatModelFactory func(ctx context.Context, config ModelConfig) (einomodel.BaseChatModel, error)
+
+var (
+	factoryMu    sync.RWMutex
+	modelFactory ChatModelFactory
+)
+
+// RegisterChatModelFactory 注册评审模型工厂
+func RegisterChatModelFactory(f ChatModelFactory) {
+	factoryMu.Lock()
+	defer factoryMu.Unlock()
+	modelFactory = f
+}
+
+func getChatModelFactory() ChatModelFactory {
+	factoryMu.RLock()
+	defer factoryMu.RUnlock()
+	return modelFactory
+}
+
+// JudgeRequest 评审请求
+type JudgeRequest struct {
+	Model     ModelConfig
+	Rubric    string  // 评分标准
+	Input     string  // 原始问题（可选）
+	Response  string  // 待评审的回复
+	Reference string  // 参考答案（可选）
+	Threshold float64 // 通过阈值，0~1
+}
+
+// JudgeResult 评审结果
+type JudgeResult struct {
+	Score     float64 `json:"score"`
+	Threshold float64 `json:"threshold"`
+	Passed    bool    `json:"passed"`
+	Rationale string  `json:"rationale"`
+	Model     string  `json:"model"`
+	Cached    bool    `json:"cached"`
+}
+
+// judgeVerdict 评审模型输出，也是缓存内容（与阈值无关）
+type judgeVerdict struct {
+	Score     float64 `json:"score"`
+	Rationale string  `json:"rationale"`
+}
+
+// Judge 让评审模型按评分标准为回复打分；相同输入命中缓存时不再调用模型
+func Judge(ctx context.Context, req *JudgeRequest) (*JudgeResult, error) {
+	if strings.TrimSpace(req.Rubric) == "" {
+		return nil, errors.New("评审需要配置 rubric")
+	}
+	if req.Model.Model == "" {
+		return nil, errors.New("评审需要配置 model")
+	}
+	threshold := req.Threshold
+	if threshold <= 0 {
+		threshold = DefaultJudgeThreshold
+	}
+
+	key := judgeCacheKey(req)
+	verdict, cached, err := defaultJudgeCache.do(key, func() (*judgeVerdict, error) {
+		return callJudge(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &JudgeResult{
+		Score:     verdict.Score,
+		Threshold: threshold,
+		Passed:    verdict.Score >= threshold,
+		Rationale: verdict.Rationale,
+		Model:     req.Model.Model,
+		Cached:    cached,
+	}, nil
+}
+
+// judgeCacheKey 缓存键：评审模型 + 评分标准 + 全部输入
+func judgeCacheKey(req *JudgeRequest) string {
+	data, _ := json.Marshal([]string{
+		req.Model.Provider, req.Model.BaseURL, req.Model.Model,
+		req.Rubric, req.Input, req.Response, req.Reference,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func callJudge(ctx context.Context, req *JudgeRequest) (*judgeVerdict, error) {
+	factory := getChatModelFactory()
+	if factory == nil {
+		return nil, errors.New("未注册评审模型工厂")
+	}
+	chatModel, err := factory(ctx, req.Model)
+	if err != nil {
+		return nil, fmt.Errorf("创建评审模型失败: %w", err)
+	}
+
+	resp, err := chatModel.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(judgeSystemPrompt),
+		schema.UserMessage(buildJudgePrompt(req)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("评审模型调用失败: %w", err)
+	}
+	return parseVerdict(resp.Content)
+}
+
+const judgeSystemPrompt = `你是一名严格、公正的评审员，负责按照给定的评分标准评估回复质量。
+只输出一个 JSON 对象，格式为 {"score": <0 到 1 之间的小数>, "rationale": "<简要评分理由>"}，不要输出任何其他内容。`
+
+func buildJudgePrompt(req *JudgeRequest) string {
+	var sb strings.Builder
+	sb.WriteString("[评分标准]\n")
+	sb.WriteString(req.Rubric)
+	if req.Input != "" {
+		sb.WriteString("\n\n[原始问题]\n")
+		sb.WriteString(req.Input)
+	}
+	if req.Reference != "" {
+		sb.WriteString("\n\n[参考答案]\n")
+		sb.WriteString(req.Reference)
+	}
+	sb.WriteString("\n\n[待评审回复]\n")
+	sb.WriteString(req.Response)
+	return sb.String()
+}
+
+// parseVerdict 解析评审输出，容忍代码块和前后说明文字
+func parseVerdict(content string) (*judgeVerdict, error) {
+	start := strings.IndexByte(content, '{')
+	end := strings.LastIndexByte(content, '}')
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("评审输出不是 JSON: %s", content)
+	}
+	var v judgeVerdict
+	if err := json.Unmarshal([]byte(content[start:end+1]), &v); err != nil {
+		return nil, fmt.Errorf("评审输出解析失败: %w", err)
+	}
+	if v.Score < 0 || v.Score > 1 {
+		return nil, fmt.Errorf("评审分数超出 0~1 范围: %v", v.Score)
+	}
+	return &v, nil
+}
@@ -0,0 +1,256 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"yqhp/workflow-engine/pkg/types"
+)
+
+const (
+	// DefaultSimilarityThreshold 语义相似度通过的默认阈值
+	DefaultSimilarityThreshold = 0.8
+
+	defaultGuluHost       = "http://127.0.0.1:5321"
+	defaultEmbeddingURL   = "https://api.openai.com/v1"
+	embeddingHTTPTimeout  = 60 * time.Second
+	maxEmbeddingCacheSize = 1024
+)
+
+// EmbeddingConfig 嵌入模型配置：配置知识库或嵌入模型 ID 时由 Gulu 使用知识库的嵌入客户端计算，
+// 否则按 OpenAI 兼容协议直接调用
+type EmbeddingConfig struct {
+	KnowledgeBaseID int64
+	ModelID         int64
+	GuluHost        string
+
+	Model   string
+	APIKey  string
+	BaseURL string
+}
+
+func (c *EmbeddingConfig) viaGulu() bool {
+	return c.KnowledgeBaseID > 0 || c.ModelID > 0
+}
+
+// SimilarityRequest 语义相似度请求
+type SimilarityRequest struct {
+	Embedding EmbeddingConfig
+	Actual    string
+	Reference string
+	Threshold float64
+}
+
+// SimilarityResult 语义相似度结果
+type SimilarityResult struct {
+	Score     float64 `json:"score"`
+	Threshold float64 `json:"threshold"`
+	Passed    bool    `json:"passed"`
+}
+
+// Similarity 计算回复与参考答案的余弦相似度
+func Similarity(ctx context.Context, req *SimilarityRequest) (*SimilarityResult, error) {
+	if !req.Embedding.viaGulu() && req.Embedding.Model == "" {
+		return nil, errors.New("语义相似度需要配置知识库、嵌入模型 ID 或嵌入模型名称")
+	}
+	threshold := req.Threshold
+	if threshold <= 0 {
+		threshold = DefaultSimilarityThreshold
+	}
+
+	vectors, err := embedCached(ctx, &req.Embedding, []string{req.Actual, req.Reference})
+	if err != nil {
+		return nil, err
+	}
+	score := CosineSimilarity(vectors[0], vectors[1])
+	return &SimilarityResult{Score: score, Threshold: threshold, Passed: score >= threshold}, nil
+}
+
+// CosineSimilarity 余弦相似度，任一向量为零向量或维度不一致时返回 0
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// embeddingCache 向量缓存：参考答案在每次迭代中都相同，避免重复计算
+var embeddingCache = struct {
+	sync.Mutex
+	entries map[string][]float32
+}{entries: make(map[string][]float32)}
+
+func embeddingCacheKey(cfg *EmbeddingConfig, text string) string {
+	data, _ := json.Marshal([]any{cfg.KnowledgeBaseID, cfg.ModelID, cfg.BaseURL, cfg.Model, text})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func embedCached(ctx context.Context, cfg *EmbeddingConfig, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	var missing []string
+	var missingIdx []int
+
+	embeddingCache.Lock()
+	for i, text := range texts {
+		if v, ok := embeddingCache.entries[embeddingCacheKey(cfg, text)]; ok {
+			vectors[i] = v
+		} else {
+			missing = append(missing, text)
+			missingIdx = append(missingIdx, i)
+		}
+	}
+	embeddingCache.Unlock()
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	var embedded [][]float32
+	var err error
+	if cfg.viaGulu() {
+		embedded, err = embedViaGulu(ctx, cfg, missing)
+	} else {
+		embedded, err = embedDirect(ctx, cfg, missing)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("Embedding 结果数量不符: 期望 %d，实际 %d", len(missing), len(embedded))
+	}
+
+	embeddingCache.Lock()
+	if len(embeddingCache.entries) >= maxEmbeddingCacheSize {
+		embeddingCache.entries = make(map[string][]float32)
+	}
+	for i, v := range embedded {
+		vectors[missingIdx[i]] = v
+		embeddingCache.entries[embeddingCacheKey(cfg, missing[i])] = v
+	}
+	embeddingCache.Unlock()
+	return vectors, nil
+}
+
+// embedViaGulu 调用 Gulu 内部接口，使用知识库配置的嵌入模型
+func embedViaGulu(ctx context.Context, cfg *EmbeddingConfig, texts []string) ([][]float32, error) {
+	host := cfg.GuluHost
+	if host == "" {
+		host = defaultGuluHost
+	}
+	body := map[string]any{
+		"knowledge_base_id": cfg.KnowledgeBaseID,
+		"model_id":          cfg.ModelID,
+		"texts":             texts,
+	}
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"message"`
+		Data struct {
+			Vectors [][]float32 `json:"vectors"`
+		} `json:"data"`
+	}
+	headers := map[string]string{types.GuluInternalTokenHeader: types.GuluInternalToken()}
+	if err := postJSON(ctx, strings.TrimRight(host, "/")+"/api/internal/embeddings", headers, body, &result); err != nil {
+		return nil, err
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("Embedding 失败: %s", result.Msg)
+	}
+	return result.Data.Vectors, nil
+}
+
+// embedDirect 按 OpenAI /embeddings 协议直接调用
+func embedDirect(ctx context.Context, cfg *EmbeddingConfig, texts []string) ([][]float32, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultEmbeddingURL
+	}
+	headers := map[string]string{}
+	if cfg.APIKey != "" {
+		headers["Authorization"] = "Bearer " + cfg.APIKey
+	}
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	body := map[string]any{"model": cfg.Model, "input": texts}
+	if err := postJSON(ctx, strings.TrimRight(baseURL, "/")+"/embeddings", headers, body, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("Embedding API 错误: %s", result.Error.Message)
+	}
+	vectors := make([][]float32, len(texts))
+	for i, d := range result.Data {
+		idx := d.Index
+		if idx < 0 || idx >= len(vectors) {
+			idx = i
+		}
+		if idx < len(vectors) {
+			vectors[idx] = d.Embedding
+		}
+	}
+	for _, v := range vectors {
+		if len(v) == 0 {
+			return nil, errors.New("Embedding 结果为空")
+		}
+	}
+	return vectors, nil
+}
+
+func postJSON(ctx context.Context, url string, headers map[string]string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, embeddingHTTPTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Embedding HTTP 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Embedding API 错误 (HTTP %d): %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("Embedding 响应解析失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package executor
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LookupJSONPath 按简化的 JSONPath 取值，支持 $.a.b、$.items[0].id、$['a.b'] 形式。
+// 为兼容旧配置，优先把 $. 之后的整体作为顶层键查找。
+func LookupJSONPath(data interface{}, expression string) (interface{}, bool) {
+	key := strings.TrimPrefix(expression, "$.")
+	if m, ok := data.(map[string]interface{}); ok {
+		if v, ok := m[key]; ok {
+			return v, true
+		}
+	}
+
+	path := strings.TrimPrefix(expression, "$")
+	cur := data
+	for path != "" {
+		var seg string
+		switch {
+		case strings.HasPrefix(path, "."):
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			seg, path = path[:end], path[end:]
+		case strings.HasPrefix(path, "['"):
+			end := strings.Index(path[2:], "']")
+			if end < 0 {
+				return nil, false
+			}
+			seg, path = path[2:2+end], path[4+end:]
+		case strings.HasPrefix(path, "["):
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, false
+			}
+			idx, err := strconv.Atoi(path[1:end])
+			if err != nil {
+				return nil, false
+			}
+			path = path[end+1:]
+			list, ok := cur.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			if idx < 0 {
+				idx += len(list)
+			}
+			if idx < 0 || idx >= len(list) {
+				return nil, false
+			}
+			cur = list[idx]
+			continue
+		default:
+			// 无前导 $. 的写法，如 data.token
+			path = "." + path
+			continue
+		}
+
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[seg]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
@@ -0,0 +1,32 @@
+package executor
+
+import "testing"
+
+func TestLookupJSONPath(t *testing.T) {
+	data := mustJSON(t, `{"code": 0, "a.b": "flat", "data": {"token": "abc", "items": [{"id": 1}, {"id": 2}]}}`)
+
+	tests := []struct {
+		expr string
+		want interface{}
+		ok   bool
+	}{
+		{"$.code", float64(0), true},
+		{"code", float64(0), true},
+		{"$.a.b", "flat", true},
+		{"$.data.token", "abc", true},
+		{"data.token", "abc", true},
+		{"$.data.items[1].id", float64(2), true},
+		{"$.data.items[-1].id", float64(2), true},
+		{"$['data']['token']", "abc", true},
+		{"$.data.items[5].id", nil, false},
+		{"$.missing", nil, false},
+		{"$[']", nil, false},
+		{"$['data", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := LookupJSONPath(data, tt.expr)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("LookupJSONPath(%q) = %v, %v; want %v, %v", tt.expr, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// maxSchemaErrors 单次校验最多收集的错误数
+const maxSchemaErrors = 10
+
+// ValidateJSONSchema 使用 JSON Schema 校验数据，返回不满足约束的描述列表。
+// 支持 OpenAPI 常用的子集：type、nullable、enum、required、properties、
+// additionalProperties(false)、items、allOf/anyOf/oneOf、长度/数量/数值范围、pattern。
+// $ref 需要在生成断言时预先展开。
+func ValidateJSONSchema(schema map[string]any, data any) []string {
+	var errs []string
+	validateSchemaNode(schema, data, "$", &errs)
+	return errs
+}
+
+func validateSchemaNode(schema map[string]any, data any, path string, errs *[]string) {
+	if len(*errs) >= maxSchemaErrors || schema == nil {
+		return
+	}
+	addErr := func(format string, args ...any) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if data == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || schemaAllowsType(schema, "null") {
+			return
+		}
+		if _, hasType := schema["type"]; hasType {
+			addErr("值不能为 null")
+		}
+		return
+	}
+
+	if t, ok := schema["type"]; ok && !matchSchemaType(t, data) {
+		addErr("类型应为 %v，实际为 %s", t, jsonTypeOf(data))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		matched := false
+		for _, v := range enum {
+			if jsonEqual(v, data) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			addErr("值 %v 不在枚举 %v 中", data, enum)
+		}
+	}
+
+	for _, sub := range schemaList(schema["allOf"]) {
+		validateSchemaNode(sub, data, path, errs)
+	}
+	if anyOf := schemaList(schema["anyOf"]); len(anyOf) > 0 && countMatches(anyOf, data) == 0 {
+		addErr("不满足 anyOf 中任一结构")
+	}
+	if oneOf := schemaList(schema["oneOf"]); len(oneOf) > 0 && countMatches(oneOf, data) != 1 {
+		addErr("应恰好满足 oneOf 中的一个结构")
+	}
+
+	switch v := data.(type) {
+	case map[string]any:
+		validateSchemaObject(schema, v, path, errs)
+	case []any:
+		if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < min {
+			addErr("元素个数 %d 少于 %v", len(v), min)
+		}
+		if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > max {
+			addErr("元素个数 %d 多于 %v", len(v), max)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateSchemaNode(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if min, ok := schemaNumber(schema["minLength"]); ok && length < min {
+			addErr("长度 %v 小于 %v", length, min)
+		}
+		if max, ok := schemaNumber(schema["maxLength"]); ok && length > max {
+			addErr("长度 %v 大于 %v", length, max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				addErr("值 %q 不匹配 %s", v, pattern)
+			}
+		}
+	case float64:
+		if min, ok := schemaNumber(schema["minimum"]); ok && v < min {
+			addErr("值 %v 小于 %v", v, min)
+		}
+		if max, ok := schemaNumber(schema["maximum"]); ok && v > max {
+			addErr("值 %v 大于 %v", v, max)
+		}
+	}
+}
+
+func validateSchemaObject(schema map[string]any, obj map[string]any, path string, errs *[]string) {
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, exists := obj[name]; !exists {
+					*errs = append(*errs, fmt.Sprintf("%s: 缺少必填字段 %s", path, name))
+				}
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if propSchema, ok := props[k].(map[string]any); ok {
+			validateSchemaNode(propSchema, obj[k], path+"."+k, errs)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional && props != nil {
+				*errs = append(*errs, fmt.Sprintf("%s: 不允许的字段 %s", path, k))
+			}
+		case map[string]any:
+			validateSchemaNode(additional, obj[k], path+"."+k, errs)
+		}
+	}
+}
+
+func countMatches(schemas []map[string]any, data any) int {
+	count := 0
+	for _, s := range schemas {
+		var subErrs []string
+		validateSchemaNode(s, data, "$", &subErrs)
+		if len(subErrs) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func schemaList(raw any) []map[string]any {
+	list, ok := raw.([]any)
+	if !ok {
+		return nil
+	}
+	result := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func schemaAllowsType(schema map[string]any, typ string) bool {
+	switch t := schema["type"].(type) {
+	case string:
+		return t == typ
+	case []any:
+		for _, item := range t {
+			if item == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchSchemaType(t any, data any) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchSingleType(tt, data)
+	case []any:
+		for _, item := range tt {
+			if s, ok := item.(string); ok && matchSingleType(s, data) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchSingleType(t string, data any) bool {
+	actual := jsonTypeOf(data)
+	switch t {
+	case "integer":
+		f, ok := data.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		return actual == "number"
+	default:
+		return actual == t
+	}
+}
+
+func jsonTypeOf(data any) string {
+	switch data.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return reflect.TypeOf(data).String()
+	}
+}
+
+func schemaNumber(raw any) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func jsonEqual(a, b any) bool {
+	if af, ok := schemaNumber(a); ok {
+		bf, ok := schemaNumber(b)
+		return ok && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
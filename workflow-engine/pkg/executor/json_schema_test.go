@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mustJSON(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid json %s: %v", s, err)
+	}
+	return v
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := mustJSON(t, `{
+		"type": "object",
+		"required": ["id", "name"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"name": {"type": "string", "minLength": 1},
+			"status": {"type": "string", "enum": ["active", "disabled"]},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"owner": {"type": "object", "nullable": true, "properties": {"id": {"type": "integer"}}}
+		}
+	}`).(map[string]any)
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", `{"id": 1, "name": "a", "status": "active", "tags": ["x"], "owner": null}`, false},
+		{"missing required", `{"id": 1}`, true},
+		{"wrong type", `{"id": "1", "name": "a"}`, true},
+		{"not integer", `{"id": 1.5, "name": "a"}`, true},
+		{"enum mismatch", `{"id": 1, "name": "a", "status": "unknown"}`, true},
+		{"item type", `{"id": 1, "name": "a", "tags": [1]}`, true},
+		{"below minimum", `{"id": 0, "name": "a"}`, true},
+		{"extra fields allowed", `{"id": 1, "name": "a", "extra": true}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateJSONSchema(schema, mustJSON(t, tt.data))
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("wantErr=%v, got %v", tt.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestValidateJSONSchema_Composition(t *testing.T) {
+	schema := mustJSON(t, `{"oneOf": [{"type": "string"}, {"type": "integer"}]}`).(map[string]any)
+	if errs := ValidateJSONSchema(schema, "a"); len(errs) != 0 {
+		t.Errorf("expected string to match oneOf, got %v", errs)
+	}
+	if errs := ValidateJSONSchema(schema, true); len(errs) == 0 {
+		t.Error("expected boolean to fail oneOf")
+	}
+
+	strict := mustJSON(t, `{"type": "object", "properties": {"a": {}}, "additionalProperties": false}`).(map[string]any)
+	if errs := ValidateJSONSchema(strict, mustJSON(t, `{"a": 1, "b": 2}`)); len(errs) != 1 {
+		t.Errorf("expected one additionalProperties error, got %v", errs)
+	}
+}
+
+func TestAssertJSONSchema(t *testing.T) {
+	e := &ProcessorExecutor{response: map[string]interface{}{"body": `{"id": 1}`}}
+	if ok, msg := e.doAssertion("json_schema", "", "", `{"type":"object","required":["id"]}`); !ok {
+		t.Errorf("expected pass, got %s", msg)
+	}
+	if ok, _ := e.doAssertion("json_schema", "", "", `{"type":"object","required":["name"]}`); ok {
+		t.Error("expected failure for missing field")
+	}
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"yqhp/workflow-engine/pkg/evaluator"
+)
+
+// executeLLMJudge 由评审模型按评分标准为响应打分，分数低于阈值时断言失败
+func (e *ProcessorExecutor) executeLLMJudge(ctx context.Context, pctx *processorContext) {
+	cfg := pctx.processor.Config
+	actual, ok := e.evalSource(cfg)
+	if !ok {
+		pctx.success = false
+		pctx.message = "无响应数据，无法执行评审"
+		return
+	}
+
+	result, err := evaluator.Judge(ctx, &evaluator.JudgeRequest{
+		Model: evaluator.ModelConfig{
+			Provider: e.configString(cfg, "provider"),
+			Model:    e.configString(cfg, "model"),
+			APIKey:   e.configString(cfg, "apiKey"),
+			BaseURL:  e.configString(cfg, "baseUrl"),
+		},
+		Rubric:    e.configString(cfg, "rubric"),
+		Input:     e.configString(cfg, "input"),
+		Response:  actual,
+		Reference: e.configString(cfg, "reference"),
+		Threshold: configFloat(cfg, "threshold"),
+	})
+	if err != nil {
+		pctx.success = false
+		pctx.message = fmt.Sprintf("评审失败: %v", err)
+		return
+	}
+
+	pctx.success = result.Passed
+	pctx.output = map[string]any{
+		"score":     result.Score,
+		"threshold": result.Threshold,
+		"passed":    result.Passed,
+		"rationale": result.Rationale,
+		"model":     result.Model,
+		"cached":    result.Cached,
+	}
+	if result.Passed {
+		pctx.message = fmt.Sprintf("断言通过: 评审分数 %.2f >= %.2f，%s", result.Score, result.Threshold, result.Rationale)
+	} else {
+		pctx.message = fmt.Sprintf("断言失败: 评审分数 %.2f < %.2f，%s", result.Score, result.Threshold, result.Rationale)
+	}
+	e.saveEvalScore(cfg, result.Score, "llm_judge")
+}
+
+// executeSemanticSimilarity 计算响应与参考答案的向量相似度，低于阈值时断言失败
+func (e *ProcessorExecutor) executeSemanticSimilarity(ctx context.Context, pctx *processorContext) {
+	cfg := pctx.processor.Config
+	actual, ok := e.evalSource(cfg)
+	if !ok {
+		pctx.success = false
+		pctx.message = "无响应数据，无法计算语义相似度"
+		return
+	}
+	expected := e.configString(cfg, "expected")
+	if expected == "" {
+		pctx.success = false
+		pctx.message = "语义相似度需要配置参考答案 expected"
+		return
+	}
+
+	result, err := evaluator.Similarity(ctx, &evaluator.SimilarityRequest{
+		Embedding: evaluator.EmbeddingConfig{
+			KnowledgeBaseID: configInt64(cfg, "knowledgeBaseId"),
+			ModelID:         configInt64(cfg, "embeddingModelId"),
+			GuluHost:        e.configString(cfg, "guluHost"),
+			Model:           e.configString(cfg, "model"),
+			APIKey:          e.configString(cfg, "apiKey"),
+			BaseURL:         e.configString(cfg, "baseUrl"),
+		},
+		Actual:    actual,
+		Reference: expected,
+		Threshold: configFloat(cfg, "threshold"),
+	})
+	if err != nil {
+		pctx.success = false
+		pctx.message = fmt.Sprintf("语义相似度计算失败: %v", err)
+		return
+	}
+
+	pctx.success = result.Passed
+	pctx.output = map[string]any{
+		"score":     result.Score,
+		"threshold": result.Threshold,
+		"passed":    result.Passed,
+	}
+	if result.Passed {
+		pctx.message = fmt.Sprintf("断言通过: 语义相似度 %.4f >= %.2f", result.Score, result.Threshold)
+	} else {
+		pctx.message = fmt.Sprintf("断言失败: 语义相似度 %.4f < %.2f", result.Score, result.Threshold)
+	}
+	e.saveEvalScore(cfg, result.Score, "semantic_similarity")
+}
+
+// evalSource 待评估文本：配置了 source 时取其变量替换结果，否则取 AI 回复内容或响应体
+func (e *ProcessorExecutor) evalSource(cfg map[string]any) (string, bool) {
+	if source := e.configString(cfg, "source"); source != "" {
+		return source, true
+	}
+	if e.response == nil {
+		return "", false
+	}
+	if content, ok := e.response["content"].(string); ok {
+		return content, true
+	}
+	body, ok := e.response["body"].(string)
+	return body, ok
+}
+
+// saveEvalScore 配置了 variableName 时保存评估分数
+func (e *ProcessorExecutor) saveEvalScore(cfg map[string]any, score float64, source string) {
+	name, _ := cfg["variableName"].(string)
+	if name == "" {
+		return
+	}
+	scope, _ := cfg["scope"].(string)
+	if scope == "" {
+		scope = "temp"
+	}
+	key := name
+	if scope == "env" {
+		key = "env." + name
+	}
+	e.setVariable(key, score, scope, source)
+}
+
+// configString 读取字符串配置并替换变量
+func (e *ProcessorExecutor) configString(cfg map[string]any, key string) string {
+	s, _ := cfg[key].(string)
+	return e.replaceVariables(s)
+}
+
+// configFloat 读取数值配置，兼容 JSON(float64)、YAML(int) 与字符串
+func configFloat(cfg map[string]any, key string) float64 {
+	switch v := cfg[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+func configInt64(cfg map[string]any, key string) int64 {
+	return int64(configFloat(cfg, key))
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -107,6 +108,12 @@ func (e *ProcessorExecutor) executeProcessor(ctx context.Context, processor type
 	case "extract_param":
 		e.executeExtractParam(pctx)
 
+	case "llm_judge":
+		e.executeLLMJudge(ctx, pctx)
+
+	case "semantic_similarity":
+		e.executeSemanticSimilarity(ctx, pctx)
+
 	default:
 		pctx.message = fmt.Sprintf("暂不支持的处理器类型: %s", processor.Type)
 	}
@@ -168,6 +175,20 @@ func (e *ProcessorExecutor) executeJsScript(ctx context.Context, pctx *processor
 		return
 	}
 
+	// 兼容模式：Postman 注入 pm.* 兼容层，k6 注入 check 与 Response 对象
+	switch compat, _ := pctx.processor.Config["compat"].(string); compat {
+	case script.CompatPostman:
+		eventName := "prerequest"
+		if pctx.phase == "post" {
+			eventName = "test"
+		}
+		requestName, _ := pctx.processor.Config["requestName"].(string)
+		scriptCode = script.WrapPostmanScript(scriptCode, eventName, requestName)
+	case script.CompatK6:
+		responseVar, _ := pctx.processor.Config["responseVar"].(string)
+		scriptCode = script.WrapK6Script(scriptCode, responseVar)
+	}
+
 	// 准备运行时配置，直接传递统一 variables（含 env. 前缀的环境变量）
 	rtConfig := &script.JSRuntimeConfig{
 		Variables: make(map[string]interface{}, len(e.variables)),
@@ -276,7 +297,13 @@ func (e *ProcessorExecutor) executeSetVariable(pctx *processorContext) {
 // executeWait 等待
 func (e *ProcessorExecutor) executeWait(pctx *processorContext) {
 	duration := 1000 // 默认 1000ms
-	if d, ok := pctx.processor.Config["duration"].(float64); ok {
+	// JSON 解码为 float64，YAML 解码为 int
+	switch d := pctx.processor.Config["duration"].(type) {
+	case float64:
+		duration = int(d)
+	case int:
+		duration = d
+	case int64:
 		duration = int(d)
 	}
 	time.Sleep(time.Duration(duration) * time.Millisecond)
@@ -331,14 +358,8 @@ func (e *ProcessorExecutor) doAssertion(assertType, operator, expression, expect
 		if bodyRaw, ok := e.response["body"].(string); ok {
 			var data interface{}
 			if err := json.Unmarshal([]byte(bodyRaw), &data); err == nil {
-				key := expression
-				if len(key) > 2 && key[:2] == "$." {
-					key = key[2:]
-				}
-				if m, ok := data.(map[string]interface{}); ok {
-					if v, ok := m[key]; ok {
-						actual = fmt.Sprintf("%v", v)
-					}
+				if v, ok := LookupJSONPath(data, expression); ok {
+					actual = fmt.Sprintf("%v", v)
 				}
 			}
 		}
@@ -352,6 +373,8 @@ func (e *ProcessorExecutor) doAssertion(assertType, operator, expression, expect
 		if duration, ok := e.response["duration"].(int64); ok {
 			actual = fmt.Sprintf("%d", duration)
 		}
+	case "json_schema":
+		return e.assertJSONSchema(expected)
 	default:
 		return false, fmt.Sprintf("不支持的断言类型: %s", assertType)
 	}
@@ -387,6 +410,12 @@ func (e *ProcessorExecutor) doAssertion(assertType, operator, expression, expect
 		fmt.Sscanf(actual, "%f", &actualNum)
 		fmt.Sscanf(expected, "%f", &expectedNum)
 		passed = actualNum <= expectedNum
+	case "matches", "not_matches":
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return false, fmt.Sprintf("正则表达式无效: %s", err.Error())
+		}
+		passed = re.MatchString(actual) == (operator == "matches")
 	default:
 		return false, fmt.Sprintf("不支持的操作符: %s", operator)
 	}
@@ -397,6 +426,25 @@ func (e *ProcessorExecutor) doAssertion(assertType, operator, expression, expect
 	return false, fmt.Sprintf("断言失败: 期望 %s %s %s，实际值: %s", assertType, operator, expected, actual)
 }
 
+// assertJSONSchema 校验响应体是否符合 expected 中的 JSON Schema
+func (e *ProcessorExecutor) assertJSONSchema(schemaJSON string) (bool, string) {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		return false, fmt.Sprintf("JSON Schema 解析失败: %v", err)
+	}
+
+	bodyRaw, _ := e.response["body"].(string)
+	var data any
+	if err := json.Unmarshal([]byte(bodyRaw), &data); err != nil {
+		return false, "断言失败: 响应体不是合法的 JSON"
+	}
+
+	if errs := ValidateJSONSchema(schema, data); len(errs) > 0 {
+		return false, "断言失败: 响应不符合 JSON Schema: " + strings.Join(errs, "; ")
+	}
+	return true, "断言通过: 响应符合 JSON Schema"
+}
+
 // executeExtractParam 提取参数
 func (e *ProcessorExecutor) executeExtractParam(pctx *processorContext) {
 	if e.response == nil {
@@ -457,14 +505,8 @@ func (e *ProcessorExecutor) extractValue(extractType, expression string) (interf
 			if err := json.Unmarshal([]byte(bodyRaw), &data); err != nil {
 				return nil, fmt.Errorf("解析 JSON 失败: %s", err.Error())
 			}
-			key := expression
-			if len(key) > 2 && key[:2] == "$." {
-				key = key[2:]
-			}
-			if m, ok := data.(map[string]interface{}); ok {
-				if v, ok := m[key]; ok {
-					return v, nil
-				}
+			if v, ok := LookupJSONPath(data, expression); ok {
+				return v, nil
 			}
 		}
 		return nil, fmt.Errorf("未找到路径: %s", expression)
@@ -477,6 +519,21 @@ func (e *ProcessorExecutor) extractValue(extractType, expression string) (interf
 		}
 		return nil, fmt.Errorf("未找到 Header: %s", expression)
 
+	case "regex":
+		// 返回第一个捕获组，无捕获组时返回整个匹配
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式无效: %s", err.Error())
+		}
+		body, _ := e.response["body"].(string)
+		if m := re.FindStringSubmatch(body); m != nil {
+			if len(m) > 1 {
+				return m[1], nil
+			}
+			return m[0], nil
+		}
+		return nil, fmt.Errorf("未匹配到正则: %s", expression)
+
 	case "cookie":
 		if cookies, ok := e.response["cookies"].(map[string]interface{}); ok {
 			if v, ok := cookies[expression]; ok {
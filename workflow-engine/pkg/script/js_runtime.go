@@ -390,6 +390,12 @@ func (r *JSRuntime) setupSimpleAPI() {
 				bodyStr = bs
 			}
 			response.Set("text", bodyStr)
+			// 响应耗时（毫秒），仅后置处理器可用
+			if duration, ok := respMap["duration"].(int64); ok {
+				response.Set("duration", duration)
+			} else {
+				response.Set("duration", 0)
+			}
 			response.Set("json", func(call goja.FunctionCall) goja.Value {
 				if bodyStr == "" {
 					return goja.Undefined()
@@ -407,6 +413,7 @@ func (r *JSRuntime) setupSimpleAPI() {
 		response.Set("headers", r.vm.NewObject())
 		response.Set("body", goja.Undefined())
 		response.Set("text", "")
+		response.Set("duration", 0)
 		response.Set("json", func(call goja.FunctionCall) goja.Value {
 			return goja.Undefined()
 		})
@@ -0,0 +1,62 @@
+package script
+
+// CompatK6 js_script 处理器的 k6 兼容模式（config.compat），用于运行从 k6 脚本转换的 check 语句
+const CompatK6 = "k6"
+
+// WrapK6Script 为 k6 脚本注入 check / fail 兼容函数，并将当前响应以 k6 Response 的形式绑定到 responseVar；
+// 任一 check 未通过时脚本抛出错误
+func WrapK6Script(code, responseVar string) string {
+	bind := ""
+	if responseVar != "" && responseVar != "__k6Response" {
+		bind = "var " + responseVar + " = __k6Response;\n"
+	}
+	return k6Prelude + bind +
+		"(function () {\n" + code + "\n})();\n" +
+		k6Epilogue
+}
+
+const k6Prelude = `var __k6Failed = [];
+var __k6Response = (function () {
+  var body = typeof response.body === "string" ? response.body : (response.body === undefined ? "" : JSON.stringify(response.body));
+  return {
+    status: response.code,
+    status_text: response.status,
+    body: body,
+    headers: response.headers || {},
+    timings: { duration: response.duration || 0 },
+    json: function (selector) {
+      var data = JSON.parse(body);
+      if (selector === undefined || selector === "") return data;
+      var parts = String(selector).split(".");
+      for (var i = 0; i < parts.length; i++) {
+        if (data === null || data === undefined) return undefined;
+        data = data[parts[i]];
+      }
+      return data;
+    }
+  };
+})();
+function check(val, sets) {
+  var ok = true;
+  Object.keys(sets).forEach(function (name) {
+    var passed = false;
+    try {
+      passed = typeof sets[name] === "function" ? !!sets[name](val) : !!sets[name];
+    } catch (e) {
+      passed = false;
+    }
+    if (passed) {
+      console.log("✓ " + name);
+    } else {
+      ok = false;
+      __k6Failed.push(name);
+      console.error("✗ " + name);
+    }
+  });
+  return ok;
+}
+function fail(msg) { throw new Error(msg); }
+`
+
+const k6Epilogue = `if (__k6Failed.length > 0) throw new Error("k6 check 未通过: " + __k6Failed.join("; "));
+`
@@ -0,0 +1,46 @@
+package script
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newK6Runtime() *JSRuntime {
+	return NewJSRuntime(&JSRuntimeConfig{
+		Response: map[string]interface{}{
+			"statusCode": 200,
+			"statusText": "OK",
+			"headers":    map[string]interface{}{"Content-Type": "application/json"},
+			"body":       `{"data": {"token": "abc123", "items": [{"id": 7}]}}`,
+			"duration":   int64(42),
+		},
+	})
+}
+
+func TestWrapK6Script_Passing(t *testing.T) {
+	code := `check(res, {
+  "status is 200": (r) => r.status === 200,
+  "has token": (r) => r.json("data.token").length > 0 && r.json().data.items[0].id === 7,
+  "fast": (r) => r.timings.duration < 100,
+});`
+	if _, err := newK6Runtime().Execute(WrapK6Script(code, "res"), 5*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWrapK6Script_Failing(t *testing.T) {
+	code := `check(r, {
+  "status is 404": (r) => r.status === 404,
+  "body has token": (r) => r.body.includes("abc123"),
+  "throws": (r) => r.json("missing.path").length > 0,
+});`
+	_, err := newK6Runtime().Execute(WrapK6Script(code, "r"), 5*time.Second)
+	if err == nil {
+		t.Fatal("expected failing checks to raise an error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "status is 404") || !strings.Contains(msg, "throws") || strings.Contains(msg, "body has token") {
+		t.Errorf("unexpected error message: %s", msg)
+	}
+}
@@ -0,0 +1,215 @@
+package script
+
+import "encoding/json"
+
+// CompatPostman js_script 处理器的 Postman 兼容模式（config.compat），用于运行从 Postman 导入的脚本
+const CompatPostman = "postman"
+
+// WrapPostmanScript 为 Postman 脚本注入 pm.* 兼容层：
+// pm.environment / pm.collectionVariables / pm.globals / pm.variables 均映射到 env（导入时 {{x}} 转换为 ${env.x}），
+// pm.variables.get 优先读取临时变量；pm.response 映射到 response；pm.test 与 tests[] 任一失败时脚本抛出错误
+func WrapPostmanScript(code, eventName, requestName string) string {
+	info, _ := json.Marshal(map[string]string{"eventName": eventName, "requestName": requestName})
+	return "var __pmInfo = " + string(info) + ";\n" +
+		postmanPrelude +
+		"(function () {\n" + code + "\n})();\n" +
+		postmanEpilogue
+}
+
+const postmanPrelude = `var __pmResults = [];
+var pm = (function () {
+  function lookup(k) { return vars.has(k) ? vars.get(k) : env.get(k); }
+  function replaceIn(s) {
+    return String(s).replace(/\{\{([^{}]+)\}\}/g, function (m, k) {
+      var v = lookup(k);
+      return v === undefined ? m : String(v);
+    });
+  }
+  function scope(get) {
+    return {
+      get: get,
+      set: function (k, v) { env.set(k, v); },
+      has: function (k) { return get(k) !== undefined; },
+      unset: function (k) { env.del(k); },
+      clear: function () {},
+      toObject: function () { return env.all(); },
+      replaceIn: replaceIn
+    };
+  }
+  var envScope = scope(function (k) { return env.get(k); });
+
+  function deepEqual(a, b) {
+    if (a === b) return true;
+    if (typeof a !== "object" || typeof b !== "object" || a === null || b === null) return false;
+    if (Array.isArray(a) !== Array.isArray(b)) return false;
+    var ka = Object.keys(a), kb = Object.keys(b);
+    if (ka.length !== kb.length) return false;
+    for (var i = 0; i < ka.length; i++) {
+      if (!deepEqual(a[ka[i]], b[ka[i]])) return false;
+    }
+    return true;
+  }
+  function show(v) {
+    try { return JSON.stringify(v); } catch (e) { return String(v); }
+  }
+
+  function Assertion(actual, negate, deep) {
+    this._actual = actual;
+    this._negate = !!negate;
+    this._deep = !!deep;
+  }
+  var proto = Assertion.prototype;
+  proto._check = function (ok, msg) {
+    if (this._negate ? ok : !ok) {
+      throw new Error("expected " + show(this._actual) + (this._negate ? " not " : " ") + msg);
+    }
+    return this;
+  };
+  ["to", "be", "been", "is", "that", "which", "and", "has", "have", "with", "at", "of", "same", "does", "still"].forEach(function (w) {
+    Object.defineProperty(proto, w, { get: function () { return this; } });
+  });
+  Object.defineProperty(proto, "not", { get: function () { return new Assertion(this._actual, !this._negate, this._deep); } });
+  Object.defineProperty(proto, "deep", { get: function () { return new Assertion(this._actual, this._negate, true); } });
+  var flags = {
+    ok: [function (v) { return !!v; }, "to be truthy"],
+    true: [function (v) { return v === true; }, "to be true"],
+    false: [function (v) { return v === false; }, "to be false"],
+    null: [function (v) { return v === null; }, "to be null"],
+    undefined: [function (v) { return v === undefined; }, "to be undefined"],
+    exist: [function (v) { return v !== null && v !== undefined; }, "to exist"],
+    empty: [function (v) {
+      if (typeof v === "string" || Array.isArray(v)) return v.length === 0;
+      return v !== null && typeof v === "object" && Object.keys(v).length === 0;
+    }, "to be empty"]
+  };
+  Object.keys(flags).forEach(function (name) {
+    Object.defineProperty(proto, name, { get: function () { return this._check(flags[name][0](this._actual), flags[name][1]); } });
+  });
+  proto.equal = proto.equals = proto.eq = function (v) {
+    return this._check(this._deep ? deepEqual(this._actual, v) : this._actual === v, "to equal " + show(v));
+  };
+  proto.eql = function (v) { return this._check(deepEqual(this._actual, v), "to deeply equal " + show(v)); };
+  proto.a = proto.an = function (type) {
+    var v = this._actual, t = Array.isArray(v) ? "array" : v === null ? "null" : typeof v;
+    return this._check(t === String(type).toLowerCase(), "to be a " + type);
+  };
+  proto.include = proto.includes = proto.contain = proto.contains = function (v) {
+    var a = this._actual, ok = false;
+    if (typeof a === "string") {
+      ok = a.indexOf(v) >= 0;
+    } else if (Array.isArray(a)) {
+      for (var i = 0; i < a.length && !ok; i++) ok = this._deep ? deepEqual(a[i], v) : a[i] === v;
+    } else if (a && typeof a === "object" && v && typeof v === "object") {
+      ok = Object.keys(v).every(function (k) { return deepEqual(a[k], v[k]); });
+    }
+    return this._check(ok, "to include " + show(v));
+  };
+  proto.property = function (name, value) {
+    var a = this._actual, ok = a !== null && a !== undefined && Object(a).hasOwnProperty(name);
+    if (ok && arguments.length > 1) ok = deepEqual(a[name], value);
+    return this._check(ok, "to have property " + name + (arguments.length > 1 ? " of " + show(value) : ""));
+  };
+  proto.lengthOf = proto.length = function (n) {
+    var a = this._actual;
+    return this._check(a !== null && a !== undefined && a.length === n, "to have length " + n);
+  };
+  proto.keys = function () {
+    var want = Array.isArray(arguments[0]) ? arguments[0] : Array.prototype.slice.call(arguments);
+    var a = this._actual || {};
+    return this._check(want.every(function (k) { return Object(a).hasOwnProperty(k); }), "to have keys " + show(want));
+  };
+  proto.above = proto.gt = proto.greaterThan = function (n) { return this._check(this._actual > n, "to be above " + n); };
+  proto.below = proto.lt = proto.lessThan = function (n) { return this._check(this._actual < n, "to be below " + n); };
+  proto.least = proto.gte = function (n) { return this._check(this._actual >= n, "to be at least " + n); };
+  proto.most = proto.lte = function (n) { return this._check(this._actual <= n, "to be at most " + n); };
+  proto.within = function (lo, hi) { return this._check(this._actual >= lo && this._actual <= hi, "to be within " + lo + ".." + hi); };
+  proto.match = function (re) { return this._check(re.test(String(this._actual)), "to match " + re); };
+  proto.oneOf = function (list) { return this._check(list.indexOf(this._actual) >= 0, "to be one of " + show(list)); };
+
+  function header(name) {
+    var h = response.headers || {}, lower = String(name).toLowerCase();
+    for (var k in h) {
+      if (k.toLowerCase() === lower) return Array.isArray(h[k]) ? h[k].join(", ") : h[k];
+    }
+    return undefined;
+  }
+  function text() {
+    if (typeof response.body === "string") return response.body;
+    return response.body === undefined ? "" : JSON.stringify(response.body);
+  }
+  function statusCheck(ok, msg) {
+    if (!ok) throw new Error("expected response " + msg + " but got " + response.code);
+  }
+  var res = {
+    code: response.code,
+    status: response.status,
+    responseTime: 0,
+    headers: {
+      get: header,
+      has: function (n) { return header(n) !== undefined; },
+      toObject: function () { return response.headers; }
+    },
+    text: text,
+    json: function () {
+      return typeof response.body === "string" ? JSON.parse(response.body) : response.body;
+    },
+    to: { have: {}, be: {} }
+  };
+  res.to.have.status = function (s) {
+    statusCheck(typeof s === "number" ? response.code === s : response.status === s, "to have status " + s);
+  };
+  res.to.have.header = function (n, v) {
+    statusCheck(header(n) !== undefined && (arguments.length < 2 || header(n) === v), "to have header " + n);
+  };
+  res.to.have.jsonBody = function () { JSON.parse(text()); };
+  Object.defineProperty(res.to.be, "ok", { get: function () { statusCheck(response.code >= 200 && response.code < 300, "to be ok"); } });
+  Object.defineProperty(res.to.be, "success", { get: function () { statusCheck(response.code >= 200 && response.code < 300, "to be success"); } });
+  Object.defineProperty(res.to.be, "json", { get: function () { JSON.parse(text()); } });
+
+  function test(name, fn) {
+    try {
+      fn();
+      __pmResults.push({ name: name, passed: true });
+      console.log("✓ " + name);
+    } catch (e) {
+      __pmResults.push({ name: name, passed: false, error: e && e.message ? e.message : String(e) });
+      console.error("✗ " + name + ": " + (e && e.message ? e.message : String(e)));
+    }
+  }
+  test.skip = function (name) { console.warn("跳过测试: " + name); };
+
+  return {
+    info: __pmInfo,
+    environment: envScope,
+    collectionVariables: envScope,
+    globals: envScope,
+    variables: scope(lookup),
+    response: res,
+    request: {},
+    test: test,
+    expect: function (v) { return new Assertion(v); },
+    sendRequest: function () { throw new Error("pm.sendRequest 暂不支持，请改用 http.request"); }
+  };
+})();
+var tests = {};
+var responseBody = pm.response.text();
+var responseCode = { code: response.code, name: response.status };
+var responseHeaders = response.headers;
+var responseTime = 0;
+var postman = {
+  setEnvironmentVariable: function (k, v) { env.set(k, v); },
+  getEnvironmentVariable: function (k) { return env.get(k); },
+  clearEnvironmentVariable: function (k) { env.del(k); },
+  setGlobalVariable: function (k, v) { env.set(k, v); },
+  getGlobalVariable: function (k) { return env.get(k); },
+  clearGlobalVariable: function (k) { env.del(k); }
+};
+`
+
+const postmanEpilogue = `(function () {
+  var failed = [];
+  __pmResults.forEach(function (r) { if (!r.passed) failed.push(r.name + ": " + r.error); });
+  Object.keys(tests).forEach(function (name) { if (!tests[name]) failed.push(name); });
+  if (failed.length > 0) throw new Error("Postman 测试未通过: " + failed.join("; "));
+})();
+`
@@ -0,0 +1,60 @@
+package script
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPostmanRuntime(vars map[string]interface{}) *JSRuntime {
+	return NewJSRuntime(&JSRuntimeConfig{
+		Variables: vars,
+		Response: map[string]interface{}{
+			"statusCode": 200,
+			"statusText": "OK",
+			"headers":    map[string]interface{}{"Content-Type": "application/json"},
+			"body":       `{"data": {"token": "abc123", "items": [1, 2]}}`,
+		},
+	})
+}
+
+func TestWrapPostmanScript_Passing(t *testing.T) {
+	code := `
+pm.test("status", function () { pm.response.to.have.status(200); });
+pm.test("body", function () {
+  var json = pm.response.json();
+  pm.expect(json.data.token).to.be.a("string").and.to.equal("abc123");
+  pm.expect(json.data.items).to.have.lengthOf(2).and.to.include(2);
+  pm.expect(json).to.have.property("data");
+  pm.expect(json.data).to.not.be.empty;
+  pm.expect(pm.response.headers.get("content-type")).to.include("json");
+});
+pm.environment.set("token", pm.response.json().data.token);
+tests["legacy"] = responseCode.code === 200;
+pm.variables.get("id") + ":" + pm.variables.replaceIn("{{host}}/x");
+`
+	rt := newPostmanRuntime(map[string]interface{}{"id": "7", "env.host": "example.com"})
+	result, err := rt.Execute(WrapPostmanScript(code, "test", "Get user"), 5*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Variables["env.token"] != "abc123" {
+		t.Errorf("expected env.token to be set, got %v", result.Variables["env.token"])
+	}
+}
+
+func TestWrapPostmanScript_Failing(t *testing.T) {
+	code := `
+pm.test("wrong status", function () { pm.response.to.have.status(404); });
+pm.test("deep equal", function () { pm.expect({a: [1]}).to.eql({a: [1]}); });
+tests["legacy failure"] = false;
+`
+	_, err := newPostmanRuntime(nil).Execute(WrapPostmanScript(code, "test", ""), 5*time.Second)
+	if err == nil {
+		t.Fatal("expected failing tests to raise an error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "wrong status") || !strings.Contains(msg, "legacy failure") || strings.Contains(msg, "deep equal") {
+		t.Errorf("unexpected error message: %s", msg)
+	}
+}
@@ -20,6 +20,30 @@ type ExecutionCallback interface {
 	OnExecutionComplete(ctx context.Context, summary *ExecutionSummary)
 }
 
+// DebugCallback 可选的调试回调接口。
+// 回调实现该接口时，引擎在每个步骤（含循环、条件与引用工作流的子步骤）开始前调用 OnBeforeStep，
+// 返回前步骤不会执行，用于断点暂停与单步调试；返回错误时中止执行。
+type DebugCallback interface {
+	OnBeforeStep(ctx context.Context, step *Step, frame *DebugFrame) error
+}
+
+// DebugFrame 步骤开始前的执行现场
+type DebugFrame struct {
+	ParentID  string
+	Iteration int
+	Scope     DebugScope
+}
+
+// DebugScope 暂停时可访问的变量作用域
+type DebugScope interface {
+	// Variables 返回变量快照（含已执行步骤的结果）
+	Variables() map[string]any
+	// SetVariable 修改变量，对后续步骤生效
+	SetVariable(name string, value any)
+	// Evaluate 在当前作用域求值条件表达式
+	Evaluate(expr string) (bool, error)
+}
+
 // AIStreamCallback 统一的 AI 流式回调接口
 // 替代原 AICallback / AIToolCallback / AIThinkingCallback / AIPlanCallback
 type AIStreamCallback interface {
@@ -62,12 +86,14 @@ type AIStreamCallback interface {
 
 // AIResult AI 执行结果
 type AIResult struct {
-	Content          string `json:"content"`
-	PromptTokens     int    `json:"promptTokens"`
-	CompletionTokens int    `json:"completionTokens"`
-	TotalTokens      int    `json:"totalTokens"`
-	Model            string `json:"model,omitempty"`
-	FinishReason     string `json:"finishReason,omitempty"`
+	Content          string  `json:"content"`
+	PromptTokens     int     `json:"promptTokens"`
+	CachedTokens     int     `json:"cachedTokens,omitempty"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	Cost             float64 `json:"cost,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	FinishReason     string  `json:"finishReason,omitempty"`
 }
 
 // PlanUpdateAction 计划更新动作
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Checkpoint 顶层步骤执行完成后的检查点，用于从失败步骤恢复执行。
+// 变量为截至该步骤的完整快照（含循环变量 loop.*），结果仅包含该步骤（及其子步骤）新产生的结果。
+type Checkpoint struct {
+	StepIndex int                          `json:"stepIndex"` // 顶层步骤序号，从 0 开始
+	StepID    string                       `json:"stepId"`
+	Status    ResultStatus                 `json:"status"`
+	Variables map[string]any               `json:"variables"`
+	Results   map[string]*CheckpointResult `json:"results,omitempty"`
+	CreatedAt time.Time                    `json:"createdAt"`
+}
+
+// CheckpointResult 可序列化的步骤结果
+type CheckpointResult struct {
+	Status     ResultStatus       `json:"status"`
+	Output     any                `json:"output,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	StartTime  time.Time          `json:"startTime"`
+	EndTime    time.Time          `json:"endTime"`
+	DurationMs int64              `json:"durationMs"`
+	Metrics    map[string]float64 `json:"metrics,omitempty"`
+}
+
+// NewCheckpointResult 将步骤结果转换为可序列化的形式
+func NewCheckpointResult(r *StepResult) *CheckpointResult {
+	cr := &CheckpointResult{
+		Status:     r.Status,
+		Output:     r.Output,
+		StartTime:  r.StartTime,
+		EndTime:    r.EndTime,
+		DurationMs: r.Duration.Milliseconds(),
+		Metrics:    r.Metrics,
+	}
+	if r.Error != nil {
+		cr.Error = r.Error.Error()
+	}
+	return cr
+}
+
+// StepResult 还原为步骤结果
+func (r *CheckpointResult) StepResult(stepID string) *StepResult {
+	result := &StepResult{
+		StepID:    stepID,
+		Status:    r.Status,
+		StartTime: r.StartTime,
+		EndTime:   r.EndTime,
+		Duration:  time.Duration(r.DurationMs) * time.Millisecond,
+		Output:    r.Output,
+		Metrics:   r.Metrics,
+	}
+	if r.Error != "" {
+		result.Error = errors.New(r.Error)
+	}
+	return result
+}
+
+// ResumePoint 恢复执行的起点：跳过 StepID 之前的顶层步骤，
+// 并用之前检查点的变量与结果重建执行上下文
+type ResumePoint struct {
+	StepID    string
+	Variables map[string]any
+	Results   map[string]*CheckpointResult
+}
+
+// CheckpointCallback 可选的检查点回调接口，回调实现该接口时每个顶层步骤完成后调用
+type CheckpointCallback interface {
+	OnCheckpoint(ctx context.Context, checkpoint *Checkpoint)
+}
@@ -0,0 +1,15 @@
+package types
+
+import "os"
+
+// Gulu 内部接口认证：workflow-engine 回调 Gulu 的 /api/internal/* 接口（向量化、知识库检索等）时
+// 在请求头中携带共享令牌。内置引擎由 Gulu 在启动时写入环境变量，独立部署的 Master / Slave 需配置相同的令牌。
+const (
+	GuluInternalTokenHeader = "X-Gulu-Internal-Token"
+	GuluInternalTokenEnv    = "GULU_INTERNAL_TOKEN"
+)
+
+// GuluInternalToken 返回回调 Gulu 内部接口使用的令牌
+func GuluInternalToken() string {
+	return os.Getenv(GuluInternalTokenEnv)
+}
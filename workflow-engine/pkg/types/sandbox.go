@@ -0,0 +1,71 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 沙箱资源限制默认值
+const (
+	DefaultSandboxCPUSeconds    = 60
+	DefaultSandboxMemoryMB      = 1024
+	DefaultSandboxMaxProcesses  = 256
+	DefaultSandboxMaxFileSizeMB = 64
+)
+
+// SandboxPolicy shell_exec / code_execute 工具的沙箱策略。
+// 零值即默认策略：启用沙箱、禁止网络、使用默认资源限制，命名空间在可用时开启。
+type SandboxPolicy struct {
+	Disabled         bool     `json:"disabled,omitempty"`          // 关闭沙箱，直接在宿主机执行
+	AllowNetwork     bool     `json:"allow_network,omitempty"`     // 允许访问网络
+	AllowedCommands  []string `json:"allowed_commands,omitempty"`  // shell_exec 允许的命令，为空时不限制
+	DeniedCommands   []string `json:"denied_commands,omitempty"`   // shell_exec 额外禁止的命令
+	AllowedLanguages []string `json:"allowed_languages,omitempty"` // code_execute 允许的语言，为空时不限制
+	CPUSeconds       int      `json:"cpu_seconds,omitempty"`       // CPU 时间上限（秒）
+	MemoryMB         int      `json:"memory_mb,omitempty"`         // 数据段内存上限（MB）
+	MaxProcesses     int      `json:"max_processes,omitempty"`     // 进程数上限，按单次执行的 cgroup 统计
+	MaxFileSizeMB    int      `json:"max_file_size_mb,omitempty"`  // 单个文件大小上限（MB）
+	Namespaces       *bool    `json:"namespaces,omitempty"`        // 使用 Linux 命名空间与文件系统隔离，默认开启
+	AllowDegraded    bool     `json:"allow_degraded,omitempty"`    // 命名空间隔离不可用时允许降级为仅资源限制执行，默认拒绝执行
+}
+
+// Validate 校验资源限制取值
+func (p *SandboxPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.CPUSeconds < 0 || p.MemoryMB < 0 || p.MaxProcesses < 0 || p.MaxFileSizeMB < 0 {
+		return errors.New("沙箱资源限制不能为负数")
+	}
+	for _, lang := range p.AllowedLanguages {
+		if lang != "python" && lang != "javascript" {
+			return fmt.Errorf("沙箱不支持的语言: %s，仅支持 python 和 javascript", lang)
+		}
+	}
+	return nil
+}
+
+// WithDefaults 返回填充默认资源限制后的副本，nil 视为默认策略
+func (p *SandboxPolicy) WithDefaults() *SandboxPolicy {
+	policy := &SandboxPolicy{}
+	if p != nil {
+		*policy = *p
+	}
+	if policy.CPUSeconds == 0 {
+		policy.CPUSeconds = DefaultSandboxCPUSeconds
+	}
+	if policy.MemoryMB == 0 {
+		policy.MemoryMB = DefaultSandboxMemoryMB
+	}
+	if policy.MaxProcesses == 0 {
+		policy.MaxProcesses = DefaultSandboxMaxProcesses
+	}
+	if policy.MaxFileSizeMB == 0 {
+		policy.MaxFileSizeMB = DefaultSandboxMaxFileSizeMB
+	}
+	if policy.Namespaces == nil {
+		enabled := true
+		policy.Namespaces = &enabled
+	}
+	return policy
+}
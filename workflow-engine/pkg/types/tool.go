@@ -30,6 +30,9 @@ type ToolResult struct {
 	IsError    bool   `json:"is_error"`
 	Silent     bool   `json:"silent"`
 	Async      bool   `json:"async"`
+
+	// Violations 沙箱策略违规记录（命令不在允许列表、超出资源限制、访问被禁止的网络等）
+	Violations []string `json:"violations,omitempty"`
 }
 
 // GetLLMContent 获取给 LLM 的内容，优先使用 ForLLM，回退到 Content
@@ -24,6 +24,8 @@ type Workflow struct {
 	FinalVariables map[string]any `yaml:"-" json:"-"`
 	// EnvVariables 环境变量快照（在执行前从环境配置加载的变量）
 	EnvVariables map[string]any `yaml:"-" json:"-"`
+	// Resume 从检查点恢复执行时的起点，为空时从第一个步骤开始
+	Resume *ResumePoint `yaml:"-" json:"-"`
 }
 
 // Step represents a single execution unit in a workflow.